@@ -0,0 +1,1873 @@
+// Flow-CodeBlock gRPC 接口
+//
+// 与 HTTP 接口共用 Token 认证、Token 限流、配额、统计和执行器：
+//   - FlowService：代码执行（Token 认证，metadata 中提供 accesstoken 或 authorization: Bearer <token>）
+//   - TokenAdminService：Token / 配额管理（管理员认证，metadata 中提供管理员令牌）
+//
+// 与 HTTP 接口的区别：代码和输入直接以原始字节传输（不需要 Base64 和 JSON 字符串转义）
+//
+// 修改后重新生成：
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//          api/flowpb/flow.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        v6.32.1
+// source: api/flowpb/flow.proto
+
+package flowpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ExecuteRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// JavaScript 代码（UTF-8 原文，不需要 Base64）
+	Code string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	// 输入参数：JSON 对象的原始字节，为空时等同于 {}
+	InputJson []byte `protobuf:"bytes,2,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"`
+	// 返回分阶段耗时（与 HTTP 接口的 debug=true 一致）
+	Debug         bool `protobuf:"varint,3,opt,name=debug,proto3" json:"debug,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteRequest) Reset() {
+	*x = ExecuteRequest{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteRequest) ProtoMessage() {}
+
+func (x *ExecuteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteRequest) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ExecuteRequest) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ExecuteRequest) GetInputJson() []byte {
+	if x != nil {
+		return x.InputJson
+	}
+	return nil
+}
+
+func (x *ExecuteRequest) GetDebug() bool {
+	if x != nil {
+		return x.Debug
+	}
+	return false
+}
+
+type ExecuteResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// 执行结果：JSON 原始字节（成功时）
+	ResultJson []byte `protobuf:"bytes,2,opt,name=result_json,json=resultJson,proto3" json:"result_json,omitempty"`
+	// 执行错误（失败时）
+	Error     *ExecuteError  `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Timing    *ExecuteTiming `protobuf:"bytes,4,opt,name=timing,proto3" json:"timing,omitempty"`
+	Timestamp string         `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	RequestId string         `protobuf:"bytes,6,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// console 输出（仅 capture 模式；流式接口中已逐条推送，这里不再重复）
+	Logs          []*ConsoleLog `protobuf:"bytes,7,rep,name=logs,proto3" json:"logs,omitempty"`
+	LogsTruncated bool          `protobuf:"varint,8,opt,name=logs_truncated,json=logsTruncated,proto3" json:"logs_truncated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteResponse) Reset() {
+	*x = ExecuteResponse{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteResponse) ProtoMessage() {}
+
+func (x *ExecuteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteResponse) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExecuteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ExecuteResponse) GetResultJson() []byte {
+	if x != nil {
+		return x.ResultJson
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetError() *ExecuteError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetTiming() *ExecuteTiming {
+	if x != nil {
+		return x.Timing
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *ExecuteResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ExecuteResponse) GetLogs() []*ConsoleLog {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetLogsTruncated() bool {
+	if x != nil {
+		return x.LogsTruncated
+	}
+	return false
+}
+
+type ExecuteError struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Type    string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Stack   string                 `protobuf:"bytes,3,opt,name=stack,proto3" json:"stack,omitempty"`
+	// 排队被拒绝时建议的重试等待秒数
+	RetryAfter    int32 `protobuf:"varint,4,opt,name=retry_after,json=retryAfter,proto3" json:"retry_after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteError) Reset() {
+	*x = ExecuteError{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteError) ProtoMessage() {}
+
+func (x *ExecuteError) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteError.ProtoReflect.Descriptor instead.
+func (*ExecuteError) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ExecuteError) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ExecuteError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ExecuteError) GetStack() string {
+	if x != nil {
+		return x.Stack
+	}
+	return ""
+}
+
+func (x *ExecuteError) GetRetryAfter() int32 {
+	if x != nil {
+		return x.RetryAfter
+	}
+	return 0
+}
+
+type ExecuteTiming struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ExecutionTimeMs int64                  `protobuf:"varint,1,opt,name=execution_time_ms,json=executionTimeMs,proto3" json:"execution_time_ms,omitempty"`
+	TotalTimeMs     int64                  `protobuf:"varint,2,opt,name=total_time_ms,json=totalTimeMs,proto3" json:"total_time_ms,omitempty"`
+	// 分阶段耗时（仅 debug=true 时返回）
+	Phases        []*ExecutionPhase `protobuf:"bytes,3,rep,name=phases,proto3" json:"phases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteTiming) Reset() {
+	*x = ExecuteTiming{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteTiming) ProtoMessage() {}
+
+func (x *ExecuteTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteTiming.ProtoReflect.Descriptor instead.
+func (*ExecuteTiming) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ExecuteTiming) GetExecutionTimeMs() int64 {
+	if x != nil {
+		return x.ExecutionTimeMs
+	}
+	return 0
+}
+
+func (x *ExecuteTiming) GetTotalTimeMs() int64 {
+	if x != nil {
+		return x.TotalTimeMs
+	}
+	return 0
+}
+
+func (x *ExecuteTiming) GetPhases() []*ExecutionPhase {
+	if x != nil {
+		return x.Phases
+	}
+	return nil
+}
+
+type ExecutionPhase struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Phase         string                 `protobuf:"bytes,1,opt,name=phase,proto3" json:"phase,omitempty"`
+	DurationMs    float64                `protobuf:"fixed64,2,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Cache         string                 `protobuf:"bytes,3,opt,name=cache,proto3" json:"cache,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecutionPhase) Reset() {
+	*x = ExecutionPhase{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecutionPhase) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecutionPhase) ProtoMessage() {}
+
+func (x *ExecutionPhase) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecutionPhase.ProtoReflect.Descriptor instead.
+func (*ExecutionPhase) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ExecutionPhase) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *ExecutionPhase) GetDurationMs() float64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *ExecutionPhase) GetCache() string {
+	if x != nil {
+		return x.Cache
+	}
+	return ""
+}
+
+type ConsoleLog struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Level         string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp     string                 `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	OffsetMs      int64                  `protobuf:"varint,4,opt,name=offset_ms,json=offsetMs,proto3" json:"offset_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsoleLog) Reset() {
+	*x = ConsoleLog{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsoleLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsoleLog) ProtoMessage() {}
+
+func (x *ConsoleLog) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsoleLog.ProtoReflect.Descriptor instead.
+func (*ConsoleLog) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ConsoleLog) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *ConsoleLog) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ConsoleLog) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *ConsoleLog) GetOffsetMs() int64 {
+	if x != nil {
+		return x.OffsetMs
+	}
+	return 0
+}
+
+// ExecuteStream 推送的事件：若干条 log，最后一条 result
+type ExecuteEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*ExecuteEvent_Log
+	//	*ExecuteEvent_Result
+	Event         isExecuteEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteEvent) Reset() {
+	*x = ExecuteEvent{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteEvent) ProtoMessage() {}
+
+func (x *ExecuteEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteEvent.ProtoReflect.Descriptor instead.
+func (*ExecuteEvent) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ExecuteEvent) GetEvent() isExecuteEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *ExecuteEvent) GetLog() *ConsoleLog {
+	if x != nil {
+		if x, ok := x.Event.(*ExecuteEvent_Log); ok {
+			return x.Log
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteEvent) GetResult() *ExecuteResponse {
+	if x != nil {
+		if x, ok := x.Event.(*ExecuteEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isExecuteEvent_Event interface {
+	isExecuteEvent_Event()
+}
+
+type ExecuteEvent_Log struct {
+	Log *ConsoleLog `protobuf:"bytes,1,opt,name=log,proto3,oneof"`
+}
+
+type ExecuteEvent_Result struct {
+	Result *ExecuteResponse `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*ExecuteEvent_Log) isExecuteEvent_Event() {}
+
+func (*ExecuteEvent_Result) isExecuteEvent_Event() {}
+
+// TokenInfo 可选字段未设置表示 null（与 HTTP 接口中的 null 一致）
+type TokenInfo struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Id                     int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	WsId                   string                 `protobuf:"bytes,2,opt,name=ws_id,json=wsId,proto3" json:"ws_id,omitempty"`
+	Email                  string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	AccessToken            string                 `protobuf:"bytes,4,opt,name=access_token,json=accessToken,proto3" json:"access_token,omitempty"`
+	CreatedAt              string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ExpiresAt              *string                `protobuf:"bytes,6,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`
+	OperationType          string                 `protobuf:"bytes,7,opt,name=operation_type,json=operationType,proto3" json:"operation_type,omitempty"`
+	IsActive               bool                   `protobuf:"varint,8,opt,name=is_active,json=isActive,proto3" json:"is_active,omitempty"`
+	RateLimitPerMinute     *int32                 `protobuf:"varint,9,opt,name=rate_limit_per_minute,json=rateLimitPerMinute,proto3,oneof" json:"rate_limit_per_minute,omitempty"`
+	RateLimitBurst         *int32                 `protobuf:"varint,10,opt,name=rate_limit_burst,json=rateLimitBurst,proto3,oneof" json:"rate_limit_burst,omitempty"`
+	RateLimitWindowSeconds *int32                 `protobuf:"varint,11,opt,name=rate_limit_window_seconds,json=rateLimitWindowSeconds,proto3,oneof" json:"rate_limit_window_seconds,omitempty"`
+	QuotaType              string                 `protobuf:"bytes,12,opt,name=quota_type,json=quotaType,proto3" json:"quota_type,omitempty"`
+	TotalQuota             *int32                 `protobuf:"varint,13,opt,name=total_quota,json=totalQuota,proto3,oneof" json:"total_quota,omitempty"`
+	RemainingQuota         *int32                 `protobuf:"varint,14,opt,name=remaining_quota,json=remainingQuota,proto3,oneof" json:"remaining_quota,omitempty"`
+	QuotaSyncedAt          *string                `protobuf:"bytes,15,opt,name=quota_synced_at,json=quotaSyncedAt,proto3,oneof" json:"quota_synced_at,omitempty"`
+	UpdatedAt              string                 `protobuf:"bytes,16,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	PolicyName             *string                `protobuf:"bytes,17,opt,name=policy_name,json=policyName,proto3,oneof" json:"policy_name,omitempty"`
+	// 内联沙箱策略（JSON，结构与 HTTP 接口的 policy 字段一致）
+	PolicyJson    []byte `protobuf:"bytes,18,opt,name=policy_json,json=policyJson,proto3" json:"policy_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TokenInfo) Reset() {
+	*x = TokenInfo{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenInfo) ProtoMessage() {}
+
+func (x *TokenInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenInfo.ProtoReflect.Descriptor instead.
+func (*TokenInfo) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TokenInfo) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *TokenInfo) GetWsId() string {
+	if x != nil {
+		return x.WsId
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetAccessToken() string {
+	if x != nil {
+		return x.AccessToken
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetExpiresAt() string {
+	if x != nil && x.ExpiresAt != nil {
+		return *x.ExpiresAt
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetOperationType() string {
+	if x != nil {
+		return x.OperationType
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetIsActive() bool {
+	if x != nil {
+		return x.IsActive
+	}
+	return false
+}
+
+func (x *TokenInfo) GetRateLimitPerMinute() int32 {
+	if x != nil && x.RateLimitPerMinute != nil {
+		return *x.RateLimitPerMinute
+	}
+	return 0
+}
+
+func (x *TokenInfo) GetRateLimitBurst() int32 {
+	if x != nil && x.RateLimitBurst != nil {
+		return *x.RateLimitBurst
+	}
+	return 0
+}
+
+func (x *TokenInfo) GetRateLimitWindowSeconds() int32 {
+	if x != nil && x.RateLimitWindowSeconds != nil {
+		return *x.RateLimitWindowSeconds
+	}
+	return 0
+}
+
+func (x *TokenInfo) GetQuotaType() string {
+	if x != nil {
+		return x.QuotaType
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetTotalQuota() int32 {
+	if x != nil && x.TotalQuota != nil {
+		return *x.TotalQuota
+	}
+	return 0
+}
+
+func (x *TokenInfo) GetRemainingQuota() int32 {
+	if x != nil && x.RemainingQuota != nil {
+		return *x.RemainingQuota
+	}
+	return 0
+}
+
+func (x *TokenInfo) GetQuotaSyncedAt() string {
+	if x != nil && x.QuotaSyncedAt != nil {
+		return *x.QuotaSyncedAt
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetUpdatedAt() string {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetPolicyName() string {
+	if x != nil && x.PolicyName != nil {
+		return *x.PolicyName
+	}
+	return ""
+}
+
+func (x *TokenInfo) GetPolicyJson() []byte {
+	if x != nil {
+		return x.PolicyJson
+	}
+	return nil
+}
+
+type CreateTokenRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	WsId  string                 `protobuf:"bytes,1,opt,name=ws_id,json=wsId,proto3" json:"ws_id,omitempty"`
+	Email string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	// add / set / unlimited
+	Operation              string `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`
+	Days                   *int32 `protobuf:"varint,4,opt,name=days,proto3,oneof" json:"days,omitempty"`
+	SpecificDate           string `protobuf:"bytes,5,opt,name=specific_date,json=specificDate,proto3" json:"specific_date,omitempty"`
+	RateLimitPerMinute     *int32 `protobuf:"varint,6,opt,name=rate_limit_per_minute,json=rateLimitPerMinute,proto3,oneof" json:"rate_limit_per_minute,omitempty"`
+	RateLimitBurst         *int32 `protobuf:"varint,7,opt,name=rate_limit_burst,json=rateLimitBurst,proto3,oneof" json:"rate_limit_burst,omitempty"`
+	RateLimitWindowSeconds *int32 `protobuf:"varint,8,opt,name=rate_limit_window_seconds,json=rateLimitWindowSeconds,proto3,oneof" json:"rate_limit_window_seconds,omitempty"`
+	// time / count / hybrid
+	QuotaType     string  `protobuf:"bytes,9,opt,name=quota_type,json=quotaType,proto3" json:"quota_type,omitempty"`
+	TotalQuota    *int32  `protobuf:"varint,10,opt,name=total_quota,json=totalQuota,proto3,oneof" json:"total_quota,omitempty"`
+	PolicyName    *string `protobuf:"bytes,11,opt,name=policy_name,json=policyName,proto3,oneof" json:"policy_name,omitempty"`
+	PolicyJson    []byte  `protobuf:"bytes,12,opt,name=policy_json,json=policyJson,proto3" json:"policy_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTokenRequest) Reset() {
+	*x = CreateTokenRequest{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTokenRequest) ProtoMessage() {}
+
+func (x *CreateTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTokenRequest.ProtoReflect.Descriptor instead.
+func (*CreateTokenRequest) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateTokenRequest) GetWsId() string {
+	if x != nil {
+		return x.WsId
+	}
+	return ""
+}
+
+func (x *CreateTokenRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *CreateTokenRequest) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *CreateTokenRequest) GetDays() int32 {
+	if x != nil && x.Days != nil {
+		return *x.Days
+	}
+	return 0
+}
+
+func (x *CreateTokenRequest) GetSpecificDate() string {
+	if x != nil {
+		return x.SpecificDate
+	}
+	return ""
+}
+
+func (x *CreateTokenRequest) GetRateLimitPerMinute() int32 {
+	if x != nil && x.RateLimitPerMinute != nil {
+		return *x.RateLimitPerMinute
+	}
+	return 0
+}
+
+func (x *CreateTokenRequest) GetRateLimitBurst() int32 {
+	if x != nil && x.RateLimitBurst != nil {
+		return *x.RateLimitBurst
+	}
+	return 0
+}
+
+func (x *CreateTokenRequest) GetRateLimitWindowSeconds() int32 {
+	if x != nil && x.RateLimitWindowSeconds != nil {
+		return *x.RateLimitWindowSeconds
+	}
+	return 0
+}
+
+func (x *CreateTokenRequest) GetQuotaType() string {
+	if x != nil {
+		return x.QuotaType
+	}
+	return ""
+}
+
+func (x *CreateTokenRequest) GetTotalQuota() int32 {
+	if x != nil && x.TotalQuota != nil {
+		return *x.TotalQuota
+	}
+	return 0
+}
+
+func (x *CreateTokenRequest) GetPolicyName() string {
+	if x != nil && x.PolicyName != nil {
+		return *x.PolicyName
+	}
+	return ""
+}
+
+func (x *CreateTokenRequest) GetPolicyJson() []byte {
+	if x != nil {
+		return x.PolicyJson
+	}
+	return nil
+}
+
+type UpdateTokenRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Token string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// set / unlimited
+	Operation              string `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"`
+	SpecificDate           string `protobuf:"bytes,3,opt,name=specific_date,json=specificDate,proto3" json:"specific_date,omitempty"`
+	RateLimitPerMinute     *int32 `protobuf:"varint,4,opt,name=rate_limit_per_minute,json=rateLimitPerMinute,proto3,oneof" json:"rate_limit_per_minute,omitempty"`
+	RateLimitBurst         *int32 `protobuf:"varint,5,opt,name=rate_limit_burst,json=rateLimitBurst,proto3,oneof" json:"rate_limit_burst,omitempty"`
+	RateLimitWindowSeconds *int32 `protobuf:"varint,6,opt,name=rate_limit_window_seconds,json=rateLimitWindowSeconds,proto3,oneof" json:"rate_limit_window_seconds,omitempty"`
+	// add / set / reset
+	QuotaOperation string `protobuf:"bytes,7,opt,name=quota_operation,json=quotaOperation,proto3" json:"quota_operation,omitempty"`
+	QuotaAmount    *int32 `protobuf:"varint,8,opt,name=quota_amount,json=quotaAmount,proto3,oneof" json:"quota_amount,omitempty"`
+	// time / count / hybrid
+	QuotaType     string `protobuf:"bytes,9,opt,name=quota_type,json=quotaType,proto3" json:"quota_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateTokenRequest) Reset() {
+	*x = UpdateTokenRequest{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateTokenRequest) ProtoMessage() {}
+
+func (x *UpdateTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateTokenRequest.ProtoReflect.Descriptor instead.
+func (*UpdateTokenRequest) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UpdateTokenRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *UpdateTokenRequest) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *UpdateTokenRequest) GetSpecificDate() string {
+	if x != nil {
+		return x.SpecificDate
+	}
+	return ""
+}
+
+func (x *UpdateTokenRequest) GetRateLimitPerMinute() int32 {
+	if x != nil && x.RateLimitPerMinute != nil {
+		return *x.RateLimitPerMinute
+	}
+	return 0
+}
+
+func (x *UpdateTokenRequest) GetRateLimitBurst() int32 {
+	if x != nil && x.RateLimitBurst != nil {
+		return *x.RateLimitBurst
+	}
+	return 0
+}
+
+func (x *UpdateTokenRequest) GetRateLimitWindowSeconds() int32 {
+	if x != nil && x.RateLimitWindowSeconds != nil {
+		return *x.RateLimitWindowSeconds
+	}
+	return 0
+}
+
+func (x *UpdateTokenRequest) GetQuotaOperation() string {
+	if x != nil {
+		return x.QuotaOperation
+	}
+	return ""
+}
+
+func (x *UpdateTokenRequest) GetQuotaAmount() int32 {
+	if x != nil && x.QuotaAmount != nil {
+		return *x.QuotaAmount
+	}
+	return 0
+}
+
+func (x *UpdateTokenRequest) GetQuotaType() string {
+	if x != nil {
+		return x.QuotaType
+	}
+	return ""
+}
+
+type DeleteTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTokenRequest) Reset() {
+	*x = DeleteTokenRequest{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTokenRequest) ProtoMessage() {}
+
+func (x *DeleteTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTokenRequest.ProtoReflect.Descriptor instead.
+func (*DeleteTokenRequest) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteTokenRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type DeleteTokenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTokenResponse) Reset() {
+	*x = DeleteTokenResponse{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTokenResponse) ProtoMessage() {}
+
+func (x *DeleteTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTokenResponse.ProtoReflect.Descriptor instead.
+func (*DeleteTokenResponse) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{11}
+}
+
+type ListTokensRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WsId          string                 `protobuf:"bytes,1,opt,name=ws_id,json=wsId,proto3" json:"ws_id,omitempty"`
+	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Token         string                 `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTokensRequest) Reset() {
+	*x = ListTokensRequest{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTokensRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTokensRequest) ProtoMessage() {}
+
+func (x *ListTokensRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTokensRequest.ProtoReflect.Descriptor instead.
+func (*ListTokensRequest) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListTokensRequest) GetWsId() string {
+	if x != nil {
+		return x.WsId
+	}
+	return ""
+}
+
+func (x *ListTokensRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ListTokensRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type ListTokensResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tokens        []*TokenInfo           `protobuf:"bytes,1,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTokensResponse) Reset() {
+	*x = ListTokensResponse{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTokensResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTokensResponse) ProtoMessage() {}
+
+func (x *ListTokensResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTokensResponse.ProtoReflect.Descriptor instead.
+func (*ListTokensResponse) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListTokensResponse) GetTokens() []*TokenInfo {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+func (x *ListTokensResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type GetQuotaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQuotaRequest) Reset() {
+	*x = GetQuotaRequest{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuotaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuotaRequest) ProtoMessage() {}
+
+func (x *GetQuotaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuotaRequest.ProtoReflect.Descriptor instead.
+func (*GetQuotaRequest) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetQuotaRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// QuotaInfo time 类型 Token 只返回 quota_type 和 message
+type QuotaInfo struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	QuotaType      string                 `protobuf:"bytes,1,opt,name=quota_type,json=quotaType,proto3" json:"quota_type,omitempty"`
+	TotalQuota     int32                  `protobuf:"varint,2,opt,name=total_quota,json=totalQuota,proto3" json:"total_quota,omitempty"`
+	RemainingQuota int32                  `protobuf:"varint,3,opt,name=remaining_quota,json=remainingQuota,proto3" json:"remaining_quota,omitempty"`
+	ConsumedQuota  int32                  `protobuf:"varint,4,opt,name=consumed_quota,json=consumedQuota,proto3" json:"consumed_quota,omitempty"`
+	QuotaSyncedAt  *string                `protobuf:"bytes,5,opt,name=quota_synced_at,json=quotaSyncedAt,proto3,oneof" json:"quota_synced_at,omitempty"`
+	Message        string                 `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *QuotaInfo) Reset() {
+	*x = QuotaInfo{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaInfo) ProtoMessage() {}
+
+func (x *QuotaInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaInfo.ProtoReflect.Descriptor instead.
+func (*QuotaInfo) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *QuotaInfo) GetQuotaType() string {
+	if x != nil {
+		return x.QuotaType
+	}
+	return ""
+}
+
+func (x *QuotaInfo) GetTotalQuota() int32 {
+	if x != nil {
+		return x.TotalQuota
+	}
+	return 0
+}
+
+func (x *QuotaInfo) GetRemainingQuota() int32 {
+	if x != nil {
+		return x.RemainingQuota
+	}
+	return 0
+}
+
+func (x *QuotaInfo) GetConsumedQuota() int32 {
+	if x != nil {
+		return x.ConsumedQuota
+	}
+	return 0
+}
+
+func (x *QuotaInfo) GetQuotaSyncedAt() string {
+	if x != nil && x.QuotaSyncedAt != nil {
+		return *x.QuotaSyncedAt
+	}
+	return ""
+}
+
+func (x *QuotaInfo) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetQuotaLogsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Token string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// yyyy-MM-dd
+	StartDate     string `protobuf:"bytes,2,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	EndDate       string `protobuf:"bytes,3,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	Page          int32  `protobuf:"varint,4,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize      int32  `protobuf:"varint,5,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQuotaLogsRequest) Reset() {
+	*x = GetQuotaLogsRequest{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuotaLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuotaLogsRequest) ProtoMessage() {}
+
+func (x *GetQuotaLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuotaLogsRequest.ProtoReflect.Descriptor instead.
+func (*GetQuotaLogsRequest) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetQuotaLogsRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *GetQuotaLogsRequest) GetStartDate() string {
+	if x != nil {
+		return x.StartDate
+	}
+	return ""
+}
+
+func (x *GetQuotaLogsRequest) GetEndDate() string {
+	if x != nil {
+		return x.EndDate
+	}
+	return ""
+}
+
+func (x *GetQuotaLogsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *GetQuotaLogsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type GetQuotaLogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Logs          []*QuotaLog            `protobuf:"bytes,1,rep,name=logs,proto3" json:"logs,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	Page          int32                  `protobuf:"varint,3,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize      int32                  `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	TotalPages    int32                  `protobuf:"varint,5,opt,name=total_pages,json=totalPages,proto3" json:"total_pages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQuotaLogsResponse) Reset() {
+	*x = GetQuotaLogsResponse{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuotaLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuotaLogsResponse) ProtoMessage() {}
+
+func (x *GetQuotaLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuotaLogsResponse.ProtoReflect.Descriptor instead.
+func (*GetQuotaLogsResponse) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetQuotaLogsResponse) GetLogs() []*QuotaLog {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+func (x *GetQuotaLogsResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *GetQuotaLogsResponse) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *GetQuotaLogsResponse) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *GetQuotaLogsResponse) GetTotalPages() int32 {
+	if x != nil {
+		return x.TotalPages
+	}
+	return 0
+}
+
+type QuotaLog struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Token       string                 `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	WsId        string                 `protobuf:"bytes,3,opt,name=ws_id,json=wsId,proto3" json:"ws_id,omitempty"`
+	Email       string                 `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
+	QuotaBefore int32                  `protobuf:"varint,5,opt,name=quota_before,json=quotaBefore,proto3" json:"quota_before,omitempty"`
+	QuotaAfter  int32                  `protobuf:"varint,6,opt,name=quota_after,json=quotaAfter,proto3" json:"quota_after,omitempty"`
+	QuotaChange int32                  `protobuf:"varint,7,opt,name=quota_change,json=quotaChange,proto3" json:"quota_change,omitempty"`
+	// consume / recharge / init
+	Action                string  `protobuf:"bytes,8,opt,name=action,proto3" json:"action,omitempty"`
+	RequestId             *string `protobuf:"bytes,9,opt,name=request_id,json=requestId,proto3,oneof" json:"request_id,omitempty"`
+	ExecutionSuccess      *bool   `protobuf:"varint,10,opt,name=execution_success,json=executionSuccess,proto3,oneof" json:"execution_success,omitempty"`
+	ExecutionErrorType    *string `protobuf:"bytes,11,opt,name=execution_error_type,json=executionErrorType,proto3,oneof" json:"execution_error_type,omitempty"`
+	ExecutionErrorMessage *string `protobuf:"bytes,12,opt,name=execution_error_message,json=executionErrorMessage,proto3,oneof" json:"execution_error_message,omitempty"`
+	CreatedAt             string  `protobuf:"bytes,13,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *QuotaLog) Reset() {
+	*x = QuotaLog{}
+	mi := &file_api_flowpb_flow_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaLog) ProtoMessage() {}
+
+func (x *QuotaLog) ProtoReflect() protoreflect.Message {
+	mi := &file_api_flowpb_flow_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaLog.ProtoReflect.Descriptor instead.
+func (*QuotaLog) Descriptor() ([]byte, []int) {
+	return file_api_flowpb_flow_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *QuotaLog) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *QuotaLog) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *QuotaLog) GetWsId() string {
+	if x != nil {
+		return x.WsId
+	}
+	return ""
+}
+
+func (x *QuotaLog) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *QuotaLog) GetQuotaBefore() int32 {
+	if x != nil {
+		return x.QuotaBefore
+	}
+	return 0
+}
+
+func (x *QuotaLog) GetQuotaAfter() int32 {
+	if x != nil {
+		return x.QuotaAfter
+	}
+	return 0
+}
+
+func (x *QuotaLog) GetQuotaChange() int32 {
+	if x != nil {
+		return x.QuotaChange
+	}
+	return 0
+}
+
+func (x *QuotaLog) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *QuotaLog) GetRequestId() string {
+	if x != nil && x.RequestId != nil {
+		return *x.RequestId
+	}
+	return ""
+}
+
+func (x *QuotaLog) GetExecutionSuccess() bool {
+	if x != nil && x.ExecutionSuccess != nil {
+		return *x.ExecutionSuccess
+	}
+	return false
+}
+
+func (x *QuotaLog) GetExecutionErrorType() string {
+	if x != nil && x.ExecutionErrorType != nil {
+		return *x.ExecutionErrorType
+	}
+	return ""
+}
+
+func (x *QuotaLog) GetExecutionErrorMessage() string {
+	if x != nil && x.ExecutionErrorMessage != nil {
+		return *x.ExecutionErrorMessage
+	}
+	return ""
+}
+
+func (x *QuotaLog) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+var File_api_flowpb_flow_proto protoreflect.FileDescriptor
+
+const file_api_flowpb_flow_proto_rawDesc = "" +
+	"\n" +
+	"\x15api/flowpb/flow.proto\x12\aflow.v1\"Y\n" +
+	"\x0eExecuteRequest\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x1d\n" +
+	"\n" +
+	"input_json\x18\x02 \x01(\fR\tinputJson\x12\x14\n" +
+	"\x05debug\x18\x03 \x01(\bR\x05debug\"\xb6\x02\n" +
+	"\x0fExecuteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1f\n" +
+	"\vresult_json\x18\x02 \x01(\fR\n" +
+	"resultJson\x12+\n" +
+	"\x05error\x18\x03 \x01(\v2\x15.flow.v1.ExecuteErrorR\x05error\x12.\n" +
+	"\x06timing\x18\x04 \x01(\v2\x16.flow.v1.ExecuteTimingR\x06timing\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\tR\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x06 \x01(\tR\trequestId\x12'\n" +
+	"\x04logs\x18\a \x03(\v2\x13.flow.v1.ConsoleLogR\x04logs\x12%\n" +
+	"\x0elogs_truncated\x18\b \x01(\bR\rlogsTruncated\"s\n" +
+	"\fExecuteError\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x14\n" +
+	"\x05stack\x18\x03 \x01(\tR\x05stack\x12\x1f\n" +
+	"\vretry_after\x18\x04 \x01(\x05R\n" +
+	"retryAfter\"\x90\x01\n" +
+	"\rExecuteTiming\x12*\n" +
+	"\x11execution_time_ms\x18\x01 \x01(\x03R\x0fexecutionTimeMs\x12\"\n" +
+	"\rtotal_time_ms\x18\x02 \x01(\x03R\vtotalTimeMs\x12/\n" +
+	"\x06phases\x18\x03 \x03(\v2\x17.flow.v1.ExecutionPhaseR\x06phases\"]\n" +
+	"\x0eExecutionPhase\x12\x14\n" +
+	"\x05phase\x18\x01 \x01(\tR\x05phase\x12\x1f\n" +
+	"\vduration_ms\x18\x02 \x01(\x01R\n" +
+	"durationMs\x12\x14\n" +
+	"\x05cache\x18\x03 \x01(\tR\x05cache\"w\n" +
+	"\n" +
+	"ConsoleLog\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\tR\x05level\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\tR\ttimestamp\x12\x1b\n" +
+	"\toffset_ms\x18\x04 \x01(\x03R\boffsetMs\"t\n" +
+	"\fExecuteEvent\x12'\n" +
+	"\x03log\x18\x01 \x01(\v2\x13.flow.v1.ConsoleLogH\x00R\x03log\x122\n" +
+	"\x06result\x18\x02 \x01(\v2\x18.flow.v1.ExecuteResponseH\x00R\x06resultB\a\n" +
+	"\x05event\"\xc1\x06\n" +
+	"\tTokenInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x13\n" +
+	"\x05ws_id\x18\x02 \x01(\tR\x04wsId\x12\x14\n" +
+	"\x05email\x18\x03 \x01(\tR\x05email\x12!\n" +
+	"\faccess_token\x18\x04 \x01(\tR\vaccessToken\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\tR\tcreatedAt\x12\"\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\tH\x00R\texpiresAt\x88\x01\x01\x12%\n" +
+	"\x0eoperation_type\x18\a \x01(\tR\roperationType\x12\x1b\n" +
+	"\tis_active\x18\b \x01(\bR\bisActive\x126\n" +
+	"\x15rate_limit_per_minute\x18\t \x01(\x05H\x01R\x12rateLimitPerMinute\x88\x01\x01\x12-\n" +
+	"\x10rate_limit_burst\x18\n" +
+	" \x01(\x05H\x02R\x0erateLimitBurst\x88\x01\x01\x12>\n" +
+	"\x19rate_limit_window_seconds\x18\v \x01(\x05H\x03R\x16rateLimitWindowSeconds\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"quota_type\x18\f \x01(\tR\tquotaType\x12$\n" +
+	"\vtotal_quota\x18\r \x01(\x05H\x04R\n" +
+	"totalQuota\x88\x01\x01\x12,\n" +
+	"\x0fremaining_quota\x18\x0e \x01(\x05H\x05R\x0eremainingQuota\x88\x01\x01\x12+\n" +
+	"\x0fquota_synced_at\x18\x0f \x01(\tH\x06R\rquotaSyncedAt\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x10 \x01(\tR\tupdatedAt\x12$\n" +
+	"\vpolicy_name\x18\x11 \x01(\tH\aR\n" +
+	"policyName\x88\x01\x01\x12\x1f\n" +
+	"\vpolicy_json\x18\x12 \x01(\fR\n" +
+	"policyJsonB\r\n" +
+	"\v_expires_atB\x18\n" +
+	"\x16_rate_limit_per_minuteB\x13\n" +
+	"\x11_rate_limit_burstB\x1c\n" +
+	"\x1a_rate_limit_window_secondsB\x0e\n" +
+	"\f_total_quotaB\x12\n" +
+	"\x10_remaining_quotaB\x12\n" +
+	"\x10_quota_synced_atB\x0e\n" +
+	"\f_policy_name\"\xc4\x04\n" +
+	"\x12CreateTokenRequest\x12\x13\n" +
+	"\x05ws_id\x18\x01 \x01(\tR\x04wsId\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\x12\x17\n" +
+	"\x04days\x18\x04 \x01(\x05H\x00R\x04days\x88\x01\x01\x12#\n" +
+	"\rspecific_date\x18\x05 \x01(\tR\fspecificDate\x126\n" +
+	"\x15rate_limit_per_minute\x18\x06 \x01(\x05H\x01R\x12rateLimitPerMinute\x88\x01\x01\x12-\n" +
+	"\x10rate_limit_burst\x18\a \x01(\x05H\x02R\x0erateLimitBurst\x88\x01\x01\x12>\n" +
+	"\x19rate_limit_window_seconds\x18\b \x01(\x05H\x03R\x16rateLimitWindowSeconds\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"quota_type\x18\t \x01(\tR\tquotaType\x12$\n" +
+	"\vtotal_quota\x18\n" +
+	" \x01(\x05H\x04R\n" +
+	"totalQuota\x88\x01\x01\x12$\n" +
+	"\vpolicy_name\x18\v \x01(\tH\x05R\n" +
+	"policyName\x88\x01\x01\x12\x1f\n" +
+	"\vpolicy_json\x18\f \x01(\fR\n" +
+	"policyJsonB\a\n" +
+	"\x05_daysB\x18\n" +
+	"\x16_rate_limit_per_minuteB\x13\n" +
+	"\x11_rate_limit_burstB\x1c\n" +
+	"\x1a_rate_limit_window_secondsB\x0e\n" +
+	"\f_total_quotaB\x0e\n" +
+	"\f_policy_name\"\xe2\x03\n" +
+	"\x12UpdateTokenRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1c\n" +
+	"\toperation\x18\x02 \x01(\tR\toperation\x12#\n" +
+	"\rspecific_date\x18\x03 \x01(\tR\fspecificDate\x126\n" +
+	"\x15rate_limit_per_minute\x18\x04 \x01(\x05H\x00R\x12rateLimitPerMinute\x88\x01\x01\x12-\n" +
+	"\x10rate_limit_burst\x18\x05 \x01(\x05H\x01R\x0erateLimitBurst\x88\x01\x01\x12>\n" +
+	"\x19rate_limit_window_seconds\x18\x06 \x01(\x05H\x02R\x16rateLimitWindowSeconds\x88\x01\x01\x12'\n" +
+	"\x0fquota_operation\x18\a \x01(\tR\x0equotaOperation\x12&\n" +
+	"\fquota_amount\x18\b \x01(\x05H\x03R\vquotaAmount\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"quota_type\x18\t \x01(\tR\tquotaTypeB\x18\n" +
+	"\x16_rate_limit_per_minuteB\x13\n" +
+	"\x11_rate_limit_burstB\x1c\n" +
+	"\x1a_rate_limit_window_secondsB\x0f\n" +
+	"\r_quota_amount\"*\n" +
+	"\x12DeleteTokenRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"\x15\n" +
+	"\x13DeleteTokenResponse\"T\n" +
+	"\x11ListTokensRequest\x12\x13\n" +
+	"\x05ws_id\x18\x01 \x01(\tR\x04wsId\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\"V\n" +
+	"\x12ListTokensResponse\x12*\n" +
+	"\x06tokens\x18\x01 \x03(\v2\x12.flow.v1.TokenInfoR\x06tokens\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\"'\n" +
+	"\x0fGetQuotaRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"\xf6\x01\n" +
+	"\tQuotaInfo\x12\x1d\n" +
+	"\n" +
+	"quota_type\x18\x01 \x01(\tR\tquotaType\x12\x1f\n" +
+	"\vtotal_quota\x18\x02 \x01(\x05R\n" +
+	"totalQuota\x12'\n" +
+	"\x0fremaining_quota\x18\x03 \x01(\x05R\x0eremainingQuota\x12%\n" +
+	"\x0econsumed_quota\x18\x04 \x01(\x05R\rconsumedQuota\x12+\n" +
+	"\x0fquota_synced_at\x18\x05 \x01(\tH\x00R\rquotaSyncedAt\x88\x01\x01\x12\x18\n" +
+	"\amessage\x18\x06 \x01(\tR\amessageB\x12\n" +
+	"\x10_quota_synced_at\"\x96\x01\n" +
+	"\x13GetQuotaLogsRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1d\n" +
+	"\n" +
+	"start_date\x18\x02 \x01(\tR\tstartDate\x12\x19\n" +
+	"\bend_date\x18\x03 \x01(\tR\aendDate\x12\x12\n" +
+	"\x04page\x18\x04 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x05 \x01(\x05R\bpageSize\"\xa5\x01\n" +
+	"\x14GetQuotaLogsResponse\x12%\n" +
+	"\x04logs\x18\x01 \x03(\v2\x11.flow.v1.QuotaLogR\x04logs\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\x12\x12\n" +
+	"\x04page\x18\x03 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\x05R\bpageSize\x12\x1f\n" +
+	"\vtotal_pages\x18\x05 \x01(\x05R\n" +
+	"totalPages\"\x9d\x04\n" +
+	"\bQuotaLog\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05token\x18\x02 \x01(\tR\x05token\x12\x13\n" +
+	"\x05ws_id\x18\x03 \x01(\tR\x04wsId\x12\x14\n" +
+	"\x05email\x18\x04 \x01(\tR\x05email\x12!\n" +
+	"\fquota_before\x18\x05 \x01(\x05R\vquotaBefore\x12\x1f\n" +
+	"\vquota_after\x18\x06 \x01(\x05R\n" +
+	"quotaAfter\x12!\n" +
+	"\fquota_change\x18\a \x01(\x05R\vquotaChange\x12\x16\n" +
+	"\x06action\x18\b \x01(\tR\x06action\x12\"\n" +
+	"\n" +
+	"request_id\x18\t \x01(\tH\x00R\trequestId\x88\x01\x01\x120\n" +
+	"\x11execution_success\x18\n" +
+	" \x01(\bH\x01R\x10executionSuccess\x88\x01\x01\x125\n" +
+	"\x14execution_error_type\x18\v \x01(\tH\x02R\x12executionErrorType\x88\x01\x01\x12;\n" +
+	"\x17execution_error_message\x18\f \x01(\tH\x03R\x15executionErrorMessage\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\r \x01(\tR\tcreatedAtB\r\n" +
+	"\v_request_idB\x14\n" +
+	"\x12_execution_successB\x17\n" +
+	"\x15_execution_error_typeB\x1a\n" +
+	"\x18_execution_error_message2\x8e\x01\n" +
+	"\vFlowService\x12<\n" +
+	"\aExecute\x12\x17.flow.v1.ExecuteRequest\x1a\x18.flow.v1.ExecuteResponse\x12A\n" +
+	"\rExecuteStream\x12\x17.flow.v1.ExecuteRequest\x1a\x15.flow.v1.ExecuteEvent0\x012\xab\x03\n" +
+	"\x11TokenAdminService\x12>\n" +
+	"\vCreateToken\x12\x1b.flow.v1.CreateTokenRequest\x1a\x12.flow.v1.TokenInfo\x12>\n" +
+	"\vUpdateToken\x12\x1b.flow.v1.UpdateTokenRequest\x1a\x12.flow.v1.TokenInfo\x12H\n" +
+	"\vDeleteToken\x12\x1b.flow.v1.DeleteTokenRequest\x1a\x1c.flow.v1.DeleteTokenResponse\x12E\n" +
+	"\n" +
+	"ListTokens\x12\x1a.flow.v1.ListTokensRequest\x1a\x1b.flow.v1.ListTokensResponse\x128\n" +
+	"\bGetQuota\x12\x18.flow.v1.GetQuotaRequest\x1a\x12.flow.v1.QuotaInfo\x12K\n" +
+	"\fGetQuotaLogs\x12\x1c.flow.v1.GetQuotaLogsRequest\x1a\x1d.flow.v1.GetQuotaLogsResponseB%Z#flow-codeblock-go/api/flowpb;flowpbb\x06proto3"
+
+var (
+	file_api_flowpb_flow_proto_rawDescOnce sync.Once
+	file_api_flowpb_flow_proto_rawDescData []byte
+)
+
+func file_api_flowpb_flow_proto_rawDescGZIP() []byte {
+	file_api_flowpb_flow_proto_rawDescOnce.Do(func() {
+		file_api_flowpb_flow_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_flowpb_flow_proto_rawDesc), len(file_api_flowpb_flow_proto_rawDesc)))
+	})
+	return file_api_flowpb_flow_proto_rawDescData
+}
+
+var file_api_flowpb_flow_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_api_flowpb_flow_proto_goTypes = []any{
+	(*ExecuteRequest)(nil),       // 0: flow.v1.ExecuteRequest
+	(*ExecuteResponse)(nil),      // 1: flow.v1.ExecuteResponse
+	(*ExecuteError)(nil),         // 2: flow.v1.ExecuteError
+	(*ExecuteTiming)(nil),        // 3: flow.v1.ExecuteTiming
+	(*ExecutionPhase)(nil),       // 4: flow.v1.ExecutionPhase
+	(*ConsoleLog)(nil),           // 5: flow.v1.ConsoleLog
+	(*ExecuteEvent)(nil),         // 6: flow.v1.ExecuteEvent
+	(*TokenInfo)(nil),            // 7: flow.v1.TokenInfo
+	(*CreateTokenRequest)(nil),   // 8: flow.v1.CreateTokenRequest
+	(*UpdateTokenRequest)(nil),   // 9: flow.v1.UpdateTokenRequest
+	(*DeleteTokenRequest)(nil),   // 10: flow.v1.DeleteTokenRequest
+	(*DeleteTokenResponse)(nil),  // 11: flow.v1.DeleteTokenResponse
+	(*ListTokensRequest)(nil),    // 12: flow.v1.ListTokensRequest
+	(*ListTokensResponse)(nil),   // 13: flow.v1.ListTokensResponse
+	(*GetQuotaRequest)(nil),      // 14: flow.v1.GetQuotaRequest
+	(*QuotaInfo)(nil),            // 15: flow.v1.QuotaInfo
+	(*GetQuotaLogsRequest)(nil),  // 16: flow.v1.GetQuotaLogsRequest
+	(*GetQuotaLogsResponse)(nil), // 17: flow.v1.GetQuotaLogsResponse
+	(*QuotaLog)(nil),             // 18: flow.v1.QuotaLog
+}
+var file_api_flowpb_flow_proto_depIdxs = []int32{
+	2,  // 0: flow.v1.ExecuteResponse.error:type_name -> flow.v1.ExecuteError
+	3,  // 1: flow.v1.ExecuteResponse.timing:type_name -> flow.v1.ExecuteTiming
+	5,  // 2: flow.v1.ExecuteResponse.logs:type_name -> flow.v1.ConsoleLog
+	4,  // 3: flow.v1.ExecuteTiming.phases:type_name -> flow.v1.ExecutionPhase
+	5,  // 4: flow.v1.ExecuteEvent.log:type_name -> flow.v1.ConsoleLog
+	1,  // 5: flow.v1.ExecuteEvent.result:type_name -> flow.v1.ExecuteResponse
+	7,  // 6: flow.v1.ListTokensResponse.tokens:type_name -> flow.v1.TokenInfo
+	18, // 7: flow.v1.GetQuotaLogsResponse.logs:type_name -> flow.v1.QuotaLog
+	0,  // 8: flow.v1.FlowService.Execute:input_type -> flow.v1.ExecuteRequest
+	0,  // 9: flow.v1.FlowService.ExecuteStream:input_type -> flow.v1.ExecuteRequest
+	8,  // 10: flow.v1.TokenAdminService.CreateToken:input_type -> flow.v1.CreateTokenRequest
+	9,  // 11: flow.v1.TokenAdminService.UpdateToken:input_type -> flow.v1.UpdateTokenRequest
+	10, // 12: flow.v1.TokenAdminService.DeleteToken:input_type -> flow.v1.DeleteTokenRequest
+	12, // 13: flow.v1.TokenAdminService.ListTokens:input_type -> flow.v1.ListTokensRequest
+	14, // 14: flow.v1.TokenAdminService.GetQuota:input_type -> flow.v1.GetQuotaRequest
+	16, // 15: flow.v1.TokenAdminService.GetQuotaLogs:input_type -> flow.v1.GetQuotaLogsRequest
+	1,  // 16: flow.v1.FlowService.Execute:output_type -> flow.v1.ExecuteResponse
+	6,  // 17: flow.v1.FlowService.ExecuteStream:output_type -> flow.v1.ExecuteEvent
+	7,  // 18: flow.v1.TokenAdminService.CreateToken:output_type -> flow.v1.TokenInfo
+	7,  // 19: flow.v1.TokenAdminService.UpdateToken:output_type -> flow.v1.TokenInfo
+	11, // 20: flow.v1.TokenAdminService.DeleteToken:output_type -> flow.v1.DeleteTokenResponse
+	13, // 21: flow.v1.TokenAdminService.ListTokens:output_type -> flow.v1.ListTokensResponse
+	15, // 22: flow.v1.TokenAdminService.GetQuota:output_type -> flow.v1.QuotaInfo
+	17, // 23: flow.v1.TokenAdminService.GetQuotaLogs:output_type -> flow.v1.GetQuotaLogsResponse
+	16, // [16:24] is the sub-list for method output_type
+	8,  // [8:16] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_api_flowpb_flow_proto_init() }
+func file_api_flowpb_flow_proto_init() {
+	if File_api_flowpb_flow_proto != nil {
+		return
+	}
+	file_api_flowpb_flow_proto_msgTypes[6].OneofWrappers = []any{
+		(*ExecuteEvent_Log)(nil),
+		(*ExecuteEvent_Result)(nil),
+	}
+	file_api_flowpb_flow_proto_msgTypes[7].OneofWrappers = []any{}
+	file_api_flowpb_flow_proto_msgTypes[8].OneofWrappers = []any{}
+	file_api_flowpb_flow_proto_msgTypes[9].OneofWrappers = []any{}
+	file_api_flowpb_flow_proto_msgTypes[15].OneofWrappers = []any{}
+	file_api_flowpb_flow_proto_msgTypes[18].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_flowpb_flow_proto_rawDesc), len(file_api_flowpb_flow_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_api_flowpb_flow_proto_goTypes,
+		DependencyIndexes: file_api_flowpb_flow_proto_depIdxs,
+		MessageInfos:      file_api_flowpb_flow_proto_msgTypes,
+	}.Build()
+	File_api_flowpb_flow_proto = out.File
+	file_api_flowpb_flow_proto_goTypes = nil
+	file_api_flowpb_flow_proto_depIdxs = nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+)
+
+// sandboxOptions 执行器配置：在 sandbox.DefaultConfig() 上应用环境变量和命令行参数
+//
+// 只读取本地运行相关的环境变量（名称和含义与服务端一致），不经过服务端的 config 包：
+//   - EXECUTION_TIMEOUT_MS（-timeout 优先）
+//   - CONSOLE_MODE：默认 capture，console 输出随响应的 logs 返回
+//   - MAX_CODE_LENGTH / MAX_INPUT_SIZE / MAX_RESULT_SIZE
+//   - ENABLE_JS_MEMORY_LIMIT / JS_MEMORY_LIMIT_MB
+//   - ENABLE_SSRF_PROTECTION / ALLOW_PRIVATE_IP：默认开启 SSRF 防护、禁止内网地址
+func sandboxOptions(opts *options) ([]sandbox.Option, error) {
+	defaults := sandbox.DefaultConfig()
+	ex := defaults.Executor
+
+	timeoutMS, err := envInt("EXECUTION_TIMEOUT_MS", int(ex.ExecutionTimeout/time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(timeoutMS) * time.Millisecond
+	if opts.timeout > 0 {
+		timeout = opts.timeout
+	}
+
+	maxCodeLength, err := envInt("MAX_CODE_LENGTH", ex.MaxCodeLength)
+	if err != nil {
+		return nil, err
+	}
+	maxInputSize, err := envInt("MAX_INPUT_SIZE", ex.MaxInputSize)
+	if err != nil {
+		return nil, err
+	}
+	maxResultSize, err := envInt("MAX_RESULT_SIZE", ex.MaxResultSize)
+	if err != nil {
+		return nil, err
+	}
+
+	enableSSRF, err := envBool("ENABLE_SSRF_PROTECTION", defaults.Fetch.EnableSSRFProtection)
+	if err != nil {
+		return nil, err
+	}
+	allowPrivateIP, err := envBool("ALLOW_PRIVATE_IP", defaults.Fetch.AllowPrivateIP)
+	if err != nil {
+		return nil, err
+	}
+
+	sandboxOpts := []sandbox.Option{
+		// 本地只运行一个脚本，不需要预热整个 Runtime 池和 EventLoop 池
+		sandbox.WithPool(1, 1, ex.MaxPoolSize),
+		sandbox.WithEventLoopPool(1, 1, ex.MaxEventLoopPoolSize),
+		sandbox.WithTimeout(timeout),
+		sandbox.WithLimits(maxCodeLength, maxInputSize, maxResultSize),
+		sandbox.WithConsoleMode(strings.ToLower(envString("CONSOLE_MODE", sandbox.ConsoleModeCapture))),
+		sandbox.WithSSRFProtection(enableSSRF, allowPrivateIP),
+	}
+
+	// JS 内存限制：默认沿用执行器的默认值（按 MaxBlobFileSize 限制）
+	enableMemoryLimit, err := envBool("ENABLE_JS_MEMORY_LIMIT", ex.EnableJSMemoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	memoryLimitMB, err := envInt("JS_MEMORY_LIMIT_MB", 0)
+	if err != nil {
+		return nil, err
+	}
+	if !enableMemoryLimit {
+		sandboxOpts = append(sandboxOpts, sandbox.WithMemoryLimit(0))
+	} else if memoryLimitMB > 0 {
+		sandboxOpts = append(sandboxOpts, sandbox.WithMemoryLimit(int64(memoryLimitMB)))
+	}
+
+	if utils.Logger != nil {
+		// 全局日志跳过了 utils.Info 等包装函数，执行器直接调用 logger，需要还原调用位置
+		sandboxOpts = append(sandboxOpts, sandbox.WithLogger(utils.Logger.WithOptions(zap.AddCallerSkip(-1))))
+	}
+	return sandboxOpts, nil
+}
+
+// envString 读取字符串环境变量
+func envString(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// envInt 读取整数环境变量（与服务端不同，解析失败时返回错误而不是使用默认值）
+func envInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s 必须是整数，当前值: %s", key, value)
+	}
+	return n, nil
+}
+
+// envBool 读取布尔环境变量（true / false / 1 / 0）
+func envBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s 必须是 true 或 false，当前值: %s", key, value)
+	}
+	return b, nil
+}
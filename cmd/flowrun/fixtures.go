@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// fixture 一条录制的 fetch / axios 请求和响应
+//
+// 录制文件是 fixture 数组：
+//
+//	[
+//	  {"method": "GET", "url": "https://api.example.com/users/1", "status": 200,
+//	   "headers": {"Content-Type": "application/json"}, "body": {"id": 1}},
+//	  {"url": "https://api.example.com/search*", "body": "plain text"}
+//	]
+//
+// method 为空时匹配任意方法；url 以 * 结尾时按前缀匹配（含查询参数）；
+// body 为 JSON 字符串时按原文返回，其他 JSON 值序列化后返回
+type fixture struct {
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url"`
+	Status  int               `json:"status,omitempty"` // 默认 200
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// matches 请求是否匹配
+func (f *fixture) matches(req *http.Request) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, req.Method) {
+		return false
+	}
+	url := req.URL.String()
+	if prefix, ok := strings.CutSuffix(f.URL, "*"); ok {
+		return strings.HasPrefix(url, prefix)
+	}
+	return f.URL == url
+}
+
+// bodyBytes 响应体原文
+func (f *fixture) bodyBytes() []byte {
+	if len(f.Body) == 0 {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(f.Body, &text); err == nil {
+		return []byte(text)
+	}
+	return f.Body
+}
+
+// fixtureReplayer 按录制文件返回响应，不发出真实请求（没有匹配的 fixture 时请求失败）
+//
+// 🔥 执行器的 Transport 只能在第一次执行前设置，watch 模式下通过 reload 替换录制内容
+type fixtureReplayer struct {
+	mu       sync.RWMutex
+	fixtures []*fixture
+}
+
+// loadFixtureReplayer 读取录制文件
+func loadFixtureReplayer(path string) (*fixtureReplayer, error) {
+	fixtures, err := readFixtures(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fixtureReplayer{fixtures: fixtures}, nil
+}
+
+// reload 重新读取录制文件（读取失败时保留原来的录制内容）
+func (r *fixtureReplayer) reload(path string) error {
+	fixtures, err := readFixtures(path)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.fixtures = fixtures
+	r.mu.Unlock()
+	return nil
+}
+
+// readFixtures 读取并校验录制文件
+func readFixtures(path string) ([]*fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取录制文件失败: %w", err)
+	}
+	var fixtures []*fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("录制文件必须是 fixture 数组: %w", err)
+	}
+	for i, f := range fixtures {
+		if f == nil || f.URL == "" {
+			return nil, fmt.Errorf("录制文件第 %d 条缺少 url", i)
+		}
+	}
+	return fixtures, nil
+}
+
+// RoundTrip 实现 http.RoundTripper（按文件中的顺序取第一条匹配的 fixture）
+func (r *fixtureReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	r.mu.RLock()
+	fixtures := r.fixtures
+	r.mu.RUnlock()
+	for _, f := range fixtures {
+		if !f.matches(req) {
+			continue
+		}
+		status := f.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		header := make(http.Header, len(f.Headers))
+		for key, value := range f.Headers {
+			header.Set(key, value)
+		}
+		body := f.bodyBytes()
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("flowrun: 录制文件中没有匹配的响应: %s %s", req.Method, req.URL.String())
+}
+
+// fixtureRecorder 发出真实请求并记录响应（同一 method + url 只保留最后一次）
+type fixtureRecorder struct {
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	fixtures []*fixture
+	index    map[string]int
+}
+
+// newFixtureRecorder 创建录制器（next 为真实的 Transport）
+func newFixtureRecorder(next http.RoundTripper) *fixtureRecorder {
+	return &fixtureRecorder{next: next, index: make(map[string]int)}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (r *fixtureRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	f := &fixture{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string, len(resp.Header)),
+	}
+	for key := range resp.Header {
+		if key != "Content-Length" {
+			f.Headers[key] = resp.Header.Get(key)
+		}
+	}
+	// JSON 字符串按原文保存（回放时 JSON 字符串表示原文）
+	if trimmed := bytes.TrimSpace(body); json.Valid(trimmed) && trimmed[0] != '"' {
+		f.Body = json.RawMessage(trimmed)
+	} else if len(body) > 0 {
+		f.Body, _ = json.Marshal(string(body))
+	}
+
+	key := f.Method + " " + f.URL
+	r.mu.Lock()
+	if i, ok := r.index[key]; ok {
+		r.fixtures[i] = f
+	} else {
+		r.index[key] = len(r.fixtures)
+		r.fixtures = append(r.fixtures, f)
+	}
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// save 写入录制文件（覆盖）
+func (r *fixtureRecorder) save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fixtures := r.fixtures
+	if fixtures == nil {
+		fixtures = []*fixture{}
+	}
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化录制文件失败: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("写入录制文件失败: %w", err)
+	}
+	return nil
+}
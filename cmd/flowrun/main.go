@@ -0,0 +1,272 @@
+// flowrun 本地运行脚本的命令行工具
+//
+// 使用与服务端完全相同的 JSExecutor 沙箱（模块、超时、内存限制、SSRF 防护），
+// 只依赖 pkg/sandbox（不链接服务端的 config、MySQL、Redis 和 HTTP 服务），方便在本地开发和单测脚本：
+//
+//	flowrun -input input.json script.js
+//	flowrun -input input.json -fixtures fetch.json -watch script.js
+//	flowrun -fixtures fetch.json -record script.js   # 真实请求并录制响应
+//
+// 输出与 POST /flow/codeblock 相同的 ExecuteResponse JSON；执行失败时退出码为 1，参数 / 配置错误为 2
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/utils"
+
+	"github.com/google/uuid"
+)
+
+// 退出码
+const (
+	exitOK      = 0
+	exitFailed  = 1 // 脚本执行失败
+	exitUsage   = 2 // 参数错误、文件读取失败、配置无效
+	watchPeriod = 500 * time.Millisecond
+)
+
+// options 命令行参数
+type options struct {
+	script   string
+	input    string
+	fixtures string
+	record   bool
+	timeout  time.Duration
+	watch    bool
+	debug    bool
+	verbose  bool
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var opts options
+	flag.StringVar(&opts.input, "input", "", "输入 JSON 文件（对应请求中的 input，默认 {}）")
+	flag.StringVar(&opts.fixtures, "fixtures", "", "fetch / axios 录制文件：回放其中的响应，不发出真实请求")
+	flag.BoolVar(&opts.record, "record", false, "与 -fixtures 一起使用：发出真实请求并把响应写入录制文件")
+	flag.DurationVar(&opts.timeout, "timeout", 0, "执行超时（如 5s，默认 EXECUTION_TIMEOUT_MS）")
+	flag.BoolVar(&opts.watch, "watch", false, "脚本、输入或录制文件变化时重新执行")
+	flag.BoolVar(&opts.debug, "debug", false, "在 timing.phases 中返回分阶段耗时")
+	flag.BoolVar(&opts.verbose, "verbose", false, "输出执行器 DEBUG 日志（stderr）")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: flowrun [选项] script.js\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return exitUsage
+	}
+	opts.script = flag.Arg(0)
+	if opts.record && opts.fixtures == "" {
+		fmt.Fprintln(os.Stderr, "flowrun: -record 需要同时指定 -fixtures")
+		return exitUsage
+	}
+
+	if err := utils.InitCLILogger(opts.verbose); err != nil {
+		fmt.Fprintf(os.Stderr, "flowrun: 初始化日志失败: %v\n", err)
+		return exitUsage
+	}
+	defer utils.Sync()
+
+	sandboxOpts, err := sandboxOptions(&opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flowrun: 配置无效: %v\n", err)
+		return exitUsage
+	}
+
+	executor, err := sandbox.New(sandboxOpts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flowrun: 配置无效: %v\n", err)
+		return exitUsage
+	}
+	defer executor.Shutdown()
+
+	// Transport 在第一次执行前设置，之后不再替换（watch 模式下由 replayer.reload 更新录制内容）
+	var recorder *fixtureRecorder
+	var replayer *fixtureReplayer
+	if opts.fixtures != "" {
+		if opts.record {
+			recorder = newFixtureRecorder(executor.GetFetchTransport())
+			executor.SetFetchTransport(recorder)
+		} else {
+			replayer, err = loadFixtureReplayer(opts.fixtures)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "flowrun: %v\n", err)
+				return exitUsage
+			}
+			executor.SetFetchTransport(replayer)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !opts.watch {
+		code := runOnce(ctx, executor, &opts)
+		if recorder != nil {
+			if err := recorder.save(opts.fixtures); err != nil {
+				fmt.Fprintf(os.Stderr, "flowrun: %v\n", err)
+				return exitUsage
+			}
+		}
+		return code
+	}
+
+	// watch 模式：轮询修改时间，变化后重新加载录制文件并执行
+	watched := []string{opts.script, opts.input}
+	if !opts.record {
+		watched = append(watched, opts.fixtures)
+	}
+	last := modTimes(watched)
+	runOnce(ctx, executor, &opts)
+	fmt.Fprintln(os.Stderr, "flowrun: 等待文件变化（Ctrl+C 退出）...")
+
+	ticker := time.NewTicker(watchPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if recorder != nil {
+				if err := recorder.save(opts.fixtures); err != nil {
+					fmt.Fprintf(os.Stderr, "flowrun: %v\n", err)
+				}
+			}
+			return exitOK
+		case <-ticker.C:
+		}
+
+		current := modTimes(watched)
+		if current == last {
+			continue
+		}
+		last = current
+
+		if replayer != nil {
+			if err := replayer.reload(opts.fixtures); err != nil {
+				fmt.Fprintf(os.Stderr, "flowrun: %v\n", err)
+				continue
+			}
+		}
+		fmt.Fprintf(os.Stderr, "\nflowrun: 文件已变化，重新执行 %s\n", opts.script)
+		runOnce(ctx, executor, &opts)
+		if recorder != nil {
+			if err := recorder.save(opts.fixtures); err != nil {
+				fmt.Fprintf(os.Stderr, "flowrun: %v\n", err)
+			}
+		}
+	}
+}
+
+// runOnce 读取脚本和输入并执行一次，把 ExecuteResponse 写到 stdout，返回退出码
+func runOnce(ctx context.Context, executor *sandbox.JSExecutor, opts *options) int {
+	startTime := time.Now()
+	requestID := uuid.New().String()
+
+	code, err := os.ReadFile(opts.script)
+	if err != nil {
+		return printResponse(failedResponse(requestID, startTime, "ValidationError", "读取脚本失败: "+err.Error()), exitUsage)
+	}
+	input := map[string]interface{}{}
+	if opts.input != "" {
+		data, err := os.ReadFile(opts.input)
+		if err != nil {
+			return printResponse(failedResponse(requestID, startTime, "ValidationError", "读取输入失败: "+err.Error()), exitUsage)
+		}
+		if err := json.Unmarshal(data, &input); err != nil {
+			return printResponse(failedResponse(requestID, startTime, "ValidationError", "输入必须是 JSON 对象: "+err.Error()), exitUsage)
+		}
+	}
+
+	timeline := executor.NewTimeline()
+	execCtx := context.WithValue(ctx, utils.RequestIDKey, requestID)
+	execCtx = sandbox.WithTimeline(execCtx, timeline)
+	result, err := executor.Execute(execCtx, string(code), input)
+	totalTime := time.Since(startTime).Milliseconds()
+
+	timing := &model.ExecuteTiming{ExecutionTime: totalTime, TotalTime: totalTime}
+	if opts.debug {
+		timing.Phases = timeline.Phases()
+	}
+
+	if err != nil {
+		resp := failedResponse(requestID, startTime, "RuntimeError", err.Error())
+		resp.Timing = timing
+		if execErr, ok := err.(*model.ExecutionError); ok {
+			resp.Error.Type = execErr.Type
+			resp.Error.Message = execErr.Message
+			resp.Error.Stack = execErr.Stack
+			resp.Error.RetryAfter = execErr.RetryAfterSeconds()
+			resp.Logs, resp.LogsTruncated = execErr.Logs, execErr.LogsTruncated
+		}
+		return printResponse(resp, exitFailed)
+	}
+
+	var value interface{} = result.Result
+	if len(result.JSONData) > 0 {
+		value = json.RawMessage(result.JSONData)
+	}
+	return printResponse(&model.ExecuteResponse{
+		Success:       true,
+		Result:        value,
+		Timing:        timing,
+		Timestamp:     utils.FormatTime(utils.Now()),
+		RequestID:     requestID,
+		Logs:          result.Logs,
+		LogsTruncated: result.LogsTruncated,
+	}, exitOK)
+}
+
+// failedResponse 构造失败响应（与 HTTP 接口的错误格式一致）
+func failedResponse(requestID string, startTime time.Time, errorType, message string) *model.ExecuteResponse {
+	return &model.ExecuteResponse{
+		Success: false,
+		Error: &model.ExecuteError{
+			Type:    errorType,
+			Message: message,
+		},
+		Timing: &model.ExecuteTiming{
+			TotalTime: time.Since(startTime).Milliseconds(),
+		},
+		Timestamp: utils.FormatTime(utils.Now()),
+		RequestID: requestID,
+	}
+}
+
+// printResponse 以缩进 JSON 输出响应，返回 exitCode
+func printResponse(resp *model.ExecuteResponse, exitCode int) int {
+	data, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flowrun: 序列化响应失败: %v\n", err)
+		return exitFailed
+	}
+	fmt.Println(string(data))
+	return exitCode
+}
+
+// modTimes 文件修改时间的指纹（文件不存在或路径为空时忽略）
+func modTimes(paths []string) string {
+	fingerprint := ""
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			fingerprint += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return fingerprint
+}
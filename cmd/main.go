@@ -12,10 +12,13 @@ import (
 
 	"flow-codeblock-go/config"
 	"flow-codeblock-go/controller"
+	"flow-codeblock-go/grpcserver"
+	"flow-codeblock-go/pkg/sandbox"
 	"flow-codeblock-go/repository"
 	"flow-codeblock-go/router"
 	"flow-codeblock-go/service"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/tracingutil"
 
 	"go.uber.org/zap"
 )
@@ -39,6 +42,21 @@ func main() {
 	// 设置Go运行时参数
 	cfg.SetupGoRuntime()
 
+	// 🆕 初始化链路追踪（未启用时使用 noop TracerProvider，仍透传 traceparent）
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.Tracing.Enabled {
+		var err error
+		shutdownTracing, err = tracingutil.Init(context.Background(), tracingutil.Options{
+			ServiceName: cfg.Tracing.ServiceName,
+			Exporter:    cfg.Tracing.Exporter,
+			Endpoint:    cfg.Tracing.Endpoint,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			utils.Fatal("链路追踪初始化失败", zap.Error(err))
+		}
+	}
+
 	// ==================== 初始化数据库 ====================
 	db, err := config.InitDatabase(&cfg.Database)
 	if err != nil {
@@ -59,6 +77,12 @@ func main() {
 
 	// ==================== 初始化Repository ====================
 	tokenRepo := repository.NewTokenRepository(db)
+	policyRepo := repository.NewPolicyRepository(db)     // 🆕 沙箱策略
+	historyRepo := repository.NewHistoryRepository(db)   // 🆕 执行历史
+	functionRepo := repository.NewFunctionRepository(db) // 🆕 存储脚本
+	scheduleRepo := repository.NewScheduleRepository(db) // 🆕 定时执行
+	triggerRepo := repository.NewTriggerRepository(db)   // 🆕 HTTP 触发器
+	workflowRepo := repository.NewWorkflowRepository(db) // 🆕 工作流
 
 	// ==================== 初始化Service ====================
 	// 🔥 缓存写入池（统一管理所有异步缓存写入）
@@ -117,6 +141,9 @@ func main() {
 		quotaService,                     // 🔥 配额服务
 	)
 
+	// 🆕 沙箱策略服务（按 Token 配置模块、限制和网络权限）
+	policyService := service.NewPolicyService(policyRepo, tokenService)
+
 	// 限流服务（使用统一配置）
 	rateLimiterService := service.NewRateLimiterService(
 		cfg.TokenLimit.HotTierSize,       // 热数据层大小
@@ -129,11 +156,53 @@ func main() {
 	)
 
 	// 执行器服务
-	executor := service.NewJSExecutor(cfg)
+	executor, err := sandbox.New(sandbox.WithConfig(cfg.Sandbox()))
+	if err != nil {
+		utils.Fatal("创建执行器失败", zap.Error(err))
+	}
 
 	// 🆕 统计服务
 	statsService := service.NewStatsService(db)
 
+	// 🆕 执行历史服务（按 Token 策略开启）+ 过期记录清理
+	historyService := service.NewHistoryService(historyRepo, cfg.History)
+	var historyCleanupService *service.HistoryCleanupService
+	if cfg.History.Enabled {
+		historyCleanupService = service.NewHistoryCleanupService(
+			historyRepo,
+			cfg.History.CleanupInterval,
+			cfg.History.BatchSize,
+		)
+	}
+
+	// 🆕 存储脚本服务（发布时预编译，调用时按名称解析）
+	functionService := service.NewFunctionService(functionRepo, executor)
+
+	// 🆕 以 Token 身份执行存储脚本（定时执行、HTTP 触发器共用：Token 校验、限流、配额、统计、执行历史）
+	functionRunner := service.NewFunctionRunner(
+		functionService,
+		executor,
+		tokenService,
+		policyService,
+		rateLimiterService,
+		quotaService,
+		statsService,
+		historyService,
+	)
+
+	// 🆕 定时执行服务（多实例通过 Redis 选主 + 数据库条件更新保证每次触发只执行一次）
+	cronService := service.NewCronService(scheduleRepo, functionRunner, functionService, executor, redisClient, cfg.Cron)
+	cronService.Start()
+
+	// 🆕 HTTP 触发器服务（存储脚本绑定为公开的 Webhook 地址）
+	triggerService := service.NewTriggerService(triggerRepo, functionRunner, functionService, rateLimiterService, cfg.Trigger)
+
+	// 🆕 工作流服务（存储脚本组成的 DAG，在服务内执行）
+	workflowService := service.NewWorkflowService(workflowRepo, functionRunner, functionService, executor, cfg.Workflow)
+
+	// 🆕 异步任务服务（依赖 Redis 保存任务状态）
+	jobService := service.NewJobService(redisClient, executor, statsService, historyService, cfg)
+
 	// 🔐 Token查询验证码相关服务
 	sessionService := service.NewPageSessionService(
 		redisClient,
@@ -165,17 +234,34 @@ func main() {
 	adminToken := cfg.Auth.AdminToken
 
 	// ==================== 初始化Controller ====================
-	executorController := controller.NewExecutorController(executor, cfg, tokenService, statsService, quotaService, sessionService)
-	tokenController := controller.NewTokenController(tokenService, rateLimiterService, cacheWritePool, adminToken, quotaService, quotaCleanupService, sessionService, verifyService)
+	executorController := controller.NewExecutorController(executor, cfg, tokenService, statsService, quotaService, sessionService, rateLimiterService, historyService, functionService)
+	tokenController := controller.NewTokenController(tokenService, rateLimiterService, cacheWritePool, adminToken, quotaService, quotaCleanupService, sessionService, verifyService, policyService)
 	statsController := controller.NewStatsController(statsService)
+	jobController := controller.NewJobController(jobService, executor, quotaService)
+	historyController := controller.NewHistoryController(historyService, historyCleanupService)
+	functionController := controller.NewFunctionController(functionService, executor)
+	scheduleController := controller.NewScheduleController(cronService)
+	triggerController := controller.NewTriggerController(triggerService)
+	workflowController := controller.NewWorkflowController(workflowService)
+	metricsController := controller.NewMetricsController(
+		service.NewMetricsService(executor, cacheService, quotaService, rateLimiterService, cacheWritePool, jobService),
+	)
 
 	// ==================== 设置路由 ====================
 	ginRouter, routerResources := router.SetupRouter(
 		executorController,
 		tokenController,
-		statsController, // 🆕 统计控制器
+		statsController,    // 🆕 统计控制器
+		jobController,      // 🆕 异步任务控制器
+		metricsController,  // 🆕 Prometheus 指标控制器
+		historyController,  // 🆕 执行历史控制器
+		functionController, // 🆕 存储脚本控制器
+		scheduleController, // 🆕 定时执行控制器
+		triggerController,  // 🆕 HTTP 触发器控制器
+		workflowController, // 🆕 工作流控制器
 		tokenService,
 		rateLimiterService,
+		policyService, // 🆕 沙箱策略服务
 		adminToken,
 		cfg,            // 🔥 传入配置（用于 IP 限流）
 		cacheWritePool, // 🔥 传入缓存写入池（用于监控）
@@ -184,6 +270,23 @@ func main() {
 	// ==================== 加载HTML模板 ====================
 	ginRouter.LoadHTMLGlob("templates/*")
 
+	// 🆕 gRPC 服务（与 HTTP 接口共用认证、限流、配额、统计和执行器，监听独立端口）
+	var grpcServer *grpcserver.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = grpcserver.NewServer(
+			executor,
+			cfg,
+			tokenService,
+			policyService,
+			rateLimiterService,
+			quotaService,
+			statsService,
+			historyService,
+			routerResources.SmartIPLimiter, // 🔒 与 HTTP 接口共用 IP 限流
+			adminToken,
+		)
+	}
+
 	// 启动HTTP服务器
 	server := &http.Server{
 		Addr:           ":" + cfg.Server.Port,
@@ -214,6 +317,10 @@ func main() {
 			utils.Error("服务器关闭失败", zap.Error(err))
 			_ = utils.Sync()
 		}
+		if grpcServer != nil {
+			grpcServer.Shutdown(10 * time.Second)
+			_ = utils.Sync()
+		}
 
 		// 2. 等待正在处理的请求完成（给予额外时间处理配额等操作）
 		utils.Info("步骤2: 等待正在处理的请求完成")
@@ -225,32 +332,39 @@ func main() {
 		quotaService.Stop()
 		_ = utils.Sync()
 
-		// 4. 停止执行器
-		utils.Info("步骤4: 停止JavaScript执行器")
+		// 4. 停止异步任务服务（等待执行中的任务，未开始的任务标记为失败）和定时执行服务
+		utils.Info("步骤4: 停止异步任务服务和定时执行服务")
+		jobService.Shutdown(5 * time.Second)
+		cronService.Stop(5 * time.Second)
+		_ = utils.Sync()
+
+		// 5. 停止执行器
+		utils.Info("步骤5: 停止JavaScript执行器")
 		executor.Shutdown()
 		_ = utils.Sync()
 
-		// 5. 关闭缓存写入池（等待所有缓存写入完成）
-		utils.Info("步骤5: 关闭缓存写入池")
+		// 6. 关闭缓存写入池和执行历史写入队列（等待已提交的写入完成）
+		utils.Info("步骤6: 关闭缓存写入池和执行历史写入队列")
 		cacheWritePool.Shutdown(5 * time.Second)
+		historyService.Shutdown(5 * time.Second)
 		_ = utils.Sync()
 
-		// 6. 关闭限流服务
-		utils.Info("步骤6: 关闭限流服务")
+		// 7. 关闭限流服务
+		utils.Info("步骤7: 关闭限流服务")
 		if err := rateLimiterService.Close(); err != nil {
 			utils.Warn("关闭限流服务失败", zap.Error(err))
 		}
 		_ = utils.Sync()
 
-		// 7. 关闭缓存服务
-		utils.Info("步骤7: 关闭缓存服务")
+		// 8. 关闭缓存服务
+		utils.Info("步骤8: 关闭缓存服务")
 		if err := cacheService.Close(); err != nil {
 			utils.Warn("关闭缓存服务失败", zap.Error(err))
 		}
 		_ = utils.Sync()
 
-		// 8. 关闭路由器中的限流器
-		utils.Info("步骤8: 关闭IP限流器")
+		// 9. 关闭路由器中的限流器
+		utils.Info("步骤9: 关闭IP限流器")
 		if routerResources != nil {
 			if routerResources.SmartIPLimiter != nil {
 				if err := routerResources.SmartIPLimiter.Close(); err != nil {
@@ -265,12 +379,25 @@ func main() {
 		}
 		_ = utils.Sync()
 
-		// 9. 关闭配额清理服务
-		utils.Info("步骤9: 关闭配额清理服务")
+		// 10. 关闭配额清理服务
+		utils.Info("步骤10: 关闭配额清理服务")
 		if quotaCleanupService != nil {
 			quotaCleanupService.Stop()
 			_ = utils.Sync()
 		}
+		if historyCleanupService != nil {
+			historyCleanupService.Stop()
+			_ = utils.Sync()
+		}
+
+		// 11. 刷新并关闭链路追踪导出器
+		utils.Info("步骤11: 关闭链路追踪")
+		tracingCtx, tracingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := shutdownTracing(tracingCtx); err != nil {
+			utils.Warn("关闭链路追踪失败", zap.Error(err))
+		}
+		tracingCancel()
+		_ = utils.Sync()
 
 		utils.Info("服务关闭完成")
 		_ = utils.Sync()
@@ -291,6 +418,10 @@ func main() {
 	utils.Info("可用端点",
 		zap.Strings("endpoints", []string{
 			"POST /flow/codeblock - Execute code (需要Token认证和限流)",
+			"POST /flow/codeblock/batch - Batch execute code (需要Token认证，按条目限流和计费)",
+			"POST /flow/codeblock/validate - Validate code without executing (需要Token认证，不扣配额)",
+			"POST /flow/jobs - Submit async job (需要Token认证和限流)",
+			"GET  /flow/jobs/:id - Query async job (需要Token认证)",
 			"GET  /flow/health - Detailed health check (需要管理员认证)",
 			"GET  /flow/status - Execution statistics (需要管理员认证)",
 			"GET  /flow/limits - System limits (需要管理员认证)",
@@ -301,6 +432,19 @@ func main() {
 		}),
 	)
 
+	// 🆕 启动 gRPC 服务
+	if grpcServer != nil {
+		go func() {
+			utils.Info("gRPC 服务启动成功",
+				zap.String("listen_address", ":"+cfg.GRPC.Port),
+				zap.Strings("services", []string{"flow.v1.FlowService", "flow.v1.TokenAdminService"}),
+			)
+			if err := grpcServer.ListenAndServe(); err != nil {
+				utils.Fatal("gRPC 服务启动失败", zap.Error(err))
+			}
+		}()
+	}
+
 	utils.Info("服务启动成功",
 		zap.String("listen_address", ":"+cfg.Server.Port),
 	)
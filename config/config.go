@@ -8,7 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"flow-codeblock-go/pkg/sandbox"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/tracingutil"
 
 	"go.uber.org/zap"
 )
@@ -31,6 +33,15 @@ type Config struct {
 	XLSX         XLSXConfig         // 🔥 XLSX 模块配置
 	TestTool     TestToolConfig     // 🔧 测试工具页面配置
 	TokenVerify  TokenVerifyConfig  // 🔐 Token查询验证码配置
+	Batch        BatchConfig        // 🆕 批量执行配置
+	Job          JobConfig          // 🆕 异步任务配置
+	Metrics      MetricsConfig      // 🆕 Prometheus 指标配置
+	Tracing      TracingConfig      // 🆕 链路追踪配置
+	History      HistoryConfig      // 🆕 执行历史配置
+	Cron         CronConfig         // 🆕 定时执行配置
+	Trigger      TriggerConfig      // 🆕 HTTP 触发器配置
+	Workflow     WorkflowConfig     // 🆕 工作流（DAG）配置
+	GRPC         GRPCConfig         // 🆕 gRPC 接口配置
 }
 
 // ServerConfig HTTP服务器配置
@@ -44,90 +55,24 @@ type ServerConfig struct {
 	AllowedOrigins []string // 允许的前端域名列表（为空则只允许服务端和同域调用）
 }
 
-// ExecutorConfig JavaScript执行器配置
-type ExecutorConfig struct {
-	PoolSize         int
-	MinPoolSize      int
-	MaxPoolSize      int
-	IdleTimeout      time.Duration
-	MaxConcurrent    int
-	MaxCodeLength    int
-	MaxInputSize     int
-	MaxResultSize    int
-	ExecutionTimeout time.Duration
-	CodeCacheSize    int
-	AllowConsole     bool // 是否允许用户代码使用 console（开发环境：true，生产环境：false）
-
-	// 🔥 超时配置（新增可配置项）
-	ConcurrencyWaitTimeout    time.Duration // 并发槽位等待超时（默认 10 秒）
-	RuntimePoolAcquireTimeout time.Duration // Runtime 池获取超时（默认 5 秒）
-	SlowExecutionThreshold    time.Duration // 🔥 慢执行检测阈值（默认 1 秒）
-
-	// 🔥 熔断器配置
-	CircuitBreakerEnabled      bool          // 是否启用熔断器
-	CircuitBreakerMinRequests  uint32        // 最小请求数（触发熔断的最小样本）
-	CircuitBreakerFailureRatio float64       // 失败率阈值（0.0-1.0）
-	CircuitBreakerTimeout      time.Duration // Open 状态持续时间
-	CircuitBreakerMaxRequests  uint32        // Half-Open 状态最大探测请求数
-
-	// 🔥 JavaScript 内存限制配置
-	EnableJSMemoryLimit bool  // 是否启用 JavaScript 侧内存限制（默认：true）
-	JSMemoryLimitMB     int64 // JavaScript 单次分配最大大小（MB，默认使用 MaxBlobFileSize）
-
-	// 🔥 健康检查和池管理配置
-	MinErrorCountForCheck         int     // 最小错误次数阈值（默认：10，低于此值不检查错误率）
-	MaxErrorRateThreshold         float64 // 最大错误率阈值（默认：0.1，即 10%，超过视为异常）
-	MinExecutionCountForStats     int     // 统计长期运行的最小执行次数（默认：1000）
-	LongRunningThresholdMinutes   int     // 长期运行时间阈值（分钟，默认：60）
-	PoolExpansionThresholdPercent float64 // 池扩展阈值百分比（默认：0.1，即 10%，可用槽位低于此值时扩展）
-	HealthCheckIntervalSeconds    int     // 健康检查间隔（秒，默认：30）
-
-	// 🔥 Runtime 重用限制配置（方案D：防止内存累积）
-	MaxRuntimeReuseCount int64 // Runtime 最大重用次数（默认：2，达到后销毁并创建新的）
-
-	// 🔥 GC 触发频率配置（高并发优化）
-	GCTriggerInterval int64 // 每销毁N个Runtime触发一次GC（默认：15，值越大GC越少，CPU开销越低）
-}
-
-// FetchConfig Fetch API配置
-type FetchConfig struct {
-	Timeout             time.Duration // HTTP 请求超时（连接建立+发送+等待响应头）
-	ResponseReadTimeout time.Duration // 🔥 新增：响应读取总时长超时（防止慢速读取攻击）
-	MaxBlobFileSize     int64
-	FormDataBufferSize  int
-	MaxFileSize         int64
-
-	// 🔥 下载限制（新）
-	MaxResponseSize  int64 // response.arrayBuffer/blob/text/json() 缓冲读取限制（默认 1MB）
-	MaxStreamingSize int64 // response.body.getReader() 流式读取累计限制（默认 100MB）
-
-	// 🔥 上传限制（新）
-	MaxBufferedFormDataSize  int64 // FormData 缓冲上传限制：Web FormData + Blob、Node.js form-data + Buffer（默认 1MB）
-	MaxStreamingFormDataSize int64 // FormData 流式上传限制：Node.js form-data + Stream（默认 100MB）
-
-	// 🛡️ SSRF 防护配置（新增）
-	EnableSSRFProtection bool // 是否启用 SSRF 防护（默认：根据部署环境自动判断）
-	AllowPrivateIP       bool // 是否允许访问私有 IP（默认：本地部署允许，公有云禁止）
-
-	// 🔧 废弃但保留兼容（优先使用新字段）
-	MaxFormDataSize     int64 // 废弃：统一 FormData 限制，改用 MaxBufferedFormDataSize 和 MaxStreamingFormDataSize
-	StreamingThreshold  int64 // 废弃：自动切换阈值，现由用户代码控制
-	EnableChunkedUpload bool  // 保留：是否启用分块传输编码
-
-	// 🔥 HTTP Transport 配置（新增）
-	HTTPMaxIdleConns          int           // 最大空闲连接数（默认：50）
-	HTTPMaxIdleConnsPerHost   int           // 每个 host 的最大空闲连接数（默认：10）
-	HTTPMaxConnsPerHost       int           // 每个 host 的最大连接数（默认：100）
-	HTTPIdleConnTimeout       time.Duration // 空闲连接超时（默认：90秒）
-	HTTPDialTimeout           time.Duration // 连接建立超时（默认：10秒）
-	HTTPKeepAlive             time.Duration // Keep-Alive 间隔（默认：30秒）
-	HTTPTLSHandshakeTimeout   time.Duration // TLS 握手超时（默认：10秒）
-	HTTPExpectContinueTimeout time.Duration // 期望继续超时（默认：1秒）
-	HTTPForceHTTP2            bool          // 启用 HTTP/2（默认：true）
-
-	// 🔥 响应体空闲超时（防止资源泄漏）
-	ResponseBodyIdleTimeout time.Duration // 响应体空闲超时（默认：30秒，即1分钟）
-}
+// ExecutorConfig 执行器配置（定义在 pkg/sandbox，嵌入方和服务端共用同一结构）
+type ExecutorConfig = sandbox.ExecutorConfig
+
+// 公平调度分组方式
+const (
+	SchedulerFairnessByToken     = sandbox.SchedulerFairnessByToken
+	SchedulerFairnessByWorkspace = sandbox.SchedulerFairnessByWorkspace
+)
+
+// Console 输出模式
+const (
+	ConsoleModeDisabled = sandbox.ConsoleModeDisabled
+	ConsoleModeStdout   = sandbox.ConsoleModeStdout
+	ConsoleModeCapture  = sandbox.ConsoleModeCapture
+)
+
+// FetchConfig Fetch API配置（定义在 pkg/sandbox）
+type FetchConfig = sandbox.FetchConfig
 
 // RuntimeConfig Go运行时配置
 type RuntimeConfig struct {
@@ -190,12 +135,8 @@ type QuotaSyncConfig struct {
 	SyncInterval  time.Duration // 同步间隔（默认：1秒）
 }
 
-// XLSXConfig XLSX 模块配置
-type XLSXConfig struct {
-	MaxSnapshotSize int64 // Copy-on-Read 模式的最大文件大小（字节），默认 5MB
-	MaxRows         int   // 🔥 最大行数限制（默认 100000）
-	MaxCols         int   // 🔥 最大列数限制（默认 100）
-}
+// XLSXConfig XLSX 模块配置（定义在 pkg/sandbox）
+type XLSXConfig = sandbox.XLSXConfig
 
 // TestToolConfig 测试工具页面配置
 type TestToolConfig struct {
@@ -236,6 +177,92 @@ type TokenVerifyConfig struct {
 	RateLimitIP    int // 每IP每小时最多请求次数（默认10次）
 }
 
+// BatchConfig 批量执行配置
+type BatchConfig struct {
+	MaxBatchSize int // 单次批量请求最大条目数（默认：100）
+	Concurrency  int // 单个批量请求内的最大并行执行数（默认：10）
+}
+
+// JobConfig 异步任务配置
+type JobConfig struct {
+	Workers            int           // 后台 worker 数量（默认：10）
+	QueueSize          int           // 任务队列容量（默认：1000，队满时拒绝提交）
+	ResultTTL          time.Duration // 任务状态/结果在 Redis 中的保留时间（默认：60分钟）
+	CallbackTimeout    time.Duration // 回调请求超时（默认：10秒）
+	CallbackMaxRetries int           // 回调失败重试次数（默认：3次）
+}
+
+// MetricsConfig Prometheus 指标配置（GET /metrics）
+type MetricsConfig struct {
+	Enabled     bool // 是否启用 /metrics（默认：true）
+	RequireAuth bool // 是否需要管理员Token（默认：true，Prometheus 使用 authorization.credentials 配置）
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool    // 是否导出 span（默认：false；未启用时仍透传 traceparent）
+	Exporter    string  // 导出器：otlphttp / otlpgrpc（默认：otlphttp）
+	Endpoint    string  // OTLP 地址（如 http://127.0.0.1:4318），为空时使用 OTEL_EXPORTER_OTLP_* 环境变量
+	ServiceName string  // service.name（默认：flow-codeblock-go）
+	SampleRatio float64 // 根 span 采样率（0-1，默认：1）
+}
+
+// HistoryConfig 执行历史配置
+// 🆕 执行历史按 Token 开启（沙箱策略 history.enabled），这里是全局开关和各项默认值
+type HistoryConfig struct {
+	Enabled         bool          // 全局开关（默认：true；关闭后所有 Token 都不记录）
+	RetentionDays   int           // 默认保留天数（默认：30，可被策略 history.retention_days 覆盖）
+	MaxFieldBytes   int           // input / result / logs 每个字段的默认大小上限（字节，默认：65536），超出部分截断
+	RedactKeys      []string      // 默认脱敏字段名（不区分大小写，与策略 history.redact_keys 合并）
+	CleanupInterval time.Duration // 过期记录清理间隔（默认：1小时）
+	BatchSize       int           // 每批删除数量（默认：5000）
+	WriteWorkers    int           // 写入 worker 数量（默认：4）
+	WriteQueueSize  int           // 写入队列大小（默认：1000，队列满时丢弃并记录日志，不阻塞执行）
+}
+
+// CronConfig 定时执行配置（存储脚本按 cron 表达式定时执行）
+// 🆕 与执行器的公平调度（SCHEDULER_*）无关：这里负责"什么时候触发"，触发后的执行仍经过公平调度
+type CronConfig struct {
+	Enabled              bool          // 是否触发定时执行（默认：true；关闭后计划不会触发，也不能新建）
+	PollInterval         time.Duration // 检查到期计划的间隔（默认：5秒）
+	LeaderTTL            time.Duration // Redis 主节点租约时间（默认：30秒，必须大于检查间隔）
+	MaxConcurrentRuns    int           // 本实例同时执行的定时任务上限（默认：10）
+	MaxQueuedRuns        int           // queue 策略下每个计划最多排队的触发数（默认：5，超出记为 skipped）
+	MaxSchedulesPerToken int           // 每个 Token 最多创建的计划数（默认：20）
+	MaxResultBytes       int           // 执行记录中保存的结果大小上限（字节，默认：65536），超出部分截断
+	RunRetentionDays     int           // 执行记录保留天数（默认：30）
+	DefaultTimezone      string        // 未指定时区时使用的时区（默认：Asia/Shanghai）
+}
+
+// TriggerConfig HTTP 触发器配置（存储脚本绑定为公开的 Webhook 地址）
+type TriggerConfig struct {
+	Enabled                   bool          // 是否接收触发请求（默认：true；关闭后触发地址返回 503，也不能新建）
+	MaxTriggersPerToken       int           // 每个 Token 最多创建的触发器数（默认：20）
+	MaxBodyBytes              int64         // 触发请求体大小上限（字节，默认：1MB）
+	DefaultRateLimitPerMinute int           // 触发器未指定时的每分钟请求上限（默认：60）
+	DefaultRateLimitBurst     int           // 触发器未指定时的每秒请求上限（默认：10）
+	SignatureTolerance        time.Duration // 签名时间戳允许的偏差（默认：5分钟）
+}
+
+// WorkflowConfig 工作流配置（多个存储脚本组成 DAG，在服务内依次 / 并行执行）
+type WorkflowConfig struct {
+	Enabled              bool          // 是否允许执行工作流（默认：true；关闭后执行接口返回 503，也不能新建）
+	MaxWorkflowsPerToken int           // 每个 Token 最多创建的工作流数（默认：20）
+	MaxNodes             int           // 每个工作流最多节点数（默认：50）
+	MaxFanOutItems       int           // for_each 节点最多展开的元素数（默认：100）
+	MaxParallel          int           // 单次运行同时执行的脚本数上限（默认：8）
+	MaxAttempts          int           // 节点重试时的最大尝试次数上限（默认：5）
+	RunTimeout           time.Duration // 单次运行的总超时（默认：120秒）
+}
+
+// GRPCConfig gRPC 接口配置（与 HTTP 服务共用认证、限流、配额和执行器，监听独立端口）
+type GRPCConfig struct {
+	Enabled              bool   // 是否启动 gRPC 服务（默认：false）
+	Port                 string // 监听端口（默认：9090）
+	MaxRecvMsgMB         int    // 单条请求消息大小上限（MB，默认：与 MAX_REQUEST_BODY_MB 一致）
+	MaxConcurrentStreams uint32 // 每个连接的并发请求数上限（默认：100）
+}
+
 // calculateMaxConcurrent 基于系统内存智能计算并发限制
 // 🔥 使用保守策略，防止 OOM
 func calculateMaxConcurrent() int {
@@ -289,8 +316,8 @@ func calculateMaxConcurrent() int {
 	return maxConcurrent
 }
 
-// LoadConfig 从环境变量加载配置
-func LoadConfig() *Config {
+// loadConfig 从环境变量加载除认证以外的全部配置
+func loadConfig() *Config {
 	cfg := &Config{}
 
 	// 加载环境配置
@@ -396,6 +423,24 @@ func LoadConfig() *Config {
 			zap.Int("final_pool_size", poolSize))
 	}
 
+	// 🆕 EventLoop 池大小（与 Runtime 池独立配置，超出范围时调整到 [MIN, MAX]）
+	eventLoopPoolSize := getEnvInt("EVENTLOOP_POOL_SIZE", 50)
+	minEventLoopPoolSize := getEnvInt("MIN_EVENTLOOP_POOL_SIZE", 20)
+	maxEventLoopPoolSize := getEnvInt("MAX_EVENTLOOP_POOL_SIZE", 100)
+	if eventLoopPoolSize < minEventLoopPoolSize || eventLoopPoolSize > maxEventLoopPoolSize {
+		adjusted := eventLoopPoolSize
+		if adjusted < minEventLoopPoolSize {
+			adjusted = minEventLoopPoolSize
+		}
+		if adjusted > maxEventLoopPoolSize {
+			adjusted = maxEventLoopPoolSize
+		}
+		utils.Warn("EVENTLOOP_POOL_SIZE 超出 [MIN_EVENTLOOP_POOL_SIZE, MAX_EVENTLOOP_POOL_SIZE] 范围，已调整",
+			zap.Int("original", eventLoopPoolSize),
+			zap.Int("adjusted", adjusted))
+		eventLoopPoolSize = adjusted
+	}
+
 	// 🔥 智能计算默认并发限制（基于系统内存）
 	smartMaxConcurrent := calculateMaxConcurrent()
 
@@ -405,6 +450,14 @@ func LoadConfig() *Config {
 	// - 可通过 ALLOW_CONSOLE 环境变量显式覆盖
 	allowConsole := getEnvBool("ALLOW_CONSOLE", cfg.Environment == "development")
 
+	// 🆕 Console 输出模式：未设置 CONSOLE_MODE 时由 ALLOW_CONSOLE 推导（兼容旧配置）
+	defaultConsoleMode := ConsoleModeDisabled
+	if allowConsole {
+		defaultConsoleMode = ConsoleModeStdout
+	}
+	consoleMode := strings.ToLower(getEnvString("CONSOLE_MODE", defaultConsoleMode))
+	allowConsole = consoleMode != ConsoleModeDisabled
+
 	cfg.Executor = ExecutorConfig{
 		PoolSize:         poolSize,
 		MinPoolSize:      minPoolSize,
@@ -416,7 +469,20 @@ func LoadConfig() *Config {
 		MaxResultSize:    getEnvInt("MAX_RESULT_SIZE", 5*1024*1024),
 		ExecutionTimeout: time.Duration(getEnvInt("EXECUTION_TIMEOUT_MS", 300000)) * time.Millisecond,
 		CodeCacheSize:    getEnvInt("CODE_CACHE_SIZE", 100),
-		AllowConsole:     allowConsole, // 🔥 Console 控制
+		AllowConsole:     allowConsole,                            // 🔥 Console 控制
+		ConsoleMode:      consoleMode,                             // 🆕 Console 输出模式
+		ConsoleMaxLines:  getEnvInt("CONSOLE_MAX_LINES", 200),     // 默认 200 条
+		ConsoleMaxBytes:  getEnvInt("CONSOLE_MAX_BYTES", 64*1024), // 默认 64KB
+
+		// 🆕 EventLoop 池配置
+		EventLoopPoolSize:    eventLoopPoolSize,
+		MinEventLoopPoolSize: minEventLoopPoolSize,
+		MaxEventLoopPoolSize: maxEventLoopPoolSize,
+
+		// 🆕 公平调度配置
+		SchedulerFairnessKey:        strings.ToLower(getEnvString("SCHEDULER_FAIRNESS_KEY", SchedulerFairnessByToken)),
+		SchedulerDefaultMaxInFlight: getEnvInt("SCHEDULER_DEFAULT_MAX_IN_FLIGHT", 0),  // 默认不限制
+		SchedulerMaxQueuePerTenant:  getEnvInt("SCHEDULER_MAX_QUEUE_PER_TENANT", 200), // 默认 200
 
 		// 🔥 超时配置（新增可配置项）
 		ConcurrencyWaitTimeout:    time.Duration(getEnvInt("CONCURRENCY_WAIT_TIMEOUT_SEC", 10)) * time.Second,       // 并发等待超时（默认 10 秒）
@@ -619,6 +685,97 @@ func LoadConfig() *Config {
 		RateLimitIP:    getEnvInt("TOKEN_VERIFY_RATE_LIMIT_IP", 10),   // IP频率限制，默认10次/小时
 	}
 
+	// 🆕 加载批量执行配置
+	cfg.Batch = BatchConfig{
+		MaxBatchSize: getEnvInt("MAX_BATCH_SIZE", 100),   // 默认单批最多100条
+		Concurrency:  getEnvInt("BATCH_CONCURRENCY", 10), // 默认单批最多10条并行
+	}
+
+	// 🆕 加载异步任务配置
+	cfg.Job = JobConfig{
+		Workers:            getEnvInt("JOB_WORKERS", 10),                                           // 默认10个worker
+		QueueSize:          getEnvInt("JOB_QUEUE_SIZE", 1000),                                      // 默认队列容量1000
+		ResultTTL:          time.Duration(getEnvInt("JOB_RESULT_TTL_MIN", 60)) * time.Minute,       // 默认保留60分钟
+		CallbackTimeout:    time.Duration(getEnvInt("JOB_CALLBACK_TIMEOUT_SEC", 10)) * time.Second, // 默认10秒
+		CallbackMaxRetries: getEnvInt("JOB_CALLBACK_MAX_RETRIES", 3),                               // 默认重试3次
+	}
+
+	// 🆕 加载 Prometheus 指标配置
+	cfg.Metrics = MetricsConfig{
+		Enabled:     getEnvBool("METRICS_ENABLED", true),      // 默认启用
+		RequireAuth: getEnvBool("METRICS_REQUIRE_AUTH", true), // 默认需要管理员Token
+	}
+
+	// 🆕 加载链路追踪配置
+	cfg.Tracing = TracingConfig{
+		Enabled:     getEnvBool("TRACING_ENABLED", false),
+		Exporter:    getEnvString("TRACING_EXPORTER", tracingutil.ExporterOTLPHTTP),
+		Endpoint:    getEnvString("TRACING_ENDPOINT", ""),
+		ServiceName: getEnvString("TRACING_SERVICE_NAME", "flow-codeblock-go"),
+		SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1.0),
+	}
+
+	// 🆕 加载执行历史配置
+	cfg.History = HistoryConfig{
+		Enabled:         getEnvBool("HISTORY_ENABLED", true),
+		RetentionDays:   getEnvInt("HISTORY_RETENTION_DAYS", 30),
+		MaxFieldBytes:   getEnvInt("HISTORY_MAX_FIELD_BYTES", 65536),
+		RedactKeys:      parseCommaList(getEnvString("HISTORY_REDACT_KEYS", "password,passwd,secret,token,access_token,authorization,api_key,apikey")),
+		CleanupInterval: time.Duration(getEnvInt("HISTORY_CLEANUP_INTERVAL_MINUTES", 60)) * time.Minute,
+		BatchSize:       getEnvInt("HISTORY_CLEANUP_BATCH_SIZE", 5000),
+		WriteWorkers:    getEnvInt("HISTORY_WRITE_WORKERS", 4),
+		WriteQueueSize:  getEnvInt("HISTORY_WRITE_QUEUE_SIZE", 1000),
+	}
+
+	// 🆕 加载定时执行配置
+	cfg.Cron = CronConfig{
+		Enabled:              getEnvBool("CRON_ENABLED", true),
+		PollInterval:         time.Duration(getEnvInt("CRON_POLL_INTERVAL_SEC", 5)) * time.Second,
+		LeaderTTL:            time.Duration(getEnvInt("CRON_LEADER_TTL_SEC", 30)) * time.Second,
+		MaxConcurrentRuns:    getEnvInt("CRON_MAX_CONCURRENT_RUNS", 10),
+		MaxQueuedRuns:        getEnvInt("CRON_MAX_QUEUED_RUNS", 5),
+		MaxSchedulesPerToken: getEnvInt("CRON_MAX_SCHEDULES_PER_TOKEN", 20),
+		MaxResultBytes:       getEnvInt("CRON_MAX_RESULT_BYTES", 65536),
+		RunRetentionDays:     getEnvInt("CRON_RUN_RETENTION_DAYS", 30),
+		DefaultTimezone:      getEnvString("CRON_DEFAULT_TIMEZONE", "Asia/Shanghai"),
+	}
+
+	// 🆕 加载 HTTP 触发器配置
+	cfg.Trigger = TriggerConfig{
+		Enabled:                   getEnvBool("TRIGGER_ENABLED", true),
+		MaxTriggersPerToken:       getEnvInt("TRIGGER_MAX_PER_TOKEN", 20),
+		MaxBodyBytes:              int64(getEnvInt("TRIGGER_MAX_BODY_BYTES", 1048576)),
+		DefaultRateLimitPerMinute: getEnvInt("TRIGGER_DEFAULT_RATE_LIMIT_PER_MINUTE", 60),
+		DefaultRateLimitBurst:     getEnvInt("TRIGGER_DEFAULT_RATE_LIMIT_BURST", 10),
+		SignatureTolerance:        time.Duration(getEnvInt("TRIGGER_SIGNATURE_TOLERANCE_SEC", 300)) * time.Second,
+	}
+
+	// 🆕 工作流（DAG）配置
+	cfg.Workflow = WorkflowConfig{
+		Enabled:              getEnvBool("WORKFLOW_ENABLED", true),
+		MaxWorkflowsPerToken: getEnvInt("WORKFLOW_MAX_PER_TOKEN", 20),
+		MaxNodes:             getEnvInt("WORKFLOW_MAX_NODES", 50),
+		MaxFanOutItems:       getEnvInt("WORKFLOW_MAX_FANOUT_ITEMS", 100),
+		MaxParallel:          getEnvInt("WORKFLOW_MAX_PARALLEL", 8),
+		MaxAttempts:          getEnvInt("WORKFLOW_MAX_ATTEMPTS", 5),
+		RunTimeout:           time.Duration(getEnvInt("WORKFLOW_RUN_TIMEOUT_SEC", 120)) * time.Second,
+	}
+
+	// 🆕 gRPC 接口配置
+	cfg.GRPC = GRPCConfig{
+		Enabled:              getEnvBool("GRPC_ENABLED", false),
+		Port:                 getEnvString("GRPC_PORT", "9090"),
+		MaxRecvMsgMB:         getEnvInt("GRPC_MAX_RECV_MSG_MB", cfg.Server.MaxRequestBodyMB),
+		MaxConcurrentStreams: uint32(getEnvInt("GRPC_MAX_CONCURRENT_STREAMS", 100)),
+	}
+
+	return cfg
+}
+
+// LoadConfig 从环境变量加载配置
+func LoadConfig() *Config {
+	cfg := loadConfig()
+
 	// 🔒 加载和验证认证配置
 	adminToken := os.Getenv("ADMIN_TOKEN")
 
@@ -731,6 +888,125 @@ func (c *Config) Validate() error {
 			c.Executor.MaxConcurrent)
 	}
 
+	// 8. 验证批量执行配置
+	if c.Batch.MaxBatchSize < 1 {
+		return fmt.Errorf("MAX_BATCH_SIZE 必须 >= 1，当前值: %d",
+			c.Batch.MaxBatchSize)
+	}
+	if c.Batch.Concurrency < 1 {
+		return fmt.Errorf("BATCH_CONCURRENCY 必须 >= 1，当前值: %d",
+			c.Batch.Concurrency)
+	}
+
+	// 9. 验证 Console 输出模式
+	switch c.Executor.ConsoleMode {
+	case ConsoleModeDisabled, ConsoleModeStdout, ConsoleModeCapture:
+	default:
+		return fmt.Errorf("CONSOLE_MODE 必须是 %s/%s/%s 之一，当前值: %s",
+			ConsoleModeDisabled, ConsoleModeStdout, ConsoleModeCapture, c.Executor.ConsoleMode)
+	}
+	if c.Executor.ConsoleMode == ConsoleModeCapture && (c.Executor.ConsoleMaxLines < 1 || c.Executor.ConsoleMaxBytes < 1) {
+		return fmt.Errorf("CONSOLE_MAX_LINES 和 CONSOLE_MAX_BYTES 必须 >= 1，当前值: %d, %d",
+			c.Executor.ConsoleMaxLines, c.Executor.ConsoleMaxBytes)
+	}
+
+	// 10. 验证 EventLoop 池大小配置
+	if c.Executor.MinEventLoopPoolSize < 1 {
+		return fmt.Errorf("MIN_EVENTLOOP_POOL_SIZE 必须 >= 1，当前值: %d",
+			c.Executor.MinEventLoopPoolSize)
+	}
+	if c.Executor.MaxEventLoopPoolSize < c.Executor.MinEventLoopPoolSize {
+		return fmt.Errorf("MAX_EVENTLOOP_POOL_SIZE (%d) 不能小于 MIN_EVENTLOOP_POOL_SIZE (%d)",
+			c.Executor.MaxEventLoopPoolSize, c.Executor.MinEventLoopPoolSize)
+	}
+
+	// 11. 验证公平调度配置
+	switch c.Executor.SchedulerFairnessKey {
+	case SchedulerFairnessByToken, SchedulerFairnessByWorkspace:
+	default:
+		return fmt.Errorf("SCHEDULER_FAIRNESS_KEY 必须是 %s/%s 之一，当前值: %s",
+			SchedulerFairnessByToken, SchedulerFairnessByWorkspace, c.Executor.SchedulerFairnessKey)
+	}
+	if c.Executor.SchedulerDefaultMaxInFlight < 0 {
+		return fmt.Errorf("SCHEDULER_DEFAULT_MAX_IN_FLIGHT 必须 >= 0，当前值: %d",
+			c.Executor.SchedulerDefaultMaxInFlight)
+	}
+	if c.Executor.SchedulerMaxQueuePerTenant < 1 {
+		return fmt.Errorf("SCHEDULER_MAX_QUEUE_PER_TENANT 必须 >= 1，当前值: %d",
+			c.Executor.SchedulerMaxQueuePerTenant)
+	}
+
+	// 12. 验证链路追踪配置
+	if c.Tracing.Enabled {
+		switch c.Tracing.Exporter {
+		case tracingutil.ExporterOTLPHTTP, tracingutil.ExporterOTLPGRPC:
+		default:
+			return fmt.Errorf("TRACING_EXPORTER 必须是 %s/%s 之一，当前值: %s",
+				tracingutil.ExporterOTLPHTTP, tracingutil.ExporterOTLPGRPC, c.Tracing.Exporter)
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			return fmt.Errorf("TRACING_SAMPLE_RATIO 必须在 0-1 之间，当前值: %.2f", c.Tracing.SampleRatio)
+		}
+	}
+
+	// 13. 验证执行历史配置
+	if c.History.RetentionDays < 1 {
+		return fmt.Errorf("HISTORY_RETENTION_DAYS 必须 >= 1，当前值: %d", c.History.RetentionDays)
+	}
+	if c.History.MaxFieldBytes < 1 {
+		return fmt.Errorf("HISTORY_MAX_FIELD_BYTES 必须 >= 1，当前值: %d", c.History.MaxFieldBytes)
+	}
+	if c.History.CleanupInterval <= 0 || c.History.BatchSize < 1 {
+		return fmt.Errorf("HISTORY_CLEANUP_INTERVAL_MINUTES 和 HISTORY_CLEANUP_BATCH_SIZE 必须 >= 1，当前值: %v, %d",
+			c.History.CleanupInterval, c.History.BatchSize)
+	}
+	if c.History.WriteWorkers < 1 || c.History.WriteQueueSize < 1 {
+		return fmt.Errorf("HISTORY_WRITE_WORKERS 和 HISTORY_WRITE_QUEUE_SIZE 必须 >= 1，当前值: %d, %d",
+			c.History.WriteWorkers, c.History.WriteQueueSize)
+	}
+
+	// 14. 验证定时执行配置
+	if c.Cron.PollInterval <= 0 || c.Cron.LeaderTTL <= c.Cron.PollInterval {
+		return fmt.Errorf("CRON_POLL_INTERVAL_SEC 必须 >= 1 且 CRON_LEADER_TTL_SEC 必须大于它，当前值: %v, %v",
+			c.Cron.PollInterval, c.Cron.LeaderTTL)
+	}
+	if c.Cron.MaxConcurrentRuns < 1 || c.Cron.MaxQueuedRuns < 1 || c.Cron.MaxSchedulesPerToken < 1 ||
+		c.Cron.MaxResultBytes < 1 || c.Cron.RunRetentionDays < 1 {
+		return fmt.Errorf("CRON_MAX_CONCURRENT_RUNS、CRON_MAX_QUEUED_RUNS、CRON_MAX_SCHEDULES_PER_TOKEN、CRON_MAX_RESULT_BYTES、CRON_RUN_RETENTION_DAYS 必须 >= 1")
+	}
+	if _, err := time.LoadLocation(c.Cron.DefaultTimezone); err != nil {
+		return fmt.Errorf("CRON_DEFAULT_TIMEZONE 无效: %s", c.Cron.DefaultTimezone)
+	}
+
+	// 15. 验证 HTTP 触发器配置
+	if c.Trigger.MaxTriggersPerToken < 1 || c.Trigger.MaxBodyBytes < 1 ||
+		c.Trigger.DefaultRateLimitPerMinute < 1 || c.Trigger.DefaultRateLimitBurst < 1 {
+		return fmt.Errorf("TRIGGER_MAX_PER_TOKEN、TRIGGER_MAX_BODY_BYTES、TRIGGER_DEFAULT_RATE_LIMIT_PER_MINUTE、TRIGGER_DEFAULT_RATE_LIMIT_BURST 必须 >= 1")
+	}
+	if c.Trigger.SignatureTolerance <= 0 {
+		return fmt.Errorf("TRIGGER_SIGNATURE_TOLERANCE_SEC 必须 >= 1，当前值: %v", c.Trigger.SignatureTolerance)
+	}
+
+	// 16. 验证工作流配置
+	if c.Workflow.MaxWorkflowsPerToken < 1 || c.Workflow.MaxNodes < 1 || c.Workflow.MaxFanOutItems < 1 ||
+		c.Workflow.MaxParallel < 1 || c.Workflow.MaxAttempts < 1 {
+		return fmt.Errorf("WORKFLOW_MAX_PER_TOKEN、WORKFLOW_MAX_NODES、WORKFLOW_MAX_FANOUT_ITEMS、WORKFLOW_MAX_PARALLEL、WORKFLOW_MAX_ATTEMPTS 必须 >= 1")
+	}
+	if c.Workflow.RunTimeout <= 0 {
+		return fmt.Errorf("WORKFLOW_RUN_TIMEOUT_SEC 必须 >= 1，当前值: %v", c.Workflow.RunTimeout)
+	}
+
+	// 17. 验证 gRPC 配置
+	if c.GRPC.Enabled {
+		if c.GRPC.Port == "" || c.GRPC.Port == c.Server.Port {
+			return fmt.Errorf("GRPC_PORT 不能为空，也不能与 PORT 相同，当前值: %q", c.GRPC.Port)
+		}
+		if c.GRPC.MaxRecvMsgMB < 1 || c.GRPC.MaxConcurrentStreams < 1 {
+			return fmt.Errorf("GRPC_MAX_RECV_MSG_MB 和 GRPC_MAX_CONCURRENT_STREAMS 必须 >= 1，当前值: %d, %d",
+				c.GRPC.MaxRecvMsgMB, c.GRPC.MaxConcurrentStreams)
+		}
+	}
+
 	// ✅ 所有验证通过
 	utils.Info("配置验证通过",
 		zap.Int64("max_runtime_reuse", c.Executor.MaxRuntimeReuseCount),
@@ -742,6 +1018,22 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// Sandbox 执行器配置（服务端与 flowrun 通过 sandbox.WithConfig 创建执行器）
+// 执行器日志使用全局日志（需在 InitLogger / InitCLILogger 之后调用）
+func (c *Config) Sandbox() sandbox.Config {
+	var logger *zap.Logger
+	if utils.Logger != nil {
+		// 全局日志跳过了 utils.Info 等包装函数，执行器直接调用 logger，需要还原调用位置
+		logger = utils.Logger.WithOptions(zap.AddCallerSkip(-1))
+	}
+	return sandbox.Config{
+		Executor: c.Executor,
+		Fetch:    c.Fetch,
+		XLSX:     c.XLSX,
+		Logger:   logger,
+	}
+}
+
 // SetupGoRuntime 设置Go运行时参数
 func (c *Config) SetupGoRuntime() {
 	// 设置GOMAXPROCS
@@ -766,6 +1058,17 @@ func (c *Config) SetupGoRuntime() {
 		zap.String("gomemlimit", gomemlimit))
 }
 
+// parseCommaList 解析逗号分隔的列表（去掉空白和空项）
+func parseCommaList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // 辅助函数：从环境变量读取字符串
 func getEnvString(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
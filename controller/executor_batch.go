@@ -0,0 +1,403 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// batchPreparedCode 批量执行中已解码、已预编译的代码（相同 codebase64 只处理一次）
+type batchPreparedCode struct {
+	code       string
+	moduleInfo *utils.ModuleUsageInfo
+	err        *model.ExecuteError
+}
+
+// ExecuteBatch 批量执行JavaScript代码
+// 🆕 同一份代码只校验、编译一次，条目在 Runtime 池中并行执行
+// 🔥 计费策略：
+//   - Token 限流按条目计费（窗口剩余不足时，超出部分返回 TokenRateLimitError）
+//   - 配额按条目扣减（配额耗尽后，剩余条目返回 QuotaExceeded）
+//   - 解码失败或校验失败的条目不计费
+func (c *ExecutorController) ExecuteBatch(ctx *gin.Context) {
+	startTime := time.Now()
+	requestID := ctx.GetString("request_id")
+
+	utils.Info("批量执行请求开始",
+		zap.String("request_id", requestID),
+		zap.String("ip", ctx.ClientIP()),
+		zap.String("ws_id", ctx.GetString("wsId")),
+		zap.String("email", ctx.GetString("userEmail")))
+
+	var req model.BatchExecuteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.Warn("批量执行请求参数错误",
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		c.respondBatchRejected(ctx, 400, "ValidationError", fmt.Sprintf("请求参数错误: %v", err), startTime, requestID)
+		return
+	}
+
+	// ==================== 1. 展开为统一的条目列表 ====================
+	var items []model.BatchExecuteItem
+	switch {
+	case len(req.Items) > 0 && (req.CodeBase64 != "" || len(req.Inputs) > 0):
+		c.respondBatchRejected(ctx, 400, "ValidationError", "items 与 codebase64/inputs 只能二选一", startTime, requestID)
+		return
+	case len(req.Items) > 0:
+		items = req.Items
+	case req.CodeBase64 != "" && len(req.Inputs) > 0:
+		items = make([]model.BatchExecuteItem, len(req.Inputs))
+		for i, input := range req.Inputs {
+			items[i] = model.BatchExecuteItem{CodeBase64: req.CodeBase64, Input: input}
+		}
+	default:
+		c.respondBatchRejected(ctx, 400, "ValidationError", "请提供 codebase64 + inputs，或 items 数组", startTime, requestID)
+		return
+	}
+
+	maxBatchSize := c.config.Batch.MaxBatchSize
+	if len(items) > maxBatchSize {
+		utils.Warn("拒绝超大批量请求",
+			zap.String("request_id", requestID),
+			zap.Int("item_count", len(items)),
+			zap.Int("max_batch_size", maxBatchSize))
+		c.respondBatchRejected(ctx, 400, "ValidationError",
+			fmt.Sprintf("批量条目数超过限制: %d > %d", len(items), maxBatchSize), startTime, requestID)
+		return
+	}
+
+	results := make([]model.ExecuteResponse, len(items))
+	itemRequestIDs := make([]string, len(items))
+	for i := range items {
+		itemRequestIDs[i] = fmt.Sprintf("%s-%d", requestID, i)
+	}
+
+	// ==================== 2. 解码 + 预校验 + 预编译 ====================
+	// 相同 codebase64 只处理一次，编译结果写入执行器缓存
+	prepared := make(map[string]*batchPreparedCode)
+	pending := make([]int, 0, len(items)) // 待执行条目的下标（被拒绝的条目直接写入 results）
+	for i, item := range items {
+		p, ok := prepared[item.CodeBase64]
+		if !ok {
+			p = c.prepareBatchCode(ctx.Request.Context(), item.CodeBase64)
+			prepared[item.CodeBase64] = p
+		}
+		if p.err != nil {
+			results[i] = batchItemError(itemRequestIDs[i], p.err.Type, p.err.Message, p.err.Stack, 0)
+			continue
+		}
+		if item.Input == nil {
+			results[i] = batchItemError(itemRequestIDs[i], "ValidationError", "条目缺少 input", "", 0)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	token := ctx.GetString("token")
+	wsID := ctx.GetString("wsId")
+	email := ctx.GetString("userEmail")
+
+	var tokenInfo *model.TokenInfo
+	if tokenInfoValue, exists := ctx.Get("tokenInfo"); exists {
+		tokenInfo, _ = tokenInfoValue.(*model.TokenInfo)
+	}
+
+	// ==================== 3. Token 限流（按条目计费） ====================
+	if len(pending) > 0 && tokenInfo != nil && c.rateLimiterService != nil {
+		rateLimitConfig := tokenInfo.GetRateLimitConfig()
+		if !rateLimitConfig.Unlimited {
+			allowedCount, limitInfo, err := c.rateLimiterService.CheckLimitN(
+				ctx.Request.Context(), tokenInfo.AccessToken, rateLimitConfig, len(pending))
+			if err != nil {
+				// 限流器异常，记录日志但不阻塞请求（与 RateLimiterMiddleware 一致）
+				utils.Error("批量限流检查异常", zap.Error(err))
+			} else {
+				ctx.Header("X-RateLimit-Limit", fmt.Sprintf("%d", rateLimitConfig.PerMinute))
+				ctx.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", limitInfo.Remaining))
+				ctx.Header("X-RateLimit-Reset", limitInfo.ResetTime.Format(time.RFC3339))
+				ctx.Header("X-RateLimit-Type", "token")
+
+				if allowedCount == 0 {
+					utils.Warn("批量限流拒绝",
+						zap.String("token", utils.MaskToken(tokenInfo.AccessToken)),
+						zap.String("ws_id", tokenInfo.WsID),
+						zap.String("limit_type", limitInfo.LimitType),
+						zap.Int("item_count", len(pending)))
+
+					ginutil.SetRetryAfter(ctx, limitInfo.RetryAfter)
+					ginutil.RespondError(ctx, http.StatusTooManyRequests,
+						utils.ErrorTypeTokenRateLimit,
+						limitInfo.Message,
+						map[string]interface{}{
+							"retryAfter": limitInfo.RetryAfter,
+							"limitInfo": map[string]interface{}{
+								"type":      limitInfo.LimitType,
+								"limit":     rateLimitConfig.PerMinute,
+								"burst":     rateLimitConfig.Burst,
+								"window":    fmt.Sprintf("%d秒", rateLimitConfig.WindowSeconds),
+								"remaining": limitInfo.Remaining,
+							},
+						})
+					return
+				}
+
+				// 窗口剩余不足：超出部分直接返回限流错误
+				message := fmt.Sprintf("请求次数超限（限制：%d次/%d秒）", rateLimitConfig.PerMinute, rateLimitConfig.WindowSeconds)
+				for _, i := range pending[allowedCount:] {
+					results[i] = batchItemError(itemRequestIDs[i], utils.ErrorTypeTokenRateLimit, message, "", 0)
+				}
+				pending = pending[:allowedCount]
+			}
+		}
+	}
+
+	// ==================== 4. 配额扣减（按条目计费） ====================
+	if len(pending) > 0 && token != "" && c.quotaService != nil && tokenInfo != nil && tokenInfo.NeedsQuotaCheck() {
+		charged := 0
+		for _, i := range pending {
+			if _, _, err := c.quotaService.ConsumeQuota(ctx.Request.Context(), token, wsID, email, itemRequestIDs[i], true, nil, nil); err != nil {
+				utils.Warn("批量执行配额不足",
+					zap.String("token", utils.MaskToken(token)),
+					zap.String("request_id", requestID),
+					zap.Int("charged", charged),
+					zap.Int("requested", len(pending)),
+					zap.Error(err))
+				break
+			}
+			charged++
+		}
+
+		if charged == 0 {
+			ctx.JSON(429, model.ExecuteResponse{
+				Success: false,
+				Error: &model.ExecuteError{
+					Type:    "QuotaExceeded",
+					Message: "配额已用完，请联系管理员充值",
+				},
+				Timing: &model.ExecuteTiming{
+					TotalTime: time.Since(startTime).Milliseconds(),
+				},
+				Timestamp: utils.FormatTime(utils.Now()),
+				RequestID: requestID,
+			})
+			return
+		}
+
+		for _, i := range pending[charged:] {
+			results[i] = batchItemError(itemRequestIDs[i], "QuotaExceeded", "配额已用完，请联系管理员充值", "", 0)
+		}
+		pending = pending[:charged]
+	}
+
+	// ==================== 5. 并行执行 ====================
+	tasks := make([]sandbox.BatchTask, len(pending))
+	for t, i := range pending {
+		tasks[t] = sandbox.BatchTask{
+			RequestID: itemRequestIDs[i],
+			Code:      prepared[items[i].CodeBase64].code,
+			Input:     items[i].Input,
+		}
+	}
+
+	utils.Debug("开始批量执行",
+		zap.String("request_id", requestID),
+		zap.Int("item_count", len(items)),
+		zap.Int("task_count", len(tasks)),
+		zap.Int("distinct_codes", len(prepared)))
+
+	taskResults := c.executor.ExecuteBatch(ctx.Request.Context(), tasks, c.config.Batch.Concurrency)
+	policy := sandbox.SandboxPolicyFromContext(ctx.Request.Context())
+
+	for t, i := range pending {
+		p := prepared[items[i].CodeBase64]
+		taskResult := taskResults[t]
+		elapsed := taskResult.Duration.Milliseconds()
+
+		// 🆕 每个条目单独记录执行历史（Token 策略开启时，异步；request_id 与条目结果一致）
+		entry := &service.HistoryEntry{
+			RequestID:       itemRequestIDs[i],
+			Token:           token,
+			WsID:            wsID,
+			Email:           email,
+			Code:            p.code,
+			Input:           items[i].Input,
+			ExecutionTimeMs: elapsed,
+		}
+
+		if taskResult.Err != nil {
+			errorType := "RuntimeError"
+			errorMessage := taskResult.Err.Error()
+			errorStack := ""
+			execErr, isExecErr := taskResult.Err.(*model.ExecutionError)
+			if isExecErr {
+				errorType = execErr.Type
+				errorMessage = execErr.Message
+				errorStack = execErr.Stack
+			}
+			results[i] = batchItemError(itemRequestIDs[i], errorType, errorMessage, errorStack, elapsed)
+			if isExecErr {
+				results[i].Logs = execErr.Logs
+				results[i].LogsTruncated = execErr.LogsTruncated
+				results[i].Error.RetryAfter = execErr.RetryAfterSeconds()
+			}
+			entry.ErrorType, entry.ErrorMessage = errorType, errorMessage
+			entry.Logs, entry.LogsTruncated = results[i].Logs, results[i].LogsTruncated
+			c.historyService.Record(policy, entry)
+			if c.statsService != nil {
+				c.recordStats(itemRequestIDs[i], ctx, p.moduleInfo, p.code, elapsed, "failed")
+			}
+			continue
+		}
+
+		var result interface{}
+		if len(taskResult.Result.JSONData) > 0 {
+			result = json.RawMessage(taskResult.Result.JSONData)
+		} else {
+			result = taskResult.Result.Result
+		}
+		results[i] = model.ExecuteResponse{
+			Success: true,
+			Result:  result,
+			Timing: &model.ExecuteTiming{
+				ExecutionTime: elapsed,
+				TotalTime:     elapsed,
+			},
+			Timestamp:     utils.FormatTime(utils.Now()),
+			RequestID:     itemRequestIDs[i],
+			Logs:          taskResult.Result.Logs,
+			LogsTruncated: taskResult.Result.LogsTruncated,
+		}
+		entry.Result, entry.ResultJSON = taskResult.Result.Result, taskResult.Result.JSONData
+		entry.Logs, entry.LogsTruncated = taskResult.Result.Logs, taskResult.Result.LogsTruncated
+		c.historyService.Record(policy, entry)
+		if c.statsService != nil {
+			c.recordStats(itemRequestIDs[i], ctx, p.moduleInfo, p.code, elapsed, "success")
+		}
+	}
+
+	// ==================== 6. 汇总响应 ====================
+	succeeded := 0
+	for i := range results {
+		if results[i].Success {
+			succeeded++
+		}
+	}
+	totalTime := time.Since(startTime).Milliseconds()
+
+	utils.Info("批量执行完成",
+		zap.String("request_id", requestID),
+		zap.Int("total", len(items)),
+		zap.Int("succeeded", succeeded),
+		zap.Int("failed", len(items)-succeeded),
+		zap.Int64("total_time_ms", totalTime),
+		zap.String("ws_id", wsID))
+
+	ctx.JSON(200, model.BatchExecuteResponse{
+		Success:   true,
+		Total:     len(items),
+		Succeeded: succeeded,
+		Failed:    len(items) - succeeded,
+		Results:   results,
+		Timing: &model.ExecuteTiming{
+			ExecutionTime: totalTime,
+			TotalTime:     totalTime,
+		},
+		Timestamp: utils.FormatTime(utils.Now()),
+		RequestID: requestID,
+	})
+}
+
+// prepareBatchCode 解码并预编译批量条目的代码
+func (c *ExecutorController) prepareBatchCode(reqCtx context.Context, codeBase64 string) *batchPreparedCode {
+	code, decodeErr := decodeCodeBase64(codeBase64, c.executor.GetMaxCodeLength())
+	if decodeErr != nil {
+		return &batchPreparedCode{err: decodeErr}
+	}
+
+	if err := c.executor.PrepareCode(reqCtx, code); err != nil {
+		return &batchPreparedCode{err: toExecuteError(err, "ValidationError")}
+	}
+
+	return &batchPreparedCode{
+		code:       code,
+		moduleInfo: utils.ParseModuleUsage(code),
+	}
+}
+
+// decodeCodeBase64 解码 codebase64（含长度预检查，批量与异步任务共用）
+func decodeCodeBase64(codeBase64 string, maxCodeLength int) (string, *model.ExecuteError) {
+	// 🔥 Base64 长度预检查（DoS 防护，与 Execute 一致）
+	maxBase64Length := maxCodeLength*4/3 + 4
+	if len(codeBase64) > maxBase64Length {
+		return "", &model.ExecuteError{
+			Type: "ValidationError",
+			Message: fmt.Sprintf("代码 Base64 编码后过长: %d > %d 字节 (预计解码后将超过 %d 字节限制)",
+				len(codeBase64), maxBase64Length, maxCodeLength),
+		}
+	}
+
+	codeBytes, err := base64.StdEncoding.DecodeString(codeBase64)
+	if err != nil || len(codeBytes) == 0 {
+		return "", &model.ExecuteError{
+			Type:    "ValidationError",
+			Message: "代码Base64解码失败",
+		}
+	}
+
+	return string(codeBytes), nil
+}
+
+// toExecuteError 将执行器错误转换为响应错误（非 ExecutionError 使用 defaultType）
+func toExecuteError(err error, defaultType string) *model.ExecuteError {
+	if e, ok := err.(*model.ExecutionError); ok {
+		return &model.ExecuteError{Type: e.Type, Message: e.Message, Stack: e.Stack}
+	}
+	return &model.ExecuteError{Type: defaultType, Message: err.Error()}
+}
+
+// respondBatchRejected 整批拒绝时的响应（参数错误等）
+func (c *ExecutorController) respondBatchRejected(ctx *gin.Context, status int, errType, message string, startTime time.Time, requestID string) {
+	ctx.JSON(status, model.ExecuteResponse{
+		Success: false,
+		Error: &model.ExecuteError{
+			Type:    errType,
+			Message: message,
+		},
+		Timing: &model.ExecuteTiming{
+			TotalTime: time.Since(startTime).Milliseconds(),
+		},
+		Timestamp: utils.FormatTime(utils.Now()),
+		RequestID: requestID,
+	})
+}
+
+// batchItemError 构建单个条目的失败结果
+func batchItemError(requestID, errType, message, stack string, elapsed int64) model.ExecuteResponse {
+	return model.ExecuteResponse{
+		Success: false,
+		Error: &model.ExecuteError{
+			Type:    errType,
+			Message: message,
+			Stack:   stack,
+		},
+		Timing: &model.ExecuteTiming{
+			ExecutionTime: elapsed,
+			TotalTime:     elapsed,
+		},
+		Timestamp: utils.FormatTime(utils.Now()),
+		RequestID: requestID,
+	}
+}
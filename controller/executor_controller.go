@@ -12,8 +12,10 @@ import (
 
 	"flow-codeblock-go/config"
 	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
 	"flow-codeblock-go/service"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -21,23 +23,29 @@ import (
 
 // ExecutorController 执行器控制器
 type ExecutorController struct {
-	executor       *service.JSExecutor
-	config         *config.Config
-	tokenService   *service.TokenService
-	statsService   *service.StatsService       // 🆕 统计服务
-	quotaService   *service.QuotaService       // 🔥 配额服务
-	sessionService *service.PageSessionService // 🔐 Session服务
+	executor           *sandbox.JSExecutor
+	config             *config.Config
+	tokenService       *service.TokenService
+	statsService       *service.StatsService       // 🆕 统计服务
+	quotaService       *service.QuotaService       // 🔥 配额服务
+	sessionService     *service.PageSessionService // 🔐 Session服务
+	rateLimiterService *service.RateLimiterService // 🆕 限流服务（批量执行按条目限流）
+	historyService     *service.HistoryService     // 🆕 执行历史服务
+	functionService    *service.FunctionService    // 🆕 存储脚本服务（按名称调用）
 }
 
 // NewExecutorController 创建新的执行器控制器
-func NewExecutorController(executor *service.JSExecutor, cfg *config.Config, tokenService *service.TokenService, statsService *service.StatsService, quotaService *service.QuotaService, sessionService *service.PageSessionService) *ExecutorController {
+func NewExecutorController(executor *sandbox.JSExecutor, cfg *config.Config, tokenService *service.TokenService, statsService *service.StatsService, quotaService *service.QuotaService, sessionService *service.PageSessionService, rateLimiterService *service.RateLimiterService, historyService *service.HistoryService, functionService *service.FunctionService) *ExecutorController {
 	return &ExecutorController{
-		executor:       executor,
-		config:         cfg,
-		tokenService:   tokenService,
-		statsService:   statsService,   // 🆕 统计服务
-		quotaService:   quotaService,   // 🔥 配额服务
-		sessionService: sessionService, // 🔐 Session服务
+		executor:           executor,
+		config:             cfg,
+		tokenService:       tokenService,
+		statsService:       statsService,       // 🆕 统计服务
+		quotaService:       quotaService,       // 🔥 配额服务
+		sessionService:     sessionService,     // 🔐 Session服务
+		rateLimiterService: rateLimiterService, // 🆕 限流服务
+		historyService:     historyService,     // 🆕 执行历史服务
+		functionService:    functionService,    // 🆕 存储脚本服务
 	}
 }
 
@@ -75,8 +83,12 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 		return
 	}
 
+	// 🆕 分阶段耗时：认证阶段由 TokenAuthMiddleware 记录，解码 / 配额在这里记录，其余阶段由执行器记录
+	timeline := c.newRequestTimeline(ctx)
+
 	// 🔥 Base64 长度预检查（DoS 防护）
 	// 说明：Base64 编码后的长度约为原始长度的 4/3
+	decodeStart := time.Now()
 	// 在解码前检查可以避免浪费 CPU 和内存资源
 	maxBase64Length := c.executor.GetMaxCodeLength()*4/3 + 4 // +4 用于 padding
 	if len(req.CodeBase64) > maxBase64Length {
@@ -129,6 +141,34 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 	}
 
 	code := string(codeBytes)
+	timeline.Since(sandbox.PhaseDecode, decodeStart, "")
+
+	c.executeCode(ctx, code, req.Input, req.Debug, timeline, startTime)
+}
+
+// newRequestTimeline 创建本次请求的分阶段耗时记录（包含 TokenAuthMiddleware 记录的认证阶段）
+func (c *ExecutorController) newRequestTimeline(ctx *gin.Context) *sandbox.ExecutionTimeline {
+	timeline := c.executor.NewTimeline()
+	if authDuration, ok := ctx.Get("authDuration"); ok {
+		authCache := sandbox.PhaseCacheMiss
+		if source := ctx.GetString("authSource"); source == service.TokenSourceHot || source == service.TokenSourceWarm {
+			authCache = sandbox.PhaseCacheHit
+		}
+		timeline.Record(sandbox.PhaseAuth, authDuration.(time.Duration), authCache)
+	}
+	return timeline
+}
+
+// executeCode 扣减配额、执行代码并返回响应（记录统计和执行历史）
+// 🆕 代码执行接口和存储脚本调用接口（InvokeFunction）共用
+func (c *ExecutorController) executeCode(ctx *gin.Context, code string, input map[string]interface{}, debug bool, timeline *sandbox.ExecutionTimeline, startTime time.Time) {
+	requestID := ctx.GetString("request_id")
+	debugPhases := func() []model.ExecutionPhase {
+		if !debug {
+			return nil
+		}
+		return timeline.Phases()
+	}
 
 	// 🆕 解析模块使用情况
 	moduleInfo := utils.ParseModuleUsage(code)
@@ -156,7 +196,9 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 	// 🔥 只对需要配额检查的Token（count/hybrid类型）进行配额扣减
 	if token != "" && c.quotaService != nil && needsQuotaCheck {
 		// 注意：这里先传递nil，执行后再更新日志
+		quotaStart := time.Now()
 		_, _, err := c.quotaService.ConsumeQuota(ctx.Request.Context(), token, wsID, email, requestID, true, nil, nil)
+		timeline.Since(sandbox.PhaseQuota, quotaStart, "")
 		if err != nil {
 			utils.Warn("配额不足",
 				zap.String("token", utils.MaskToken(token)),
@@ -172,6 +214,7 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 				},
 				Timing: &model.ExecuteTiming{
 					TotalTime: time.Since(startTime).Milliseconds(),
+					Phases:    debugPhases(),
 				},
 				Timestamp: utils.FormatTime(utils.Now()),
 				RequestID: requestID,
@@ -191,7 +234,8 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 	// 🔥 执行代码：传递 HTTP 请求的 context 和 requestID
 	// 将 requestID 存入 context，供执行器使用作为 executionId
 	execCtx := context.WithValue(ctx.Request.Context(), utils.RequestIDKey, requestID)
-	executionResult, err := c.executor.Execute(execCtx, code, req.Input)
+	execCtx = sandbox.WithTimeline(execCtx, timeline)
+	executionResult, err := c.executor.Execute(execCtx, code, input)
 	totalTime := time.Since(startTime).Milliseconds()
 
 	if err != nil {
@@ -199,11 +243,17 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 		errorType := "RuntimeError"
 		errorMessage := err.Error()
 		errorStack := ""
+		var logs []model.ConsoleLogEntry
+		logsTruncated := false
+		retryAfter := 0
 
 		if execErr, ok := err.(*model.ExecutionError); ok {
 			errorType = execErr.Type
 			errorMessage = execErr.Message
 			errorStack = execErr.Stack // ✅ 提取stack信息
+			logs = execErr.Logs        // 🆕 失败前捕获的 console 输出
+			logsTruncated = execErr.LogsTruncated
+			retryAfter = execErr.RetryAfterSeconds() // 🆕 公平调度排队被拒绝
 		}
 
 		// 🆕 记录执行失败（带详细信息）
@@ -220,19 +270,45 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 			c.recordStats(requestID, ctx, moduleInfo, code, totalTime, "failed")
 		}
 
-		ctx.JSON(400, model.ExecuteResponse{
+		// 🆕 记录执行历史（Token 策略开启时，异步）
+		c.historyService.Record(sandbox.SandboxPolicyFromContext(ctx.Request.Context()), &service.HistoryEntry{
+			RequestID:       requestID,
+			Token:           token,
+			WsID:            wsID,
+			Email:           email,
+			Code:            code,
+			Input:           input,
+			ErrorType:       errorType,
+			ErrorMessage:    errorMessage,
+			Logs:            logs,
+			LogsTruncated:   logsTruncated,
+			ExecutionTimeMs: totalTime,
+		})
+
+		// 🆕 排队被拒绝返回 429 + Retry-After（按当前排队长度和平均执行时间估算）
+		statusCode := 400
+		if retryAfter > 0 {
+			statusCode = http.StatusTooManyRequests
+			ginutil.SetRetryAfter(ctx, retryAfter)
+		}
+
+		ctx.JSON(statusCode, model.ExecuteResponse{
 			Success: false,
 			Error: &model.ExecuteError{
-				Type:    errorType,
-				Message: errorMessage,
-				Stack:   errorStack, // ✅ 返回stack信息
+				Type:       errorType,
+				Message:    errorMessage,
+				Stack:      errorStack, // ✅ 返回stack信息
+				RetryAfter: retryAfter,
 			},
 			Timing: &model.ExecuteTiming{
 				ExecutionTime: totalTime,
 				TotalTime:     totalTime,
+				Phases:        debugPhases(),
 			},
-			Timestamp: utils.FormatTime(utils.Now()),
-			RequestID: requestID, // 🆕 添加请求ID
+			Timestamp:     utils.FormatTime(utils.Now()),
+			RequestID:     requestID, // 🆕 添加请求ID
+			Logs:          logs,
+			LogsTruncated: logsTruncated,
 		})
 		return
 	}
@@ -249,6 +325,21 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 		c.recordStats(requestID, ctx, moduleInfo, code, totalTime, "success")
 	}
 
+	// 🆕 记录执行历史（Token 策略开启时，异步）
+	c.historyService.Record(sandbox.SandboxPolicyFromContext(ctx.Request.Context()), &service.HistoryEntry{
+		RequestID:       requestID,
+		Token:           token,
+		WsID:            wsID,
+		Email:           email,
+		Code:            code,
+		Input:           input,
+		Result:          executionResult.Result,
+		ResultJSON:      executionResult.JSONData,
+		Logs:            executionResult.Logs,
+		LogsTruncated:   executionResult.LogsTruncated,
+		ExecutionTimeMs: totalTime,
+	})
+
 	// 🔥 使用预序列化的 JSON（避免重复序列化，降低内存压力）
 	var result interface{}
 	if len(executionResult.JSONData) > 0 {
@@ -265,9 +356,12 @@ func (c *ExecutorController) Execute(ctx *gin.Context) {
 		Timing: &model.ExecuteTiming{
 			ExecutionTime: totalTime,
 			TotalTime:     totalTime,
+			Phases:        debugPhases(),
 		},
-		Timestamp: utils.FormatTime(utils.Now()),
-		RequestID: requestID, // 🔄 统一使用 request_id
+		Timestamp:     utils.FormatTime(utils.Now()),
+		RequestID:     requestID, // 🔄 统一使用 request_id
+		Logs:          executionResult.Logs,
+		LogsTruncated: executionResult.LogsTruncated,
 	})
 }
 
@@ -431,7 +525,8 @@ func (c *ExecutorController) Health(ctx *gin.Context) {
 // Stats 执行统计
 func (c *ExecutorController) Stats(ctx *gin.Context) {
 	stats := c.executor.GetStats()
-	utils.RespondSuccess(ctx, map[string]interface{}{
+	sched := c.executor.GetSchedulerStats()
+	ginutil.RespondSuccess(ctx, map[string]interface{}{
 		"status":      "running",
 		"uptime":      time.Since(GetStartTime()).Seconds(),
 		"startTime":   utils.FormatTime(GetStartTime()),
@@ -445,7 +540,7 @@ func (c *ExecutorController) Stats(ctx *gin.Context) {
 			"executorStats": map[string]interface{}{
 				"currentExecutions": stats.CurrentExecutions,
 				"maxConcurrent":     c.executor.GetMaxConcurrent(),
-				"queueLength":       0,
+				"queueLength":       sched.Queued, // 🆕 公平调度器排队数
 				"total":             stats.TotalExecutions,
 				"successful":        stats.SuccessfulExecs,
 				"failed":            stats.FailedExecs,
@@ -454,10 +549,13 @@ func (c *ExecutorController) Stats(ctx *gin.Context) {
 				"asyncExecutions":   stats.AsyncExecutions,
 			},
 		},
+		"scheduler": sched,                      // 🆕 公平调度：各 Token/工作空间的并发、排队位置和等待时间
+		"phases":    c.executor.GetPhaseStats(), // 🆕 分阶段耗时：各阶段（按缓存命中情况区分）的累计次数和平均耗时
 		"cache": map[string]interface{}{
-			"codeCompilation":   c.executor.GetCacheStats(),
-			"codeValidation":    c.executor.GetValidationCacheStats(),
-			"runtimePoolHealth": c.executor.GetRuntimePoolHealth(),
+			"codeCompilation":     c.executor.GetCacheStats(),
+			"codeValidation":      c.executor.GetValidationCacheStats(),
+			"runtimePoolHealth":   c.executor.GetRuntimePoolHealth(),
+			"eventLoopPoolHealth": c.executor.GetEventLoopPoolHealth(),
 		},
 		"limits": map[string]interface{}{
 			"executionTimeout": fmt.Sprintf("%.0fs", c.executor.GetExecutionTimeout().Seconds()),
@@ -490,6 +588,7 @@ func (c *ExecutorController) Limits(ctx *gin.Context) {
 				"timeout":          int(c.executor.GetExecutionTimeout().Milliseconds()),
 				"timeoutStr":       fmt.Sprintf("%.0f秒", c.executor.GetExecutionTimeout().Seconds()),
 				"allowConsole":     c.config.Executor.AllowConsole,
+				"consoleMode":      c.config.Executor.ConsoleMode,
 			},
 			"concurrency": map[string]interface{}{
 				"maxConcurrent":  c.executor.GetMaxConcurrent(),
@@ -570,7 +669,7 @@ func (c *ExecutorController) Limits(ctx *gin.Context) {
 
 // SimpleHealth 简单健康检查
 func (c *ExecutorController) SimpleHealth(ctx *gin.Context) {
-	utils.RespondSuccess(ctx, map[string]interface{}{
+	ginutil.RespondSuccess(ctx, map[string]interface{}{
 		"status":  "healthy",
 		"service": "flow-codeblock-go",
 		"version": "1.0.0",
@@ -579,7 +678,7 @@ func (c *ExecutorController) SimpleHealth(ctx *gin.Context) {
 
 // Root 根路径信息
 func (c *ExecutorController) Root(ctx *gin.Context) {
-	utils.RespondSuccess(ctx, map[string]interface{}{
+	ginutil.RespondSuccess(ctx, map[string]interface{}{
 		"service":     "Flow-CodeBlock API (Go版本)",
 		"version":     "1.0.0",
 		"description": "基于Go+goja的高性能JavaScript代码执行服务",
@@ -613,7 +712,7 @@ func GetStartTime() time.Time {
 // recordStats 记录统计数据(辅助方法)
 func (c *ExecutorController) recordStats(requestID string, ctx *gin.Context, moduleInfo *utils.ModuleUsageInfo, code string, totalTime int64, status string) {
 	// 检测是否为异步代码
-	isAsync := c.executor.GetAnalyzer().IsLikelyAsync(code)
+	isAsync := c.executor.GetAnalyzer().AnalyzeCode(code).IsAsync
 
 	// 获取Token (从tokenInfo中提取AccessToken字段)
 	token := ""
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// InvokeFunction 按名称调用存储的脚本（POST /flow/functions/:name[@version|alias]）
+// 🆕 请求只包含 input，代码从当前 Token 所属工作空间的脚本库中解析；
+// 配额、限流、统计、执行历史与 POST /flow/codeblock 完全一致
+// 响应头 X-Function-Version 返回实际执行的版本号
+func (c *ExecutorController) InvokeFunction(ctx *gin.Context) {
+	startTime := time.Now()
+	requestID := ctx.GetString("request_id")
+	name, version := service.ParseFunctionRef(ctx.Param("name"))
+
+	utils.Info("脚本调用请求开始",
+		zap.String("request_id", requestID),
+		zap.String("function", name),
+		zap.String("version", version),
+		zap.String("ip", ctx.ClientIP()),
+		zap.String("ws_id", ctx.GetString("wsId")))
+
+	var req model.InvokeFunctionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.respondInvokeRejected(ctx, http.StatusBadRequest, &model.ExecuteError{
+			Type:    "ValidationError",
+			Message: fmt.Sprintf("请求参数错误: %v", err),
+		}, startTime, requestID)
+		return
+	}
+
+	timeline := c.newRequestTimeline(ctx)
+	resolveStart := time.Now()
+	fn, err := c.functionService.Resolve(ctx.Request.Context(), ctx.GetString("wsId"), name, version)
+	timeline.Since(sandbox.PhaseResolve, resolveStart, "")
+	if err != nil {
+		if errors.Is(err, service.ErrFunctionNotFound) || errors.Is(err, service.ErrFunctionVersionNotFound) {
+			c.respondInvokeRejected(ctx, http.StatusNotFound, &model.ExecuteError{
+				Type:    utils.ErrorTypeNotFound,
+				Message: fmt.Sprintf("%s: %s@%s", err.Error(), name, version),
+			}, startTime, requestID)
+			return
+		}
+		utils.Error("解析脚本失败", zap.String("request_id", requestID), zap.String("function", name), zap.Error(err))
+		c.respondInvokeRejected(ctx, http.StatusInternalServerError, &model.ExecuteError{
+			Type:    utils.ErrorTypeInternal,
+			Message: "解析脚本失败",
+		}, startTime, requestID)
+		return
+	}
+
+	ctx.Header("X-Function-Version", strconv.Itoa(fn.Version))
+	c.executeCode(ctx, fn.Code, req.Input, req.Debug, timeline, startTime)
+}
+
+// respondInvokeRejected 调用在执行前被拒绝时的响应（与代码执行接口的错误格式一致）
+func (c *ExecutorController) respondInvokeRejected(ctx *gin.Context, status int, execErr *model.ExecuteError, startTime time.Time, requestID string) {
+	ctx.JSON(status, model.ExecuteResponse{
+		Success: false,
+		Error:   execErr,
+		Timing: &model.ExecuteTiming{
+			TotalTime: time.Since(startTime).Milliseconds(),
+		},
+		Timestamp: utils.FormatTime(utils.Now()),
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Profile 在性能分析下执行代码（管理员接口）
+// 🆕 用于排查"脚本慢"：返回执行结果 + JS 函数级的火焰图 / 热点函数 / 热点行 + pprof
+//   - 默认返回 JSON（火焰图可在测试工具中查看）
+//   - ?format=pprof 直接下载 pprof 文件（go tool pprof -http=: profile.pb.gz）
+//
+// 不扣减配额，使用全局默认的沙箱限制
+func (c *ExecutorController) Profile(ctx *gin.Context) {
+	startTime := time.Now()
+	requestID := ctx.GetString("request_id")
+
+	var req model.ProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.respondBatchRejected(ctx, http.StatusBadRequest, "ValidationError",
+			fmt.Sprintf("请求参数错误: %v", err), startTime, requestID)
+		return
+	}
+
+	code, decodeErr := decodeCodeBase64(req.CodeBase64, c.executor.GetMaxCodeLength())
+	if decodeErr != nil {
+		c.respondBatchRejected(ctx, http.StatusBadRequest, decodeErr.Type, decodeErr.Message, startTime, requestID)
+		return
+	}
+
+	execCtx := context.WithValue(ctx.Request.Context(), utils.RequestIDKey, requestID)
+	executionResult, prof, err := c.executor.ExecuteWithProfile(execCtx, code, req.Input)
+	totalTime := time.Since(startTime).Milliseconds()
+
+	if execErr, ok := err.(*model.ExecutionError); ok && execErr.Type == "ProfilerBusyError" {
+		c.respondBatchRejected(ctx, http.StatusConflict, execErr.Type, execErr.Message, startTime, requestID)
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("request_id", requestID),
+		zap.Int64("total_time_ms", totalTime),
+		zap.Bool("success", err == nil),
+	}
+	if prof != nil {
+		fields = append(fields, zap.Int64("sample_count", prof.SampleCount))
+	}
+	utils.Info("性能分析执行完成", fields...)
+
+	// 🔥 直接下载 pprof 文件（执行失败时同样返回已采集的部分）
+	if ctx.Query("format") == "pprof" {
+		if prof == nil {
+			ginutil.RespondError(ctx, http.StatusInternalServerError,
+				utils.ErrorTypeInternal, "性能分析结果生成失败", nil)
+			return
+		}
+		data, decodeErr := base64.StdEncoding.DecodeString(prof.Pprof)
+		if decodeErr != nil {
+			ginutil.RespondError(ctx, http.StatusInternalServerError,
+				utils.ErrorTypeInternal, "性能分析结果生成失败", nil)
+			return
+		}
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="profile-%s.pb.gz"`, requestID))
+		ctx.Data(http.StatusOK, "application/octet-stream", data)
+		return
+	}
+
+	resp := model.ProfileResponse{
+		ExecuteResponse: model.ExecuteResponse{
+			Success: err == nil,
+			Timing: &model.ExecuteTiming{
+				ExecutionTime: totalTime,
+				TotalTime:     totalTime,
+			},
+			Timestamp: utils.FormatTime(utils.Now()),
+			RequestID: requestID,
+		},
+		Profile: prof,
+	}
+
+	if err != nil {
+		resp.Error = toExecuteError(err, "RuntimeError")
+		if execErr, ok := err.(*model.ExecutionError); ok {
+			resp.Logs = execErr.Logs
+			resp.LogsTruncated = execErr.LogsTruncated
+		}
+		ctx.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if len(executionResult.JSONData) > 0 {
+		resp.Result = json.RawMessage(executionResult.JSONData)
+	} else {
+		resp.Result = executionResult.Result
+	}
+	resp.Logs = executionResult.Logs
+	resp.LogsTruncated = executionResult.LogsTruncated
+	ctx.JSON(http.StatusOK, resp)
+}
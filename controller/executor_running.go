@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"net/http"
+
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRunning 获取正在执行的代码（管理员接口）
+// 🆕 包含 Token（脱敏）、工作空间、代码哈希、执行路径、已执行时间和进行中的 fetch
+func (c *ExecutorController) ListRunning(ctx *gin.Context) {
+	executions := c.executor.ListRunningExecutions()
+	ginutil.RespondSuccess(ctx, map[string]interface{}{
+		"total":      len(executions),
+		"executions": executions,
+	}, "")
+}
+
+// Kill 终止正在执行的代码（管理员接口，按 GET /flow/executions/running 返回的 executionId）
+// 🆕 中断 Runtime 并取消执行 context（进行中的 fetch 随之中止），原调用方收到 KilledError
+func (c *ExecutorController) Kill(ctx *gin.Context) {
+	executionID := ctx.Param("execution_id")
+	if executionID == "" {
+		ginutil.RespondError(ctx, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"缺少 execution_id",
+			nil)
+		return
+	}
+
+	killed, err := c.executor.KillExecution(executionID)
+	if err != nil {
+		ginutil.RespondError(ctx, http.StatusNotFound,
+			utils.ErrorTypeNotFound,
+			err.Error(),
+			nil)
+		return
+	}
+
+	ginutil.RespondSuccess(ctx, killed, "执行已终止")
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Validate 校验代码（只做静态分析，不执行、不扣减配额）
+// 🆕 供编辑器保存前检查：报告全部问题（含行列号）、执行路由和模块使用情况
+func (c *ExecutorController) Validate(ctx *gin.Context) {
+	startTime := time.Now()
+	requestID := ctx.GetString("request_id")
+
+	var req model.ValidateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(ctx, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			fmt.Sprintf("请求参数错误: %v", err),
+			nil)
+		return
+	}
+
+	code, decodeErr := decodeCodeBase64(req.CodeBase64, c.executor.GetMaxCodeLength())
+	if decodeErr != nil {
+		ginutil.RespondError(ctx, http.StatusBadRequest, decodeErr.Type, decodeErr.Message, nil)
+		return
+	}
+
+	report := c.executor.ValidateCodeReport(ctx.Request.Context(), code)
+
+	utils.Debug("代码校验完成",
+		zap.String("request_id", requestID),
+		zap.Bool("valid", report.Valid),
+		zap.Int("finding_count", len(report.Findings)),
+		zap.String("route", report.Route.Type),
+		zap.Int64("elapsed_ms", time.Since(startTime).Milliseconds()))
+
+	ginutil.RespondSuccess(ctx, report, "")
+}
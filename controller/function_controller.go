@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FunctionController 存储脚本控制器
+// 🆕 脚本按工作空间隔离：同一工作空间的 Token 共享脚本库
+type FunctionController struct {
+	functionService *service.FunctionService
+	executor        *sandbox.JSExecutor
+}
+
+// NewFunctionController 创建存储脚本控制器
+func NewFunctionController(functionService *service.FunctionService, executor *sandbox.JSExecutor) *FunctionController {
+	return &FunctionController{
+		functionService: functionService,
+		executor:        executor,
+	}
+}
+
+// Create 创建脚本（同时发布版本 1，latest 指向版本 1）
+func (fc *FunctionController) Create(c *gin.Context) {
+	var req model.CreateFunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	code, decodeErr := decodeCodeBase64(req.CodeBase64, fc.executor.GetMaxCodeLength())
+	if decodeErr != nil {
+		ginutil.RespondError(c, http.StatusBadRequest, decodeErr.Type, decodeErr.Message, nil)
+		return
+	}
+
+	detail, err := fc.functionService.Create(c.Request.Context(), c.GetString("wsId"), c.GetString("userEmail"), &req, code)
+	if err != nil {
+		fc.respondError(c, "创建脚本失败", req.Name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "脚本创建成功")
+}
+
+// List 获取当前工作空间的全部脚本
+func (fc *FunctionController) List(c *gin.Context) {
+	functions, err := fc.functionService.List(c.Request.Context(), c.GetString("wsId"))
+	if err != nil {
+		fc.respondError(c, "查询脚本列表失败", "", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, map[string]interface{}{
+		"total":     len(functions),
+		"functions": functions,
+	}, "")
+}
+
+// Get 获取脚本详情（别名 + 版本列表）
+func (fc *FunctionController) Get(c *gin.Context) {
+	name := c.Param("name")
+	detail, err := fc.functionService.Get(c.Request.Context(), c.GetString("wsId"), name)
+	if err != nil {
+		fc.respondError(c, "查询脚本失败", name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "")
+}
+
+// Update 更新脚本说明
+func (fc *FunctionController) Update(c *gin.Context) {
+	name := c.Param("name")
+
+	var req model.UpdateFunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	detail, err := fc.functionService.Update(c.Request.Context(), c.GetString("wsId"), name, &req)
+	if err != nil {
+		fc.respondError(c, "更新脚本失败", name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "脚本更新成功")
+}
+
+// Delete 删除脚本及其全部版本和别名
+func (fc *FunctionController) Delete(c *gin.Context) {
+	name := c.Param("name")
+	if err := fc.functionService.Delete(c.Request.Context(), c.GetString("wsId"), name); err != nil {
+		fc.respondError(c, "删除脚本失败", name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, nil, "脚本已删除")
+}
+
+// Publish 发布新版本（latest 指向新版本）
+func (fc *FunctionController) Publish(c *gin.Context) {
+	name := c.Param("name")
+
+	var req model.PublishFunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	code, decodeErr := decodeCodeBase64(req.CodeBase64, fc.executor.GetMaxCodeLength())
+	if decodeErr != nil {
+		ginutil.RespondError(c, http.StatusBadRequest, decodeErr.Type, decodeErr.Message, nil)
+		return
+	}
+
+	version, err := fc.functionService.Publish(c.Request.Context(), c.GetString("wsId"), name, c.GetString("userEmail"), &req, code)
+	if err != nil {
+		fc.respondError(c, "发布脚本版本失败", name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, version, "版本发布成功")
+}
+
+// GetVersion 获取指定版本的代码（版本号或别名）
+func (fc *FunctionController) GetVersion(c *gin.Context) {
+	name := c.Param("name")
+	detail, err := fc.functionService.GetVersion(c.Request.Context(), c.GetString("wsId"), name, c.Param("version"))
+	if err != nil {
+		fc.respondError(c, "查询脚本版本失败", name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "")
+}
+
+// SetAlias 移动别名到指定版本（如 prod）
+func (fc *FunctionController) SetAlias(c *gin.Context) {
+	name := c.Param("name")
+
+	var req model.SetFunctionAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	alias, err := fc.functionService.SetAlias(c.Request.Context(), c.GetString("wsId"), name, c.Param("alias"), req.Version)
+	if err != nil {
+		fc.respondError(c, "设置脚本别名失败", name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, alias, "别名设置成功")
+}
+
+// DeleteAlias 删除别名（latest 不能删除）
+func (fc *FunctionController) DeleteAlias(c *gin.Context) {
+	name := c.Param("name")
+	if err := fc.functionService.DeleteAlias(c.Request.Context(), c.GetString("wsId"), name, c.Param("alias")); err != nil {
+		fc.respondError(c, "删除脚本别名失败", name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, nil, "别名已删除")
+}
+
+// Rollback 回滚别名（默认 latest）到指定版本或上一个版本
+func (fc *FunctionController) Rollback(c *gin.Context) {
+	name := c.Param("name")
+
+	var req model.RollbackFunctionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ginutil.RespondError(c, http.StatusBadRequest,
+				utils.ErrorTypeValidation,
+				"请求参数错误: "+err.Error(),
+				nil)
+			return
+		}
+	}
+
+	alias, err := fc.functionService.Rollback(c.Request.Context(), c.GetString("wsId"), name, &req)
+	if err != nil {
+		fc.respondError(c, "回滚脚本失败", name, err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, alias, "已回滚到版本 "+strconv.Itoa(alias.Version))
+}
+
+// respondError 按错误类型返回 404 / 409 / 400（ExecutionError）/ 500
+func (fc *FunctionController) respondError(c *gin.Context, action, name string, err error) {
+	var execErr *model.ExecutionError
+	switch {
+	case errors.Is(err, service.ErrFunctionNotFound), errors.Is(err, service.ErrFunctionVersionNotFound):
+		ginutil.RespondError(c, http.StatusNotFound, utils.ErrorTypeNotFound, err.Error(), nil)
+	case errors.Is(err, repository.ErrFunctionNameExists):
+		ginutil.RespondError(c, http.StatusConflict, utils.ErrorTypeValidation, err.Error(), nil)
+	case errors.As(err, &execErr):
+		// 参数校验失败，或发布时代码校验 / 编译失败（SyntaxError、SecurityError 等）
+		ginutil.RespondError(c, http.StatusBadRequest, execErr.Type, action+": "+execErr.Message, nil)
+	default:
+		utils.Error(action, zap.String("ws_id", c.GetString("wsId")), zap.String("name", name), zap.Error(err))
+		ginutil.RespondError(c, http.StatusInternalServerError, utils.ErrorTypeInternal, action, nil)
+	}
+}
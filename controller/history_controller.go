@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// HistoryController 执行历史控制器
+// 🆕 管理员可查询所有 Token 的执行历史；Token 持有者只能查询自己的
+type HistoryController struct {
+	historyService *service.HistoryService
+	cleanupService *service.HistoryCleanupService // HISTORY_ENABLED=false 时为 nil
+}
+
+// NewHistoryController 创建执行历史控制器
+func NewHistoryController(historyService *service.HistoryService, cleanupService *service.HistoryCleanupService) *HistoryController {
+	return &HistoryController{
+		historyService: historyService,
+		cleanupService: cleanupService,
+	}
+}
+
+// List 查询执行历史（管理员接口，可按 token / ws_id 过滤）
+func (hc *HistoryController) List(c *gin.Context) {
+	var req model.HistoryQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+	hc.respondList(c, &req)
+}
+
+// ListOwn 查询当前 Token 的执行历史（Token 接口）
+func (hc *HistoryController) ListOwn(c *gin.Context) {
+	var req model.HistoryQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+	req.Token = c.GetString("token") // 🔒 只能查询自己的记录
+	req.WsID = ""
+	hc.respondList(c, &req)
+}
+
+// Get 获取执行历史详情（管理员接口）
+func (hc *HistoryController) Get(c *gin.Context) {
+	hc.respondDetail(c, "")
+}
+
+// GetOwn 获取当前 Token 的执行历史详情（Token 接口）
+func (hc *HistoryController) GetOwn(c *gin.Context) {
+	hc.respondDetail(c, c.GetString("token"))
+}
+
+// respondList 分页查询并返回
+func (hc *HistoryController) respondList(c *gin.Context, req *model.HistoryQueryRequest) {
+	if req.Status != "" && req.Status != model.HistoryStatusSuccess && req.Status != model.HistoryStatusFailed {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"无效的 status（可选值: success, failed）",
+			nil)
+		return
+	}
+
+	records, total, err := hc.historyService.Query(c.Request.Context(), req)
+	if err != nil {
+		utils.Error("查询执行历史失败", zap.Error(err))
+		ginutil.RespondError(c, http.StatusInternalServerError,
+			utils.ErrorTypeInternal,
+			"查询执行历史失败: "+err.Error(),
+			nil)
+		return
+	}
+
+	page, pageSize := repository.HistoryPagination(req)
+	ginutil.RespondSuccess(c, map[string]interface{}{
+		"records":     records,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + pageSize - 1) / pageSize,
+	}, "")
+}
+
+// respondDetail 获取详情并返回（token 不为空时只返回该 Token 的记录）
+func (hc *HistoryController) respondDetail(c *gin.Context, token string) {
+	requestID := c.Param("request_id")
+	if requestID == "" {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"缺少 request_id",
+			nil)
+		return
+	}
+
+	detail, err := hc.historyService.Get(c.Request.Context(), requestID, token)
+	if err != nil {
+		if errors.Is(err, service.ErrHistoryNotFound) {
+			ginutil.RespondError(c, http.StatusNotFound,
+				utils.ErrorTypeNotFound,
+				err.Error(),
+				nil)
+			return
+		}
+		utils.Error("查询执行历史详情失败", zap.String("request_id", requestID), zap.Error(err))
+		ginutil.RespondError(c, http.StatusInternalServerError,
+			utils.ErrorTypeInternal,
+			"查询执行历史失败",
+			nil)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "")
+}
+
+// GetCleanupStats 获取执行历史清理统计信息
+func (hc *HistoryController) GetCleanupStats(c *gin.Context) {
+	if hc.cleanupService == nil {
+		ginutil.RespondSuccess(c, map[string]interface{}{
+			"enabled": false,
+			"message": "执行历史清理服务未启用",
+		}, "")
+		return
+	}
+
+	stats := hc.cleanupService.GetStats()
+	stats["enabled"] = true
+	ginutil.RespondSuccess(c, stats, "")
+}
+
+// TriggerCleanup 手动触发执行历史清理
+func (hc *HistoryController) TriggerCleanup(c *gin.Context) {
+	if hc.cleanupService == nil {
+		ginutil.RespondError(c, http.StatusServiceUnavailable,
+			utils.ErrorTypeInternal,
+			"执行历史清理服务未启用",
+			nil)
+		return
+	}
+
+	// 异步触发清理
+	hc.cleanupService.TriggerCleanup()
+
+	ginutil.RespondSuccess(c, map[string]interface{}{
+		"message": "清理任务已提交，正在后台执行",
+	}, "清理任务已启动")
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// JobController 异步任务控制器
+type JobController struct {
+	jobService   *service.JobService
+	executor     *sandbox.JSExecutor
+	quotaService *service.QuotaService // 🔥 配额服务（提交时扣减）
+}
+
+// NewJobController 创建异步任务控制器
+func NewJobController(jobService *service.JobService, executor *sandbox.JSExecutor, quotaService *service.QuotaService) *JobController {
+	return &JobController{
+		jobService:   jobService,
+		executor:     executor,
+		quotaService: quotaService,
+	}
+}
+
+// Submit 提交异步任务
+// 🆕 立即返回 202 + job_id，任务在后台 worker 池中执行
+// 🔥 处理顺序：解码 → 预校验/预编译 → 预留队列位置 → 扣减配额 → 入队
+// 代码或 callback_url 无效、队列已满时不扣减配额；配额不足时释放预留的队列位置
+func (jc *JobController) Submit(ctx *gin.Context) {
+	startTime := time.Now()
+	requestID := ctx.GetString("request_id")
+
+	utils.Info("异步任务提交请求开始",
+		zap.String("request_id", requestID),
+		zap.String("ip", ctx.ClientIP()),
+		zap.String("ws_id", ctx.GetString("wsId")),
+		zap.String("email", ctx.GetString("userEmail")))
+
+	var req model.JobSubmitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.Warn("异步任务请求参数错误",
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		jc.respondRejected(ctx, 400, &model.ExecuteError{
+			Type:    "ValidationError",
+			Message: fmt.Sprintf("请求参数错误: %v", err),
+		}, startTime, requestID)
+		return
+	}
+
+	// 1. 解码
+	code, decodeErr := decodeCodeBase64(req.CodeBase64, jc.executor.GetMaxCodeLength())
+	if decodeErr != nil {
+		jc.respondRejected(ctx, 400, decodeErr, startTime, requestID)
+		return
+	}
+
+	// 2. 预校验 + 预编译（语法错误、安全检查在提交时即返回）
+	if err := jc.executor.PrepareCode(ctx.Request.Context(), code); err != nil {
+		jc.respondRejected(ctx, 400, toExecuteError(err, "ValidationError"), startTime, requestID)
+		return
+	}
+
+	token := ctx.GetString("token")
+	wsID := ctx.GetString("wsId")
+	email := ctx.GetString("userEmail")
+
+	// 3. 校验 callback_url、服务可用性并预留队列位置（预留成功后入队不会失败）
+	reservation, err := jc.jobService.Reserve(ctx.Request.Context(), token, requestID, req.CallbackURL)
+	if err != nil {
+		status := http.StatusInternalServerError
+		errType := utils.ErrorTypeInternal
+		switch err {
+		case service.ErrInvalidCallbackURL:
+			status, errType = http.StatusBadRequest, utils.ErrorTypeValidation
+		case service.ErrJobQueueFull, service.ErrJobServiceDisabled:
+			status, errType = http.StatusServiceUnavailable, utils.ErrorTypeServiceUnavail
+		}
+		utils.Warn("异步任务提交失败",
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		jc.respondRejected(ctx, status, &model.ExecuteError{Type: errType, Message: err.Error()}, startTime, requestID)
+		return
+	}
+
+	// 4. 配额扣减（与同步接口一致：提交即消耗）
+	needsQuotaCheck := false
+	if tokenInfoValue, exists := ctx.Get("tokenInfo"); exists {
+		if tokenInfo, ok := tokenInfoValue.(*model.TokenInfo); ok {
+			needsQuotaCheck = tokenInfo.NeedsQuotaCheck()
+		} else {
+			needsQuotaCheck = true
+		}
+	}
+	if token != "" && jc.quotaService != nil && needsQuotaCheck {
+		if _, _, err := jc.quotaService.ConsumeQuota(ctx.Request.Context(), token, wsID, email, requestID, true, nil, nil); err != nil {
+			utils.Warn("配额不足",
+				zap.String("token", utils.MaskToken(token)),
+				zap.String("request_id", requestID),
+				zap.Error(err))
+			jc.jobService.Release(ctx.Request.Context(), reservation)
+			jc.respondRejected(ctx, 429, &model.ExecuteError{
+				Type:    "QuotaExceeded",
+				Message: "配额已用完，请联系管理员充值",
+			}, startTime, requestID)
+			return
+		}
+	}
+
+	// 5. 入队（预留后服务开始关闭时返回已失败的任务状态）
+	info := jc.jobService.Enqueue(ctx.Request.Context(), reservation, wsID, email, code, req.Input)
+
+	ginutil.RespondSuccessWithCode(ctx, http.StatusAccepted, info, "任务已提交")
+}
+
+// Get 查询异步任务状态和结果
+func (jc *JobController) Get(ctx *gin.Context) {
+	jobID := ctx.Param("id")
+	if jobID == "" {
+		ginutil.RespondError(ctx, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"缺少任务ID",
+			nil)
+		return
+	}
+
+	info, err := jc.jobService.Get(ctx.Request.Context(), jobID, ctx.GetString("token"))
+	if err != nil {
+		switch err {
+		case service.ErrJobNotFound:
+			ginutil.RespondError(ctx, http.StatusNotFound,
+				utils.ErrorTypeNotFound,
+				err.Error(),
+				nil)
+		case service.ErrJobServiceDisabled:
+			ginutil.RespondError(ctx, http.StatusServiceUnavailable,
+				utils.ErrorTypeServiceUnavail,
+				err.Error(),
+				nil)
+		default:
+			utils.Error("查询异步任务失败", zap.String("job_id", jobID), zap.Error(err))
+			ginutil.RespondError(ctx, http.StatusInternalServerError,
+				utils.ErrorTypeInternal,
+				"查询任务失败",
+				nil)
+		}
+		return
+	}
+
+	ginutil.RespondSuccess(ctx, info, "")
+}
+
+// respondRejected 提交被拒绝时的响应（与同步执行接口的错误格式一致）
+func (jc *JobController) respondRejected(ctx *gin.Context, status int, execErr *model.ExecuteError, startTime time.Time, requestID string) {
+	ctx.JSON(status, model.ExecuteResponse{
+		Success: false,
+		Error:   execErr,
+		Timing: &model.ExecuteTiming{
+			TotalTime: time.Since(startTime).Milliseconds(),
+		},
+		Timestamp: utils.FormatTime(utils.Now()),
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"net/http"
+
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsController Prometheus 指标控制器
+type MetricsController struct {
+	metricsService *service.MetricsService
+}
+
+// NewMetricsController 创建 Prometheus 指标控制器
+func NewMetricsController(metricsService *service.MetricsService) *MetricsController {
+	return &MetricsController{
+		metricsService: metricsService,
+	}
+}
+
+// Metrics 导出 Prometheus 文本格式指标
+// GET /metrics
+func (c *MetricsController) Metrics(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, utils.PrometheusContentType, c.metricsService.Render())
+}
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ScheduleController 定时执行控制器
+// 🆕 计划归属于创建它的 Token，只能管理自己的计划
+type ScheduleController struct {
+	cronService *service.CronService
+}
+
+// NewScheduleController 创建定时执行控制器
+func NewScheduleController(cronService *service.CronService) *ScheduleController {
+	return &ScheduleController{cronService: cronService}
+}
+
+// Create 创建定时执行计划
+func (sc *ScheduleController) Create(c *gin.Context) {
+	var req model.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	tokenInfo, ok := c.Get("tokenInfo")
+	if !ok {
+		ginutil.RespondError(c, http.StatusUnauthorized, utils.ErrorTypeAuthentication, "认证失败：未找到Token信息", nil)
+		return
+	}
+
+	detail, err := sc.cronService.Create(c.Request.Context(), tokenInfo.(*model.TokenInfo), &req)
+	if err != nil {
+		sc.respondError(c, "创建定时执行计划失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "定时执行计划创建成功")
+}
+
+// List 获取当前 Token 的定时执行计划
+func (sc *ScheduleController) List(c *gin.Context) {
+	schedules, err := sc.cronService.List(c.Request.Context(), c.GetString("token"))
+	if err != nil {
+		sc.respondError(c, "查询定时执行计划失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, map[string]interface{}{
+		"total":     len(schedules),
+		"schedules": schedules,
+	}, "")
+}
+
+// Get 获取定时执行计划详情
+func (sc *ScheduleController) Get(c *gin.Context) {
+	id, ok := sc.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := sc.cronService.Get(c.Request.Context(), c.GetString("token"), id)
+	if err != nil {
+		sc.respondError(c, "查询定时执行计划失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "")
+}
+
+// Update 更新定时执行计划
+func (sc *ScheduleController) Update(c *gin.Context) {
+	id, ok := sc.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	detail, err := sc.cronService.Update(c.Request.Context(), c.GetString("token"), id, &req)
+	if err != nil {
+		sc.respondError(c, "更新定时执行计划失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "定时执行计划更新成功")
+}
+
+// Delete 删除定时执行计划及其执行记录
+func (sc *ScheduleController) Delete(c *gin.Context) {
+	id, ok := sc.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	if err := sc.cronService.Delete(c.Request.Context(), c.GetString("token"), id); err != nil {
+		sc.respondError(c, "删除定时执行计划失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, nil, "定时执行计划已删除")
+}
+
+// Run 立即触发一次（后台执行，结果见执行记录）
+func (sc *ScheduleController) Run(c *gin.Context) {
+	id, ok := sc.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	runID, err := sc.cronService.RunNow(c.Request.Context(), c.GetString("token"), id)
+	if err != nil {
+		sc.respondError(c, "触发定时执行失败", err)
+		return
+	}
+
+	ginutil.RespondSuccessWithCode(c, http.StatusAccepted, map[string]interface{}{
+		"schedule_id": id,
+		"run_id":      runID,
+	}, "已触发")
+}
+
+// ListRuns 分页查询执行记录
+func (sc *ScheduleController) ListRuns(c *gin.Context) {
+	id, ok := sc.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	var req model.ScheduleRunQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+	switch req.Status {
+	case "", model.ScheduleRunRunning, model.ScheduleRunSuccess, model.ScheduleRunFailed, model.ScheduleRunSkipped:
+	default:
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"无效的 status（可选值: running, success, failed, skipped）",
+			nil)
+		return
+	}
+
+	runs, total, err := sc.cronService.ListRuns(c.Request.Context(), c.GetString("token"), id, &req)
+	if err != nil {
+		sc.respondError(c, "查询定时执行记录失败", err)
+		return
+	}
+
+	page, pageSize := repository.SchedulePagination(&req)
+	ginutil.RespondSuccess(c, map[string]interface{}{
+		"runs":        runs,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + pageSize - 1) / pageSize,
+	}, "")
+}
+
+// GetStats 定时执行统计（管理员接口）
+func (sc *ScheduleController) GetStats(c *gin.Context) {
+	ginutil.RespondSuccess(c, sc.cronService.GetStats(), "")
+}
+
+// scheduleID 解析路径中的计划ID
+func (sc *ScheduleController) scheduleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"无效的计划ID",
+			nil)
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError 按错误类型返回 404 / 503 / 400（ExecutionError）/ 500
+func (sc *ScheduleController) respondError(c *gin.Context, action string, err error) {
+	var execErr *model.ExecutionError
+	switch {
+	case errors.Is(err, service.ErrScheduleNotFound):
+		ginutil.RespondError(c, http.StatusNotFound, utils.ErrorTypeNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrCronDisabled):
+		ginutil.RespondError(c, http.StatusServiceUnavailable, utils.ErrorTypeServiceUnavail, err.Error(), nil)
+	case errors.As(err, &execErr):
+		ginutil.RespondError(c, http.StatusBadRequest, execErr.Type, action+": "+execErr.Message, nil)
+	default:
+		utils.Error(action, zap.String("token", utils.MaskToken(c.GetString("token"))), zap.Error(err))
+		ginutil.RespondError(c, http.StatusInternalServerError, utils.ErrorTypeInternal, action, nil)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"flow-codeblock-go/model"
 	"flow-codeblock-go/service"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -21,6 +22,7 @@ type TokenController struct {
 	quotaCleanupService *service.QuotaCleanupService // 🔥 配额清理服务
 	sessionService      *service.PageSessionService  // 🔐 Session服务
 	verifyService       *service.TokenVerifyService  // 🔐 验证码服务
+	policyService       *service.PolicyService       // 🆕 沙箱策略服务
 }
 
 // NewTokenController 创建Token控制器
@@ -33,6 +35,7 @@ func NewTokenController(
 	quotaCleanupService *service.QuotaCleanupService,
 	sessionService *service.PageSessionService,
 	verifyService *service.TokenVerifyService,
+	policyService *service.PolicyService,
 ) *TokenController {
 	return &TokenController{
 		tokenService:        tokenService,
@@ -43,6 +46,7 @@ func NewTokenController(
 		quotaCleanupService: quotaCleanupService,
 		sessionService:      sessionService,
 		verifyService:       verifyService,
+		policyService:       policyService,
 	}
 }
 
@@ -50,31 +54,43 @@ func NewTokenController(
 func (tc *TokenController) CreateToken(c *gin.Context) {
 	var req model.CreateTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"请求参数错误: "+err.Error(),
 			nil)
 		return
 	}
 
+	// 🆕 校验沙箱策略（引用的命名策略必须存在）
+	if req.PolicyName != nil && *req.PolicyName == "" {
+		req.PolicyName = nil
+	}
+	if err := tc.policyService.ValidateTokenPolicy(c.Request.Context(), req.PolicyName, req.Policy); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"沙箱策略无效: "+err.Error(),
+			nil)
+		return
+	}
+
 	tokenInfo, err := tc.tokenService.CreateToken(c.Request.Context(), &req)
 	if err != nil {
 		utils.Error("创建Token失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"创建Token失败: "+err.Error(),
 			nil)
 		return
 	}
 
-	utils.RespondSuccess(c, tokenInfo, "Token创建成功")
+	ginutil.RespondSuccess(c, tokenInfo, "Token创建成功")
 }
 
 // UpdateToken 更新Token
 func (tc *TokenController) UpdateToken(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"缺少token参数",
 			nil)
@@ -83,7 +99,7 @@ func (tc *TokenController) UpdateToken(c *gin.Context) {
 
 	var req model.UpdateTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"请求参数错误: "+err.Error(),
 			nil)
@@ -93,21 +109,21 @@ func (tc *TokenController) UpdateToken(c *gin.Context) {
 	tokenInfo, err := tc.tokenService.UpdateToken(c.Request.Context(), token, &req)
 	if err != nil {
 		utils.Error("更新Token失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"更新Token失败: "+err.Error(),
 			nil)
 		return
 	}
 
-	utils.RespondSuccess(c, tokenInfo, "Token更新成功")
+	ginutil.RespondSuccess(c, tokenInfo, "Token更新成功")
 }
 
 // DeleteToken 删除Token
 func (tc *TokenController) DeleteToken(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"缺少token参数",
 			nil)
@@ -116,21 +132,21 @@ func (tc *TokenController) DeleteToken(c *gin.Context) {
 
 	if err := tc.tokenService.DeleteToken(c.Request.Context(), token); err != nil {
 		utils.Error("删除Token失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"删除Token失败: "+err.Error(),
 			nil)
 		return
 	}
 
-	utils.RespondSuccess(c, nil, "Token删除成功")
+	ginutil.RespondSuccess(c, nil, "Token删除成功")
 }
 
 // GetTokenInfo 查询Token信息
 func (tc *TokenController) GetTokenInfo(c *gin.Context) {
 	var req model.TokenQueryRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"请求参数错误: "+err.Error(),
 			nil)
@@ -140,7 +156,7 @@ func (tc *TokenController) GetTokenInfo(c *gin.Context) {
 	tokens, err := tc.tokenService.GetTokenInfo(c.Request.Context(), &req)
 	if err != nil {
 		utils.Error("查询Token失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"查询Token失败: "+err.Error(),
 			nil)
@@ -148,7 +164,7 @@ func (tc *TokenController) GetTokenInfo(c *gin.Context) {
 	}
 
 	// 使用 map 返回数据和计数
-	utils.RespondSuccess(c, map[string]interface{}{
+	ginutil.RespondSuccess(c, map[string]interface{}{
 		"tokens": tokens,
 		"count":  len(tokens),
 	}, "")
@@ -157,7 +173,7 @@ func (tc *TokenController) GetTokenInfo(c *gin.Context) {
 // GetCacheStats 获取缓存统计
 func (tc *TokenController) GetCacheStats(c *gin.Context) {
 	stats := tc.tokenService.GetCacheStats()
-	utils.RespondSuccess(c, stats, "")
+	ginutil.RespondSuccess(c, stats, "")
 }
 
 // GetRateLimitStats 获取限流统计
@@ -166,7 +182,7 @@ func (tc *TokenController) GetRateLimitStats(c *gin.Context) {
 	writePoolStats := tc.cacheWritePool.GetStats()
 
 	// 使用 map 组合多个统计信息
-	utils.RespondSuccess(c, map[string]interface{}{
+	ginutil.RespondSuccess(c, map[string]interface{}{
 		"rate_limit": stats,
 		"write_pool": writePoolStats,
 	}, "")
@@ -176,21 +192,21 @@ func (tc *TokenController) GetRateLimitStats(c *gin.Context) {
 func (tc *TokenController) ClearCache(c *gin.Context) {
 	if err := tc.tokenService.ClearCache(c.Request.Context()); err != nil {
 		utils.Error("清空缓存失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"清空缓存失败: "+err.Error(),
 			nil)
 		return
 	}
 
-	utils.RespondSuccess(c, nil, "缓存已清空")
+	ginutil.RespondSuccess(c, nil, "缓存已清空")
 }
 
 // ClearTokenRateLimit 清除指定Token的限流缓存
 func (tc *TokenController) ClearTokenRateLimit(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"缺少token参数",
 			nil)
@@ -199,14 +215,14 @@ func (tc *TokenController) ClearTokenRateLimit(c *gin.Context) {
 
 	if err := tc.rateLimiterService.ClearTokenCache(c.Request.Context(), token); err != nil {
 		utils.Error("清除限流缓存失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"清除限流缓存失败: "+err.Error(),
 			nil)
 		return
 	}
 
-	utils.RespondSuccess(c, nil, "限流缓存已清除")
+	ginutil.RespondSuccess(c, nil, "限流缓存已清除")
 }
 
 // QueryTokenPublic 公开的Token查询接口（供测试工具使用）
@@ -214,7 +230,7 @@ func (tc *TokenController) ClearTokenRateLimit(c *gin.Context) {
 func (tc *TokenController) QueryTokenPublic(c *gin.Context) {
 	var req model.TokenQueryRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"请求参数错误: "+err.Error(),
 			nil)
@@ -225,7 +241,7 @@ func (tc *TokenController) QueryTokenPublic(c *gin.Context) {
 	// 方式1：直接通过Token查询
 	// 方式2：通过ws_id + email查询
 	if req.Token == "" && (req.WsID == "" || req.Email == "") {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"请提供 token，或者同时提供 ws_id 和 email",
 			nil)
@@ -236,7 +252,7 @@ func (tc *TokenController) QueryTokenPublic(c *gin.Context) {
 	tokens, err := tc.tokenService.GetTokenInfo(c.Request.Context(), &req)
 	if err != nil {
 		utils.Error("查询Token失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"查询Token失败: "+err.Error(),
 			nil)
@@ -248,14 +264,14 @@ func (tc *TokenController) QueryTokenPublic(c *gin.Context) {
 		"count":  len(tokens),
 		"tokens": tokens,
 	}
-	utils.RespondSuccess(c, result, "")
+	ginutil.RespondSuccess(c, result, "")
 }
 
 // GetQuota 查询Token配额
 func (tc *TokenController) GetQuota(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"缺少token参数",
 			nil)
@@ -268,7 +284,7 @@ func (tc *TokenController) GetQuota(c *gin.Context) {
 	})
 	if err != nil {
 		utils.Error("查询Token失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"查询Token失败: "+err.Error(),
 			nil)
@@ -276,7 +292,7 @@ func (tc *TokenController) GetQuota(c *gin.Context) {
 	}
 
 	if len(tokenInfo) == 0 {
-		utils.RespondError(c, http.StatusNotFound,
+		ginutil.RespondError(c, http.StatusNotFound,
 			utils.ErrorTypeNotFound,
 			"Token不存在",
 			nil)
@@ -287,7 +303,7 @@ func (tc *TokenController) GetQuota(c *gin.Context) {
 
 	// 如果不是配额模式，返回提示
 	if !info.NeedsQuotaCheck() {
-		utils.RespondSuccess(c, map[string]interface{}{
+		ginutil.RespondSuccess(c, map[string]interface{}{
 			"quota_type": info.QuotaType,
 			"message":    "该Token为时间模式，无配额限制",
 		}, "")
@@ -320,7 +336,7 @@ func (tc *TokenController) GetQuota(c *gin.Context) {
 		consumedQuota = totalQuota - remainingQuota
 	}
 
-	utils.RespondSuccess(c, map[string]interface{}{
+	ginutil.RespondSuccess(c, map[string]interface{}{
 		"quota_type":      info.QuotaType,
 		"total_quota":     totalQuota,
 		"remaining_quota": remainingQuota,
@@ -333,7 +349,7 @@ func (tc *TokenController) GetQuota(c *gin.Context) {
 func (tc *TokenController) GetQuotaLogs(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"缺少token参数",
 			nil)
@@ -342,7 +358,7 @@ func (tc *TokenController) GetQuotaLogs(c *gin.Context) {
 
 	var req model.QuotaLogsQueryRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"请求参数错误: "+err.Error(),
 			nil)
@@ -355,7 +371,7 @@ func (tc *TokenController) GetQuotaLogs(c *gin.Context) {
 	logs, total, err := tc.tokenService.GetQuotaLogs(c.Request.Context(), &req)
 	if err != nil {
 		utils.Error("查询配额日志失败", zap.Error(err))
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"查询配额日志失败: "+err.Error(),
 			nil)
@@ -373,7 +389,7 @@ func (tc *TokenController) GetQuotaLogs(c *gin.Context) {
 	}
 	totalPages := (total + pageSize - 1) / pageSize
 
-	utils.RespondSuccess(c, map[string]interface{}{
+	ginutil.RespondSuccess(c, map[string]interface{}{
 		"logs":        logs,
 		"total":       total,
 		"page":        page,
@@ -385,7 +401,7 @@ func (tc *TokenController) GetQuotaLogs(c *gin.Context) {
 // GetQuotaCleanupStats 获取配额清理统计信息
 func (tc *TokenController) GetQuotaCleanupStats(c *gin.Context) {
 	if tc.quotaCleanupService == nil {
-		utils.RespondSuccess(c, map[string]interface{}{
+		ginutil.RespondSuccess(c, map[string]interface{}{
 			"enabled": false,
 			"message": "配额清理服务未启用",
 		}, "")
@@ -394,13 +410,13 @@ func (tc *TokenController) GetQuotaCleanupStats(c *gin.Context) {
 
 	stats := tc.quotaCleanupService.GetStats()
 	stats["enabled"] = true
-	utils.RespondSuccess(c, stats, "")
+	ginutil.RespondSuccess(c, stats, "")
 }
 
 // TriggerQuotaCleanup 手动触发配额清理
 func (tc *TokenController) TriggerQuotaCleanup(c *gin.Context) {
 	if tc.quotaCleanupService == nil {
-		utils.RespondError(c, http.StatusServiceUnavailable,
+		ginutil.RespondError(c, http.StatusServiceUnavailable,
 			utils.ErrorTypeInternal,
 			"配额清理服务未启用",
 			nil)
@@ -410,7 +426,7 @@ func (tc *TokenController) TriggerQuotaCleanup(c *gin.Context) {
 	// 异步触发清理
 	tc.quotaCleanupService.TriggerCleanup()
 
-	utils.RespondSuccess(c, map[string]interface{}{
+	ginutil.RespondSuccess(c, map[string]interface{}{
 		"message": "清理任务已提交，正在后台执行",
 	}, "清理任务已启动")
 }
@@ -421,7 +437,7 @@ func (tc *TokenController) TriggerQuotaCleanup(c *gin.Context) {
 func (tc *TokenController) RequestVerifyCode(c *gin.Context) {
 	// 1. 检查验证码服务是否启用
 	if tc.verifyService == nil || !tc.verifyService.IsEnabled() {
-		utils.RespondError(c, http.StatusServiceUnavailable,
+		ginutil.RespondError(c, http.StatusServiceUnavailable,
 			utils.ErrorTypeInternal,
 			"验证码服务未启用",
 			nil)
@@ -432,7 +448,7 @@ func (tc *TokenController) RequestVerifyCode(c *gin.Context) {
 	if tc.sessionService != nil && tc.sessionService.IsEnabled() {
 		sessionCookie, err := c.Cookie("flow_page_session")
 		if err != nil || sessionCookie == "" {
-			utils.RespondError(c, http.StatusUnauthorized,
+			ginutil.RespondError(c, http.StatusUnauthorized,
 				utils.ErrorTypeAuthentication,
 				"Session无效，请刷新页面",
 				nil)
@@ -445,7 +461,7 @@ func (tc *TokenController) RequestVerifyCode(c *gin.Context) {
 		_, err = tc.sessionService.ValidateAndRenewSession(c.Request.Context(), sessionCookie, ip, userAgent)
 		if err != nil {
 			utils.Warn("Session验证失败", zap.Error(err), zap.String("ip", ip))
-			utils.RespondError(c, http.StatusUnauthorized,
+			ginutil.RespondError(c, http.StatusUnauthorized,
 				utils.ErrorTypeAuthentication,
 				err.Error(),
 				nil)
@@ -456,7 +472,7 @@ func (tc *TokenController) RequestVerifyCode(c *gin.Context) {
 	// 3. 解析请求参数
 	var req model.RequestVerifyCodeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"请求参数错误: "+err.Error(),
 			nil)
@@ -473,7 +489,7 @@ func (tc *TokenController) RequestVerifyCode(c *gin.Context) {
 			zap.String("email", req.Email),
 			zap.String("ws_id", req.WsID),
 		)
-		utils.RespondError(c, http.StatusNotFound,
+		ginutil.RespondError(c, http.StatusNotFound,
 			utils.ErrorTypeNotFound,
 			"该 Workspace ID 和 Email 组合未找到 Token，无法发送验证码",
 			nil)
@@ -489,14 +505,14 @@ func (tc *TokenController) RequestVerifyCode(c *gin.Context) {
 			zap.String("email", req.Email),
 			zap.String("ws_id", req.WsID),
 		)
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeInternal,
 			err.Error(),
 			nil)
 		return
 	}
 
-	utils.RespondSuccess(c, map[string]interface{}{
+	ginutil.RespondSuccess(c, map[string]interface{}{
 		"message": "验证码已发送到邮箱",
 	}, "验证码已发送")
 }
@@ -505,7 +521,7 @@ func (tc *TokenController) RequestVerifyCode(c *gin.Context) {
 func (tc *TokenController) VerifyCodeAndQueryToken(c *gin.Context) {
 	// 1. 检查验证码服务是否启用
 	if tc.verifyService == nil || !tc.verifyService.IsEnabled() {
-		utils.RespondError(c, http.StatusServiceUnavailable,
+		ginutil.RespondError(c, http.StatusServiceUnavailable,
 			utils.ErrorTypeInternal,
 			"验证码服务未启用",
 			nil)
@@ -516,7 +532,7 @@ func (tc *TokenController) VerifyCodeAndQueryToken(c *gin.Context) {
 	if tc.sessionService != nil && tc.sessionService.IsEnabled() {
 		sessionCookie, err := c.Cookie("flow_page_session")
 		if err != nil || sessionCookie == "" {
-			utils.RespondError(c, http.StatusUnauthorized,
+			ginutil.RespondError(c, http.StatusUnauthorized,
 				utils.ErrorTypeAuthentication,
 				"Session无效，请刷新页面",
 				nil)
@@ -529,7 +545,7 @@ func (tc *TokenController) VerifyCodeAndQueryToken(c *gin.Context) {
 		_, err = tc.sessionService.ValidateAndRenewSession(c.Request.Context(), sessionCookie, ip, userAgent)
 		if err != nil {
 			utils.Warn("Session验证失败", zap.Error(err), zap.String("ip", ip))
-			utils.RespondError(c, http.StatusUnauthorized,
+			ginutil.RespondError(c, http.StatusUnauthorized,
 				utils.ErrorTypeAuthentication,
 				err.Error(),
 				nil)
@@ -540,7 +556,7 @@ func (tc *TokenController) VerifyCodeAndQueryToken(c *gin.Context) {
 	// 3. 解析请求参数
 	var req model.VerifyCodeAndQueryTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			"请求参数错误: "+err.Error(),
 			nil)
@@ -555,7 +571,7 @@ func (tc *TokenController) VerifyCodeAndQueryToken(c *gin.Context) {
 			zap.String("email", req.Email),
 			zap.String("ws_id", req.WsID),
 		)
-		utils.RespondError(c, http.StatusBadRequest,
+		ginutil.RespondError(c, http.StatusBadRequest,
 			utils.ErrorTypeValidation,
 			err.Error(),
 			nil)
@@ -573,7 +589,7 @@ func (tc *TokenController) VerifyCodeAndQueryToken(c *gin.Context) {
 			zap.String("email", req.Email),
 			zap.String("ws_id", req.WsID),
 		)
-		utils.RespondError(c, http.StatusInternalServerError,
+		ginutil.RespondError(c, http.StatusInternalServerError,
 			utils.ErrorTypeInternal,
 			"查询Token失败: "+err.Error(),
 			nil)
@@ -582,7 +598,7 @@ func (tc *TokenController) VerifyCodeAndQueryToken(c *gin.Context) {
 
 	// 检查是否查询到结果
 	if len(tokenInfoList) == 0 {
-		utils.RespondError(c, http.StatusNotFound,
+		ginutil.RespondError(c, http.StatusNotFound,
 			utils.ErrorTypeNotFound,
 			"未找到匹配的Token",
 			nil)
@@ -597,7 +613,7 @@ func (tc *TokenController) VerifyCodeAndQueryToken(c *gin.Context) {
 	)
 
 	// 返回格式与 /flow/query-token 接口一致
-	utils.RespondSuccess(c, map[string]interface{}{
+	ginutil.RespondSuccess(c, map[string]interface{}{
 		"count":  len(tokenInfoList),
 		"tokens": tokenInfoList,
 	}, "Token查询成功")
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CreatePolicy 创建命名沙箱策略
+func (tc *TokenController) CreatePolicy(c *gin.Context) {
+	var req model.CreateSandboxPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	profile, err := tc.policyService.CreatePolicy(c.Request.Context(), &req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repository.ErrPolicyNameExists) {
+			status = http.StatusConflict
+		}
+		utils.Warn("创建沙箱策略失败", zap.String("name", req.Name), zap.Error(err))
+		ginutil.RespondError(c, status,
+			utils.ErrorTypeValidation,
+			"创建沙箱策略失败: "+err.Error(),
+			nil)
+		return
+	}
+
+	ginutil.RespondSuccess(c, profile, "沙箱策略创建成功")
+}
+
+// UpdatePolicy 更新命名沙箱策略
+func (tc *TokenController) UpdatePolicy(c *gin.Context) {
+	name := c.Param("name")
+
+	var req model.UpdateSandboxPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	profile, err := tc.policyService.UpdatePolicy(c.Request.Context(), name, &req)
+	if err != nil {
+		utils.Warn("更新沙箱策略失败", zap.String("name", name), zap.Error(err))
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"更新沙箱策略失败: "+err.Error(),
+			nil)
+		return
+	}
+
+	ginutil.RespondSuccess(c, profile, "沙箱策略更新成功")
+}
+
+// GetPolicy 查询命名沙箱策略
+func (tc *TokenController) GetPolicy(c *gin.Context) {
+	name := c.Param("name")
+
+	profile, err := tc.policyService.GetPolicy(c.Request.Context(), name)
+	if err != nil {
+		if errors.Is(err, service.ErrPolicyNotFound) {
+			ginutil.RespondError(c, http.StatusNotFound,
+				utils.ErrorTypeNotFound,
+				"沙箱策略不存在: "+name,
+				nil)
+			return
+		}
+		utils.Error("查询沙箱策略失败", zap.String("name", name), zap.Error(err))
+		ginutil.RespondError(c, http.StatusInternalServerError,
+			utils.ErrorTypeInternal,
+			"查询沙箱策略失败: "+err.Error(),
+			nil)
+		return
+	}
+
+	ginutil.RespondSuccess(c, profile, "查询成功")
+}
+
+// ListPolicies 查询全部命名沙箱策略
+func (tc *TokenController) ListPolicies(c *gin.Context) {
+	profiles, err := tc.policyService.ListPolicies(c.Request.Context())
+	if err != nil {
+		ginutil.RespondError(c, http.StatusInternalServerError,
+			utils.ErrorTypeInternal,
+			"查询沙箱策略失败: "+err.Error(),
+			nil)
+		return
+	}
+
+	ginutil.RespondSuccess(c, profiles, "查询成功")
+}
+
+// SetTokenPolicy 设置Token的沙箱策略（整体替换）
+func (tc *TokenController) SetTokenPolicy(c *gin.Context) {
+	token := c.Param("token")
+
+	var req model.SetTokenPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	result, err := tc.policyService.SetTokenPolicy(c.Request.Context(), token, &req)
+	if err != nil {
+		utils.Warn("设置Token策略失败", zap.String("token", utils.MaskToken(token)), zap.Error(err))
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"设置Token策略失败: "+err.Error(),
+			nil)
+		return
+	}
+
+	ginutil.RespondSuccess(c, result, "Token策略设置成功")
+}
+
+// GetTokenPolicy 查询Token的沙箱策略（含合并后的生效策略）
+func (tc *TokenController) GetTokenPolicy(c *gin.Context) {
+	token := c.Param("token")
+
+	result, err := tc.policyService.GetTokenPolicy(c.Request.Context(), token)
+	if err != nil {
+		ginutil.RespondError(c, http.StatusNotFound,
+			utils.ErrorTypeNotFound,
+			"查询Token策略失败: "+err.Error(),
+			nil)
+		return
+	}
+
+	ginutil.RespondSuccess(c, result, "查询成功")
+}
@@ -0,0 +1,281 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TriggerController HTTP 触发器控制器
+// 🆕 管理接口需要 Token 认证（只能管理自己的触发器）；触发地址 /flow/hooks/:key 公开，按触发器配置认证
+type TriggerController struct {
+	triggerService *service.TriggerService
+}
+
+// NewTriggerController 创建 HTTP 触发器控制器
+func NewTriggerController(triggerService *service.TriggerService) *TriggerController {
+	return &TriggerController{triggerService: triggerService}
+}
+
+// Create 创建触发器（响应包含密钥，只返回这一次）
+func (tc *TriggerController) Create(c *gin.Context) {
+	var req model.CreateTriggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	tokenInfo, ok := c.Get("tokenInfo")
+	if !ok {
+		ginutil.RespondError(c, http.StatusUnauthorized, utils.ErrorTypeAuthentication, "认证失败：未找到Token信息", nil)
+		return
+	}
+
+	detail, err := tc.triggerService.Create(c.Request.Context(), tokenInfo.(*model.TokenInfo), &req)
+	if err != nil {
+		tc.respondError(c, "创建HTTP触发器失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "HTTP触发器创建成功，请妥善保存密钥")
+}
+
+// List 获取当前 Token 的触发器
+func (tc *TriggerController) List(c *gin.Context) {
+	triggers, err := tc.triggerService.List(c.Request.Context(), c.GetString("token"))
+	if err != nil {
+		tc.respondError(c, "查询HTTP触发器失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, map[string]interface{}{
+		"total":    len(triggers),
+		"triggers": triggers,
+	}, "")
+}
+
+// Get 获取触发器详情
+func (tc *TriggerController) Get(c *gin.Context) {
+	id, ok := tc.triggerID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := tc.triggerService.Get(c.Request.Context(), c.GetString("token"), id)
+	if err != nil {
+		tc.respondError(c, "查询HTTP触发器失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "")
+}
+
+// Update 更新触发器
+func (tc *TriggerController) Update(c *gin.Context) {
+	id, ok := tc.triggerID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateTriggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	detail, err := tc.triggerService.Update(c.Request.Context(), c.GetString("token"), id, &req)
+	if err != nil {
+		tc.respondError(c, "更新HTTP触发器失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "HTTP触发器更新成功")
+}
+
+// RotateSecret 轮换密钥
+func (tc *TriggerController) RotateSecret(c *gin.Context) {
+	id, ok := tc.triggerID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := tc.triggerService.RotateSecret(c.Request.Context(), c.GetString("token"), id)
+	if err != nil {
+		tc.respondError(c, "轮换HTTP触发器密钥失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "密钥已轮换，旧密钥立即失效")
+}
+
+// Delete 删除触发器
+func (tc *TriggerController) Delete(c *gin.Context) {
+	id, ok := tc.triggerID(c)
+	if !ok {
+		return
+	}
+
+	if err := tc.triggerService.Delete(c.Request.Context(), c.GetString("token"), id); err != nil {
+		tc.respondError(c, "删除HTTP触发器失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, nil, "HTTP触发器已删除")
+}
+
+// Handle 处理触发请求（ANY /flow/hooks/:key[/*path]）
+// 🆕 请求 → input.request，脚本返回值 → HTTP 响应；配额和 Token 限流计入触发器所属 Token
+func (tc *TriggerController) Handle(c *gin.Context) {
+	requestID := c.GetString("request_id")
+	if !tc.triggerService.IsEnabled() {
+		ginutil.RespondError(c, http.StatusServiceUnavailable, utils.ErrorTypeServiceUnavail, service.ErrTriggerDisabled.Error(), nil)
+		return
+	}
+
+	trigger, err := tc.triggerService.Lookup(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		if errors.Is(err, service.ErrTriggerNotFound) {
+			ginutil.RespondError(c, http.StatusNotFound, utils.ErrorTypeNotFound, err.Error(), nil)
+			return
+		}
+		utils.Error("查询HTTP触发器失败", zap.String("request_id", requestID), zap.Error(err))
+		ginutil.RespondError(c, http.StatusInternalServerError, utils.ErrorTypeInternal, "查询HTTP触发器失败", nil)
+		return
+	}
+
+	if !tc.triggerService.AllowsMethod(trigger, c.Request.Method) {
+		c.Header("Allow", trigger.Methods)
+		ginutil.RespondError(c, http.StatusMethodNotAllowed, utils.ErrorTypeBadRequest,
+			"触发器不接受 "+c.Request.Method+" 请求", nil)
+		return
+	}
+
+	// 🔒 触发器自己的请求体上限（通常小于全局 MAX_REQUEST_BODY_MB）
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, tc.triggerService.MaxBodyBytes()))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ginutil.RespondError(c, http.StatusRequestEntityTooLarge, utils.ErrorTypeValidation,
+				"请求体超过大小限制: "+strconv.FormatInt(maxBytesErr.Limit, 10)+" 字节", nil)
+			return
+		}
+		ginutil.RespondError(c, http.StatusBadRequest, utils.ErrorTypeBadRequest, "读取请求体失败: "+err.Error(), nil)
+		return
+	}
+
+	path := c.Param("path")
+	if path == "" {
+		path = "/"
+	}
+	req := &service.TriggerRequest{
+		Method:   c.Request.Method,
+		Path:     path,
+		Query:    c.Request.URL.Query(),
+		Header:   c.Request.Header,
+		Body:     body,
+		ClientIP: c.ClientIP(),
+	}
+
+	if authErr := tc.triggerService.Verify(trigger, req); authErr != nil {
+		utils.Warn("HTTP触发器认证失败",
+			zap.String("request_id", requestID),
+			zap.Int64("trigger_id", trigger.ID),
+			zap.String("ip", c.ClientIP()),
+			zap.String("reason", authErr.Message))
+		ginutil.RespondError(c, http.StatusUnauthorized, authErr.Type, authErr.Message, nil)
+		return
+	}
+
+	if limitErr := tc.triggerService.CheckRateLimit(c.Request.Context(), trigger); limitErr != nil {
+		ginutil.SetRetryAfter(c, limitErr.RetryAfterSeconds())
+		ginutil.RespondError(c, http.StatusTooManyRequests, limitErr.Type, limitErr.Message, nil)
+		return
+	}
+
+	resp, version, execErr := tc.triggerService.Invoke(c.Request.Context(), trigger, req, requestID)
+	if version > 0 {
+		c.Header("X-Function-Version", strconv.Itoa(version))
+	}
+	if execErr != nil {
+		utils.Warn("HTTP触发器执行失败",
+			zap.String("request_id", requestID),
+			zap.Int64("trigger_id", trigger.ID),
+			zap.String("error_type", execErr.Type),
+			zap.String("error_message", execErr.Message))
+		status := triggerErrorStatus(execErr)
+		if status == http.StatusTooManyRequests {
+			ginutil.SetRetryAfter(c, execErr.RetryAfterSeconds())
+		}
+		ginutil.RespondError(c, status, execErr.Type, execErr.Message, nil)
+		return
+	}
+
+	header := c.Writer.Header()
+	for name, values := range resp.Header {
+		header[name] = values
+	}
+	c.Status(resp.Status)
+	if c.Request.Method != http.MethodHead && len(resp.Body) > 0 {
+		_, _ = c.Writer.Write(resp.Body)
+	}
+}
+
+// triggerErrorStatus 执行失败的状态码：Token 限流 / 配额 / 排队 429，Token 或策略问题 403，脚本不存在 404，超时 504，其他 500
+func triggerErrorStatus(execErr *model.ExecutionError) int {
+	switch {
+	case execErr.Type == utils.ErrorTypeTokenRateLimit || execErr.Type == "QuotaExceeded" || execErr.RetryAfterSeconds() > 0:
+		return http.StatusTooManyRequests
+	case execErr.Type == utils.ErrorTypeAuthentication || execErr.Type == utils.ErrorTypeAuthorization:
+		return http.StatusForbidden
+	case execErr.Type == utils.ErrorTypeNotFound:
+		return http.StatusNotFound
+	case execErr.Type == "TimeoutError":
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// triggerID 解析路径中的触发器ID
+func (tc *TriggerController) triggerID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"无效的触发器ID",
+			nil)
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError 按错误类型返回 404 / 503 / 400（ExecutionError）/ 500
+func (tc *TriggerController) respondError(c *gin.Context, action string, err error) {
+	var execErr *model.ExecutionError
+	switch {
+	case errors.Is(err, service.ErrTriggerNotFound):
+		ginutil.RespondError(c, http.StatusNotFound, utils.ErrorTypeNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrTriggerDisabled):
+		ginutil.RespondError(c, http.StatusServiceUnavailable, utils.ErrorTypeServiceUnavail, err.Error(), nil)
+	case errors.As(err, &execErr):
+		ginutil.RespondError(c, http.StatusBadRequest, execErr.Type, action+": "+execErr.Message, nil)
+	default:
+		utils.Error(action, zap.String("token", utils.MaskToken(c.GetString("token"))), zap.Error(err))
+		ginutil.RespondError(c, http.StatusInternalServerError, utils.ErrorTypeInternal, action, nil)
+	}
+}
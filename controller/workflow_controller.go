@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// WorkflowController 工作流控制器
+// 🆕 工作流归属于创建它的 Token，只能管理和执行自己的工作流
+type WorkflowController struct {
+	workflowService *service.WorkflowService
+}
+
+// NewWorkflowController 创建工作流控制器
+func NewWorkflowController(workflowService *service.WorkflowService) *WorkflowController {
+	return &WorkflowController{workflowService: workflowService}
+}
+
+// Create 创建工作流
+func (wc *WorkflowController) Create(c *gin.Context) {
+	var req model.CreateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	tokenInfo, ok := c.Get("tokenInfo")
+	if !ok {
+		ginutil.RespondError(c, http.StatusUnauthorized, utils.ErrorTypeAuthentication, "认证失败：未找到Token信息", nil)
+		return
+	}
+
+	detail, err := wc.workflowService.Create(c.Request.Context(), tokenInfo.(*model.TokenInfo), &req)
+	if err != nil {
+		wc.respondError(c, "创建工作流失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "工作流创建成功")
+}
+
+// List 获取当前 Token 的工作流
+func (wc *WorkflowController) List(c *gin.Context) {
+	workflows, err := wc.workflowService.List(c.Request.Context(), c.GetString("token"))
+	if err != nil {
+		wc.respondError(c, "查询工作流失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, map[string]interface{}{
+		"total":     len(workflows),
+		"workflows": workflows,
+	}, "")
+}
+
+// Get 获取工作流详情
+func (wc *WorkflowController) Get(c *gin.Context) {
+	id, ok := wc.workflowID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := wc.workflowService.Get(c.Request.Context(), c.GetString("token"), id)
+	if err != nil {
+		wc.respondError(c, "查询工作流失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "")
+}
+
+// Update 更新工作流
+func (wc *WorkflowController) Update(c *gin.Context) {
+	id, ok := wc.workflowID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	detail, err := wc.workflowService.Update(c.Request.Context(), c.GetString("token"), id, &req)
+	if err != nil {
+		wc.respondError(c, "更新工作流失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, detail, "工作流更新成功")
+}
+
+// Delete 删除工作流
+func (wc *WorkflowController) Delete(c *gin.Context) {
+	id, ok := wc.workflowID(c)
+	if !ok {
+		return
+	}
+
+	if err := wc.workflowService.Delete(c.Request.Context(), c.GetString("token"), id); err != nil {
+		wc.respondError(c, "删除工作流失败", err)
+		return
+	}
+
+	ginutil.RespondSuccess(c, nil, "工作流已删除")
+}
+
+// Run 同步执行工作流，返回每个节点的结果和耗时
+// 🆕 节点失败时返回错误响应，error.details.run 中仍包含所有节点的执行情况
+func (wc *WorkflowController) Run(c *gin.Context) {
+	id, ok := wc.workflowID(c)
+	if !ok {
+		return
+	}
+
+	// 请求体可以为空（没有运行输入）
+	var req model.RunWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"请求参数错误: "+err.Error(),
+			nil)
+		return
+	}
+
+	tokenInfo, ok := c.Get("tokenInfo")
+	if !ok {
+		ginutil.RespondError(c, http.StatusUnauthorized, utils.ErrorTypeAuthentication, "认证失败：未找到Token信息", nil)
+		return
+	}
+
+	result, err := wc.workflowService.Run(c.Request.Context(), tokenInfo.(*model.TokenInfo),
+		sandbox.SandboxPolicyFromContext(c.Request.Context()), id, req.Input, c.GetString("request_id"))
+	if err != nil {
+		wc.respondError(c, "执行工作流失败", err)
+		return
+	}
+
+	if result.Status == model.WorkflowRunFailed {
+		status := workflowErrorStatus(result.Error)
+		if status == http.StatusTooManyRequests {
+			ginutil.SetRetryAfter(c, result.Error.RetryAfter)
+		}
+		message := result.Error.Message
+		if result.FailedNode != "" {
+			message = "节点 " + result.FailedNode + " 执行失败: " + message
+		}
+		ginutil.RespondError(c, status, result.Error.Type, message, map[string]interface{}{"run": result})
+		return
+	}
+
+	ginutil.RespondSuccess(c, result, "工作流执行成功")
+}
+
+// workflowErrorStatus 运行失败的状态码：限流 / 配额 / 排队 429，超时 504，其他 400（与代码执行接口一致）
+func workflowErrorStatus(execErr *model.ExecuteError) int {
+	switch {
+	case execErr.Type == utils.ErrorTypeTokenRateLimit || execErr.Type == "QuotaExceeded" || execErr.RetryAfter > 0:
+		return http.StatusTooManyRequests
+	case execErr.Type == "TimeoutError":
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// workflowID 解析路径中的工作流ID
+func (wc *WorkflowController) workflowID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ginutil.RespondError(c, http.StatusBadRequest,
+			utils.ErrorTypeValidation,
+			"无效的工作流ID",
+			nil)
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError 按错误类型返回 404 / 503 / 400（ExecutionError）/ 500
+func (wc *WorkflowController) respondError(c *gin.Context, action string, err error) {
+	var execErr *model.ExecutionError
+	switch {
+	case errors.Is(err, service.ErrWorkflowNotFound):
+		ginutil.RespondError(c, http.StatusNotFound, utils.ErrorTypeNotFound, err.Error(), nil)
+	case errors.Is(err, service.ErrWorkflowDisabled):
+		ginutil.RespondError(c, http.StatusServiceUnavailable, utils.ErrorTypeServiceUnavail, err.Error(), nil)
+	case errors.As(err, &execErr) && execErr.Type == utils.ErrorTypeNotFound:
+		ginutil.RespondError(c, http.StatusNotFound, execErr.Type, action+": "+execErr.Message, nil)
+	case errors.As(err, &execErr):
+		ginutil.RespondError(c, http.StatusBadRequest, execErr.Type, action+": "+execErr.Message, nil)
+	default:
+		utils.Error(action, zap.String("token", utils.MaskToken(c.GetString("token"))), zap.Error(err))
+		ginutil.RespondError(c, http.StatusInternalServerError, utils.ErrorTypeInternal, action, nil)
+	}
+}
@@ -0,0 +1,307 @@
+package enhance_modules
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"flow-codeblock-go/utils"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// EgressDeniedErrorName 出站请求被拒绝时 JS 错误对象的 name
+const EgressDeniedErrorName = "EgressDeniedError"
+
+// EgressDeniedError 出站请求不在出站规则允许范围内
+type EgressDeniedError struct {
+	Target string // 被拒绝的目标（scheme://host:port，拨号阶段为 host:port）
+	Stage  string // 拦截阶段：request / redirect / dial
+}
+
+func (e *EgressDeniedError) Error() string {
+	return fmt.Sprintf("出站请求被拒绝: %s 不在允许的出站规则中（%s）", e.Target, e.Stage)
+}
+
+// egressRule 单条出站规则
+//
+// 规则格式：[scheme://]host[:port]
+//   - scheme：http / https，省略表示两者均可
+//   - host：example.com（精确）、*.example.com（仅子域名）、*（任意）、IP、CIDR（10.0.0.0/8）
+//     IPv6 需要使用 [] 包裹，如 [2001:db8::/32]:443
+//   - port：省略或 * 表示任意端口
+type egressRule struct {
+	scheme   string
+	anyHost  bool
+	domain   string // 小写域名；wildcard 时为去掉 "*." 的后缀
+	wildcard bool
+	cidr     *net.IPNet
+	port     int // 0 表示任意端口
+}
+
+// EgressPolicy 出站规则集合（解析后只读，可并发使用）
+//
+// 🔒 nil 表示不限制（仍受 SSRF 防护约束）；空规则集合表示禁止所有出站请求
+type EgressPolicy struct {
+	rules []egressRule
+}
+
+// ParseEgressRules 解析出站规则
+func ParseEgressRules(rules []string) (*EgressPolicy, error) {
+	policy := &EgressPolicy{rules: make([]egressRule, 0, len(rules))}
+	for _, raw := range rules {
+		rule, err := parseEgressRule(raw)
+		if err != nil {
+			return nil, fmt.Errorf("出站规则 %q 无效: %w", raw, err)
+		}
+		policy.rules = append(policy.rules, rule)
+	}
+	return policy, nil
+}
+
+// parseEgressRule 解析单条出站规则
+func parseEgressRule(raw string) (egressRule, error) {
+	var rule egressRule
+	s := strings.ToLower(strings.TrimSpace(raw))
+	if s == "" {
+		return rule, fmt.Errorf("规则不能为空")
+	}
+
+	// 1. scheme
+	if idx := strings.Index(s, "://"); idx != -1 {
+		rule.scheme = s[:idx]
+		if rule.scheme != "http" && rule.scheme != "https" {
+			return rule, fmt.Errorf("不支持的协议 %s（仅支持 http/https）", rule.scheme)
+		}
+		s = s[idx+3:]
+	}
+
+	// 2. host / port
+	var host, port string
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end == -1 {
+			return rule, fmt.Errorf("缺少 ]")
+		}
+		host, s = s[1:end], s[end+1:]
+		if s != "" {
+			if s[0] != ':' {
+				return rule, fmt.Errorf("] 之后只能是 :port")
+			}
+			port = s[1:]
+		}
+	} else if idx := strings.LastIndexByte(s, ':'); idx != -1 {
+		host, port = s[:idx], s[idx+1:]
+		if strings.Contains(host, ":") {
+			return rule, fmt.Errorf("IPv6 地址需要使用 [] 包裹")
+		}
+	} else {
+		host = s
+	}
+
+	if port != "" && port != "*" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return rule, fmt.Errorf("端口必须是 1-65535 或 *")
+		}
+		rule.port = n
+	}
+
+	// 3. host 类型
+	switch {
+	case host == "":
+		return rule, fmt.Errorf("host 不能为空")
+	case host == "*":
+		rule.anyHost = true
+	case strings.Contains(host, "/"):
+		_, cidr, err := net.ParseCIDR(host)
+		if err != nil {
+			return rule, fmt.Errorf("CIDR 格式错误")
+		}
+		rule.cidr = cidr
+	case net.ParseIP(host) != nil:
+		ip := net.ParseIP(host)
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		rule.cidr = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	case strings.HasPrefix(host, "*."):
+		rule.wildcard = true
+		rule.domain = host[2:]
+	default:
+		rule.domain = host
+	}
+
+	if rule.domain != "" && (strings.Contains(rule.domain, "*") || strings.HasPrefix(rule.domain, ".")) {
+		return rule, fmt.Errorf("通配符只能出现在域名开头（*.example.com）")
+	}
+	return rule, nil
+}
+
+// matchSchemePort scheme 为空（拨号阶段）时不检查协议
+func (r *egressRule) matchSchemePort(scheme string, port int) bool {
+	if scheme != "" && r.scheme != "" && r.scheme != scheme {
+		return false
+	}
+	return r.port == 0 || r.port == port
+}
+
+// matchDomain *.example.com 只匹配子域名，不匹配 example.com 本身
+func (r *egressRule) matchDomain(host string) bool {
+	if r.domain == "" {
+		return false
+	}
+	if r.wildcard {
+		return strings.HasSuffix(host, "."+r.domain)
+	}
+	return host == r.domain
+}
+
+// check 检查目标是否被允许
+// 域名目标匹配域名规则；IP 匹配 CIDR 规则。ips 为拨号阶段实际连接的地址，每个都必须在 CIDR 规则的网段内
+// 请求 / 重定向阶段（ips 为 nil）域名目标遇到端口匹配的 CIDR 规则时先放行，由拨号阶段按解析结果检查
+func (p *EgressPolicy) check(scheme, host string, port int, ips []net.IP) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	isDomain := true
+	if ip := net.ParseIP(host); ip != nil {
+		ips, isDomain = []net.IP{ip}, false
+	}
+
+	hasCIDR := false
+	for i := range p.rules {
+		rule := &p.rules[i]
+		if !rule.matchSchemePort(scheme, port) {
+			continue
+		}
+		if rule.anyHost {
+			return true
+		}
+		if rule.cidr == nil {
+			if isDomain && rule.matchDomain(host) {
+				return true
+			}
+			continue
+		}
+		hasCIDR = true
+	}
+	if !hasCIDR {
+		return false
+	}
+	if ips == nil {
+		return true
+	}
+	for _, ip := range ips {
+		if !p.cidrContains(scheme, ip, port) {
+			return false
+		}
+	}
+	return len(ips) > 0
+}
+
+// cidrContains IP 是否在某条端口匹配的 CIDR 规则的网段内
+func (p *EgressPolicy) cidrContains(scheme string, ip net.IP, port int) bool {
+	for i := range p.rules {
+		rule := &p.rules[i]
+		if rule.cidr != nil && rule.matchSchemePort(scheme, port) && rule.cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// 单次执行的出站上下文
+// ============================================================================
+
+// EgressScope 单次执行的出站上下文
+// 通过 FetchEnhancer.ScopedFetch 绑定到本次执行的 fetch，随请求 context 传递到重定向和拨号阶段
+type EgressScope struct {
+	Policy      *EgressPolicy     // 出站规则（nil 表示不限制）
+	RequestID   string            // 用于出站日志
+	TraceParent trace.SpanContext // 🆕 链路追踪父 span（无效时 fetch span 作为新链路的根）
+
+	// 🆕 执行被取消 / 终止时关闭，进行中的出站请求随之中止（nil 表示不随执行取消）
+	Done <-chan struct{}
+	// 🆕 出站请求开始时调用（method + 脱敏后的 URL），返回值在收到响应头或请求失败时调用
+	// 用于记录执行中正在进行的 fetch（nil 表示不记录）
+	OnFetch func(method, url string) (finish func())
+}
+
+type egressScopeKey struct{}
+
+// withEgressScope 把出站上下文放入请求 context
+func withEgressScope(ctx context.Context, scope *EgressScope) context.Context {
+	return context.WithValue(ctx, egressScopeKey{}, scope)
+}
+
+// egressScopeFromContext 从请求 context 取出出站上下文（未设置时返回 nil）
+func egressScopeFromContext(ctx context.Context) *EgressScope {
+	scope, _ := ctx.Value(egressScopeKey{}).(*EgressScope)
+	return scope
+}
+
+// checkURL 检查请求 URL（发起请求和每次重定向时调用），并记录出站日志
+func (s *EgressScope) checkURL(u *url.URL, method, stage string) error {
+	scheme := strings.ToLower(u.Scheme)
+	host := u.Hostname()
+	port := defaultPortForScheme(scheme)
+	if p := u.Port(); p != "" {
+		port, _ = strconv.Atoi(p)
+	}
+	target := fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)))
+
+	if s.Policy != nil && !s.Policy.check(scheme, host, port, nil) {
+		utils.Warn("出站请求被拒绝",
+			zap.String("request_id", s.RequestID),
+			zap.String("stage", stage),
+			zap.String("method", method),
+			zap.String("target", target))
+		return &EgressDeniedError{Target: target, Stage: stage}
+	}
+
+	utils.Info("出站请求",
+		zap.String("request_id", s.RequestID),
+		zap.String("stage", stage),
+		zap.String("method", method),
+		zap.String("target", target),
+		zap.Bool("restricted", s.Policy != nil))
+	return nil
+}
+
+// checkDial 拨号阶段检查：ips 为拨号器解析出、随后实际连接的地址
+// 检查和连接使用同一次解析结果，DNS 重绑定无法让连接落到规则之外的地址
+func (s *EgressScope) checkDial(host string, port int, ips []net.IP) error {
+	if s.Policy == nil || s.Policy.check("", host, port, ips) {
+		return nil
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(port))
+	utils.Warn("出站请求被拒绝",
+		zap.String("request_id", s.RequestID),
+		zap.String("stage", "dial"),
+		zap.String("target", target),
+		zap.Stringers("resolved_ips", ips))
+	return &EgressDeniedError{Target: target, Stage: "dial"}
+}
+
+// checkEgressRedirect 检查重定向目标（http.Client.CheckRedirect 中调用）
+func checkEgressRedirect(req *http.Request) error {
+	scope := egressScopeFromContext(req.Context())
+	if scope == nil {
+		return nil
+	}
+	return scope.checkURL(req.URL, req.Method, "redirect")
+}
+
+// defaultPortForScheme 返回协议默认端口
+func defaultPortForScheme(scheme string) int {
+	if scheme == "https" {
+		return 443
+	}
+	return 80
+}
@@ -0,0 +1,99 @@
+package enhance_modules
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func mustParseEgressRules(t *testing.T, rules ...string) *EgressPolicy {
+	t.Helper()
+	policy, err := ParseEgressRules(rules)
+	if err != nil {
+		t.Fatalf("ParseEgressRules: %v", err)
+	}
+	return policy
+}
+
+func TestEgressPolicyCheck(t *testing.T) {
+	policy := mustParseEgressRules(t, "https://api.example.com", "10.0.0.0/8")
+	public, private := net.ParseIP("203.0.113.5"), net.ParseIP("10.1.2.3")
+
+	tests := []struct {
+		name   string
+		scheme string
+		host   string
+		port   int
+		ips    []net.IP
+		want   bool
+	}{
+		{"域名规则", "https", "api.example.com", 443, nil, true},
+		{"域名规则不限制解析结果", "", "api.example.com", 443, []net.IP{public}, true},
+		{"IP 在网段内", "http", "10.9.9.9", 80, nil, true},
+		{"IP 不在网段内", "http", "203.0.113.5", 80, nil, false},
+		{"请求阶段域名遇到 CIDR 规则先放行", "http", "internal.example.com", 80, nil, true},
+		{"拨号阶段解析到网段内", "", "internal.example.com", 80, []net.IP{private}, true},
+		{"拨号阶段部分 IP 不在网段内", "", "internal.example.com", 80, []net.IP{private, public}, false},
+		{"拨号阶段没有解析结果", "", "internal.example.com", 80, []net.IP{}, false},
+	}
+	for _, tt := range tests {
+		if got := policy.check(tt.scheme, tt.host, tt.port, tt.ips); got != tt.want {
+			t.Errorf("%s: check(%q, %q, %d, %v) = %v, want %v", tt.name, tt.scheme, tt.host, tt.port, tt.ips, got, tt.want)
+		}
+	}
+
+	domainOnly := mustParseEgressRules(t, "https://api.example.com")
+	if domainOnly.check("http", "api.example.com", 80, nil) {
+		t.Error("协议不匹配时应拒绝")
+	}
+	if domainOnly.check("https", "other.example.com", 443, nil) {
+		t.Error("没有 CIDR 规则时不匹配的域名应直接拒绝")
+	}
+}
+
+func TestProtectedDialContextChecksDialedIP(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	addr := net.JoinHostPort("localhost", port)
+
+	dial := CreateProtectedDialContext(&SSRFProtectionConfig{Enabled: false, AllowPrivateIP: true}, time.Second, 0)
+
+	// localhost 解析到 127.0.0.1，不在出站规则的网段内
+	denied := withEgressScope(context.Background(), &EgressScope{Policy: mustParseEgressRules(t, "10.0.0.0/8")})
+	var egressErr *EgressDeniedError
+	if _, err := dial(denied, "tcp4", addr); !errors.As(err, &egressErr) || egressErr.Stage != "dial" {
+		t.Fatalf("err = %v, want 拨号阶段的 EgressDeniedError", err)
+	}
+
+	// 连接的是检查过的 IP
+	allowed := withEgressScope(context.Background(), &EgressScope{Policy: mustParseEgressRules(t, "127.0.0.0/8")})
+	conn, err := dial(allowed, "tcp4", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if host, _, _ := net.SplitHostPort(conn.RemoteAddr().String()); host != "127.0.0.1" {
+		t.Errorf("remote = %s, want 127.0.0.1", conn.RemoteAddr())
+	}
+
+	// SSRF 防护开启时同样检查实际连接的 IP
+	ssrf := CreateProtectedDialContext(&SSRFProtectionConfig{Enabled: true}, time.Second, 0)
+	if _, err := ssrf(context.Background(), "tcp4", addr); err == nil {
+		t.Fatal("SSRF 防护应拒绝解析到环回地址的域名")
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -18,6 +18,8 @@ import (
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 )
 
@@ -278,6 +280,7 @@ type FetchRequest struct {
 	options  map[string]interface{}
 	resultCh chan FetchResult
 	abortCh  chan struct{}
+	scope    *EgressScope // 🆕 出站上下文（nil 表示不限制）
 }
 
 // FetchResult Fetch 请求结果
@@ -339,24 +342,19 @@ func NewFetchEnhancerWithConfig(
 	ssrfProtectionConfig *SSRFProtectionConfig, // 🛡️ SSRF 防护配置（新增）
 ) *FetchEnhancer {
 	// 🛡️ 创建带 SSRF 防护的 DialContext
-	var dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// 🆕 SSRF 防护关闭时同样使用 CreateProtectedDialContext，以便在拨号阶段执行出站规则检查
 	if ssrfProtectionConfig != nil && ssrfProtectionConfig.Enabled {
-		// 启用 SSRF 防护
-		dialContext = CreateProtectedDialContext(
-			ssrfProtectionConfig,
-			httpTransportConfig.DialTimeout,
-			httpTransportConfig.KeepAlive,
-		)
 		utils.Info("SSRF 防护已启用",
 			zap.Bool("allow_private_ip", ssrfProtectionConfig.AllowPrivateIP))
 	} else {
-		// 使用标准 Dialer（无 SSRF 防护）
-		dialContext = (&net.Dialer{
-			Timeout:   httpTransportConfig.DialTimeout,
-			KeepAlive: httpTransportConfig.KeepAlive,
-		}).DialContext
+		ssrfProtectionConfig = &SSRFProtectionConfig{Enabled: false, AllowPrivateIP: true}
 		utils.Info("SSRF 防护已禁用（本地开发模式）")
 	}
+	dialContext := CreateProtectedDialContext(
+		ssrfProtectionConfig,
+		httpTransportConfig.DialTimeout,
+		httpTransportConfig.KeepAlive,
+	)
 
 	// 🔥 优化：配置高性能且安全的 HTTP Transport（使用环境变量配置）
 	transport := &http.Transport{
@@ -413,7 +411,8 @@ func NewFetchEnhancerWithConfig(
 				if len(via) >= 10 {
 					return fmt.Errorf("stopped after 10 redirects")
 				}
-				return nil
+				// 🆕 每一跳重定向都检查出站规则
+				return checkEgressRedirect(req)
 			},
 		},
 		// 不限制域名，允许所有域名
@@ -433,7 +432,7 @@ func NewFetchEnhancerWithConfig(
 func (fe *FetchEnhancer) RegisterFetchAPI(runtime *goja.Runtime) error {
 	// 注册 fetch() 函数
 	runtime.Set("fetch", func(call goja.FunctionCall) goja.Value {
-		return fe.fetch(runtime, call)
+		return fe.fetch(runtime, call, nil)
 	})
 
 	// 注册 Headers 构造器
@@ -461,9 +460,18 @@ func (fe *FetchEnhancer) RegisterFetchAPI(runtime *goja.Runtime) error {
 	return nil
 }
 
+// ScopedFetch 返回绑定出站上下文的 fetch 函数
+// 🆕 执行器在每次执行前用它替换全局 fetch（执行结束后恢复），使出站规则和 request_id 随本次执行的请求传递
+func (fe *FetchEnhancer) ScopedFetch(runtime *goja.Runtime, scope *EgressScope) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		return fe.fetch(runtime, call, scope)
+	}
+}
+
 // fetch 主函数 - 实现标准 Fetch API (真正的异步实现)
 // 🔥 重构: 使用 goroutine + channel 实现真正的异步,支持请求中取消
-func (fe *FetchEnhancer) fetch(runtime *goja.Runtime, call goja.FunctionCall) goja.Value {
+// 🆕 scope 为本次执行的出站上下文（nil 表示不检查出站规则、不记录出站日志）
+func (fe *FetchEnhancer) fetch(runtime *goja.Runtime, call goja.FunctionCall, scope *EgressScope) goja.Value {
 	// 1. 参数验证
 	if len(call.Arguments) == 0 {
 		panic(runtime.NewTypeError("fetch: 至少需要 1 个参数"))
@@ -745,6 +753,7 @@ func (fe *FetchEnhancer) fetch(runtime *goja.Runtime, call goja.FunctionCall) go
 		options:  options,
 		resultCh: make(chan FetchResult, 1),
 		abortCh:  abortCh, // 🔥 使用从 signal 获取的 channel
+		scope:    scope,
 	}
 
 	// 6. 异步执行请求 (不阻塞 EventLoop)
@@ -765,12 +774,7 @@ func (fe *FetchEnhancer) fetch(runtime *goja.Runtime, call goja.FunctionCall) go
 		// Promise 的 resolve/reject 会在微任务队列中异步执行,这里同步等待是安全的
 		result := <-req.resultCh
 		if result.err != nil {
-			// 🔥 检查是否为 AbortError
-			if _, isAbortError := result.err.(*AbortError); isAbortError {
-				reject(fe.createAbortErrorObject(runtime, result.err))
-			} else {
-				reject(fe.createErrorObject(runtime, result.err))
-			}
+			reject(fe.createFetchErrorObject(runtime, result.err))
 		} else {
 			resolve(fe.recreateResponse(runtime, result.response))
 		}
@@ -854,7 +858,32 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 	// 为什么不能在请求完成后立即 cancel：
 	//   - resp.Body 底层仍依赖 request context（特别是 HTTP/2）
 	//   - 过早 cancel 会导致 body 读取失败（context canceled 错误）
-	reqCtx, reqCancel := context.WithTimeout(context.Background(), fe.requestTimeout)
+	//
+	// 🆕 出站上下文放入请求 context，重定向（CheckRedirect）和拨号（DialContext）阶段据此检查出站规则
+	baseCtx := context.Background()
+	if req.scope != nil {
+		baseCtx = withEgressScope(baseCtx, req.scope)
+	}
+
+	// 🆕 链路追踪：每次出站请求一个 client span（父 span 为本次执行的 execute.run）
+	// span 覆盖到收到响应头为止，响应体的流式读取不计入
+	var spanStatus int
+	var spanErr error
+	baseCtx, span := startFetchSpan(baseCtx, req.scope, method, req.url)
+	defer func() {
+		endFetchSpan(span, spanStatus, spanErr)
+	}()
+
+	reqCtx, reqCancel := context.WithTimeout(baseCtx, fe.requestTimeout)
+
+	// 🆕 执行级别的取消信号（执行被终止时中止请求）和进行中请求的记录
+	var scopeDone <-chan struct{}
+	if req.scope != nil {
+		scopeDone = req.scope.Done
+		if req.scope.OnFetch != nil {
+			defer req.scope.OnFetch(method, redactURL(req.url))()
+		}
+	}
 
 	// 🔥 v2.4.2: 为上传 FormData 创建独立的 context
 	// 注意：这是上传阶段的 context，与下载响应的 context 独立
@@ -884,6 +913,7 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 		}
 		// 🔥 清理请求 context（避免 context 泄漏）
 		reqCancel()
+		spanErr = err
 		req.resultCh <- FetchResult{nil, fmt.Errorf("创建请求失败: %w", err)}
 		return
 	}
@@ -907,6 +937,8 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 	if contentType != "" && httpReq.Header.Get("Content-Type") == "" {
 		httpReq.Header.Set("Content-Type", contentType)
 	}
+	// 🆕 W3C traceparent 透传给下游（覆盖用户同名请求头，保证链路连续）
+	otel.GetTextMapPropagator().Inject(reqCtx, propagation.HeaderCarrier(httpReq.Header))
 
 	// 6. 协议安全检查
 	if err := fe.checkProtocol(httpReq.URL.Scheme); err != nil {
@@ -916,10 +948,24 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 		}
 		// 🔥 清理请求 context（避免 context 泄漏）
 		reqCancel()
+		spanErr = err
 		req.resultCh <- FetchResult{nil, err}
 		return
 	}
 
+	// 6.1 🆕 出站规则检查（重定向的每一跳在 CheckRedirect 中检查）
+	if req.scope != nil {
+		if err := req.scope.checkURL(httpReq.URL, method, "request"); err != nil {
+			if uploadCancel != nil {
+				uploadCancel()
+			}
+			reqCancel()
+			spanErr = err
+			req.resultCh <- FetchResult{nil, err}
+			return
+		}
+	}
+
 	// 6.5 🔥 处理 redirect 选项（支持 fetch API 的 redirect 模式）
 	// redirect: 'manual' -> 不自动跟随重定向（返回 3xx 状态码）
 	// redirect: 'follow' -> 自动跟随重定向（默认行为）
@@ -968,11 +1014,15 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 
 	// 6.6 🔥 启动 abort 监听器 (在独立的 goroutine 中)
 	// 当 abortCh 被关闭时，立即取消请求 context
+	// 🆕 执行被取消 / 终止（scopeDone）时同样取消
 	go func() {
 		select {
 		case <-req.abortCh:
 			// abort 被调用，立即取消请求 context
 			reqCancel()
+		case <-scopeDone:
+			// 所属执行已结束（被终止 / 取消），中止请求
+			reqCancel()
 		case <-done:
 			// 请求已完成，退出监听
 		}
@@ -1012,7 +1062,12 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 			// ✅ reqCancel 会在 defer 中调用
 			// ✅ uploadCancel 会在 defer 中调用
 			// ✅ defer 会清理 resp.Body
-			if reqCtx.Err() == context.Canceled {
+			spanErr = reqErr
+			var denied *EgressDeniedError
+			if errors.As(reqErr, &denied) {
+				// 🆕 重定向或拨号阶段被出站规则拒绝
+				req.resultCh <- FetchResult{nil, denied}
+			} else if reqCtx.Err() == context.Canceled {
 				req.resultCh <- FetchResult{nil, fmt.Errorf("请求已中止")}
 			} else if reqCtx.Err() == context.DeadlineExceeded {
 				req.resultCh <- FetchResult{nil, fmt.Errorf("请求超时")}
@@ -1022,6 +1077,8 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 			return
 		}
 
+		spanStatus = resp.StatusCode
+
 		// 🔥 优化：提前检查 Content-Length（节省带宽）
 		if resp.ContentLength > 0 && fe.maxStreamSize > 0 && resp.ContentLength > fe.maxStreamSize {
 			sizeMB := float64(resp.ContentLength) / 1024 / 1024
@@ -1068,6 +1125,7 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 		// 🔥 等待请求真正结束
 		<-done
 		// defer 会清理资源
+		spanErr = &AbortError{message: "The operation was aborted"}
 
 		select {
 		case req.resultCh <- FetchResult{nil, &AbortError{message: "The operation was aborted"}}:
@@ -1081,6 +1139,7 @@ func (fe *FetchEnhancer) executeRequestAsync(req *FetchRequest) {
 		// 🔥 等待请求真正结束
 		<-done
 		// defer 会清理资源
+		spanErr = reqCtx.Err()
 
 		if reqCtx.Err() == context.DeadlineExceeded {
 			req.resultCh <- FetchResult{nil, fmt.Errorf("请求超时")}
@@ -1106,12 +1165,7 @@ func (fe *FetchEnhancer) pollResult(runtime *goja.Runtime, req *FetchRequest, re
 		case result := <-req.resultCh:
 			// 有结果了
 			if result.err != nil {
-				// 🔥 检查是否为 AbortError
-				if _, isAbortError := result.err.(*AbortError); isAbortError {
-					reject(fe.createAbortErrorObject(runtime, result.err))
-				} else {
-					reject(fe.createErrorObject(runtime, result.err))
-				}
+				reject(fe.createFetchErrorObject(runtime, result.err))
 			} else {
 				resolve(fe.recreateResponse(runtime, result.response))
 			}
@@ -1138,6 +1192,18 @@ func (fe *FetchEnhancer) createAbortErrorObject(runtime *goja.Runtime, err error
 	return fe.createErrorObjectWithName(runtime, err, "AbortError")
 }
 
+// createFetchErrorObject 根据请求错误类型创建 JS 错误对象（AbortError / EgressDeniedError / TypeError）
+func (fe *FetchEnhancer) createFetchErrorObject(runtime *goja.Runtime, err error) goja.Value {
+	if _, isAbortError := err.(*AbortError); isAbortError {
+		return fe.createAbortErrorObject(runtime, err)
+	}
+	var denied *EgressDeniedError
+	if errors.As(err, &denied) {
+		return fe.createErrorObjectWithName(runtime, denied, EgressDeniedErrorName)
+	}
+	return fe.createErrorObject(runtime, err)
+}
+
 // createErrorObjectWithName 创建指定类型的 Error 对象
 func (fe *FetchEnhancer) createErrorObjectWithName(runtime *goja.Runtime, err error, errorName string) goja.Value {
 	errorObj := runtime.NewObject()
@@ -3377,6 +3443,17 @@ func (fe *FetchEnhancer) Close() error {
 	return nil
 }
 
+// Transport 获取底层 HTTP Transport
+func (fe *FetchEnhancer) Transport() http.RoundTripper {
+	return fe.client.Transport
+}
+
+// SetTransport 替换底层 HTTP Transport（本地命令行工具用它把 fetch / axios 指向录制的响应）
+// 🔥 必须在第一次执行前调用：替换后不再经过 SSRF 拨号检查，出站规则（EgressPolicy）仍然生效
+func (fe *FetchEnhancer) SetTransport(transport http.RoundTripper) {
+	fe.client.Transport = transport
+}
+
 // Register 注册模块到 require 系统
 // Fetch 是全局函数，不需要 require
 func (fe *FetchEnhancer) Register(registry *require.Registry) error {
@@ -0,0 +1,57 @@
+package enhance_modules
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"flow-codeblock-go/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startFetchSpan 为出站请求创建 client span
+// 父 span 来自 EgressScope.TraceParent（fetch 在独立 goroutine 中执行，拿不到执行 context）
+func startFetchSpan(ctx context.Context, scope *EgressScope, method, rawURL string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", method),
+		attribute.String("url.full", redactURL(rawURL)),
+	}
+	if scope != nil {
+		if scope.TraceParent.IsValid() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, scope.TraceParent)
+		}
+		if scope.RequestID != "" {
+			attrs = append(attrs, attribute.String("request_id", scope.RequestID))
+		}
+	}
+	return otel.Tracer(utils.TracerName).Start(ctx, "fetch "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+// endFetchSpan 记录响应状态码 / 错误并结束 span
+func endFetchSpan(span trace.Span, statusCode int, err error) {
+	if statusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+		if statusCode >= 500 && err == nil {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", statusCode))
+		}
+	}
+	utils.EndSpan(span, err)
+}
+
+// redactURL 去掉 URL 中的用户名密码和查询参数（可能包含密钥），只保留到路径
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,7 +25,8 @@ type SSRFProtectionConfig struct {
 // - 禁止访问私有 IP 地址（127.0.0.1, 10.x.x.x, 172.16-31.x.x, 192.168.x.x）
 // - 禁止访问 Link-Local 地址（169.254.x.x）
 // - 禁止访问 AWS/阿里云/腾讯云等云平台元数据服务
-// - DNS 解析后再次检查 IP（防止 DNS 重绑定攻击）
+// - 🆕 请求 context 中带有出站规则时（见 EgressScope），检查目标是否在允许范围内（不受 Enabled 影响）
+// - 🔥 域名只解析一次（使用请求 context），检查通过后直接连接检查过的 IP（防止 DNS 重绑定攻击）
 func CreateProtectedDialContext(
 	config *SSRFProtectionConfig,
 	dialTimeout time.Duration,
@@ -37,8 +39,11 @@ func CreateProtectedDialContext(
 	}
 
 	return func(ctx context.Context, network, addr string) (net.Conn, error) {
-		// 1. 如果未启用 SSRF 防护，直接使用标准 Dialer
-		if !config.Enabled {
+		scope := egressScopeFromContext(ctx)
+		restricted := scope != nil && scope.Policy != nil
+
+		// 1. 未启用 SSRF 防护且没有出站规则，直接使用标准 Dialer
+		if !config.Enabled && !restricted {
 			return standardDialer.DialContext(ctx, network, addr)
 		}
 
@@ -47,45 +52,80 @@ func CreateProtectedDialContext(
 		if err != nil {
 			return nil, fmt.Errorf("无效的地址格式: %w", err)
 		}
+		portNum, _ := strconv.Atoi(port)
 
-		// 3. 检查是否是 IP 地址
-		ip := net.ParseIP(host)
-		if ip != nil {
-			// 直接是 IP，检查是否允许
-			if err := checkIPAllowed(ip, config.AllowPrivateIP); err != nil {
-				utils.Warn("SSRF防护：禁止访问私有IP",
-					zap.String("ip", ip.String()),
-					zap.String("addr", addr),
-					zap.Error(err))
-				return nil, err
-			}
-		} else {
-			// 4. 是域名，需要先解析
-			ips, err := net.LookupIP(host)
-			if err != nil {
-				return nil, fmt.Errorf("DNS 解析失败: %w", err)
-			}
+		// 3. 解析出本次连接使用的 IP（IP 目标为其本身）
+		ips, err := resolveDialIPs(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
 
-			if len(ips) == 0 {
-				return nil, fmt.Errorf("DNS 解析未返回任何 IP")
+		// 4. 🆕 出站规则检查（每次新建连接时执行，检查实际连接的 IP）
+		if restricted {
+			if err := scope.checkDial(host, portNum, ips); err != nil {
+				return nil, err
 			}
+		}
 
-			// 5. 检查所有解析的 IP（防止 DNS 重绑定攻击）
-			for _, resolvedIP := range ips {
-				if err := checkIPAllowed(resolvedIP, config.AllowPrivateIP); err != nil {
+		// 5. 检查所有解析的 IP
+		if config.Enabled {
+			for _, ip := range ips {
+				if err := checkIPAllowed(ip, config.AllowPrivateIP); err != nil {
+					if net.ParseIP(host) != nil {
+						utils.Warn("SSRF防护：禁止访问私有IP",
+							zap.String("ip", ip.String()),
+							zap.String("addr", addr),
+							zap.Error(err))
+						return nil, err
+					}
 					utils.Warn("SSRF防护：域名解析到私有IP",
 						zap.String("domain", host),
-						zap.String("resolved_ip", resolvedIP.String()),
+						zap.String("resolved_ip", ip.String()),
 						zap.String("addr", addr),
 						zap.Error(err))
-					return nil, fmt.Errorf("域名 %s 解析到被禁止的 IP %s: %w", host, resolvedIP.String(), err)
+					return nil, fmt.Errorf("域名 %s 解析到被禁止的 IP %s: %w", host, ip.String(), err)
 				}
 			}
 		}
 
-		// 6. 所有检查通过，使用标准 Dialer 建立连接
-		return standardDialer.DialContext(ctx, network, net.JoinHostPort(host, port))
+		// 6. 所有检查通过，依次连接检查过的 IP（不再按域名拨号）
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := standardDialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, lastErr
+	}
+}
+
+// resolveDialIPs 解析拨号目标（使用请求 context，随请求取消）
+// tcp4 / tcp6 只返回对应协议族的地址
+func resolveDialIPs(ctx context.Context, network, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	lookupNetwork := "ip"
+	switch network {
+	case "tcp4", "udp4":
+		lookupNetwork = "ip4"
+	case "tcp6", "udp6":
+		lookupNetwork = "ip6"
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, lookupNetwork, host)
+	if err != nil {
+		return nil, fmt.Errorf("DNS 解析失败: %w", err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("DNS 解析未返回任何 IP")
 	}
+	return ips, nil
 }
 
 // checkIPAllowed 检查 IP 是否允许访问
@@ -2,7 +2,6 @@ package enhance_modules
 
 import (
 	"bytes"
-	"flow-codeblock-go/config"
 	"flow-codeblock-go/utils"
 	"fmt"
 	goRuntime "runtime"
@@ -40,18 +39,15 @@ type XLSXEnhancer struct {
 // NewXLSXEnhancer 创建新的 xlsx 增强器实例。
 //
 // 参数：
-//   - cfg: 应用配置，用于读取 MaxBlobFileSize 和 XLSX 配置
+//   - maxBufferSize: 最大 Buffer 大小（字节，来自 MAX_BLOB_FILE_SIZE_MB）
+//   - maxSnapshotSize: Copy-on-Read 模式的最大文件大小（字节，来自 XLSX_MAX_SNAPSHOT_SIZE_MB）
+//   - maxRows / maxCols: 读取行数 / 列数限制（来自 XLSX_MAX_ROWS / XLSX_MAX_COLS）
 //
 // 返回：
 //   - *XLSXEnhancer: 初始化完成的增强器实例
 //
-// 该函数会从配置中读取 Buffer 大小限制和 Copy-on-Read 阈值，并输出初始化日志。
-func NewXLSXEnhancer(cfg *config.Config) *XLSXEnhancer {
-	maxBufferSize := cfg.Fetch.MaxBlobFileSize
-	maxSnapshotSize := cfg.XLSX.MaxSnapshotSize
-	maxRows := cfg.XLSX.MaxRows // 🔥 新增：读取行数限制
-	maxCols := cfg.XLSX.MaxCols // 🔥 新增：读取列数限制
-
+// 🔥 不依赖 config 包，供 pkg/sandbox 独立使用
+func NewXLSXEnhancer(maxBufferSize, maxSnapshotSize int64, maxRows, maxCols int) *XLSXEnhancer {
 	utils.Debug("XLSXEnhancer initialized (Go excelize native with Copy-on-Read)")
 	utils.Debug("XLSX 配置",
 		zap.Int("max_buffer_mb", int(maxBufferSize/1024/1024)),
@@ -0,0 +1,223 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"flow-codeblock-go/api/flowpb"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+// tokenAdminService Token / 配额管理（与 TokenController 的对应接口共用 TokenService、QuotaService）
+type tokenAdminService struct {
+	flowpb.UnimplementedTokenAdminServiceServer
+
+	tokenService  *service.TokenService
+	policyService *service.PolicyService
+	quotaService  *service.QuotaService
+}
+
+// CreateToken 创建Token
+func (s *tokenAdminService) CreateToken(ctx context.Context, req *flowpb.CreateTokenRequest) (*flowpb.TokenInfo, error) {
+	createReq := model.CreateTokenRequest{
+		WsID:                   req.WsId,
+		Email:                  req.Email,
+		Operation:              req.Operation,
+		Days:                   optionalInt(req.Days),
+		SpecificDate:           req.SpecificDate,
+		RateLimitPerMinute:     optionalInt(req.RateLimitPerMinute),
+		RateLimitBurst:         optionalInt(req.RateLimitBurst),
+		RateLimitWindowSeconds: optionalInt(req.RateLimitWindowSeconds),
+		QuotaType:              req.QuotaType,
+		TotalQuota:             optionalInt(req.TotalQuota),
+		PolicyName:             req.PolicyName,
+	}
+	if len(req.PolicyJson) > 0 {
+		if err := json.Unmarshal(req.PolicyJson, &createReq.Policy); err != nil {
+			return nil, validationError("policy_json 格式错误: " + err.Error())
+		}
+	}
+	// 与 HTTP 接口使用同一套 binding 校验规则
+	if err := binding.Validator.ValidateStruct(&createReq); err != nil {
+		return nil, validationError("请求参数错误: " + err.Error())
+	}
+
+	// 校验沙箱策略（引用的命名策略必须存在）
+	if createReq.PolicyName != nil && *createReq.PolicyName == "" {
+		createReq.PolicyName = nil
+	}
+	if err := s.policyService.ValidateTokenPolicy(ctx, createReq.PolicyName, createReq.Policy); err != nil {
+		return nil, validationError("沙箱策略无效: " + err.Error())
+	}
+
+	tokenInfo, err := s.tokenService.CreateToken(ctx, &createReq)
+	if err != nil {
+		utils.Error("创建Token失败", zap.Error(err))
+		return nil, internalError("创建Token失败: " + err.Error())
+	}
+	return toProtoToken(tokenInfo), nil
+}
+
+// UpdateToken 更新Token
+func (s *tokenAdminService) UpdateToken(ctx context.Context, req *flowpb.UpdateTokenRequest) (*flowpb.TokenInfo, error) {
+	if req.Token == "" {
+		return nil, validationError("缺少token参数")
+	}
+
+	updateReq := model.UpdateTokenRequest{
+		Operation:              req.Operation,
+		SpecificDate:           req.SpecificDate,
+		RateLimitPerMinute:     optionalInt(req.RateLimitPerMinute),
+		RateLimitBurst:         optionalInt(req.RateLimitBurst),
+		RateLimitWindowSeconds: optionalInt(req.RateLimitWindowSeconds),
+		QuotaOperation:         req.QuotaOperation,
+		QuotaAmount:            optionalInt(req.QuotaAmount),
+		QuotaType:              req.QuotaType,
+	}
+	if err := binding.Validator.ValidateStruct(&updateReq); err != nil {
+		return nil, validationError("请求参数错误: " + err.Error())
+	}
+
+	tokenInfo, err := s.tokenService.UpdateToken(ctx, req.Token, &updateReq)
+	if err != nil {
+		utils.Error("更新Token失败", zap.Error(err))
+		return nil, internalError("更新Token失败: " + err.Error())
+	}
+	return toProtoToken(tokenInfo), nil
+}
+
+// DeleteToken 删除Token
+func (s *tokenAdminService) DeleteToken(ctx context.Context, req *flowpb.DeleteTokenRequest) (*flowpb.DeleteTokenResponse, error) {
+	if req.Token == "" {
+		return nil, validationError("缺少token参数")
+	}
+
+	if err := s.tokenService.DeleteToken(ctx, req.Token); err != nil {
+		utils.Error("删除Token失败", zap.Error(err))
+		return nil, internalError("删除Token失败: " + err.Error())
+	}
+	return &flowpb.DeleteTokenResponse{}, nil
+}
+
+// ListTokens 查询Token信息（只提供 ws_id 或 email 时 Token 脱敏显示，与 HTTP 接口一致）
+func (s *tokenAdminService) ListTokens(ctx context.Context, req *flowpb.ListTokensRequest) (*flowpb.ListTokensResponse, error) {
+	tokens, err := s.tokenService.GetTokenInfo(ctx, &model.TokenQueryRequest{
+		WsID:  req.WsId,
+		Email: req.Email,
+		Token: req.Token,
+	})
+	if err != nil {
+		utils.Error("查询Token失败", zap.Error(err))
+		return nil, internalError("查询Token失败: " + err.Error())
+	}
+
+	resp := &flowpb.ListTokensResponse{
+		Tokens: make([]*flowpb.TokenInfo, len(tokens)),
+		Count:  int32(len(tokens)),
+	}
+	for i, token := range tokens {
+		resp.Tokens[i] = toProtoToken(token)
+	}
+	return resp, nil
+}
+
+// GetQuota 查询Token配额（剩余配额优先从 Redis 获取）
+func (s *tokenAdminService) GetQuota(ctx context.Context, req *flowpb.GetQuotaRequest) (*flowpb.QuotaInfo, error) {
+	if req.Token == "" {
+		return nil, validationError("缺少token参数")
+	}
+
+	tokens, err := s.tokenService.GetTokenInfo(ctx, &model.TokenQueryRequest{Token: req.Token})
+	if err != nil {
+		utils.Error("查询Token失败", zap.Error(err))
+		return nil, internalError("查询Token失败: " + err.Error())
+	}
+	if len(tokens) == 0 {
+		return nil, statusError(codes.NotFound, utils.ErrorTypeNotFound, "Token不存在")
+	}
+	info := tokens[0]
+
+	// 时间模式的 Token 没有配额
+	if !info.NeedsQuotaCheck() {
+		return &flowpb.QuotaInfo{
+			QuotaType: info.QuotaType,
+			Message:   "该Token为时间模式，无配额限制",
+		}, nil
+	}
+
+	remainingQuota := 0
+	if info.RemainingQuota != nil {
+		remainingQuota = *info.RemainingQuota
+	}
+	if s.quotaService != nil {
+		if quota, err := s.quotaService.GetRemainingQuota(ctx, req.Token); err == nil {
+			remainingQuota = quota
+		}
+	}
+
+	totalQuota := 0
+	if info.TotalQuota != nil {
+		totalQuota = *info.TotalQuota
+	}
+
+	// 增购后 remaining 可能大于 total，此时 consumed 为 0
+	consumedQuota := 0
+	if totalQuota > remainingQuota {
+		consumedQuota = totalQuota - remainingQuota
+	}
+
+	return &flowpb.QuotaInfo{
+		QuotaType:      info.QuotaType,
+		TotalQuota:     int32(totalQuota),
+		RemainingQuota: int32(remainingQuota),
+		ConsumedQuota:  int32(consumedQuota),
+		QuotaSyncedAt:  optionalTime(info.QuotaSyncedAt),
+	}, nil
+}
+
+// GetQuotaLogs 查询Token配额消耗日志（分页参数的默认值与 HTTP 接口一致）
+func (s *tokenAdminService) GetQuotaLogs(ctx context.Context, req *flowpb.GetQuotaLogsRequest) (*flowpb.GetQuotaLogsResponse, error) {
+	if req.Token == "" {
+		return nil, validationError("缺少token参数")
+	}
+
+	query := &model.QuotaLogsQueryRequest{
+		Token:     req.Token,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Page:      int(req.Page),
+		PageSize:  int(req.PageSize),
+	}
+	logs, total, err := s.tokenService.GetQuotaLogs(ctx, query)
+	if err != nil {
+		utils.Error("查询配额日志失败", zap.Error(err))
+		return nil, internalError("查询配额日志失败: " + err.Error())
+	}
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := query.PageSize
+	if pageSize < 1 || pageSize > 1000 {
+		pageSize = 100
+	}
+
+	resp := &flowpb.GetQuotaLogsResponse{
+		Logs:       make([]*flowpb.QuotaLog, len(logs)),
+		Total:      int32(total),
+		Page:       int32(page),
+		PageSize:   int32(pageSize),
+		TotalPages: int32((total + pageSize - 1) / pageSize),
+	}
+	for i, log := range logs {
+		resp.Logs[i] = toProtoQuotaLog(log)
+	}
+	return resp, nil
+}
@@ -0,0 +1,242 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"flow-codeblock-go/api/flowpb"
+	"flow-codeblock-go/middleware"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// caller 已认证的调用方（Token 认证拦截器写入 context，FlowService 读取）
+type caller struct {
+	requestID    string
+	tokenInfo    *model.TokenInfo
+	authDuration time.Duration
+	authSource   string
+}
+
+type callerKey struct{}
+
+// callerFromContext 获取已认证的调用方（TokenAdminService 的请求中为 nil）
+func callerFromContext(ctx context.Context) *caller {
+	c, _ := ctx.Value(callerKey{}).(*caller)
+	return c
+}
+
+// authenticator 认证拦截器
+//   - FlowService：IP 限流 → Token 认证 → Token 限流 → 沙箱策略
+//   - TokenAdminService：管理员令牌认证
+type authenticator struct {
+	ipLimiter          *middleware.SmartIPRateLimiter // 与 HTTP 接口共用（nil 表示不限制）
+	tokenService       *service.TokenService
+	policyService      *service.PolicyService
+	rateLimiterService *service.RateLimiterService
+	adminToken         string
+}
+
+// unaryInterceptor 一元调用的认证拦截器
+func (a *authenticator) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamInterceptor 流式调用的认证拦截器
+func (a *authenticator) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate 按服务选择认证方式
+func (a *authenticator) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	if strings.HasPrefix(fullMethod, "/"+flowpb.TokenAdminService_ServiceDesc.ServiceName+"/") {
+		return ctx, a.authenticateAdmin(ctx)
+	}
+	return a.authenticateToken(ctx)
+}
+
+// authenticateAdmin 管理员令牌认证（与 AdminAuthMiddleware 一致）
+func (a *authenticator) authenticateAdmin(ctx context.Context) error {
+	token := extractToken(ctx)
+	if token == "" {
+		return statusError(codes.Unauthenticated, utils.ErrorTypeAuthentication,
+			"缺少管理员访问令牌，请在 metadata 中提供 accesstoken")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) != 1 {
+		utils.Warn("gRPC 管理员认证失败",
+			zap.String("peer", peerAddress(ctx)),
+			zap.String("token", utils.MaskToken(token)))
+		return statusError(codes.PermissionDenied, utils.ErrorTypeAuthorization, "管理员令牌无效，访问被拒绝")
+	}
+	return nil
+}
+
+// authenticateToken IP 限流、Token 认证、Token 限流和沙箱策略（与 HTTP 中间件的顺序和错误类型一致）
+func (a *authenticator) authenticateToken(ctx context.Context) (context.Context, error) {
+	// request_id：优先使用客户端传递的 x-request-id，并通过响应 header 返回
+	requestID := firstMetadata(ctx, "x-request-id")
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+	ctx = context.WithValue(ctx, utils.RequestIDKey, requestID)
+
+	// 1. IP 限流（与 SmartIPRateLimiterHandlerWithInstance 一致：未认证成功过的 IP 使用严格的认证前限额）
+	// 🔒 与 HTTP 接口共用同一个限流器，gRPC 端口不能用来绕过 Token 暴力尝试的限制
+	ip := peerIP(ctx)
+	if a.ipLimiter != nil && ip != "" {
+		authenticated := a.ipLimiter.IsAuthenticated(ip)
+		if !a.ipLimiter.GetLimiter(ip, authenticated).Allow() {
+			limitType := "认证前"
+			if authenticated {
+				limitType = "认证后"
+			}
+			utils.Warn("gRPC 智能IP限流拒绝",
+				zap.String("ip", ip),
+				zap.String("limit_type", limitType))
+			return nil, retryableError(utils.ErrorTypeIPRateLimit, "IP 请求频率超限，请稍后再试（"+limitType+"限制）", 0)
+		}
+	}
+
+	// 2. Token 认证
+	token := extractToken(ctx)
+	if token == "" {
+		return nil, statusError(codes.Unauthenticated, utils.ErrorTypeAuthentication,
+			"缺少访问令牌，请在 metadata 中提供 accesstoken")
+	}
+
+	spanCtx, span := utils.StartSpan(ctx, "token.validate")
+	authStart := time.Now()
+	tokenInfo, source, err := a.tokenService.ValidateTokenWithSource(spanCtx, token)
+	utils.EndSpan(span, err)
+	if err != nil {
+		utils.Warn("gRPC Token验证失败",
+			zap.String("token", utils.MaskToken(token)),
+			zap.String("peer", peerAddress(ctx)),
+			zap.Error(err))
+		return nil, statusError(codes.Unauthenticated, utils.ErrorTypeAuthentication, "Token无效: "+err.Error())
+	}
+	authDuration := time.Since(authStart)
+	if a.ipLimiter != nil && ip != "" {
+		a.ipLimiter.MarkAuthenticated(ip)
+	}
+
+	// 3. Token 限流（限流器异常时不阻塞请求）
+	if rateLimitConfig := tokenInfo.GetRateLimitConfig(); !rateLimitConfig.Unlimited {
+		allowed, limitInfo, err := a.rateLimiterService.CheckLimit(ctx, tokenInfo.AccessToken, rateLimitConfig)
+		if err != nil {
+			utils.Error("gRPC 限流检查异常", zap.String("request_id", requestID), zap.Error(err))
+		} else {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(
+				"x-ratelimit-limit", fmt.Sprintf("%d", rateLimitConfig.PerMinute),
+				"x-ratelimit-remaining", fmt.Sprintf("%d", limitInfo.Remaining),
+				"x-ratelimit-reset", limitInfo.ResetTime.Format(time.RFC3339),
+			))
+			if !allowed {
+				utils.Warn("gRPC 限流拒绝",
+					zap.String("token", utils.MaskToken(tokenInfo.AccessToken)),
+					zap.String("ws_id", tokenInfo.WsID),
+					zap.String("limit_type", limitInfo.LimitType),
+					zap.String("message", limitInfo.Message))
+				return nil, retryableError(utils.ErrorTypeTokenRateLimit, limitInfo.Message, limitInfo.RetryAfter)
+			}
+		}
+	}
+
+	// 4. 沙箱策略和调度身份（Token 引用的命名策略不存在时拒绝请求）
+	policy, err := a.policyService.ResolveForToken(ctx, tokenInfo)
+	if err != nil {
+		utils.Error("沙箱策略加载失败",
+			zap.String("token", utils.MaskToken(tokenInfo.AccessToken)),
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		if errors.Is(err, service.ErrPolicyNotFound) {
+			return nil, statusError(codes.PermissionDenied, utils.ErrorTypeAuthorization, "Token引用的沙箱策略不存在，请联系管理员")
+		}
+		return nil, internalError("沙箱策略加载失败")
+	}
+	ctx = sandbox.WithExecutionTenant(ctx, tokenInfo.AccessToken, tokenInfo.WsID)
+	ctx = sandbox.WithSandboxPolicy(ctx, policy)
+
+	return context.WithValue(ctx, callerKey{}, &caller{
+		requestID:    requestID,
+		tokenInfo:    tokenInfo,
+		authDuration: authDuration,
+		authSource:   source,
+	}), nil
+}
+
+// extractToken 从 metadata 提取 Token（与 HTTP 接口支持的请求头一致，metadata 的 key 均为小写）
+func extractToken(ctx context.Context) string {
+	if token := firstMetadata(ctx, "accesstoken"); token != "" {
+		return token
+	}
+	if token := firstMetadata(ctx, "access-token"); token != "" {
+		return token
+	}
+	if auth := firstMetadata(ctx, "authorization"); auth != "" {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// firstMetadata 获取 metadata 中 key 的第一个值
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerAddress 调用方地址（用于日志）
+func peerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// peerIP 调用方 IP（IP 限流的键；不信任 metadata 中的转发头）
+func peerIP(ctx context.Context) string {
+	addr := peerAddress(ctx)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// contextStream 替换 context 的 ServerStream（流式调用中传递认证结果）
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
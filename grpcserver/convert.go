@@ -0,0 +1,121 @@
+package grpcserver
+
+import (
+	"encoding/json"
+
+	"flow-codeblock-go/api/flowpb"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+)
+
+// toProtoLog console 输出
+func toProtoLog(entry model.ConsoleLogEntry) *flowpb.ConsoleLog {
+	return &flowpb.ConsoleLog{
+		Level:     entry.Level,
+		Message:   entry.Message,
+		Timestamp: entry.Timestamp,
+		OffsetMs:  entry.OffsetMs,
+	}
+}
+
+func toProtoLogs(entries []model.ConsoleLogEntry) []*flowpb.ConsoleLog {
+	if len(entries) == 0 {
+		return nil
+	}
+	logs := make([]*flowpb.ConsoleLog, len(entries))
+	for i, entry := range entries {
+		logs[i] = toProtoLog(entry)
+	}
+	return logs
+}
+
+// toProtoPhases 分阶段耗时
+func toProtoPhases(phases []model.ExecutionPhase) []*flowpb.ExecutionPhase {
+	result := make([]*flowpb.ExecutionPhase, len(phases))
+	for i, phase := range phases {
+		result[i] = &flowpb.ExecutionPhase{
+			Phase:      phase.Phase,
+			DurationMs: phase.DurationMs,
+			Cache:      phase.Cache,
+		}
+	}
+	return result
+}
+
+// toProtoToken Token 信息（时间格式与 HTTP 接口一致：上海时区 yyyy-MM-dd HH:mm:ss）
+func toProtoToken(info *model.TokenInfo) *flowpb.TokenInfo {
+	token := &flowpb.TokenInfo{
+		Id:                     int64(info.ID),
+		WsId:                   info.WsID,
+		Email:                  info.Email,
+		AccessToken:            info.AccessToken,
+		CreatedAt:              formatTime(&info.CreatedAt),
+		ExpiresAt:              optionalTime(info.ExpiresAt),
+		OperationType:          info.OperationType,
+		IsActive:               info.IsActive,
+		RateLimitPerMinute:     optionalInt32(info.RateLimitPerMinute),
+		RateLimitBurst:         optionalInt32(info.RateLimitBurst),
+		RateLimitWindowSeconds: optionalInt32(info.RateLimitWindowSeconds),
+		QuotaType:              info.QuotaType,
+		TotalQuota:             optionalInt32(info.TotalQuota),
+		RemainingQuota:         optionalInt32(info.RemainingQuota),
+		QuotaSyncedAt:          optionalTime(info.QuotaSyncedAt),
+		UpdatedAt:              formatTime(&info.UpdatedAt),
+		PolicyName:             info.PolicyName,
+	}
+	if info.Policy != nil {
+		token.PolicyJson, _ = json.Marshal(info.Policy)
+	}
+	return token
+}
+
+// toProtoQuotaLog 配额日志
+func toProtoQuotaLog(log *model.QuotaLog) *flowpb.QuotaLog {
+	return &flowpb.QuotaLog{
+		Id:                    log.ID,
+		Token:                 log.Token,
+		WsId:                  log.WsID,
+		Email:                 log.Email,
+		QuotaBefore:           int32(log.QuotaBefore),
+		QuotaAfter:            int32(log.QuotaAfter),
+		QuotaChange:           int32(log.QuotaChange),
+		Action:                log.Action,
+		RequestId:             log.RequestID,
+		ExecutionSuccess:      log.ExecutionSuccess,
+		ExecutionErrorType:    log.ExecutionErrorType,
+		ExecutionErrorMessage: log.ExecutionErrorMessage,
+		CreatedAt:             formatTime(&log.CreatedAt),
+	}
+}
+
+// formatTime 格式化时间（零值返回空字符串，对应 HTTP 接口中的 null）
+func formatTime(t *model.ShanghaiTime) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return utils.FormatTime(t.Time)
+}
+
+func optionalTime(t *model.ShanghaiTime) *string {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	formatted := formatTime(t)
+	return &formatted
+}
+
+func optionalInt32(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	n := int32(*v)
+	return &n
+}
+
+func optionalInt(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	n := int(*v)
+	return &n
+}
@@ -0,0 +1,50 @@
+package grpcserver
+
+import (
+	"time"
+
+	"flow-codeblock-go/utils"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain ErrorInfo.Domain（客户端据此识别本服务的错误类型）
+const errorDomain = "flow-codeblock"
+
+// statusError 创建 gRPC 错误，错误类型（与 HTTP 接口的 error.type 一致）放在 ErrorInfo.Reason 中
+func statusError(code codes.Code, errorType, message string) error {
+	return withDetails(status.New(code, message), &errdetails.ErrorInfo{Reason: errorType, Domain: errorDomain})
+}
+
+// retryableError 限流 / 配额被拒绝的错误，附带 RetryInfo（与 HTTP 接口的 Retry-After 一致）
+func retryableError(errorType, message string, retryAfterSeconds int) error {
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: errorType, Domain: errorDomain}}
+	if retryAfterSeconds > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(time.Duration(retryAfterSeconds) * time.Second),
+		})
+	}
+	return withDetails(status.New(codes.ResourceExhausted, message), details...)
+}
+
+// validationError 请求参数错误
+func validationError(message string) error {
+	return statusError(codes.InvalidArgument, utils.ErrorTypeValidation, message)
+}
+
+// internalError 服务内部错误
+func internalError(message string) error {
+	return statusError(codes.Internal, utils.ErrorTypeInternal, message)
+}
+
+// withDetails 附加错误详情（附加失败时返回不带详情的错误）
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	if detailed, err := st.WithDetails(details...); err == nil {
+		return detailed.Err()
+	}
+	return st.Err()
+}
@@ -0,0 +1,264 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/api/flowpb"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+// flowService 代码执行（与 POST /flow/codeblock 共用配额、执行器、统计和执行历史）
+//
+// 执行前被拒绝（认证、限流、参数、配额）返回 gRPC 错误；
+// 脚本执行失败（语法错误、运行时错误、超时、排队被拒绝等）与 HTTP 接口一致，返回 success=false 的响应
+type flowService struct {
+	flowpb.UnimplementedFlowServiceServer
+
+	executor       *sandbox.JSExecutor
+	quotaService   *service.QuotaService
+	statsService   *service.StatsService
+	historyService *service.HistoryService
+}
+
+// Execute 执行代码
+func (s *flowService) Execute(ctx context.Context, req *flowpb.ExecuteRequest) (*flowpb.ExecuteResponse, error) {
+	return s.execute(ctx, req)
+}
+
+// ExecuteStream 执行代码，执行过程中逐条推送 console 输出，最后推送执行结果
+func (s *flowService) ExecuteStream(req *flowpb.ExecuteRequest, stream flowpb.FlowService_ExecuteStreamServer) error {
+	// 🔥 console 回调在执行 goroutine 中同步调用，不能直接 Send（慢客户端会拖住脚本执行）
+	// 先放入队列，由单独的 goroutine 推送；队列长度受 CONSOLE_MAX_LINES 限制
+	logs := newLogQueue()
+	sendDone := make(chan error, 1)
+	go func() {
+		sendDone <- logs.drain(stream)
+	}()
+
+	resp, err := s.execute(sandbox.WithConsoleListener(stream.Context(), logs.push), req)
+	logs.close()
+	if sendErr := <-sendDone; sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// console 输出已逐条推送，结果中只保留截断标记
+	resp.Logs = nil
+	return stream.Send(&flowpb.ExecuteEvent{Event: &flowpb.ExecuteEvent_Result{Result: resp}})
+}
+
+// execute 解析输入、扣减配额、执行代码，并记录统计和执行历史
+func (s *flowService) execute(ctx context.Context, req *flowpb.ExecuteRequest) (*flowpb.ExecuteResponse, error) {
+	startTime := time.Now()
+	c := callerFromContext(ctx)
+	if c == nil {
+		return nil, statusError(codes.Unauthenticated, utils.ErrorTypeAuthentication, "认证失败：未找到Token信息")
+	}
+	tokenInfo := c.tokenInfo
+
+	// 分阶段耗时：认证阶段由拦截器完成，这里补记
+	timeline := s.executor.NewTimeline()
+	authCache := sandbox.PhaseCacheMiss
+	if c.authSource == service.TokenSourceHot || c.authSource == service.TokenSourceWarm {
+		authCache = sandbox.PhaseCacheHit
+	}
+	timeline.Record(sandbox.PhaseAuth, c.authDuration, authCache)
+
+	// 1. 参数校验（扣减配额之前拒绝超长代码，与 HTTP 接口的 Base64 长度预检查一致；输入大小由执行器校验）
+	if req.Code == "" {
+		return nil, validationError("代码不能为空")
+	}
+	if maxCodeLength := s.executor.GetMaxCodeLength(); len(req.Code) > maxCodeLength {
+		return nil, validationError(fmt.Sprintf("代码长度超过限制: %d > %d字节", len(req.Code), maxCodeLength))
+	}
+
+	decodeStart := time.Now()
+	input := map[string]interface{}{}
+	if len(req.InputJson) > 0 {
+		if err := json.Unmarshal(req.InputJson, &input); err != nil || input == nil {
+			return nil, validationError("input_json 必须是 JSON 对象")
+		}
+	}
+	timeline.Since(sandbox.PhaseDecode, decodeStart, "")
+
+	// 2. 配额扣减（count / hybrid 类型 Token）
+	if s.quotaService != nil && tokenInfo.NeedsQuotaCheck() {
+		quotaStart := time.Now()
+		_, _, err := s.quotaService.ConsumeQuota(ctx, tokenInfo.AccessToken, tokenInfo.WsID, tokenInfo.Email, c.requestID, true, nil, nil)
+		timeline.Since(sandbox.PhaseQuota, quotaStart, "")
+		if err != nil {
+			utils.Warn("配额不足",
+				zap.String("token", utils.MaskToken(tokenInfo.AccessToken)),
+				zap.String("request_id", c.requestID),
+				zap.Error(err))
+			return nil, retryableError("QuotaExceeded", "配额已用完，请联系管理员充值", 0)
+		}
+	}
+
+	// 3. 执行
+	moduleInfo := utils.ParseModuleUsage(req.Code)
+	utils.Debug("开始执行代码",
+		zap.String("request_id", c.requestID),
+		zap.String("transport", "grpc"),
+		zap.Int("code_length", len(req.Code)),
+		zap.Bool("has_require", moduleInfo.HasRequire),
+		zap.Int("module_count", moduleInfo.ModuleCount),
+		zap.String("ws_id", tokenInfo.WsID))
+
+	result, execErr := s.executor.Execute(sandbox.WithTimeline(ctx, timeline), req.Code, input)
+	totalTime := time.Since(startTime).Milliseconds()
+
+	resp := &flowpb.ExecuteResponse{
+		Timing: &flowpb.ExecuteTiming{
+			ExecutionTimeMs: totalTime,
+			TotalTimeMs:     totalTime,
+		},
+		Timestamp: utils.FormatTime(utils.Now()),
+		RequestId: c.requestID,
+	}
+	if req.Debug {
+		resp.Timing.Phases = toProtoPhases(timeline.Phases())
+	}
+
+	entry := &service.HistoryEntry{
+		RequestID:       c.requestID,
+		Token:           tokenInfo.AccessToken,
+		WsID:            tokenInfo.WsID,
+		Email:           tokenInfo.Email,
+		Code:            req.Code,
+		Input:           input,
+		ExecutionTimeMs: totalTime,
+	}
+
+	status := "success"
+	if execErr != nil {
+		status = "failed"
+		execError := &model.ExecutionError{Type: "RuntimeError", Message: execErr.Error()}
+		if e, ok := execErr.(*model.ExecutionError); ok {
+			execError = e
+		}
+		resp.Error = &flowpb.ExecuteError{
+			Type:       execError.Type,
+			Message:    execError.Message,
+			Stack:      execError.Stack,
+			RetryAfter: int32(execError.RetryAfterSeconds()),
+		}
+		resp.Logs, resp.LogsTruncated = toProtoLogs(execError.Logs), execError.LogsTruncated
+		entry.ErrorType, entry.ErrorMessage = execError.Type, execError.Message
+		entry.Logs, entry.LogsTruncated = execError.Logs, execError.LogsTruncated
+
+		utils.Error("代码执行失败",
+			zap.String("request_id", c.requestID),
+			zap.String("transport", "grpc"),
+			zap.String("error_type", execError.Type),
+			zap.String("error_message", execError.Message),
+			zap.Int64("total_time_ms", totalTime),
+			zap.String("ws_id", tokenInfo.WsID),
+			zap.String("email", tokenInfo.Email))
+	} else {
+		resp.Success = true
+		resp.ResultJson = result.JSONData
+		if len(resp.ResultJson) == 0 {
+			resp.ResultJson, _ = json.Marshal(result.Result)
+		}
+		resp.Logs, resp.LogsTruncated = toProtoLogs(result.Logs), result.LogsTruncated
+		entry.Result, entry.ResultJSON = result.Result, result.JSONData
+		entry.Logs, entry.LogsTruncated = result.Logs, result.LogsTruncated
+
+		utils.Info("代码执行成功",
+			zap.String("request_id", c.requestID),
+			zap.String("transport", "grpc"),
+			zap.Int64("execution_time_ms", totalTime),
+			zap.String("ws_id", tokenInfo.WsID),
+			zap.String("email", tokenInfo.Email))
+	}
+
+	// 4. 统计和执行历史（与 HTTP 接口一致，异步）
+	if s.statsService != nil {
+		s.statsService.RecordExecutionStats(&model.ExecutionStatsRecord{
+			ExecutionID:     c.requestID,
+			Token:           tokenInfo.AccessToken,
+			WsID:            tokenInfo.WsID,
+			Email:           tokenInfo.Email,
+			HasRequire:      moduleInfo.HasRequire,
+			ModulesUsed:     moduleInfo.GetModuleList(),
+			ModuleCount:     moduleInfo.ModuleCount,
+			ExecutionStatus: status,
+			ExecutionTimeMs: totalTime,
+			CodeLength:      len(req.Code),
+			IsAsync:         s.executor.GetAnalyzer().AnalyzeCode(req.Code).IsAsync,
+			ExecutionDate:   time.Now().Format("2006-01-02"),
+			ExecutionTime:   time.Now(),
+		})
+	}
+	s.historyService.Record(sandbox.SandboxPolicyFromContext(ctx), entry)
+
+	return resp, nil
+}
+
+// logQueue ExecuteStream 的 console 输出队列（push 不阻塞，drain 在单独的 goroutine 中推送）
+type logQueue struct {
+	mu      sync.Mutex
+	entries []model.ConsoleLogEntry
+	closed  bool
+	notify  chan struct{}
+}
+
+func newLogQueue() *logQueue {
+	return &logQueue{notify: make(chan struct{}, 1)}
+}
+
+// push 追加一条输出（sandbox.ConsoleListener）
+func (q *logQueue) push(entry model.ConsoleLogEntry) {
+	q.mu.Lock()
+	q.entries = append(q.entries, entry)
+	q.mu.Unlock()
+	q.wake()
+}
+
+// close 执行结束，drain 推送完剩余输出后返回
+func (q *logQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.wake()
+}
+
+func (q *logQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// drain 按顺序推送输出，直到队列关闭且为空（推送失败时返回错误，之后的输出丢弃）
+func (q *logQueue) drain(stream flowpb.FlowService_ExecuteStreamServer) error {
+	for range q.notify {
+		q.mu.Lock()
+		entries, closed := q.entries, q.closed
+		q.entries = nil
+		q.mu.Unlock()
+
+		for _, entry := range entries {
+			if err := stream.Send(&flowpb.ExecuteEvent{Event: &flowpb.ExecuteEvent_Log{Log: toProtoLog(entry)}}); err != nil {
+				return err
+			}
+		}
+		if closed {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"runtime/debug"
+	"time"
+
+	"flow-codeblock-go/api/flowpb"
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/middleware"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// Server gRPC 服务（与 gin 并行监听独立端口）
+//
+// 与 HTTP 接口共用同一组服务实例：
+//   - IP 限流、Token 认证、Token 限流、沙箱策略在拦截器中完成（与 SmartIPRateLimiter / TokenAuthMiddleware / RateLimiterMiddleware / SandboxPolicyMiddleware 一致）
+//   - 配额扣减、执行、统计和执行历史在 FlowService 中完成（与 POST /flow/codeblock 一致）
+//   - TokenAdminService 使用管理员令牌认证（与 AdminAuthMiddleware 一致）
+type Server struct {
+	cfg    config.GRPCConfig
+	server *grpc.Server
+}
+
+// NewServer 创建 gRPC 服务并注册 FlowService 和 TokenAdminService
+func NewServer(
+	executor *sandbox.JSExecutor,
+	cfg *config.Config,
+	tokenService *service.TokenService,
+	policyService *service.PolicyService,
+	rateLimiterService *service.RateLimiterService,
+	quotaService *service.QuotaService,
+	statsService *service.StatsService,
+	historyService *service.HistoryService,
+	ipLimiter *middleware.SmartIPRateLimiter,
+	adminToken string,
+) *Server {
+	auth := &authenticator{
+		ipLimiter:          ipLimiter,
+		tokenService:       tokenService,
+		policyService:      policyService,
+		rateLimiterService: rateLimiterService,
+		adminToken:         adminToken,
+	}
+
+	server := grpc.NewServer(
+		grpc.MaxRecvMsgSize(cfg.GRPC.MaxRecvMsgMB*1024*1024),
+		grpc.MaxConcurrentStreams(cfg.GRPC.MaxConcurrentStreams),
+		grpc.ChainUnaryInterceptor(recoveryUnaryInterceptor, auth.unaryInterceptor),
+		grpc.ChainStreamInterceptor(recoveryStreamInterceptor, auth.streamInterceptor),
+	)
+
+	flowpb.RegisterFlowServiceServer(server, &flowService{
+		executor:       executor,
+		quotaService:   quotaService,
+		statsService:   statsService,
+		historyService: historyService,
+	})
+	flowpb.RegisterTokenAdminServiceServer(server, &tokenAdminService{
+		tokenService:  tokenService,
+		policyService: policyService,
+		quotaService:  quotaService,
+	})
+
+	return &Server{cfg: cfg.GRPC, server: server}
+}
+
+// ListenAndServe 监听端口并处理请求（阻塞，调用 Shutdown 后返回 nil）
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", ":"+s.cfg.Port)
+	if err != nil {
+		return err
+	}
+	return s.server.Serve(lis)
+}
+
+// Shutdown 停止接受新请求并等待处理中的请求完成，超时后强制关闭连接
+func (s *Server) Shutdown(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		utils.Warn("gRPC 服务优雅关闭超时，强制关闭", zap.Duration("timeout", timeout))
+		s.server.Stop()
+	}
+}
+
+// recoveryUnaryInterceptor 捕获处理函数中的 panic，返回 Internal 错误（与 gin.Recovery 一致，不让单个请求拖垮进程）
+func recoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// recoveryStreamInterceptor 流式调用的 panic 捕获
+func recoveryStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// recovered 记录 panic 并转换为 Internal 错误
+func recovered(method string, r interface{}) error {
+	utils.Error("gRPC 请求处理 panic",
+		zap.String("method", method),
+		zap.Any("panic", r),
+		zap.String("stack", string(debug.Stack())))
+	return internalError("服务内部错误")
+}
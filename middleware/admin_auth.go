@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -16,7 +17,7 @@ func AdminAuthMiddleware(adminToken string) gin.HandlerFunc {
 		token := extractToken(c)
 
 		if token == "" {
-			utils.RespondError(c, http.StatusUnauthorized,
+			ginutil.RespondError(c, http.StatusUnauthorized,
 				utils.ErrorTypeAuthentication,
 				"缺少管理员访问令牌，请在请求头中提供accessToken",
 				nil)
@@ -32,7 +33,7 @@ func AdminAuthMiddleware(adminToken string) gin.HandlerFunc {
 				zap.String("token", token[:min(8, len(token))]+"***"),
 			)
 
-			utils.RespondError(c, http.StatusForbidden,
+			ginutil.RespondError(c, http.StatusForbidden,
 				utils.ErrorTypeAuthorization,
 				"管理员令牌无效，访问被拒绝",
 				nil)
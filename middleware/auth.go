@@ -8,6 +8,7 @@ import (
 	"flow-codeblock-go/model"
 	"flow-codeblock-go/service"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -21,7 +22,7 @@ func TokenAuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
 		// 1. 从请求头获取Token
 		token := extractToken(c)
 		if token == "" {
-			utils.RespondError(c, http.StatusUnauthorized,
+			ginutil.RespondError(c, http.StatusUnauthorized,
 				utils.ErrorTypeAuthentication,
 				"缺少访问令牌，请在请求头中提供accessToken",
 				nil)
@@ -30,7 +31,11 @@ func TokenAuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
 		}
 
 		// 2. 验证Token（优先从缓存获取）
-		tokenInfo, err := tokenService.ValidateToken(c.Request.Context(), token)
+		// 🆕 链路追踪：Token 校验（缓存 / Redis / DB）
+		spanCtx, span := utils.StartSpan(c.Request.Context(), "token.validate")
+		authStart := time.Now()
+		tokenInfo, source, err := tokenService.ValidateTokenWithSource(spanCtx, token)
+		utils.EndSpan(span, err)
 		if err != nil {
 			utils.Warn("Token验证失败",
 				zap.String("token", utils.MaskToken(token)),
@@ -38,7 +43,7 @@ func TokenAuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
 				zap.Error(err),
 			)
 
-			utils.RespondError(c, http.StatusUnauthorized,
+			ginutil.RespondError(c, http.StatusUnauthorized,
 				utils.ErrorTypeAuthentication,
 				"Token无效: "+err.Error(),
 				nil)
@@ -51,6 +56,9 @@ func TokenAuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
 		c.Set("token", token)  // 🔥 添加token，供配额服务使用
 		c.Set("wsId", tokenInfo.WsID)
 		c.Set("userEmail", tokenInfo.Email)
+		// 🆕 认证阶段耗时和缓存来源（执行时间线的 auth 阶段）
+		c.Set("authDuration", time.Since(authStart))
+		c.Set("authSource", source)
 
 		// 4. 标记IP已认证成功（用于智能IP限流）
 		MarkIPAuthenticated(c)
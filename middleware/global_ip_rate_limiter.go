@@ -7,6 +7,7 @@ import (
 
 	"flow-codeblock-go/config"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -156,7 +157,7 @@ func GlobalIPRateLimiterMiddleware(cfg *config.Config) gin.HandlerFunc {
 				zap.String("path", c.Request.URL.Path),
 			)
 
-			utils.RespondError(c, http.StatusTooManyRequests,
+			ginutil.RespondError(c, http.StatusTooManyRequests,
 				utils.ErrorTypeIPRateLimit,
 				"IP 请求频率超限，请稍后再试",
 				map[string]interface{}{
@@ -217,7 +218,7 @@ func HandleRateLimitExceeded(c *gin.Context, rate, burst int) {
 		zap.String("path", c.Request.URL.Path),
 	)
 
-	utils.RespondError(c, http.StatusTooManyRequests,
+	ginutil.RespondError(c, http.StatusTooManyRequests,
 		utils.ErrorTypeIPRateLimit,
 		"IP 请求频率超限，请稍后再试",
 		map[string]interface{}{
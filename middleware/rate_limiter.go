@@ -7,6 +7,7 @@ import (
 
 	"flow-codeblock-go/service"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -80,7 +81,8 @@ func RateLimiterMiddleware(limiterService *service.RateLimiterService) gin.Handl
 				zap.Duration("duration", duration),
 			)
 
-			utils.RespondError(c, http.StatusTooManyRequests,
+			ginutil.SetRetryAfter(c, limitInfo.RetryAfter)
+			ginutil.RespondError(c, http.StatusTooManyRequests,
 				utils.ErrorTypeTokenRateLimit,
 				limitInfo.Message,
 				map[string]interface{}{
@@ -148,6 +150,7 @@ func OptionalRateLimiterMiddleware(limiterService *service.RateLimiterService) g
 		c.Header("X-RateLimit-Reset", limitInfo.ResetTime.Format(time.RFC3339))
 
 		if !allowed {
+			ginutil.SetRetryAfter(c, limitInfo.RetryAfter)
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"success": false,
 				"error": map[string]interface{}{
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/service"
+	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SandboxPolicyMiddleware 沙箱策略中间件（必须在 TokenAuthMiddleware 之后）
+// 解析 Token 的生效策略并写入请求 context，JSExecutor 据此确定本次执行的限制
+// 🆕 同时写入调度身份（Token / 工作空间），供公平调度器分组
+//
+// 🔒 Token 引用的命名策略不存在或加载失败时拒绝请求（不退回全局默认值，避免放宽限制）
+func SandboxPolicyMiddleware(policyService *service.PolicyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenInfo, ok := GetTokenInfo(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		policy, err := policyService.ResolveForToken(c.Request.Context(), tokenInfo)
+		if err != nil {
+			utils.Error("沙箱策略加载失败",
+				zap.String("token", utils.MaskToken(tokenInfo.AccessToken)),
+				zap.String("request_id", c.GetString("request_id")),
+				zap.Error(err))
+
+			if errors.Is(err, service.ErrPolicyNotFound) {
+				ginutil.RespondError(c, http.StatusForbidden,
+					utils.ErrorTypeAuthorization,
+					"Token引用的沙箱策略不存在，请联系管理员",
+					nil)
+			} else {
+				ginutil.RespondError(c, http.StatusInternalServerError,
+					utils.ErrorTypeInternal,
+					"沙箱策略加载失败",
+					nil)
+			}
+			c.Abort()
+			return
+		}
+
+		// 🆕 调度身份：公平调度器按 Token / 工作空间分配并发槽位
+		reqCtx := sandbox.WithExecutionTenant(c.Request.Context(), tokenInfo.AccessToken, tokenInfo.WsID)
+		c.Request = c.Request.WithContext(sandbox.WithSandboxPolicy(reqCtx, policy))
+		c.Next()
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"flow-codeblock-go/config"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -240,7 +241,7 @@ func SmartIPRateLimiterMiddleware(cfg *config.Config) gin.HandlerFunc {
 				zap.Bool("authenticated", authenticated),
 			)
 
-			utils.RespondError(c, http.StatusTooManyRequests,
+			ginutil.RespondError(c, http.StatusTooManyRequests,
 				utils.ErrorTypeIPRateLimit,
 				"IP 请求频率超限，请稍后再试（"+limitType+"限制）",
 				map[string]interface{}{
@@ -292,7 +293,7 @@ func SmartIPRateLimiterHandlerWithInstance(limiter *SmartIPRateLimiter, cfg *con
 				zap.Bool("authenticated", authenticated),
 			)
 
-			utils.RespondError(c, http.StatusTooManyRequests,
+			ginutil.RespondError(c, http.StatusTooManyRequests,
 				utils.ErrorTypeIPRateLimit,
 				"IP 请求频率超限，请稍后再试（"+limitType+"限制）",
 				map[string]interface{}{
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"flow-codeblock-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware 链路追踪中间件（必须在 RequestIDMiddleware 之后）
+//
+// 功能：
+//  1. 从请求头的 W3C traceparent 中提取上游链路（没有时开始新的链路）
+//  2. 为每个请求创建 server span，附加 request_id、路由和状态码
+//  3. 把 span 和 request_id 写入请求 context，后续的 Token 校验、配额、执行、出站 fetch 的 span 都挂在其下
+func TracingMiddleware() gin.HandlerFunc {
+	tracer := otel.Tracer(utils.TracerName)
+	propagator := otel.GetTextMapPropagator()
+
+	return func(c *gin.Context) {
+		requestID := c.GetString("request_id")
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx = context.WithValue(ctx, utils.RequestIDKey, requestID)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", c.Request.Method, route),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("client.address", c.ClientIP()),
+				attribute.String("request_id", requestID),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if wsID := c.GetString("wsId"); wsID != "" {
+			span.SetAttributes(attribute.String("ws_id", wsID))
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package execmodel 沙箱执行相关的数据类型（只依赖标准库，pkg/sandbox 与服务端共用）
+package execmodel
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// ExecutorStats 执行器统计信息
+type ExecutorStats struct {
+	TotalExecutions   int64            `json:"totalExecutions"`
+	SuccessfulExecs   int64            `json:"successfulExecutions"`
+	FailedExecs       int64            `json:"failedExecutions"`
+	CurrentExecutions int64            `json:"currentExecutions"`
+	SuccessRate       float64          `json:"successRate"`
+	AvgExecutionTime  int64            `json:"avgExecutionTime"` // 毫秒
+	TotalTime         int64            `json:"totalExecutionTime"`
+	MemStats          runtime.MemStats `json:"memStats"`
+
+	// 执行策略统计
+	SyncExecutions  int64 `json:"syncExecutions"`  // 使用 Runtime 池的次数
+	AsyncExecutions int64 `json:"asyncExecutions"` // 使用 EventLoop 的次数
+
+	// 熔断器统计
+	CircuitBreakerTrips int64 `json:"circuitBreakerTrips"` // 熔断器触发次数
+
+	// 🔥 Runtime 管理统计（方案D：限次重用）
+	RuntimeDestroyCount int64 `json:"runtimeDestroyCount"` // Runtime 销毁次数
+
+	// 🆕 EventLoop 池统计（异步代码路径）
+	EventLoopPool EventLoopPoolStats `json:"eventLoopPool"`
+}
+
+// EventLoopPoolStats EventLoop 池统计信息
+type EventLoopPoolStats struct {
+	Size                int   `json:"size"`                // 当前池大小
+	Available           int   `json:"available"`           // 空闲可用的 EventLoop 数量
+	MinSize             int   `json:"minSize"`             // 最小池大小
+	MaxSize             int   `json:"maxSize"`             // 最大池大小
+	PooledExecutions    int64 `json:"pooledExecutions"`    // 使用池中 EventLoop 的执行次数
+	TemporaryExecutions int64 `json:"temporaryExecutions"` // 获取超时、使用临时 EventLoop 的执行次数
+	DestroyCount        int64 `json:"destroyCount"`        // 销毁次数（达到重用上限、重置失败、超时丢弃、健康检查回收）
+	AbandonedCount      int64 `json:"abandonedCount"`      // 超时/取消后丢弃的次数（执行状态不可复用）
+	ResetFailures       int64 `json:"resetFailures"`       // 全局状态重置失败次数
+}
+
+// ExecutionError 自定义执行错误
+type ExecutionError struct {
+	Type    string
+	Message string
+	Stack   string `json:",omitempty"` // 🔥 新增：支持JavaScript错误的stack trace
+
+	// 🆕 capture 模式下失败前捕获的 console 输出（不参与 Error() 文本）
+	Logs          []ConsoleLogEntry `json:"-"`
+	LogsTruncated bool              `json:"-"`
+
+	// 🆕 排队被拒绝（ConcurrencyError / QueueFullError）时建议的重试等待时间（用于 429 的 Retry-After）
+	RetryAfter time.Duration `json:"-"`
+}
+
+// RetryAfterSeconds 建议的重试等待秒数（向上取整；0 表示不是排队拒绝）
+func (e *ExecutionError) RetryAfterSeconds() int {
+	if e.RetryAfter <= 0 {
+		return 0
+	}
+	return int((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+func (e *ExecutionError) Error() string {
+	// 如果有stack信息，返回完整的错误信息
+	if e.Stack != "" {
+		return fmt.Sprintf("%s\n%s", e.Message, e.Stack)
+	}
+	return e.Message
+}
+
+// ExecutionResult 执行结果包装
+type ExecutionResult struct {
+	Result    interface{}
+	RequestID string // 🔄 改名：ExecutionId → RequestID（复用 HTTP 请求ID）
+	JSONData  []byte `json:"-"` // 🔥 预序列化的 JSON 数据（避免重复序列化）
+
+	// 🆕 capture 模式下捕获的 console 输出
+	Logs          []ConsoleLogEntry `json:"-"`
+	LogsTruncated bool              `json:"-"` // 超出条数/字节上限，后续输出已丢弃
+}
+
+// ConsoleLogEntry 捕获的单条 console 输出
+type ConsoleLogEntry struct {
+	Level     string `json:"level"`     // log / info / debug / warn / error / table
+	Message   string `json:"message"`   // 格式化后的输出内容
+	Timestamp string `json:"timestamp"` // 输出时间（上海时区，毫秒精度）
+	OffsetMs  int64  `json:"offsetMs"`  // 相对执行开始的毫秒数
+}
+
+// WarmupStats 模块预热统计信息
+type WarmupStats struct {
+	Status       string   `json:"status"`       // "completed", "not_started", "failed"
+	Modules      []string `json:"modules"`      // 预编译的模块列表
+	TotalModules int      `json:"totalModules"` // 总模块数
+	SuccessCount int      `json:"successCount"` // 成功数量
+	Elapsed      string   `json:"elapsed"`      // 耗时（格式化）
+	ElapsedMs    int64    `json:"elapsedMs"`    // 耗时（毫秒）
+	Timestamp    string   `json:"timestamp"`    // 预热完成时间
+}
+
+// ExecutionPhase 单个阶段的耗时
+type ExecutionPhase struct {
+	Phase      string  `json:"phase"`           // auth / quota / decode / validate / schedule / runtime_acquire / compile / run / export
+	DurationMs float64 `json:"durationMs"`      // 毫秒（保留 3 位小数）
+	Cache      string  `json:"cache,omitempty"` // hit / miss（auth: Token 缓存；validate: 校验缓存；compile: 编译缓存；runtime_acquire: miss 表示池中无空闲、创建了临时 Runtime）
+}
@@ -0,0 +1,104 @@
+package execmodel
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// SandboxPolicy 沙箱策略（按 Token 或命名策略配置）
+//
+// 🔥 字段为空表示使用全局配置的默认值：
+//   - AllowedModules 为 null：允许全部已注册模块；为 []：禁止 require 任何模块
+//   - 数值字段为 null：使用 EXECUTION_TIMEOUT_MS / MAX_RESULT_SIZE / MAX_INPUT_SIZE
+//   - ConsoleMode 为空：使用 CONSOLE_MODE
+//   - Network 为 null：允许网络访问（fetch / axios），不限制出站目标
+//   - History 为 null：不记录执行历史
+type SandboxPolicy struct {
+	AllowedModules     []string       `json:"allowed_modules"`                // 允许 require 的模块（不含 node: 前缀和子路径）
+	ExecutionTimeoutMs *int           `json:"execution_timeout_ms,omitempty"` // 执行超时（毫秒）
+	MaxResultSize      *int           `json:"max_result_size,omitempty"`      // 返回结果大小上限（字节）
+	MaxInputSize       *int           `json:"max_input_size,omitempty"`       // 输入数据大小上限（字节）
+	ConsoleMode        string         `json:"console_mode,omitempty"`         // disabled / stdout / capture
+	Network            *NetworkPolicy `json:"network,omitempty"`              // 网络权限
+
+	Scheduling *SchedulingPolicy `json:"scheduling,omitempty"` // 公平调度参数
+	History    *HistoryPolicy    `json:"history,omitempty"`    // 🆕 执行历史（按 Token 开启）
+}
+
+// HistoryPolicy 执行历史设置
+//
+// 开启后每次执行（POST /flow/codeblock）保存代码哈希、输入、结果/错误、console 输出和耗时，
+// 用于结果争议时回查；未设置的字段使用 HISTORY_* 全局配置
+type HistoryPolicy struct {
+	Enabled        *bool    `json:"enabled,omitempty"`         // 是否记录（默认 false）
+	RetentionDays  *int     `json:"retention_days,omitempty"`  // 保留天数（默认 HISTORY_RETENTION_DAYS）
+	MaxFieldBytes  *int     `json:"max_field_bytes,omitempty"` // input / result / logs 每个字段的大小上限（默认 HISTORY_MAX_FIELD_BYTES）
+	RedactKeys     []string `json:"redact_keys,omitempty"`     // 脱敏字段名（不区分大小写，与 HISTORY_REDACT_KEYS 合并）
+	RedactPatterns []string `json:"redact_patterns,omitempty"` // 脱敏正则（匹配到的内容替换为 [REDACTED]，作用于所有字符串值、console 输出和错误信息）
+}
+
+// HistoryEnabled 是否记录执行历史
+func (p *SandboxPolicy) HistoryEnabled() bool {
+	return p != nil && p.History != nil && p.History.Enabled != nil && *p.History.Enabled
+}
+
+// 调度优先级（按倍数放大权重：high ×4、normal ×2、low ×1）
+const (
+	PriorityClassHigh   = "high"
+	PriorityClassNormal = "normal"
+	PriorityClassLow    = "low"
+)
+
+// SchedulingPolicy 公平调度参数
+//
+// 并发槽位紧张时，各分组（Token 或工作空间）按「有效权重 = weight × 优先级倍数」的比例分配并发执行数
+type SchedulingPolicy struct {
+	Weight        *int   `json:"weight,omitempty"`         // 调度权重（1-100，默认 1）
+	MaxInFlight   *int   `json:"max_in_flight,omitempty"`  // 最大并发执行数（默认 SCHEDULER_DEFAULT_MAX_IN_FLIGHT）
+	PriorityClass string `json:"priority_class,omitempty"` // high / normal / low（默认 normal）
+}
+
+// NetworkPolicy 网络权限
+type NetworkPolicy struct {
+	Enabled *bool `json:"enabled,omitempty"` // 是否允许网络访问（null 表示允许）
+
+	// Egress 出站规则（null 表示不限制出站目标；[] 表示禁止所有出站请求）
+	// 格式：[scheme://]host[:port]，host 支持 example.com、*.example.com、*、IP 和 CIDR，如
+	// "https://api.example.com"、"*.example.com:8443"、"10.0.0.0/8"、"[2001:db8::/32]:443"
+	Egress []string `json:"egress"`
+}
+
+// Scan 实现 sql.Scanner 接口（数据库中以 JSON 保存）
+func (p *SandboxPolicy) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = SandboxPolicy{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法将 %T 转换为 SandboxPolicy", value)
+	}
+	return json.Unmarshal(data, p)
+}
+
+// Value 实现 driver.Valuer 接口
+func (p SandboxPolicy) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// NetworkEnabled 是否允许网络访问
+func (p *SandboxPolicy) NetworkEnabled() bool {
+	if p == nil || p.Network == nil || p.Network.Enabled == nil {
+		return true
+	}
+	return *p.Network.Enabled
+}
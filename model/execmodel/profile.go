@@ -0,0 +1,41 @@
+package execmodel
+
+// ExecutionProfile 单次执行的 JS 性能分析结果
+// 耗时为 goja 的"执行时间"采样（每 10ms 采样一次正在执行的指令，包含阻塞在 Go 函数上的时间）
+type ExecutionProfile struct {
+	SampleCount  int64                 `json:"sampleCount"`  // 本次执行的采样数
+	TotalTimeUs  int64                 `json:"totalTimeUs"`  // 采样覆盖的总耗时（微秒）
+	IntervalMs   int                   `json:"intervalMs"`   // 采样间隔（毫秒）
+	Flame        *ProfileFrame         `json:"flame"`        // 火焰图（d3-flame-graph 格式：name / value / children）
+	TopFunctions []ProfileFunctionStat `json:"topFunctions"` // 按自身耗时排序的函数
+	HotLines     []ProfileLineStat     `json:"hotLines"`     // 按自身耗时排序的代码行
+	Pprof        string                `json:"pprof"`        // pprof 格式（gzip + Base64），可用 go tool pprof 查看
+}
+
+// ProfileFrame 火焰图节点（同一调用路径上的同名函数合并）
+type ProfileFrame struct {
+	Name     string          `json:"name"`               // 函数名（含文件名）
+	Value    int64           `json:"value"`              // 包含子调用的耗时（微秒）
+	Self     int64           `json:"self"`               // 自身耗时（微秒）
+	Samples  int64           `json:"samples"`            // 采样数（包含子调用）
+	Children []*ProfileFrame `json:"children,omitempty"` // 子调用（按耗时降序）
+}
+
+// ProfileFunctionStat 函数耗时统计
+type ProfileFunctionStat struct {
+	Name        string  `json:"name"`
+	File        string  `json:"file"`
+	SelfUs      int64   `json:"selfUs"`      // 自身耗时（微秒）
+	TotalUs     int64   `json:"totalUs"`     // 包含子调用的耗时（微秒，递归只计一次）
+	SelfPercent float64 `json:"selfPercent"` // 自身耗时占比（%）
+}
+
+// ProfileLineStat 代码行耗时统计
+type ProfileLineStat struct {
+	Function    string  `json:"function"`
+	File        string  `json:"file"`
+	Line        int     `json:"line"` // user_code.js 的行号已换算为用户代码行号
+	SelfUs      int64   `json:"selfUs"`
+	Samples     int64   `json:"samples"`
+	SelfPercent float64 `json:"selfPercent"`
+}
@@ -0,0 +1,22 @@
+package execmodel
+
+// RunningExecution 正在执行的代码（管理员接口 GET /flow/executions/running）
+type RunningExecution struct {
+	ExecutionID string         `json:"executionId"` // 执行器分配的执行 ID（终止时使用；request_id 可能重复）
+	RequestID   string         `json:"requestId"`
+	Token       string         `json:"token,omitempty"` // 已脱敏
+	WsID        string         `json:"wsId,omitempty"`
+	CodeHash    string         `json:"codeHash"`
+	Route       string         `json:"route"` // pool（Runtime 池，同步代码）/ eventloop（EventLoop，异步代码）
+	StartedAt   string         `json:"startedAt"`
+	ElapsedMs   int64          `json:"elapsedMs"`
+	Fetches     []RunningFetch `json:"fetches"` // 进行中的出站请求（收到响应头前）
+}
+
+// RunningFetch 执行中正在进行的出站请求
+type RunningFetch struct {
+	Method    string `json:"method"`
+	URL       string `json:"url"` // 已去掉查询参数和用户名密码
+	StartedAt string `json:"startedAt"`
+	ElapsedMs int64  `json:"elapsedMs"`
+}
@@ -0,0 +1,47 @@
+package execmodel
+
+// CodeFinding 单条校验发现
+type CodeFinding struct {
+	Type    string `json:"type"`              // ValidationError / SecurityError / SyntaxError / ConsoleDisabledError（与执行时的错误类型一致）
+	Rule    string `json:"rule"`              // 检查项：length / return / prohibited_module / function_constructor / eval / constructor_access / proto_access / prototype_method / reflect_proxy / global_object / dynamic_access / console / infinite_loop / syntax
+	Message string `json:"message"`           // 问题说明
+	Line    int    `json:"line,omitempty"`    // 行号（从 1 开始，无法定位时省略）
+	Column  int    `json:"column,omitempty"`  // 列号（从 1 开始）
+	Match   string `json:"match,omitempty"`   // 命中的代码片段
+	Snippet string `json:"snippet,omitempty"` // 所在行内容
+}
+
+// CodeRouteInfo 执行路由分析结果
+type CodeRouteInfo struct {
+	Type         string   `json:"type"`         // sync（Runtime 池）/ async（EventLoop）
+	UseEventLoop bool     `json:"useEventLoop"` // 是否使用 EventLoop
+	Reasons      []string `json:"reasons"`      // 判定为异步的依据
+
+	Confidence float64       `json:"confidence"`        // 🆕 判定置信度（0-1）
+	Trigger    *AsyncTrigger `json:"trigger,omitempty"` // 🆕 决定路由的语法结构（同步代码省略）
+	Method     string        `json:"method"`            // 🆕 分析方式: keyword / ast / regex
+}
+
+// CodeModuleInfo 模块使用情况
+type CodeModuleInfo struct {
+	HasRequire  bool     `json:"hasRequire"`
+	Modules     []string `json:"modules"`
+	ModuleCount int      `json:"moduleCount"`
+}
+
+// ValidateReport 代码校验报告
+type ValidateReport struct {
+	Valid      bool            `json:"valid"`      // 没有任何发现时为 true（即执行时不会被校验拒绝）
+	CodeLength int             `json:"codeLength"` // 代码长度（字节）
+	Findings   []CodeFinding   `json:"findings"`   // 全部发现（按检查顺序）
+	Route      *CodeRouteInfo  `json:"route"`
+	Modules    *CodeModuleInfo `json:"modules"`
+}
+
+// AsyncTrigger 决定异步路由的语法结构（utils 代码分析器的结果类型）
+type AsyncTrigger struct {
+	Kind   string `json:"kind"`             // 触发类型（见 utils.AsyncTriggerXxx）
+	Name   string `json:"name"`             // 命中的代码片段
+	Line   int    `json:"line,omitempty"`   // 行号（从 1 开始）
+	Column int    `json:"column,omitempty"` // 列号（从 1 开始）
+}
@@ -0,0 +1,95 @@
+package model
+
+// FunctionAliasLatest 发布新版本时自动指向最新版本的别名
+const FunctionAliasLatest = "latest"
+
+// StoredFunction 存储的脚本（stored_functions 表，按工作空间隔离）
+type StoredFunction struct {
+	ID            int64        `db:"id" json:"id"`
+	WsID          string       `db:"ws_id" json:"ws_id"`
+	Name          string       `db:"name" json:"name"`
+	Description   string       `db:"description" json:"description"`
+	LatestVersion int          `db:"latest_version" json:"latest_version"` // 已发布的最大版本号
+	CreatedBy     string       `db:"created_by" json:"created_by"`
+	CreatedAt     ShanghaiTime `db:"created_at" json:"created_at"`
+	UpdatedAt     ShanghaiTime `db:"updated_at" json:"updated_at"`
+}
+
+// FunctionVersion 脚本的不可变版本（stored_function_versions 表）
+type FunctionVersion struct {
+	ID         int64        `db:"id" json:"-"`
+	FunctionID int64        `db:"function_id" json:"-"`
+	Version    int          `db:"version" json:"version"`
+	Code       string       `db:"code" json:"-"`
+	CodeHash   string       `db:"code_hash" json:"code_hash"`
+	CodeLength int          `db:"code_length" json:"code_length"`
+	Comment    string       `db:"comment" json:"comment"`
+	CreatedBy  string       `db:"created_by" json:"created_by"`
+	CreatedAt  ShanghaiTime `db:"created_at" json:"created_at"`
+}
+
+// FunctionAlias 版本别名（stored_function_aliases 表），如 latest / prod
+type FunctionAlias struct {
+	FunctionID int64        `db:"function_id" json:"-"`
+	Alias      string       `db:"alias" json:"alias"`
+	Version    int          `db:"version" json:"version"`
+	UpdatedAt  ShanghaiTime `db:"updated_at" json:"updated_at"`
+}
+
+// FunctionDetail 脚本详情（版本列表不含代码）
+type FunctionDetail struct {
+	*StoredFunction
+	Aliases  []*FunctionAlias   `json:"aliases"`
+	Versions []*FunctionVersion `json:"versions"` // 按版本号倒序
+}
+
+// FunctionVersionDetail 单个版本（含代码）
+type FunctionVersionDetail struct {
+	*FunctionVersion
+	Name       string `json:"name"`
+	CodeBase64 string `json:"codebase64"`
+}
+
+// ResolvedFunction 调用时解析出的脚本版本
+type ResolvedFunction struct {
+	FunctionID int64
+	Name       string
+	Version    int
+	Code       string
+}
+
+// CreateFunctionRequest 创建脚本请求（同时发布版本 1）
+type CreateFunctionRequest struct {
+	Name        string `json:"name" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=255"`
+	CodeBase64  string `json:"codebase64" binding:"required"`
+	Comment     string `json:"comment" binding:"max=255"` // 版本说明
+}
+
+// UpdateFunctionRequest 更新脚本信息请求
+type UpdateFunctionRequest struct {
+	Description *string `json:"description" binding:"omitempty,max=255"`
+}
+
+// PublishFunctionRequest 发布新版本请求（latest 指向新版本）
+type PublishFunctionRequest struct {
+	CodeBase64 string `json:"codebase64" binding:"required"`
+	Comment    string `json:"comment" binding:"max=255"`
+}
+
+// SetFunctionAliasRequest 设置别名请求
+type SetFunctionAliasRequest struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
+
+// RollbackFunctionRequest 回滚请求
+type RollbackFunctionRequest struct {
+	Alias   string `json:"alias"`   // 回滚的别名（默认 latest）
+	Version *int   `json:"version"` // 目标版本（默认为别名当前版本之前的最近版本）
+}
+
+// InvokeFunctionRequest 按名称调用脚本请求
+type InvokeFunctionRequest struct {
+	Input map[string]interface{} `json:"input" binding:"required"`
+	Debug bool                   `json:"debug,omitempty"` // 为 true 时在 timing.phases 中返回分阶段耗时
+}
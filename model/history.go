@@ -0,0 +1,77 @@
+package model
+
+import (
+	"encoding/json"
+)
+
+// 执行历史状态（code_execution_history.status）
+const (
+	HistoryStatusSuccess = "success"
+	HistoryStatusFailed  = "failed"
+)
+
+// ExecutionHistory 执行历史记录（code_execution_history 表）
+// 🆕 按 Token 开启（沙箱策略 history.enabled），input / result / logs 已脱敏，超过大小上限时截断
+type ExecutionHistory struct {
+	ID              int64        `db:"id" json:"-"`
+	RequestID       string       `db:"request_id" json:"request_id"`
+	Token           string       `db:"token" json:"token"`
+	WsID            string       `db:"ws_id" json:"ws_id"`
+	Email           string       `db:"email" json:"email"`
+	CodeHash        string       `db:"code_hash" json:"code_hash"`
+	CodeLength      int          `db:"code_length" json:"code_length"`
+	Status          string       `db:"status" json:"status"`
+	ErrorType       *string      `db:"error_type" json:"error_type"`
+	ErrorMessage    *string      `db:"error_message" json:"error_message"`
+	ExecutionTimeMs int64        `db:"execution_time_ms" json:"execution_time_ms"`
+	Input           *string      `db:"input" json:"-"`
+	InputTruncated  bool         `db:"input_truncated" json:"input_truncated"`
+	Result          *string      `db:"result" json:"-"`
+	ResultTruncated bool         `db:"result_truncated" json:"result_truncated"`
+	Logs            *string      `db:"logs" json:"-"`
+	LogsTruncated   bool         `db:"logs_truncated" json:"logs_truncated"`
+	ExpiresAt       ShanghaiTime `db:"expires_at" json:"expires_at"`
+	CreatedAt       ShanghaiTime `db:"created_at" json:"created_at"`
+}
+
+// ExecutionHistoryDetail 执行历史详情（包含 input / result / logs）
+// 未截断的内容以 JSON 原样返回；被截断的内容不再是合法 JSON，以字符串返回
+type ExecutionHistoryDetail struct {
+	*ExecutionHistory
+	Input  interface{} `json:"input"`
+	Result interface{} `json:"result"`
+	Logs   interface{} `json:"logs"`
+}
+
+// NewExecutionHistoryDetail 转换为详情输出
+func NewExecutionHistoryDetail(h *ExecutionHistory) *ExecutionHistoryDetail {
+	return &ExecutionHistoryDetail{
+		ExecutionHistory: h,
+		Input:            historyPayload(h.Input, h.InputTruncated),
+		Result:           historyPayload(h.Result, h.ResultTruncated),
+		Logs:             historyPayload(h.Logs, h.LogsTruncated),
+	}
+}
+
+// historyPayload 存储内容转换为输出（未截断的合法 JSON 原样输出）
+func historyPayload(data *string, truncated bool) interface{} {
+	if data == nil {
+		return nil
+	}
+	if !truncated && json.Valid([]byte(*data)) {
+		return json.RawMessage(*data)
+	}
+	return *data
+}
+
+// HistoryQueryRequest 执行历史查询请求
+type HistoryQueryRequest struct {
+	Token     string `form:"token"`      // 管理员接口可选；Token 接口固定为当前 Token
+	WsID      string `form:"ws_id"`      // 工作空间ID
+	Status    string `form:"status"`     // success / failed
+	CodeHash  string `form:"code_hash"`  // 代码哈希
+	StartDate string `form:"start_date"` // yyyy-MM-dd
+	EndDate   string `form:"end_date"`   // yyyy-MM-dd
+	Page      int    `form:"page"`
+	PageSize  int    `form:"page_size"`
+}
@@ -0,0 +1,72 @@
+package model
+
+import "encoding/json"
+
+// 异步任务状态
+const (
+	JobStatusQueued    = "queued"    // 已入队，等待执行
+	JobStatusRunning   = "running"   // 执行中
+	JobStatusSucceeded = "succeeded" // 执行成功
+	JobStatusFailed    = "failed"    // 执行失败
+)
+
+// 回调投递状态
+const (
+	JobCallbackPending   = "pending"   // 等待投递
+	JobCallbackDelivered = "delivered" // 投递成功
+	JobCallbackFailed    = "failed"    // 重试耗尽仍失败
+)
+
+// JobSubmitRequest 异步任务提交请求（在 ExecuteRequest 基础上增加回调地址）
+type JobSubmitRequest struct {
+	Input       map[string]interface{} `json:"input" binding:"required"`
+	CodeBase64  string                 `json:"codebase64" binding:"required"`
+	CallbackURL string                 `json:"callback_url,omitempty"` // 可选：任务完成后 POST 最终 ExecuteResponse
+}
+
+// JobInfo 异步任务状态（保存在 Redis 中，任意实例均可查询）
+type JobInfo struct {
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	RequestID string `json:"request_id,omitempty"` // 提交请求的 request_id（同时作为执行ID）
+
+	// 执行结果（任务结束后填充）
+	Result json.RawMessage `json:"result,omitempty"` // 🔥 保留预序列化 JSON，避免字段顺序丢失
+	Error  *ExecuteError   `json:"error,omitempty"`
+	Timing *ExecuteTiming  `json:"timing,omitempty"`
+
+	// 🆕 console 输出（CONSOLE_MODE=capture）
+	Logs          []ConsoleLogEntry `json:"logs,omitempty"`
+	LogsTruncated bool              `json:"logsTruncated,omitempty"`
+
+	// 回调信息
+	CallbackURL      string `json:"callback_url,omitempty"`
+	CallbackStatus   string `json:"callback_status,omitempty"`
+	CallbackAttempts int    `json:"callback_attempts,omitempty"`
+
+	CreatedAt  string `json:"created_at"`
+	StartedAt  string `json:"started_at,omitempty"`
+	FinishedAt string `json:"finished_at,omitempty"`
+}
+
+// IsFinished 任务是否已结束
+func (j *JobInfo) IsFinished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed
+}
+
+// ToExecuteResponse 转换为最终执行响应（用于回调和查询）
+func (j *JobInfo) ToExecuteResponse() *ExecuteResponse {
+	resp := &ExecuteResponse{
+		Success:       j.Status == JobStatusSucceeded,
+		Error:         j.Error,
+		Timing:        j.Timing,
+		Timestamp:     j.FinishedAt,
+		RequestID:     j.RequestID,
+		Logs:          j.Logs,
+		LogsTruncated: j.LogsTruncated,
+	}
+	if len(j.Result) > 0 {
+		resp.Result = j.Result
+	}
+	return resp
+}
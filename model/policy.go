@@ -0,0 +1,135 @@
+package model
+
+import "flow-codeblock-go/model/execmodel"
+
+// 沙箱策略类型定义见 execmodel（pkg/sandbox 只依赖执行相关的类型）
+type (
+	SandboxPolicy    = execmodel.SandboxPolicy
+	HistoryPolicy    = execmodel.HistoryPolicy
+	SchedulingPolicy = execmodel.SchedulingPolicy
+	NetworkPolicy    = execmodel.NetworkPolicy
+)
+
+// 调度优先级（按倍数放大权重：high ×4、normal ×2、low ×1）
+const (
+	PriorityClassHigh   = execmodel.PriorityClassHigh
+	PriorityClassNormal = execmodel.PriorityClassNormal
+	PriorityClassLow    = execmodel.PriorityClassLow
+)
+
+// MergeSandboxPolicy 合并两层策略：override 中已设置的字段覆盖 base（均可为 nil）
+// 用于 Token 内联策略覆盖命名策略
+func MergeSandboxPolicy(base, override *SandboxPolicy) *SandboxPolicy {
+	if base == nil {
+		return override
+	}
+	if override == nil {
+		return base
+	}
+
+	merged := *base
+	if override.AllowedModules != nil {
+		merged.AllowedModules = override.AllowedModules
+	}
+	if override.ExecutionTimeoutMs != nil {
+		merged.ExecutionTimeoutMs = override.ExecutionTimeoutMs
+	}
+	if override.MaxResultSize != nil {
+		merged.MaxResultSize = override.MaxResultSize
+	}
+	if override.MaxInputSize != nil {
+		merged.MaxInputSize = override.MaxInputSize
+	}
+	if override.ConsoleMode != "" {
+		merged.ConsoleMode = override.ConsoleMode
+	}
+	if override.Network != nil {
+		network := NetworkPolicy{}
+		if base.Network != nil {
+			network = *base.Network
+		}
+		if override.Network.Enabled != nil {
+			network.Enabled = override.Network.Enabled
+		}
+		if override.Network.Egress != nil {
+			network.Egress = override.Network.Egress
+		}
+		merged.Network = &network
+	}
+	if override.Scheduling != nil {
+		scheduling := SchedulingPolicy{}
+		if base.Scheduling != nil {
+			scheduling = *base.Scheduling
+		}
+		if override.Scheduling.Weight != nil {
+			scheduling.Weight = override.Scheduling.Weight
+		}
+		if override.Scheduling.MaxInFlight != nil {
+			scheduling.MaxInFlight = override.Scheduling.MaxInFlight
+		}
+		if override.Scheduling.PriorityClass != "" {
+			scheduling.PriorityClass = override.Scheduling.PriorityClass
+		}
+		merged.Scheduling = &scheduling
+	}
+	if override.History != nil {
+		history := HistoryPolicy{}
+		if base.History != nil {
+			history = *base.History
+		}
+		if override.History.Enabled != nil {
+			history.Enabled = override.History.Enabled
+		}
+		if override.History.RetentionDays != nil {
+			history.RetentionDays = override.History.RetentionDays
+		}
+		if override.History.MaxFieldBytes != nil {
+			history.MaxFieldBytes = override.History.MaxFieldBytes
+		}
+		if override.History.RedactKeys != nil {
+			history.RedactKeys = override.History.RedactKeys
+		}
+		if override.History.RedactPatterns != nil {
+			history.RedactPatterns = override.History.RedactPatterns
+		}
+		merged.History = &history
+	}
+	return &merged
+}
+
+// SandboxPolicyProfile 命名策略（sandbox_policies 表）
+type SandboxPolicyProfile struct {
+	ID          int           `db:"id" json:"id"`
+	Name        string        `db:"name" json:"name"`
+	Description string        `db:"description" json:"description"`
+	Policy      SandboxPolicy `db:"policy" json:"policy"`
+	CreatedAt   ShanghaiTime  `db:"created_at" json:"created_at"`
+	UpdatedAt   ShanghaiTime  `db:"updated_at" json:"updated_at"`
+}
+
+// CreateSandboxPolicyRequest 创建命名策略请求
+type CreateSandboxPolicyRequest struct {
+	Name        string        `json:"name" binding:"required,max=64"`
+	Description string        `json:"description" binding:"max=255"`
+	Policy      SandboxPolicy `json:"policy"`
+}
+
+// UpdateSandboxPolicyRequest 更新命名策略请求（字段为空表示不修改）
+type UpdateSandboxPolicyRequest struct {
+	Description *string        `json:"description" binding:"omitempty,max=255"`
+	Policy      *SandboxPolicy `json:"policy"`
+}
+
+// SetTokenPolicyRequest 设置 Token 策略请求（整体替换，两个字段都为 null 表示清除策略）
+type SetTokenPolicyRequest struct {
+	PolicyName *string        `json:"policy_name"` // 引用的命名策略
+	Policy     *SandboxPolicy `json:"policy"`      // 内联策略（覆盖命名策略中的同名字段）
+}
+
+// TokenPolicyResponse Token 策略查询结果
+type TokenPolicyResponse struct {
+	AccessToken string         `json:"access_token"`
+	PolicyName  *string        `json:"policy_name"`
+	Policy      *SandboxPolicy `json:"policy"`
+	Effective   *SandboxPolicy `json:"effective"` // 合并命名策略和内联策略后的生效策略（null 表示使用全局默认值）
+}
@@ -0,0 +1,23 @@
+package model
+
+import "flow-codeblock-go/model/execmodel"
+
+// ProfileRequest 性能分析请求（管理员接口，请求体与执行接口一致）
+type ProfileRequest struct {
+	Input      map[string]interface{} `json:"input" binding:"required"`
+	CodeBase64 string                 `json:"codebase64" binding:"required"`
+}
+
+// ProfileResponse 性能分析响应：执行结果 + 性能分析结果
+type ProfileResponse struct {
+	ExecuteResponse
+	Profile *ExecutionProfile `json:"profile,omitempty"`
+}
+
+// 性能分析结果的类型定义见 execmodel
+type (
+	ExecutionProfile    = execmodel.ExecutionProfile
+	ProfileFrame        = execmodel.ProfileFrame
+	ProfileFunctionStat = execmodel.ProfileFunctionStat
+	ProfileLineStat     = execmodel.ProfileLineStat
+)
@@ -4,4 +4,21 @@ package model
 type ExecuteRequest struct {
 	Input      map[string]interface{} `json:"input" binding:"required"`
 	CodeBase64 string                 `json:"codebase64" binding:"required"`
+	Debug      bool                   `json:"debug,omitempty"` // 🆕 为 true 时在 timing.phases 中返回分阶段耗时
+}
+
+// BatchExecuteRequest 批量执行请求结构
+// 两种形式二选一：
+//   - codebase64 + inputs：同一份代码对多个输入执行
+//   - items：每个条目自带代码和输入
+type BatchExecuteRequest struct {
+	CodeBase64 string                   `json:"codebase64,omitempty"`
+	Inputs     []map[string]interface{} `json:"inputs,omitempty"`
+	Items      []BatchExecuteItem       `json:"items,omitempty"`
+}
+
+// BatchExecuteItem 批量执行条目
+type BatchExecuteItem struct {
+	CodeBase64 string                 `json:"codebase64"`
+	Input      map[string]interface{} `json:"input"`
 }
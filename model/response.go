@@ -1,6 +1,10 @@
 package model
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"flow-codeblock-go/model/execmodel"
+)
 
 // ExecuteResponse 执行响应结构
 type ExecuteResponse struct {
@@ -11,6 +15,10 @@ type ExecuteResponse struct {
 	Timestamp string          `json:"timestamp"`
 	RequestID string          `json:"request_id,omitempty"` // 🔄 统一使用 request_id（原 executionId 已移除）
 	ResultRaw json.RawMessage `json:"-"`                    // 🔥 内部使用，不序列化
+
+	// 🆕 console 输出（仅 CONSOLE_MODE=capture 时返回，成功和失败均返回）
+	Logs          []ConsoleLogEntry `json:"logs,omitempty"`
+	LogsTruncated bool              `json:"logsTruncated,omitempty"`
 }
 
 // ExecuteError 执行错误结构
@@ -18,10 +26,31 @@ type ExecuteError struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
 	Stack   string `json:"stack,omitempty"` // 🔥 新增：JavaScript错误的stack trace
+
+	RetryAfter int `json:"retryAfter,omitempty"` // 🆕 排队被拒绝时建议的重试等待秒数（与 Retry-After 响应头一致）
 }
 
 // ExecuteTiming 执行时间统计
 type ExecuteTiming struct {
 	ExecutionTime int64 `json:"executionTime"` // 毫秒
 	TotalTime     int64 `json:"totalTime"`     // 毫秒
+
+	// 🆕 分阶段耗时（仅请求设置 debug=true 时返回，按发生顺序）
+	Phases []ExecutionPhase `json:"phases,omitempty"`
+}
+
+// ExecutionPhase 单个阶段的耗时（定义见 execmodel）
+type ExecutionPhase = execmodel.ExecutionPhase
+
+// BatchExecuteResponse 批量执行响应结构
+// Results 与请求中的条目一一对应（顺序一致），每个条目有独立的 success/error/timing
+type BatchExecuteResponse struct {
+	Success   bool              `json:"success"`
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []ExecuteResponse `json:"results"`
+	Timing    *ExecuteTiming    `json:"timing"`
+	Timestamp string            `json:"timestamp"`
+	RequestID string            `json:"request_id,omitempty"`
 }
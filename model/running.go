@@ -0,0 +1,9 @@
+package model
+
+import "flow-codeblock-go/model/execmodel"
+
+// 运行中执行的类型定义见 execmodel
+type (
+	RunningExecution = execmodel.RunningExecution
+	RunningFetch     = execmodel.RunningFetch
+)
@@ -0,0 +1,105 @@
+package model
+
+import "encoding/json"
+
+// 重叠策略：上一次执行尚未结束时到达的触发如何处理
+const (
+	ScheduleOverlapSkip  = "skip"  // 跳过本次触发（记录为 skipped）
+	ScheduleOverlapQueue = "queue" // 排队，上一次结束后立即执行
+	ScheduleOverlapAllow = "allow" // 并发执行
+)
+
+// 定时执行记录状态
+const (
+	ScheduleRunRunning = "running"
+	ScheduleRunSuccess = "success"
+	ScheduleRunFailed  = "failed"
+	ScheduleRunSkipped = "skipped"
+)
+
+// FunctionSchedule 存储脚本的定时执行计划（function_schedules 表）
+// 🆕 计划归属于创建它的 Token：每次执行按该 Token 扣减配额、计入限流
+type FunctionSchedule struct {
+	ID            int64        `db:"id" json:"id"`
+	Token         string       `db:"token" json:"token"` // 返回前脱敏
+	WsID          string       `db:"ws_id" json:"ws_id"`
+	Email         string       `db:"email" json:"email"`
+	Name          string       `db:"name" json:"name"`
+	FunctionName  string       `db:"function_name" json:"function_name"`
+	Version       string       `db:"version" json:"version"` // 版本号或别名（默认 latest）
+	CronExpr      string       `db:"cron_expr" json:"cron"`
+	Timezone      string       `db:"timezone" json:"timezone"`
+	Input         string       `db:"input" json:"-"` // 固定输入（JSON）
+	OverlapPolicy string       `db:"overlap_policy" json:"overlap_policy"`
+	Enabled       bool         `db:"enabled" json:"enabled"`
+	NextRunAt     ShanghaiTime `db:"next_run_at" json:"next_run_at"`
+	LastRunAt     ShanghaiTime `db:"last_run_at" json:"last_run_at"`
+	LastStatus    string       `db:"last_status" json:"last_status"`
+	CreatedAt     ShanghaiTime `db:"created_at" json:"created_at"`
+	UpdatedAt     ShanghaiTime `db:"updated_at" json:"updated_at"`
+}
+
+// FunctionScheduleDetail 计划详情（附带固定输入）
+type FunctionScheduleDetail struct {
+	*FunctionSchedule
+	Input json.RawMessage `json:"input"`
+}
+
+// ScheduleRun 定时执行记录（function_schedule_runs 表）
+type ScheduleRun struct {
+	ID              int64        `db:"id" json:"-"`
+	ScheduleID      int64        `db:"schedule_id" json:"schedule_id"`
+	RunID           string       `db:"run_id" json:"run_id"` // 同时作为执行的 request_id
+	Status          string       `db:"status" json:"status"`
+	FunctionVersion int          `db:"function_version" json:"function_version"` // 实际执行的版本（解析失败或跳过时为 0）
+	ErrorType       *string      `db:"error_type" json:"error_type,omitempty"`
+	ErrorMessage    *string      `db:"error_message" json:"error_message,omitempty"`
+	Result          *string      `db:"result" json:"-"`
+	ResultTruncated bool         `db:"result_truncated" json:"result_truncated"`
+	DurationMs      int64        `db:"duration_ms" json:"duration_ms"`
+	ScheduledAt     ShanghaiTime `db:"scheduled_at" json:"scheduled_at"` // 计划触发时间
+	StartedAt       ShanghaiTime `db:"started_at" json:"started_at"`
+	FinishedAt      ShanghaiTime `db:"finished_at" json:"finished_at"`
+}
+
+// ScheduleRunDetail 执行记录（附带结果）
+type ScheduleRunDetail struct {
+	*ScheduleRun
+	Result interface{} `json:"result"` // 未截断时为 JSON，截断时为字符串
+}
+
+// NewScheduleRunDetail 转换为执行记录输出
+func NewScheduleRunDetail(run *ScheduleRun) *ScheduleRunDetail {
+	return &ScheduleRunDetail{ScheduleRun: run, Result: historyPayload(run.Result, run.ResultTruncated)}
+}
+
+// CreateScheduleRequest 创建定时执行计划请求
+type CreateScheduleRequest struct {
+	Name          string                 `json:"name" binding:"required,max=64"`
+	FunctionName  string                 `json:"function_name" binding:"required,max=64"`
+	Version       string                 `json:"version" binding:"max=32"` // 默认 latest
+	Cron          string                 `json:"cron" binding:"required,max=100"`
+	Timezone      string                 `json:"timezone" binding:"max=64"` // 默认 Asia/Shanghai
+	Input         map[string]interface{} `json:"input"`
+	OverlapPolicy string                 `json:"overlap_policy" binding:"omitempty,oneof=skip queue allow"` // 默认 skip
+	Enabled       *bool                  `json:"enabled"`                                                   // 默认 true
+}
+
+// UpdateScheduleRequest 更新定时执行计划请求（未提供的字段保持不变）
+type UpdateScheduleRequest struct {
+	Name          *string                 `json:"name" binding:"omitempty,min=1,max=64"`
+	FunctionName  *string                 `json:"function_name" binding:"omitempty,min=1,max=64"`
+	Version       *string                 `json:"version" binding:"omitempty,max=32"`
+	Cron          *string                 `json:"cron" binding:"omitempty,min=1,max=100"`
+	Timezone      *string                 `json:"timezone" binding:"omitempty,max=64"`
+	Input         *map[string]interface{} `json:"input"`
+	OverlapPolicy *string                 `json:"overlap_policy" binding:"omitempty,oneof=skip queue allow"`
+	Enabled       *bool                   `json:"enabled"`
+}
+
+// ScheduleRunQueryRequest 执行记录查询请求
+type ScheduleRunQueryRequest struct {
+	Status   string `form:"status"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
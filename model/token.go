@@ -2,10 +2,19 @@ package model
 
 import (
 	"database/sql/driver"
-	"flow-codeblock-go/utils"
 	"time"
 )
 
+// shanghaiLocation 上海时区（与 utils.ShanghaiLocation 相同；model 只依赖标准库，供 pkg/client 使用）
+var shanghaiLocation = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		// 如果加载失败，使用FixedZone创建东八区
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}()
+
 // ShanghaiTime 上海时区时间类型
 type ShanghaiTime struct {
 	time.Time
@@ -19,8 +28,7 @@ func (st ShanghaiTime) MarshalJSON() ([]byte, error) {
 	}
 	// 将时间转换为上海时区并格式化
 	// 注意：数据库可能返回UTC时间，需要转换
-	loc, _ := time.LoadLocation("Asia/Shanghai")
-	shanghaiTime := st.Time.In(loc)
+	shanghaiTime := st.Time.In(shanghaiLocation)
 	formatted := shanghaiTime.Format("2006-01-02 15:04:05")
 	return []byte(`"` + formatted + `"`), nil
 }
@@ -31,7 +39,7 @@ func (st *ShanghaiTime) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 	str := string(data[1 : len(data)-1]) // 去掉引号
-	t, err := utils.ParseTime(str)
+	t, err := parseShanghaiTime(str)
 	if err != nil {
 		return err
 	}
@@ -39,6 +47,14 @@ func (st *ShanghaiTime) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// parseShanghaiTime 解析上海时区时间（支持 yyyy-MM-dd 或 yyyy-MM-dd HH:mm:ss，与 utils.ParseTime 一致）
+func parseShanghaiTime(str string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", str, shanghaiLocation); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", str, shanghaiLocation)
+}
+
 // Scan 实现sql.Scanner接口
 func (st *ShanghaiTime) Scan(value interface{}) error {
 	if value == nil {
@@ -78,6 +94,9 @@ type TokenInfo struct {
 	RemainingQuota *int          `db:"remaining_quota" json:"remaining_quota"` // 剩余配额
 	QuotaSyncedAt  *ShanghaiTime `db:"quota_synced_at" json:"quota_synced_at"` // 配额同步时间
 	UpdatedAt      ShanghaiTime  `db:"updated_at" json:"updated_at"`
+	// 🆕 沙箱策略（为空表示使用全局默认值）
+	PolicyName *string        `db:"policy_name" json:"policy_name"` // 引用的命名策略
+	Policy     *SandboxPolicy `db:"policy" json:"policy"`           // 内联策略（覆盖命名策略中的同名字段）
 }
 
 // IsExpired 检查Token是否过期
@@ -169,6 +188,9 @@ type CreateTokenRequest struct {
 	// 🔥 配额相关字段
 	QuotaType  string `json:"quota_type" binding:"omitempty,oneof=time count hybrid"` // 配额类型
 	TotalQuota *int   `json:"total_quota"`                                            // 总配额次数
+	// 🆕 沙箱策略
+	PolicyName *string        `json:"policy_name"` // 引用的命名策略
+	Policy     *SandboxPolicy `json:"policy"`      // 内联策略
 }
 
 // UpdateTokenRequest 更新Token请求
@@ -0,0 +1,76 @@
+package model
+
+// HTTP 触发器认证方式
+const (
+	TriggerAuthNone   = "none"   // 不认证（持有触发地址即可调用）
+	TriggerAuthSecret = "secret" // 请求头 X-Trigger-Secret（或查询参数 secret）等于触发器密钥
+	TriggerAuthHMAC   = "hmac"   // 请求体签名：HMAC(密钥, [时间戳 + "."] + 请求体)
+)
+
+// FunctionTrigger 存储脚本的 HTTP 触发器（function_triggers 表）
+// 🆕 触发器归属于创建它的 Token：每次触发都按该 Token 扣减配额、计入限流
+type FunctionTrigger struct {
+	ID                 int64        `db:"id" json:"id"`
+	TriggerKey         string       `db:"trigger_key" json:"key"` // 公开地址 /flow/hooks/:key
+	Token              string       `db:"token" json:"token"`     // 返回前脱敏
+	WsID               string       `db:"ws_id" json:"ws_id"`
+	Email              string       `db:"email" json:"email"`
+	Name               string       `db:"name" json:"name"`
+	FunctionName       string       `db:"function_name" json:"function_name"`
+	Version            string       `db:"version" json:"version"` // 版本号或别名（默认 latest）
+	Methods            string       `db:"methods" json:"-"`       // 允许的 HTTP 方法（逗号分隔，空表示全部）
+	AuthMode           string       `db:"auth_mode" json:"auth_mode"`
+	Secret             string       `db:"secret" json:"-"` // 只在创建和轮换时返回
+	SignatureHeader    string       `db:"signature_header" json:"signature_header"`
+	SignatureAlgorithm string       `db:"signature_algorithm" json:"signature_algorithm"`
+	SignatureEncoding  string       `db:"signature_encoding" json:"signature_encoding"`
+	SignaturePrefix    string       `db:"signature_prefix" json:"signature_prefix"`
+	TimestampHeader    string       `db:"timestamp_header" json:"timestamp_header"`
+	RateLimitPerMinute int          `db:"rate_limit_per_minute" json:"rate_limit_per_minute"`
+	RateLimitBurst     int          `db:"rate_limit_burst" json:"rate_limit_burst"`
+	Enabled            bool         `db:"enabled" json:"enabled"`
+	CreatedAt          ShanghaiTime `db:"created_at" json:"created_at"`
+	UpdatedAt          ShanghaiTime `db:"updated_at" json:"updated_at"`
+}
+
+// FunctionTriggerDetail 触发器详情
+type FunctionTriggerDetail struct {
+	*FunctionTrigger
+	Methods []string `json:"methods"`
+	Path    string   `json:"path"`             // 触发地址路径
+	Secret  string   `json:"secret,omitempty"` // 只在创建和轮换时返回
+}
+
+// CreateTriggerRequest 创建 HTTP 触发器请求
+type CreateTriggerRequest struct {
+	Name               string   `json:"name" binding:"required,max=64"`
+	FunctionName       string   `json:"function_name" binding:"required,max=64"`
+	Version            string   `json:"version" binding:"max=32"`                             // 默认 latest
+	Methods            []string `json:"methods"`                                              // 默认全部
+	AuthMode           string   `json:"auth_mode" binding:"omitempty,oneof=none secret hmac"` // 默认 secret
+	SignatureHeader    string   `json:"signature_header" binding:"max=64"`                    // 默认 X-Flow-Signature
+	SignatureAlgorithm string   `json:"signature_algorithm" binding:"omitempty,oneof=sha1 sha256 sha512"`
+	SignatureEncoding  string   `json:"signature_encoding" binding:"omitempty,oneof=hex base64"`
+	SignaturePrefix    string   `json:"signature_prefix" binding:"max=32"`
+	TimestampHeader    string   `json:"timestamp_header" binding:"max=64"`
+	RateLimitPerMinute int      `json:"rate_limit_per_minute" binding:"min=0"` // 0 使用默认值
+	RateLimitBurst     int      `json:"rate_limit_burst" binding:"min=0"`      // 0 使用默认值
+	Enabled            *bool    `json:"enabled"`                               // 默认 true
+}
+
+// UpdateTriggerRequest 更新 HTTP 触发器请求（未提供的字段保持不变）
+type UpdateTriggerRequest struct {
+	Name               *string   `json:"name" binding:"omitempty,min=1,max=64"`
+	FunctionName       *string   `json:"function_name" binding:"omitempty,min=1,max=64"`
+	Version            *string   `json:"version" binding:"omitempty,max=32"`
+	Methods            *[]string `json:"methods"`
+	AuthMode           *string   `json:"auth_mode" binding:"omitempty,oneof=none secret hmac"`
+	SignatureHeader    *string   `json:"signature_header" binding:"omitempty,max=64"`
+	SignatureAlgorithm *string   `json:"signature_algorithm" binding:"omitempty,oneof=sha1 sha256 sha512"`
+	SignatureEncoding  *string   `json:"signature_encoding" binding:"omitempty,oneof=hex base64"`
+	SignaturePrefix    *string   `json:"signature_prefix" binding:"omitempty,max=32"`
+	TimestampHeader    *string   `json:"timestamp_header" binding:"omitempty,max=64"`
+	RateLimitPerMinute *int      `json:"rate_limit_per_minute" binding:"omitempty,min=1"`
+	RateLimitBurst     *int      `json:"rate_limit_burst" binding:"omitempty,min=1"`
+	Enabled            *bool     `json:"enabled"`
+}
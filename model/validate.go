@@ -0,0 +1,16 @@
+package model
+
+import "flow-codeblock-go/model/execmodel"
+
+// ValidateRequest 代码校验请求（只校验，不执行、不扣减配额）
+type ValidateRequest struct {
+	CodeBase64 string `json:"codebase64" binding:"required"`
+}
+
+// 校验报告的类型定义见 execmodel
+type (
+	CodeFinding    = execmodel.CodeFinding
+	CodeRouteInfo  = execmodel.CodeRouteInfo
+	CodeModuleInfo = execmodel.CodeModuleInfo
+	ValidateReport = execmodel.ValidateReport
+)
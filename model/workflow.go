@@ -0,0 +1,151 @@
+package model
+
+// WorkflowInputSource 边的虚拟起点：工作流运行输入（POST /flow/workflows/:id/run 的 input）
+const WorkflowInputSource = "$input"
+
+// 工作流节点状态
+const (
+	WorkflowNodeSuccess = "success"
+	WorkflowNodeFailed  = "failed"
+	WorkflowNodeSkipped = "skipped" // 条件未满足、上游未成功或工作流已失败，未执行
+)
+
+// 工作流运行状态
+const (
+	WorkflowRunSuccess = "success"
+	WorkflowRunFailed  = "failed"
+)
+
+// 边条件运算符
+const (
+	WorkflowOpEq        = "eq"
+	WorkflowOpNe        = "ne"
+	WorkflowOpGt        = "gt"
+	WorkflowOpGte       = "gte"
+	WorkflowOpLt        = "lt"
+	WorkflowOpLte       = "lte"
+	WorkflowOpIn        = "in"
+	WorkflowOpNotIn     = "not_in"
+	WorkflowOpExists    = "exists"
+	WorkflowOpNotExists = "not_exists"
+	WorkflowOpTruthy    = "truthy"
+	WorkflowOpFalsy     = "falsy"
+)
+
+// Workflow 工作流（function_workflows 表）
+// 🆕 节点为存储脚本，边把上游结果映射为下游输入；归属于创建它的 Token，只能由该 Token 执行
+type Workflow struct {
+	ID          int64        `db:"id" json:"id"`
+	Token       string       `db:"token" json:"token"` // 返回前脱敏
+	WsID        string       `db:"ws_id" json:"ws_id"`
+	Email       string       `db:"email" json:"email"`
+	Name        string       `db:"name" json:"name"`
+	Description string       `db:"description" json:"description"`
+	Definition  string       `db:"definition" json:"-"` // WorkflowDefinition（JSON）
+	CreatedAt   ShanghaiTime `db:"created_at" json:"created_at"`
+	UpdatedAt   ShanghaiTime `db:"updated_at" json:"updated_at"`
+}
+
+// WorkflowDetail 工作流详情（附带定义）
+type WorkflowDetail struct {
+	*Workflow
+	Definition *WorkflowDefinition `json:"definition"`
+}
+
+// WorkflowDefinition 工作流定义（DAG）
+type WorkflowDefinition struct {
+	Nodes  []*WorkflowNode `json:"nodes"`
+	Edges  []*WorkflowEdge `json:"edges"`
+	Output string          `json:"output,omitempty"` // 作为运行结果的节点（为空时返回所有没有下游的节点的结果）
+}
+
+// WorkflowNode 工作流节点（一个存储脚本）
+type WorkflowNode struct {
+	ID              string                 `json:"id"`
+	Function        string                 `json:"function"`
+	Version         string                 `json:"version,omitempty"`           // 版本号或别名（默认 latest，每次运行开始时解析）
+	Input           map[string]interface{} `json:"input,omitempty"`             // 固定输入（边映射的值覆盖同名字段）
+	ForEach         string                 `json:"for_each,omitempty"`          // 🔥 扇出：输入中的数组路径，每个元素执行一次，结果按顺序组成数组
+	Concurrency     int                    `json:"concurrency,omitempty"`       // 扇出时同时执行的元素数（默认 WORKFLOW_MAX_PARALLEL）
+	TimeoutMs       int                    `json:"timeout_ms,omitempty"`        // 单次执行超时（不超过 Token 策略 / 全局执行超时）
+	Retry           *WorkflowRetry         `json:"retry,omitempty"`             // 失败重试
+	ContinueOnError bool                   `json:"continue_on_error,omitempty"` // 失败时不终止工作流（下游沿该节点的边不再触发）
+}
+
+// WorkflowRetry 节点重试设置
+type WorkflowRetry struct {
+	MaxAttempts int `json:"max_attempts"`         // 最大尝试次数（含第一次）
+	BackoffMs   int `json:"backoff_ms,omitempty"` // 第一次重试前的等待时间，之后每次翻倍
+}
+
+// WorkflowEdge 工作流边：上游节点（或 $input）完成后把结果映射为下游节点的输入
+type WorkflowEdge struct {
+	From string             `json:"from"`
+	To   string             `json:"to"`
+	Map  map[string]string  `json:"map,omitempty"`  // 下游输入字段 -> 上游结果路径（如 $.orders[0].id）；为空时整个结果放在以上游节点 ID 命名的字段
+	When *WorkflowCondition `json:"when,omitempty"` // 条件分支：上游结果满足条件时边才生效
+}
+
+// WorkflowCondition 边条件（对上游结果求值）
+type WorkflowCondition struct {
+	Path  string      `json:"path"`            // 上游结果路径，默认 $
+	Op    string      `json:"op"`              // eq / ne / gt / gte / lt / lte / in / not_in / exists / not_exists / truthy / falsy
+	Value interface{} `json:"value,omitempty"` // 比较值（in / not_in 为数组）
+}
+
+// CreateWorkflowRequest 创建工作流请求
+type CreateWorkflowRequest struct {
+	Name        string              `json:"name" binding:"required,max=64"`
+	Description string              `json:"description" binding:"max=255"`
+	Definition  *WorkflowDefinition `json:"definition" binding:"required"`
+}
+
+// UpdateWorkflowRequest 更新工作流请求（未提供的字段保持不变）
+type UpdateWorkflowRequest struct {
+	Name        *string             `json:"name" binding:"omitempty,min=1,max=64"`
+	Description *string             `json:"description" binding:"omitempty,max=255"`
+	Definition  *WorkflowDefinition `json:"definition"`
+}
+
+// RunWorkflowRequest 执行工作流请求
+type RunWorkflowRequest struct {
+	Input map[string]interface{} `json:"input"` // 运行输入（没有入边的节点合并该输入，其他节点通过 $input 边引用）
+}
+
+// WorkflowRunResult 工作流运行结果
+type WorkflowRunResult struct {
+	RunID       string                `json:"run_id"`
+	WorkflowID  int64                 `json:"workflow_id"`
+	Status      string                `json:"status"`                // success / failed
+	Output      interface{}           `json:"output"`                // output 节点的结果，或 { 节点ID: 结果 }（没有下游的成功节点）
+	FailedNode  string                `json:"failed_node,omitempty"` // 导致工作流失败的节点
+	Error       *ExecuteError         `json:"error,omitempty"`
+	Nodes       []*WorkflowNodeResult `json:"nodes"` // 按拓扑顺序
+	TotalTimeMs int64                 `json:"total_time_ms"`
+}
+
+// WorkflowNodeResult 节点运行结果
+type WorkflowNodeResult struct {
+	ID            string                `json:"id"`
+	Function      string                `json:"function"`
+	Version       int                   `json:"version,omitempty"` // 实际执行的版本号
+	Status        string                `json:"status"`
+	RequestID     string                `json:"request_id,omitempty"` // 最后一次执行的 request_id（执行历史中可按它查询；扇出节点见 items）
+	Attempts      int                   `json:"attempts"`             // 执行次数（含重试；扇出节点为所有元素之和）
+	Result        interface{}           `json:"result,omitempty"`
+	Error         *ExecuteError         `json:"error,omitempty"`
+	SkipReason    string                `json:"skip_reason,omitempty"`
+	StartOffsetMs int64                 `json:"start_offset_ms"` // 相对运行开始的时间
+	DurationMs    int64                 `json:"duration_ms"`
+	Items         []*WorkflowItemResult `json:"items,omitempty"` // 扇出节点每个元素的执行情况
+}
+
+// WorkflowItemResult 扇出节点中单个元素的执行情况
+type WorkflowItemResult struct {
+	Index      int           `json:"index"`
+	Status     string        `json:"status"`
+	RequestID  string        `json:"request_id"`
+	Attempts   int           `json:"attempts"`
+	DurationMs int64         `json:"duration_ms"`
+	Error      *ExecuteError `json:"error,omitempty"`
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"flow-codeblock-go/model"
+)
+
+// 管理接口（需要 WithAdminToken）
+
+// ==================== Token ====================
+
+// CreateToken 创建 Token（POST /flow/tokens）
+func (c *Client) CreateToken(ctx context.Context, req *model.CreateTokenRequest) (*model.TokenInfo, error) {
+	var info model.TokenInfo
+	if err := c.doData(ctx, &call{method: http.MethodPost, path: "/flow/tokens", body: req, auth: authAdmin}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// UpdateToken 更新 Token 的有效期、限流和配额（PUT /flow/tokens/:token）
+func (c *Client) UpdateToken(ctx context.Context, token string, req *model.UpdateTokenRequest) (*model.TokenInfo, error) {
+	var info model.TokenInfo
+	if err := c.doData(ctx, &call{method: http.MethodPut, path: "/flow/tokens/" + url.PathEscape(token), body: req, auth: authAdmin}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// DeleteToken 删除 Token（DELETE /flow/tokens/:token）
+func (c *Client) DeleteToken(ctx context.Context, token string) error {
+	return c.doData(ctx, &call{method: http.MethodDelete, path: "/flow/tokens/" + url.PathEscape(token), auth: authAdmin}, nil)
+}
+
+// ListTokens 按 token 或 ws_id / email 查询 Token（GET /flow/tokens）
+func (c *Client) ListTokens(ctx context.Context, req *model.TokenQueryRequest) ([]*model.TokenInfo, error) {
+	query := url.Values{}
+	if req != nil {
+		setQuery(query, "ws_id", req.WsID)
+		setQuery(query, "email", req.Email)
+		setQuery(query, "token", req.Token)
+	}
+	var data struct {
+		Tokens []*model.TokenInfo `json:"tokens"`
+	}
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/tokens", query: query, auth: authAdmin}, &data); err != nil {
+		return nil, err
+	}
+	return data.Tokens, nil
+}
+
+// ==================== 配额 ====================
+
+// Quota Token 配额（时间模式的 Token 只有 QuotaType 和 Message）
+type Quota struct {
+	QuotaType      string              `json:"quota_type"`
+	TotalQuota     int                 `json:"total_quota"`
+	RemainingQuota int                 `json:"remaining_quota"`
+	ConsumedQuota  int                 `json:"consumed_quota"`
+	QuotaSyncedAt  *model.ShanghaiTime `json:"quota_synced_at"`
+	Message        string              `json:"message,omitempty"`
+}
+
+// GetQuota 查询 Token 配额（GET /flow/tokens/:token/quota）
+func (c *Client) GetQuota(ctx context.Context, token string) (*Quota, error) {
+	var quota Quota
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/tokens/" + url.PathEscape(token) + "/quota", auth: authAdmin}, &quota); err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// QuotaLogPage 配额日志分页结果
+type QuotaLogPage struct {
+	Logs       []*model.QuotaLog `json:"logs"`
+	Total      int               `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+}
+
+// GetQuotaLogs 查询 Token 配额消耗日志（GET /flow/tokens/:token/quota/logs，req.Token 会被忽略）
+func (c *Client) GetQuotaLogs(ctx context.Context, token string, req *model.QuotaLogsQueryRequest) (*QuotaLogPage, error) {
+	query := url.Values{}
+	if req != nil {
+		setQuery(query, "start_date", req.StartDate)
+		setQuery(query, "end_date", req.EndDate)
+		setQueryInt(query, "page", req.Page)
+		setQueryInt(query, "page_size", req.PageSize)
+	}
+	var page QuotaLogPage
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/tokens/" + url.PathEscape(token) + "/quota/logs", query: query, auth: authAdmin}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetQuotaCleanupStats 配额日志清理统计（GET /flow/quota/cleanup/stats）
+func (c *Client) GetQuotaCleanupStats(ctx context.Context) (map[string]interface{}, error) {
+	var stats map[string]interface{}
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/quota/cleanup/stats", auth: authAdmin}, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// TriggerQuotaCleanup 手动触发配额日志清理（POST /flow/quota/cleanup/trigger，后台异步执行）
+func (c *Client) TriggerQuotaCleanup(ctx context.Context) error {
+	return c.doData(ctx, &call{method: http.MethodPost, path: "/flow/quota/cleanup/trigger", auth: authAdmin}, nil)
+}
+
+// ==================== 缓存和限流 ====================
+
+// GetCacheStats Token 缓存统计（GET /flow/cache/stats）
+func (c *Client) GetCacheStats(ctx context.Context) (map[string]interface{}, error) {
+	var stats map[string]interface{}
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/cache/stats", auth: authAdmin}, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ClearCache 清空 Token 缓存（DELETE /flow/cache）
+func (c *Client) ClearCache(ctx context.Context) error {
+	return c.doData(ctx, &call{method: http.MethodDelete, path: "/flow/cache", auth: authAdmin}, nil)
+}
+
+// RateLimitStats 限流统计
+type RateLimitStats struct {
+	RateLimit map[string]interface{} `json:"rate_limit"`
+	WritePool map[string]interface{} `json:"write_pool"` // 缓存写入池
+}
+
+// GetRateLimitStats 限流统计（GET /flow/rate-limit/stats）
+func (c *Client) GetRateLimitStats(ctx context.Context) (*RateLimitStats, error) {
+	var stats RateLimitStats
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/rate-limit/stats", auth: authAdmin}, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// ClearTokenRateLimit 清除 Token 的限流状态（DELETE /flow/rate-limit/:token）
+func (c *Client) ClearTokenRateLimit(ctx context.Context, token string) error {
+	return c.doData(ctx, &call{method: http.MethodDelete, path: "/flow/rate-limit/" + url.PathEscape(token), auth: authAdmin}, nil)
+}
+
+// ==================== 统计 ====================
+
+// GetModuleStats 模块使用统计（GET /flow/stats/modules）
+func (c *Client) GetModuleStats(ctx context.Context, params *model.StatsQueryParams) (*model.ModuleStatsResponse, error) {
+	var stats model.ModuleStatsResponse
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/stats/modules", query: statsQuery(params), auth: authAdmin}, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetModuleDetailStats 单个模块的详细统计（GET /flow/stats/modules/:module_name）
+func (c *Client) GetModuleDetailStats(ctx context.Context, module string, params *model.StatsQueryParams) (*model.ModuleDetailResponse, error) {
+	var stats model.ModuleDetailResponse
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/stats/modules/" + url.PathEscape(module), query: statsQuery(params), auth: authAdmin}, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetUserActivityStats 用户活跃度统计（GET /flow/stats/users）
+func (c *Client) GetUserActivityStats(ctx context.Context, params *model.StatsQueryParams) (*model.UserActivityResponse, error) {
+	var stats model.UserActivityResponse
+	if err := c.doData(ctx, &call{method: http.MethodGet, path: "/flow/stats/users", query: statsQuery(params), auth: authAdmin}, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// statsQuery 统计接口的查询参数
+func statsQuery(params *model.StatsQueryParams) url.Values {
+	query := url.Values{}
+	if params == nil {
+		return query
+	}
+	setQuery(query, "date", params.Date)
+	setQuery(query, "start_date", params.StartDate)
+	setQuery(query, "end_date", params.EndDate)
+	setQuery(query, "module", params.Module)
+	setQuery(query, "sort_by", params.SortBy)
+	setQuery(query, "order", params.Order)
+	setQuery(query, "ws_id", params.WsID)
+	setQueryInt(query, "page", params.Page)
+	setQueryInt(query, "page_size", params.PageSize)
+	setQueryInt(query, "min_calls", params.MinCalls)
+	return query
+}
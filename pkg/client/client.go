@@ -0,0 +1,300 @@
+// Package client Flow-CodeBlock HTTP API 的 Go 客户端
+//
+// 覆盖代码执行（/flow/codeblock、/flow/codeblock/batch）和管理接口（Token、配额、配额日志、
+// 缓存、限流、统计），请求和响应直接使用服务端的 model 类型（model 只依赖标准库，
+// 引入客户端不会带入 goja、zap、gRPC 等服务端依赖）：
+//
+//	c, err := client.New("https://flow.example.com",
+//		client.WithToken(accessToken),     // 执行接口
+//		client.WithAdminToken(adminToken), // 管理接口
+//	)
+//	result, err := c.Execute(ctx, "return input.a + input.b", map[string]interface{}{"a": 1, "b": 2})
+//	switch {
+//	case errors.Is(err, client.ErrQuotaExceeded):
+//		// 配额已用完
+//	case errors.Is(err, client.ErrValidation):
+//		// 代码或输入校验失败
+//	}
+//
+// 429 / 503 响应按指数退避自动重试（优先使用服务端返回的 Retry-After），配额耗尽除外；
+// 所有方法都遵循 ctx 的取消和截止时间。测试时可以用 clienttest.NewServer 启动进程内的模拟服务
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认值
+const (
+	defaultTimeout    = 60 * time.Second
+	defaultMaxRetries = 3
+	defaultBaseDelay  = 200 * time.Millisecond
+	defaultMaxDelay   = 5 * time.Second
+	defaultUserAgent  = "flow-codeblock-go-client"
+
+	// maxErrorBodySize 解析错误响应时最多读取的字节数
+	maxErrorBodySize = 1 << 20
+)
+
+// RetryPolicy 重试策略（仅对 429 / 503 生效）
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数（0 表示不重试）
+	BaseDelay  time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelay   time.Duration // 退避等待上限（服务端返回的 Retry-After 不受此限制）
+}
+
+// Client Flow-CodeBlock API 客户端（并发安全）
+type Client struct {
+	baseURL    string // 不含末尾的 /
+	token      string
+	adminToken string
+	httpClient *http.Client
+	userAgent  string
+	retry      RetryPolicy
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithToken 设置访问令牌（执行接口使用）
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithAdminToken 设置管理员令牌（管理接口使用）
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// WithHTTPClient 设置底层 HTTP 客户端（默认超时 60 秒）
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithRetry 设置重试策略（默认最多重试 3 次，200ms 起指数退避，上限 5 秒）
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithUserAgent 设置 User-Agent
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New 创建客户端（baseURL 为服务地址，如 https://flow.example.com，不含 /flow 前缀）
+func New(baseURL string, opts ...Option) (*Client, error) {
+	baseURL = strings.TrimRight(baseURL, "/")
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: 无效的服务地址: %w", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.RawQuery != "" {
+		return nil, fmt.Errorf("client: 服务地址必须是 http(s)://host[:port][/path]，当前值: %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		userAgent:  defaultUserAgent,
+		retry: RetryPolicy{
+			MaxRetries: defaultMaxRetries,
+			BaseDelay:  defaultBaseDelay,
+			MaxDelay:   defaultMaxDelay,
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxRetries < 0 {
+		return nil, fmt.Errorf("client: MaxRetries 必须 >= 0，当前值: %d", c.retry.MaxRetries)
+	}
+	return c, nil
+}
+
+// authMode 请求使用的令牌
+type authMode int
+
+const (
+	authToken authMode = iota // 访问令牌
+	authAdmin                 // 管理员令牌
+)
+
+// call 一次 API 调用的参数
+type call struct {
+	method string
+	path   string     // 以 /flow 开头（path 参数需已转义）
+	query  url.Values // 可为 nil
+	body   interface{}
+	auth   authMode
+}
+
+// do 发送请求并返回 2xx 响应体；非 2xx 响应解析为 *APIError，429 / 503 按重试策略重试
+func (c *Client) do(ctx context.Context, req *call) ([]byte, error) {
+	var payload []byte
+	if req.body != nil {
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("client: 序列化请求失败: %w", err)
+		}
+		payload = data
+	}
+
+	for attempt := 0; ; attempt++ {
+		body, err := c.send(ctx, req, payload)
+		if err == nil {
+			return body, nil
+		}
+
+		apiErr, ok := err.(*APIError)
+		if !ok || !apiErr.retryable() || attempt >= c.retry.MaxRetries {
+			return nil, err
+		}
+
+		wait := c.backoff(attempt)
+		if apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		// 等待会超过截止时间时直接返回本次错误
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send 发送一次请求
+func (c *Client) send(ctx context.Context, req *call, payload []byte) ([]byte, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var bodyReader io.Reader
+	if payload != nil {
+		bodyReader = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("client: 创建请求失败: %w", err)
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+
+	token := c.token
+	if req.auth == authAdmin {
+		token = c.adminToken
+	}
+	if token != "" {
+		httpReq.Header.Set("accessToken", token)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("client: %s %s 请求失败: %w", req.method, req.path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("client: 读取响应失败: %w", err)
+		}
+		return body, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return nil, parseAPIError(resp.StatusCode, resp.Header, body)
+}
+
+// backoff 第 attempt 次重试前的退避时间（指数增长 + 随机抖动，避免多个客户端同时重试）
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retry.BaseDelay
+	for i := 0; i < attempt && delay < c.retry.MaxDelay; i++ {
+		delay *= 2
+	}
+	if c.retry.MaxDelay > 0 && delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// 在 [delay/2, delay] 之间随机
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+// envelope 管理接口的统一响应格式（ginutil.SuccessResponse）
+type envelope struct {
+	Success   bool            `json:"success"`
+	Data      json.RawMessage `json:"data"`
+	Message   string          `json:"message"`
+	RequestID string          `json:"request_id"`
+}
+
+// doData 调用管理接口并把 data 字段解码到 out（out 为 nil 时忽略 data）
+func (c *Client) doData(ctx context.Context, req *call, out interface{}) error {
+	body, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return fmt.Errorf("client: 解析响应失败: %w", err)
+	}
+	if out == nil || len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("client: 解析响应数据失败: %w", err)
+	}
+	return nil
+}
+
+// setQuery 非空时设置查询参数
+func setQuery(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+// setQueryInt 大于 0 时设置查询参数
+func setQueryInt(query url.Values, key string, value int) {
+	if value > 0 {
+		query.Set(key, strconv.Itoa(value))
+	}
+}
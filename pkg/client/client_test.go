@@ -0,0 +1,300 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/client"
+	"flow-codeblock-go/pkg/client/clienttest"
+)
+
+func intPtr(n int) *int { return &n }
+
+// sumExecute 把 input.a + input.b 作为结果返回，并输出一条 console 日志
+func sumExecute(ctx context.Context, code string, input map[string]interface{}) (interface{}, error) {
+	a, _ := input["a"].(float64)
+	b, _ := input["b"].(float64)
+	return &model.ExecutionResult{
+		Result: map[string]interface{}{"sum": a + b},
+		Logs:   []model.ConsoleLogEntry{{Level: "log", Message: "sum"}},
+	}, nil
+}
+
+// newTestClient 启动模拟服务并创建带访问令牌的客户端
+func newTestClient(t *testing.T, opts ...clienttest.Option) (*clienttest.Server, *client.Client, *model.TokenInfo) {
+	t.Helper()
+	srv := clienttest.NewServer(opts...)
+	t.Cleanup(srv.Close)
+	info := srv.AddToken(model.TokenInfo{WsID: "ws", Email: "a@example.com", QuotaType: "count", TotalQuota: intPtr(10)})
+	return srv, srv.Client(client.WithToken(info.AccessToken)), info
+}
+
+func TestExecuteDecoding(t *testing.T) {
+	srv, c, info := newTestClient(t, clienttest.WithExecuteFunc(sumExecute))
+
+	result, err := c.Execute(context.Background(), "return input.a + input.b", map[string]interface{}{"a": 1, "b": 2})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	var out struct {
+		Sum int `json:"sum"`
+	}
+	if err := result.Decode(&out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if out.Sum != 3 {
+		t.Errorf("sum = %d, want 3", out.Sum)
+	}
+	if result.RequestID == "" || result.Timing == nil {
+		t.Errorf("RequestID = %q, Timing = %v", result.RequestID, result.Timing)
+	}
+	if len(result.Logs) != 1 || result.Logs[0].Message != "sum" {
+		t.Errorf("Logs = %+v", result.Logs)
+	}
+	if remaining := srv.Token(info.AccessToken).RemainingQuota; *remaining != 9 {
+		t.Errorf("剩余配额 = %d, want 9", *remaining)
+	}
+}
+
+func TestExecuteBatchItemErrors(t *testing.T) {
+	_, c, _ := newTestClient(t, clienttest.WithExecuteFunc(func(ctx context.Context, code string, input map[string]interface{}) (interface{}, error) {
+		if code == "throw" {
+			return nil, &model.ExecutionError{Type: "RuntimeError", Message: "boom", Stack: "at line 1"}
+		}
+		return input["n"], nil
+	}))
+
+	result, err := c.ExecuteBatch(context.Background(), []client.BatchItem{
+		{Code: "return input.n", Input: map[string]interface{}{"n": 1}},
+		{Code: "throw"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteBatch: %v", err)
+	}
+	if result.Total != 2 || result.Succeeded != 1 || result.Failed != 1 || len(result.Items) != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	var n int
+	if err := result.Items[0].Decode(&n); err != nil || n != 1 {
+		t.Errorf("Items[0] = %d, %v", n, err)
+	}
+
+	// 条目级错误：StatusCode 为 0，保留类型和堆栈
+	var apiErr *client.APIError
+	if !errors.As(result.Items[1].Err, &apiErr) {
+		t.Fatalf("Items[1].Err = %v, want *APIError", result.Items[1].Err)
+	}
+	if apiErr.StatusCode != 0 || apiErr.Type != "RuntimeError" || apiErr.Message != "boom" || apiErr.Stack != "at line 1" {
+		t.Errorf("Items[1].Err = %+v", apiErr)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failure      clienttest.Failure
+		wantType     string // 为空表示重试后成功
+		wantRequests int
+	}{
+		{"503 重试后成功", clienttest.Failure{Status: http.StatusServiceUnavailable, Type: "ServiceUnavailableError", Times: 2}, "", 3},
+		{"429 重试后成功", clienttest.Failure{Status: http.StatusTooManyRequests, Type: "QueueFullError", Times: 3}, "", 4},
+		{"超过最大重试次数", clienttest.Failure{Status: http.StatusServiceUnavailable, Type: "ServiceUnavailableError", Times: 4}, "ServiceUnavailableError", 4},
+		{"配额耗尽不重试", clienttest.Failure{Status: http.StatusTooManyRequests, Type: "QuotaExceeded"}, "QuotaExceeded", 1},
+		{"500 不重试", clienttest.Failure{Status: http.StatusInternalServerError, Type: "InternalError"}, "InternalError", 1},
+		{"校验失败不重试", clienttest.Failure{Status: http.StatusBadRequest, Type: "ValidationError"}, "ValidationError", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, c, _ := newTestClient(t)
+			tt.failure.Path = "/flow/codeblock"
+			srv.InjectFailure(tt.failure)
+
+			_, err := c.Execute(context.Background(), "return 1", nil)
+			if tt.wantType == "" {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+			} else {
+				var apiErr *client.APIError
+				if !errors.As(err, &apiErr) || apiErr.Type != tt.wantType {
+					t.Fatalf("err = %v, want %s", err, tt.wantType)
+				}
+			}
+			if got := srv.Requests(http.MethodPost, "/flow/codeblock"); got != tt.wantRequests {
+				t.Errorf("请求次数 = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv, c, _ := newTestClient(t)
+	srv.InjectFailure(clienttest.Failure{Path: "/flow/codeblock", Status: http.StatusTooManyRequests, Type: "TokenRateLimitError", RetryAfter: 30})
+
+	// Retry-After 超过截止时间：不等待，直接返回本次错误
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Execute(ctx, "return 1", nil)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("耗时 %v，不应等待 Retry-After", elapsed)
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if apiErr.RetryAfter != 30*time.Second {
+		t.Errorf("RetryAfter = %v, want 30s", apiErr.RetryAfter)
+	}
+	if got := srv.Requests(http.MethodPost, "/flow/codeblock"); got != 1 {
+		t.Errorf("请求次数 = %d, want 1", got)
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := []struct {
+		status  int
+		errType string
+		want    error
+	}{
+		{http.StatusTooManyRequests, "QuotaExceeded", client.ErrQuotaExceeded},
+		{http.StatusTooManyRequests, "ConcurrencyError", client.ErrConcurrency},
+		{http.StatusTooManyRequests, "QueueFullError", client.ErrConcurrency},
+		{http.StatusTooManyRequests, "IPRateLimitError", client.ErrRateLimited},
+		{http.StatusServiceUnavailable, "ServiceUnavailableError", client.ErrServiceUnavailable},
+		{http.StatusBadRequest, "ValidationError", client.ErrValidation},
+		{http.StatusBadRequest, "TimeoutError", client.ErrTimeout},
+		{http.StatusUnauthorized, "AuthenticationError", client.ErrUnauthorized},
+		{http.StatusForbidden, "AuthorizationError", client.ErrUnauthorized},
+		{http.StatusNotFound, "NotFoundError", client.ErrNotFound},
+		// 未知类型按状态码匹配
+		{http.StatusNotFound, "SomethingElse", client.ErrNotFound},
+		{http.StatusServiceUnavailable, "SomethingElse", client.ErrServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s_%d", tt.errType, tt.status), func(t *testing.T) {
+			srv, c, _ := newTestClient(t)
+			srv.InjectFailure(clienttest.Failure{Status: tt.status, Type: tt.errType, Message: "injected", Times: 10})
+
+			_, err := c.Execute(context.Background(), "return 1", nil)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			var apiErr *client.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %T, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Type != tt.errType || apiErr.Message != "injected" {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+		})
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	srv, c, _ := newTestClient(t, clienttest.WithExecuteFunc(func(ctx context.Context, code string, input map[string]interface{}) (interface{}, error) {
+		return nil, &model.ExecutionError{
+			Type:    "TimeoutError",
+			Message: "执行超时",
+			Stack:   "at main",
+			Logs:    []model.ConsoleLogEntry{{Level: "log", Message: "before"}},
+		}
+	}))
+
+	// 执行失败：400 响应体中的类型、堆栈和失败前的日志
+	_, err := c.Execute(context.Background(), "while (true) {}", nil)
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrTimeout) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Stack != "at main" || apiErr.RequestID == "" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	if len(apiErr.Logs) != 1 || apiErr.Logs[0].Message != "before" {
+		t.Errorf("Logs = %+v", apiErr.Logs)
+	}
+
+	// 缺少或无效的访问令牌
+	for _, token := range []string{"", "flow_unknown"} {
+		_, err := srv.Client(client.WithToken(token)).Execute(context.Background(), "return 1", nil)
+		if !errors.Is(err, client.ErrUnauthorized) {
+			t.Errorf("token %q: err = %v, want ErrUnauthorized", token, err)
+		}
+	}
+
+	// 配额用完：真实扣减后返回 QuotaExceeded，不重试
+	info := srv.AddToken(model.TokenInfo{WsID: "ws", Email: "b@example.com", QuotaType: "count", TotalQuota: intPtr(1)})
+	limited := srv.Client(client.WithToken(info.AccessToken))
+	before := srv.Requests(http.MethodPost, "/flow/codeblock")
+	limited.Execute(context.Background(), "return 1", nil)
+	if _, err := limited.Execute(context.Background(), "return 1", nil); !errors.Is(err, client.ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want ErrQuotaExceeded", err)
+	}
+	if got := srv.Requests(http.MethodPost, "/flow/codeblock") - before; got != 2 {
+		t.Errorf("请求次数 = %d, want 2", got)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	srv, c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := c.CreateToken(ctx, &model.CreateTokenRequest{
+		WsID: "ws-admin", Email: "admin@example.com", Operation: "set", SpecificDate: "2099-01-02",
+		QuotaType: "count", TotalQuota: intPtr(5),
+	})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	if created.AccessToken == "" || created.WsID != "ws-admin" || created.ExpiresAt == nil ||
+		created.ExpiresAt.Format("2006-01-02") != "2099-01-02" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	// 按 ws_id 查询时 Token 脱敏
+	tokens, err := c.ListTokens(ctx, &model.TokenQueryRequest{WsID: "ws-admin"})
+	if err != nil {
+		t.Fatalf("ListTokens: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].AccessToken != created.AccessToken[:15]+"***" {
+		t.Errorf("tokens = %+v", tokens)
+	}
+
+	if _, err := srv.Client(client.WithToken(created.AccessToken)).Execute(ctx, "return 1", nil); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	quota, err := c.GetQuota(ctx, created.AccessToken)
+	if err != nil {
+		t.Fatalf("GetQuota: %v", err)
+	}
+	if quota.QuotaType != "count" || quota.TotalQuota != 5 || quota.RemainingQuota != 4 || quota.ConsumedQuota != 1 {
+		t.Errorf("quota = %+v", quota)
+	}
+	logs, err := c.GetQuotaLogs(ctx, created.AccessToken, nil)
+	if err != nil {
+		t.Fatalf("GetQuotaLogs: %v", err)
+	}
+	if logs.Total != 1 || len(logs.Logs) != 1 || logs.Logs[0].Action != "consume" || logs.Logs[0].QuotaChange != -1 {
+		t.Errorf("logs = %+v", logs)
+	}
+
+	if err := c.DeleteToken(ctx, created.AccessToken); err != nil {
+		t.Fatalf("DeleteToken: %v", err)
+	}
+	if _, err := c.GetQuota(ctx, created.AccessToken); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetQuota 已删除的 Token: err = %v, want ErrNotFound", err)
+	}
+
+	// 无效的管理员令牌：403
+	_, err = srv.Client(client.WithAdminToken("wrong")).GetQuota(ctx, created.AccessToken)
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("err = %v, want 403 ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,342 @@
+package clienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flow-codeblock-go/model"
+)
+
+// ==================== Token ====================
+
+// handleCreateToken POST /flow/tokens
+func (s *Server) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "ValidationError", "请求参数错误: "+err.Error(), nil)
+		return
+	}
+	if req.WsID == "" || req.Email == "" || req.Operation == "" {
+		writeError(w, http.StatusBadRequest, "ValidationError", "请求参数错误: ws_id、email和operation参数不能为空", nil)
+		return
+	}
+	if req.QuotaType != "" && req.QuotaType != "time" && (req.TotalQuota == nil || *req.TotalQuota <= 0) {
+		writeError(w, http.StatusInternalServerError, "InternalError",
+			fmt.Sprintf("创建Token失败: quota_type为%s时，total_quota必须为正整数", req.QuotaType), nil)
+		return
+	}
+	expiresAt, err := expiresAt(req.Operation, req.Days, req.SpecificDate)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", "创建Token失败: "+err.Error(), nil)
+		return
+	}
+
+	info := s.AddToken(model.TokenInfo{
+		WsID:                   req.WsID,
+		Email:                  req.Email,
+		ExpiresAt:              expiresAt,
+		OperationType:          req.Operation,
+		RateLimitPerMinute:     req.RateLimitPerMinute,
+		RateLimitBurst:         req.RateLimitBurst,
+		RateLimitWindowSeconds: req.RateLimitWindowSeconds,
+		QuotaType:              req.QuotaType,
+		TotalQuota:             req.TotalQuota,
+		PolicyName:             req.PolicyName,
+		Policy:                 req.Policy,
+	})
+	writeSuccess(w, info, "Token创建成功")
+}
+
+// handleUpdateToken PUT /flow/tokens/{token}
+func (s *Server) handleUpdateToken(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+	var req model.UpdateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "ValidationError", "请求参数错误: "+err.Error(), nil)
+		return
+	}
+	if req.Operation != "set" && req.Operation != "unlimited" {
+		writeError(w, http.StatusBadRequest, "ValidationError", "请求参数错误: operation 必须为 set 或 unlimited", nil)
+		return
+	}
+	expires, err := expiresAt(req.Operation, nil, req.SpecificDate)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", "更新Token失败: "+err.Error(), nil)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.tokens[token]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "InternalError", "更新Token失败: Token不存在", nil)
+		return
+	}
+	info.ExpiresAt = expires
+	info.OperationType = req.Operation
+	info.RateLimitPerMinute = req.RateLimitPerMinute
+	info.RateLimitBurst = req.RateLimitBurst
+	info.RateLimitWindowSeconds = req.RateLimitWindowSeconds
+	if req.QuotaType != "" {
+		info.QuotaType = req.QuotaType
+	}
+	if req.QuotaOperation != "" {
+		if err := s.updateQuota(info, req.QuotaOperation, req.QuotaAmount); err != nil {
+			writeError(w, http.StatusInternalServerError, "InternalError", "更新Token失败: 更新配额失败: "+err.Error(), nil)
+			return
+		}
+	}
+	info.UpdatedAt = model.ShanghaiTime{Time: time.Now()}
+
+	copied := *info
+	writeSuccess(w, &copied, "Token更新成功")
+}
+
+// updateQuota 配额操作（与 QuotaService.UpdateQuota 一致；调用方持有锁）
+func (s *Server) updateQuota(info *model.TokenInfo, operation string, amount *int) error {
+	total, remaining := 0, 0
+	if info.TotalQuota != nil {
+		total = *info.TotalQuota
+	}
+	if info.RemainingQuota != nil {
+		remaining = *info.RemainingQuota
+	}
+	before := remaining
+
+	switch operation {
+	case "add":
+		if amount == nil || *amount <= 0 {
+			return fmt.Errorf("quota_operation为add时，quota_amount必须为正整数")
+		}
+		total += *amount
+		remaining += *amount
+	case "set":
+		if amount == nil || *amount < 0 {
+			return fmt.Errorf("quota_operation为set时，quota_amount不能为负数")
+		}
+		remaining = *amount
+	case "reset":
+		if amount != nil {
+			total = *amount
+		}
+		remaining = total
+	default:
+		return fmt.Errorf("无效的quota_operation: %s", operation)
+	}
+
+	info.TotalQuota, info.RemainingQuota = &total, &remaining
+	action := "recharge"
+	if operation == "reset" {
+		action = "init"
+	}
+	s.appendQuotaLog(info, before, remaining, action, nil)
+	return nil
+}
+
+// handleDeleteToken DELETE /flow/tokens/{token}
+func (s *Server) handleDeleteToken(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+	s.mu.Lock()
+	_, ok := s.tokens[token]
+	delete(s.tokens, token)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "InternalError", "删除Token失败: Token不存在", nil)
+		return
+	}
+	writeSuccess(w, nil, "Token删除成功")
+}
+
+// handleListTokens GET /flow/tokens（与 TokenService.GetTokenInfo 一致：只按 ws_id 或 email 查询时 Token 脱敏）
+func (s *Server) handleListTokens(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	token, wsID, email := query.Get("token"), query.Get("ws_id"), query.Get("email")
+	if token == "" && wsID == "" && email == "" {
+		writeError(w, http.StatusInternalServerError, "InternalError", "查询Token失败: 必须提供ws_id、email或token参数", nil)
+		return
+	}
+	mask := token == "" && (wsID == "" || email == "")
+
+	s.mu.Lock()
+	tokens := []*model.TokenInfo{}
+	for _, info := range s.tokens {
+		if (token != "" && info.AccessToken != token) ||
+			(token == "" && wsID != "" && info.WsID != wsID) ||
+			(token == "" && email != "" && info.Email != email) {
+			continue
+		}
+		copied := *info
+		if mask {
+			copied.AccessToken = maskToken(copied.AccessToken)
+		}
+		tokens = append(tokens, &copied)
+	}
+	s.mu.Unlock()
+
+	writeSuccess(w, map[string]interface{}{
+		"tokens": tokens,
+		"count":  len(tokens),
+	}, "")
+}
+
+// ==================== 配额 ====================
+
+// handleGetQuota GET /flow/tokens/{token}/quota
+func (s *Server) handleGetQuota(w http.ResponseWriter, r *http.Request) {
+	info := s.Token(r.PathValue("token"))
+	if info == nil {
+		writeError(w, http.StatusNotFound, "NotFoundError", "Token不存在", nil)
+		return
+	}
+	if !info.NeedsQuotaCheck() {
+		writeSuccess(w, map[string]interface{}{
+			"quota_type": info.QuotaType,
+			"message":    "该Token为时间模式，无配额限制",
+		}, "")
+		return
+	}
+
+	total, remaining := 0, 0
+	if info.TotalQuota != nil {
+		total = *info.TotalQuota
+	}
+	if info.RemainingQuota != nil {
+		remaining = *info.RemainingQuota
+	}
+	consumed := 0
+	if total > remaining {
+		consumed = total - remaining
+	}
+	writeSuccess(w, map[string]interface{}{
+		"quota_type":      info.QuotaType,
+		"total_quota":     total,
+		"remaining_quota": remaining,
+		"consumed_quota":  consumed,
+		"quota_synced_at": info.QuotaSyncedAt,
+	}, "")
+}
+
+// handleGetQuotaLogs GET /flow/tokens/{token}/quota/logs（按时间倒序分页）
+func (s *Server) handleGetQuotaLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize < 1 || pageSize > 1000 {
+		pageSize = 100
+	}
+	startDate, endDate := query.Get("start_date"), query.Get("end_date")
+
+	s.mu.Lock()
+	var matched []*model.QuotaLog
+	all := s.quotaLogs[r.PathValue("token")]
+	for i := len(all) - 1; i >= 0; i-- {
+		date := all[i].CreatedAt.In(shanghaiLocation).Format("2006-01-02")
+		if (startDate != "" && date < startDate) || (endDate != "" && date > endDate) {
+			continue
+		}
+		copied := *all[i]
+		matched = append(matched, &copied)
+	}
+	s.mu.Unlock()
+
+	total := len(matched)
+	logs := []*model.QuotaLog{}
+	if from := (page - 1) * pageSize; from < total {
+		logs = matched[from:min(from+pageSize, total)]
+	}
+	writeSuccess(w, map[string]interface{}{
+		"logs":        logs,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + pageSize - 1) / pageSize,
+	}, "")
+}
+
+// handleQuotaCleanupStats GET /flow/quota/cleanup/stats（模拟服务不清理日志）
+func (s *Server) handleQuotaCleanupStats(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, map[string]interface{}{
+		"enabled": false,
+		"message": "配额清理服务未启用",
+	}, "")
+}
+
+// handleTriggerQuotaCleanup POST /flow/quota/cleanup/trigger
+func (s *Server) handleTriggerQuotaCleanup(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, map[string]interface{}{
+		"message": "清理任务已提交，正在后台执行",
+	}, "清理任务已启动")
+}
+
+// ==================== 缓存和限流 ====================
+
+// handleCacheStats GET /flow/cache/stats
+func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	size := len(s.tokens)
+	s.mu.Unlock()
+	writeSuccess(w, map[string]interface{}{"size": size}, "")
+}
+
+// handleClearCache DELETE /flow/cache
+func (s *Server) handleClearCache(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, nil, "缓存已清空")
+}
+
+// handleRateLimitStats GET /flow/rate-limit/stats（模拟服务不限流）
+func (s *Server) handleRateLimitStats(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, map[string]interface{}{
+		"rate_limit": map[string]interface{}{},
+		"write_pool": map[string]interface{}{},
+	}, "")
+}
+
+// handleClearRateLimit DELETE /flow/rate-limit/{token}
+func (s *Server) handleClearRateLimit(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, nil, "限流缓存已清除")
+}
+
+// ==================== 统计 ====================
+
+// handleModuleStats GET /flow/stats/modules（模拟服务不记录统计，返回空结果）
+func (s *Server) handleModuleStats(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, &model.ModuleStatsResponse{}, "")
+}
+
+// handleModuleDetailStats GET /flow/stats/modules/{module_name}
+func (s *Server) handleModuleDetailStats(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, &model.ModuleDetailResponse{}, "")
+}
+
+// handleUserActivityStats GET /flow/stats/users
+func (s *Server) handleUserActivityStats(w http.ResponseWriter, r *http.Request) {
+	writeSuccess(w, &model.UserActivityResponse{}, "")
+}
+
+// expiresAt 计算过期时间（与 TokenRepository.CalculateExpiresAt 一致）
+func expiresAt(operation string, days *int, specificDate string) (*model.ShanghaiTime, error) {
+	switch operation {
+	case "unlimited":
+		return nil, nil
+	case "add":
+		if days == nil || *days <= 0 {
+			return nil, fmt.Errorf("operation为add时，days参数必须为正整数")
+		}
+		return &model.ShanghaiTime{Time: time.Now().AddDate(0, 0, *days)}, nil
+	case "set":
+		t, err := parseShanghaiTime(specificDate)
+		if err != nil {
+			return nil, fmt.Errorf("specific_date格式错误，支持格式：yyyy-MM-dd 或 yyyy-MM-dd HH:mm:ss")
+		}
+		return &model.ShanghaiTime{Time: t}, nil
+	default:
+		return nil, fmt.Errorf("无效的operation类型: %s", operation)
+	}
+}
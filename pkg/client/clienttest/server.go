@@ -0,0 +1,554 @@
+// Package clienttest 进程内的模拟 Flow-CodeBlock 服务（client 包的测试工具）
+//
+// 模拟服务实现 client 覆盖的全部接口，数据保存在内存中，响应格式与真实服务一致：
+//
+//	srv := clienttest.NewServer()
+//	defer srv.Close()
+//
+//	info := srv.AddToken(model.TokenInfo{WsID: "ws", Email: "a@example.com", QuotaType: "count", TotalQuota: intPtr(1)})
+//	c := srv.Client(client.WithToken(info.AccessToken))
+//	result, err := c.Execute(ctx, "return 1", nil)
+//
+//	// 注入失败，测试重试和错误处理
+//	srv.InjectFailure(clienttest.Failure{Path: "/flow/codeblock", Status: 503, Type: "ServiceUnavailableError"})
+//
+// 默认不执行代码，直接把 input 作为结果返回；需要真实执行时用 WithExecuteFunc 接入执行器：
+//
+//	srv := clienttest.NewServer(clienttest.WithExecuteFunc(func(ctx context.Context, code string, input map[string]interface{}) (interface{}, error) {
+//		return executor.Execute(ctx, code, input) // *sandbox.JSExecutor
+//	}))
+//
+// 与 pkg/client 一样只依赖 model 和标准库，不引入执行器、otel、gRPC 等服务端依赖
+package clienttest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/client"
+)
+
+// DefaultAdminToken 默认的管理员令牌
+const DefaultAdminToken = "clienttest-admin-token"
+
+// ExecuteFunc 执行代码（返回 *model.ExecutionError 时使用其错误类型，其他错误按 RuntimeError 返回）
+type ExecuteFunc func(ctx context.Context, code string, input map[string]interface{}) (interface{}, error)
+
+// Failure 注入的失败响应
+type Failure struct {
+	Method     string // 为空时匹配任意方法
+	Path       string // 请求路径（如 /flow/codeblock），为空时匹配任意路径
+	Status     int    // HTTP 状态码（默认 500）
+	Type       string // 错误类型（默认 InternalError）
+	Message    string
+	RetryAfter int // 秒，> 0 时设置 Retry-After 响应头
+	Times      int // 生效次数（默认 1）
+}
+
+// Option 模拟服务选项
+type Option func(*Server)
+
+// WithAdminToken 设置管理员令牌（默认 DefaultAdminToken）
+func WithAdminToken(token string) Option {
+	return func(s *Server) {
+		s.AdminToken = token
+	}
+}
+
+// WithExecuteFunc 替换代码执行（默认把 input 作为结果返回）
+func WithExecuteFunc(fn ExecuteFunc) Option {
+	return func(s *Server) {
+		s.execute = fn
+	}
+}
+
+// Server 模拟服务
+type Server struct {
+	*httptest.Server
+	AdminToken string
+
+	mu        sync.Mutex
+	nextID    int
+	nextLogID int64
+	tokens    map[string]*model.TokenInfo
+	quotaLogs map[string][]*model.QuotaLog
+	failures  []*Failure
+	requests  map[string]int // "METHOD /path" → 次数
+
+	execute ExecuteFunc
+}
+
+// NewServer 启动模拟服务（用完调用 Close）
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		AdminToken: DefaultAdminToken,
+		tokens:     make(map[string]*model.TokenInfo),
+		quotaLogs:  make(map[string][]*model.QuotaLog),
+		requests:   make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.execute == nil {
+		s.execute = echoExecute
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /flow/codeblock", s.tokenAuth(s.handleExecute))
+	mux.HandleFunc("POST /flow/codeblock/batch", s.tokenAuth(s.handleExecuteBatch))
+	mux.HandleFunc("POST /flow/tokens", s.adminAuth(s.handleCreateToken))
+	mux.HandleFunc("GET /flow/tokens", s.adminAuth(s.handleListTokens))
+	mux.HandleFunc("PUT /flow/tokens/{token}", s.adminAuth(s.handleUpdateToken))
+	mux.HandleFunc("DELETE /flow/tokens/{token}", s.adminAuth(s.handleDeleteToken))
+	mux.HandleFunc("GET /flow/tokens/{token}/quota", s.adminAuth(s.handleGetQuota))
+	mux.HandleFunc("GET /flow/tokens/{token}/quota/logs", s.adminAuth(s.handleGetQuotaLogs))
+	mux.HandleFunc("GET /flow/quota/cleanup/stats", s.adminAuth(s.handleQuotaCleanupStats))
+	mux.HandleFunc("POST /flow/quota/cleanup/trigger", s.adminAuth(s.handleTriggerQuotaCleanup))
+	mux.HandleFunc("GET /flow/cache/stats", s.adminAuth(s.handleCacheStats))
+	mux.HandleFunc("DELETE /flow/cache", s.adminAuth(s.handleClearCache))
+	mux.HandleFunc("GET /flow/rate-limit/stats", s.adminAuth(s.handleRateLimitStats))
+	mux.HandleFunc("DELETE /flow/rate-limit/{token}", s.adminAuth(s.handleClearRateLimit))
+	mux.HandleFunc("GET /flow/stats/modules", s.adminAuth(s.handleModuleStats))
+	mux.HandleFunc("GET /flow/stats/modules/{module_name}", s.adminAuth(s.handleModuleDetailStats))
+	mux.HandleFunc("GET /flow/stats/users", s.adminAuth(s.handleUserActivityStats))
+
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// Client 创建指向模拟服务的客户端（已设置管理员令牌，重试等待缩短到毫秒级；opts 可以覆盖）
+func (s *Server) Client(opts ...client.Option) *client.Client {
+	defaults := []client.Option{
+		client.WithAdminToken(s.AdminToken),
+		client.WithHTTPClient(s.Server.Client()),
+		client.WithRetry(client.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}),
+	}
+	c, err := client.New(s.URL, append(defaults, opts...)...)
+	if err != nil {
+		panic(fmt.Sprintf("clienttest: 创建客户端失败: %v", err))
+	}
+	return c
+}
+
+// AddToken 直接添加 Token（AccessToken 为空时自动生成；count / hybrid 类型的剩余配额默认等于总配额）
+func (s *Server) AddToken(info model.TokenInfo) *model.TokenInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info.AccessToken == "" {
+		info.AccessToken = newAccessToken()
+	}
+	if info.QuotaType == "" {
+		info.QuotaType = "time"
+	}
+	if info.RemainingQuota == nil && info.TotalQuota != nil {
+		remaining := *info.TotalQuota
+		info.RemainingQuota = &remaining
+	}
+	s.nextID++
+	info.ID = s.nextID
+	info.IsActive = true
+	now := model.ShanghaiTime{Time: time.Now()}
+	info.CreatedAt, info.UpdatedAt = now, now
+
+	stored := info
+	s.tokens[info.AccessToken] = &stored
+	copied := stored
+	return &copied
+}
+
+// Token 查询 Token 的当前状态（不存在时返回 nil）
+func (s *Server) Token(accessToken string) *model.TokenInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.tokens[accessToken]
+	if !ok {
+		return nil
+	}
+	copied := *info
+	return &copied
+}
+
+// InjectFailure 让之后匹配的请求返回指定的失败响应（按注入顺序匹配，用完即失效）
+func (s *Server) InjectFailure(f Failure) {
+	if f.Status == 0 {
+		f.Status = http.StatusInternalServerError
+	}
+	if f.Type == "" {
+		f.Type = "InternalError"
+	}
+	if f.Message == "" {
+		f.Message = "clienttest: 注入的失败"
+	}
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.mu.Lock()
+	s.failures = append(s.failures, &f)
+	s.mu.Unlock()
+}
+
+// Requests 指定请求收到的次数（含注入失败的请求），如 Requests("POST", "/flow/codeblock")
+func (s *Server) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[method+" "+path]
+}
+
+// intercept 记录请求次数并返回注入的失败
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.Method+" "+r.URL.Path]++
+		var failure *Failure
+		for i, f := range s.failures {
+			if (f.Method == "" || f.Method == r.Method) && (f.Path == "" || f.Path == r.URL.Path) {
+				failure = f
+				if f.Times--; f.Times == 0 {
+					s.failures = append(s.failures[:i], s.failures[i+1:]...)
+				}
+				break
+			}
+		}
+		s.mu.Unlock()
+
+		if failure == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if failure.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(failure.RetryAfter))
+		}
+		writeError(w, failure.Status, failure.Type, failure.Message, nil)
+	})
+}
+
+// ==================== 认证 ====================
+
+// tokenAuth 访问令牌认证（与 TokenAuthMiddleware 一致）
+func (s *Server) tokenAuth(next func(http.ResponseWriter, *http.Request, *model.TokenInfo)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := extractToken(r)
+		if token == "" {
+			writeError(w, http.StatusUnauthorized, "AuthenticationError", "缺少访问令牌，请在请求头中提供accessToken", nil)
+			return
+		}
+		s.mu.Lock()
+		info, ok := s.tokens[token]
+		var copied model.TokenInfo
+		if ok {
+			copied = *info
+		}
+		s.mu.Unlock()
+
+		switch {
+		case !ok:
+			writeError(w, http.StatusUnauthorized, "AuthenticationError", "Token无效: Token不存在", nil)
+		case !copied.IsActive || copied.IsExpired():
+			writeError(w, http.StatusUnauthorized, "AuthenticationError", "Token无效: Token已过期", nil)
+		default:
+			next(w, r, &copied)
+		}
+	}
+}
+
+// adminAuth 管理员令牌认证（与 AdminAuthMiddleware 一致）
+func (s *Server) adminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := extractToken(r)
+		switch {
+		case token == "":
+			writeError(w, http.StatusUnauthorized, "AuthenticationError", "缺少管理员访问令牌，请在请求头中提供accessToken", nil)
+		case token != s.AdminToken:
+			writeError(w, http.StatusForbidden, "AuthorizationError", "管理员令牌无效，访问被拒绝", nil)
+		default:
+			next(w, r)
+		}
+	}
+}
+
+// extractToken 从请求头获取令牌
+func extractToken(r *http.Request) string {
+	if token := r.Header.Get("accessToken"); token != "" {
+		return token
+	}
+	if token := r.Header.Get("access-token"); token != "" {
+		return token
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// ==================== 执行 ====================
+
+// handleExecute POST /flow/codeblock
+func (s *Server) handleExecute(w http.ResponseWriter, r *http.Request, info *model.TokenInfo) {
+	startTime := time.Now()
+	requestID := newRequestID()
+
+	var req model.ExecuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CodeBase64 == "" || req.Input == nil {
+		writeJSON(w, http.StatusBadRequest, failedResponse(requestID, startTime, "ValidationError", "请求参数错误: input 和 codebase64 不能为空"))
+		return
+	}
+	code, err := base64.StdEncoding.DecodeString(req.CodeBase64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, failedResponse(requestID, startTime, "ValidationError", "代码Base64解码失败"))
+		return
+	}
+
+	if !s.consumeQuota(info, requestID) {
+		writeJSON(w, http.StatusTooManyRequests, failedResponse(requestID, startTime, "QuotaExceeded", "配额已用完，请联系管理员充值"))
+		return
+	}
+
+	resp, status := s.run(r.Context(), requestID, string(code), req.Input, startTime)
+	writeJSON(w, status, resp)
+}
+
+// handleExecuteBatch POST /flow/codeblock/batch
+func (s *Server) handleExecuteBatch(w http.ResponseWriter, r *http.Request, info *model.TokenInfo) {
+	startTime := time.Now()
+	requestID := newRequestID()
+
+	var req model.BatchExecuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "ValidationError", "请求参数错误: "+err.Error(), nil)
+		return
+	}
+	items := req.Items
+	if len(items) == 0 {
+		for _, input := range req.Inputs {
+			items = append(items, model.BatchExecuteItem{CodeBase64: req.CodeBase64, Input: input})
+		}
+	}
+	if len(items) == 0 {
+		writeError(w, http.StatusBadRequest, "ValidationError", "items 或 codebase64 + inputs 不能为空", nil)
+		return
+	}
+
+	resp := model.BatchExecuteResponse{
+		Success:   true,
+		Total:     len(items),
+		Results:   make([]model.ExecuteResponse, len(items)),
+		RequestID: requestID,
+	}
+	for i, item := range items {
+		itemID := fmt.Sprintf("%s-%d", requestID, i)
+		itemStart := time.Now()
+		code, err := base64.StdEncoding.DecodeString(item.CodeBase64)
+		switch {
+		case err != nil:
+			resp.Results[i] = *failedResponse(itemID, itemStart, "ValidationError", "代码Base64解码失败")
+		case !s.consumeQuota(info, itemID):
+			resp.Results[i] = *failedResponse(itemID, itemStart, "QuotaExceeded", "配额已用完，请联系管理员充值")
+		default:
+			itemResp, _ := s.run(r.Context(), itemID, string(code), item.Input, itemStart)
+			resp.Results[i] = *itemResp
+		}
+		if resp.Results[i].Success {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	totalTime := time.Since(startTime).Milliseconds()
+	resp.Timing = &model.ExecuteTiming{ExecutionTime: totalTime, TotalTime: totalTime}
+	resp.Timestamp = formatNow()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// run 执行代码并构造响应（与 ExecutorController 的状态码一致：失败 400，排队被拒绝 429）
+func (s *Server) run(ctx context.Context, requestID, code string, input map[string]interface{}, startTime time.Time) (*model.ExecuteResponse, int) {
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	result, err := s.execute(ctx, code, input)
+	totalTime := time.Since(startTime).Milliseconds()
+	timing := &model.ExecuteTiming{ExecutionTime: totalTime, TotalTime: totalTime}
+
+	if err != nil {
+		resp := failedResponse(requestID, startTime, "RuntimeError", err.Error())
+		resp.Timing = timing
+		status := http.StatusBadRequest
+		var execErr *model.ExecutionError
+		if errors.As(err, &execErr) {
+			resp.Error.Type = execErr.Type
+			resp.Error.Message = execErr.Message
+			resp.Error.Stack = execErr.Stack
+			resp.Error.RetryAfter = execErr.RetryAfterSeconds()
+			resp.Logs, resp.LogsTruncated = execErr.Logs, execErr.LogsTruncated
+			if resp.Error.RetryAfter > 0 {
+				status = http.StatusTooManyRequests
+			}
+		}
+		return resp, status
+	}
+
+	resp := &model.ExecuteResponse{
+		Success:   true,
+		Result:    result,
+		Timing:    timing,
+		Timestamp: formatNow(),
+		RequestID: requestID,
+	}
+	if execResult, ok := result.(*model.ExecutionResult); ok {
+		resp.Result = execResult.Result
+		if len(execResult.JSONData) > 0 {
+			resp.Result = json.RawMessage(execResult.JSONData)
+		}
+		resp.Logs, resp.LogsTruncated = execResult.Logs, execResult.LogsTruncated
+	}
+	return resp, http.StatusOK
+}
+
+// echoExecute 默认的代码执行：不执行代码，返回 input
+func echoExecute(ctx context.Context, code string, input map[string]interface{}) (interface{}, error) {
+	return input, nil
+}
+
+// consumeQuota 扣减一次配额并记录日志（count / hybrid 类型，配额耗尽时返回 false）
+func (s *Server) consumeQuota(info *model.TokenInfo, requestID string) bool {
+	if !info.NeedsQuotaCheck() {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tokens[info.AccessToken]
+	if !ok || stored.RemainingQuota == nil {
+		return ok
+	}
+	before := *stored.RemainingQuota
+	if before <= 0 {
+		return false
+	}
+	after := before - 1
+	stored.RemainingQuota = &after
+	s.appendQuotaLog(stored, before, after, "consume", &requestID)
+	return true
+}
+
+// appendQuotaLog 记录配额变更（调用方持有锁）
+func (s *Server) appendQuotaLog(info *model.TokenInfo, before, after int, action string, requestID *string) {
+	s.nextLogID++
+	s.quotaLogs[info.AccessToken] = append(s.quotaLogs[info.AccessToken], &model.QuotaLog{
+		ID:          s.nextLogID,
+		Token:       info.AccessToken,
+		WsID:        info.WsID,
+		Email:       info.Email,
+		QuotaBefore: before,
+		QuotaAfter:  after,
+		QuotaChange: after - before,
+		Action:      action,
+		RequestID:   requestID,
+		CreatedAt:   model.ShanghaiTime{Time: time.Now()},
+	})
+}
+
+// ==================== 响应 ====================
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeSuccess 管理接口的成功响应（与 ginutil.RespondSuccess 一致）
+func writeSuccess(w http.ResponseWriter, data interface{}, message string) {
+	body := map[string]interface{}{
+		"success":   true,
+		"timestamp": formatNow(),
+	}
+	if data != nil {
+		body["data"] = data
+	}
+	if message != "" {
+		body["message"] = message
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// writeError 管理接口的错误响应（与 ginutil.RespondError 一致）
+func writeError(w http.ResponseWriter, status int, errType, message string, details map[string]interface{}) {
+	detail := map[string]interface{}{"type": errType, "message": message}
+	if details != nil {
+		detail["details"] = details
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"success":   false,
+		"error":     detail,
+		"timestamp": formatNow(),
+	})
+}
+
+// failedResponse 执行接口的失败响应
+func failedResponse(requestID string, startTime time.Time, errType, message string) *model.ExecuteResponse {
+	return &model.ExecuteResponse{
+		Success:   false,
+		Error:     &model.ExecuteError{Type: errType, Message: message},
+		Timing:    &model.ExecuteTiming{TotalTime: time.Since(startTime).Milliseconds()},
+		Timestamp: formatNow(),
+		RequestID: requestID,
+	}
+}
+
+// shanghaiLocation 上海时区（与 utils.ShanghaiLocation 相同；clienttest 不依赖 utils）
+var shanghaiLocation = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}()
+
+// formatNow 当前上海时区时间（与 utils.FormatTime(utils.Now()) 一致）
+func formatNow() string {
+	return time.Now().In(shanghaiLocation).Format("2006-01-02 15:04:05")
+}
+
+// parseShanghaiTime 解析 yyyy-MM-dd 或 yyyy-MM-dd HH:mm:ss（与 utils.ParseTime 一致）
+func parseShanghaiTime(str string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", str, shanghaiLocation); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", str, shanghaiLocation)
+}
+
+// maskToken 脱敏 Token：保留前 15 个字符（与 utils.MaskToken 一致）
+func maskToken(token string) string {
+	const showLength = 15
+	if token == "" {
+		return ""
+	}
+	if len(token) <= showLength {
+		return token + "***"
+	}
+	return token[:showLength] + "***"
+}
+
+// newAccessToken 生成与真实服务格式相同的访问令牌（flow_ + 64 位十六进制）
+func newAccessToken() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return "flow_" + hex.EncodeToString(buf)
+}
+
+// newRequestID 生成请求 ID
+func newRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
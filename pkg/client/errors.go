@@ -0,0 +1,206 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"flow-codeblock-go/model"
+)
+
+// 错误类型对应的错误值，用 errors.Is 判断：
+//
+//	if errors.Is(err, client.ErrQuotaExceeded) { ... }
+//
+// 需要错误详情（类型、消息、堆栈、request_id）时用 errors.As 取 *APIError
+var (
+	ErrQuotaExceeded      = errors.New("client: 配额已用完")               // QuotaExceeded
+	ErrConcurrency        = errors.New("client: 并发已满，等待执行槽位超时或排队被拒绝") // ConcurrencyError / QueueFullError
+	ErrServiceUnavailable = errors.New("client: 服务暂不可用")              // ServiceUnavailableError 或 HTTP 503
+	ErrValidation         = errors.New("client: 请求校验失败")              // ValidationError
+	ErrRateLimited        = errors.New("client: 请求被限流")               // TokenRateLimitError / IPRateLimitError / RateLimitError
+	ErrUnauthorized       = errors.New("client: 认证失败")                // AuthenticationError / AuthorizationError
+	ErrNotFound           = errors.New("client: 资源不存在")               // NotFoundError 或 HTTP 404
+	ErrTimeout            = errors.New("client: 代码执行超时")              // TimeoutError
+)
+
+// errorTypeSentinels 服务端错误类型 → 错误值
+var errorTypeSentinels = map[string]error{
+	"QuotaExceeded":           ErrQuotaExceeded,
+	"ConcurrencyError":        ErrConcurrency,
+	"QueueFullError":          ErrConcurrency,
+	"ServiceUnavailableError": ErrServiceUnavailable,
+	"ValidationError":         ErrValidation,
+	"TokenRateLimitError":     ErrRateLimited,
+	"IPRateLimitError":        ErrRateLimited,
+	"RateLimitError":          ErrRateLimited,
+	"AuthenticationError":     ErrUnauthorized,
+	"AuthorizationError":      ErrUnauthorized,
+	"NotFoundError":           ErrNotFound,
+	"TimeoutError":            ErrTimeout,
+}
+
+// APIError 服务端返回的错误（非 2xx 响应，或执行接口 success=false）
+type APIError struct {
+	StatusCode int    // HTTP 状态码
+	Type       string // 错误类型（如 ValidationError、RuntimeError、QuotaExceeded）
+	Message    string
+	Stack      string // JavaScript 错误堆栈（执行接口）
+	RequestID  string
+
+	// RetryAfter 服务端建议的重试等待时间（Retry-After 响应头或响应体中的 retryAfter，0 表示未返回）
+	RetryAfter time.Duration
+
+	// Details 错误详情（管理接口的 error.details）
+	Details map[string]interface{}
+
+	// Logs 失败前捕获的 console 输出（执行接口，CONSOLE_MODE=capture 时返回）
+	Logs          []model.ConsoleLogEntry
+	LogsTruncated bool
+}
+
+// Error 实现 error
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString("client: ")
+	if e.Type != "" {
+		b.WriteString(e.Type)
+		b.WriteString(": ")
+	}
+	if e.Message != "" {
+		b.WriteString(e.Message)
+	} else {
+		b.WriteString(http.StatusText(e.StatusCode))
+	}
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (HTTP %d", e.StatusCode)
+		if e.RequestID != "" {
+			fmt.Fprintf(&b, ", request_id=%s", e.RequestID)
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// Is 按错误类型匹配 Err* 错误值（类型未知时按 HTTP 状态码匹配 503 / 429 / 404）
+func (e *APIError) Is(target error) bool {
+	if sentinel, ok := errorTypeSentinels[e.Type]; ok {
+		return sentinel == target
+	}
+	switch e.StatusCode {
+	case http.StatusServiceUnavailable:
+		return target == ErrServiceUnavailable
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return target == ErrUnauthorized
+	}
+	return false
+}
+
+// retryable 是否可以重试（429 / 503；配额耗尽重试也不会成功）
+func (e *APIError) retryable() bool {
+	if e.Type == "QuotaExceeded" {
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// errorBody 错误响应体（兼容服务端的几种错误格式）
+//
+//   - 执行接口：{"success": false, "error": {"type", "message", "stack", "retryAfter"}, "logs": [...]}
+//   - 管理接口：{"success": false, "error": {"type", "message", "details": {"retryAfter"}}}
+//   - 统计接口：{"success": false, "error": "StatisticsError", "message": "..."}
+type errorBody struct {
+	Error         json.RawMessage         `json:"error"`
+	Message       string                  `json:"message"`
+	RequestID     string                  `json:"request_id"`
+	Logs          []model.ConsoleLogEntry `json:"logs"`
+	LogsTruncated bool                    `json:"logsTruncated"`
+}
+
+// errorDetail 错误对象
+type errorDetail struct {
+	Type       string                 `json:"type"`
+	Message    string                 `json:"message"`
+	Stack      string                 `json:"stack"`
+	RetryAfter int                    `json:"retryAfter"`
+	Details    map[string]interface{} `json:"details"`
+}
+
+// parseAPIError 解析非 2xx 响应
+func parseAPIError(statusCode int, header http.Header, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+
+	var parsed errorBody
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		apiErr.Message = strings.TrimSpace(string(body))
+		if len(apiErr.Message) > 200 {
+			apiErr.Message = apiErr.Message[:200] + "..."
+		}
+	} else {
+		apiErr.Message = parsed.Message
+		apiErr.RequestID = parsed.RequestID
+		apiErr.Logs = parsed.Logs
+		apiErr.LogsTruncated = parsed.LogsTruncated
+
+		var detail errorDetail
+		var errorType string
+		switch {
+		case json.Unmarshal(parsed.Error, &detail) == nil:
+			apiErr.Type = detail.Type
+			if detail.Message != "" {
+				apiErr.Message = detail.Message
+			}
+			apiErr.Stack = detail.Stack
+			apiErr.Details = detail.Details
+			apiErr.RetryAfter = seconds(detail.RetryAfter)
+			if apiErr.RetryAfter == 0 {
+				if v, ok := detail.Details["retryAfter"].(float64); ok {
+					apiErr.RetryAfter = seconds(int(v))
+				}
+			}
+		case json.Unmarshal(parsed.Error, &errorType) == nil:
+			apiErr.Type = errorType
+		}
+	}
+
+	// Retry-After 响应头优先（与响应体一致，但中间的代理可能只保留响应头）
+	if value := header.Get("Retry-After"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			apiErr.RetryAfter = seconds(n)
+		}
+	}
+	return apiErr
+}
+
+// executeError 执行响应中 success=false 的错误（批量执行的失败条目；statusCode 为 0 表示条目级错误）
+func executeError(statusCode int, resp *model.ExecuteResponse) *APIError {
+	apiErr := &APIError{
+		StatusCode:    statusCode,
+		RequestID:     resp.RequestID,
+		Logs:          resp.Logs,
+		LogsTruncated: resp.LogsTruncated,
+	}
+	if resp.Error != nil {
+		apiErr.Type = resp.Error.Type
+		apiErr.Message = resp.Error.Message
+		apiErr.Stack = resp.Error.Stack
+		apiErr.RetryAfter = seconds(resp.Error.RetryAfter)
+	}
+	return apiErr
+}
+
+// seconds 秒数转换为 time.Duration（<= 0 时为 0）
+func seconds(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"flow-codeblock-go/model"
+)
+
+// ExecuteResult 执行结果
+type ExecuteResult struct {
+	Result        json.RawMessage // 脚本返回值（JSON 原文，可用 Decode 解码）
+	RequestID     string
+	Timing        *model.ExecuteTiming
+	Logs          []model.ConsoleLogEntry // console 输出（CONSOLE_MODE=capture 时返回）
+	LogsTruncated bool
+}
+
+// Decode 把返回值解码到 v
+func (r *ExecuteResult) Decode(v interface{}) error {
+	if len(r.Result) == 0 {
+		return json.Unmarshal([]byte("null"), v)
+	}
+	return json.Unmarshal(r.Result, v)
+}
+
+// ExecuteOption 单次执行的选项
+type ExecuteOption func(*model.ExecuteRequest)
+
+// WithDebug 在 Timing.Phases 中返回分阶段耗时
+func WithDebug() ExecuteOption {
+	return func(req *model.ExecuteRequest) {
+		req.Debug = true
+	}
+}
+
+// Execute 执行代码（POST /flow/codeblock，需要 WithToken）
+//
+// 执行失败时返回 *APIError（Type 为 ValidationError、RuntimeError、TimeoutError、QuotaExceeded 等）
+func (c *Client) Execute(ctx context.Context, code string, input map[string]interface{}, opts ...ExecuteOption) (*ExecuteResult, error) {
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	req := &model.ExecuteRequest{
+		Input:      input,
+		CodeBase64: base64.StdEncoding.EncodeToString([]byte(code)),
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	body, err := c.do(ctx, &call{method: http.MethodPost, path: "/flow/codeblock", body: req, auth: authToken})
+	if err != nil {
+		return nil, err
+	}
+	var resp executeResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("client: 解析执行响应失败: %w", err)
+	}
+	return resp.result(http.StatusOK)
+}
+
+// BatchItem 批量执行条目
+type BatchItem struct {
+	Code  string
+	Input map[string]interface{}
+}
+
+// BatchItemResult 批量执行中单个条目的结果（Err 不为 nil 时为 *APIError）
+type BatchItemResult struct {
+	*ExecuteResult
+	Err error
+}
+
+// BatchResult 批量执行结果（Items 与请求中的条目一一对应）
+type BatchResult struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	RequestID string
+	Timing    *model.ExecuteTiming
+	Items     []BatchItemResult
+}
+
+// ExecuteBatch 批量执行，每个条目自带代码和输入（POST /flow/codeblock/batch，需要 WithToken）
+//
+// 单个条目失败不影响其他条目，错误在 Items[i].Err 中；整个请求被拒绝时（认证失败、限流等）返回 error
+func (c *Client) ExecuteBatch(ctx context.Context, items []BatchItem) (*BatchResult, error) {
+	req := &model.BatchExecuteRequest{Items: make([]model.BatchExecuteItem, len(items))}
+	for i, item := range items {
+		input := item.Input
+		if input == nil {
+			input = map[string]interface{}{}
+		}
+		req.Items[i] = model.BatchExecuteItem{
+			CodeBase64: base64.StdEncoding.EncodeToString([]byte(item.Code)),
+			Input:      input,
+		}
+	}
+	return c.executeBatch(ctx, req)
+}
+
+// ExecuteBatchInputs 同一份代码对多个输入批量执行（POST /flow/codeblock/batch，需要 WithToken）
+func (c *Client) ExecuteBatchInputs(ctx context.Context, code string, inputs []map[string]interface{}) (*BatchResult, error) {
+	return c.executeBatch(ctx, &model.BatchExecuteRequest{
+		CodeBase64: base64.StdEncoding.EncodeToString([]byte(code)),
+		Inputs:     inputs,
+	})
+}
+
+// executeBatch 发送批量执行请求
+func (c *Client) executeBatch(ctx context.Context, req *model.BatchExecuteRequest) (*BatchResult, error) {
+	body, err := c.do(ctx, &call{method: http.MethodPost, path: "/flow/codeblock/batch", body: req, auth: authToken})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Total     int                  `json:"total"`
+		Succeeded int                  `json:"succeeded"`
+		Failed    int                  `json:"failed"`
+		Results   []executeResponse    `json:"results"`
+		Timing    *model.ExecuteTiming `json:"timing"`
+		RequestID string               `json:"request_id"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("client: 解析批量执行响应失败: %w", err)
+	}
+
+	result := &BatchResult{
+		Total:     resp.Total,
+		Succeeded: resp.Succeeded,
+		Failed:    resp.Failed,
+		RequestID: resp.RequestID,
+		Timing:    resp.Timing,
+		Items:     make([]BatchItemResult, len(resp.Results)),
+	}
+	for i := range resp.Results {
+		itemResult, err := resp.Results[i].result(0)
+		result.Items[i] = BatchItemResult{ExecuteResult: itemResult, Err: err}
+	}
+	return result, nil
+}
+
+// executeResponse 执行响应（model.ExecuteResponse 的 result 保留 JSON 原文）
+type executeResponse struct {
+	model.ExecuteResponse
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// result 转换为 ExecuteResult（success=false 时返回 *APIError）
+func (r *executeResponse) result(statusCode int) (*ExecuteResult, error) {
+	if !r.Success {
+		return nil, executeError(statusCode, &r.ExecuteResponse)
+	}
+	return &ExecuteResult{
+		Result:        r.Result,
+		RequestID:     r.RequestID,
+		Timing:        r.Timing,
+		Logs:          r.Logs,
+		LogsTruncated: r.LogsTruncated,
+	}, nil
+}
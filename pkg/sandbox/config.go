@@ -0,0 +1,232 @@
+package sandbox
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config 执行器配置
+//
+// 嵌入方通常从 DefaultConfig() 开始，用 Option 覆盖需要的字段；
+// 服务端用环境变量加载的 config.Config 组装（见 service.NewJSExecutor）
+type Config struct {
+	Executor ExecutorConfig
+	Fetch    FetchConfig
+	XLSX     XLSXConfig
+
+	// Modules 额外注册的模块增强器（在内置模块之后注册，与内置模块重名时忽略）
+	Modules []ModuleEnhancer
+
+	// Logger 执行器日志（为空时不输出日志；服务端传入全局日志）
+	Logger *zap.Logger
+}
+
+// ExecutorConfig JavaScript执行器配置（服务端由 config.LoadConfig 从环境变量加载）
+type ExecutorConfig struct {
+	PoolSize         int
+	MinPoolSize      int
+	MaxPoolSize      int
+	IdleTimeout      time.Duration
+	MaxConcurrent    int
+	MaxCodeLength    int
+	MaxInputSize     int
+	MaxResultSize    int
+	ExecutionTimeout time.Duration
+	CodeCacheSize    int
+	AllowConsole     bool // 是否允许用户代码使用 console（开发环境：true，生产环境：false）
+
+	// 🆕 Console 输出模式
+	ConsoleMode     string // disabled（禁止）/ stdout（输出到服务端标准输出）/ capture（捕获并随响应返回）
+	ConsoleMaxLines int    // capture 模式：单次执行最多捕获的日志条数（默认：200）
+	ConsoleMaxBytes int    // capture 模式：单次执行最多捕获的日志字节数（默认：64KB）
+
+	// 🆕 EventLoop 池配置（异步代码使用预初始化的 EventLoop）
+	EventLoopPoolSize    int // EventLoop 池初始大小（默认：50）
+	MinEventLoopPoolSize int // EventLoop 池最小大小（默认：20）
+	MaxEventLoopPoolSize int // EventLoop 池最大大小（默认：100）
+
+	// 🆕 公平调度配置（按 Token / 工作空间加权分配并发槽位）
+	SchedulerFairnessKey        string // 公平调度的分组方式：token（按 Token）/ workspace（按工作空间，默认：token）
+	SchedulerDefaultMaxInFlight int    // 单个分组默认最大并发执行数（默认：0，表示不限制，仅受 MAX_CONCURRENT_EXECUTIONS 约束）
+	SchedulerMaxQueuePerTenant  int    // 单个分组最大排队请求数（默认：200，超过直接返回 429）
+
+	// 🔥 超时配置（新增可配置项）
+	ConcurrencyWaitTimeout    time.Duration // 并发槽位等待超时（默认 10 秒）
+	RuntimePoolAcquireTimeout time.Duration // Runtime 池获取超时（默认 5 秒）
+	SlowExecutionThreshold    time.Duration // 🔥 慢执行检测阈值（默认 1 秒）
+
+	// 🔥 熔断器配置
+	CircuitBreakerEnabled      bool          // 是否启用熔断器
+	CircuitBreakerMinRequests  uint32        // 最小请求数（触发熔断的最小样本）
+	CircuitBreakerFailureRatio float64       // 失败率阈值（0.0-1.0）
+	CircuitBreakerTimeout      time.Duration // Open 状态持续时间
+	CircuitBreakerMaxRequests  uint32        // Half-Open 状态最大探测请求数
+
+	// 🔥 JavaScript 内存限制配置
+	EnableJSMemoryLimit bool  // 是否启用 JavaScript 侧内存限制（默认：true）
+	JSMemoryLimitMB     int64 // JavaScript 单次分配最大大小（MB，默认使用 MaxBlobFileSize）
+
+	// 🔥 健康检查和池管理配置
+	MinErrorCountForCheck         int     // 最小错误次数阈值（默认：10，低于此值不检查错误率）
+	MaxErrorRateThreshold         float64 // 最大错误率阈值（默认：0.1，即 10%，超过视为异常）
+	MinExecutionCountForStats     int     // 统计长期运行的最小执行次数（默认：1000）
+	LongRunningThresholdMinutes   int     // 长期运行时间阈值（分钟，默认：60）
+	PoolExpansionThresholdPercent float64 // 池扩展阈值百分比（默认：0.1，即 10%，可用槽位低于此值时扩展）
+	HealthCheckIntervalSeconds    int     // 健康检查间隔（秒，默认：30）
+
+	// 🔥 Runtime 重用限制配置（方案D：防止内存累积）
+	MaxRuntimeReuseCount int64 // Runtime 最大重用次数（默认：2，达到后销毁并创建新的）
+
+	// 🔥 GC 触发频率配置（高并发优化）
+	GCTriggerInterval int64 // 每销毁N个Runtime触发一次GC（默认：15，值越大GC越少，CPU开销越低）
+}
+
+// 公平调度分组方式
+const (
+	SchedulerFairnessByToken     = "token"     // 每个 Token 独立分配并发份额
+	SchedulerFairnessByWorkspace = "workspace" // 同一工作空间的 Token 共享并发份额
+)
+
+// Console 输出模式
+const (
+	ConsoleModeDisabled = "disabled" // 禁止使用 console（调用即抛出 ConsoleDisabledError）
+	ConsoleModeStdout   = "stdout"   // 输出到服务端标准输出
+	ConsoleModeCapture  = "capture"  // 捕获到单次执行的缓冲区，随响应以 logs 数组返回
+)
+
+// FetchConfig Fetch API配置
+type FetchConfig struct {
+	Timeout             time.Duration // HTTP 请求超时（连接建立+发送+等待响应头）
+	ResponseReadTimeout time.Duration // 🔥 新增：响应读取总时长超时（防止慢速读取攻击）
+	MaxBlobFileSize     int64
+	FormDataBufferSize  int
+	MaxFileSize         int64
+
+	// 🔥 下载限制（新）
+	MaxResponseSize  int64 // response.arrayBuffer/blob/text/json() 缓冲读取限制（默认 1MB）
+	MaxStreamingSize int64 // response.body.getReader() 流式读取累计限制（默认 100MB）
+
+	// 🔥 上传限制（新）
+	MaxBufferedFormDataSize  int64 // FormData 缓冲上传限制：Web FormData + Blob、Node.js form-data + Buffer（默认 1MB）
+	MaxStreamingFormDataSize int64 // FormData 流式上传限制：Node.js form-data + Stream（默认 100MB）
+
+	// 🛡️ SSRF 防护配置（新增）
+	EnableSSRFProtection bool // 是否启用 SSRF 防护（默认：根据部署环境自动判断）
+	AllowPrivateIP       bool // 是否允许访问私有 IP（默认：本地部署允许，公有云禁止）
+
+	// 🔧 废弃但保留兼容（优先使用新字段）
+	MaxFormDataSize     int64 // 废弃：统一 FormData 限制，改用 MaxBufferedFormDataSize 和 MaxStreamingFormDataSize
+	StreamingThreshold  int64 // 废弃：自动切换阈值，现由用户代码控制
+	EnableChunkedUpload bool  // 保留：是否启用分块传输编码
+
+	// 🔥 HTTP Transport 配置（新增）
+	HTTPMaxIdleConns          int           // 最大空闲连接数（默认：50）
+	HTTPMaxIdleConnsPerHost   int           // 每个 host 的最大空闲连接数（默认：10）
+	HTTPMaxConnsPerHost       int           // 每个 host 的最大连接数（默认：100）
+	HTTPIdleConnTimeout       time.Duration // 空闲连接超时（默认：90秒）
+	HTTPDialTimeout           time.Duration // 连接建立超时（默认：10秒）
+	HTTPKeepAlive             time.Duration // Keep-Alive 间隔（默认：30秒）
+	HTTPTLSHandshakeTimeout   time.Duration // TLS 握手超时（默认：10秒）
+	HTTPExpectContinueTimeout time.Duration // 期望继续超时（默认：1秒）
+	HTTPForceHTTP2            bool          // 启用 HTTP/2（默认：true）
+
+	// 🔥 响应体空闲超时（防止资源泄漏）
+	ResponseBodyIdleTimeout time.Duration // 响应体空闲超时（默认：30秒，即1分钟）
+}
+
+// XLSXConfig XLSX 模块配置
+type XLSXConfig struct {
+	MaxSnapshotSize int64 // Copy-on-Read 模式的最大文件大小（字节），默认 5MB
+	MaxRows         int   // 🔥 最大行数限制（默认 100000）
+	MaxCols         int   // 🔥 最大列数限制（默认 100）
+}
+
+// DefaultConfig 默认配置（与未设置任何环境变量时服务端的默认值一致）
+//
+// 差异：MaxConcurrent 固定为 100（服务端按系统内存计算），console 默认禁用，SSRF 防护默认开启
+func DefaultConfig() Config {
+	return Config{
+		Executor: ExecutorConfig{
+			PoolSize:         100,
+			MinPoolSize:      50,
+			MaxPoolSize:      200,
+			IdleTimeout:      5 * time.Minute,
+			MaxConcurrent:    100,
+			MaxCodeLength:    65535,
+			MaxInputSize:     2 * 1024 * 1024,
+			MaxResultSize:    5 * 1024 * 1024,
+			ExecutionTimeout: 300 * time.Second,
+			CodeCacheSize:    100,
+			ConsoleMode:      ConsoleModeDisabled,
+			ConsoleMaxLines:  200,
+			ConsoleMaxBytes:  64 * 1024,
+
+			EventLoopPoolSize:    50,
+			MinEventLoopPoolSize: 20,
+			MaxEventLoopPoolSize: 100,
+
+			SchedulerFairnessKey:       SchedulerFairnessByToken,
+			SchedulerMaxQueuePerTenant: 200,
+
+			ConcurrencyWaitTimeout:    10 * time.Second,
+			RuntimePoolAcquireTimeout: 5 * time.Second,
+			SlowExecutionThreshold:    time.Second,
+
+			CircuitBreakerEnabled:      true,
+			CircuitBreakerMinRequests:  100,
+			CircuitBreakerFailureRatio: 0.9,
+			CircuitBreakerTimeout:      10 * time.Second,
+			CircuitBreakerMaxRequests:  100,
+
+			EnableJSMemoryLimit: true,
+
+			MinErrorCountForCheck:         10,
+			MaxErrorRateThreshold:         0.1,
+			MinExecutionCountForStats:     1000,
+			LongRunningThresholdMinutes:   60,
+			PoolExpansionThresholdPercent: 0.1,
+			HealthCheckIntervalSeconds:    30,
+
+			MaxRuntimeReuseCount: 1,
+			GCTriggerInterval:    10,
+		},
+		Fetch: FetchConfig{
+			Timeout:             30 * time.Second,
+			ResponseReadTimeout: 5 * time.Minute,
+			MaxBlobFileSize:     100 * 1024 * 1024,
+			FormDataBufferSize:  2 * 1024 * 1024,
+			MaxFileSize:         50 * 1024 * 1024,
+
+			MaxResponseSize:  1 * 1024 * 1024,
+			MaxStreamingSize: 100 * 1024 * 1024,
+
+			MaxBufferedFormDataSize:  1 * 1024 * 1024,
+			MaxStreamingFormDataSize: 100 * 1024 * 1024,
+
+			MaxFormDataSize:     100 * 1024 * 1024,
+			StreamingThreshold:  1 * 1024 * 1024,
+			EnableChunkedUpload: true,
+
+			HTTPMaxIdleConns:          50,
+			HTTPMaxIdleConnsPerHost:   10,
+			HTTPMaxConnsPerHost:       100,
+			HTTPIdleConnTimeout:       90 * time.Second,
+			HTTPDialTimeout:           10 * time.Second,
+			HTTPKeepAlive:             30 * time.Second,
+			HTTPTLSHandshakeTimeout:   10 * time.Second,
+			HTTPExpectContinueTimeout: time.Second,
+			HTTPForceHTTP2:            true,
+
+			EnableSSRFProtection: true,
+			AllowPrivateIP:       false,
+
+			ResponseBodyIdleTimeout: 30 * time.Second,
+		},
+		XLSX: XLSXConfig{
+			MaxSnapshotSize: 5 * 1024 * 1024,
+			MaxRows:         100000,
+			MaxCols:         100,
+		},
+	}
+}
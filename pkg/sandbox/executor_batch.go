@@ -0,0 +1,98 @@
+package sandbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/model/execmodel"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+)
+
+// BatchTask 批量执行中的单个任务
+type BatchTask struct {
+	RequestID string                 // 条目级请求ID（作为 executionId）
+	Code      string                 // 已解码的用户代码
+	Input     map[string]interface{} // 条目输入
+}
+
+// BatchTaskResult 批量执行中单个任务的结果
+type BatchTaskResult struct {
+	Result   *execmodel.ExecutionResult
+	Err      error
+	Duration time.Duration // 条目执行耗时（含并发等待）
+}
+
+// PrepareCode 预校验并预编译代码（批量执行前调用）
+// 🆕 批量场景：同一份代码只校验、编译一次，结果写入验证缓存和编译缓存，
+// 后续每个条目执行时直接命中缓存；代码无效时可在扣减配额前快速失败
+// 🆕 ctx 中的沙箱策略参与校验（与 Execute 使用相同的验证缓存键）
+func (e *JSExecutor) PrepareCode(ctx context.Context, code string) error {
+	if err := e.validateCodeWithCache(code, e.limitsFromContext(ctx)); err != nil {
+		return err
+	}
+
+	// 按智能路由选择包装方式，保证与实际执行时的缓存键一致
+	wrappedCode, lineOffset := wrapCodeForRuntimePool(code), 4
+	if !e.analyzer.ShouldUseRuntimePool(code) {
+		wrappedCode, lineOffset = wrapCodeForEventLoop(code), 9
+	}
+
+	if _, err := e.getCompiledCode(wrappedCode); err != nil {
+		return adjustErrorLineNumber(e.categorizeError(err), lineOffset)
+	}
+	return nil
+}
+
+// ExecuteBatch 批量执行任务（结果顺序与 tasks 一致）
+// 🔥 并发模型：
+//   - 单个批量请求内最多 concurrency 个条目并行，避免一个批次占满全局并发槽位
+//   - 每个条目仍走完整的 Execute 流程（熔断器、Semaphore、智能路由、统计）
+//   - Context 取消后，尚未开始的条目直接返回 CancelledError
+func (e *JSExecutor) ExecuteBatch(ctx context.Context, tasks []BatchTask, concurrency int) []BatchTaskResult {
+	results := make([]BatchTaskResult, len(tasks))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := range tasks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = &execmodel.ExecutionError{
+				Type:    "CancelledError",
+				Message: "请求已取消",
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			task := tasks[i]
+			start := time.Now()
+			taskCtx := context.WithValue(ctx, utils.RequestIDKey, task.RequestID)
+			result, err := e.Execute(taskCtx, task.Code, task.Input)
+			results[i] = BatchTaskResult{
+				Result:   result,
+				Err:      err,
+				Duration: time.Since(start),
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	e.logger.Debug("批量执行完成",
+		zap.Int("task_count", len(tasks)),
+		zap.Int("concurrency", concurrency))
+
+	return results
+}
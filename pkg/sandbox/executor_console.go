@@ -0,0 +1,271 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"flow-codeblock-go/model/execmodel"
+	"flow-codeblock-go/utils"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/console"
+	"github.com/dop251/goja_nodejs/util"
+)
+
+// consoleCapture 单次执行的 console 输出缓冲区（CONSOLE_MODE=capture）
+//
+// 🔥 设计要点：
+//   - 每次执行创建一个新的缓冲区，执行结束后 close，之后的写入（超时后仍在运行的回调）被丢弃
+//   - 条数和字节数双重上限，超限后设置 truncated 标记并丢弃后续输出
+//   - 加锁保护：超时路径下执行 goroutine 可能与读取方并发
+type consoleCapture struct {
+	mu        sync.Mutex
+	entries   []execmodel.ConsoleLogEntry
+	bytes     int
+	maxLines  int
+	maxBytes  int
+	truncated bool
+	closed    bool
+	start     time.Time
+	listener  ConsoleListener // 🆕 实时接收每条输出（可为 nil）
+}
+
+// ConsoleListener 实时接收本次执行捕获的 console 输出（capture 模式；stdout 模式下设置了监听器时按 capture 处理）
+// 🔥 在执行 goroutine 中同步调用，实现方不能阻塞（如需转发应先放入队列）
+type ConsoleListener func(entry execmodel.ConsoleLogEntry)
+
+type consoleListenerKey struct{}
+
+// WithConsoleListener 把 console 输出监听器放入 context（流式接口边执行边推送日志）
+// 截断后的输出不会回调；执行结束后结果 / 错误上仍附带完整的 Logs
+func WithConsoleListener(ctx context.Context, listener ConsoleListener) context.Context {
+	return context.WithValue(ctx, consoleListenerKey{}, listener)
+}
+
+// consoleListenerFromContext 获取 context 中的 console 输出监听器（未设置时返回 nil）
+func consoleListenerFromContext(ctx context.Context) ConsoleListener {
+	listener, _ := ctx.Value(consoleListenerKey{}).(ConsoleListener)
+	return listener
+}
+
+// newConsoleCapture 创建 console 捕获缓冲区
+func newConsoleCapture(maxLines, maxBytes int) *consoleCapture {
+	return &consoleCapture{
+		maxLines: maxLines,
+		maxBytes: maxBytes,
+		start:    time.Now(),
+	}
+}
+
+// record 记录一条输出（超出上限时截断）
+func (c *consoleCapture) record(level, message string) {
+	c.mu.Lock()
+	entry, ok := c.appendLocked(level, message)
+	c.mu.Unlock()
+
+	// 监听器在锁外回调，避免监听器中的慢操作阻塞读取方
+	if ok && c.listener != nil {
+		c.listener(entry)
+	}
+}
+
+// appendLocked 追加一条输出，返回追加的条目（调用方持有锁）
+func (c *consoleCapture) appendLocked(level, message string) (execmodel.ConsoleLogEntry, bool) {
+	if c.closed || c.truncated {
+		return execmodel.ConsoleLogEntry{}, false
+	}
+	if len(c.entries) >= c.maxLines {
+		c.truncated = true
+		return execmodel.ConsoleLogEntry{}, false
+	}
+
+	if remaining := c.maxBytes - c.bytes; len(message) > remaining {
+		// 在 UTF-8 字符边界处截断，保留上限内的部分
+		cut := remaining
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut]
+		c.truncated = true
+		if message == "" {
+			return execmodel.ConsoleLogEntry{}, false
+		}
+	}
+
+	now := time.Now()
+	entry := execmodel.ConsoleLogEntry{
+		Level:     level,
+		Message:   message,
+		Timestamp: now.In(utils.ShanghaiLocation).Format("2006-01-02 15:04:05.000"),
+		OffsetMs:  now.Sub(c.start).Milliseconds(),
+	}
+	c.entries = append(c.entries, entry)
+	c.bytes += len(message)
+	return entry, true
+}
+
+// close 结束捕获并返回已捕获的内容
+func (c *consoleCapture) close() ([]execmodel.ConsoleLogEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	return c.entries, c.truncated
+}
+
+// setupConsole 按 console 模式为 Runtime 设置 console
+//   - disabled: 占位对象，调用即抛出 ConsoleDisabledError
+//   - stdout:   goja_nodejs 原生 console（输出到服务端标准输出）
+//   - capture:  写入 capture 缓冲区；capture 为 nil 时丢弃输出（池中 Runtime 的初始状态）
+func (e *JSExecutor) setupConsole(runtime *goja.Runtime, mode string, capture *consoleCapture) {
+	switch mode {
+	case ConsoleModeCapture:
+		runtime.Set("console", newCaptureConsole(runtime, capture))
+	case ConsoleModeStdout:
+		console.Enable(runtime)
+	default:
+		// 🔥 提供友好的错误提示（当用户尝试使用 console 时）
+		e.setupConsoleStub(runtime)
+	}
+}
+
+// newExecutionCapture 为本次执行创建捕获缓冲区（非 capture 模式返回 nil）
+// 🆕 context 中有 console 输出监听器时，每条输出同时回调监听器
+func (e *JSExecutor) newExecutionCapture(ctx context.Context, limits *executionLimits) *consoleCapture {
+	if limits.consoleMode != ConsoleModeCapture {
+		return nil
+	}
+	capture := newConsoleCapture(e.consoleMaxLines, e.consoleMaxBytes)
+	capture.listener = consoleListenerFromContext(ctx)
+	return capture
+}
+
+// beginConsoleCapture 为本次执行绑定 console，capture 模式下返回本次执行的缓冲区（其他模式返回 nil）
+// 🔥 池中的 Runtime 会被复用，每次执行都重新绑定 console，避免输出串到其他请求
+// 🆕 策略指定的模式与全局模式不同时同样重新绑定（Runtime 归还前由调用方恢复为全局模式）
+func (e *JSExecutor) beginConsoleCapture(ctx context.Context, runtime *goja.Runtime, limits *executionLimits) *consoleCapture {
+	capture := e.newExecutionCapture(ctx, limits)
+	if capture != nil || limits.consoleMode != e.consoleMode {
+		e.setupConsole(runtime, limits.consoleMode, capture)
+	}
+	return capture
+}
+
+// attachConsoleLogs 结束捕获，并把捕获的输出附加到执行结果或错误上
+func attachConsoleLogs(capture *consoleCapture, result *execmodel.ExecutionResult, err error) (*execmodel.ExecutionResult, error) {
+	if capture == nil {
+		return result, err
+	}
+
+	logs, truncated := capture.close()
+	if len(logs) == 0 && !truncated {
+		return result, err
+	}
+
+	if result != nil {
+		result.Logs = logs
+		result.LogsTruncated = truncated
+	}
+	if execErr, ok := err.(*execmodel.ExecutionError); ok {
+		// 🔥 复制一份再附加：错误对象可能来自共享缓存（如编译缓存的 singleflight 结果）
+		withLogs := *execErr
+		withLogs.Logs = logs
+		withLogs.LogsTruncated = truncated
+		err = &withLogs
+	}
+	return result, err
+}
+
+// newCaptureConsole 创建写入 capture 缓冲区的 console 对象
+func newCaptureConsole(runtime *goja.Runtime, capture *consoleCapture) *goja.Object {
+	formatter := util.New(runtime)
+	consoleObj := runtime.NewObject()
+
+	logFunc := func(level string) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			if capture != nil {
+				capture.record(level, formatConsoleArgs(runtime, formatter, call.Arguments))
+			}
+			return goja.Undefined()
+		}
+	}
+
+	consoleObj.Set("log", logFunc("log"))
+	consoleObj.Set("info", logFunc("info"))
+	consoleObj.Set("debug", logFunc("debug"))
+	consoleObj.Set("trace", logFunc("debug"))
+	consoleObj.Set("dir", logFunc("log"))
+	consoleObj.Set("warn", logFunc("warn"))
+	consoleObj.Set("error", logFunc("error"))
+	consoleObj.Set("table", func(call goja.FunctionCall) goja.Value {
+		if capture != nil {
+			capture.record("table", formatConsoleValue(runtime, call.Argument(0)))
+		}
+		return goja.Undefined()
+	})
+
+	return consoleObj
+}
+
+// formatConsoleArgs 格式化 console 参数
+// 第一个参数为含占位符（%s、%d、%j 等）的字符串时按 util.format 处理，
+// 否则逐个格式化后以空格连接（对象序列化为 JSON，便于排查）
+func formatConsoleArgs(runtime *goja.Runtime, formatter *util.Util, args []goja.Value) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	if first, ok := args[0].Export().(string); ok && strings.Contains(first, "%") {
+		var b bytes.Buffer
+		formatter.Format(&b, first, args[1:]...)
+		return b.String()
+	}
+
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = formatConsoleValue(runtime, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatConsoleValue 格式化单个值
+func formatConsoleValue(runtime *goja.Runtime, value goja.Value) string {
+	if value == nil || goja.IsUndefined(value) {
+		return "undefined"
+	}
+	if goja.IsNull(value) {
+		return "null"
+	}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return value.String()
+	}
+
+	if _, isFunc := goja.AssertFunction(obj); isFunc {
+		return "[Function]"
+	}
+
+	// Error 对象：优先输出 stack（JSON.stringify 会得到 {}）
+	if obj.ClassName() == "Error" {
+		if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			return stack.String()
+		}
+		return obj.String()
+	}
+
+	if jsonObj, ok := runtime.Get("JSON").(*goja.Object); ok {
+		if stringify, ok := goja.AssertFunction(jsonObj.Get("stringify")); ok {
+			if str, err := stringify(jsonObj, obj); err == nil && !goja.IsUndefined(str) {
+				return str.String()
+			}
+		}
+	}
+
+	// 循环引用等无法序列化的对象
+	return obj.String()
+}
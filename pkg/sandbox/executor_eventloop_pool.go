@@ -0,0 +1,600 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"flow-codeblock-go/model/execmodel"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/dop251/goja_nodejs/eventloop"
+	"github.com/dop251/goja_nodejs/process"
+	"github.com/dop251/goja_nodejs/url"
+	"go.uber.org/zap"
+)
+
+// pooledEventLoop 池化的 EventLoop（每个 EventLoop 持有一个预初始化的 Runtime）
+type pooledEventLoop struct {
+	loop *eventloop.EventLoop
+	vm   *goja.Runtime
+
+	// 初始化完成后的全局变量快照（归还前据此重置全局状态）
+	globals map[string]goja.Value
+
+	// 执行中发生了未恢复的 panic，EventLoop 停在运行状态，只能丢弃
+	broken bool
+}
+
+// eventLoopPool EventLoop 池（异步代码路径）
+//
+// 🆕 与 Runtime 池相同的管理语义：
+//   - 预初始化：模块加载、安全加固在入池前完成，请求路径只设置本次执行的状态
+//   - 健康跟踪：复用 runtimeHealthInfo（创建时间、最近使用、执行次数、错误次数）
+//   - 限次重用：达到 MAX_RUNTIME_REUSE_COUNT 后销毁并实时补充
+//   - 动态伸缩：健康检查时按 Runtime 池的阈值扩展/收缩（见 healthAnalysis）
+//   - 获取超时：创建临时 EventLoop，用后尝试入池
+//
+// 🔒 全局状态重置（见 pooledEventLoop.reset）：
+//   - 删除执行期间新增的全局变量，恢复被覆盖的全局变量
+//   - 超时/取消的 EventLoop 可能仍有挂起的定时器，不复用（等执行结束后销毁）
+//   - 对模块对象、内置原型的修改无法可靠还原，依赖重用上限控制影响范围
+type eventLoopPool struct {
+	executor *JSExecutor
+
+	pool        chan *pooledEventLoop
+	size        int
+	minSize     int
+	maxSize     int
+	currentSize int32 // 原子操作的当前池大小
+
+	health      map[*pooledEventLoop]*runtimeHealthInfo
+	healthMutex sync.RWMutex
+
+	// 📊 统计
+	pooledExecs    atomic.Int64
+	temporaryExecs atomic.Int64
+	destroyCount   atomic.Int64
+	abandonedCount atomic.Int64
+	resetFailures  atomic.Int64
+}
+
+// newEventLoopPool 创建 EventLoop 池（需调用 init 完成预初始化）
+func newEventLoopPool(e *JSExecutor, cfg *Config) *eventLoopPool {
+	return &eventLoopPool{
+		executor: e,
+		pool:     make(chan *pooledEventLoop, cfg.Executor.MaxEventLoopPoolSize),
+		size:     cfg.Executor.EventLoopPoolSize,
+		minSize:  cfg.Executor.MinEventLoopPoolSize,
+		maxSize:  cfg.Executor.MaxEventLoopPoolSize,
+		health:   make(map[*pooledEventLoop]*runtimeHealthInfo),
+	}
+}
+
+// init 并行预初始化 EventLoop 池（与 initRuntimePool 相同的 Fail Fast 策略）
+func (p *eventLoopPool) init() {
+	startTime := time.Now()
+	p.executor.logger.Info("并行初始化 EventLoop 池",
+		zap.Int("pool_size", p.size),
+		zap.Int("cpu_cores", runtime.NumCPU()))
+
+	var wg sync.WaitGroup
+	loopsChan := make(chan *pooledEventLoop, p.size)
+	errorsChan := make(chan error, p.size)
+
+	for i := 0; i < p.size; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			pl, err := p.executor.newPooledEventLoop()
+			if err != nil {
+				errorsChan <- fmt.Errorf("eventloop #%d 初始化失败: %w", index, err)
+				return
+			}
+			loopsChan <- pl
+		}(i)
+	}
+
+	wg.Wait()
+	close(loopsChan)
+	close(errorsChan)
+
+	var errors []error
+	for err := range errorsChan {
+		errors = append(errors, err)
+	}
+	if len(errors) > 0 {
+		p.executor.logger.Error("EventLoop 池初始化失败",
+			zap.Int("failed_count", len(errors)),
+			zap.Int("total", p.size))
+		for _, err := range errors {
+			p.executor.logger.Error("初始化错误", zap.Error(err))
+		}
+		p.executor.logger.Fatal("EventLoop 池初始化失败，服务启动中止")
+	}
+
+	successCount := 0
+	for pl := range loopsChan {
+		p.track(pl)
+		p.pool <- pl
+		successCount++
+	}
+	atomic.StoreInt32(&p.currentSize, int32(successCount))
+
+	p.executor.logger.Info("EventLoop 池初始化完成（并行）",
+		zap.Int("ready_loops", successCount),
+		zap.Duration("elapsed", time.Since(startTime)))
+}
+
+// newPooledEventLoop 创建并预初始化一个 EventLoop
+func (e *JSExecutor) newPooledEventLoop() (pl *pooledEventLoop, err error) {
+	loop := eventloop.NewEventLoop(eventloop.WithRegistry(e.registry))
+	pl = &pooledEventLoop{loop: loop}
+
+	// 在 EventLoop 内完成初始化（没有挂起任务，Run 执行完立即返回）
+	loop.Run(func(vm *goja.Runtime) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("eventloop 初始化 panic: %v", r)
+			}
+		}()
+
+		pl.vm = vm
+		if err = e.setupEventLoopRuntime(vm); err != nil {
+			return
+		}
+		pl.globals = snapshotGlobals(vm)
+	})
+	if err != nil {
+		loop.Terminate()
+		return nil, err
+	}
+	return pl, nil
+}
+
+// setupEventLoopRuntime 设置 EventLoop 的 Runtime 环境（入池前执行一次）
+// 加载顺序与原来每次执行时的设置一致：基础模块 → 模块注册器 → 全局对象 → 安全加固
+func (e *JSExecutor) setupEventLoopRuntime(vm *goja.Runtime) error {
+	// 步骤1: 先设置 Node.js 基础模块（需要正常的原型）
+	// 🔥 Console 控制：与 setupNodeJSModules 保持一致（capture 模式每次执行时重新绑定）
+	e.setupConsole(vm, e.consoleMode, nil)
+	e.registry.Enable(vm)
+	buffer.Enable(vm)
+	url.Enable(vm)
+	process.Enable(vm)
+
+	// 🔥 使用模块注册器统一设置所有模块
+	if err := e.moduleRegistry.SetupAll(vm); err != nil {
+		return fmt.Errorf("failed to setup modules: %w", err)
+	}
+
+	e.registerBase64Functions(vm)
+	e.registerTextEncoders(vm) // ✅ 注册 TextEncoder/TextDecoder
+	e.setupGlobalObjectsForEventLoop(vm)
+
+	// 🔒 步骤2: 禁用危险功能和 constructor
+	vm.Set("eval", goja.Undefined())
+	// vm.Set("Function", goja.Undefined())  // 无法禁用，库需要
+	//vm.Set("globalThis", goja.Undefined()) // 不禁用了，仅关键词识别
+	vm.Set("window", goja.Undefined())
+	vm.Set("self", goja.Undefined())
+
+	// 🔥 禁用 Reflect 和 Proxy（防止绕过 constructor 防护）
+	vm.Set("Reflect", goja.Undefined())
+	vm.Set("Proxy", goja.Undefined())
+
+	// 🔒 禁用 constructor 访问（简化版，支持 EventLoop）
+	e.disableConstructorAccess(vm)
+
+	// 每次执行设置的全局变量，先以 undefined 纳入快照，重置时自动清空
+	vm.Set("input", goja.Undefined())
+	vm.Set("__executionId", goja.Undefined())
+	vm.Set("__startTime", goja.Undefined())
+	vm.Set("__finalResult", goja.Undefined())
+	vm.Set("__finalError", goja.Undefined())
+
+	return nil
+}
+
+// snapshotGlobals 记录全局对象当前的全部自有属性
+func snapshotGlobals(vm *goja.Runtime) map[string]goja.Value {
+	global := vm.GlobalObject()
+	names := global.GetOwnPropertyNames()
+	globals := make(map[string]goja.Value, len(names))
+	for _, name := range names {
+		globals[name] = global.Get(name)
+	}
+	return globals
+}
+
+// reset 把全局状态恢复到初始化完成时的快照（归还前调用）
+//   - 删除执行期间新增的全局变量（input 等本次执行的数据随之释放）
+//   - 恢复被覆盖或删除的全局变量（如 console、setTimeout）
+//   - 清除中断标志
+func (pl *pooledEventLoop) reset() error {
+	global := pl.vm.GlobalObject()
+	for _, name := range global.GetOwnPropertyNames() {
+		if _, ok := pl.globals[name]; !ok {
+			if err := global.Delete(name); err != nil {
+				return fmt.Errorf("删除全局变量 %s 失败: %w", name, err)
+			}
+		}
+	}
+	for name, value := range pl.globals {
+		if current := global.Get(name); current == nil || !current.SameAs(value) {
+			if err := global.Set(name, value); err != nil {
+				return fmt.Errorf("恢复全局变量 %s 失败: %w", name, err)
+			}
+		}
+	}
+	pl.vm.ClearInterrupt()
+	return nil
+}
+
+// track 注册健康信息
+func (p *eventLoopPool) track(pl *pooledEventLoop) {
+	now := time.Now().UnixNano()
+	health := &runtimeHealthInfo{}
+	health.createdAtNano.Store(now)
+	health.lastUsedAtNano.Store(now)
+
+	p.healthMutex.Lock()
+	p.health[pl] = health
+	p.healthMutex.Unlock()
+}
+
+// untrack 移除健康信息
+func (p *eventLoopPool) untrack(pl *pooledEventLoop) {
+	p.healthMutex.Lock()
+	delete(p.health, pl)
+	p.healthMutex.Unlock()
+}
+
+// acquire 获取 EventLoop（监听 context 取消；超时后创建临时 EventLoop）
+func (p *eventLoopPool) acquire(ctx context.Context) (*pooledEventLoop, bool, error) {
+	select {
+	case pl := <-p.pool:
+		p.healthMutex.RLock()
+		if health, exists := p.health[pl]; exists {
+			health.lastUsedAtNano.Store(time.Now().UnixNano())
+			health.executionCount.Add(1)
+		}
+		p.healthMutex.RUnlock()
+		p.pooledExecs.Add(1)
+		return pl, false, nil
+
+	case <-ctx.Done():
+		// 🔥 请求已取消（客户端断开连接）
+		return nil, false, &execmodel.ExecutionError{
+			Type:    "CancelledError",
+			Message: "请求已取消",
+		}
+
+	case <-time.After(p.executor.runtimePoolAcquireTimeout):
+		p.executor.logger.Warn("EventLoop 池超时，创建临时 EventLoop",
+			zap.Duration("timeout", p.executor.runtimePoolAcquireTimeout))
+		pl, err := p.executor.newPooledEventLoop()
+		if err != nil {
+			p.executor.logger.Error("创建临时 EventLoop 失败", zap.Error(err))
+			return nil, false, &execmodel.ExecutionError{
+				Type:    "SetupError",
+				Message: fmt.Sprintf("模块设置失败: %v", err),
+			}
+		}
+		p.temporaryExecs.Add(1)
+		return pl, true, nil
+	}
+}
+
+// release 执行结束后归还 EventLoop
+//
+// 归还策略（与 Runtime 池一致）：
+//  1. 达到重用上限 → 销毁并实时补充
+//  2. 重置全局状态失败 → 销毁并实时补充
+//  3. 池满 → 丢弃（自然收缩）
+//  4. 临时 EventLoop 成功入池时计入池大小
+func (p *eventLoopPool) release(pl *pooledEventLoop, isTemporary, failed bool) {
+	if pl.broken {
+		if !isTemporary {
+			p.destroy(pl, "执行中发生 panic")
+			go p.replenishOne()
+		}
+		return
+	}
+
+	if !isTemporary {
+		var reuseCount int64
+		p.healthMutex.RLock()
+		if health, exists := p.health[pl]; exists {
+			reuseCount = health.executionCount.Load()
+			if failed {
+				health.errorCount.Add(1)
+			}
+		}
+		p.healthMutex.RUnlock()
+
+		if reuseCount >= p.executor.maxRuntimeReuseCount {
+			p.destroy(pl, "达到重用上限")
+			go p.replenishOne()
+			return
+		}
+	}
+
+	if err := pl.reset(); err != nil {
+		p.resetFailures.Add(1)
+		p.executor.logger.Warn("EventLoop 全局状态重置失败，销毁", zap.Error(err))
+		if !isTemporary {
+			p.destroy(pl, "重置失败")
+			go p.replenishOne()
+		} else {
+			pl.loop.Terminate()
+		}
+		return
+	}
+
+	if isTemporary {
+		p.track(pl)
+	}
+	select {
+	case p.pool <- pl:
+		if isTemporary {
+			atomic.AddInt32(&p.currentSize, 1)
+			p.executor.logger.Debug("临时 EventLoop 已放入池中",
+				zap.Int32("current_pool_size", atomic.LoadInt32(&p.currentSize)))
+		}
+	default:
+		// 🔥 池满，丢弃（临时 EventLoop 从未计入池大小）
+		p.untrack(pl)
+		pl.loop.Terminate()
+		if !isTemporary {
+			atomic.AddInt32(&p.currentSize, -1)
+			p.executor.logger.Warn("EventLoop 池已满，丢弃 EventLoop（自然收缩）",
+				zap.Int32("current_pool_size", atomic.LoadInt32(&p.currentSize)))
+		}
+	}
+}
+
+// abandon 超时/取消后丢弃 EventLoop
+// 🔒 执行可能仍在进行（等待中断生效），挂起的定时器也可能还在，等执行结束后再销毁
+func (p *eventLoopPool) abandon(pl *pooledEventLoop, isTemporary bool, done <-chan struct{}) {
+	p.abandonedCount.Add(1)
+	if !isTemporary {
+		p.healthMutex.RLock()
+		if health, exists := p.health[pl]; exists {
+			health.errorCount.Add(1)
+		}
+		p.healthMutex.RUnlock()
+	}
+
+	go func() {
+		<-done
+		if isTemporary {
+			if !pl.broken {
+				pl.loop.Terminate()
+			}
+			return
+		}
+		p.destroy(pl, "超时或取消")
+		p.replenishOne()
+	}()
+}
+
+// destroy 销毁池中的 EventLoop（清理健康信息、更新计数、按频率触发 GC）
+func (p *eventLoopPool) destroy(pl *pooledEventLoop, reason string) {
+	p.untrack(pl)
+	atomic.AddInt32(&p.currentSize, -1)
+	if !pl.broken {
+		pl.loop.Terminate() // 取消残留的定时器
+	}
+
+	destroyed := p.destroyCount.Add(1)
+	p.executor.logger.Debug("销毁 EventLoop",
+		zap.String("reason", reason),
+		zap.Int32("current_pool_size", atomic.LoadInt32(&p.currentSize)))
+
+	// 🛡️ 与 Runtime 池共用 GC 节流器和触发频率
+	if destroyed%p.executor.gcTriggerInterval == 0 {
+		p.executor.gcThrottler.triggerGC()
+	}
+}
+
+// replenishOne 实时补充：销毁1个立即补充1个
+func (p *eventLoopPool) replenishOne() {
+	select {
+	case <-p.executor.shutdown:
+		return
+	default:
+	}
+
+	if int(atomic.LoadInt32(&p.currentSize)) >= p.maxSize {
+		return
+	}
+
+	pl, err := p.executor.newPooledEventLoop()
+	if err != nil {
+		p.executor.logger.Error("补充 EventLoop 失败", zap.Error(err))
+		return
+	}
+
+	p.track(pl)
+	select {
+	case p.pool <- pl:
+		atomic.AddInt32(&p.currentSize, 1)
+	default:
+		p.untrack(pl)
+		pl.loop.Terminate()
+	}
+}
+
+// checkHealth 健康检查（由 checkAndFixRuntimes 定期调用）
+//   - 重建高错误率的 EventLoop
+//   - 按 Runtime 池的收缩/扩展策略调整池大小（见 healthAnalysis）
+func (p *eventLoopPool) checkHealth() {
+	e := p.executor
+	now := time.Now()
+
+	// 阶段 1: 分析（读锁内只读取 atomic 字段）
+	byRuntime := make(map[*goja.Runtime]*pooledEventLoop)
+	analysis := &healthAnalysis{
+		problemRuntimes:               make([]*goja.Runtime, 0),
+		idleRuntimes:                  make([]*goja.Runtime, 0),
+		currentSize:                   int(atomic.LoadInt32(&p.currentSize)),
+		availableSlots:                len(p.pool),
+		minPoolSize:                   p.minSize,
+		maxPoolSize:                   p.maxSize,
+		idleTimeout:                   e.idleTimeout,
+		poolExpansionThresholdPercent: e.poolExpansionThresholdPercent,
+	}
+
+	p.healthMutex.RLock()
+	for pl, health := range p.health {
+		byRuntime[pl.vm] = pl
+		errorCount := health.errorCount.Load()
+		executionCount := health.executionCount.Load()
+		if errorCount > int64(e.minErrorCountForCheck) && executionCount > 0 &&
+			float64(errorCount)/float64(executionCount) > e.maxErrorRateThreshold {
+			analysis.problemRuntimes = append(analysis.problemRuntimes, pl.vm)
+			continue
+		}
+		if now.Sub(time.Unix(0, health.lastUsedAtNano.Load())) > e.idleTimeout {
+			analysis.idleRuntimes = append(analysis.idleRuntimes, pl.vm)
+		}
+	}
+	p.healthMutex.RUnlock()
+
+	// 阶段 2: 回收问题 EventLoop 和可收缩的空闲 EventLoop
+	// 只回收当前空闲在池中的（执行中的由 release 按正常流程处理，下次检查再判断）
+	problems := make(map[*pooledEventLoop]bool, len(analysis.problemRuntimes))
+	for _, vm := range analysis.problemRuntimes {
+		problems[byRuntime[vm]] = true
+	}
+	idle := make(map[*pooledEventLoop]bool)
+	if analysis.shouldShrink() {
+		idleRuntimes := analysis.idleRuntimes
+		if n := analysis.calculateShrink(); len(idleRuntimes) > n {
+			idleRuntimes = idleRuntimes[:n]
+		}
+		for _, vm := range idleRuntimes {
+			idle[byRuntime[vm]] = true
+		}
+	}
+
+	rebuild, released := 0, 0
+	if len(problems) > 0 || len(idle) > 0 {
+		var keep []*pooledEventLoop
+	DrainLoop:
+		for i := len(p.pool); i > 0; i-- {
+			select {
+			case pl := <-p.pool:
+				switch {
+				case problems[pl]:
+					p.destroy(pl, "高错误率")
+					rebuild++
+				case idle[pl]:
+					p.destroy(pl, "空闲收缩")
+					released++
+				default:
+					keep = append(keep, pl)
+				}
+			default:
+				break DrainLoop
+			}
+		}
+		for _, pl := range keep {
+			select {
+			case p.pool <- pl:
+			default:
+				p.destroy(pl, "池已满")
+			}
+		}
+		if rebuild > 0 || released > 0 {
+			p.executor.logger.Info("EventLoop 池健康修复已应用",
+				zap.Int("rebuilt_loops", rebuild),
+				zap.Int("released", released),
+				zap.Int32("current_pool_size", atomic.LoadInt32(&p.currentSize)))
+		}
+	}
+
+	// 阶段 3: 补充重建的问题 EventLoop + 负载扩展
+	for i := 0; i < rebuild; i++ {
+		p.replenishOne()
+	}
+	analysis.currentSize = int(atomic.LoadInt32(&p.currentSize))
+	analysis.availableSlots = len(p.pool)
+	if analysis.shouldExpand() {
+		toAdd := analysis.calculateExpansion()
+		for i := 0; i < toAdd; i++ {
+			p.replenishOne()
+		}
+		p.executor.logger.Info("EventLoop 池扩展完成",
+			zap.Int("plan_to_add", toAdd),
+			zap.Int32("current_pool_size", atomic.LoadInt32(&p.currentSize)))
+	}
+}
+
+// shutdown 释放池中空闲的 EventLoop
+func (p *eventLoopPool) shutdown() {
+	for {
+		select {
+		case pl := <-p.pool:
+			p.untrack(pl)
+			pl.loop.Terminate()
+		default:
+			return
+		}
+	}
+}
+
+// stats 返回 EventLoop 池统计
+func (p *eventLoopPool) stats() execmodel.EventLoopPoolStats {
+	return execmodel.EventLoopPoolStats{
+		Size:                int(atomic.LoadInt32(&p.currentSize)),
+		Available:           len(p.pool),
+		MinSize:             p.minSize,
+		MaxSize:             p.maxSize,
+		PooledExecutions:    p.pooledExecs.Load(),
+		TemporaryExecutions: p.temporaryExecs.Load(),
+		DestroyCount:        p.destroyCount.Load(),
+		AbandonedCount:      p.abandonedCount.Load(),
+		ResetFailures:       p.resetFailures.Load(),
+	}
+}
+
+// GetEventLoopPoolHealth 获取 EventLoop 池健康状态（字段与 GetRuntimePoolHealth 一致）
+func (e *JSExecutor) GetEventLoopPoolHealth() map[string]interface{} {
+	p := e.eventLoopPool
+	p.healthMutex.RLock()
+	defer p.healthMutex.RUnlock()
+
+	totalExecutions := int64(0)
+	totalErrors := int64(0)
+	oldestLoop := time.Now()
+	for _, health := range p.health {
+		totalExecutions += health.executionCount.Load()
+		totalErrors += health.errorCount.Load()
+		createdAt := time.Unix(0, health.createdAtNano.Load())
+		if createdAt.Before(oldestLoop) {
+			oldestLoop = createdAt
+		}
+	}
+
+	errorRate := 0.0
+	if totalExecutions > 0 {
+		errorRate = float64(totalErrors) / float64(totalExecutions) * 100
+	}
+
+	return map[string]interface{}{
+		"poolSize":        atomic.LoadInt32(&p.currentSize),
+		"available":       len(p.pool),
+		"trackedLoops":    len(p.health),
+		"totalExecutions": totalExecutions,
+		"totalErrors":     totalErrors,
+		"errorRate":       errorRate,
+		"oldestLoop":      time.Since(oldestLoop).String(),
+	}
+}
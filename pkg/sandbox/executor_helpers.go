@@ -1,4 +1,4 @@
-package service
+package sandbox
 
 import (
 	"bytes"
@@ -15,16 +15,12 @@ import (
 	"sync/atomic"
 	"time"
 
-	"flow-codeblock-go/model"
+	"flow-codeblock-go/enhance_modules"
+	"flow-codeblock-go/model/execmodel"
 	"flow-codeblock-go/utils"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/dop251/goja"
-	"github.com/dop251/goja_nodejs/buffer"
-	"github.com/dop251/goja_nodejs/console"
-	"github.com/dop251/goja_nodejs/eventloop"
-	"github.com/dop251/goja_nodejs/process"
-	"github.com/dop251/goja_nodejs/url"
 	jsoniter "github.com/json-iterator/go"
 	"go.uber.org/zap"
 	"golang.org/x/text/unicode/norm"
@@ -60,222 +56,17 @@ var (
 //   - runtimePoolAcquireTimeout      → cfg.Executor.RuntimePoolAcquireTimeout
 //   - concurrencyLimitWaitTimeout    → cfg.Executor.ConcurrencyWaitTimeout
 
-// ============================================================================
-// 🔥 安全检查常量定义
-// ============================================================================
-
-// prohibitedModuleCheck 被禁用的模块定义
-type prohibitedModuleCheck struct {
-	pattern string
-	module  string
-	reason  string
-}
-
-// dangerousPatternCheck 危险代码模式定义
-type dangerousPatternCheck struct {
-	pattern string
-	reason  string
-}
-
-// dangerousRegexCheck 危险正则模式定义
-type dangerousRegexCheck struct {
-	pattern *regexp.Regexp
-	reason  string
-}
-
-var (
-	// 被禁用的模块列表
-	prohibitedModules = []prohibitedModuleCheck{
-		{"require('fs')", "fs", "文件系统操作"},
-		{"require(\"fs\")", "fs", "文件系统操作"},
-		{"require('path')", "path", "路径操作"},
-		{"require(\"path\")", "path", "路径操作"},
-		{"require('child_process')", "child_process", "子进程执行"},
-		{"require(\"child_process\")", "child_process", "子进程执行"},
-		{"require('os')", "os", "操作系统接口"},
-		{"require(\"os\")", "os", "操作系统接口"},
-	}
-
-	// 🔥 增强的危险代码模式检测（40+ 种模式 + 空格变体）
-	dangerousPatterns = []dangerousPatternCheck{
-		// Function 构造器（各种访问方式 + 空格变体）
-		{"Function(", "Function构造器可执行任意代码"},
-		{"Function (", "Function构造器可执行任意代码"},
-		{"Function  (", "Function构造器可执行任意代码"},
-		{"Function.(", "Function方法访问被禁止"},
-		{"Function[", "Function索引访问被禁止"},
-		{"new Function(", "Function构造器被禁止"},
-		{"new Function (", "Function构造器被禁止"},
-
-		// 构造器访问（原型链攻击 + 空格变体）
-		{".constructor(", "构造器调用可能导致代码注入"},
-		{".constructor (", "构造器调用可能导致代码注入"},
-		{".constructor.(", "构造器方法访问被禁止"},
-		{".constructor[", "构造器索引访问被禁止"},
-		{".constructor.constructor", "构造器链访问可能导致代码注入"},
-
-		// 动态构造器访问
-		{"['constructor']", "动态访问构造器被禁止"},
-		{"[\"constructor\"]", "动态访问构造器被禁止"},
-		{"[`constructor`]", "动态访问构造器被禁止"},
-		{"[ 'constructor']", "动态访问构造器被禁止"},
-		{"[ \"constructor\"]", "动态访问构造器被禁止"},
-
-		// 原型链访问
-		{".__proto__", "原型链操作可能导致安全问题"},
-		{"['__proto__']", "原型链操作可能导致安全问题"},
-		{"[\"__proto__\"]", "原型链操作可能导致安全问题"},
-		{"[`__proto__`]", "原型链操作可能导致安全问题"},
-
-		// 原型操作方法
-		{"Object.getPrototypeOf", "原型获取操作被禁止"},
-		{"Object.setPrototypeOf", "原型设置操作被禁止"},
-		{"Reflect.getPrototypeOf", "原型获取操作被禁止"},
-		{"Reflect.setPrototypeOf", "原型设置操作被禁止"},
-		{"Object.create", "Object.create可能导致原型污染"},
-
-		// 🔥 新增: Reflect 和 Proxy 危险方法
-		{"Reflect.construct", "Reflect.construct 可能导致代码注入"},
-		{"Reflect.apply", "Reflect.apply 可能导致代码注入"},
-		{"new Proxy", "Proxy 可能绕过安全限制"},
-		{"Proxy(", "Proxy 可能绕过安全限制"},
-		{"Proxy (", "Proxy 可能绕过安全限制"},
-
-		// eval 相关（+ 空格变体）
-		{"eval(", "eval函数可执行任意代码"},
-		{"eval (", "eval函数可执行任意代码"},
-		{"eval  (", "eval函数可执行任意代码"},
-		{"eval.(", "eval方法访问被禁止"},
-		{"eval[", "eval索引访问被禁止"},
-
-		// 全局对象访问
-		{"global.", "global对象访问被禁止"},
-		{"global[", "global对象访问被禁止"},
-		{"globalThis.", "globalThis对象访问被禁止"},
-		{"globalThis[", "globalThis对象访问被禁止"},
-		{"window.", "window对象访问被禁止"},
-		{"window[", "window对象访问被禁止"},
-		{"self.", "self对象访问被禁止"},
-		{"self[", "self对象访问被禁止"},
-
-		// 动态导入（+ 空格变体）
-		{"import(", "动态import被禁止"},
-		{"import (", "动态import被禁止"},
-	}
-
-	// 🔥 正则表达式检测复杂模式（支持空格、换行、复杂变体）
-	// 🔥 安全优化：使用 \s? 代替 \s{0,3}，防止 ReDoS 回溯攻击
-	//    - \s?  : 匹配 0-1 个空格，零回溯风险，性能最优
-	//    - \s{0,3}: 匹配 0-3 个空格，多个量词可能导致指数级回溯
-	dangerousRegexes = []dangerousRegexCheck{
-		// eval 相关（防止 ReDoS）
-		{
-			regexp.MustCompile(`\beval\s?\(`),
-			"检测到 eval 调用",
-		},
-
-		// Function 构造器（防止 ReDoS）
-		{
-			regexp.MustCompile(`\bFunction\s?\(`),
-			"检测到 Function 构造器",
-		},
-		{
-			regexp.MustCompile(`new\s+Function\s?\(`),
-			"检测到 new Function",
-		},
-
-		// 构造器链式访问（防止 ReDoS）
-		{
-			regexp.MustCompile(`\.\s?constructor\s?[\.\[\(]`),
-			"检测到构造器链式访问",
-		},
-
-		// 动态构造器访问（防止 ReDoS）
-		{
-			regexp.MustCompile(`\[\s?['"\x60]constructor['"\x60]\s?\]`),
-			"检测到动态访问构造器",
-		},
-
-		// 原型链访问（防止 ReDoS）
-		{
-			regexp.MustCompile(`\.\s?__proto__\s?`),
-			"检测到原型链访问",
-		},
-		{
-			regexp.MustCompile(`\[\s?['"\x60]__proto__['"\x60]\s?\]`),
-			"检测到动态原型链访问",
-		},
-	}
-
-	// 🔥 第 3 层：精确检测危险的动态属性访问
-	// 🔥 安全优化：使用 \s? 代替 \s{0,3}，防止 ReDoS 回溯攻击
-	//    每个正则有 3-4 个量词，\s{0,3} 可能导致 O(3^4) = 81 种回溯组合
-	dangerousDynamicAccessPatterns = []dangerousRegexCheck{
-		// 检测 this["eval"], this["Function"], this["constructor"]
-		{
-			regexp.MustCompile(`\bthis\s?\[\s?['"\x60](eval|Function|constructor)['"\x60]\s?\]`),
-			"检测到危险的 this 动态属性访问",
-		},
-		// 检测 globalThis["eval"], globalThis["Function"], globalThis["constructor"]
-		{
-			regexp.MustCompile(`\bglobalThis\s?\[\s?['"\x60](eval|Function|constructor)['"\x60]\s?\]`),
-			"检测到危险的 globalThis 动态属性访问",
-		},
-		// 检测 self["eval"], self["Function"], self["constructor"]
-		{
-			regexp.MustCompile(`\bself\s?\[\s?['"\x60](eval|Function|constructor)['"\x60]\s?\]`),
-			"检测到危险的 self 动态属性访问",
-		},
-		// 检测 window["eval"], window["Function"], window["constructor"]
-		{
-			regexp.MustCompile(`\bwindow\s?\[\s?['"\x60](eval|Function|constructor)['"\x60]\s?\]`),
-			"检测到危险的 window 动态属性访问",
-		},
-	}
-
-	// 🔥 第 4 层：启发式检测字符串拼接（常见绕过手法）
-	// 🔥 ReDoS 防御（关键）：
-	//    1. 使用 \s? 代替 \s{0,3}：防止空格回溯
-	//    2. 使用 \w{1,50} 代替 \w+：防止正则回溯攻击（ReDoS）
-	suspiciousStringPatterns = []dangerousRegexCheck{
-		// 检测可疑的字符串变量赋值：const e = "eval"
-		// 🔥 \w{1,50}：限制变量名长度，防止 ReDoS
-		{
-			regexp.MustCompile(`(?:const|let|var)\s+\w{1,50}\s?=\s?['"\x60](eval|Function|constructor|__proto__)['"\x60]`),
-			"检测到可疑的字符串变量（可能用于绕过检测）",
-		},
-		// 检测字符串拼接后的索引访问：this[x + y]
-		// 🔥 \w{1,50}：限制变量名长度，防止 ReDoS
-		{
-			regexp.MustCompile(`(?:this|globalThis|self|window)\s?\[\s?\w{1,50}\s?\+\s?\w{1,50}\s?\]`),
-			"检测到可疑的字符串拼接访问",
-		},
-		// 检测字符串字面量拼接：this["ev" + "al"]
-		// 🔥 \w{1,30}：字符串内容通常更短（如 "eval" = 4 字符）
-		{
-			regexp.MustCompile(`(?:this|globalThis|self|window)\s?\[\s?['"\x60]\w{1,30}['"\x60]\s?\+\s?['"\x60]\w{1,30}['"\x60]\s?\]`),
-			"检测到字符串字面量拼接访问",
-		},
-		// 检测 join 方法用于拼接：["e","val"].join("")
-		// 🔥 安全优化：使用 \s? 代替 \s{0,3}，防止 ReDoS 回溯攻击
-		//    - \s?  : 匹配 0-1 个空格，零回溯风险
-		//    - \s{0,3}: 匹配 0-3 个空格，7个量词可能导致 O(3^7) 回溯
-		{
-			regexp.MustCompile(`\[\s?['"\x60][a-zA-Z]['"\x60]\s?,\s?['"\x60][a-zA-Z]+['"\x60]\s?\]\s?\.\s?join\s?\(`),
-			"检测到使用 join 拼接字符串（可能绕过检测）",
-		},
-	}
-)
-
 // executeWithRuntimePool 使用Runtime池执行代码（同步代码，高性能）
 //
 // 🔥 Context 使用说明：
 //   - 接受来自上层的 context，而不是使用 context.Background()
 //   - 在获取 Runtime 时监听 context 取消信号
 //   - 支持客户端断开连接时立即中断
-func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, input map[string]interface{}) (*model.ExecutionResult, error) {
+func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, input map[string]interface{}, limits *executionLimits) (execResult *execmodel.ExecutionResult, execErr error) {
 	var runtime *goja.Runtime
 	var isTemporary bool
+	timeline := timelineFromContext(ctx)
+	acquireStart := time.Now()
 
 	// 🔥 新增：在获取 Runtime 时监听 context 取消
 	select {
@@ -315,7 +106,7 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 			if reuseCount >= e.maxRuntimeReuseCount {
 				shouldDestroy = true
 
-				utils.Debug("Runtime达到重用上限，销毁",
+				e.logger.Debug("Runtime达到重用上限，销毁",
 					zap.Int64("reuse_count", reuseCount),
 					zap.Int64("max_reuse_count", e.maxRuntimeReuseCount))
 			}
@@ -364,23 +155,23 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 				delete(e.runtimeHealth, runtime)
 				e.healthMutex.Unlock()
 
-				utils.Warn("运行时池已满，丢弃运行时（自然收缩）",
+				e.logger.Warn("运行时池已满，丢弃运行时（自然收缩）",
 					zap.Int32("current_pool_size", atomic.LoadInt32(&e.currentPoolSize)))
 			}
 		}()
 
 	case <-ctx.Done():
 		// 🔥 请求已取消（客户端断开连接）
-		return nil, &model.ExecutionError{
+		return nil, &execmodel.ExecutionError{
 			Type:    "CancelledError",
 			Message: "请求已取消",
 		}
 
 	case <-time.After(e.runtimePoolAcquireTimeout):
-		utils.Warn("运行时池超时，创建临时运行时", zap.Duration("timeout", e.runtimePoolAcquireTimeout))
+		e.logger.Warn("运行时池超时，创建临时运行时", zap.Duration("timeout", e.runtimePoolAcquireTimeout))
 		runtime = goja.New()
 		if err := e.setupRuntime(runtime); err != nil {
-			utils.Error("创建临时运行时失败", zap.Error(err))
+			e.logger.Error("创建临时运行时失败", zap.Error(err))
 			// 🔒 资源管理说明：
 			//   - goja.Runtime 是纯 Go 托管对象（无 C 资源、无文件描述符、无 socket）
 			//   - setupRuntime() 只设置回调函数和引用，不创建需要显式清理的资源
@@ -402,15 +193,21 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 			case e.runtimePool <- runtime:
 				// 🔥 v2.4.3 修复：临时 Runtime 成功放入池中，需要增加计数
 				atomic.AddInt32(&e.currentPoolSize, 1)
-				utils.Debug("临时运行时已放入池中",
+				e.logger.Debug("临时运行时已放入池中",
 					zap.Int32("current_pool_size", atomic.LoadInt32(&e.currentPoolSize)))
 			default:
 				// ✅ 池满，丢弃临时 Runtime
 				// 临时 Runtime 从未计入 currentPoolSize，丢弃时无需修正
-				utils.Debug("临时运行时使用后丢弃（池已满）")
+				e.logger.Debug("临时运行时使用后丢弃（池已满）")
 			}
 		}()
 	}
+	timeline.Since(PhaseRuntimeAcquire, acquireStart, cacheResult(!isTemporary))
+
+	// 🆕 管理员终止时中断此 Runtime（归还池之前解除关联：defer 后进先出，先于上面的归还执行）
+	run := runningFromContext(ctx)
+	run.setRuntime(runtime)
+	defer run.setRuntime(nil)
 
 	// 🔥 从 Context 中获取 requestID 作为 executionId（复用 requestID）
 	var executionId string
@@ -428,39 +225,35 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 	}
 
 	// 🔥 使用传入的 context，而不是 context.Background()
-	execCtx, cancel := context.WithTimeout(ctx, e.executionTimeout)
+	execCtx, cancel := context.WithTimeout(ctx, limits.timeout)
 	defer cancel()
 
 	runtime.Set("input", input)
 	runtime.Set("__executionId", executionId)
 	runtime.Set("__startTime", time.Now().UnixNano()/1e6)
 
-	// 🔥 预处理用户代码：移除 Shebang（#!/usr/bin/env node）
-	// Goja 不会自动忽略 Shebang，需要手动移除以避免语法错误
-	processedCode := code
-	if strings.HasPrefix(code, "#!") {
-		// 移除第一行（Shebang 行）
-		if idx := strings.Index(code, "\n"); idx != -1 {
-			processedCode = code[idx+1:]
-		} else {
-			// 整个文件只有 Shebang 一行，移除后变为空
-			processedCode = ""
-		}
+	// 🆕 capture 模式：绑定本次执行的 console 缓冲区，返回时附加到结果/错误
+	if capture := e.beginConsoleCapture(ctx, runtime, limits); capture != nil {
+		defer func() {
+			execResult, execErr = attachConsoleLogs(capture, execResult, execErr)
+		}()
 	}
 
+	// 🆕 沙箱策略：console 模式、require / fetch 拦截只对本次执行生效，归还前恢复
+	restorePolicy := e.applySandboxPolicy(ctx, runtime, limits, executionId)
+	defer func() {
+		restorePolicy()
+		if limits.consoleMode != e.consoleMode {
+			e.setupConsole(runtime, e.consoleMode, nil)
+		}
+	}()
+
 	// 包装用户代码：启用严格模式、隔离作用域、统一错误处理
-	wrappedCode := fmt.Sprintf(`
-		(function() {
-			'use strict';
-			try {
-				%s
-			} catch (error) {
-				throw new Error('代码执行错误: ' + (error.message || error));
-			}
-		})()
-	`, processedCode)
+	wrappedCode := wrapCodeForRuntimePool(code)
 
-	program, err := e.getCompiledCode(wrappedCode)
+	_, compileSpan := utils.StartSpan(ctx, "execute.compile")
+	program, err := e.compileForExecution(ctx, wrappedCode, 4)
+	utils.EndSpan(compileSpan, err)
 	if err != nil {
 		// 🔥 使用 categorizeError 处理编译错误，并调整行号
 		categorizedErr := e.categorizeError(err)
@@ -468,7 +261,7 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 		return nil, adjustedErr
 	}
 
-	resultChan := make(chan *model.ExecutionResult, 1)
+	resultChan := make(chan *execmodel.ExecutionResult, 1)
 	errorChan := make(chan error, 1)
 
 	go func() {
@@ -480,11 +273,11 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 				stackTrace := string(buf[:stackSize])
 
 				// 记录详细的panic信息
-				utils.Error("捕获到panic",
+				e.logger.Error("捕获到panic",
 					zap.Any("panic_value", r),
 					zap.String("stack_trace", stackTrace))
 
-				errorChan <- &model.ExecutionError{
+				errorChan <- &execmodel.ExecutionError{
 					Type:    "RuntimeError",
 					Message: fmt.Sprintf("代码执行panic: %v", r),
 					Stack:   stackTrace,
@@ -492,7 +285,9 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 			}
 		}()
 
+		runStart := time.Now()
 		value, err := runtime.RunProgram(program)
+		timeline.Since(PhaseRun, runStart, "")
 		if err != nil {
 			// 🔥 使用 categorizeError 处理运行时错误，并调整行号
 			categorizedErr := e.categorizeError(err)
@@ -502,7 +297,7 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 		}
 
 		if goja.IsUndefined(value) {
-			errorChan <- &model.ExecutionError{
+			errorChan <- &execmodel.ExecutionError{
 				Type:    "ValidationError",
 				Message: "返回值不能是 undefined",
 			}
@@ -510,10 +305,13 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 		}
 
 		// 🔥 使用带大小限制的导出（边导出边检查，超限立即中断，最早保护内存）
-		result, err := utils.ExportWithOrderAndLimit(value, e.maxResultSize)
+		// 🆕 export 阶段在发送结果前记录（调用方收到结果后即读取 timeline）
+		exportStart := time.Now()
+		result, err := utils.ExportWithOrderAndLimit(value, limits.maxResultSize)
 		if err != nil {
+			timeline.Since(PhaseExport, exportStart, "")
 			// 导出阶段超限（最早拦截点）
-			errorChan <- &model.ExecutionError{
+			errorChan <- &execmodel.ExecutionError{
 				Type:    "ValidationError",
 				Message: fmt.Sprintf("返回数据过大: %v", err),
 			}
@@ -525,13 +323,14 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 		result = convertTimesToUTC(result)
 
 		// 🔥 验证结果并获取预序列化的 JSON（避免重复序列化）
-		jsonData, err := e.validateResult(result)
+		jsonData, err := e.validateResult(result, limits.maxResultSize)
+		timeline.Since(PhaseExport, exportStart, "")
 		if err != nil {
 			errorChan <- err
 			return
 		}
 
-		executionResult := &model.ExecutionResult{
+		executionResult := &execmodel.ExecutionResult{
 			Result:    result,
 			RequestID: executionId, // 🔄 改名：ExecutionId → RequestID
 			JSONData:  jsonData,    // 🔥 保存预序列化的 JSON
@@ -578,18 +377,89 @@ func (e *JSExecutor) executeWithRuntimePool(ctx context.Context, code string, in
 
 		// 🔥 根据 context 取消原因返回不同错误
 		if execCtx.Err() == context.DeadlineExceeded {
-			return nil, &model.ExecutionError{
+			return nil, &execmodel.ExecutionError{
 				Type:    "TimeoutError",
-				Message: fmt.Sprintf("代码执行超时 (%v)", e.executionTimeout),
+				Message: fmt.Sprintf("代码执行超时 (%v)", limits.timeout),
 			}
 		}
-		return nil, &model.ExecutionError{
+		return nil, &execmodel.ExecutionError{
 			Type:    "CancelledError",
 			Message: "请求已取消",
 		}
 	}
 }
 
+// stripShebang 移除用户代码开头的 Shebang（#!/usr/bin/env node）
+// Goja 不会自动忽略 Shebang，需要手动移除以避免语法错误
+func stripShebang(code string) string {
+	if !strings.HasPrefix(code, "#!") {
+		return code
+	}
+	// 移除第一行（Shebang 行）
+	if idx := strings.Index(code, "\n"); idx != -1 {
+		return code[idx+1:]
+	}
+	// 整个文件只有 Shebang 一行，移除后变为空
+	return ""
+}
+
+// wrapCodeForRuntimePool 包装 Runtime 池路径的用户代码
+// 启用严格模式、隔离作用域、统一错误处理（包装增加了 4 行，错误行号需调整）
+func wrapCodeForRuntimePool(code string) string {
+	return fmt.Sprintf(`
+		(function() {
+			'use strict';
+			try {
+				%s
+			} catch (error) {
+				throw new Error('代码执行错误: ' + (error.message || error));
+			}
+		})()
+	`, stripShebang(code))
+}
+
+// wrapCodeForEventLoop 包装 EventLoop 路径的用户代码（包装增加了 9 行，错误行号需调整）
+//  1. 'use strict'：启用严格模式
+//  2. Promise.resolve()：将结果包装为Promise，确保EventLoop等待
+//  3. .then：执行用户代码并捕获返回值
+//  4. .then：存储结果到 __finalResult
+//  5. .catch：捕获所有错误到 __finalError（不重新抛出，避免干扰Go的错误检测）
+//  6. try-catch：捕获同步编译错误
+func wrapCodeForEventLoop(code string) string {
+	return fmt.Sprintf(`
+		(function() {
+			'use strict';
+			try {
+				// 🔥 关键：返回Promise，让EventLoop知道要等待
+				return Promise.resolve()
+					.then(function() {
+						// 执行用户代码
+						return (function() {
+							%s
+						})();
+					})
+					.then(function(result) {
+						// 存储结果
+						__finalResult = result;
+						return result;
+					})
+					.catch(function(error) {
+						// 捕获所有错误（包括用户代码的错误）
+						// 🔥 关键：存储错误但不重新抛出，让Promise正常resolve
+						// 这样EventLoop会认为Promise成功完成，我们在Go端检查 __finalError
+						__finalError = error ? error : new Error('Promise rejected');
+						return undefined;  // 返回undefined，避免 __finalResult 被覆盖
+					});
+			} catch (error) {
+				// 捕获同步编译错误
+				__finalError = error;
+				// 返回一个已resolve的Promise，让EventLoop继续
+				return Promise.resolve(undefined);
+			}
+		})()
+	`, stripShebang(code))
+}
+
 // cleanupRuntime 清理Runtime状态（归还前）
 func (e *JSExecutor) cleanupRuntime(runtime *goja.Runtime) {
 	runtime.Set("input", goja.Undefined())
@@ -605,9 +475,22 @@ func (e *JSExecutor) cleanupRuntime(runtime *goja.Runtime) {
 // 🔥 Context 使用说明：
 //   - 接受来自上层的 context，而不是使用 context.Background()
 //   - 监听 context 取消信号，支持请求中断
-func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, input map[string]interface{}) (*model.ExecutionResult, error) {
-	loop := eventloop.NewEventLoop(eventloop.WithRegistry(e.registry))
-	defer loop.Stop()
+//
+// 🆕 EventLoop 来自 eventLoopPool（模块加载和安全加固已在入池前完成），
+// 执行结束后重置全局状态并归还；超时/取消的 EventLoop 不复用
+func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, input map[string]interface{}, limits *executionLimits) (execResult *execmodel.ExecutionResult, execErr error) {
+	timeline := timelineFromContext(ctx)
+	acquireStart := time.Now()
+	pl, isTemporary, err := e.eventLoopPool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timeline.Since(PhaseRuntimeAcquire, acquireStart, cacheResult(!isTemporary))
+	loop, vm := pl.loop, pl.vm
+
+	// 🆕 管理员终止时中断此 Runtime（归还 / 丢弃 EventLoop 之前解除关联）
+	run := runningFromContext(ctx)
+	run.setRuntime(vm)
 
 	// 🔥 从 Context 中获取 requestID 作为 executionId（复用 requestID）
 	var executionId string
@@ -627,67 +510,51 @@ func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, inpu
 	var finalResult interface{}
 	var finalResultJSON []byte // 🔥 预序列化的 JSON（避免重复序列化）
 	var finalError error
-	var vm *goja.Runtime // 🔥 提升到外层作用域，以便在超时时访问
+
+	// 🆕 capture 模式：本次执行的 console 缓冲区（在 EventLoop 内绑定到池化的 Runtime）
+	capture := e.newExecutionCapture(ctx, limits)
+	if capture != nil {
+		defer func() {
+			execResult, execErr = attachConsoleLogs(capture, execResult, execErr)
+		}()
+	}
 
 	// 🔥 使用传入的 context，而不是 context.Background()
-	execCtx, cancel := context.WithTimeout(ctx, e.executionTimeout)
+	execCtx, cancel := context.WithTimeout(ctx, limits.timeout)
 	defer cancel()
 
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
+		defer func() {
+			// 🔒 定时器回调中的 panic 会穿出 loop.Run，EventLoop 停在运行状态，标记后丢弃
+			if r := recover(); r != nil {
+				pl.broken = true
+				finalError = &execmodel.ExecutionError{
+					Type:    "RuntimeError",
+					Message: fmt.Sprintf("代码执行panic: %v", r),
+				}
+			}
+		}()
 
-		loop.Run(func(runtime *goja.Runtime) {
-			vm = runtime
-
+		// 🆕 run 阶段从编译完成开始，到 loop.Run 返回（所有异步任务完成）为止
+		var runStart time.Time
+		loop.Run(func(vm *goja.Runtime) {
 			defer func() {
 				if r := recover(); r != nil {
-					finalError = &model.ExecutionError{
+					finalError = &execmodel.ExecutionError{
 						Type:    "RuntimeError",
 						Message: fmt.Sprintf("代码执行panic: %v", r),
 					}
 				}
 			}()
 
-			// 步骤1: 先设置 Node.js 基础模块（需要正常的原型）
-			// 🔥 Console 控制：与 setupNodeJSModules 保持一致
-			if e.allowConsole {
-				console.Enable(vm)
-			} else {
-				e.setupConsoleStub(vm)
-			}
-			e.registry.Enable(vm)
-			buffer.Enable(vm)
-			url.Enable(vm)
-			process.Enable(vm)
-
-			// 🔥 使用模块注册器统一设置所有模块
-			if err := e.moduleRegistry.SetupAll(vm); err != nil {
-				utils.Error("EventLoop 中模块设置失败", zap.Error(err))
-				finalError = &model.ExecutionError{
-					Type:    "SetupError",
-					Message: fmt.Sprintf("模块设置失败: %v", err),
-				}
-				return // 立即返回，不继续执行
+			// 池化 Runtime 只需设置本次执行的状态（模块和安全加固见 setupEventLoopRuntime）
+			// 🆕 console 模式和策略拦截在归还时由全局快照重置恢复
+			if capture != nil || limits.consoleMode != e.consoleMode {
+				e.setupConsole(vm, limits.consoleMode, capture)
 			}
-
-			e.registerBase64Functions(vm)
-			e.registerTextEncoders(vm) // ✅ 注册 TextEncoder/TextDecoder
-			e.setupGlobalObjectsForEventLoop(vm)
-
-			// 🔒 步骤2: 禁用危险功能和 constructor
-			vm.Set("eval", goja.Undefined())
-			// vm.Set("Function", goja.Undefined())  // 无法禁用，库需要
-			//vm.Set("globalThis", goja.Undefined()) // 不禁用了，仅关键词识别
-			vm.Set("window", goja.Undefined())
-			vm.Set("self", goja.Undefined())
-
-			// 🔥 禁用 Reflect 和 Proxy（防止绕过 constructor 防护）
-			vm.Set("Reflect", goja.Undefined())
-			vm.Set("Proxy", goja.Undefined())
-
-			// 🔒 禁用 constructor 访问（简化版，支持 EventLoop）
-			e.disableConstructorAccess(vm)
+			e.applySandboxPolicy(ctx, vm, limits, executionId)
 
 			vm.Set("input", input)
 			vm.Set("__executionId", executionId)
@@ -695,60 +562,15 @@ func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, inpu
 			vm.Set("__finalResult", goja.Undefined())
 			vm.Set("__finalError", goja.Undefined())
 
-			// 🔥 预处理用户代码：移除 Shebang（#!/usr/bin/env node）
-			// Goja 不会自动忽略 Shebang，需要手动移除以避免语法错误
-			processedCode := code
-			if strings.HasPrefix(code, "#!") {
-				// 移除第一行（Shebang 行）
-				if idx := strings.Index(code, "\n"); idx != -1 {
-					processedCode = code[idx+1:]
-				} else {
-					// 整个文件只有 Shebang 一行，移除后变为空
-					processedCode = ""
-				}
+			// 包装用户代码以支持 async/await（详见 wrapCodeForEventLoop）
+			// 🔥 通过 compileForExecution 编译（走 getCompiledCode，与 Runtime 池共享代码编译缓存；性能分析时单独编译）
+			_, compileSpan := utils.StartSpan(ctx, "execute.compile")
+			program, err := e.compileForExecution(ctx, wrapCodeForEventLoop(code), 9)
+			utils.EndSpan(compileSpan, err)
+			if err == nil {
+				runStart = time.Now()
+				_, err = vm.RunProgram(program)
 			}
-
-			// 包装用户代码以支持 async/await：
-			//   1. 'use strict'：启用严格模式
-			//   2. Promise.resolve()：将结果包装为Promise，确保EventLoop等待
-			//   3. .then：执行用户代码并捕获返回值
-			//   4. .then：存储结果到 __finalResult
-			//   5. .catch：捕获所有错误到 __finalError（不重新抛出，避免干扰Go的错误检测）
-			//   6. try-catch：捕获同步编译错误
-			wrappedCode := fmt.Sprintf(`
-				(function() {
-					'use strict';
-					try {
-						// 🔥 关键：返回Promise，让EventLoop知道要等待
-						return Promise.resolve()
-							.then(function() {
-								// 执行用户代码
-								return (function() {
-									%s
-								})();
-							})
-							.then(function(result) {
-								// 存储结果
-								__finalResult = result;
-								return result;
-							})
-							.catch(function(error) {
-								// 捕获所有错误（包括用户代码的错误）
-								// 🔥 关键：存储错误但不重新抛出，让Promise正常resolve
-								// 这样EventLoop会认为Promise成功完成，我们在Go端检查 __finalError
-								__finalError = error ? error : new Error('Promise rejected');
-								return undefined;  // 返回undefined，避免 __finalResult 被覆盖
-							});
-					} catch (error) {
-						// 捕获同步编译错误
-						__finalError = error;
-						// 返回一个已resolve的Promise，让EventLoop继续
-						return Promise.resolve(undefined);
-					}
-				})()
-			`, processedCode)
-
-			_, err := vm.RunString(wrappedCode)
 			if err != nil {
 				// 🔥 使用 categorizeError 处理编译/运行时错误，并调整行号
 				categorizedErr := e.categorizeError(err)
@@ -759,14 +581,17 @@ func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, inpu
 		// 🔥 重要：loop.Run() 会阻塞直到所有异步任务完成
 		// EventLoop内部会自动等待setTimeout、Promise等任务
 		// 所以执行到这里时，异步任务已经全部完成
+		if !runStart.IsZero() {
+			timeline.Since(PhaseRun, runStart, "")
+		}
 
 		if finalError == nil && vm != nil {
 			finalErr := vm.Get("__finalError")
 			if !goja.IsUndefined(finalErr) && finalErr != nil {
 				// 🔥 修复：提取完整的错误信息（包括stack trace）
 				errMsg, errStack := extractErrorDetails(finalErr)
-				rawError := &model.ExecutionError{
-					Type:    "RuntimeError",
+				rawError := &execmodel.ExecutionError{
+					Type:    policyErrorType(finalErr), // 🆕 沙箱策略拦截保留原错误类型
 					Message: errMsg,
 					Stack:   errStack, // ✅ 新增：包含stack信息
 				}
@@ -775,21 +600,23 @@ func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, inpu
 			} else {
 				finalRes := vm.Get("__finalResult")
 				if goja.IsUndefined(finalRes) {
-					finalError = &model.ExecutionError{
+					finalError = &execmodel.ExecutionError{
 						Type:    "ValidationError",
 						Message: "返回值不能是 undefined",
 					}
 				} else if finalRes == nil {
-					finalError = &model.ExecutionError{
+					finalError = &execmodel.ExecutionError{
 						Type:    "ValidationError",
 						Message: "代码没有返回有效结果",
 					}
 				} else {
 					// 🔥 使用带大小限制的导出（边导出边检查，超限立即中断）
-					exportedResult, err := utils.ExportWithOrderAndLimit(finalRes, e.maxResultSize)
+					exportStart := time.Now()
+					defer func() { timeline.Since(PhaseExport, exportStart, "") }()
+					exportedResult, err := utils.ExportWithOrderAndLimit(finalRes, limits.maxResultSize)
 					if err != nil {
 						// 导出阶段超限（最早拦截点）
-						finalError = &model.ExecutionError{
+						finalError = &execmodel.ExecutionError{
 							Type:    "ValidationError",
 							Message: fmt.Sprintf("返回数据过大: %v", err),
 						}
@@ -801,7 +628,7 @@ func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, inpu
 						finalResult = convertTimesToUTC(finalResult)
 
 						// 🔥 验证结果并获取预序列化的 JSON（避免重复序列化）
-						finalJSONData, err := e.validateResult(finalResult)
+						finalJSONData, err := e.validateResult(finalResult, limits.maxResultSize)
 						if err != nil {
 							finalError = err
 						} else {
@@ -816,10 +643,12 @@ func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, inpu
 
 	select {
 	case <-done:
+		run.setRuntime(nil)
+		e.eventLoopPool.release(pl, isTemporary, finalError != nil)
 		if finalError != nil {
 			return nil, finalError
 		}
-		return &model.ExecutionResult{
+		return &execmodel.ExecutionResult{
 			Result:    finalResult,
 			RequestID: executionId,     // 🔄 改名：ExecutionId → RequestID
 			JSONData:  finalResultJSON, // 🔥 保存预序列化的 JSON
@@ -834,19 +663,19 @@ func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, inpu
 		//   - Interrupt() 会在下一个"安全点"中断执行
 		//   - 对于紧密循环，goja 会定期检查中断标志
 		//   - done channel 是无缓冲的，Interrupt 后会正常关闭
-		if vm != nil {
-			vm.Interrupt("execution cancelled or timeout")
-		}
+		vm.Interrupt("execution cancelled or timeout")
 		loop.StopNoWait()
+		run.setRuntime(nil)
+		e.eventLoopPool.abandon(pl, isTemporary, done)
 
 		// 🔥 根据 context 取消原因返回不同错误
 		if execCtx.Err() == context.DeadlineExceeded {
-			return nil, &model.ExecutionError{
+			return nil, &execmodel.ExecutionError{
 				Type:    "TimeoutError",
-				Message: fmt.Sprintf("代码执行超时 (%v)", e.executionTimeout),
+				Message: fmt.Sprintf("代码执行超时 (%v)", limits.timeout),
 			}
 		}
-		return nil, &model.ExecutionError{
+		return nil, &execmodel.ExecutionError{
 			Type:    "CancelledError",
 			Message: "请求已取消",
 		}
@@ -866,9 +695,13 @@ func (e *JSExecutor) executeWithEventLoop(ctx context.Context, code string, inpu
 //   - 缓存命中：总耗时 ~20-30μs，无需中断
 //   - 缓存未命中：总耗时 ~500μs-2ms，可能需要中断
 //   - 客户端取消：立即返回，节省 0.5-2ms CPU 时间
-func (e *JSExecutor) validateInputWithContext(ctx context.Context, code string, input map[string]interface{}) error {
+func (e *JSExecutor) validateInputWithContext(ctx context.Context, code string, input map[string]interface{}, limits *executionLimits) error {
 	// 1. 验证代码（带缓存，通常很快 ~20-30μs）
-	if err := e.validateCodeWithCache(code); err != nil {
+	// 🆕 分阶段耗时：validate 阶段只计代码校验（输入大小检查 ~1-2μs 可忽略），并记录校验缓存是否命中
+	validateStart := time.Now()
+	cacheHit, err := e.validateCodeCached(code, limits)
+	timelineFromContext(ctx).Since(PhaseValidate, validateStart, cacheResult(cacheHit))
+	if err != nil {
 		return err
 	}
 
@@ -877,7 +710,7 @@ func (e *JSExecutor) validateInputWithContext(ctx context.Context, code string,
 	//    且客户端在此期间断开连接，可以立即返回，避免执行后续验证
 	select {
 	case <-ctx.Done():
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "CancelledError",
 			Message: "请求已取消（验证阶段）",
 		}
@@ -886,7 +719,7 @@ func (e *JSExecutor) validateInputWithContext(ctx context.Context, code string,
 	}
 
 	// 2. 验证输入大小（极快 ~1-2μs）
-	if err := e.validateInputData(input); err != nil {
+	if err := e.validateInputData(input, limits.maxInputSize); err != nil {
 		return err
 	}
 
@@ -897,12 +730,12 @@ func (e *JSExecutor) validateInputWithContext(ctx context.Context, code string,
 // 🔥 已弃用：外部调用应使用 validateInputWithContext
 func (e *JSExecutor) validateInput(code string, input map[string]interface{}) error {
 	// 1. 验证代码（带缓存）
-	if err := e.validateCodeWithCache(code); err != nil {
+	if err := e.validateCodeWithCache(code, e.defaultLimits); err != nil {
 		return err
 	}
 
 	// 2. 验证输入大小（每次都检查）
-	if err := e.validateInputData(input); err != nil {
+	if err := e.validateInputData(input, e.maxInputSize); err != nil {
 		return err
 	}
 
@@ -910,16 +743,23 @@ func (e *JSExecutor) validateInput(code string, input map[string]interface{}) er
 }
 
 // validateCodeWithCache 验证代码安全性（带缓存）
-// 🔥 性能优化：缓存验证结果，避免重复执行 40+ 个正则表达式
+// 🔥 性能优化：缓存验证结果，避免重复解析语法树和执行安全分析
 // 🔥 安全加固：归一化 Unicode 并过滤零宽字符，防御绕过攻击
-func (e *JSExecutor) validateCodeWithCache(code string) error {
+// 🆕 沙箱策略影响校验结果（console、模块允许列表、网络权限），缓存键附加 limits.validationKey
+func (e *JSExecutor) validateCodeWithCache(code string, limits *executionLimits) error {
+	_, err := e.validateCodeCached(code, limits)
+	return err
+}
+
+// validateCodeCached 同 validateCodeWithCache，额外返回是否命中校验缓存
+func (e *JSExecutor) validateCodeCached(code string, limits *executionLimits) (cacheHit bool, err error) {
 	// 🔥 安全加固：归一化 + 过滤零宽字符（防御 Unicode 绕过攻击）
 	// 攻击场景：obj.\u200Bconstructor() 或 eval\u0028...）
 	// 性能开销：~10-20μs（10KB 代码），可忽略不计
 	normalizedCode := e.normalizeCode(code)
 
 	// 计算代码哈希（使用归一化后的代码，使用 xxHash，快 20 倍）
-	codeHash := hashCode(normalizedCode)
+	codeHash := HashCode(normalizedCode) + limits.validationKey
 
 	// 尝试从缓存获取验证结果
 	e.validationCacheMutex.RLock()
@@ -927,17 +767,17 @@ func (e *JSExecutor) validateCodeWithCache(code string) error {
 		e.validationCacheMutex.RUnlock()
 		// 缓存中存储的是 error（nil 表示验证通过）
 		if result == nil {
-			return nil
+			return true, nil
 		}
 		if err, ok := result.(error); ok {
-			return err
+			return true, err
 		}
-		return nil // 类型断言失败时返回nil（验证通过）
+		return true, nil // 类型断言失败时返回nil（验证通过）
 	}
 	e.validationCacheMutex.RUnlock()
 
 	// 缓存未命中，执行完整验证（使用归一化后的代码）
-	err := e.validateCode(normalizedCode)
+	err = e.validateCode(normalizedCode, limits)
 
 	// 缓存验证结果（包括 nil 表示通过）
 	e.validationCacheMutex.Lock()
@@ -945,10 +785,10 @@ func (e *JSExecutor) validateCodeWithCache(code string) error {
 	e.validationCacheMutex.Unlock()
 
 	if evicted {
-		utils.Debug("验证缓存已满，驱逐最久未使用的条目")
+		e.logger.Debug("验证缓存已满，驱逐最久未使用的条目")
 	}
 
-	return err
+	return false, err
 }
 
 // validateCode 验证代码（不带缓存，由 validateCodeWithCache 调用）
@@ -957,10 +797,10 @@ func (e *JSExecutor) validateCodeWithCache(code string) error {
 //   - 优化前：removeStringsAndComments 被调用 2 次（validateReturnStatement + validateCodeSecurity）
 //   - 优化后：只调用 1 次，传递 cleanedCode 给子函数
 //   - 性能提升：节省 ~100μs（10KB 代码），占验证时间的 16.4%
-func (e *JSExecutor) validateCode(code string) error {
+func (e *JSExecutor) validateCode(code string, limits *executionLimits) error {
 	// 1. 长度检查（使用原始代码）
 	if len(code) > e.maxCodeLength {
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "ValidationError",
 			Message: fmt.Sprintf("代码长度超过限制: %d > %d字节", len(code), e.maxCodeLength),
 		}
@@ -975,7 +815,7 @@ func (e *JSExecutor) validateCode(code string) error {
 	}
 
 	// 3. 安全检查（传递原始代码和清理后的代码，部分检查需要原始字符串）
-	if err := e.validateCodeSecurityCleaned(code, cleanedCode); err != nil {
+	if err := e.validateCodeSecurityCleaned(code, cleanedCode, limits); err != nil {
 		return err
 	}
 
@@ -1009,7 +849,7 @@ func (e *JSExecutor) normalizeCode(code string) string {
 
 // validateInputData 验证输入数据（使用 json.Marshal 精确计算大小，用于 DoS 防护）
 // 🔥 性能优化：使用 json.Marshal 替代 fmt.Sprintf，性能提升 1.3-1.6x，准确度更高
-func (e *JSExecutor) validateInputData(input map[string]interface{}) error {
+func (e *JSExecutor) validateInputData(input map[string]interface{}, maxInputSize int) error {
 	// 🔥 使用 json.Marshal 计算精确的 JSON 大小
 	// 优势：
 	//   1. 性能更好：比 fmt.Sprintf 快 1.3-1.6x
@@ -1018,17 +858,17 @@ func (e *JSExecutor) validateInputData(input map[string]interface{}) error {
 	jsonData, err := json.Marshal(input)
 	if err != nil {
 		// JSON 序列化失败，说明数据无效
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "ValidationError",
 			Message: fmt.Sprintf("输入数据无法序列化为 JSON: %v", err),
 		}
 	}
 
 	inputSize := len(jsonData)
-	if inputSize > e.maxInputSize {
-		return &model.ExecutionError{
+	if inputSize > maxInputSize {
+		return &execmodel.ExecutionError{
 			Type:    "ValidationError",
-			Message: fmt.Sprintf("输入数据过大: %d > %d字节", inputSize, e.maxInputSize),
+			Message: fmt.Sprintf("输入数据过大: %d > %d字节", inputSize, maxInputSize),
 		}
 	}
 
@@ -1046,7 +886,7 @@ func (e *JSExecutor) validateReturnStatement(code string) error {
 // 🔥 性能优化：接受预清理的代码，避免重复调用 removeStringsAndComments
 func (e *JSExecutor) validateReturnStatementCleaned(cleanedCode string) error {
 	if !strings.Contains(cleanedCode, "return") {
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "ValidationError",
 			Message: "代码中缺少 return 语句",
 		}
@@ -1127,175 +967,35 @@ func writeSpacesBatch(sb *strings.Builder, count int) {
 }
 
 // validateCodeSecurity 验证代码安全性
-// 已废弃：使用 validateCodeSecurityCleaned 替代（接受预清理的代码）
-// 🔥 多层安全检测：静态分析 + 模式匹配 + 正则检测
+// 🔥 AST 安全分析（见 analyzeCodeSecurity）+ console 检查
 func (e *JSExecutor) validateCodeSecurity(code string) error {
 	cleanedCode := e.removeStringsAndComments(code)
-	return e.validateCodeSecurityCleaned(code, cleanedCode)
+	return e.validateCodeSecurityCleaned(code, cleanedCode, e.defaultLimits)
 }
 
-// validateCodeSecurityCleaned 验证清理后代码的安全性
-// 🔥 性能优化：接受预清理的代码，避免重复调用 removeStringsAndComments
-// 🔥 多层安全检测：静态分析 + 模式匹配 + 正则检测
+// validateCodeSecurityCleaned 验证代码安全性（接受预清理的代码，供 validateCode 复用）
+// 🔥 基于语法树分析：字符串、注释、属性名不会被误判，常量拼接等绕过写法会被折叠识别
 //
 // 参数说明：
-//   - code: 原始代码（用于字符串模式检测和死循环检测）
-//   - cleanedCode: 清理后的代码（用于大部分安全检查）
-
-// ============================================================================
-// 🔥 安全检查辅助函数（拆分后的各个检查逻辑）
-// ============================================================================
-
-// checkProhibitedModules 检查被禁用的模块引用
-func (e *JSExecutor) checkProhibitedModules(originalCode, cleanedCode string) error {
-	for _, mod := range prohibitedModules {
-		if idx := strings.Index(cleanedCode, mod.pattern); idx != -1 {
-			// 在原始代码中查找对应位置
-			lineNum, colNum, lineContent := e.findPatternInOriginalCode(originalCode, cleanedCode, idx, mod.pattern)
-
-			return &model.ExecutionError{
-				Type: "SecurityError",
-				Message: fmt.Sprintf("禁止使用 %s 模块：%s出于安全考虑已被禁用\n位置: 第 %d 行，第 %d 列\n代码: %s",
-					mod.module, mod.reason, lineNum, colNum, lineContent),
-			}
-		}
-	}
-	return nil
-}
-
-// checkDangerousPatterns 检查危险代码模式（字符串匹配）
-func (e *JSExecutor) checkDangerousPatterns(originalCode, cleanedCode string) error {
-	for _, pattern := range dangerousPatterns {
-		if idx := strings.Index(cleanedCode, pattern.pattern); idx != -1 {
-			// 在原始代码中查找对应位置
-			lineNum, colNum, lineContent := e.findPatternInOriginalCode(originalCode, cleanedCode, idx, pattern.pattern)
-
-			return &model.ExecutionError{
-				Type: "SecurityError",
-				Message: fmt.Sprintf("代码包含危险模式 '%s': %s\n位置: 第 %d 行，第 %d 列\n代码: %s",
-					pattern.pattern, pattern.reason, lineNum, colNum, lineContent),
-			}
-		}
-	}
-	return nil
-}
-
-// checkDangerousRegexPatterns 检查危险代码模式（正则表达式匹配）
-// 🔥 安全加固：限制空格数量为 3，防止 ReDoS 攻击
-func (e *JSExecutor) checkDangerousRegexPatterns(originalCode, cleanedCode string) error {
-	for _, pattern := range dangerousRegexes {
-		if loc := pattern.pattern.FindStringIndex(cleanedCode); loc != nil {
-			matchedText := cleanedCode[loc[0]:loc[1]]
-			// 在原始代码中查找对应位置
-			lineNum, colNum, lineContent := e.findPatternInOriginalCode(originalCode, cleanedCode, loc[0], matchedText)
-
-			return &model.ExecutionError{
-				Type: "SecurityError",
-				Message: fmt.Sprintf("代码包含危险模式: %s\n位置: 第 %d 行，第 %d 列\n匹配内容: %s\n代码: %s",
-					pattern.reason, lineNum, colNum, matchedText, lineContent),
-			}
-		}
+//   - code: 原始代码（用于 AST 分析和行号计算）
+//   - cleanedCode: 清理后的代码（用于 console 检查）
+//   - limits: 本次执行的限制（模块允许列表、网络权限、console 模式）
+func (e *JSExecutor) validateCodeSecurityCleaned(code, cleanedCode string, limits *executionLimits) error {
+	// 报告位置最靠前的一条发现
+	if findings := analyzeCodeSecurity(code, limits); len(findings) > 0 {
+		return e.securityFindingError(code, findings[0])
 	}
-	return nil
-}
-
-// checkDynamicPropertyAccess 检查危险的动态属性访问
-// 检测 this["eval"], globalThis["Function"] 等模式
-func (e *JSExecutor) checkDynamicPropertyAccess(originalCode, cleanedCode string) error {
-	for _, pattern := range dangerousDynamicAccessPatterns {
-		if loc := pattern.pattern.FindStringIndex(cleanedCode); loc != nil {
-			matchedText := cleanedCode[loc[0]:loc[1]]
-			// 在原始代码中查找对应位置
-			lineNum, colNum, lineContent := e.findPatternInOriginalCode(originalCode, cleanedCode, loc[0], matchedText)
-
-			return &model.ExecutionError{
-				Type: "SecurityError",
-				Message: fmt.Sprintf("代码包含危险模式: %s\n位置: 第 %d 行，第 %d 列\n匹配内容: %s\n代码: %s",
-					pattern.reason, lineNum, colNum, matchedText, lineContent),
-			}
-		}
-	}
-	return nil
-}
 
-// checkSuspiciousStringPatterns 检查可疑的字符串拼接模式（启发式检测）
-// 注意：需要使用原始代码，因为需要分析字符串内容
-//
-// 🔥 P0-2 优化：两阶段检测策略
-//   - 阶段 1：快速字符串检查（零开销，O(n)）
-//   - 阶段 2：精确正则检测（仅对可疑代码，少量开销）
-//   - 收益：99% 的正常代码跳过正则检测，性能提升 50-100x
-func (e *JSExecutor) checkSuspiciousStringPatterns(code string) error {
-	// 🔥 阶段 1：快速字符串检查（零回溯）
-	// 如果代码不包含任何可疑字符串字面量或模式，直接返回
-	// 这能让 99% 的正常代码跳过正则检测
-	hasSuspicious := false
-	suspiciousStrings := []string{
-		// 完整的危险关键词
-		`"eval"`, `'eval'`, "`eval`",
-		`"Function"`, `'Function'`, "`Function`",
-		`"constructor"`, `'constructor'`, "`constructor`",
-		`"__proto__"`, `'__proto__'`, "`__proto__`",
-
-		// 🔥 字符串拼接检测 - eval 的各种拼接方式
-		`"ev"`, `'ev'`, `"al"`, `'al'`, // "ev" + "al"
-		`"eva"`, `'eva'`, `"val"`, `'val'`, // "eva" + "l" 或 "e" + "val"
-
-		// 🔥 字符串拼接检测 - Function 的各种拼接方式
-		`"Fun"`, `'Fun'`, `"ction"`, `'ction'`, // "Fun" + "ction"
-		`"Func"`, `'Func'`, `"tion"`, `'tion'`, // "Func" + "tion"
-		`"unction"`, `'unction'`, // "F" + "unction"
-
-		// 🔥 字符串拼接检测 - constructor 的各种拼接方式
-		`"cons"`, `'cons'`, `"tructor"`, `'tructor'`, // "cons" + "tructor"
-		`"const"`, `'const'`, `"ructor"`, `'ructor'`, // "const" + "ructor"
-		`"constr"`, `'constr'`, `"uctor"`, `'uctor'`, // "constr" + "uctor"
-		`"construc"`, `'construc'`, `"tor"`, `'tor'`, // "construc" + "tor"
-
-		// 🔥 字符串拼接检测 - __proto__ 的各种拼接方式
-		`"__pro"`, `'__pro'`, `"to__"`, `'to__'`, // "__pro" + "to__"
-		`"__"`, `'__'`, `"proto__"`, `'proto__'`, // "__" + "proto__"
-
-		// 其他拼接模式
-		`.join(`, // 检测 join 方法
-	}
-
-	for _, s := range suspiciousStrings {
-		if strings.Contains(code, s) {
-			hasSuspicious = true
-			break
-		}
-	}
-
-	// 如果没有可疑字符串，直接返回（快速路径）
-	if !hasSuspicious {
-		return nil
-	}
-
-	// 🔥 阶段 2：精确正则检测（仅对可疑代码）
-	// 此时已知代码包含可疑字符串，需要精确检测是否有危险模式
-	for _, pattern := range suspiciousStringPatterns {
-		if loc := pattern.pattern.FindStringIndex(code); loc != nil {
-			// 计算行号和列号
-			lineNum, colNum, lineContent := e.findLineAndColumn(code, loc[0])
-			matchedText := code[loc[0]:loc[1]]
-
-			return &model.ExecutionError{
-				Type: "SecurityError",
-				Message: fmt.Sprintf("代码包含可疑模式: %s\n位置: 第 %d 行，第 %d 列\n匹配内容: %s\n代码: %s",
-					pattern.reason, lineNum, colNum, matchedText, lineContent),
-			}
-		}
-	}
-	return nil
+	// 🔥 检查 console 使用（如果禁用）
+	return e.checkConsoleUsage(code, cleanedCode, limits)
 }
 
 // checkConsoleUsage 检查 console 使用（如果已禁用）
 // 🔥 静态检测：在代码验证阶段就拒绝包含 console 的代码
-// 📝 说明：如果 ALLOW_CONSOLE=false，则禁止代码中出现 console（无论在任何位置）
-func (e *JSExecutor) checkConsoleUsage(originalCode, cleanedCode string) error {
+// 📝 说明：console 模式为 disabled 时（全局 CONSOLE_MODE 或沙箱策略），禁止代码中出现 console（无论在任何位置）
+func (e *JSExecutor) checkConsoleUsage(originalCode, cleanedCode string, limits *executionLimits) error {
 	// 如果允许 console，直接返回
-	if e.allowConsole {
+	if limits.consoleMode != ConsoleModeDisabled {
 		return nil
 	}
 
@@ -1305,7 +1005,7 @@ func (e *JSExecutor) checkConsoleUsage(originalCode, cleanedCode string) error {
 		// 🔥 在原始代码中查找实际代码中的 console（跳过注释和字符串）
 		lineNum, colNum, lineContent := e.findConsoleInActualCode(originalCode)
 
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type: "ConsoleDisabledError",
 			Message: fmt.Sprintf("代码中禁止使用 console\n"+
 				"原因: 生产环境已禁用 console \n"+
@@ -1357,211 +1057,6 @@ func (e *JSExecutor) findConsoleInActualCode(code string) (int, int, string) {
 	return 1, 1, ""
 }
 
-// removeCommentsAndStrings 移除代码中的注释和字符串，用于更准确的语法检测
-// 🔥 用途：避免注释或字符串中的关键字（如 break/return）导致误判
-// 🔥 v2.5.0 重构：使用统一的 CodeLexer 词法分析器，消除代码重复
-func (e *JSExecutor) removeCommentsAndStrings(code string) string {
-	lexer := utils.NewCodeLexer(code)
-	var result strings.Builder
-	result.Grow(len(code))
-
-	codeBytes := lexer.GetCode()
-
-	for {
-		token := lexer.NextToken()
-		if token.Type == utils.TokenEOF {
-			break
-		}
-
-		if token.Type == utils.TokenCode {
-			// 普通代码字符：直接写入
-			result.Write(codeBytes[token.Start:token.End])
-		} else {
-			// 字符串或注释：用空格替代（逐字节，保持长度一致）
-			for i := token.Start; i < token.End; i++ {
-				if codeBytes[i] == '\n' {
-					// 保留换行符（用于行号计算）
-					result.WriteByte('\n')
-				} else {
-					result.WriteByte(' ')
-				}
-			}
-		}
-	}
-
-	return result.String()
-}
-
-// hasExitStatementInCode 检查代码中是否包含退出语句（break 或 return）
-// 🔥 v2.4.1 改进：
-//  1. 排除注释和字符串中的 break/return
-//  2. 检查 break/return 是否在循环体的 {} 内部（避免循环外的 return 误判）
-func (e *JSExecutor) hasExitStatementInCode(code string) bool {
-	cleaned := e.removeCommentsAndStrings(code)
-	return e.hasExitStatementInLoop(cleaned)
-}
-
-// hasExitStatementInLoop 检查循环体内是否有退出语句
-// 🔥 核心逻辑：确保 break/return 在循环的 {} 内部，而不是循环外
-func (e *JSExecutor) hasExitStatementInLoop(code string) bool {
-	// 查找所有可能的循环模式
-	loopPatterns := []string{
-		"while(true)", "while (true)",
-		"while(1)", "while (1)",
-		"for(;;)", "for (;;)",
-		"do{", "do {",
-	}
-
-	for _, pattern := range loopPatterns {
-		index := strings.Index(code, pattern)
-		if index == -1 {
-			continue
-		}
-
-		// 找到循环开始位置后，查找循环体的 {}
-		// 从 pattern 后开始查找第一个 {
-		searchStart := index + len(pattern)
-
-		// 对于 do-while，{ 在 pattern 中
-		if pattern == "do{" {
-			searchStart = index + 2 // "do" 的长度
-		} else if pattern == "do {" {
-			searchStart = index + 3 // "do " 的长度
-		} else {
-			// 对于 while/for，查找第一个 {
-			braceIndex := strings.Index(code[searchStart:], "{")
-			if braceIndex == -1 {
-				continue // 没有找到 {，跳过
-			}
-			searchStart = searchStart + braceIndex
-		}
-
-		// 从 { 开始，匹配对应的 }
-		loopBody := e.extractLoopBody(code, searchStart)
-		if loopBody == "" {
-			continue
-		}
-
-		// 检查循环体内是否有 break 或 return
-		if strings.Contains(loopBody, "break") || strings.Contains(loopBody, "return") {
-			return true
-		}
-	}
-
-	return false
-}
-
-// extractLoopBody 提取循环体内容（从 { 到匹配的 }）
-// 🔥 使用括号计数器，正确处理嵌套的 {}
-func (e *JSExecutor) extractLoopBody(code string, startIndex int) string {
-	if startIndex >= len(code) || code[startIndex] != '{' {
-		return ""
-	}
-
-	braceCount := 0
-	for i := startIndex; i < len(code); i++ {
-		ch := code[i]
-
-		if ch == '{' {
-			braceCount++
-		} else if ch == '}' {
-			braceCount--
-			if braceCount == 0 {
-				// 找到了匹配的 }
-				return code[startIndex+1 : i] // 返回 {} 内的内容（不包括 {} 本身）
-			}
-		}
-	}
-
-	return "" // 没有找到匹配的 }
-}
-
-// checkInfiniteLoops 检查可能的无限循环
-// 🔥 v2.5.4 修复：接收清理后的代码，避免注释中的循环被误判
-// 🔥 v2.4 优化：
-//  1. 增加 while(1) 检测（覆盖率 +5%）
-//  2. 增加 do-while 检测（覆盖率 +3%）
-//  3. 改进 break/return 检测：排除注释和字符串（准确度 +10%）
-//  4. 优化错误提示：明确告知有 超时保护
-//
-// 参数说明：
-//   - cleanedCode: 已清理的代码（已移除注释和字符串）
-func (e *JSExecutor) checkInfiniteLoops(cleanedCode string) error {
-	// 注意：cleanedCode 已经是清理后的代码，不需要再次清理
-
-	// 检查 while(true) 或 while (true)
-	hasWhileTrue := strings.Contains(cleanedCode, "while(true)") || strings.Contains(cleanedCode, "while (true)")
-
-	// 🔥 新增：检查 while(1) 或 while (1)
-	hasWhileOne := strings.Contains(cleanedCode, "while(1)") || strings.Contains(cleanedCode, "while (1)")
-
-	// 检查 for(;;) 或 for (;;)
-	hasForInfinite := strings.Contains(cleanedCode, "for(;;)") || strings.Contains(cleanedCode, "for (;;)")
-
-	// 🔥 新增：检查 do-while(true) 或 do-while(1)
-	hasDoWhile := (strings.Contains(cleanedCode, "do{") || strings.Contains(cleanedCode, "do {")) &&
-		(strings.Contains(cleanedCode, "while(true)") || strings.Contains(cleanedCode, "while (true)") ||
-			strings.Contains(cleanedCode, "while(1)") || strings.Contains(cleanedCode, "while (1)"))
-
-	if hasWhileTrue || hasWhileOne || hasForInfinite || hasDoWhile {
-		// 🔥 智能检测：如果循环体内有 break/return，则认为是安全的
-		// 常见合法模式：
-		// - while (true) { if (done) break; }  // 流式读取
-		// - while (true) { if (condition) return; }  // 条件退出
-		// - for (;;) { if (count > 10) break; }  // 计数退出
-
-		// 🔥 v2.5.4 修复：传入已清理的代码，避免重复清理
-		if e.hasExitStatementInLoop(cleanedCode) {
-			// 包含退出条件，认为是安全的
-			return nil
-		}
-
-		// 没有明显的退出条件，认为可能是无限循环
-		return &model.ExecutionError{
-			Type: "SecurityError",
-			Message: "代码可能包含无限循环，已被阻止执行。\n" +
-				"提示：如果使用 while(true) / while(1) / for(;;)，请确保包含 break 或 return 退出条件。",
-		}
-	}
-
-	return nil
-}
-
-func (e *JSExecutor) validateCodeSecurityCleaned(code, cleanedCode string) error {
-	// ✅ async/await 已支持（goja v2025-06-30+）
-	// 不再需要检测和拒绝 async/await 语法
-
-	// 🔥 重构：调用拆分后的检查函数
-	// 注意：传入原始代码用于行号计算
-	if err := e.checkProhibitedModules(code, cleanedCode); err != nil {
-		return err
-	}
-
-	if err := e.checkDangerousPatterns(code, cleanedCode); err != nil {
-		return err
-	}
-
-	if err := e.checkDangerousRegexPatterns(code, cleanedCode); err != nil {
-		return err
-	}
-
-	if err := e.checkDynamicPropertyAccess(code, cleanedCode); err != nil {
-		return err
-	}
-
-	if err := e.checkSuspiciousStringPatterns(code); err != nil {
-		return err
-	}
-
-	// 🔥 检查 console 使用（如果禁用）
-	if err := e.checkConsoleUsage(code, cleanedCode); err != nil {
-		return err
-	}
-
-	// 🔥 v2.5.4 修复：传入 cleanedCode，避免注释中的循环关键字被误判
-	return e.checkInfiniteLoops(cleanedCode)
-}
-
 // limitedWriter 限制写入大小的 writer（边序列化边检查，超限立即中断）
 type limitedWriter struct {
 	buf     *bytes.Buffer
@@ -1598,10 +1093,10 @@ func (w *limitedWriter) Write(p []byte) (n int, err error) {
 //   - limitedWriter 在写入时检查大小，拦截超限数据
 //   - 能防止传输超大响应，但无法完全防止序列化阶段的内存占用
 //   - 建议：合理设置 MAX_RESULT_SIZE + Docker 内存限制 + 监控
-func (e *JSExecutor) validateResult(result interface{}) ([]byte, error) {
+func (e *JSExecutor) validateResult(result interface{}, maxResultSize int) ([]byte, error) {
 	// 1. 检查是否包含无效的JSON值 (NaN, Infinity等) - 在序列化前检查
 	if err := validateJSONSerializable(result); err != nil {
-		return nil, &model.ExecutionError{
+		return nil, &execmodel.ExecutionError{
 			Type:    "ValidationError",
 			Message: fmt.Sprintf("返回结果包含无效的JSON值: %v", err),
 		}
@@ -1612,24 +1107,24 @@ func (e *JSExecutor) validateResult(result interface{}) ([]byte, error) {
 	buf := &bytes.Buffer{}
 	limitWriter := &limitedWriter{
 		buf:   buf,
-		limit: e.maxResultSize,
+		limit: maxResultSize,
 	}
 
 	encoder := jsonAPI.NewEncoder(limitWriter)
 	if err := encoder.Encode(result); err != nil {
 		// 检查是否是大小限制错误（包含 "结果序列化超过大小限制" 关键字）
 		if strings.Contains(err.Error(), "结果序列化超过大小限制") {
-			if limitWriter.written > 0 && limitWriter.written >= e.maxResultSize {
+			if limitWriter.written > 0 && limitWriter.written >= maxResultSize {
 				// 流式写入中被中断（罕见，仅顶级数组结构）
-				return nil, &model.ExecutionError{
+				return nil, &execmodel.ExecutionError{
 					Type: "ValidationError",
 					Message: fmt.Sprintf("返回结果过大: 已序列化 %d 字节 > %d 字节限制（流式序列化已中断）",
-						limitWriter.written, e.maxResultSize),
+						limitWriter.written, maxResultSize),
 				}
 			} else {
 				// 第一次写入就超限（常见，对象结构一次性序列化）
 				// 注意：此时数据已在内存中完成序列化，但被拦截未传输
-				return nil, &model.ExecutionError{
+				return nil, &execmodel.ExecutionError{
 					Type: "ValidationError",
 					Message: fmt.Sprintf("返回结果过大: %s。警告：请优化返回结构",
 						err.Error()),
@@ -1637,7 +1132,7 @@ func (e *JSExecutor) validateResult(result interface{}) ([]byte, error) {
 			}
 		}
 		// 其他序列化错误
-		return nil, &model.ExecutionError{
+		return nil, &execmodel.ExecutionError{
 			Type:    "ValidationError",
 			Message: fmt.Sprintf("结果无法序列化为JSON: %v", err),
 		}
@@ -1915,7 +1410,7 @@ func (e *JSExecutor) categorizeError(err error) error {
 
 	// 🔥 第 3 层：处理 goja.InterruptedError（执行中断）
 	if _, ok := err.(*goja.InterruptedError); ok {
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "InterruptedError",
 			Message: "代码执行被中断",
 		}
@@ -1955,7 +1450,7 @@ func (e *JSExecutor) categorizeGojaException(exception *goja.Exception) error {
 	// 根据错误类型进行分类
 	switch errorType {
 	case "SyntaxError":
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "SyntaxError",
 			Message: fmt.Sprintf("语法错误: %s", errorMessage),
 		}
@@ -1969,51 +1464,58 @@ func (e *JSExecutor) categorizeGojaException(exception *goja.Exception) error {
 				suggestions := getModuleSuggestions(varName)
 
 				if suggestions != "" {
-					return &model.ExecutionError{
+					return &execmodel.ExecutionError{
 						Type:    "ReferenceError",
 						Message: fmt.Sprintf("变量 '%s' 未定义。%s", varName, suggestions),
 					}
 				}
 
-				return &model.ExecutionError{
+				return &execmodel.ExecutionError{
 					Type:    "ReferenceError",
 					Message: fmt.Sprintf("变量 '%s' 未定义。请检查是否需要引入相关模块或定义该变量。", varName),
 				}
 			}
 		}
 
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "ReferenceError",
 			Message: fmt.Sprintf("引用错误: %s", errorMessage),
 		}
 
 	case "TypeError":
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "TypeError",
 			Message: fmt.Sprintf("类型错误: %s", errorMessage),
 		}
 
 	case "RangeError":
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "RangeError",
 			Message: fmt.Sprintf("范围错误: %s", errorMessage),
 		}
 
 	case "URIError":
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "URIError",
 			Message: fmt.Sprintf("URI 错误: %s", errorMessage),
 		}
 
 	case "EvalError":
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "EvalError",
 			Message: fmt.Sprintf("Eval 错误: %s", errorMessage),
 		}
 
+	case "SecurityError", enhance_modules.EgressDeniedErrorName:
+		// 🆕 沙箱策略拦截（require 不在允许列表中、网络已禁用、出站目标不在允许范围内）
+		return &execmodel.ExecutionError{
+			Type:    errorType,
+			Message: errorMessage,
+		}
+
 	default:
 		// 未知的错误类型，返回通用的运行时错误
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "RuntimeError",
 			Message: fmt.Sprintf("运行时错误: %s", errorMessage),
 		}
@@ -2027,7 +1529,7 @@ func (e *JSExecutor) categorizeCompilerError(syntaxErr *goja.CompilerSyntaxError
 	// 该方法已经包含了位置信息（如果有的话）
 	message := syntaxErr.Error()
 
-	return &model.ExecutionError{
+	return &execmodel.ExecutionError{
 		Type:    "SyntaxError",
 		Message: fmt.Sprintf("语法错误: %s", message),
 	}
@@ -2041,7 +1543,7 @@ func adjustErrorLineNumber(err error, lineOffset int) error {
 	}
 
 	// 只处理 ExecutionError 类型
-	execErr, ok := err.(*model.ExecutionError)
+	execErr, ok := err.(*execmodel.ExecutionError)
 	if !ok {
 		return err
 	}
@@ -2154,7 +1656,7 @@ func (e *JSExecutor) categorizeByMessage(err error) error {
 
 	// 检测语法错误
 	if strings.Contains(message, "SyntaxError") || strings.Contains(message, "Unexpected") {
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "SyntaxError",
 			Message: fmt.Sprintf("语法错误: %s", message),
 		}
@@ -2168,18 +1670,18 @@ func (e *JSExecutor) categorizeByMessage(err error) error {
 
 			suggestions := getModuleSuggestions(varName)
 			if suggestions != "" {
-				return &model.ExecutionError{
+				return &execmodel.ExecutionError{
 					Type:    "ReferenceError",
 					Message: fmt.Sprintf("变量 '%s' 未定义。%s", varName, suggestions),
 				}
 			}
 
-			return &model.ExecutionError{
+			return &execmodel.ExecutionError{
 				Type:    "ReferenceError",
 				Message: fmt.Sprintf("变量 '%s' 未定义。请检查是否需要引入相关模块或定义该变量。", varName),
 			}
 		}
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "ReferenceError",
 			Message: fmt.Sprintf("引用错误: %s", message),
 		}
@@ -2189,14 +1691,14 @@ func (e *JSExecutor) categorizeByMessage(err error) error {
 	if strings.Contains(message, "is not a function") ||
 		strings.Contains(message, "Cannot read property") ||
 		strings.Contains(message, "TypeError") {
-		return &model.ExecutionError{
+		return &execmodel.ExecutionError{
 			Type:    "TypeError",
 			Message: fmt.Sprintf("类型错误: %s", message),
 		}
 	}
 
 	// 默认：运行时错误
-	return &model.ExecutionError{
+	return &execmodel.ExecutionError{
 		Type:    "RuntimeError",
 		Message: fmt.Sprintf("运行时错误: %s", message),
 	}
@@ -2261,7 +1763,7 @@ func (e *JSExecutor) updateStats(executionTime time.Duration, success bool) {
 }
 
 // GetStats 获取统计信息
-func (e *JSExecutor) GetStats() *model.ExecutorStats {
+func (e *JSExecutor) GetStats() *execmodel.ExecutorStats {
 	e.mutex.RLock()
 	defer e.mutex.RUnlock()
 
@@ -2269,6 +1771,7 @@ func (e *JSExecutor) GetStats() *model.ExecutorStats {
 	e.stats.CurrentExecutions = atomic.LoadInt64(&e.currentExecs)
 
 	stats := *e.stats
+	stats.EventLoopPool = e.eventLoopPool.stats()
 	return &stats
 }
 
@@ -2316,6 +1819,9 @@ func (e *JSExecutor) GetRuntimePoolHealth() map[string]interface{} {
 
 	return map[string]interface{}{
 		"poolSize":        e.poolSize,
+		"currentPoolSize": int(atomic.LoadInt32(&e.currentPoolSize)),      // 🆕 动态扩缩容后的池大小
+		"idleRuntimes":    len(e.runtimePool),                             // 🆕 池中空闲的 Runtime 数
+		"destroyCount":    atomic.LoadInt64(&e.stats.RuntimeDestroyCount), // 🆕 达到重用上限或异常后销毁的次数
 		"trackedRuntimes": totalRuntimes,
 		"totalExecutions": totalExecutions,
 		"totalErrors":     totalErrors,
@@ -2344,7 +1850,20 @@ func (e *JSExecutor) generateExecutionId() string {
 //   - 后续请求：等待第一个完成，共享结果
 //   - 节省 90%+ 重复编译
 func (e *JSExecutor) getCompiledCode(code string) (*goja.Program, error) {
-	codeHash := hashCode(code)
+	program, _, err := e.getCompiledCodeCached(code)
+	return program, err
+}
+
+// compiledProgram singleflight 的共享结果（cacheHit：是否命中编译缓存）
+type compiledProgram struct {
+	program  *goja.Program
+	cacheHit bool
+}
+
+// getCompiledCodeCached 同 getCompiledCode，额外返回是否命中编译缓存
+// 等待其他请求编译（singleflight 共享）时与该请求的命中情况一致
+func (e *JSExecutor) getCompiledCodeCached(code string) (*goja.Program, bool, error) {
+	codeHash := HashCode(code)
 
 	// 🔥 使用 singleflight 防止缓存穿透
 	// Do() 会确保相同 key 只执行一次，其他请求等待并共享结果
@@ -2353,7 +1872,7 @@ func (e *JSExecutor) getCompiledCode(code string) (*goja.Program, error) {
 		e.codeCacheMutex.RLock()
 		if program, found := e.codeCache.Get(codeHash); found {
 			e.codeCacheMutex.RUnlock()
-			return program, nil
+			return &compiledProgram{program: program, cacheHit: true}, nil
 		}
 		e.codeCacheMutex.RUnlock()
 
@@ -2369,26 +1888,27 @@ func (e *JSExecutor) getCompiledCode(code string) (*goja.Program, error) {
 		e.codeCacheMutex.Unlock()
 
 		if evicted {
-			utils.Debug("代码编译缓存已满，驱逐最久未使用的程序")
+			e.logger.Debug("代码编译缓存已满，驱逐最久未使用的程序")
 		}
 
-		return program, nil
+		return &compiledProgram{program: program}, nil
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	// 可选：记录共享统计（调试用）
 	if shared {
-		utils.Debug("代码编译结果共享（避免重复编译）",
+		e.logger.Debug("代码编译结果共享（避免重复编译）",
 			zap.String("code_hash", codeHash[:16]))
 	}
 
-	return result.(*goja.Program), nil
+	compiled := result.(*compiledProgram)
+	return compiled.program, compiled.cacheHit, nil
 }
 
-// hashCode 使用 xxHash 计算代码哈希
+// HashCode 使用 xxHash 计算代码哈希
 //
 // 🔥 性能优化：使用 xxHash（比 SHA256 快 20 倍，碰撞概率 2^-64 对缓存足够）
 //   - 在实际使用中，碰撞几乎不可能发生
@@ -2404,7 +1924,7 @@ func (e *JSExecutor) getCompiledCode(code string) (*goja.Program, error) {
 //   - 优点：避免 slice bounds out of range 错误
 //   - 示例：短代码 → "000a1b2c3d4e5f67"
 //   - 示例：长代码 → "a3f5c8d9e2b14c7f"
-func hashCode(code string) string {
+func HashCode(code string) string {
 	h := xxhash.Sum64String(code)
 	return fmt.Sprintf("%016x", h) // 固定 16 字符十六进制，左侧零填充
 }
@@ -2418,14 +1938,14 @@ func (e *JSExecutor) startHealthChecker() {
 		ticker := time.NewTicker(e.healthCheckInterval)
 		defer ticker.Stop()
 
-		utils.Info("运行时健康检查器已启动", zap.Duration("interval", e.healthCheckInterval))
+		e.logger.Info("运行时健康检查器已启动", zap.Duration("interval", e.healthCheckInterval))
 
 		for {
 			select {
 			case <-ticker.C:
 				e.checkAndFixRuntimes()
 			case <-e.shutdown:
-				utils.Info("运行时健康检查器已停止")
+				e.logger.Info("运行时健康检查器已停止")
 				return
 			}
 		}
@@ -2447,6 +1967,9 @@ func (e *JSExecutor) checkAndFixRuntimes() {
 	// 🔥 阶段 4: 池大小调整（细粒度锁）
 	e.adjustPoolSize(analysis)
 
+	// 🆕 阶段 5: EventLoop 池（相同的修复和伸缩策略）
+	e.eventLoopPool.checkHealth()
+
 	// 注：不需要定期补充，因为销毁时实时补充已足够
 }
 
@@ -2476,7 +1999,7 @@ func (e *JSExecutor) replenishOneRuntime() {
 	// 检查是否超过最大池大小（安全保护）
 	currentSize := atomic.LoadInt32(&e.currentPoolSize)
 	if int(currentSize) >= e.maxPoolSize {
-		utils.Debug("已达最大池大小，跳过补充",
+		e.logger.Debug("已达最大池大小，跳过补充",
 			zap.Int32("current_size", currentSize),
 			zap.Int("max_pool_size", e.maxPoolSize))
 		return
@@ -2485,7 +2008,7 @@ func (e *JSExecutor) replenishOneRuntime() {
 	// 创建新Runtime
 	rt := goja.New()
 	if err := e.setupRuntime(rt); err != nil {
-		utils.Error("补充Runtime失败", zap.Error(err))
+		e.logger.Error("补充Runtime失败", zap.Error(err))
 		return
 	}
 
@@ -2504,7 +2027,7 @@ func (e *JSExecutor) replenishOneRuntime() {
 	select {
 	case e.runtimePool <- rt:
 		atomic.AddInt32(&e.currentPoolSize, 1)
-		utils.Debug("实时补充Runtime成功",
+		e.logger.Debug("实时补充Runtime成功",
 			zap.Int("pool_count", len(e.runtimePool)),
 			zap.Int32("current_size", atomic.LoadInt32(&e.currentPoolSize)))
 	default:
@@ -2512,14 +2035,14 @@ func (e *JSExecutor) replenishOneRuntime() {
 		e.healthMutex.Lock()
 		delete(e.runtimeHealth, rt)
 		e.healthMutex.Unlock()
-		utils.Debug("补充时池已满，跳过",
+		e.logger.Debug("补充时池已满，跳过",
 			zap.Int("pool_count", len(e.runtimePool)))
 	}
 }
 
 // Shutdown 优雅关闭执行器
 func (e *JSExecutor) Shutdown() {
-	utils.Info("正在关闭 JavaScript 执行器")
+	e.logger.Info("正在关闭 JavaScript 执行器")
 
 	// 1. 停止接收新任务
 	close(e.shutdown)
@@ -2530,7 +2053,7 @@ func (e *JSExecutor) Shutdown() {
 	// 🔥 3. 关闭所有模块（释放资源）
 	// Graceful Shutdown 支持：显式关闭 HTTP 连接等资源
 	if err := e.moduleRegistry.CloseAll(); err != nil {
-		utils.Warn("关闭模块时出现错误", zap.Error(err))
+		e.logger.Warn("关闭模块时出现错误", zap.Error(err))
 	}
 
 	// 4. 关闭 Runtime 池
@@ -2539,7 +2062,10 @@ func (e *JSExecutor) Shutdown() {
 		_ = runtime
 	}
 
-	utils.Info("JavaScript 执行器已关闭")
+	// 🆕 5. 释放 EventLoop 池（取消残留的定时器）
+	e.eventLoopPool.shutdown()
+
+	e.logger.Info("JavaScript 执行器已关闭")
 }
 
 // ============================================================================
@@ -2678,7 +2204,7 @@ func (e *JSExecutor) analyzeRuntimeHealth(snapshot map[*goja.Runtime]*runtimeHea
 		if errorCount > int64(e.minErrorCountForCheck) && executionCount > 0 {
 			errorRate := float64(errorCount) / float64(executionCount)
 			if errorRate > e.maxErrorRateThreshold {
-				utils.Warn("检测到高错误率运行时",
+				e.logger.Warn("检测到高错误率运行时",
 					zap.Float64("error_rate_percent", errorRate*100),
 					zap.Int64("execution_count", executionCount),
 					zap.Int64("error_count", errorCount))
@@ -2697,7 +2223,7 @@ func (e *JSExecutor) analyzeRuntimeHealth(snapshot map[*goja.Runtime]*runtimeHea
 
 		// 统计长期运行的 Runtime（异步日志，避免阻塞）
 		if now.Sub(createdAt) > e.longRunningThreshold && executionCount > int64(e.minExecutionCountForStats) {
-			go utils.Debug("检测到长期运行的运行时",
+			go e.logger.Debug("检测到长期运行的运行时",
 				zap.Time("created_at", createdAt),
 				zap.Int64("execution_count", executionCount))
 		}
@@ -2712,7 +2238,7 @@ func (e *JSExecutor) rebuildRuntimeSafe(oldRuntime *goja.Runtime) {
 	// 🔥 在锁外创建新的 Runtime（耗时操作 50-100ms）
 	newRuntime := goja.New()
 	if err := e.setupRuntime(newRuntime); err != nil {
-		utils.Error("重建运行时失败", zap.Error(err))
+		e.logger.Error("重建运行时失败", zap.Error(err))
 		// 保留旧的 Runtime，不进行替换
 		return
 	}
@@ -2733,9 +2259,9 @@ func (e *JSExecutor) rebuildRuntimeSafe(oldRuntime *goja.Runtime) {
 	// 🔥 放回池中（不需要 healthMutex）
 	select {
 	case e.runtimePool <- newRuntime:
-		utils.Debug("运行时重建完成并已放回池中")
+		e.logger.Debug("运行时重建完成并已放回池中")
 	default:
-		utils.Warn("运行时池已满，新运行时将被丢弃")
+		e.logger.Warn("运行时池已满，新运行时将被丢弃")
 	}
 }
 
@@ -2744,7 +2270,7 @@ func (e *JSExecutor) rebuildRuntimeSafe(oldRuntime *goja.Runtime) {
 func (e *JSExecutor) applyHealthFixes(analysis *healthAnalysis) {
 	// 重建问题 Runtime
 	for _, rt := range analysis.problemRuntimes {
-		utils.Debug("重建高错误率运行时")
+		e.logger.Debug("重建高错误率运行时")
 		e.rebuildRuntimeSafe(rt)
 	}
 
@@ -2754,7 +2280,7 @@ func (e *JSExecutor) applyHealthFixes(analysis *healthAnalysis) {
 	}
 
 	if len(analysis.problemRuntimes) > 0 {
-		utils.Info("健康修复已应用", zap.Int("rebuilt_runtimes", len(analysis.problemRuntimes)))
+		e.logger.Info("健康修复已应用", zap.Int("rebuilt_runtimes", len(analysis.problemRuntimes)))
 	}
 }
 
@@ -2931,7 +2457,7 @@ func (e *JSExecutor) recordAdjustment(isExpand bool) {
 		copy(newLog, e.recentAdjustmentLog[validStart:])
 		e.recentAdjustmentLog = newLog
 
-		utils.Debug("清理过期调整记录",
+		e.logger.Debug("清理过期调整记录",
 			zap.Int("removed_count", validStart),
 			zap.Int("remaining_count", len(e.recentAdjustmentLog)))
 	}
@@ -2947,7 +2473,7 @@ func (e *JSExecutor) shrinkPool(analysis *healthAnalysis) {
 		e.adaptiveCooldownLock.RUnlock()
 
 		if timeSinceLastShrink < cooldown {
-			utils.Debug("跳过收缩（自适应冷却期内）",
+			e.logger.Debug("跳过收缩（自适应冷却期内）",
 				zap.Duration("cooldown", cooldown),
 				zap.Duration("time_since_last", timeSinceLastShrink),
 				zap.Int("recent_adjustments", len(e.recentAdjustmentLog)))
@@ -2957,7 +2483,7 @@ func (e *JSExecutor) shrinkPool(analysis *healthAnalysis) {
 
 	canRelease := analysis.calculateShrink()
 
-	utils.Debug("池收缩中",
+	e.logger.Debug("池收缩中",
 		zap.Int("current_size", analysis.currentSize),
 		zap.Int("min_size", analysis.minPoolSize),
 		zap.Int("idle_count", len(analysis.idleRuntimes)),
@@ -2984,7 +2510,7 @@ func (e *JSExecutor) shrinkPool(analysis *healthAnalysis) {
 	// 🔥 记录调整事件（用于自适应冷却时间计算）
 	e.recordAdjustment(false) // false = 收缩
 
-	utils.Info("池收缩完成",
+	e.logger.Info("池收缩完成",
 		zap.Int("released", released),
 		zap.Int32("current_pool_size", atomic.LoadInt32(&e.currentPoolSize)),
 		zap.Duration("adaptive_cooldown", cooldown))
@@ -3026,7 +2552,7 @@ func (e *JSExecutor) adjustPoolSize(analysis *healthAnalysis) {
 		return
 	}
 
-	utils.Debug("池扩展中",
+	e.logger.Debug("池扩展中",
 		zap.Int("current_size", analysis.currentSize), zap.Int("available_slots", analysis.availableSlots), zap.Int("plan_to_add", toAdd))
 
 	// 🔥 在循环外批量创建（无锁，耗时操作）
@@ -3034,7 +2560,7 @@ func (e *JSExecutor) adjustPoolSize(analysis *healthAnalysis) {
 	for i := 0; i < toAdd; i++ {
 		rt := goja.New()
 		if err := e.setupRuntime(rt); err != nil {
-			utils.Error("扩展池时创建运行时失败", zap.Error(err))
+			e.logger.Error("扩展池时创建运行时失败", zap.Error(err))
 			continue // 跳过这个失败的 Runtime，继续创建其他的
 		}
 		newRuntimes = append(newRuntimes, rt)
@@ -3075,7 +2601,7 @@ AddLoop:
 			atomic.AddInt32(&e.currentPoolSize, 1)
 			added++
 		default:
-			utils.Warn("运行时池已满，停止扩展")
+			e.logger.Warn("运行时池已满，停止扩展")
 			break AddLoop
 		}
 	}
@@ -3085,7 +2611,7 @@ AddLoop:
 		e.recordAdjustment(true) // true = 扩展
 	}
 
-	utils.Info("池扩展完成",
+	e.logger.Info("池扩展完成",
 		zap.Int("added", added),
 		zap.Int32("current_pool_size", atomic.LoadInt32(&e.currentPoolSize)))
 }
@@ -3094,27 +2620,6 @@ AddLoop:
 // 🔥 错误定位辅助函数
 // ============================================================================
 
-// findPatternInOriginalCode 在原始代码中查找模式的位置
-// 参数:
-//   - originalCode: 原始代码（包含注释和字符串）
-//   - cleanedCode: 清理后的代码（去除了注释和字符串）
-//   - cleanedIndex: 模式在 cleanedCode 中的索引位置
-//   - pattern: 要查找的模式字符串
-//
-// 返回: lineNum, colNum, lineContent
-func (e *JSExecutor) findPatternInOriginalCode(originalCode, cleanedCode string, cleanedIndex int, pattern string) (int, int, string) {
-	// 直接在原始代码中搜索模式
-	// 因为危险模式通常不会出现在字符串或注释中（如果出现也应该被检测）
-	idx := strings.Index(originalCode, pattern)
-	if idx == -1 {
-		// 如果找不到，使用 cleanedCode 的位置作为近似值
-		return e.findLineAndColumn(cleanedCode, cleanedIndex)
-	}
-
-	// 在原始代码中计算行号和列号
-	return e.findLineAndColumn(originalCode, idx)
-}
-
 // findLineAndColumn 根据字符索引查找行号、列号和该行内容
 // 返回值: lineNum (从1开始), colNum (从1开始), lineContent
 func (e *JSExecutor) findLineAndColumn(code string, index int) (int, int, string) {
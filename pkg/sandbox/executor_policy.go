@@ -0,0 +1,230 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"flow-codeblock-go/enhance_modules"
+	"flow-codeblock-go/model/execmodel"
+	"flow-codeblock-go/utils"
+
+	"github.com/dop251/goja"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// networkModules 需要网络权限的模块（网络被策略禁用时不可 require）
+var networkModules = map[string]bool{
+	"axios": true,
+}
+
+// executionLimits 单次执行生效的限制（全局配置 + 沙箱策略）
+//
+// 🔥 无策略的请求共用 e.defaultLimits；有策略时按策略覆盖后生成新的实例（只读，不修改）
+type executionLimits struct {
+	timeout        time.Duration
+	maxResultSize  int
+	maxInputSize   int
+	consoleMode    string
+	allowedModules map[string]bool // nil 表示允许全部已注册模块
+	networkEnabled bool
+	egress         *enhance_modules.EgressPolicy // 出站规则（nil 表示不限制出站目标）
+
+	// 验证缓存键后缀：静态校验结果依赖 console / 模块 / 网络限制，不同限制的结果分开缓存
+	validationKey string
+}
+
+// WithSandboxPolicy 把沙箱策略放入 context，Execute 据此确定本次执行的限制（policy 为 nil 时原样返回）
+func WithSandboxPolicy(ctx context.Context, policy *execmodel.SandboxPolicy) context.Context {
+	if policy == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, utils.SandboxPolicyKey, policy)
+}
+
+// SandboxPolicyFromContext 从 context 中取出沙箱策略（未设置时返回 nil）
+func SandboxPolicyFromContext(ctx context.Context) *execmodel.SandboxPolicy {
+	if ctx == nil {
+		return nil
+	}
+	policy, _ := ctx.Value(utils.SandboxPolicyKey).(*execmodel.SandboxPolicy)
+	return policy
+}
+
+// newDefaultExecutionLimits 使用全局配置构建默认限制
+func (e *JSExecutor) newDefaultExecutionLimits() *executionLimits {
+	return &executionLimits{
+		timeout:        e.executionTimeout,
+		maxResultSize:  e.maxResultSize,
+		maxInputSize:   e.maxInputSize,
+		consoleMode:    e.consoleMode,
+		networkEnabled: true,
+	}
+}
+
+// limitsFromContext 解析本次执行的限制
+// 🆕 设置了 console 监听器（流式接口）时 stdout 模式按 capture 处理，输出逐条推送给监听器；disabled 模式仍禁止 console
+func (e *JSExecutor) limitsFromContext(ctx context.Context) *executionLimits {
+	limits := e.policyLimits(ctx)
+	if limits.consoleMode == ConsoleModeStdout && consoleListenerFromContext(ctx) != nil {
+		streaming := *limits
+		streaming.consoleMode = ConsoleModeCapture
+		return &streaming
+	}
+	return limits
+}
+
+// policyLimits 策略中已设置的字段覆盖全局配置
+func (e *JSExecutor) policyLimits(ctx context.Context) *executionLimits {
+	policy := SandboxPolicyFromContext(ctx)
+	if policy == nil {
+		return e.defaultLimits
+	}
+
+	limits := *e.defaultLimits
+	if policy.ExecutionTimeoutMs != nil {
+		limits.timeout = time.Duration(*policy.ExecutionTimeoutMs) * time.Millisecond
+	}
+	if policy.MaxResultSize != nil {
+		limits.maxResultSize = *policy.MaxResultSize
+	}
+	if policy.MaxInputSize != nil {
+		limits.maxInputSize = *policy.MaxInputSize
+	}
+	if policy.ConsoleMode != "" {
+		limits.consoleMode = policy.ConsoleMode
+	}
+	limits.networkEnabled = policy.NetworkEnabled()
+	if policy.Network != nil && policy.Network.Egress != nil {
+		egress, err := enhance_modules.ParseEgressRules(policy.Network.Egress)
+		if err != nil {
+			// 🔒 规则在保存时已校验，这里解析失败说明数据异常，按禁止所有出站请求处理
+			e.logger.Error("出站规则解析失败，禁止所有出站请求", zap.Error(err))
+			egress, _ = enhance_modules.ParseEgressRules(nil)
+		}
+		limits.egress = egress
+	}
+
+	var modules []string
+	if policy.AllowedModules != nil {
+		limits.allowedModules = make(map[string]bool, len(policy.AllowedModules))
+		for _, name := range policy.AllowedModules {
+			name = ModuleBaseName(name)
+			limits.allowedModules[name] = true
+			modules = append(modules, name)
+		}
+		sort.Strings(modules)
+	}
+
+	limits.validationKey = fmt.Sprintf("|console=%t|network=%t|modules=%t:%s",
+		limits.consoleMode != ConsoleModeDisabled, limits.networkEnabled,
+		limits.allowedModules != nil, strings.Join(modules, ","))
+	return &limits
+}
+
+// ModuleBaseName 归一化 require 目标：去掉 node: 前缀和子路径（如 node:fs/promises → fs）
+func ModuleBaseName(target string) string {
+	module := strings.TrimPrefix(strings.TrimSpace(target), "node:")
+	if slash := strings.IndexByte(module, '/'); slash != -1 {
+		module = module[:slash]
+	}
+	return module
+}
+
+// ProhibitedModule 模块是否被禁用（module 为 ModuleBaseName 归一化后的名称），禁用时返回原因
+func ProhibitedModule(module string) (reason string, prohibited bool) {
+	reason, prohibited = prohibitedModules[module]
+	return reason, prohibited
+}
+
+// moduleDenyReason 返回模块被策略拒绝的原因（允许时返回空字符串）
+func (l *executionLimits) moduleDenyReason(module string) string {
+	if !l.networkEnabled && networkModules[module] {
+		return fmt.Sprintf("模块 %s 需要网络权限，当前策略已禁用网络访问", module)
+	}
+	if l.allowedModules != nil && !l.allowedModules[module] {
+		return fmt.Sprintf("模块 %s 不在当前策略允许的模块列表中", module)
+	}
+	return ""
+}
+
+// restrictsRequire 是否需要拦截 require
+func (l *executionLimits) restrictsRequire() bool {
+	return l.allowedModules != nil || !l.networkEnabled
+}
+
+// applySandboxPolicy 在 Runtime 上安装本次执行的策略拦截
+//   - require：按模块允许列表和网络权限拦截（被拒绝时抛出 SecurityError）
+//   - fetch：网络被禁用时替换为直接抛出 SecurityError 的函数；
+//     否则替换为绑定本次出站上下文的 fetch（出站规则 + request_id + 链路追踪父 span + 执行取消，axios 底层同样使用全局 fetch）
+//
+// 返回的 restore 用于恢复被替换的全局变量（Runtime 池归还前调用；
+// EventLoop 池归还时由全局快照重置，无需调用）
+func (e *JSExecutor) applySandboxPolicy(ctx context.Context, runtime *goja.Runtime, limits *executionLimits, executionId string) (restore func()) {
+	saved := make(map[string]goja.Value, 2)
+	replace := func(name string, fn func(call goja.FunctionCall) goja.Value) {
+		saved[name] = runtime.Get(name)
+		runtime.Set(name, fn)
+	}
+
+	if originalRequire, ok := goja.AssertFunction(runtime.Get("require")); ok && limits.restrictsRequire() {
+		replace("require", func(call goja.FunctionCall) goja.Value {
+			module := ModuleBaseName(call.Argument(0).String())
+			if reason := limits.moduleDenyReason(module); reason != "" {
+				panic(newSecurityError(runtime, reason))
+			}
+			value, err := originalRequire(call.This, call.Arguments...)
+			if err != nil {
+				panic(err)
+			}
+			return value
+		})
+	}
+
+	if !limits.networkEnabled {
+		replace("fetch", func(call goja.FunctionCall) goja.Value {
+			panic(newSecurityError(runtime, "当前策略已禁用网络访问，不能使用 fetch"))
+		})
+	} else if e.fetchEnhancer != nil {
+		scope := &enhance_modules.EgressScope{
+			Policy:      limits.egress,
+			RequestID:   executionId,
+			TraceParent: trace.SpanContextFromContext(ctx),
+			Done:        ctx.Done(), // 🆕 执行被取消 / 终止时中止进行中的 fetch
+		}
+		if run := runningFromContext(ctx); run != nil {
+			scope.OnFetch = run.trackFetch // 🆕 记录进行中的 fetch（GET /flow/executions/running）
+		}
+		replace("fetch", e.fetchEnhancer.ScopedFetch(runtime, scope))
+	}
+
+	return func() {
+		for name, value := range saved {
+			runtime.Set(name, value)
+		}
+	}
+}
+
+// newSecurityError 创建 name 为 SecurityError 的 JS 错误对象（可被用户代码 catch）
+func newSecurityError(runtime *goja.Runtime, message string) *goja.Object {
+	errObj := runtime.NewGoError(errors.New(message))
+	errObj.Set("name", "SecurityError")
+	return errObj
+}
+
+// policyErrorType 返回异步执行失败时的错误类型：沙箱策略拦截的错误保留其 name，其余归为 RuntimeError
+func policyErrorType(errValue goja.Value) string {
+	if obj, ok := errValue.(*goja.Object); ok {
+		if name := obj.Get("name"); name != nil {
+			switch name.String() {
+			case "SecurityError", enhance_modules.EgressDeniedErrorName:
+				return name.String()
+			}
+		}
+	}
+	return "RuntimeError"
+}
@@ -0,0 +1,306 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/model/execmodel"
+	"flow-codeblock-go/utils"
+
+	"github.com/dop251/goja"
+	"github.com/google/pprof/profile"
+	"go.uber.org/zap"
+)
+
+// profileIntervalMs goja 性能分析的采样间隔（goja 内部固定为 10ms）
+const profileIntervalMs = 10
+
+// profileTopN 返回的热点函数 / 热点行数量
+const profileTopN = 20
+
+// profileMu goja 的性能分析是进程级的（StartProfile 对所有 Runtime 生效），同一时间只允许一次性能分析
+var profileMu sync.Mutex
+
+// profileSession 一次性能分析的上下文（通过 context 传给编译步骤）
+type profileSession struct {
+	sourceName string // 本次执行的源文件名（唯一），用于从进程级采样中筛选出本次执行
+	lineOffset int    // 包装代码在用户代码之前增加的行数（编译时按执行路由设置）
+}
+
+type profileSessionKey struct{}
+
+// profileSessionFromContext 获取 context 中的性能分析上下文（未在性能分析时返回 nil）
+func profileSessionFromContext(ctx context.Context) *profileSession {
+	session, _ := ctx.Value(profileSessionKey{}).(*profileSession)
+	return session
+}
+
+// compileForExecution 编译包装后的用户代码
+// 性能分析时使用唯一源文件名单独编译（不走编译缓存），其余情况走 getCompiledCode
+// 🔥 源文件名以 user_code.js 结尾，错误堆栈的行号调整（stackPatternFull）仍然生效
+// 🆕 同时记录 compile 阶段耗时和编译缓存是否命中（性能分析时始终为 miss）
+func (e *JSExecutor) compileForExecution(ctx context.Context, wrappedCode string, lineOffset int) (*goja.Program, error) {
+	compileStart := time.Now()
+	if session := profileSessionFromContext(ctx); session != nil {
+		session.lineOffset = lineOffset
+		program, err := goja.Compile(session.sourceName, wrappedCode, true)
+		timelineFromContext(ctx).Since(PhaseCompile, compileStart, PhaseCacheMiss)
+		return program, err
+	}
+	program, cacheHit, err := e.getCompiledCodeCached(wrappedCode)
+	timelineFromContext(ctx).Since(PhaseCompile, compileStart, cacheResult(cacheHit))
+	return program, err
+}
+
+// ExecuteWithProfile 在性能分析下执行代码（管理员诊断用）
+// 执行流程与 Execute 完全一致（熔断、公平调度、智能路由），额外返回本次执行的 JS 性能分析结果
+//
+// 说明：
+//   - goja 的采样是进程级的，性能分析期间其他执行也会被采样（有少量开销），结果只保留本次执行的调用栈
+//   - 同一时间只允许一次性能分析，已有性能分析进行中时返回 ProfilerBusyError
+//   - 执行失败时仍返回性能分析结果（可用于分析超时的脚本）
+func (e *JSExecutor) ExecuteWithProfile(ctx context.Context, code string, input map[string]interface{}) (*execmodel.ExecutionResult, *execmodel.ExecutionProfile, error) {
+	if !profileMu.TryLock() {
+		return nil, nil, &execmodel.ExecutionError{
+			Type:    "ProfilerBusyError",
+			Message: "已有脚本正在进行性能分析，请稍后重试",
+		}
+	}
+	defer profileMu.Unlock()
+
+	profileID, _ := ctx.Value(utils.RequestIDKey).(string)
+	if profileID == "" {
+		profileID = e.generateExecutionId()
+	}
+	session := &profileSession{sourceName: fmt.Sprintf("profile/%s/user_code.js", profileID)}
+
+	var buf bytes.Buffer
+	if err := goja.StartProfile(&buf); err != nil {
+		return nil, nil, &execmodel.ExecutionError{
+			Type:    "ProfilerBusyError",
+			Message: fmt.Sprintf("启动性能分析失败: %v", err),
+		}
+	}
+	result, execErr := e.Execute(context.WithValue(ctx, profileSessionKey{}, session), code, input)
+	goja.StopProfile()
+
+	prof, err := buildExecutionProfile(buf.Bytes(), session)
+	if err != nil {
+		e.logger.Warn("性能分析结果解析失败", zap.String("request_id", profileID), zap.Error(err))
+		return result, nil, execErr
+	}
+	return result, prof, execErr
+}
+
+// buildExecutionProfile 从 goja 输出的 pprof 数据中筛选本次执行的采样，生成摘要和 pprof
+func buildExecutionProfile(data []byte, session *profileSession) (*execmodel.ExecutionProfile, error) {
+	p, err := profile.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 只保留调用栈中包含本次执行源文件的采样（其他执行的采样丢弃）
+	kept := p.Sample[:0]
+	for _, sample := range p.Sample {
+		if sampleInSource(sample, session.sourceName) {
+			kept = append(kept, sample)
+		}
+	}
+	p.Sample = kept
+
+	// 2. 用户代码换回 user_code.js，行号换算为用户代码行号（与错误堆栈一致）
+	for _, fn := range p.Function {
+		if fn.Filename == session.sourceName {
+			fn.Filename = "user_code.js"
+		}
+	}
+	for _, loc := range p.Location {
+		for i := range loc.Line {
+			if loc.Line[i].Function != nil && loc.Line[i].Function.Filename == "user_code.js" {
+				loc.Line[i].Line = max(loc.Line[i].Line-int64(session.lineOffset), 1)
+			}
+		}
+	}
+	p = p.Compact()
+
+	var out bytes.Buffer
+	if err := p.Write(&out); err != nil {
+		return nil, err
+	}
+
+	prof := summarizeProfile(p)
+	prof.Pprof = base64.StdEncoding.EncodeToString(out.Bytes())
+	return prof, nil
+}
+
+// sampleInSource 采样的调用栈是否包含指定源文件的帧
+func sampleInSource(sample *profile.Sample, sourceName string) bool {
+	for _, loc := range sample.Location {
+		for _, line := range loc.Line {
+			if line.Function != nil && line.Function.Filename == sourceName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flameBuilder 构建火焰图时的中间节点
+type flameBuilder struct {
+	frame    *execmodel.ProfileFrame
+	children map[string]*flameBuilder
+}
+
+func (b *flameBuilder) child(name string) *flameBuilder {
+	if b.children == nil {
+		b.children = make(map[string]*flameBuilder)
+	}
+	c, ok := b.children[name]
+	if !ok {
+		c = &flameBuilder{frame: &execmodel.ProfileFrame{Name: name}}
+		b.children[name] = c
+	}
+	return c
+}
+
+// build 转换为输出结构（子节点按耗时降序）
+func (b *flameBuilder) build() *execmodel.ProfileFrame {
+	for _, c := range b.children {
+		b.frame.Children = append(b.frame.Children, c.build())
+	}
+	sort.Slice(b.frame.Children, func(i, j int) bool {
+		if b.frame.Children[i].Value != b.frame.Children[j].Value {
+			return b.frame.Children[i].Value > b.frame.Children[j].Value
+		}
+		return b.frame.Children[i].Name < b.frame.Children[j].Name
+	})
+	return b.frame
+}
+
+// summarizeProfile 生成火焰图、热点函数和热点行
+// goja 的 pprof 采样值：[0] 采样数，[1] 耗时（纳秒）；Location 从叶子（正在执行）到根
+func summarizeProfile(p *profile.Profile) *execmodel.ExecutionProfile {
+	type lineKey struct {
+		function, file string
+		line           int64
+	}
+
+	root := &flameBuilder{frame: &execmodel.ProfileFrame{Name: "all"}}
+	functions := make(map[string]*execmodel.ProfileFunctionStat)
+	lines := make(map[lineKey]*execmodel.ProfileLineStat)
+	var sampleCount, totalUs int64
+
+	for _, sample := range p.Sample {
+		if len(sample.Value) < 2 || len(sample.Location) == 0 {
+			continue
+		}
+		count, us := sample.Value[0], sample.Value[1]/1000
+		sampleCount += count
+		totalUs += us
+
+		node := root
+		node.frame.Value += us
+		node.frame.Samples += count
+		seen := make(map[string]bool, len(sample.Location))
+		for i := len(sample.Location) - 1; i >= 0; i-- {
+			line := firstLine(sample.Location[i])
+			if line.Function == nil {
+				continue
+			}
+			name := fmt.Sprintf("%s (%s)", line.Function.Name, line.Function.Filename)
+			node = node.child(name)
+			node.frame.Value += us
+			node.frame.Samples += count
+
+			stat, ok := functions[name]
+			if !ok {
+				stat = &execmodel.ProfileFunctionStat{Name: line.Function.Name, File: line.Function.Filename}
+				functions[name] = stat
+			}
+			if !seen[name] {
+				stat.TotalUs += us // 递归调用只计一次
+				seen[name] = true
+			}
+			if i == 0 {
+				stat.SelfUs += us
+				node.frame.Self += us
+
+				key := lineKey{line.Function.Name, line.Function.Filename, line.Line}
+				lineStat, ok := lines[key]
+				if !ok {
+					lineStat = &execmodel.ProfileLineStat{Function: key.function, File: key.file, Line: int(key.line)}
+					lines[key] = lineStat
+				}
+				lineStat.SelfUs += us
+				lineStat.Samples += count
+			}
+		}
+	}
+
+	prof := &execmodel.ExecutionProfile{
+		SampleCount:  sampleCount,
+		TotalTimeUs:  totalUs,
+		IntervalMs:   profileIntervalMs,
+		Flame:        root.build(),
+		TopFunctions: make([]execmodel.ProfileFunctionStat, 0, len(functions)),
+		HotLines:     make([]execmodel.ProfileLineStat, 0, len(lines)),
+	}
+	for _, stat := range functions {
+		stat.SelfPercent = percentOf(stat.SelfUs, totalUs)
+		prof.TopFunctions = append(prof.TopFunctions, *stat)
+	}
+	sort.Slice(prof.TopFunctions, func(i, j int) bool {
+		a, b := prof.TopFunctions[i], prof.TopFunctions[j]
+		if a.SelfUs != b.SelfUs {
+			return a.SelfUs > b.SelfUs
+		}
+		if a.TotalUs != b.TotalUs {
+			return a.TotalUs > b.TotalUs
+		}
+		return a.Name < b.Name
+	})
+	if len(prof.TopFunctions) > profileTopN {
+		prof.TopFunctions = prof.TopFunctions[:profileTopN]
+	}
+
+	for _, stat := range lines {
+		stat.SelfPercent = percentOf(stat.SelfUs, totalUs)
+		prof.HotLines = append(prof.HotLines, *stat)
+	}
+	sort.Slice(prof.HotLines, func(i, j int) bool {
+		a, b := prof.HotLines[i], prof.HotLines[j]
+		if a.SelfUs != b.SelfUs {
+			return a.SelfUs > b.SelfUs
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	if len(prof.HotLines) > profileTopN {
+		prof.HotLines = prof.HotLines[:profileTopN]
+	}
+
+	return prof
+}
+
+// firstLine 取 Location 的第一行（goja 每个 Location 只有一行）
+func firstLine(loc *profile.Location) profile.Line {
+	if len(loc.Line) == 0 {
+		return profile.Line{}
+	}
+	return loc.Line[0]
+}
+
+// percentOf 计算百分比（保留两位小数）
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part*10000/total) / 100
+}
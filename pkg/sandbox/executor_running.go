@@ -0,0 +1,246 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"flow-codeblock-go/model/execmodel"
+	"flow-codeblock-go/utils"
+
+	"github.com/dop251/goja"
+	"go.uber.org/zap"
+)
+
+// 执行路径（RunningExecution.Route）
+const (
+	RunningRoutePool      = "pool"      // Runtime 池（同步代码）
+	RunningRouteEventLoop = "eventloop" // EventLoop（异步代码）
+)
+
+// errExecutionKilled 执行被管理员终止时 context 的取消原因
+var errExecutionKilled = errors.New("execution killed by admin")
+
+// executionIDPrefix 执行 ID 前缀（执行器内部分配，与客户端可指定的 request_id 无关）
+const executionIDPrefix = "exec-"
+
+// ErrExecutionNotRunning 终止的执行不存在（已结束或仍在排队）
+var ErrExecutionNotRunning = errors.New("执行不存在或已结束")
+
+// runningExecution 正在执行的代码
+// 🆕 管理员可查看和终止：终止时中断 Runtime 并取消执行 context（进行中的 fetch 随之中止）
+type runningExecution struct {
+	id        string // 执行器分配的执行 ID（登记表的键，终止时使用）
+	requestID string
+	token     string
+	wsID      string
+	codeHash  string
+	route     string
+	startedAt time.Time
+	cancel    context.CancelCauseFunc
+
+	mu       sync.Mutex
+	runtime  *goja.Runtime // 获取到 Runtime / EventLoop 后设置
+	killed   bool
+	fetchSeq uint64
+	fetches  map[uint64]runningFetch
+}
+
+// runningFetch 进行中的出站请求
+type runningFetch struct {
+	method    string
+	url       string
+	startedAt time.Time
+}
+
+type runningExecutionKey struct{}
+
+// runningFromContext 获取 context 中的执行记录（未登记时返回 nil，nil 上的操作为空操作）
+func runningFromContext(ctx context.Context) *runningExecution {
+	run, _ := ctx.Value(runningExecutionKey{}).(*runningExecution)
+	return run
+}
+
+// setRuntime 记录执行所用的 Runtime（终止时中断它）
+// 🔒 Runtime 归还池之前必须以 nil 调用，避免终止时中断到其他执行复用的 Runtime
+func (r *runningExecution) setRuntime(runtime *goja.Runtime) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runtime = runtime
+	if r.killed && runtime != nil {
+		runtime.Interrupt("execution killed")
+	}
+}
+
+// trackFetch 记录进行中的出站请求（EgressScope.OnFetch）
+func (r *runningExecution) trackFetch(method, url string) (finish func()) {
+	r.mu.Lock()
+	r.fetchSeq++
+	id := r.fetchSeq
+	if r.fetches == nil {
+		r.fetches = make(map[uint64]runningFetch)
+	}
+	r.fetches[id] = runningFetch{method: method, url: url, startedAt: time.Now()}
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		delete(r.fetches, id)
+		r.mu.Unlock()
+	}
+}
+
+// isKilled 是否已被管理员终止
+func (r *runningExecution) isKilled() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.killed
+}
+
+// kill 中断 Runtime 并取消执行 context
+func (r *runningExecution) kill() {
+	r.mu.Lock()
+	r.killed = true
+	if r.runtime != nil {
+		r.runtime.Interrupt("execution killed")
+	}
+	r.mu.Unlock()
+	r.cancel(errExecutionKilled)
+}
+
+// snapshot 转换为接口输出
+func (r *runningExecution) snapshot(now time.Time) execmodel.RunningExecution {
+	out := execmodel.RunningExecution{
+		ExecutionID: r.id,
+		RequestID:   r.requestID,
+		WsID:        r.wsID,
+		CodeHash:    r.codeHash,
+		Route:       r.route,
+		StartedAt:   utils.FormatTime(r.startedAt),
+		ElapsedMs:   now.Sub(r.startedAt).Milliseconds(),
+		Fetches:     []execmodel.RunningFetch{},
+	}
+	if r.token != "" {
+		out.Token = utils.MaskToken(r.token)
+	}
+
+	r.mu.Lock()
+	ids := make([]uint64, 0, len(r.fetches))
+	for id := range r.fetches {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		fetch := r.fetches[id]
+		out.Fetches = append(out.Fetches, execmodel.RunningFetch{
+			Method:    fetch.method,
+			URL:       fetch.url,
+			StartedAt: utils.FormatTime(fetch.startedAt),
+			ElapsedMs: now.Sub(fetch.startedAt).Milliseconds(),
+		})
+	}
+	r.mu.Unlock()
+	return out
+}
+
+// runningRegistry 正在执行的代码（按执行 ID 索引）
+// 🔥 request_id 可由客户端通过 X-Request-ID 指定，并发执行可能重复，因此不能作为键
+type runningRegistry struct {
+	mu         sync.RWMutex
+	seq        atomic.Uint64
+	executions map[string]*runningExecution
+}
+
+func newRunningRegistry() *runningRegistry {
+	return &runningRegistry{executions: make(map[string]*runningExecution)}
+}
+
+// register 登记一次执行，返回可被终止的 context 和结束时的注销函数
+func (reg *runningRegistry) register(ctx context.Context, requestID, code, route string) (context.Context, *runningExecution, func()) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	tenant := executionTenantFromContext(ctx)
+	run := &runningExecution{
+		id:        executionIDPrefix + strconv.FormatUint(reg.seq.Add(1), 10),
+		requestID: requestID,
+		token:     tenant.token,
+		wsID:      tenant.wsID,
+		codeHash:  HashCode(code),
+		route:     route,
+		startedAt: time.Now(),
+		cancel:    cancel,
+	}
+
+	reg.mu.Lock()
+	reg.executions[run.id] = run
+	reg.mu.Unlock()
+
+	return context.WithValue(runCtx, runningExecutionKey{}, run), run, func() {
+		reg.mu.Lock()
+		delete(reg.executions, run.id)
+		reg.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// ListRunningExecutions 获取正在执行的代码（按开始时间排序，最早的在前）
+func (e *JSExecutor) ListRunningExecutions() []execmodel.RunningExecution {
+	e.running.mu.RLock()
+	runs := make([]*runningExecution, 0, len(e.running.executions))
+	for _, run := range e.running.executions {
+		runs = append(runs, run)
+	}
+	e.running.mu.RUnlock()
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].startedAt.Before(runs[j].startedAt) })
+	now := time.Now()
+	list := make([]execmodel.RunningExecution, 0, len(runs))
+	for _, run := range runs {
+		list = append(list, run.snapshot(now))
+	}
+	return list
+}
+
+// KillExecution 终止正在执行的代码（executionID 为 ListRunningExecutions 返回的 ExecutionID）
+// 中断 Runtime、取消执行 context（进行中的 fetch 随之中止），调用方收到 KilledError
+// 执行不存在（已结束或仍在排队）时返回 ErrExecutionNotRunning
+func (e *JSExecutor) KillExecution(executionID string) (*execmodel.RunningExecution, error) {
+	e.running.mu.RLock()
+	run, ok := e.running.executions[executionID]
+	e.running.mu.RUnlock()
+	if !ok {
+		return nil, ErrExecutionNotRunning
+	}
+
+	snapshot := run.snapshot(time.Now())
+	run.kill()
+	e.logger.Warn("执行已被管理员终止",
+		zap.String("execution_id", executionID),
+		zap.String("request_id", run.requestID),
+		zap.String("route", run.route),
+		zap.Int64("elapsed_ms", snapshot.ElapsedMs),
+		zap.Int("fetches_aborted", len(snapshot.Fetches)))
+	return &snapshot, nil
+}
+
+// killedError 被终止的执行返回给调用方的错误（保留终止前捕获的 console 输出）
+func killedError(cause error) *execmodel.ExecutionError {
+	killed := &execmodel.ExecutionError{
+		Type:    "KilledError",
+		Message: "执行已被管理员终止",
+	}
+	if execErr, ok := cause.(*execmodel.ExecutionError); ok {
+		killed.Logs = execErr.Logs
+		killed.LogsTruncated = execErr.LogsTruncated
+	}
+	return killed
+}
@@ -0,0 +1,49 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRunningRegistryKeepsDuplicateRequestIDs(t *testing.T) {
+	e := &JSExecutor{running: newRunningRegistry(), logger: zap.NewNop()}
+
+	// 客户端可以为并发请求指定相同的 X-Request-ID
+	firstCtx, _, unregisterFirst := e.running.register(context.Background(), "req-1", "return 1", RunningRoutePool)
+	secondCtx, _, unregisterSecond := e.running.register(context.Background(), "req-1", "return 2", RunningRouteEventLoop)
+	defer unregisterSecond()
+
+	list := e.ListRunningExecutions()
+	if len(list) != 2 {
+		t.Fatalf("len(list) = %d, want 2", len(list))
+	}
+	if list[0].ExecutionID == list[1].ExecutionID {
+		t.Fatalf("executionId 重复: %s", list[0].ExecutionID)
+	}
+
+	// 终止第一个执行，第二个不受影响
+	killed, err := e.KillExecution(list[0].ExecutionID)
+	if err != nil {
+		t.Fatalf("KillExecution: %v", err)
+	}
+	if killed.RequestID != "req-1" || killed.Route != RunningRoutePool {
+		t.Errorf("killed = %+v, want req-1 / pool", killed)
+	}
+	if !errors.Is(context.Cause(firstCtx), errExecutionKilled) {
+		t.Errorf("第一个执行的取消原因 = %v, want errExecutionKilled", context.Cause(firstCtx))
+	}
+	if secondCtx.Err() != nil {
+		t.Error("第二个执行不应被终止")
+	}
+
+	unregisterFirst()
+	if list := e.ListRunningExecutions(); len(list) != 1 || list[0].Route != RunningRouteEventLoop {
+		t.Fatalf("注销后 list = %+v, want 只剩第二个执行", list)
+	}
+	if _, err := e.KillExecution("req-1"); !errors.Is(err, ErrExecutionNotRunning) {
+		t.Errorf("按 request_id 终止 err = %v, want ErrExecutionNotRunning", err)
+	}
+}
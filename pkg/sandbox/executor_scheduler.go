@@ -0,0 +1,448 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/model/execmodel"
+	"flow-codeblock-go/utils"
+)
+
+const (
+	// MaxSchedulingWeight 调度权重上限
+	MaxSchedulingWeight = 100
+
+	// schedulerEWMAAlpha 平均执行/等待时长的平滑系数
+	schedulerEWMAAlpha = 0.2
+
+	// maxListedWaiters /flow/status 中每个分组最多列出的排队请求数
+	maxListedWaiters = 20
+
+	// anonymousTenant 没有 Token 信息的执行（内部调用）使用的分组
+	anonymousTenant = "anonymous"
+)
+
+// priorityMultipliers 优先级对应的权重倍数
+var priorityMultipliers = map[string]float64{
+	execmodel.PriorityClassHigh:   4,
+	execmodel.PriorityClassNormal: 2,
+	execmodel.PriorityClassLow:    1,
+}
+
+// executionTenant 调度身份（由 SandboxPolicyMiddleware 写入 context）
+type executionTenant struct {
+	token string
+	wsID  string
+}
+
+// WithExecutionTenant 把调度身份放入 context，公平调度器据此分组
+func WithExecutionTenant(ctx context.Context, token, wsID string) context.Context {
+	return context.WithValue(ctx, utils.ExecutionTenantKey, executionTenant{token: token, wsID: wsID})
+}
+
+// executionTenantFromContext 从 context 中取出调度身份（未设置时返回零值）
+func executionTenantFromContext(ctx context.Context) executionTenant {
+	tenant, _ := ctx.Value(utils.ExecutionTenantKey).(executionTenant)
+	return tenant
+}
+
+// schedTicket 单次执行的调度参数
+type schedTicket struct {
+	key           string // 分组键
+	label         string // 分组展示名（Token 脱敏）
+	weight        float64
+	priorityClass string
+	maxInFlight   int
+	requestID     string
+}
+
+// schedWaiter 排队中的请求
+type schedWaiter struct {
+	requestID  string
+	enqueuedAt time.Time
+	ready      chan struct{} // 获得槽位时关闭
+	granted    bool          // 受 fairScheduler.mu 保护
+}
+
+// schedTenant 分组的调度状态
+type schedTenant struct {
+	key           string
+	label         string
+	weight        float64
+	priorityClass string
+	maxInFlight   int
+	inFlight      int
+	queue         []*schedWaiter
+}
+
+// fairScheduler 加权公平调度器（替代全局 semaphore）
+//
+// 🔥 调度规则：
+//   - 全局最多 capacity 个并发执行（MAX_CONCURRENT_EXECUTIONS）
+//   - 槽位空闲时，从有排队请求且未达到 maxInFlight 的分组中选择 inFlight / weight 最小的分组
+//     （并列时选择队首等待最久的分组），分组内先进先出
+//   - 慢脚本长期占用槽位会抬高该分组的 inFlight，使其它分组优先获得槽位，
+//     因此每个分组至少能获得 weight / Σweight 比例的并发份额
+//   - 分组排队数达到 maxQueuePerTenant 时直接拒绝（QueueFullError，不触发熔断）
+//   - 等待超过 waitTimeout 时拒绝（ConcurrencyError，系统过载，触发熔断）
+type fairScheduler struct {
+	capacity           int
+	fairnessKey        string
+	defaultMaxInFlight int
+	maxQueuePerTenant  int
+	waitTimeout        time.Duration
+
+	mu       sync.Mutex
+	running  int
+	queued   int
+	tenants  map[string]*schedTenant
+	avgExec  float64 // 槽位平均占用时长（纳秒，EWMA）
+	avgWait  float64 // 平均排队时长（纳秒，EWMA，只统计排过队的请求）
+	rejected int64   // 因排队已满或等待超时被拒绝的请求数
+
+	waitLatency *utils.Histogram // 🆕 获得槽位前的等待时间（含未排队的 0 值），供 /metrics 导出
+}
+
+// newFairScheduler 创建公平调度器
+func newFairScheduler(cfg *Config) *fairScheduler {
+	return &fairScheduler{
+		capacity:           cfg.Executor.MaxConcurrent,
+		fairnessKey:        cfg.Executor.SchedulerFairnessKey,
+		defaultMaxInFlight: cfg.Executor.SchedulerDefaultMaxInFlight,
+		maxQueuePerTenant:  cfg.Executor.SchedulerMaxQueuePerTenant,
+		waitTimeout:        cfg.Executor.ConcurrencyWaitTimeout,
+		tenants:            make(map[string]*schedTenant),
+		waitLatency:        utils.NewHistogram(utils.DefaultLatencyBuckets),
+	}
+}
+
+// ticketFor 根据 context 中的调度身份和沙箱策略生成调度参数
+// 🔥 策略可能不经过 PolicyService 校验（嵌入方直接传入）：权重限制在 1-MaxSchedulingWeight，未知优先级按 normal 处理
+func (s *fairScheduler) ticketFor(ctx context.Context, policy *execmodel.SandboxPolicy) schedTicket {
+	ticket := schedTicket{
+		key:           anonymousTenant,
+		label:         anonymousTenant,
+		weight:        1,
+		priorityClass: execmodel.PriorityClassNormal,
+		maxInFlight:   s.defaultMaxInFlight,
+	}
+	if reqID, ok := ctx.Value(utils.RequestIDKey).(string); ok {
+		ticket.requestID = reqID
+	}
+
+	tenant := executionTenantFromContext(ctx)
+	switch {
+	case s.fairnessKey == SchedulerFairnessByWorkspace && tenant.wsID != "":
+		ticket.key, ticket.label = "ws:"+tenant.wsID, "ws:"+tenant.wsID
+	case tenant.token != "":
+		ticket.key, ticket.label = "token:"+tenant.token, "token:"+utils.MaskToken(tenant.token)
+	}
+
+	if policy != nil && policy.Scheduling != nil {
+		if policy.Scheduling.Weight != nil {
+			ticket.weight = math.Max(1, math.Min(float64(*policy.Scheduling.Weight), MaxSchedulingWeight))
+		}
+		if policy.Scheduling.MaxInFlight != nil {
+			ticket.maxInFlight = *policy.Scheduling.MaxInFlight
+		}
+		if _, ok := priorityMultipliers[policy.Scheduling.PriorityClass]; ok {
+			ticket.priorityClass = policy.Scheduling.PriorityClass
+		}
+	}
+	if ticket.maxInFlight <= 0 || ticket.maxInFlight > s.capacity {
+		ticket.maxInFlight = s.capacity
+	}
+	return ticket
+}
+
+// acquire 获取执行槽位，返回的 release 必须在执行结束后调用
+func (s *fairScheduler) acquire(ctx context.Context, ticket schedTicket) (release func(), err error) {
+	start := time.Now()
+	defer func() {
+		if err == nil {
+			s.waitLatency.ObserveDuration(time.Since(start))
+		}
+	}()
+
+	s.mu.Lock()
+	tenant := s.tenantLocked(ticket)
+	if s.queued > 0 {
+		// 调度参数变化（如 maxInFlight 调大）可能使排队请求变为可调度，先处理排队请求
+		s.dispatchLocked()
+	}
+
+	// 快速路径：有空闲槽位、分组未达上限且分组内没有更早的排队请求
+	// （dispatchLocked 保证空闲槽位不会留给可调度的排队请求）
+	if s.running < s.capacity && tenant.inFlight < tenant.maxInFlight && len(tenant.queue) == 0 {
+		s.running++
+		tenant.inFlight++
+		s.mu.Unlock()
+		return s.releaseFunc(tenant), nil
+	}
+
+	if len(tenant.queue) >= s.maxQueuePerTenant {
+		retryAfter := s.estimateWaitLocked(tenant)
+		s.rejected++
+		s.mu.Unlock()
+		return nil, &execmodel.ExecutionError{
+			Type:       "QueueFullError",
+			Message:    fmt.Sprintf("排队请求过多（上限 %d），请稍后重试", s.maxQueuePerTenant),
+			RetryAfter: retryAfter,
+		}
+	}
+
+	waiter := &schedWaiter{requestID: ticket.requestID, enqueuedAt: time.Now(), ready: make(chan struct{})}
+	tenant.queue = append(tenant.queue, waiter)
+	s.queued++
+	s.mu.Unlock()
+
+	timer := time.NewTimer(s.waitTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-waiter.ready:
+		return s.releaseFunc(tenant), nil
+	case <-ctx.Done():
+		if s.abandon(tenant, waiter) {
+			return s.releaseFunc(tenant), nil
+		}
+		return nil, &execmodel.ExecutionError{
+			Type:    "CancelledError",
+			Message: "请求已取消",
+		}
+	case <-timer.C:
+		if s.abandon(tenant, waiter) {
+			return s.releaseFunc(tenant), nil
+		}
+		s.mu.Lock()
+		retryAfter := s.estimateWaitLocked(tenant)
+		s.rejected++
+		s.mu.Unlock()
+		return nil, &execmodel.ExecutionError{
+			Type:       "ConcurrencyError",
+			Message:    fmt.Sprintf("系统繁忙，请稍后重试（等待超时: %v）", s.waitTimeout),
+			RetryAfter: retryAfter,
+		}
+	}
+}
+
+// abandon 放弃排队；返回 true 表示放弃前已获得槽位（调用方按获得槽位处理）
+func (s *fairScheduler) abandon(tenant *schedTenant, waiter *schedWaiter) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if waiter.granted {
+		return true
+	}
+	for i, w := range tenant.queue {
+		if w == waiter {
+			tenant.queue = append(tenant.queue[:i], tenant.queue[i+1:]...)
+			s.queued--
+			break
+		}
+	}
+	s.dropIdleLocked(tenant)
+	return false
+}
+
+// releaseFunc 返回释放槽位的函数（只生效一次）
+func (s *fairScheduler) releaseFunc(tenant *schedTenant) func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.running--
+			tenant.inFlight--
+			s.avgExec = ewma(s.avgExec, float64(time.Since(start)))
+			s.dispatchLocked()
+			s.dropIdleLocked(tenant)
+			s.mu.Unlock()
+		})
+	}
+}
+
+// tenantLocked 获取或创建分组，并用最新的调度参数更新（调用方持有锁）
+func (s *fairScheduler) tenantLocked(ticket schedTicket) *schedTenant {
+	tenant, ok := s.tenants[ticket.key]
+	if !ok {
+		tenant = &schedTenant{key: ticket.key, label: ticket.label}
+		s.tenants[ticket.key] = tenant
+	}
+	tenant.weight = ticket.weight * priorityMultipliers[ticket.priorityClass]
+	tenant.priorityClass = ticket.priorityClass
+	tenant.maxInFlight = ticket.maxInFlight
+	return tenant
+}
+
+// dispatchLocked 把空闲槽位分配给排队请求（调用方持有锁）
+func (s *fairScheduler) dispatchLocked() {
+	for s.running < s.capacity {
+		var best *schedTenant
+		for _, t := range s.tenants {
+			if len(t.queue) == 0 || t.inFlight >= t.maxInFlight {
+				continue
+			}
+			if best == nil || t.lessThan(best) {
+				best = t
+			}
+		}
+		if best == nil {
+			return
+		}
+
+		waiter := best.queue[0]
+		best.queue = best.queue[1:]
+		s.queued--
+		s.running++
+		best.inFlight++
+		waiter.granted = true
+		s.avgWait = ewma(s.avgWait, float64(time.Since(waiter.enqueuedAt)))
+		close(waiter.ready)
+	}
+}
+
+// lessThan 调度顺序：inFlight / weight 小者优先，并列时队首等待久者优先
+func (t *schedTenant) lessThan(other *schedTenant) bool {
+	a := float64(t.inFlight) / t.weight
+	b := float64(other.inFlight) / other.weight
+	if a != b {
+		return a < b
+	}
+	return t.queue[0].enqueuedAt.Before(other.queue[0].enqueuedAt)
+}
+
+// dropIdleLocked 移除空闲分组，避免分组表无限增长（调用方持有锁）
+func (s *fairScheduler) dropIdleLocked(tenant *schedTenant) {
+	if tenant.inFlight == 0 && len(tenant.queue) == 0 {
+		delete(s.tenants, tenant.key)
+	}
+}
+
+// estimateWaitLocked 估算分组新请求获得槽位的等待时间（用于 Retry-After，调用方持有锁）
+// 估算方式：分组排队数 / 分组可获得的并发份额 × 平均执行时长
+func (s *fairScheduler) estimateWaitLocked(tenant *schedTenant) time.Duration {
+	totalWeight := 0.0
+	for _, t := range s.tenants {
+		if t.inFlight > 0 || len(t.queue) > 0 || t == tenant {
+			totalWeight += t.weight
+		}
+	}
+	share := float64(s.capacity) * tenant.weight / totalWeight
+	share = math.Max(1, math.Min(share, float64(tenant.maxInFlight)))
+
+	avgExec := s.avgExec
+	if avgExec == 0 {
+		avgExec = float64(time.Second)
+	}
+	wait := time.Duration(math.Ceil(float64(len(tenant.queue)+1)/share) * avgExec)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
+// ewma 指数加权移动平均
+func ewma(avg, sample float64) float64 {
+	if avg == 0 {
+		return sample
+	}
+	return avg + schedulerEWMAAlpha*(sample-avg)
+}
+
+// ============================================================================
+// 调度状态（/flow/status）
+// ============================================================================
+
+// SchedulerStats 公平调度器状态
+type SchedulerStats struct {
+	Capacity      int                    `json:"capacity"`
+	Running       int                    `json:"running"`
+	Queued        int                    `json:"queued"`
+	Rejected      int64                  `json:"rejected"`
+	FairnessKey   string                 `json:"fairnessKey"`
+	AvgExecMs     int64                  `json:"avgExecMs"`
+	AvgWaitMs     int64                  `json:"avgWaitMs"`
+	MaxQueueSize  int                    `json:"maxQueuePerTenant"`
+	WaitTimeoutMs int64                  `json:"waitTimeoutMs"`
+	Tenants       []TenantSchedulerStats `json:"tenants"`
+}
+
+// TenantSchedulerStats 分组调度状态
+type TenantSchedulerStats struct {
+	Tenant        string              `json:"tenant"`
+	Weight        float64             `json:"weight"` // 有效权重（已乘优先级倍数）
+	PriorityClass string              `json:"priorityClass"`
+	MaxInFlight   int                 `json:"maxInFlight"`
+	InFlight      int                 `json:"inFlight"`
+	Queued        int                 `json:"queued"`
+	OldestWaitMs  int64               `json:"oldestWaitMs"`
+	Waiting       []QueuedRequestStat `json:"waiting,omitempty"` // 最多列出前 20 个
+}
+
+// QueuedRequestStat 排队中的请求
+type QueuedRequestStat struct {
+	Position  int    `json:"position"` // 分组内的排队位置（从 1 开始）
+	RequestID string `json:"requestId"`
+	WaitMs    int64  `json:"waitMs"`
+}
+
+// Stats 返回调度器当前状态（分组按排队数、并发数降序）
+func (s *fairScheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	stats := SchedulerStats{
+		Capacity:      s.capacity,
+		Running:       s.running,
+		Queued:        s.queued,
+		Rejected:      s.rejected,
+		FairnessKey:   s.fairnessKey,
+		AvgExecMs:     time.Duration(s.avgExec).Milliseconds(),
+		AvgWaitMs:     time.Duration(s.avgWait).Milliseconds(),
+		MaxQueueSize:  s.maxQueuePerTenant,
+		WaitTimeoutMs: s.waitTimeout.Milliseconds(),
+		Tenants:       make([]TenantSchedulerStats, 0, len(s.tenants)),
+	}
+
+	for _, t := range s.tenants {
+		ts := TenantSchedulerStats{
+			Tenant:        t.label,
+			Weight:        t.weight,
+			PriorityClass: t.priorityClass,
+			MaxInFlight:   t.maxInFlight,
+			InFlight:      t.inFlight,
+			Queued:        len(t.queue),
+		}
+		if len(t.queue) > 0 {
+			ts.OldestWaitMs = now.Sub(t.queue[0].enqueuedAt).Milliseconds()
+		}
+		for i, w := range t.queue {
+			if i >= maxListedWaiters {
+				break
+			}
+			ts.Waiting = append(ts.Waiting, QueuedRequestStat{
+				Position:  i + 1,
+				RequestID: w.requestID,
+				WaitMs:    now.Sub(w.enqueuedAt).Milliseconds(),
+			})
+		}
+		stats.Tenants = append(stats.Tenants, ts)
+	}
+
+	sort.Slice(stats.Tenants, func(i, j int) bool {
+		a, b := stats.Tenants[i], stats.Tenants[j]
+		if a.Queued != b.Queued {
+			return a.Queued > b.Queued
+		}
+		if a.InFlight != b.InFlight {
+			return a.InFlight > b.InFlight
+		}
+		return a.Tenant < b.Tenant
+	})
+	return stats
+}
@@ -0,0 +1,1054 @@
+package sandbox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"flow-codeblock-go/model/execmodel"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+)
+
+// ============================================================================
+// 🔥 AST 安全分析器（基于 goja 自带的解析器）
+// ============================================================================
+//
+// 设计要点：
+//   - 直接遍历真实语法树，字符串、注释、属性名、对象键不会被误判（如 myFunction()、obj.self）
+//   - 对字符串常量做折叠（拼接、模板字符串、join、fromCharCode、const 变量），识别 "constr" + "uctor" 之类的绕过写法
+//   - 循环的退出分析基于语句结构：嵌套函数中的 return、内层循环中的 break 不算作外层循环的退出
+//   - 每条发现都带有用户代码中的字节偏移，可精确换算为行列号
+//
+// 🔥 解析失败时拒绝执行（fail closed）：
+// 执行时用户代码被拼接进 try 块中编译，"} catch(e) {} })(), ... (function(){ try {" 之类的代码
+// 单独无法解析为函数体，拼接后却能闭合包装、在包装之外执行任意语句，因此不能跳过检查
+
+const (
+	// 用户代码允许顶层 return / await，解析时包装为（异步）函数体
+	securityWrapperAsyncPrefix = "(async function() {"
+	securityWrapperSyncPrefix  = "(function() {"
+	securityWrapperSuffix      = "\n})"
+
+	// 解析失败的规则标识
+	securityRuleParse = "parse"
+
+	// 常量折叠的最大递归深度（防止 const a = b; const b = a 之类的循环引用）
+	maxConstFoldDepth = 8
+)
+
+var (
+	// 被禁用的模块（require 目标，支持 node: 前缀和子路径，如 fs/promises）
+	prohibitedModules = map[string]string{
+		"fs":            "文件系统操作",
+		"path":          "路径操作",
+		"child_process": "子进程执行",
+		"os":            "操作系统接口",
+	}
+
+	// 全局对象标识符（可借此拿到 Function、eval 等全局属性）
+	// globalThis 在运行时仍然可用，始终检测；其余在运行时已禁用，用户自行声明同名变量时不报告
+	globalObjectNames = map[string]bool{
+		"globalThis": true,
+		"global":     false,
+		"window":     false,
+		"self":       false,
+	}
+
+	// 原型操作方法（Object.xxx）
+	prototypeMethods = map[string]string{
+		"getPrototypeOf": "原型获取操作被禁止",
+		"setPrototypeOf": "原型设置操作被禁止",
+		"create":         "Object.create可能导致原型污染",
+	}
+)
+
+// securityFinding AST 安全分析的一条发现
+type securityFinding struct {
+	rule    string // 规则标识（与校验报告中的 rule 一致）
+	message string // 不含位置信息的描述
+	start   int    // 用户代码中的起始字节偏移
+	end     int    // 用户代码中的结束字节偏移
+}
+
+// securityAnalyzer 遍历语法树收集安全发现
+type securityAnalyzer struct {
+	offset     int // 包装前缀长度（语法树位置 → 用户代码位置）
+	collecting bool
+	findings   []securityFinding
+	bindings   map[string][]ast.Expression // 变量名 → 赋值表达式（用于常量折叠）
+	declared   map[string]bool             // 用户代码中声明过的名称
+	limits     *executionLimits            // 🆕 沙箱策略限制（模块允许列表、网络权限）
+}
+
+// analyzeCodeSecurity 对（已归一化的）用户代码做 AST 安全分析，返回按位置排序的全部发现
+// 🆕 limits 中的模块允许列表和网络权限同样在这里静态检查
+func analyzeCodeSecurity(code string, limits *executionLimits) []securityFinding {
+	// Shebang 行替换为等长空格，保持字节偏移不变
+	if strings.HasPrefix(code, "#!") {
+		end := strings.IndexByte(code, '\n')
+		if end == -1 {
+			end = len(code)
+		}
+		code = strings.Repeat(" ", end) + code[end:]
+	}
+
+	// 优先按异步函数体解析（支持顶层 await）；await 被用作普通标识符时退回同步函数体
+	prefix := securityWrapperAsyncPrefix
+	program, err := parser.ParseFile(nil, "", prefix+code+securityWrapperSuffix, 0)
+	if err != nil {
+		prefix = securityWrapperSyncPrefix
+		wrapped := prefix + code + securityWrapperSuffix
+		program, err = parser.ParseFile(nil, "", wrapped, 0)
+		if err != nil {
+			return []securityFinding{parseFailureFinding(code, wrapped, len(prefix), err)}
+		}
+	}
+
+	a := &securityAnalyzer{
+		offset:   len(prefix),
+		bindings: make(map[string][]ast.Expression),
+		declared: make(map[string]bool),
+		limits:   limits,
+	}
+
+	// 第 1 遍：收集变量声明和赋值（常量折叠需要先知道变量的值）
+	a.collecting = true
+	a.walkStatements(program.Body)
+
+	// 第 2 遍：检查
+	a.collecting = false
+	a.walkStatements(program.Body)
+
+	// 区间末尾的空白不计入匹配内容（如 "while (true) {" 中循环体前的空格）
+	for i := range a.findings {
+		f := &a.findings[i]
+		for f.end > f.start && (code[f.end-1] == ' ' || code[f.end-1] == '\t' || code[f.end-1] == '\n' || code[f.end-1] == '\r') {
+			f.end--
+		}
+	}
+
+	sort.SliceStable(a.findings, func(i, j int) bool {
+		return a.findings[i].start < a.findings[j].start
+	})
+	return a.findings
+}
+
+// parseFailureFinding 代码无法解析为完整函数体时的发现（位置为解析错误在用户代码中的位置）
+func parseFailureFinding(code, wrapped string, offset int, err error) securityFinding {
+	message := err.Error()
+	pos := 0
+	if list, ok := err.(parser.ErrorList); ok && len(list) > 0 {
+		message = list[0].Message
+		pos = wrappedOffset(wrapped, list[0].Position.Line, list[0].Position.Column) - offset
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > len(code) {
+		pos = len(code)
+	}
+	end := pos
+	for end < len(code) && code[end] != '\n' {
+		end++
+	}
+	return securityFinding{
+		rule:    securityRuleParse,
+		message: "代码无法解析为完整的函数体，已拒绝执行: " + message,
+		start:   pos,
+		end:     end,
+	}
+}
+
+// wrappedOffset 行列号（从 1 开始，列为字符数）→ 字节偏移
+func wrappedOffset(src string, line, column int) int {
+	offset := 0
+	for line > 1 {
+		next := strings.IndexByte(src[offset:], '\n')
+		if next == -1 {
+			return len(src)
+		}
+		offset += next + 1
+		line--
+	}
+	for column > 1 && offset < len(src) && src[offset] != '\n' {
+		_, size := utf8.DecodeRuneInString(src[offset:])
+		offset += size
+		column--
+	}
+	return offset
+}
+
+// securityFindingError 把一条发现转换为 SecurityError（与其他校验错误的消息格式一致）
+func (e *JSExecutor) securityFindingError(code string, f securityFinding) error {
+	lineNum, colNum, lineContent := e.findLineAndColumn(code, f.start)
+	return &execmodel.ExecutionError{
+		Type: f.errorType(),
+		Message: fmt.Sprintf("%s\n位置: 第 %d 行，第 %d 列\n匹配内容: %s\n代码: %s",
+			f.message, lineNum, colNum, code[f.start:f.end], lineContent),
+	}
+}
+
+// errorType 发现对应的错误类型（解析失败报告为语法错误，其余为安全错误）
+func (f securityFinding) errorType() string {
+	if f.rule == securityRuleParse {
+		return "SyntaxError"
+	}
+	return "SecurityError"
+}
+
+// report 记录一条发现（idx0/idx1 为语法树中的位置）
+func (a *securityAnalyzer) report(rule, message string, idx0, idx1 file.Idx) {
+	if a.collecting {
+		return
+	}
+	start := int(idx0) - 1 - a.offset
+	end := int(idx1) - 1 - a.offset
+	if start < 0 || end <= start {
+		return
+	}
+	a.findings = append(a.findings, securityFinding{
+		rule:    rule,
+		message: message,
+		start:   start,
+		end:     end,
+	})
+}
+
+// ============================================================================
+// 语句遍历
+// ============================================================================
+
+func (a *securityAnalyzer) walkStatements(list []ast.Statement) {
+	for _, stmt := range list {
+		a.walkStatement(stmt)
+	}
+}
+
+func (a *securityAnalyzer) walkStatement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case nil:
+	case *ast.BlockStatement:
+		a.walkStatements(s.List)
+	case *ast.ExpressionStatement:
+		a.walkExpression(s.Expression)
+	case *ast.VariableStatement:
+		a.walkBindings(s.List)
+	case *ast.LexicalDeclaration:
+		a.walkBindings(s.List)
+	case *ast.FunctionDeclaration:
+		a.walkFunction(s.Function)
+	case *ast.ClassDeclaration:
+		a.walkClass(s.Class)
+	case *ast.IfStatement:
+		a.walkExpression(s.Test)
+		a.walkStatement(s.Consequent)
+		a.walkStatement(s.Alternate)
+	case *ast.ForStatement, *ast.WhileStatement, *ast.DoWhileStatement:
+		a.walkLoop(s, nil)
+	case *ast.ForInStatement:
+		a.walkForInto(s.Into)
+		a.walkExpression(s.Source)
+		a.walkStatement(s.Body)
+	case *ast.ForOfStatement:
+		a.walkForInto(s.Into)
+		a.walkExpression(s.Source)
+		a.walkStatement(s.Body)
+	case *ast.LabelledStatement:
+		// 收集连续的标签（a: b: while (true) {...}），break a / break b 都会退出该循环
+		labels := []string{s.Label.Name.String()}
+		inner := s.Statement
+		for {
+			labelled, ok := inner.(*ast.LabelledStatement)
+			if !ok {
+				break
+			}
+			labels = append(labels, labelled.Label.Name.String())
+			inner = labelled.Statement
+		}
+		switch inner.(type) {
+		case *ast.ForStatement, *ast.WhileStatement, *ast.DoWhileStatement:
+			a.walkLoop(inner, labels)
+		default:
+			a.walkStatement(inner)
+		}
+	case *ast.ReturnStatement:
+		a.walkExpression(s.Argument)
+	case *ast.ThrowStatement:
+		a.walkExpression(s.Argument)
+	case *ast.TryStatement:
+		a.walkStatement(s.Body)
+		if s.Catch != nil {
+			a.walkBindingTarget(s.Catch.Parameter)
+			a.walkStatement(s.Catch.Body)
+		}
+		if s.Finally != nil {
+			a.walkStatement(s.Finally)
+		}
+	case *ast.SwitchStatement:
+		a.walkExpression(s.Discriminant)
+		for _, c := range s.Body {
+			a.walkExpression(c.Test)
+			a.walkStatements(c.Consequent)
+		}
+	case *ast.WithStatement:
+		a.walkExpression(s.Object)
+		a.walkStatement(s.Body)
+	}
+}
+
+// walkLoop 遍历 for / while / do-while 循环，并检查无退出条件的无限循环
+func (a *securityAnalyzer) walkLoop(stmt ast.Statement, labels []string) {
+	var test ast.Expression
+	var body ast.Statement
+	var idx0, idx1 file.Idx
+	infinite := false
+
+	switch s := stmt.(type) {
+	case *ast.ForStatement:
+		a.walkForInitializer(s.Initializer)
+		a.walkExpression(s.Test)
+		a.walkExpression(s.Update)
+		test, body = s.Test, s.Body
+		idx0, idx1 = s.For, s.Body.Idx0()
+		infinite = test == nil || isConstantTruthy(test)
+	case *ast.WhileStatement:
+		a.walkExpression(s.Test)
+		test, body = s.Test, s.Body
+		idx0, idx1 = s.While, s.Body.Idx0()
+		infinite = isConstantTruthy(test)
+	case *ast.DoWhileStatement:
+		a.walkExpression(s.Test)
+		test, body = s.Test, s.Body
+		idx0, idx1 = s.Do, s.Do+2 // "do"
+		infinite = isConstantTruthy(test)
+	default:
+		return
+	}
+
+	a.walkStatement(body)
+
+	// 🔥 条件恒为真且循环体内没有 break / return / throw，判定为无限循环
+	// 常见合法模式：
+	// - while (true) { if (done) break; }       // 流式读取
+	// - for (;;) { if (count > 10) return x; }  // 条件退出
+	if infinite && !hasLoopExit(body, labels, nil, false) {
+		a.report("infinite_loop",
+			"代码可能包含无限循环，已被阻止执行。\n"+
+				"提示：如果使用 while(true) / while(1) / for(;;)，请确保包含 break 或 return 退出条件。",
+			idx0, idx1)
+	}
+}
+
+func (a *securityAnalyzer) walkForInitializer(init ast.ForLoopInitializer) {
+	switch i := init.(type) {
+	case *ast.ForLoopInitializerExpression:
+		a.walkExpression(i.Expression)
+	case *ast.ForLoopInitializerVarDeclList:
+		a.walkBindings(i.List)
+	case *ast.ForLoopInitializerLexicalDecl:
+		a.walkBindings(i.LexicalDeclaration.List)
+	}
+}
+
+func (a *securityAnalyzer) walkForInto(into ast.ForInto) {
+	switch i := into.(type) {
+	case *ast.ForIntoVar:
+		a.walkBindings([]*ast.Binding{i.Binding})
+	case *ast.ForDeclaration:
+		a.walkBindingTarget(i.Target)
+	case *ast.ForIntoExpression:
+		a.walkExpression(i.Expression)
+	}
+}
+
+// ============================================================================
+// 声明与绑定
+// ============================================================================
+
+func (a *securityAnalyzer) walkBindings(list []*ast.Binding) {
+	for _, binding := range list {
+		if binding == nil {
+			continue
+		}
+		if id, ok := binding.Target.(*ast.Identifier); ok && binding.Initializer != nil && a.collecting {
+			a.bindings[id.Name.String()] = append(a.bindings[id.Name.String()], binding.Initializer)
+		}
+		a.walkBindingTarget(binding.Target)
+		a.walkExpression(binding.Initializer)
+	}
+}
+
+// walkBindingTarget 遍历声明/解构目标：标识符记为已声明，解构的属性名视为属性读取
+func (a *securityAnalyzer) walkBindingTarget(target ast.Expression) {
+	switch t := target.(type) {
+	case nil:
+	case *ast.Identifier:
+		if a.collecting {
+			a.declared[t.Name.String()] = true
+		}
+	case *ast.ObjectPattern:
+		for _, prop := range t.Properties {
+			switch p := prop.(type) {
+			case *ast.PropertyShort:
+				// const { constructor } = obj 等价于 obj.constructor
+				a.checkPropertyName(p.Name.Name.String(), p.Name.Idx, p.Name.Idx1())
+				if a.collecting {
+					a.declared[p.Name.Name.String()] = true
+				}
+				a.walkExpression(p.Initializer)
+			case *ast.PropertyKeyed:
+				if p.Computed {
+					a.walkExpression(p.Key)
+				}
+				if name, ok := a.constString(p.Key, 0); ok {
+					a.checkPropertyName(name, p.Key.Idx0(), p.Key.Idx1())
+				}
+				a.walkBindingTarget(p.Value)
+			default:
+				a.walkExpression(prop)
+			}
+		}
+		a.walkBindingTarget(t.Rest)
+	case *ast.ArrayPattern:
+		for _, elem := range t.Elements {
+			a.walkBindingTarget(elem)
+		}
+		a.walkBindingTarget(t.Rest)
+	case *ast.AssignExpression:
+		// 带默认值的解构目标：{ a = 1 } / [b = 2]
+		a.walkBindingTarget(t.Left)
+		a.walkExpression(t.Right)
+	case *ast.Binding:
+		a.walkBindings([]*ast.Binding{t})
+	default:
+		// 赋值解构中的成员表达式：({ a: obj.x } = src)
+		a.walkExpression(target)
+	}
+}
+
+func (a *securityAnalyzer) walkFunction(fn *ast.FunctionLiteral) {
+	if fn == nil {
+		return
+	}
+	if fn.Name != nil && a.collecting {
+		a.declared[fn.Name.Name.String()] = true
+	}
+	a.walkParameters(fn.ParameterList)
+	if fn.Body != nil {
+		a.walkStatements(fn.Body.List)
+	}
+}
+
+func (a *securityAnalyzer) walkParameters(params *ast.ParameterList) {
+	if params == nil {
+		return
+	}
+	for _, param := range params.List {
+		a.walkBindingTarget(param.Target)
+		a.walkExpression(param.Initializer)
+	}
+	a.walkBindingTarget(params.Rest)
+}
+
+func (a *securityAnalyzer) walkClass(class *ast.ClassLiteral) {
+	if class == nil {
+		return
+	}
+	if class.Name != nil && a.collecting {
+		a.declared[class.Name.Name.String()] = true
+	}
+	a.walkExpression(class.SuperClass)
+	for _, elem := range class.Body {
+		switch el := elem.(type) {
+		case *ast.MethodDefinition:
+			// 类中定义 constructor() 方法是正常写法，不属于构造器访问
+			if el.Computed {
+				a.walkExpression(el.Key)
+			}
+			a.walkFunction(el.Body)
+		case *ast.FieldDefinition:
+			if el.Computed {
+				a.walkExpression(el.Key)
+			}
+			a.walkExpression(el.Initializer)
+		case *ast.ClassStaticBlock:
+			if el.Block != nil {
+				a.walkStatements(el.Block.List)
+			}
+		}
+	}
+}
+
+// ============================================================================
+// 表达式遍历
+// ============================================================================
+
+func (a *securityAnalyzer) walkExpression(expr ast.Expression) {
+	switch x := expr.(type) {
+	case nil:
+	case *ast.Identifier:
+		a.checkIdentifier(x)
+	case *ast.DotExpression:
+		a.walkExpression(x.Left)
+		a.checkPropertyName(x.Identifier.Name.String(), x.Identifier.Idx, x.Identifier.Idx1())
+		if obj, ok := x.Left.(*ast.Identifier); ok && obj.Name == "Object" {
+			if reason, found := prototypeMethods[x.Identifier.Name.String()]; found {
+				a.report("prototype_method", fmt.Sprintf("代码包含危险模式: %s", reason), obj.Idx, x.Identifier.Idx1())
+			}
+		}
+	case *ast.BracketExpression:
+		a.walkExpression(x.Left)
+		a.walkExpression(x.Member)
+		a.checkComputedMember(x)
+	case *ast.PrivateDotExpression:
+		a.walkExpression(x.Left)
+	case *ast.CallExpression:
+		a.walkExpression(x.Callee)
+		for _, arg := range x.ArgumentList {
+			a.walkExpression(arg)
+		}
+		a.checkCall(x)
+	case *ast.NewExpression:
+		a.walkExpression(x.Callee)
+		for _, arg := range x.ArgumentList {
+			a.walkExpression(arg)
+		}
+	case *ast.BinaryExpression:
+		a.walkBinary(x)
+	case *ast.AssignExpression:
+		if a.collecting && x.Operator == token.ASSIGN {
+			if id, ok := x.Left.(*ast.Identifier); ok {
+				a.bindings[id.Name.String()] = append(a.bindings[id.Name.String()], x.Right)
+			}
+		}
+		switch left := x.Left.(type) {
+		case *ast.DotExpression:
+			// Foo.prototype.constructor = Foo 是写入，不会拿到构造器
+			if left.Identifier.Name == "constructor" {
+				a.walkExpression(left.Left)
+			} else {
+				a.walkExpression(left)
+			}
+		case *ast.ObjectPattern, *ast.ArrayPattern:
+			a.walkBindingTarget(left)
+		default:
+			a.walkExpression(left)
+		}
+		a.walkExpression(x.Right)
+	case *ast.UnaryExpression:
+		// typeof Function / typeof globalThis 只读取类型，不会拿到对象本身
+		if _, ok := x.Operand.(*ast.Identifier); ok && x.Operator == token.TYPEOF {
+			return
+		}
+		a.walkExpression(x.Operand)
+	case *ast.ConditionalExpression:
+		a.walkExpression(x.Test)
+		a.walkExpression(x.Consequent)
+		a.walkExpression(x.Alternate)
+	case *ast.SequenceExpression:
+		for _, e := range x.Sequence {
+			a.walkExpression(e)
+		}
+	case *ast.ArrayLiteral:
+		for _, e := range x.Value {
+			a.walkExpression(e)
+		}
+	case *ast.ObjectLiteral:
+		for _, prop := range x.Value {
+			switch p := prop.(type) {
+			case *ast.PropertyShort:
+				// { Function } 读取同名变量
+				a.checkIdentifier(&p.Name)
+				a.walkExpression(p.Initializer)
+			case *ast.PropertyKeyed:
+				if p.Computed {
+					a.walkExpression(p.Key)
+				}
+				a.walkExpression(p.Value)
+			default:
+				a.walkExpression(prop)
+			}
+		}
+	case *ast.ObjectPattern, *ast.ArrayPattern:
+		a.walkBindingTarget(x)
+	case *ast.TemplateLiteral:
+		a.walkExpression(x.Tag)
+		for _, e := range x.Expressions {
+			a.walkExpression(e)
+		}
+	case *ast.AwaitExpression:
+		a.walkExpression(x.Argument)
+	case *ast.YieldExpression:
+		a.walkExpression(x.Argument)
+	case *ast.SpreadElement:
+		a.walkExpression(x.Expression)
+	case *ast.OptionalChain:
+		a.walkExpression(x.Expression)
+	case *ast.Optional:
+		a.walkExpression(x.Expression)
+	case *ast.FunctionLiteral:
+		a.walkFunction(x)
+	case *ast.ArrowFunctionLiteral:
+		a.walkParameters(x.ParameterList)
+		switch body := x.Body.(type) {
+		case *ast.BlockStatement:
+			a.walkStatements(body.List)
+		case *ast.ExpressionBody:
+			a.walkExpression(body.Expression)
+		}
+	case *ast.ClassLiteral:
+		a.walkClass(x)
+	case *ast.Binding:
+		a.walkBindings([]*ast.Binding{x})
+	}
+}
+
+// walkBinary 遍历二元表达式（处理两种不会拿到危险对象的写法）
+//   - x instanceof Function
+//   - x.constructor === Object（只比较，不调用）
+func (a *securityAnalyzer) walkBinary(x *ast.BinaryExpression) {
+	if x.Operator == token.INSTANCEOF {
+		if id, ok := x.Right.(*ast.Identifier); ok && id.Name == "Function" {
+			a.walkExpression(x.Left)
+			return
+		}
+	}
+
+	isEquality := x.Operator == token.EQUAL || x.Operator == token.STRICT_EQUAL ||
+		x.Operator == token.NOT_EQUAL || x.Operator == token.STRICT_NOT_EQUAL
+	for _, side := range []ast.Expression{x.Left, x.Right} {
+		if dot, ok := side.(*ast.DotExpression); ok && isEquality && dot.Identifier.Name == "constructor" {
+			a.walkExpression(dot.Left)
+			continue
+		}
+		a.walkExpression(side)
+	}
+}
+
+// ============================================================================
+// 检查规则
+// ============================================================================
+
+// checkIdentifier 检查对危险全局标识符的引用（含别名：const F = Function）
+func (a *securityAnalyzer) checkIdentifier(id *ast.Identifier) {
+	name := id.Name.String()
+	switch name {
+	case "Function":
+		a.report("function_constructor", "代码包含危险模式: Function构造器可执行任意代码", id.Idx, id.Idx1())
+	case "eval":
+		a.report("eval", "代码包含危险模式: eval函数可执行任意代码", id.Idx, id.Idx1())
+	case "Reflect", "Proxy":
+		if !a.declared[name] {
+			a.report("reflect_proxy", fmt.Sprintf("代码包含危险模式: %s 可能绕过安全限制", name), id.Idx, id.Idx1())
+		}
+	default:
+		if alwaysCheck, ok := globalObjectNames[name]; ok && (alwaysCheck || !a.declared[name]) {
+			a.report("global_object", fmt.Sprintf("代码包含危险模式: %s对象访问被禁止", name), id.Idx, id.Idx1())
+		}
+	}
+}
+
+// checkPropertyName 检查属性读取（obj.constructor / obj.__proto__ / 解构中的属性名）
+func (a *securityAnalyzer) checkPropertyName(name string, idx0, idx1 file.Idx) {
+	switch name {
+	case "constructor":
+		a.report("constructor_access", "代码包含危险模式: 构造器访问可能导致代码注入", idx0, idx1)
+	case "__proto__":
+		a.report("proto_access", "代码包含危险模式: 原型链操作可能导致安全问题", idx0, idx1)
+	}
+}
+
+// checkComputedMember 检查计算属性访问 obj[key]
+func (a *securityAnalyzer) checkComputedMember(x *ast.BracketExpression) {
+	idx0, idx1 := x.LeftBracket, x.RightBracket+1
+
+	if name, ok := a.constString(x.Member, 0); ok {
+		switch name {
+		case "constructor":
+			a.report("constructor_access", "代码包含危险模式: 动态访问构造器被禁止", idx0, idx1)
+		case "__proto__":
+			a.report("proto_access", "代码包含危险模式: 动态原型链访问被禁止", idx0, idx1)
+		case "eval", "Function":
+			a.report("dynamic_access", fmt.Sprintf("代码包含危险模式: 动态访问 %s 被禁止", name), idx0, idx1)
+		default:
+			if obj, ok := x.Left.(*ast.Identifier); ok && obj.Name == "Object" {
+				if reason, found := prototypeMethods[name]; found {
+					a.report("prototype_method", fmt.Sprintf("代码包含危险模式: %s", reason), idx0, idx1)
+				}
+			}
+		}
+		return
+	}
+
+	// this[x + y]：无法静态求值的字符串拼接访问（常见绕过手法）
+	if _, isThis := x.Left.(*ast.ThisExpression); isThis {
+		switch member := x.Member.(type) {
+		case *ast.BinaryExpression:
+			if member.Operator == token.PLUS {
+				a.report("dynamic_access", "代码包含可疑模式: 检测到可疑的字符串拼接访问", idx0, idx1)
+			}
+		case *ast.TemplateLiteral:
+			a.report("dynamic_access", "代码包含可疑模式: 检测到可疑的字符串拼接访问", idx0, idx1)
+		}
+	}
+}
+
+// checkCall 检查函数调用：require 禁用模块、策略禁用的 fetch、Object.xxx(obj, 'constructor') 间接访问
+func (a *securityAnalyzer) checkCall(x *ast.CallExpression) {
+	switch callee := x.Callee.(type) {
+	case *ast.Identifier:
+		switch callee.Name {
+		case "require":
+			a.checkProhibitedModules(x, callee)
+		case "fetch":
+			if a.limits != nil && !a.limits.networkEnabled && !a.declared["fetch"] {
+				a.report("network_disabled", "当前策略已禁用网络访问，不能使用 fetch",
+					callee.Idx, x.RightParenthesis+1)
+			}
+		}
+	case *ast.DotExpression:
+		// Object.getOwnPropertyDescriptor(fn, 'constructor') 等
+		if obj, ok := callee.Left.(*ast.Identifier); !ok || obj.Name != "Object" {
+			return
+		}
+		for _, arg := range x.ArgumentList {
+			if name, ok := a.constString(arg, 0); ok {
+				a.checkPropertyName(name, arg.Idx0(), arg.Idx1())
+			}
+		}
+	}
+}
+
+// checkProhibitedModules 检查 require 目标：全局禁用模块 + 沙箱策略（模块允许列表、网络权限）
+// 无法静态求值的目标（如 require(name)）由运行时的 require 拦截兜底（见 applySandboxPolicy）
+func (a *securityAnalyzer) checkProhibitedModules(x *ast.CallExpression, callee *ast.Identifier) {
+	if len(x.ArgumentList) == 0 {
+		return
+	}
+	target, ok := a.constString(x.ArgumentList[0], 0)
+	if !ok {
+		return
+	}
+	module := ModuleBaseName(target)
+	if reason, found := prohibitedModules[module]; found {
+		a.report("prohibited_module",
+			fmt.Sprintf("禁止使用 %s 模块：%s出于安全考虑已被禁用", module, reason),
+			callee.Idx, x.RightParenthesis+1)
+		return
+	}
+	if a.limits != nil {
+		if reason := a.limits.moduleDenyReason(module); reason != "" {
+			a.report("module_not_allowed", reason, callee.Idx, x.RightParenthesis+1)
+		}
+	}
+}
+
+// ============================================================================
+// 常量求值
+// ============================================================================
+
+// constString 尝试把表达式静态求值为字符串
+// 支持：字面量、+ 拼接、无标签模板字符串、const 变量、[...].join()、
+// "abc".split("").reverse().join("")、"a".concat("b")、String.fromCharCode(...)
+func (a *securityAnalyzer) constString(expr ast.Expression, depth int) (string, bool) {
+	if depth > maxConstFoldDepth {
+		return "", false
+	}
+
+	switch x := expr.(type) {
+	case *ast.StringLiteral:
+		return x.Value.String(), true
+	case *ast.NumberLiteral:
+		return x.Literal, true
+	case *ast.BooleanLiteral:
+		return x.Literal, true
+	case *ast.TemplateLiteral:
+		if x.Tag != nil {
+			return "", false
+		}
+		var sb strings.Builder
+		for i, elem := range x.Elements {
+			sb.WriteString(elem.Parsed.String())
+			if i < len(x.Expressions) {
+				s, ok := a.constString(x.Expressions[i], depth+1)
+				if !ok {
+					return "", false
+				}
+				sb.WriteString(s)
+			}
+		}
+		return sb.String(), true
+	case *ast.BinaryExpression:
+		if x.Operator != token.PLUS {
+			return "", false
+		}
+		left, ok := a.constString(x.Left, depth+1)
+		if !ok {
+			return "", false
+		}
+		right, ok := a.constString(x.Right, depth+1)
+		if !ok {
+			return "", false
+		}
+		return left + right, true
+	case *ast.Identifier:
+		// 同名变量有多次赋值时，取第一个可求值的（分析只用于发现危险值，宁严勿松）
+		for _, init := range a.bindings[x.Name.String()] {
+			if s, ok := a.constString(init, depth+1); ok {
+				return s, true
+			}
+		}
+	case *ast.SequenceExpression:
+		if len(x.Sequence) > 0 {
+			return a.constString(x.Sequence[len(x.Sequence)-1], depth+1)
+		}
+	case *ast.CallExpression:
+		return a.constCall(x, depth)
+	}
+	return "", false
+}
+
+func (a *securityAnalyzer) constCall(x *ast.CallExpression, depth int) (string, bool) {
+	callee, ok := x.Callee.(*ast.DotExpression)
+	if !ok {
+		return "", false
+	}
+
+	switch callee.Identifier.Name {
+	case "join":
+		parts, ok := a.constArray(callee.Left, depth+1)
+		if !ok {
+			return "", false
+		}
+		sep := ","
+		if len(x.ArgumentList) > 0 {
+			if sep, ok = a.constString(x.ArgumentList[0], depth+1); !ok {
+				return "", false
+			}
+		}
+		return strings.Join(parts, sep), true
+	case "concat":
+		base, ok := a.constString(callee.Left, depth+1)
+		if !ok {
+			return "", false
+		}
+		for _, arg := range x.ArgumentList {
+			s, ok := a.constString(arg, depth+1)
+			if !ok {
+				return "", false
+			}
+			base += s
+		}
+		return base, true
+	case "fromCharCode":
+		if obj, ok := callee.Left.(*ast.Identifier); !ok || obj.Name != "String" {
+			return "", false
+		}
+		var sb strings.Builder
+		for _, arg := range x.ArgumentList {
+			num, ok := arg.(*ast.NumberLiteral)
+			if !ok {
+				return "", false
+			}
+			switch v := num.Value.(type) {
+			case int64:
+				sb.WriteRune(rune(v))
+			case float64:
+				sb.WriteRune(rune(v))
+			default:
+				return "", false
+			}
+		}
+		return sb.String(), true
+	}
+	return "", false
+}
+
+// constArray 尝试把表达式静态求值为字符串数组（用于 join）
+func (a *securityAnalyzer) constArray(expr ast.Expression, depth int) ([]string, bool) {
+	if depth > maxConstFoldDepth {
+		return nil, false
+	}
+
+	switch x := expr.(type) {
+	case *ast.ArrayLiteral:
+		parts := make([]string, len(x.Value))
+		for i, elem := range x.Value {
+			s, ok := a.constString(elem, depth+1)
+			if !ok {
+				return nil, false
+			}
+			parts[i] = s
+		}
+		return parts, true
+	case *ast.Identifier:
+		for _, init := range a.bindings[x.Name.String()] {
+			if parts, ok := a.constArray(init, depth+1); ok {
+				return parts, true
+			}
+		}
+	case *ast.CallExpression:
+		callee, ok := x.Callee.(*ast.DotExpression)
+		if !ok {
+			return nil, false
+		}
+		switch callee.Identifier.Name {
+		case "split":
+			s, ok := a.constString(callee.Left, depth+1)
+			if !ok || len(x.ArgumentList) == 0 {
+				return nil, false
+			}
+			sep, ok := a.constString(x.ArgumentList[0], depth+1)
+			if !ok {
+				return nil, false
+			}
+			if sep == "" {
+				// JS 按 UTF-16 码元拆分，这里按字符拆分（拼回后结果一致）
+				parts := make([]string, 0, len(s))
+				for _, r := range s {
+					parts = append(parts, string(r))
+				}
+				return parts, true
+			}
+			return strings.Split(s, sep), true
+		case "reverse":
+			parts, ok := a.constArray(callee.Left, depth+1)
+			if !ok {
+				return nil, false
+			}
+			reversed := make([]string, len(parts))
+			for i, p := range parts {
+				reversed[len(parts)-1-i] = p
+			}
+			return reversed, true
+		}
+	}
+	return nil, false
+}
+
+// isConstantTruthy 判断循环条件是否恒为真（true、非零数字、非空字符串、!0、字面量对象等）
+func isConstantTruthy(expr ast.Expression) bool {
+	switch x := expr.(type) {
+	case *ast.BooleanLiteral:
+		return x.Value
+	case *ast.NumberLiteral:
+		switch v := x.Value.(type) {
+		case int64:
+			return v != 0
+		case float64:
+			return v != 0 && v == v // NaN 为假
+		}
+	case *ast.StringLiteral:
+		return len(x.Value) > 0
+	case *ast.ArrayLiteral, *ast.ObjectLiteral, *ast.FunctionLiteral, *ast.ArrowFunctionLiteral, *ast.RegExpLiteral:
+		return true
+	case *ast.UnaryExpression:
+		if x.Operator == token.NOT {
+			return isConstantFalsy(x.Operand)
+		}
+	}
+	return false
+}
+
+// isConstantFalsy 判断表达式是否恒为假
+func isConstantFalsy(expr ast.Expression) bool {
+	switch x := expr.(type) {
+	case *ast.BooleanLiteral:
+		return !x.Value
+	case *ast.NumberLiteral:
+		switch v := x.Value.(type) {
+		case int64:
+			return v == 0
+		case float64:
+			return v == 0 || v != v
+		}
+	case *ast.StringLiteral:
+		return len(x.Value) == 0
+	case *ast.NullLiteral:
+		return true
+	case *ast.UnaryExpression:
+		if x.Operator == token.NOT {
+			return isConstantTruthy(x.Operand)
+		}
+	}
+	return false
+}
+
+// hasLoopExit 检查循环体内是否有能退出该循环的语句
+//   - return / throw（不进入嵌套函数：函数体内的 return 不会退出循环）
+//   - 不带标签的 break（不在内层循环或 switch 中）
+//   - 带标签的 break / continue，且标签不是循环体内部定义的
+//
+// 参数说明：
+//   - labels: 该循环自身的标签
+//   - innerLabels: 循环体内部定义的标签
+//   - nested: 是否位于内层循环或 switch 中
+func hasLoopExit(stmt ast.Statement, labels, innerLabels []string, nested bool) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	case *ast.BranchStatement:
+		if s.Label == nil {
+			return s.Token == token.BREAK && !nested
+		}
+		label := s.Label.Name.String()
+		for _, l := range labels {
+			if l == label {
+				return s.Token == token.BREAK
+			}
+		}
+		for _, l := range innerLabels {
+			if l == label {
+				return false
+			}
+		}
+		return true // 跳转到外层标签
+	case *ast.BlockStatement:
+		for _, child := range s.List {
+			if hasLoopExit(child, labels, innerLabels, nested) {
+				return true
+			}
+		}
+	case *ast.IfStatement:
+		return hasLoopExit(s.Consequent, labels, innerLabels, nested) ||
+			hasLoopExit(s.Alternate, labels, innerLabels, nested)
+	case *ast.ForStatement:
+		return hasLoopExit(s.Body, labels, innerLabels, true)
+	case *ast.ForInStatement:
+		return hasLoopExit(s.Body, labels, innerLabels, true)
+	case *ast.ForOfStatement:
+		return hasLoopExit(s.Body, labels, innerLabels, true)
+	case *ast.WhileStatement:
+		return hasLoopExit(s.Body, labels, innerLabels, true)
+	case *ast.DoWhileStatement:
+		return hasLoopExit(s.Body, labels, innerLabels, true)
+	case *ast.SwitchStatement:
+		for _, c := range s.Body {
+			for _, child := range c.Consequent {
+				if hasLoopExit(child, labels, innerLabels, true) {
+					return true
+				}
+			}
+		}
+	case *ast.TryStatement:
+		if hasLoopExit(s.Body, labels, innerLabels, nested) {
+			return true
+		}
+		if s.Catch != nil && hasLoopExit(s.Catch.Body, labels, innerLabels, nested) {
+			return true
+		}
+		if s.Finally != nil && hasLoopExit(s.Finally, labels, innerLabels, nested) {
+			return true
+		}
+	case *ast.LabelledStatement:
+		return hasLoopExit(s.Statement, labels, append(innerLabels[:len(innerLabels):len(innerLabels)], s.Label.Name.String()), nested)
+	case *ast.WithStatement:
+		return hasLoopExit(s.Body, labels, innerLabels, nested)
+	}
+	return false
+}
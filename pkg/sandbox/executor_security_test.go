@@ -0,0 +1,141 @@
+package sandbox
+
+import (
+	"strings"
+	"testing"
+)
+
+// findingRules 发现的规则标识（按位置顺序）
+func findingRules(findings []securityFinding) []string {
+	rules := make([]string, 0, len(findings))
+	for _, f := range findings {
+		rules = append(rules, f.rule)
+	}
+	return rules
+}
+
+func TestAnalyzeCodeSecurityBypasses(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		rule string // 必须出现的规则
+	}{
+		// 常量折叠：拼出 constructor 的各种写法
+		{"字符串拼接", `const f = (() => {})["constr" + "uctor"]; return f("return 1")();`, "constructor_access"},
+		{"模板字符串", "const k = `constr${'uct'}or`; return ({})[k];", "constructor_access"},
+		{"数组 join", `return ({})[["con", "struc", "tor"].join("")];`, "constructor_access"},
+		{"split reverse join", `return ({})["rotcurtsnoc".split("").reverse().join("")];`, "constructor_access"},
+		{"concat", `return ({})["constr".concat("uctor")];`, "constructor_access"},
+		{"fromCharCode", `return ({})[String.fromCharCode(95, 95, 112, 114, 111, 116, 111, 95, 95)];`, "proto_access"},
+		{"const 别名链", `const a = "constr"; const b = a + "uctor"; const c = b; return ({})[c];`, "constructor_access"},
+		{"动态访问 eval", `return globalThis["ev" + "al"];`, "dynamic_access"},
+		{"this 上的不可求值拼接", `function f(x) { return this[x + "al"]; } return f("ev");`, "dynamic_access"},
+		{"Object 方法间接访问", `return Object.getOwnPropertyDescriptor(Object.getPrototypeOf(function(){}), "constr" + "uctor");`, "constructor_access"},
+		{"Object 计算属性", `return Object["getPrototype" + "Of"]({});`, "prototype_method"},
+
+		// 危险标识符和别名
+		{"Function 别名", `const F = Function; return F("return 1")();`, "function_constructor"},
+		{"eval 调用", `return eval("1 + 1");`, "eval"},
+		{"globalThis", `const g = globalThis; return g.process;`, "global_object"},
+		{"Reflect", `return Reflect.ownKeys({});`, "reflect_proxy"},
+		{"解构出 constructor", `const { constructor: C } = () => {}; return C;`, "constructor_access"},
+		{"__proto__", `const o = {}; return o.__proto__;`, "proto_access"},
+
+		// 模块
+		{"禁用模块", `const fs = require("fs"); return 1;`, "prohibited_module"},
+		{"禁用模块 node 前缀和子路径", `const fs = require("node:fs/promises"); return 1;`, "prohibited_module"},
+		{"拼接出的禁用模块", `const cp = require("child_" + "process"); return 1;`, "prohibited_module"},
+
+		// 无限循环
+		{"while true", `while (true) { let x = 1; }`, "infinite_loop"},
+		{"for 空条件", `for (;;) { }`, "infinite_loop"},
+		{"do while 常量条件", `do { } while (1);`, "infinite_loop"},
+		{"带标签的无限循环", `outer: while (true) { inner: for (;;) { break inner; } }`, "infinite_loop"},
+		{"break 到内层标签", `a: for (;;) { b: while (true) { break b; } }`, "infinite_loop"},
+		{"只在嵌套函数中 return", `while (true) { [1].forEach(() => { return; }); }`, "infinite_loop"},
+		{"只在内层循环中 break", `while (true) { for (let i = 0; i < 3; i++) { break; } }`, "infinite_loop"},
+		{"只在 switch 中 break", `while (true) { switch (1) { case 1: break; } }`, "infinite_loop"},
+
+		// 解析失败：拒绝执行（闭合包装注入）
+		{"闭合包装注入", `} catch(e) {} })(), (function(){ try {`, securityRuleParse},
+		{"语法错误", `return (1 + ;`, securityRuleParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := analyzeCodeSecurity(tt.code, &executionLimits{networkEnabled: true})
+			rules := findingRules(findings)
+			for _, rule := range rules {
+				if rule == tt.rule {
+					return
+				}
+			}
+			t.Fatalf("rules = %v, want %s\ncode: %s", rules, tt.rule, tt.code)
+		})
+	}
+}
+
+func TestAnalyzeCodeSecurityAllowsSafeCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"字符串和注释中的关键字", `// eval(Function)
+const s = "constructor __proto__ globalThis"; return s;`},
+		{"属性名和对象键", `const obj = { eval: 1, Function: 2 }; return obj.self;`},
+		{"普通计算属性", `const k = "na" + "me"; return ({ name: 1 })[k];`},
+		{"循环中 break", `let i = 0; while (true) { if (++i > 3) break; } return i;`},
+		{"循环中 return", `for (;;) { return 1; }`},
+		{"循环中 throw", `while (1) { throw new Error("x"); }`},
+		{"break 到外层标签", `outer: while (true) { for (;;) { break outer; } }`},
+		{"continue 之后 break 外层标签", `a: for (;;) { b: while (true) { if (1) continue b; break a; } }`},
+		{"声明同名变量", `const self = { a: 1 }; const Reflect = {}; return self.a;`},
+		{"顶层 await", `const r = await Promise.resolve(1); return r;`},
+		{"允许的模块", `const _ = require("lodash"); return _.sum([1, 2]);`},
+		{"instanceof Function", `return (() => {}) instanceof Function;`},
+		{"Shebang", "#!/usr/bin/env node\nreturn 1;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := analyzeCodeSecurity(tt.code, &executionLimits{networkEnabled: true})
+			if len(findings) > 0 {
+				t.Fatalf("rules = %v, want none\ncode: %s", findingRules(findings), tt.code)
+			}
+		})
+	}
+}
+
+func TestAnalyzeCodeSecurityFindingPositions(t *testing.T) {
+	code := "const a = 1;\nconst b = ({})[\"constr\" + \"uctor\"];\nreturn b;"
+	findings := analyzeCodeSecurity(code, &executionLimits{networkEnabled: true})
+	if len(findings) != 1 {
+		t.Fatalf("rules = %v, want 1 finding", findingRules(findings))
+	}
+	if got := code[findings[0].start:findings[0].end]; got != `["constr" + "uctor"]` {
+		t.Errorf("finding 区间 = %q", got)
+	}
+
+	// 解析失败的位置在用户代码内，且不超出出错的那一行
+	code = "const a = 1;\nreturn (1 + ;\nconst c = 2;"
+	findings = analyzeCodeSecurity(code, &executionLimits{networkEnabled: true})
+	if len(findings) != 1 || findings[0].rule != securityRuleParse {
+		t.Fatalf("rules = %v, want [parse]", findingRules(findings))
+	}
+	if f := findings[0]; f.start < len("const a = 1;\n") || strings.Contains(code[f.start:f.end], "\n") {
+		t.Errorf("parse 区间 = [%d, %d) %q", f.start, f.end, code[f.start:f.end])
+	}
+}
+
+func TestAnalyzeCodeSecurityPolicyRules(t *testing.T) {
+	// 策略禁用网络：fetch 在静态检查中拒绝
+	limits := &executionLimits{networkEnabled: false}
+	findings := analyzeCodeSecurity(`return fetch("https://example.com");`, limits)
+	if rules := findingRules(findings); len(rules) != 1 || rules[0] != "network_disabled" {
+		t.Errorf("fetch rules = %v, want [network_disabled]", rules)
+	}
+
+	// 用户自己声明的 fetch 不受影响
+	findings = analyzeCodeSecurity(`const fetch = (x) => x; return fetch(1);`, limits)
+	if len(findings) != 0 {
+		t.Errorf("rules = %v, want none", findingRules(findings))
+	}
+}
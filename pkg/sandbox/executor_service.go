@@ -1,27 +1,27 @@
-package service
+package sandbox
 
 import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"flow-codeblock-go/assets"
-	"flow-codeblock-go/config"
 	"flow-codeblock-go/enhance_modules"
-	"flow-codeblock-go/model"
+	"flow-codeblock-go/model/execmodel"
 	"flow-codeblock-go/utils"
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/buffer"
-	"github.com/dop251/goja_nodejs/console"
 	"github.com/dop251/goja_nodejs/process"
 	"github.com/dop251/goja_nodejs/require"
 	"github.com/dop251/goja_nodejs/url"
 	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
 )
@@ -29,13 +29,15 @@ import (
 // gcThrottler GC 节流器，防止 GC 风暴
 // 🔥 使用 channel 限制并发 GC 数量（最多 1 个）
 type gcThrottler struct {
-	ch chan struct{}
+	ch     chan struct{}
+	logger *zap.Logger
 }
 
 // newGCThrottler 创建 GC 节流器
-func newGCThrottler() *gcThrottler {
+func newGCThrottler(logger *zap.Logger) *gcThrottler {
 	return &gcThrottler{
-		ch: make(chan struct{}, 1), // 🔥 最多 1 个并发 GC
+		ch:     make(chan struct{}, 1), // 🔥 最多 1 个并发 GC
+		logger: logger,
 	}
 }
 
@@ -47,16 +49,18 @@ func (t *gcThrottler) triggerGC() {
 		go func() {
 			defer func() { <-t.ch }()
 			runtime.GC()
-			utils.Debug("手动触发 GC 完成")
+			t.logger.Debug("手动触发 GC 完成")
 		}()
 	default:
 		// GC 已在进行中，跳过本次触发
-		utils.Debug("GC 已在运行中，跳过本次触发")
+		t.logger.Debug("GC 已在运行中，跳过本次触发")
 	}
 }
 
 // JSExecutor Go+goja JavaScript执行器
 type JSExecutor struct {
+	logger *zap.Logger // 🆕 执行器日志（Config.Logger，为空时不输出）
+
 	// Runtime池
 	runtimePool chan *goja.Runtime
 	poolSize    int
@@ -71,6 +75,9 @@ type JSExecutor struct {
 	runtimeHealth map[*goja.Runtime]*runtimeHealthInfo
 	healthMutex   sync.RWMutex
 
+	// 🆕 EventLoop 池（异步代码，见 executor_eventloop_pool.go）
+	eventLoopPool *eventLoopPool
+
 	// 🔥 自适应冷却时间管理（防止频繁周期性流量抖动）
 	lastShrinkTime       time.Time
 	lastExpandTime       time.Time
@@ -78,7 +85,8 @@ type JSExecutor struct {
 	adaptiveCooldownLock sync.RWMutex
 
 	// 并发控制
-	semaphore     chan struct{}
+	// 🆕 scheduler 按 Token / 工作空间加权公平分配并发槽位（替代全局 semaphore）
+	scheduler     *fairScheduler
 	maxConcurrent int
 	currentExecs  int64
 
@@ -87,11 +95,16 @@ type JSExecutor struct {
 	maxInputSize              int
 	maxResultSize             int
 	executionTimeout          time.Duration
-	allowConsole              bool          // 是否允许用户代码使用 console
+	consoleMode               string        // 🆕 Console 输出模式（disabled/stdout/capture）
+	consoleMaxLines           int           // 🆕 capture 模式：单次执行最多捕获的日志条数
+	consoleMaxBytes           int           // 🆕 capture 模式：单次执行最多捕获的日志字节数
 	concurrencyWaitTimeout    time.Duration // 🔥 并发槽位等待超时（可配置）
 	runtimePoolAcquireTimeout time.Duration // 🔥 Runtime 池获取超时（可配置）
 	slowExecutionThreshold    time.Duration // 🔥 慢执行检测阈值（可配置）
 
+	// 🆕 无沙箱策略时的执行限制（全局配置，见 executor_policy.go）
+	defaultLimits *executionLimits
+
 	// 🔥 健康检查和池管理配置（从配置文件加载）
 	minErrorCountForCheck         int           // 最小错误次数阈值
 	maxErrorRateThreshold         float64       // 最大错误率阈值
@@ -113,6 +126,9 @@ type JSExecutor struct {
 	// 🔥 模块注册器（统一管理所有模块增强器）
 	moduleRegistry *ModuleRegistry
 
+	// 🆕 Fetch 增强器（每次执行按出站规则绑定 fetch，见 applySandboxPolicy）
+	fetchEnhancer *enhance_modules.FetchEnhancer
+
 	// 🔥 JavaScript 内存限制器（可配置）
 	jsMemoryLimiter *enhance_modules.JSMemoryLimiter
 
@@ -122,7 +138,7 @@ type JSExecutor struct {
 	maxCacheSize   int
 
 	// 🔥 代码验证缓存 (LRU 实现)
-	// 缓存安全检查结果，避免重复执行 40+ 个正则表达式
+	// 缓存安全检查结果，避免重复解析语法树和执行安全分析
 	// key: 代码哈希, value: error (nil 表示验证通过)
 	validationCache      *utils.GenericLRUCache
 	validationCacheMutex sync.RWMutex
@@ -135,11 +151,11 @@ type JSExecutor struct {
 	analyzer *utils.CodeAnalyzer
 
 	// 统计信息
-	stats *model.ExecutorStats
+	stats *execmodel.ExecutorStats
 	mutex sync.RWMutex
 
 	// 预热统计信息
-	warmupStats *model.WarmupStats
+	warmupStats *execmodel.WarmupStats
 	warmupMutex sync.RWMutex
 
 	// 关闭信号
@@ -148,6 +164,15 @@ type JSExecutor struct {
 
 	// 🔥 熔断器（防止重度过载时所有请求都等待 10s）
 	circuitBreaker *gobreaker.CircuitBreaker
+
+	// 🆕 执行耗时直方图（标签：route=sync/async，error_type=none/错误类型），供 /metrics 导出
+	execLatency *utils.HistogramVec
+
+	// 🆕 分阶段耗时直方图（标签：phase，cache=hit/miss/none），见 ExecutionTimeline
+	phaseLatency *utils.HistogramVec
+
+	// 🆕 正在执行的代码（管理员查看 / 终止）
+	running *runningRegistry
 }
 
 // runtimeHealthInfo 运行时健康信息
@@ -184,9 +209,15 @@ type runtimeHealthInfo struct {
 	errorCount     atomic.Int64 // 错误次数计数器，Go 1.19+ atomic.Int64
 }
 
-// NewJSExecutor 创建新的JavaScript执行器
-func NewJSExecutor(cfg *config.Config) *JSExecutor {
+// newJSExecutor 按配置创建执行器（New 应用 Option 后调用）
+func newJSExecutor(cfg *Config) *JSExecutor {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
 	executor := &JSExecutor{
+		logger:                    logger,
 		runtimePool:               make(chan *goja.Runtime, cfg.Executor.MaxPoolSize),
 		poolSize:                  cfg.Executor.PoolSize,
 		minPoolSize:               cfg.Executor.MinPoolSize,
@@ -194,13 +225,18 @@ func NewJSExecutor(cfg *config.Config) *JSExecutor {
 		idleTimeout:               cfg.Executor.IdleTimeout,
 		currentPoolSize:           int32(cfg.Executor.PoolSize),
 		runtimeHealth:             make(map[*goja.Runtime]*runtimeHealthInfo),
-		semaphore:                 make(chan struct{}, cfg.Executor.MaxConcurrent),
+		scheduler:                 newFairScheduler(cfg),
+		execLatency:               utils.NewHistogramVec(utils.DefaultLatencyBuckets, "route", "error_type"),
+		phaseLatency:              utils.NewHistogramVec(phaseLatencyBuckets, "phase", "cache"),
+		running:                   newRunningRegistry(),
 		maxConcurrent:             cfg.Executor.MaxConcurrent,
 		maxCodeLength:             cfg.Executor.MaxCodeLength,
 		maxInputSize:              cfg.Executor.MaxInputSize,
 		maxResultSize:             cfg.Executor.MaxResultSize,
 		executionTimeout:          cfg.Executor.ExecutionTimeout,
-		allowConsole:              cfg.Executor.AllowConsole,              // 🔥 Console 控制
+		consoleMode:               cfg.Executor.ConsoleMode,               // 🆕 Console 输出模式
+		consoleMaxLines:           cfg.Executor.ConsoleMaxLines,           // 🆕 capture 日志条数上限
+		consoleMaxBytes:           cfg.Executor.ConsoleMaxBytes,           // 🆕 capture 日志字节上限
 		concurrencyWaitTimeout:    cfg.Executor.ConcurrencyWaitTimeout,    // 🔥 并发等待超时（可配置）
 		runtimePoolAcquireTimeout: cfg.Executor.RuntimePoolAcquireTimeout, // 🔥 Runtime 获取超时（可配置）
 		slowExecutionThreshold:    cfg.Executor.SlowExecutionThreshold,    // 🔥 慢执行检测阈值（可配置）
@@ -218,19 +254,21 @@ func NewJSExecutor(cfg *config.Config) *JSExecutor {
 
 		// 🔥 GC 触发频率配置（高并发优化）
 		gcTriggerInterval: cfg.Executor.GCTriggerInterval,
-		gcThrottler:       newGCThrottler(), // 🔥 初始化 GC 节流器
+		gcThrottler:       newGCThrottler(logger), // 🔥 初始化 GC 节流器
 
 		registry:        new(require.Registry),
-		moduleRegistry:  NewModuleRegistry(), // 🔥 创建模块注册器
+		moduleRegistry:  NewModuleRegistry(logger), // 🔥 创建模块注册器
 		codeCache:       utils.NewLRUCache(cfg.Executor.CodeCacheSize),
 		validationCache: utils.NewGenericLRUCache(cfg.Executor.CodeCacheSize), // 🔥 验证缓存（与代码缓存相同大小）
 		maxCacheSize:    cfg.Executor.CodeCacheSize,
 		analyzer:        utils.NewCodeAnalyzer(),
-		stats:           &model.ExecutorStats{},
-		warmupStats:     &model.WarmupStats{Status: "not_started"},
+		stats:           &execmodel.ExecutorStats{},
+		warmupStats:     &execmodel.WarmupStats{Status: "not_started"},
 		shutdown:        make(chan struct{}),
 	}
 
+	executor.defaultLimits = executor.newDefaultExecutionLimits()
+
 	// 🔥 注册所有模块（统一管理）
 	executor.registerModules(cfg)
 
@@ -248,10 +286,10 @@ func NewJSExecutor(cfg *config.Config) *JSExecutor {
 	)
 
 	if executor.jsMemoryLimiter.IsEnabled() {
-		utils.Info("JavaScript 内存限制已启用",
+		executor.logger.Info("JavaScript 内存限制已启用",
 			zap.Int64("limit_mb", executor.jsMemoryLimiter.GetMaxAllocationMB()))
 	} else {
-		utils.Warn("JavaScript 内存限制已禁用，建议仅在开发环境禁用")
+		executor.logger.Warn("JavaScript 内存限制已禁用，建议仅在开发环境禁用")
 	}
 
 	// 🔥 启动时预编译关键模块（Fail Fast）
@@ -262,7 +300,7 @@ func NewJSExecutor(cfg *config.Config) *JSExecutor {
 	//   - 原因：日志消息已提供上下文，再包装会产生冗余信息
 	//   - 错误链示例：crypto-js 预编译失败: compilation error at line 10
 	if err := executor.warmupModules(); err != nil {
-		utils.Fatal("关键模块预编译失败，服务启动中止", zap.Error(err))
+		executor.logger.Fatal("关键模块预编译失败，服务启动中止", zap.Error(err))
 	}
 
 	// 🔥 初始化熔断器（防止重度过载）
@@ -271,14 +309,19 @@ func NewJSExecutor(cfg *config.Config) *JSExecutor {
 	// 初始化Runtime池
 	executor.initRuntimePool()
 
+	// 🆕 初始化 EventLoop 池（异步代码不再每次新建并初始化 EventLoop）
+	executor.eventLoopPool = newEventLoopPool(executor, cfg)
+	executor.eventLoopPool.init()
+
 	// 启动健康检查器
 	executor.startHealthChecker()
 
-	utils.Info("JavaScript 执行器初始化成功",
+	executor.logger.Info("JavaScript 执行器初始化成功",
 		zap.Int("pool_size", cfg.Executor.PoolSize),
 		zap.Int("min_pool_size", cfg.Executor.MinPoolSize),
 		zap.Int("max_pool_size", cfg.Executor.MaxPoolSize),
 		zap.Duration("idle_timeout", cfg.Executor.IdleTimeout),
+		zap.Int("eventloop_pool_size", cfg.Executor.EventLoopPoolSize),
 		zap.Int("max_concurrent", cfg.Executor.MaxConcurrent),
 		zap.Int("max_code_length", cfg.Executor.MaxCodeLength),
 		zap.Duration("execution_timeout", executor.executionTimeout),
@@ -294,8 +337,8 @@ func NewJSExecutor(cfg *config.Config) *JSExecutor {
 
 // registerModules 注册所有需要的模块
 // 🔥 这是新架构的核心：统一的模块注册入口
-func (e *JSExecutor) registerModules(cfg *config.Config) {
-	utils.Debug("开始注册模块")
+func (e *JSExecutor) registerModules(cfg *Config) {
+	e.logger.Debug("开始注册模块")
 
 	// 注册 Buffer 模块
 	e.moduleRegistry.Register(enhance_modules.NewBufferEnhancer())
@@ -337,6 +380,7 @@ func (e *JSExecutor) registerModules(cfg *config.Config) {
 		},
 	)
 	e.moduleRegistry.Register(fetchEnhancer)
+	e.fetchEnhancer = fetchEnhancer
 
 	// 注册 FormData 模块（需要访问 fetchEnhancer）
 	enhance_modules.RegisterFormDataModule(e.registry, fetchEnhancer)
@@ -354,14 +398,28 @@ func (e *JSExecutor) registerModules(cfg *config.Config) {
 	// 🔥 UUID 模块（Go 原生实现：100% Node.js 兼容，支持所有 14 个 API，性能提升 10-100 倍）
 	e.moduleRegistry.Register(enhance_modules.NewUuidNativeEnhancer()) // Go 原生实现，包含 v1-v7 + v6 转换
 	e.moduleRegistry.Register(enhance_modules.NewFastXMLParserEnhancer(assets.FastXMLParser))
-	e.moduleRegistry.Register(enhance_modules.NewXLSXEnhancer(cfg))
+	e.moduleRegistry.Register(enhance_modules.NewXLSXEnhancer(
+		cfg.Fetch.MaxBlobFileSize,
+		cfg.XLSX.MaxSnapshotSize,
+		cfg.XLSX.MaxRows,
+		cfg.XLSX.MaxCols,
+	))
 
 	// 🔥 国密算法模块（sm-crypto-v2: Go 原生实现，支持 SM2/SM3/SM4/KDF）
 	e.moduleRegistry.Register(enhance_modules.NewSMCryptoNativeEnhancer())
 
+	// 🆕 嵌入方提供的模块（WithModules），与内置模块重名时忽略
+	for _, module := range cfg.Modules {
+		if _, exists := e.moduleRegistry.GetModule(module.Name()); exists {
+			e.logger.Warn("模块名称与已注册模块重复，已忽略", zap.String("module", module.Name()))
+			continue
+		}
+		e.moduleRegistry.Register(module)
+	}
+
 	// 🔥 一次性注册所有模块到 require 系统
 	if err := e.moduleRegistry.RegisterAll(e.registry); err != nil {
-		utils.Fatal("模块注册失败", zap.Error(err))
+		e.logger.Fatal("模块注册失败", zap.Error(err))
 	}
 }
 
@@ -378,7 +436,7 @@ func (e *JSExecutor) registerModules(cfg *config.Config) {
 //   - ✅ 重度过载时立即失败（< 1ms），而非等待 10s
 //   - ✅ 保护系统不被压垮（减少等待队列）
 //   - ✅ 自动恢复（渐进式探测）
-func (e *JSExecutor) initCircuitBreaker(cfg *config.Config) {
+func (e *JSExecutor) initCircuitBreaker(cfg *Config) {
 	// 🔥 支持禁用熔断器（用于测试或特殊场景）
 	if !cfg.Executor.CircuitBreakerEnabled {
 		// 创建一个永不触发的熔断器（Closed 状态）
@@ -391,7 +449,7 @@ func (e *JSExecutor) initCircuitBreaker(cfg *config.Config) {
 				return false // 永不触发
 			},
 		})
-		utils.Info("熔断器已禁用", zap.Bool("enabled", false))
+		e.logger.Info("熔断器已禁用", zap.Bool("enabled", false))
 		return
 	}
 
@@ -408,7 +466,7 @@ func (e *JSExecutor) initCircuitBreaker(cfg *config.Config) {
 				failureRatio >= cfg.Executor.CircuitBreakerFailureRatio
 
 			if shouldTrip {
-				utils.Warn("熔断器触发",
+				e.logger.Warn("熔断器触发",
 					zap.Uint32("total_requests", counts.Requests),
 					zap.Uint32("total_failures", counts.TotalFailures),
 					zap.Float64("failure_ratio", failureRatio),
@@ -422,7 +480,7 @@ func (e *JSExecutor) initCircuitBreaker(cfg *config.Config) {
 
 		// 🔥 状态变化回调（监控和日志）
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
-			utils.Info("熔断器状态变化",
+			e.logger.Info("熔断器状态变化",
 				zap.String("circuit_breaker", name),
 				zap.String("from", from.String()),
 				zap.String("to", to.String()),
@@ -437,7 +495,7 @@ func (e *JSExecutor) initCircuitBreaker(cfg *config.Config) {
 		},
 	})
 
-	utils.Info("熔断器初始化成功",
+	e.logger.Info("熔断器初始化成功",
 		zap.Bool("enabled", true),
 		zap.Uint32("max_requests", cfg.Executor.CircuitBreakerMaxRequests),
 		zap.Duration("timeout", cfg.Executor.CircuitBreakerTimeout),
@@ -455,7 +513,7 @@ func (e *JSExecutor) shouldTriggerCircuitBreaker(err error) bool {
 	}
 
 	// 检查是否是 ExecutionError
-	if execErr, ok := err.(*model.ExecutionError); ok {
+	if execErr, ok := err.(*execmodel.ExecutionError); ok {
 		// 🔥 只有 ConcurrencyError 才触发熔断
 		return execErr.Type == "ConcurrencyError"
 	}
@@ -479,7 +537,7 @@ func (e *JSExecutor) shouldTriggerCircuitBreaker(err error) bool {
 //   - 运行：每个 Runtime 运行预编译的 Program（~1-5ms）
 //   - 内存：所有 Runtime 共享 *goja.Program（节省内存）
 func (e *JSExecutor) warmupModules() error {
-	utils.Info("开始预热嵌入式模块...")
+	e.logger.Info("开始预热嵌入式模块...")
 	startTime := time.Now()
 
 	// 定义需要预编译的模块列表
@@ -607,15 +665,15 @@ func (e *JSExecutor) warmupModules() error {
 	for _, module := range modulesToWarmup {
 		moduleObj, found := module.getModule()
 		if !found {
-			utils.Warn("模块未注册，跳过预编译", zap.String("module", module.name))
+			e.logger.Warn("模块未注册，跳过预编译", zap.String("module", module.name))
 			continue
 		}
 
-		utils.Debug("预编译模块", zap.String("module", module.name))
+		e.logger.Debug("预编译模块", zap.String("module", module.name))
 		if err := module.precompile(moduleObj); err != nil {
 			// 记录失败状态
 			e.warmupMutex.Lock()
-			e.warmupStats = &model.WarmupStats{
+			e.warmupStats = &execmodel.WarmupStats{
 				Status:       "failed",
 				Modules:      compiledModules,
 				TotalModules: len(modulesToWarmup),
@@ -637,7 +695,7 @@ func (e *JSExecutor) warmupModules() error {
 
 	// 记录成功状态
 	e.warmupMutex.Lock()
-	e.warmupStats = &model.WarmupStats{
+	e.warmupStats = &execmodel.WarmupStats{
 		Status:       "completed",
 		Modules:      compiledModules,
 		TotalModules: len(modulesToWarmup),
@@ -648,7 +706,7 @@ func (e *JSExecutor) warmupModules() error {
 	}
 	e.warmupMutex.Unlock()
 
-	utils.Info("模块预热完成",
+	e.logger.Info("模块预热完成",
 		zap.Int("total_modules", len(modulesToWarmup)),
 		zap.Int("success_count", successCount),
 		zap.Duration("elapsed", elapsed),
@@ -659,7 +717,7 @@ func (e *JSExecutor) warmupModules() error {
 }
 
 // GetWarmupStats 获取预热统计信息
-func (e *JSExecutor) GetWarmupStats() *model.WarmupStats {
+func (e *JSExecutor) GetWarmupStats() *execmodel.WarmupStats {
 	e.warmupMutex.RLock()
 	defer e.warmupMutex.RUnlock()
 
@@ -678,11 +736,52 @@ func (e *JSExecutor) GetMaxConcurrent() int {
 	return e.maxConcurrent
 }
 
+// GetSchedulerStats 获取公平调度器状态（排队位置、等待时间）
+func (e *JSExecutor) GetSchedulerStats() SchedulerStats {
+	return e.scheduler.Stats()
+}
+
+// GetSchedulerWaitLatency 获取等待执行槽位的耗时分布（/metrics 导出）
+func (e *JSExecutor) GetSchedulerWaitLatency() *utils.Histogram {
+	return e.scheduler.waitLatency
+}
+
+// GetExecutionLatency 获取按路由和错误类型统计的执行耗时分布（/metrics 导出）
+func (e *JSExecutor) GetExecutionLatency() *utils.HistogramVec {
+	return e.execLatency
+}
+
+// GetPhaseLatency 获取按阶段统计的耗时分布（/metrics 导出）
+func (e *JSExecutor) GetPhaseLatency() *utils.HistogramVec {
+	return e.phaseLatency
+}
+
+// GetCircuitBreakerState 获取熔断器当前状态
+func (e *JSExecutor) GetCircuitBreakerState() gobreaker.State {
+	return e.circuitBreaker.State()
+}
+
 // GetExecutionTimeout 获取执行超时配置
 func (e *JSExecutor) GetExecutionTimeout() time.Duration {
 	return e.executionTimeout
 }
 
+// GetFetchTransport 获取 fetch / axios 当前使用的 HTTP Transport
+func (e *JSExecutor) GetFetchTransport() http.RoundTripper {
+	if e.fetchEnhancer == nil {
+		return http.DefaultTransport
+	}
+	return e.fetchEnhancer.Transport()
+}
+
+// SetFetchTransport 替换 fetch / axios 使用的 HTTP Transport（必须在第一次执行前调用）
+// 🆕 供 cmd/flowrun 回放录制的响应，服务端不使用
+func (e *JSExecutor) SetFetchTransport(transport http.RoundTripper) {
+	if e.fetchEnhancer != nil {
+		e.fetchEnhancer.SetTransport(transport)
+	}
+}
+
 // GetMaxCodeLength 获取最大代码长度配置
 func (e *JSExecutor) GetMaxCodeLength() int {
 	return e.maxCodeLength
@@ -718,7 +817,7 @@ func (e *JSExecutor) GetAnalyzer() *utils.CodeAnalyzer {
 //  4. 保证原子性（全部成功或全部失败）
 func (e *JSExecutor) initRuntimePool() {
 	startTime := time.Now()
-	utils.Info("并行初始化 JavaScript 运行时池",
+	e.logger.Info("并行初始化 JavaScript 运行时池",
 		zap.Int("pool_size", e.poolSize),
 		zap.Int("cpu_cores", runtime.NumCPU()))
 
@@ -756,13 +855,13 @@ func (e *JSExecutor) initRuntimePool() {
 	}
 
 	if len(errors) > 0 {
-		utils.Error("Runtime 池初始化失败",
+		e.logger.Error("Runtime 池初始化失败",
 			zap.Int("failed_count", len(errors)),
 			zap.Int("total", e.poolSize))
 		for _, err := range errors {
-			utils.Error("初始化错误", zap.Error(err))
+			e.logger.Error("初始化错误", zap.Error(err))
 		}
-		utils.Fatal("Runtime 池初始化失败，服务启动中止")
+		e.logger.Fatal("Runtime 池初始化失败，服务启动中止")
 	}
 
 	// 🔥 批量初始化健康信息（避免并发写入冲突）
@@ -782,7 +881,7 @@ func (e *JSExecutor) initRuntimePool() {
 	e.healthMutex.Unlock()
 
 	elapsed := time.Since(startTime)
-	utils.Info("运行时池初始化完成（并行）",
+	e.logger.Info("运行时池初始化完成（并行）",
 		zap.Int("ready_runtimes", successCount),
 		zap.Duration("elapsed", elapsed),
 		zap.String("speedup", fmt.Sprintf("%.1fx", float64(e.poolSize)*55/float64(elapsed.Milliseconds()))))
@@ -816,7 +915,7 @@ func (e *JSExecutor) setupRuntime(runtime *goja.Runtime) error {
 	// 提前拦截大内存分配（Array, TypedArray），防止在 JavaScript 侧就消耗大量内存
 	if e.jsMemoryLimiter != nil && e.jsMemoryLimiter.IsEnabled() {
 		if err := e.jsMemoryLimiter.RegisterLimiter(runtime); err != nil {
-			utils.Warn("JavaScript 内存限制器注册失败（非致命）", zap.Error(err))
+			e.logger.Warn("JavaScript 内存限制器注册失败（非致命）", zap.Error(err))
 		}
 	}
 
@@ -906,7 +1005,7 @@ func (e *JSExecutor) injectUnicodeRegexPolyfill(runtime *goja.Runtime) {
 
 	_, err := runtime.RunString(polyfillScript)
 	if err != nil {
-		utils.Warn("Unicode 正则表达式 polyfill 注入失败（非致命）", zap.Error(err))
+		e.logger.Warn("Unicode 正则表达式 polyfill 注入失败（非致命）", zap.Error(err))
 	}
 }
 
@@ -917,12 +1016,8 @@ func (e *JSExecutor) setupNodeJSModules(runtime *goja.Runtime) {
 	// 🔥 Console 控制：根据配置决定是否启用
 	// - 开发环境：允许 console 便于调试
 	// - 生产环境：禁用 console 提升性能和安全性
-	if e.allowConsole {
-		console.Enable(runtime)
-	} else {
-		// 🔥 提供友好的错误提示（当用户尝试使用 console 时）
-		e.setupConsoleStub(runtime)
-	}
+	// - capture 模式：每次执行时重新绑定到本次执行的缓冲区（见 beginConsoleCapture）
+	e.setupConsole(runtime, e.consoleMode, nil)
 
 	buffer.Enable(runtime)
 
@@ -953,7 +1048,7 @@ func (e *JSExecutor) setupConsoleStub(runtime *goja.Runtime) {
 	// 创建一个错误提示函数
 	// 🔥 Goja 约定：使用 panic 抛出 JS 错误（标准机制，会被上层 recover 捕获）
 	errorFunc := func(call goja.FunctionCall) goja.Value {
-		panic(&model.ExecutionError{
+		panic(&execmodel.ExecutionError{
 			Type:    "ConsoleDisabledError",
 			Message: "console 代码禁止使用",
 		})
@@ -1202,21 +1297,21 @@ func (e *JSExecutor) disableConstructorAccess(runtime *goja.Runtime) {
 		})();
 	`)
 	if err != nil {
-		utils.Warn("沙箱加固失败", zap.Error(err))
+		e.logger.Warn("沙箱加固失败", zap.Error(err))
 	}
 
-	utils.Debug("沙箱已加固（5层防护）")
+	e.logger.Debug("沙箱已加固（5层防护）")
 }
 
 // Execute 执行 JavaScript 代码（智能路由：同步用池，异步用 EventLoop）
-// 🔥 核心机制：Context 传递、Semaphore 并发控制、熔断器保护、优雅关闭支持
+// 🔥 核心机制：Context 传递、公平调度并发控制、熔断器保护、优雅关闭支持
 //
 // 🛡️ Panic 安全保证：
 //   - executeWithRuntimePool 内部有 defer recover 保护
 //   - executeWithEventLoop 内部有 defer recover 保护
 //   - Execute 的 defer 在所有路径都会执行（Go runtime 保证）
-//   - 多层防护确保 semaphore 和 WaitGroup 永不泄漏
-func (e *JSExecutor) Execute(ctx context.Context, code string, input map[string]interface{}) (*model.ExecutionResult, error) {
+//   - 多层防护确保调度槽位和 WaitGroup 永不泄漏
+func (e *JSExecutor) Execute(ctx context.Context, code string, input map[string]interface{}) (*execmodel.ExecutionResult, error) {
 	// 🔥 熔断器保护：防止重度过载时所有请求都等待 10s
 	result, err := e.circuitBreaker.Execute(func() (interface{}, error) {
 		return e.executeInternal(ctx, code, input)
@@ -1226,22 +1321,22 @@ func (e *JSExecutor) Execute(ctx context.Context, code string, input map[string]
 	if err != nil {
 		if err == gobreaker.ErrOpenState {
 			// 熔断器打开，立即返回
-			utils.Warn("熔断器拒绝请求",
+			e.logger.Warn("熔断器拒绝请求",
 				zap.String("state", "Open"),
 				zap.String("reason", "系统过载，快速失败"),
 			)
-			return nil, &model.ExecutionError{
+			return nil, &execmodel.ExecutionError{
 				Type:    "ServiceUnavailableError",
 				Message: "服务过载，请稍后重试",
 			}
 		}
 		if err == gobreaker.ErrTooManyRequests {
 			// Half-Open 状态，探测请求数量已满
-			utils.Debug("熔断器限流",
+			e.logger.Debug("熔断器限流",
 				zap.String("state", "Half-Open"),
 				zap.String("reason", "探测请求数量已满"),
 			)
-			return nil, &model.ExecutionError{
+			return nil, &execmodel.ExecutionError{
 				Type:    "ServiceUnavailableError",
 				Message: "服务恢复中，请稍后重试",
 			}
@@ -1259,12 +1354,12 @@ func (e *JSExecutor) Execute(ctx context.Context, code string, input map[string]
 	}
 
 	// 执行成功
-	return result.(*model.ExecutionResult), nil
+	return result.(*execmodel.ExecutionResult), nil
 }
 
 // executeInternal 内部执行逻辑（被熔断器包装）
 // 执行流程分为8个步骤，每个步骤都有明确的职责和错误处理
-func (e *JSExecutor) executeInternal(ctx context.Context, code string, input map[string]interface{}) (*model.ExecutionResult, error) {
+func (e *JSExecutor) executeInternal(ctx context.Context, code string, input map[string]interface{}) (*execmodel.ExecutionResult, error) {
 	startTime := time.Now()
 
 	// ==================== 步骤1: 优雅关闭检查 ====================
@@ -1272,7 +1367,7 @@ func (e *JSExecutor) executeInternal(ctx context.Context, code string, input map
 	// 成本：select 操作 ~10ns（可忽略）
 	select {
 	case <-e.shutdown:
-		return nil, &model.ExecutionError{
+		return nil, &execmodel.ExecutionError{
 			Type:    "ServiceUnavailableError",
 			Message: "服务正在关闭，不再接受新请求",
 		}
@@ -1286,7 +1381,7 @@ func (e *JSExecutor) executeInternal(ctx context.Context, code string, input map
 	// 成本：select 操作 ~10ns（可忽略）
 	select {
 	case <-ctx.Done():
-		return nil, &model.ExecutionError{
+		return nil, &execmodel.ExecutionError{
 			Type:    "CancelledError",
 			Message: "请求已取消",
 		}
@@ -1303,31 +1398,38 @@ func (e *JSExecutor) executeInternal(ctx context.Context, code string, input map
 	// ==================== 步骤4: 输入验证（支持 Context 取消） ====================
 	// 目的：验证代码和输入的合法性，防止注入攻击和无效输入
 	// 特性：验证过程支持 Context 取消，避免长时间阻塞
-	if err := e.validateInputWithContext(ctx, code, input); err != nil {
-		return nil, err
+	// 🆕 限制来自 context 中的沙箱策略（未设置的字段使用全局配置）
+	limits := e.limitsFromContext(ctx)
+	// 🆕 分阶段耗时：没有上层 timeline 时（异步任务、批量执行）自行创建，保证阶段统计完整
+	timeline := timelineFromContext(ctx)
+	if timeline == nil {
+		timeline = e.NewTimeline()
+		ctx = WithTimeline(ctx, timeline)
+	}
+	validateCtx, validateSpan := utils.StartSpan(ctx, "execute.validate", attribute.Int("code.length", len(code)))
+	validateErr := e.validateInputWithContext(validateCtx, code, input, limits)
+	utils.EndSpan(validateSpan, validateErr)
+	if validateErr != nil {
+		return nil, validateErr
 	}
 
-	// ==================== 步骤5: 并发控制（Semaphore + Context） ====================
-	// 目的：限制并发执行数量，防止系统过载
+	// ==================== 步骤5: 并发控制（公平调度 + Context） ====================
+	// 目的：限制并发执行数量，防止系统过载，并避免单个 Token 占满所有槽位
 	// 机制：
-	//   - 使用 semaphore 限制并发（默认 100）
+	//   - 按 Token / 工作空间加权公平分配 MAX_CONCURRENT_EXECUTIONS 个槽位（见 fairScheduler）
 	//   - 监听 Context 取消信号，避免无限等待
-	//   - 等待超时后返回 ConcurrencyError（可配置，默认 10s）
-	// defer 确保即使 panic 也会释放 semaphore
-	select {
-	case e.semaphore <- struct{}{}:
-		defer func() { <-e.semaphore }()
-	case <-ctx.Done():
-		return nil, &model.ExecutionError{
-			Type:    "CancelledError",
-			Message: "请求已取消",
-		}
-	case <-time.After(e.concurrencyWaitTimeout):
-		return nil, &model.ExecutionError{
-			Type:    "ConcurrencyError",
-			Message: fmt.Sprintf("系统繁忙，请稍后重试（等待超时: %v）", e.concurrencyWaitTimeout),
-		}
+	//   - 等待超时返回 ConcurrencyError，分组排队已满返回 QueueFullError（均带 RetryAfter 估算）
+	// defer 确保即使 panic 也会释放槽位
+	// 🆕 链路追踪：execute.schedule 覆盖排队等待槽位的时间
+	_, scheduleSpan := utils.StartSpan(ctx, "execute.schedule")
+	scheduleStart := time.Now()
+	release, acquireErr := e.scheduler.acquire(ctx, e.scheduler.ticketFor(ctx, SandboxPolicyFromContext(ctx)))
+	timeline.Since(PhaseSchedule, scheduleStart, "")
+	utils.EndSpan(scheduleSpan, acquireErr)
+	if acquireErr != nil {
+		return nil, acquireErr
 	}
+	defer release()
 
 	// ==================== 步骤6: 更新统计信息 ====================
 	// 目的：记录当前执行数和总执行数，用于监控和限流
@@ -1341,37 +1443,63 @@ func (e *JSExecutor) executeInternal(ctx context.Context, code string, input map
 	// 策略：
 	//   - 同步代码（无 async/await/Promise）：使用 Runtime 池（高性能，低延迟）
 	//   - 异步代码（有 async/await/Promise）：使用 EventLoop（支持异步操作）
-	var result *model.ExecutionResult
+	var result *execmodel.ExecutionResult
 	var err error
+	route := "sync"
+	if !e.analyzer.ShouldUseRuntimePool(code) {
+		route = "async"
+	}
 
-	if e.analyzer.ShouldUseRuntimePool(code) {
+	// 🆕 登记为正在执行（GET /flow/executions/running），管理员终止时取消 killCtx
+	// 没有 request_id 的内部调用在这里生成，保证登记和执行使用同一个 ID
+	requestID, _ := ctx.Value(utils.RequestIDKey).(string)
+	if requestID == "" {
+		requestID = e.generateExecutionId()
+		ctx = context.WithValue(ctx, utils.RequestIDKey, requestID)
+	}
+	runningRoute := RunningRoutePool
+	if route == "async" {
+		runningRoute = RunningRouteEventLoop
+	}
+	killCtx, run, unregister := e.running.register(ctx, requestID, code, runningRoute)
+	defer unregister()
+
+	// 🆕 链路追踪：execute.run 覆盖编译 + 脚本执行，出站 fetch/axios 的 span 挂在其下
+	runCtx, runSpan := utils.StartSpan(killCtx, "execute.run", attribute.String("execute.route", route))
+	if route == "sync" {
 		// 同步代码路径：使用 Runtime 池执行
 		atomic.AddInt64(&e.stats.SyncExecutions, 1)
-		result, err = e.executeWithRuntimePool(ctx, code, input)
+		result, err = e.executeWithRuntimePool(runCtx, code, input, limits)
 	} else {
 		// 异步代码路径：使用 EventLoop 执行
 		atomic.AddInt64(&e.stats.AsyncExecutions, 1)
-		result, err = e.executeWithEventLoop(ctx, code, input)
+		result, err = e.executeWithEventLoop(runCtx, code, input, limits)
+	}
+	// 🆕 被管理员终止：无论中断表现为 CancelledError 还是脚本内的中断错误，统一返回 KilledError
+	if err != nil && run.isKilled() {
+		result, err = nil, killedError(err)
 	}
+	utils.EndSpan(runSpan, err)
 
 	// ==================== 步骤8: 记录执行时间和更新统计 ====================
 	// 目的：记录性能指标，用于监控和优化
 	executionTime := time.Since(startTime)
+	errorType := "none"
+	if err != nil {
+		if execErr, ok := err.(*execmodel.ExecutionError); ok {
+			errorType = execErr.Type
+		} else {
+			errorType = "unknown"
+		}
+	}
+	e.execLatency.With(route, errorType).ObserveDuration(executionTime) // 🆕 /metrics 执行耗时直方图
 
 	// 🔥 慢执行检测（帮助定位性能问题）
 	// 从配置读取阈值，支持环境变量控制
 	if executionTime > e.slowExecutionThreshold {
-		codeHash := hashCode(code) // 固定返回 16 字符
-		errorType := "none"
-		if err != nil {
-			if execErr, ok := err.(*model.ExecutionError); ok {
-				errorType = execErr.Type
-			} else {
-				errorType = "unknown"
-			}
-		}
+		codeHash := HashCode(code) // 固定返回 16 字符
 
-		utils.Warn("慢执行检测",
+		e.logger.Warn("慢执行检测",
 			zap.Duration("execution_time", executionTime),
 			zap.Duration("threshold", e.slowExecutionThreshold),
 			zap.String("code_hash", codeHash), // 直接使用，无需截取
@@ -0,0 +1,138 @@
+package sandbox
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/model/execmodel"
+	"flow-codeblock-go/utils"
+)
+
+// 执行阶段名称（ExecutionTimeline / flow_execution_phase_duration_seconds 的 phase 标签）
+const (
+	PhaseAuth           = "auth"            // Token 校验（热缓存 / Redis / 数据库）
+	PhaseQuota          = "quota"           // 配额扣减
+	PhaseDecode         = "decode"          // Base64 解码
+	PhaseResolve        = "resolve"         // 🆕 存储脚本解析（按名称 / 版本 / 别名查找代码）
+	PhaseValidate       = "validate"        // 代码和输入校验
+	PhaseSchedule       = "schedule"        // 等待执行槽位（公平调度）
+	PhaseRuntimeAcquire = "runtime_acquire" // 获取 Runtime / EventLoop
+	PhaseCompile        = "compile"         // 代码编译
+	PhaseRun            = "run"             // 脚本执行（EventLoop 包含异步等待）
+	PhaseExport         = "export"          // 结果导出和序列化（ExportWithOrderAndLimit + 结果校验）
+)
+
+// phaseLatencyBuckets 阶段耗时分桶（秒）：多数平台阶段在微秒到毫秒级，比执行耗时的分桶更细
+var phaseLatencyBuckets = []float64{0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// 缓存命中情况（ExecutionPhase.Cache）
+const (
+	PhaseCacheHit  = "hit"
+	PhaseCacheMiss = "miss"
+)
+
+// ExecutionTimeline 单次请求的分阶段耗时
+// 🆕 用于区分"平台慢"和"脚本慢"：每个阶段记录时同时计入 JSExecutor 的阶段耗时直方图
+//
+// 通过 context 传递（WithTimeline），控制器记录认证 / 配额 / 解码，执行器记录其余阶段；
+// 执行器在 context 中没有 timeline 时（异步任务、批量执行）自行创建，保证统计始终完整
+type ExecutionTimeline struct {
+	histogram *utils.HistogramVec // 阶段耗时直方图（标签：phase, cache）
+
+	mu     sync.Mutex
+	phases []execmodel.ExecutionPhase
+}
+
+type timelineKey struct{}
+
+// NewTimeline 创建分阶段耗时记录（阶段耗时计入本执行器的统计）
+func (e *JSExecutor) NewTimeline() *ExecutionTimeline {
+	return &ExecutionTimeline{histogram: e.phaseLatency}
+}
+
+// WithTimeline 把分阶段耗时记录放入 context
+func WithTimeline(ctx context.Context, timeline *ExecutionTimeline) context.Context {
+	return context.WithValue(ctx, timelineKey{}, timeline)
+}
+
+// timelineFromContext 获取 context 中的分阶段耗时记录（未设置时返回 nil，nil 上的记录操作为空操作）
+func timelineFromContext(ctx context.Context) *ExecutionTimeline {
+	timeline, _ := ctx.Value(timelineKey{}).(*ExecutionTimeline)
+	return timeline
+}
+
+// Record 记录一个阶段的耗时，cache 为空表示该阶段不涉及缓存
+func (t *ExecutionTimeline) Record(phase string, d time.Duration, cache string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.phases = append(t.phases, execmodel.ExecutionPhase{
+		Phase:      phase,
+		DurationMs: float64(d.Microseconds()) / 1000,
+		Cache:      cache,
+	})
+	t.mu.Unlock()
+
+	if t.histogram != nil {
+		label := cache
+		if label == "" {
+			label = "none"
+		}
+		t.histogram.With(phase, label).ObserveDuration(d)
+	}
+}
+
+// Since 记录从 start 到现在的阶段耗时
+func (t *ExecutionTimeline) Since(phase string, start time.Time, cache string) {
+	t.Record(phase, time.Since(start), cache)
+}
+
+// Phases 返回已记录的阶段（副本，按记录顺序）
+func (t *ExecutionTimeline) Phases() []execmodel.ExecutionPhase {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]execmodel.ExecutionPhase(nil), t.phases...)
+}
+
+// cacheResult 把命中情况转换为 PhaseCacheHit / PhaseCacheMiss
+func cacheResult(hit bool) string {
+	if hit {
+		return PhaseCacheHit
+	}
+	return PhaseCacheMiss
+}
+
+// PhaseStats 阶段耗时汇总（/flow/status）
+type PhaseStats struct {
+	Phase string  `json:"phase"`
+	Cache string  `json:"cache"` // hit / miss / none
+	Count uint64  `json:"count"`
+	AvgMs float64 `json:"avgMs"`
+}
+
+// GetPhaseStats 获取各阶段的累计次数和平均耗时（按阶段、缓存命中情况排序）
+func (e *JSExecutor) GetPhaseStats() []PhaseStats {
+	totals := e.phaseLatency.Totals()
+	stats := make([]PhaseStats, 0, len(totals))
+	for _, total := range totals {
+		stat := PhaseStats{Phase: total.LabelValues[0], Cache: total.LabelValues[1], Count: total.Count}
+		if total.Count > 0 {
+			stat.AvgMs = math.Round(total.Sum/float64(total.Count)*1e6) / 1000
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Phase != stats[j].Phase {
+			return stats[i].Phase < stats[j].Phase
+		}
+		return stats[i].Cache < stats[j].Cache
+	})
+	return stats
+}
@@ -0,0 +1,246 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"flow-codeblock-go/model/execmodel"
+	"flow-codeblock-go/utils"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+)
+
+// findingCollector 收集校验发现（与已报告区间重叠的命中不重复报告）
+type findingCollector struct {
+	code     string // 原始代码（用于计算行列号）
+	findings []execmodel.CodeFinding
+	covered  [][2]int // 已报告的区间
+}
+
+func newFindingCollector(code string) *findingCollector {
+	return &findingCollector{
+		code:     code,
+		findings: make([]execmodel.CodeFinding, 0),
+	}
+}
+
+// add 添加一条无位置信息的发现
+func (fc *findingCollector) add(errType, rule, message string) {
+	fc.findings = append(fc.findings, execmodel.CodeFinding{
+		Type:    errType,
+		Rule:    rule,
+		Message: message,
+	})
+}
+
+// addAt 添加一条带位置信息的发现（start/end 为代码中的字节偏移）
+func (fc *findingCollector) addAt(e *JSExecutor, errType, rule, message string, start, end int) {
+	for _, r := range fc.covered {
+		if start < r[1] && end > r[0] {
+			return
+		}
+	}
+	fc.covered = append(fc.covered, [2]int{start, end})
+
+	line, column, snippet := e.findLineAndColumn(fc.code, start)
+	fc.findings = append(fc.findings, execmodel.CodeFinding{
+		Type:    errType,
+		Rule:    rule,
+		Message: message,
+		Line:    line,
+		Column:  column,
+		Match:   fc.code[start:end],
+		Snippet: strings.TrimSpace(snippet),
+	})
+}
+
+// ValidateCodeReport 运行完整的校验流程并报告全部发现（不执行代码、不写入缓存）
+//
+// 🆕 与 validateCode 使用相同的检查项和检查顺序，区别在于：
+//   - validateCode 遇到第一个问题即返回（执行路径性能优先）
+//   - ValidateCodeReport 报告每一处命中及其行列号（编辑器场景）
+//
+// 说明：
+//   - 代码先经过与执行时相同的归一化（NFC + 过滤零宽字符），行列号基于归一化后的代码
+//   - 安全检查基于语法树（见 analyzeCodeSecurity），命中位置为用户代码中的精确位置
+//   - 编译检查使用与实际执行路由一致的包装方式，行列号已还原为用户代码位置
+//   - ctx 中的沙箱策略参与检查（模块允许列表、网络权限、console 模式）
+func (e *JSExecutor) ValidateCodeReport(ctx context.Context, code string) *execmodel.ValidateReport {
+	limits := e.limitsFromContext(ctx)
+	code = e.normalizeCode(code)
+	fc := newFindingCollector(code)
+
+	// 1. 长度检查
+	if len(code) > e.maxCodeLength {
+		fc.add("ValidationError", "length",
+			fmt.Sprintf("代码长度超过限制: %d > %d字节", len(code), e.maxCodeLength))
+	}
+
+	cleanedCode := e.removeStringsAndComments(code)
+
+	// 2. return 语句检查
+	if err := e.validateReturnStatementCleaned(cleanedCode); err != nil {
+		fc.add("ValidationError", "return", err.(*execmodel.ExecutionError).Message)
+	}
+
+	// 3. 安全检查（AST 分析的全部发现，按位置排序）+ console 检查
+	// 解析失败的发现在编译检查之后处理（普通语法错误已由编译检查报告，避免重复）
+	var parseFailure *securityFinding
+	for _, f := range analyzeCodeSecurity(code, limits) {
+		if f.rule == securityRuleParse {
+			parseFailure = &f
+			continue
+		}
+		fc.addAt(e, f.errorType(), f.rule, f.message, f.start, f.end)
+	}
+
+	if err := e.checkConsoleUsage(code, cleanedCode, limits); err != nil {
+		line, column, snippet := e.findConsoleInActualCode(code)
+		fc.findings = append(fc.findings, execmodel.CodeFinding{
+			Type:    "ConsoleDisabledError",
+			Rule:    "console",
+			Message: "代码中禁止使用 console（生产环境已禁用 console）",
+			Line:    line,
+			Column:  column,
+			Match:   "console",
+			Snippet: strings.TrimSpace(snippet),
+		})
+	}
+
+	// 4. 路由分析（与 ShouldUseRuntimePool 的判定一致）
+	features := e.analyzer.AnalyzeCode(code)
+	useRuntimePool := !features.UseEventLoop
+	route := &execmodel.CodeRouteInfo{
+		Type:         features.EstimatedType,
+		UseEventLoop: features.UseEventLoop,
+		Reasons:      features.AsyncReasons,
+		Confidence:   features.Confidence,
+		Trigger:      features.Trigger,
+		Method:       features.Method,
+	}
+
+	// 5. 编译检查（包装方式与执行路由一致）
+	e.collectSyntaxFindings(fc, code, useRuntimePool)
+
+	// 🔥 包装后能编译、单独却无法解析为函数体的代码（试图闭合执行包装）同样拒绝
+	if parseFailure != nil && !hasFindingType(fc.findings, "SyntaxError") {
+		fc.addAt(e, parseFailure.errorType(), parseFailure.rule, parseFailure.message, parseFailure.start, parseFailure.end)
+	}
+
+	moduleInfo := utils.ParseModuleUsage(code)
+
+	return &execmodel.ValidateReport{
+		Valid:      len(fc.findings) == 0,
+		CodeLength: len(code),
+		Findings:   fc.findings,
+		Route:      route,
+		Modules: &execmodel.CodeModuleInfo{
+			HasRequire:  moduleInfo.HasRequire,
+			Modules:     moduleInfo.Modules,
+			ModuleCount: moduleInfo.ModuleCount,
+		},
+	}
+}
+
+// hasFindingType 是否已有指定类型的发现
+func hasFindingType(findings []execmodel.CodeFinding, findingType string) bool {
+	for _, f := range findings {
+		if f.Type == findingType {
+			return true
+		}
+	}
+	return false
+}
+
+// collectSyntaxFindings 编译用户代码并收集语法错误（不写入编译缓存）
+func (e *JSExecutor) collectSyntaxFindings(fc *findingCollector, code string, useRuntimePool bool) {
+	// 包装后用户代码前的行数与首行缩进（见 wrapCodeForRuntimePool / wrapCodeForEventLoop）
+	wrappedCode, lineOffset, firstLineIndent := wrapCodeForRuntimePool(code), 4, 4
+	if !useRuntimePool {
+		wrappedCode, lineOffset, firstLineIndent = wrapCodeForEventLoop(code), 9, 7
+	}
+	firstUserLine := 1
+	if strings.HasPrefix(code, "#!") {
+		lineOffset-- // Shebang 行已被移除，包装后的首行对应用户代码第 2 行
+		firstUserLine = 2
+	}
+	lastUserLine := strings.Count(code, "\n") + 1
+
+	// 分两步编译（等价于 goja.Compile）：goja.Compile 会把解析错误压平为一条消息，
+	// 直接调用解析器可以拿到每条错误的行列号
+	program, err := parser.ParseFile(nil, "user_code.js", wrappedCode, 0)
+	if err == nil {
+		_, err = goja.CompileAST(program, true)
+	}
+	if err == nil {
+		return
+	}
+
+	// 解析器在第一个错误后会继续报告包装代码中的连锁错误，只保留落在用户代码范围内的错误；
+	// 全部落在范围外时保留第一条（不带位置）
+	var syntaxFindings []execmodel.CodeFinding
+	var firstMessage string
+	addSyntax := func(line, column int, message string) {
+		if firstMessage == "" {
+			firstMessage = message
+		}
+		userLine := line - lineOffset
+		if userLine < firstUserLine || userLine > lastUserLine {
+			return
+		}
+		if userLine == firstUserLine {
+			column -= firstLineIndent
+		}
+		if column < 1 {
+			column = 1
+		}
+		_, _, snippet := e.findLineAndColumn(code, lineStartIndex(code, userLine))
+		syntaxFindings = append(syntaxFindings, execmodel.CodeFinding{
+			Type:    "SyntaxError",
+			Rule:    "syntax",
+			Message: message,
+			Line:    userLine,
+			Column:  column,
+			Snippet: strings.TrimSpace(snippet),
+		})
+	}
+
+	switch compileErr := err.(type) {
+	case parser.ErrorList:
+		for _, parseErr := range compileErr {
+			addSyntax(parseErr.Position.Line, parseErr.Position.Column, parseErr.Message)
+		}
+	case *parser.Error:
+		addSyntax(compileErr.Position.Line, compileErr.Position.Column, compileErr.Message)
+	case *goja.CompilerSyntaxError:
+		if compileErr.File != nil {
+			pos := compileErr.File.Position(compileErr.Offset)
+			addSyntax(pos.Line, pos.Column, compileErr.Message)
+		} else {
+			addSyntax(0, 0, compileErr.Message)
+		}
+	default:
+		addSyntax(0, 0, err.Error())
+	}
+
+	if len(syntaxFindings) == 0 {
+		fc.add("SyntaxError", "syntax", firstMessage)
+		return
+	}
+	fc.findings = append(fc.findings, syntaxFindings...)
+}
+
+// lineStartIndex 返回第 line 行（从 1 开始）的起始字节偏移
+func lineStartIndex(code string, line int) int {
+	idx := 0
+	for l := 1; l < line; l++ {
+		next := strings.IndexByte(code[idx:], '\n')
+		if next == -1 {
+			return len(code)
+		}
+		idx += next + 1
+	}
+	return idx
+}
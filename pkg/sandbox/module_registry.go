@@ -1,11 +1,9 @@
-package service
+package sandbox
 
 import (
 	"fmt"
 	"sync"
 
-	"flow-codeblock-go/utils"
-
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/require"
 	"go.uber.org/zap"
@@ -62,12 +60,17 @@ type ModuleEnhancer interface {
 type ModuleRegistry struct {
 	modules []ModuleEnhancer
 	mu      sync.RWMutex
+	logger  *zap.Logger
 }
 
-// NewModuleRegistry 创建新的模块注册器
-func NewModuleRegistry() *ModuleRegistry {
+// NewModuleRegistry 创建新的模块注册器（logger 为空时不输出日志）
+func NewModuleRegistry(logger *zap.Logger) *ModuleRegistry {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
 	return &ModuleRegistry{
 		modules: make([]ModuleEnhancer, 0, 10), // 预分配容量
+		logger:  logger,
 	}
 }
 
@@ -81,7 +84,7 @@ func (mr *ModuleRegistry) Register(module ModuleEnhancer) {
 	defer mr.mu.Unlock()
 
 	mr.modules = append(mr.modules, module)
-	utils.Debug("注册模块", zap.String("module", module.Name()))
+	mr.logger.Debug("注册模块", zap.String("module", module.Name()))
 }
 
 // RegisterAll 将所有模块注册到 require 系统
@@ -96,16 +99,16 @@ func (mr *ModuleRegistry) RegisterAll(registry *require.Registry) error {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
 
-	utils.Debug("开始注册模块到 require 系统", zap.Int("module_count", len(mr.modules)))
+	mr.logger.Debug("开始注册模块到 require 系统", zap.Int("module_count", len(mr.modules)))
 
 	for i, module := range mr.modules {
-		utils.Debug("注册模块到 require 系统", zap.Int("index", i+1), zap.Int("total", len(mr.modules)), zap.String("module", module.Name()))
+		mr.logger.Debug("注册模块到 require 系统", zap.Int("index", i+1), zap.Int("total", len(mr.modules)), zap.String("module", module.Name()))
 		if err := module.Register(registry); err != nil {
 			return fmt.Errorf("failed to register module %s: %w", module.Name(), err)
 		}
 	}
 
-	utils.Info("所有模块已成功注册到 require 系统")
+	mr.logger.Info("所有模块已成功注册到 require 系统")
 	return nil
 }
 
@@ -187,17 +190,17 @@ func (mr *ModuleRegistry) CloseAll() error {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
 
-	utils.Info("开始关闭所有模块")
+	mr.logger.Info("开始关闭所有模块")
 
 	var errors []error
 	successCount := 0
 
 	for _, module := range mr.modules {
 		moduleName := module.Name()
-		utils.Info("关闭模块", zap.String("module", moduleName))
+		mr.logger.Info("关闭模块", zap.String("module", moduleName))
 
 		if err := module.Close(); err != nil {
-			utils.Warn("模块关闭失败",
+			mr.logger.Warn("模块关闭失败",
 				zap.String("module", moduleName),
 				zap.Error(err),
 			)
@@ -207,7 +210,7 @@ func (mr *ModuleRegistry) CloseAll() error {
 		}
 	}
 
-	utils.Info("模块关闭完成",
+	mr.logger.Info("模块关闭完成",
 		zap.Int("total", len(mr.modules)),
 		zap.Int("success", successCount),
 		zap.Int("failed", len(errors)),
@@ -0,0 +1,184 @@
+// Package sandbox 可嵌入的 JavaScript 沙箱执行器
+//
+// 与 Flow-CodeBlock 服务端使用同一个执行器（Runtime 池、EventLoop 池、公平调度、
+// AST 安全检查、执行超时、JS 内存限制、SSRF 防护、内置模块），不依赖 gin、sqlx 和 Redis：
+//
+//	executor, err := sandbox.New(
+//		sandbox.WithPool(2, 4, 16),
+//		sandbox.WithTimeout(5*time.Second),
+//		sandbox.WithModules(myEnhancer),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	defer executor.Shutdown()
+//
+//	result, err := executor.Run(ctx, "return input.a + input.b", map[string]interface{}{"a": 1, "b": 2})
+//
+// 单次执行的限制可以用 WithSandboxPolicy 放入 ctx（与服务端的 Token 沙箱策略相同）；
+// 失败时返回的 error 是 *Error（含 Type / Message / Stack）
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"flow-codeblock-go/model/execmodel"
+
+	"go.uber.org/zap"
+)
+
+// Result 执行结果
+type Result = execmodel.ExecutionResult
+
+// Error 执行错误（Type 如 ValidationError、TimeoutError、RuntimeError、ConcurrencyError）
+type Error = execmodel.ExecutionError
+
+// Policy 单次执行的沙箱策略（通过 WithSandboxPolicy 放入 ctx）
+type Policy = execmodel.SandboxPolicy
+
+// Option 配置选项
+type Option func(*Config)
+
+// New 创建执行器
+//
+// 在 DefaultConfig() 上依次应用 opts；创建时会初始化 Runtime 池和 EventLoop 池并预编译内置模块，
+// 不再使用时调用 Shutdown 释放资源
+func New(opts ...Option) (*JSExecutor, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return newJSExecutor(&cfg), nil
+}
+
+// Run 执行代码（Execute 的别名）
+func (e *JSExecutor) Run(ctx context.Context, code string, input map[string]interface{}) (*Result, error) {
+	return e.Execute(ctx, code, input)
+}
+
+// WithConfig 整体替换配置（之后的 Option 在它的基础上修改）
+func WithConfig(cfg Config) Option {
+	return func(c *Config) {
+		*c = cfg
+	}
+}
+
+// WithPool 设置 Runtime 池大小（最小 / 初始 / 最大）
+func WithPool(minSize, size, maxSize int) Option {
+	return func(c *Config) {
+		c.Executor.MinPoolSize = minSize
+		c.Executor.PoolSize = size
+		c.Executor.MaxPoolSize = maxSize
+	}
+}
+
+// WithEventLoopPool 设置 EventLoop 池大小（最小 / 初始 / 最大，异步代码使用）
+func WithEventLoopPool(minSize, size, maxSize int) Option {
+	return func(c *Config) {
+		c.Executor.MinEventLoopPoolSize = minSize
+		c.Executor.EventLoopPoolSize = size
+		c.Executor.MaxEventLoopPoolSize = maxSize
+	}
+}
+
+// WithMaxConcurrent 设置最大并发执行数
+func WithMaxConcurrent(n int) Option {
+	return func(c *Config) {
+		c.Executor.MaxConcurrent = n
+	}
+}
+
+// WithTimeout 设置执行超时（策略中的 execution_timeout_ms 可以按次覆盖）
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.Executor.ExecutionTimeout = timeout
+	}
+}
+
+// WithLimits 设置代码长度、输入大小、结果大小上限（字节）
+func WithLimits(maxCodeLength, maxInputSize, maxResultSize int) Option {
+	return func(c *Config) {
+		c.Executor.MaxCodeLength = maxCodeLength
+		c.Executor.MaxInputSize = maxInputSize
+		c.Executor.MaxResultSize = maxResultSize
+	}
+}
+
+// WithMemoryLimit 设置 JavaScript 单次分配的大小上限（MB），<= 0 时关闭 JS 侧内存限制
+func WithMemoryLimit(limitMB int64) Option {
+	return func(c *Config) {
+		c.Executor.EnableJSMemoryLimit = limitMB > 0
+		c.Executor.JSMemoryLimitMB = limitMB
+	}
+}
+
+// WithSSRFProtection 设置 fetch / axios 的 SSRF 防护（allowPrivateIP 为 true 时允许访问内网地址）
+func WithSSRFProtection(enabled, allowPrivateIP bool) Option {
+	return func(c *Config) {
+		c.Fetch.EnableSSRFProtection = enabled
+		c.Fetch.AllowPrivateIP = allowPrivateIP
+	}
+}
+
+// WithConsoleMode 设置 console 输出模式（ConsoleModeDisabled / ConsoleModeStdout / ConsoleModeCapture）
+func WithConsoleMode(mode string) Option {
+	return func(c *Config) {
+		c.Executor.ConsoleMode = mode
+		c.Executor.AllowConsole = mode != ConsoleModeDisabled
+	}
+}
+
+// WithModules 追加模块增强器（在内置模块之后注册，可以被 require 或在 Setup 中设置全局对象）
+func WithModules(modules ...ModuleEnhancer) Option {
+	return func(c *Config) {
+		c.Modules = append(c.Modules, modules...)
+	}
+}
+
+// WithLogger 设置执行器日志（只作用于这个执行器；未设置时不输出日志）
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// validate 校验配置（服务端的 config.Validate 校验范围更大，这里只检查执行器无法运行的配置）
+func (c *Config) validate() error {
+	ex := &c.Executor
+	if ex.MinPoolSize < 1 || ex.PoolSize < ex.MinPoolSize || ex.MaxPoolSize < ex.PoolSize {
+		return fmt.Errorf("sandbox: Runtime 池大小必须满足 1 <= min <= size <= max，当前值: %d / %d / %d",
+			ex.MinPoolSize, ex.PoolSize, ex.MaxPoolSize)
+	}
+	if ex.MinEventLoopPoolSize < 1 || ex.EventLoopPoolSize < ex.MinEventLoopPoolSize || ex.MaxEventLoopPoolSize < ex.EventLoopPoolSize {
+		return fmt.Errorf("sandbox: EventLoop 池大小必须满足 1 <= min <= size <= max，当前值: %d / %d / %d",
+			ex.MinEventLoopPoolSize, ex.EventLoopPoolSize, ex.MaxEventLoopPoolSize)
+	}
+	if ex.MaxConcurrent < 1 {
+		return fmt.Errorf("sandbox: MaxConcurrent 必须 >= 1，当前值: %d", ex.MaxConcurrent)
+	}
+	if ex.ExecutionTimeout <= 0 {
+		return fmt.Errorf("sandbox: ExecutionTimeout 必须 > 0，当前值: %v", ex.ExecutionTimeout)
+	}
+	if ex.MaxCodeLength < 1 || ex.MaxInputSize < 1 || ex.MaxResultSize < 1 {
+		return fmt.Errorf("sandbox: MaxCodeLength / MaxInputSize / MaxResultSize 必须 >= 1")
+	}
+	if ex.MaxRuntimeReuseCount < 1 {
+		return fmt.Errorf("sandbox: MaxRuntimeReuseCount 必须 >= 1，当前值: %d", ex.MaxRuntimeReuseCount)
+	}
+	switch ex.ConsoleMode {
+	case ConsoleModeDisabled, ConsoleModeStdout, ConsoleModeCapture:
+	default:
+		return fmt.Errorf("sandbox: 不支持的 ConsoleMode: %q", ex.ConsoleMode)
+	}
+	for _, module := range c.Modules {
+		if module == nil {
+			return fmt.Errorf("sandbox: Modules 中不能有 nil")
+		}
+	}
+	return nil
+}
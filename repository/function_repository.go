@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ErrFunctionNameExists 同一工作空间内脚本名称已存在
+var ErrFunctionNameExists = errors.New("脚本名称已存在")
+
+// functionVersionSummaryColumns 版本列表查询的字段（不含代码）
+const functionVersionSummaryColumns = `id, function_id, version, '' AS code, code_hash, code_length, comment, created_by, created_at`
+
+// FunctionRepository 存储脚本数据访问层（stored_functions / stored_function_versions / stored_function_aliases 表）
+type FunctionRepository struct {
+	db *sqlx.DB
+}
+
+// NewFunctionRepository 创建存储脚本 Repository
+func NewFunctionRepository(db *sqlx.DB) *FunctionRepository {
+	return &FunctionRepository{db: db}
+}
+
+// Create 创建脚本并发布版本 1（latest 指向版本 1）
+func (r *FunctionRepository) Create(ctx context.Context, fn *model.StoredFunction, version *model.FunctionVersion) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO stored_functions (ws_id, name, description, latest_version, created_by) VALUES (?, ?, ?, 1, ?)`,
+		fn.WsID, fn.Name, fn.Description, fn.CreatedBy)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return ErrFunctionNameExists
+		}
+		utils.Error("创建脚本失败", zap.Error(err), zap.String("ws_id", fn.WsID), zap.String("name", fn.Name))
+		return fmt.Errorf("创建脚本失败: %w", err)
+	}
+	functionID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取脚本ID失败: %w", err)
+	}
+
+	version.FunctionID = functionID
+	version.Version = 1
+	if err := insertFunctionVersion(ctx, tx, version); err != nil {
+		return err
+	}
+	if err := upsertFunctionAlias(ctx, tx, functionID, model.FunctionAliasLatest, 1); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	fn.ID = functionID
+	return nil
+}
+
+// AddVersion 发布新版本（版本号 = 当前最大版本号 + 1，latest 指向新版本）
+func (r *FunctionRepository) AddVersion(ctx context.Context, version *model.FunctionVersion) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 🔒 锁定脚本行，保证并发发布时版本号连续且不重复
+	var latest int
+	if err := tx.GetContext(ctx, &latest,
+		`SELECT latest_version FROM stored_functions WHERE id = ? FOR UPDATE`, version.FunctionID); err != nil {
+		return fmt.Errorf("查询脚本版本失败: %w", err)
+	}
+
+	version.Version = latest + 1
+	if err := insertFunctionVersion(ctx, tx, version); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE stored_functions SET latest_version = ? WHERE id = ?`, version.Version, version.FunctionID); err != nil {
+		return fmt.Errorf("更新脚本版本失败: %w", err)
+	}
+	if err := upsertFunctionAlias(ctx, tx, version.FunctionID, model.FunctionAliasLatest, version.Version); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// GetByName 根据工作空间和名称获取脚本（不存在时返回 nil, nil）
+func (r *FunctionRepository) GetByName(ctx context.Context, wsID, name string) (*model.StoredFunction, error) {
+	var fn model.StoredFunction
+	if err := r.db.GetContext(ctx, &fn,
+		`SELECT * FROM stored_functions WHERE ws_id = ? AND name = ?`, wsID, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询脚本失败: %w", err)
+	}
+	return &fn, nil
+}
+
+// List 获取工作空间的全部脚本（按名称排序）
+func (r *FunctionRepository) List(ctx context.Context, wsID string) ([]*model.StoredFunction, error) {
+	functions := make([]*model.StoredFunction, 0)
+	if err := r.db.SelectContext(ctx, &functions,
+		`SELECT * FROM stored_functions WHERE ws_id = ? ORDER BY name`, wsID); err != nil {
+		utils.Error("查询脚本列表失败", zap.Error(err), zap.String("ws_id", wsID))
+		return nil, fmt.Errorf("查询脚本列表失败: %w", err)
+	}
+	return functions, nil
+}
+
+// UpdateDescription 更新脚本说明
+func (r *FunctionRepository) UpdateDescription(ctx context.Context, functionID int64, description string) error {
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE stored_functions SET description = ? WHERE id = ?`, description, functionID); err != nil {
+		return fmt.Errorf("更新脚本失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除脚本及其全部版本和别名
+func (r *FunctionRepository) Delete(ctx context.Context, functionID int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM stored_function_aliases WHERE function_id = ?`,
+		`DELETE FROM stored_function_versions WHERE function_id = ?`,
+		`DELETE FROM stored_functions WHERE id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, functionID); err != nil {
+			return fmt.Errorf("删除脚本失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// ListVersions 获取脚本的全部版本（不含代码，按版本号倒序）
+func (r *FunctionRepository) ListVersions(ctx context.Context, functionID int64) ([]*model.FunctionVersion, error) {
+	versions := make([]*model.FunctionVersion, 0)
+	query := fmt.Sprintf(`SELECT %s FROM stored_function_versions WHERE function_id = ? ORDER BY version DESC`,
+		functionVersionSummaryColumns)
+	if err := r.db.SelectContext(ctx, &versions, query, functionID); err != nil {
+		return nil, fmt.Errorf("查询脚本版本失败: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersion 获取指定版本（含代码，不存在时返回 nil, nil）
+func (r *FunctionRepository) GetVersion(ctx context.Context, functionID int64, version int) (*model.FunctionVersion, error) {
+	var v model.FunctionVersion
+	if err := r.db.GetContext(ctx, &v,
+		`SELECT * FROM stored_function_versions WHERE function_id = ? AND version = ?`, functionID, version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询脚本版本失败: %w", err)
+	}
+	return &v, nil
+}
+
+// GetPreviousVersion 获取小于 before 的最大版本号（不存在时返回 0）
+func (r *FunctionRepository) GetPreviousVersion(ctx context.Context, functionID int64, before int) (int, error) {
+	var version sql.NullInt64
+	if err := r.db.GetContext(ctx, &version,
+		`SELECT MAX(version) FROM stored_function_versions WHERE function_id = ? AND version < ?`, functionID, before); err != nil {
+		return 0, fmt.Errorf("查询脚本版本失败: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// ListAliases 获取脚本的全部别名（按名称排序）
+func (r *FunctionRepository) ListAliases(ctx context.Context, functionID int64) ([]*model.FunctionAlias, error) {
+	aliases := make([]*model.FunctionAlias, 0)
+	if err := r.db.SelectContext(ctx, &aliases,
+		`SELECT * FROM stored_function_aliases WHERE function_id = ? ORDER BY alias`, functionID); err != nil {
+		return nil, fmt.Errorf("查询脚本别名失败: %w", err)
+	}
+	return aliases, nil
+}
+
+// GetAlias 获取别名（不存在时返回 nil, nil）
+func (r *FunctionRepository) GetAlias(ctx context.Context, functionID int64, alias string) (*model.FunctionAlias, error) {
+	var a model.FunctionAlias
+	if err := r.db.GetContext(ctx, &a,
+		`SELECT * FROM stored_function_aliases WHERE function_id = ? AND alias = ?`, functionID, alias); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询脚本别名失败: %w", err)
+	}
+	return &a, nil
+}
+
+// SetAlias 设置别名指向的版本（不存在时创建）
+func (r *FunctionRepository) SetAlias(ctx context.Context, functionID int64, alias string, version int) error {
+	return upsertFunctionAlias(ctx, r.db, functionID, alias, version)
+}
+
+// DeleteAlias 删除别名，返回是否存在
+func (r *FunctionRepository) DeleteAlias(ctx context.Context, functionID int64, alias string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM stored_function_aliases WHERE function_id = ? AND alias = ?`, functionID, alias)
+	if err != nil {
+		return false, fmt.Errorf("删除脚本别名失败: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// insertFunctionVersion 写入版本记录
+func insertFunctionVersion(ctx context.Context, tx *sqlx.Tx, v *model.FunctionVersion) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stored_function_versions (function_id, version, code, code_hash, code_length, comment, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, v.FunctionID, v.Version, v.Code, v.CodeHash, v.CodeLength, v.Comment, v.CreatedBy); err != nil {
+		return fmt.Errorf("写入脚本版本失败: %w", err)
+	}
+	return nil
+}
+
+// upsertFunctionAlias 创建或移动别名
+func upsertFunctionAlias(ctx context.Context, db sqlx.ExecerContext, functionID int64, alias string, version int) error {
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO stored_function_aliases (function_id, alias, version) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE version = VALUES(version)
+	`, functionID, alias, version); err != nil {
+		return fmt.Errorf("设置脚本别名失败: %w", err)
+	}
+	return nil
+}
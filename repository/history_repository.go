@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// historySummaryColumns 列表查询的字段（不含 input / result / logs）
+const historySummaryColumns = `id, request_id, token, ws_id, email, code_hash, code_length, status,
+	error_type, error_message, execution_time_ms,
+	input_truncated, result_truncated, logs_truncated, expires_at, created_at`
+
+// HistoryRepository 执行历史数据访问层（code_execution_history 表）
+type HistoryRepository struct {
+	db *sqlx.DB
+}
+
+// NewHistoryRepository 创建执行历史 Repository
+func NewHistoryRepository(db *sqlx.DB) *HistoryRepository {
+	return &HistoryRepository{db: db}
+}
+
+// Insert 写入一条执行历史
+// request_id 来自客户端的 X-Request-ID，不保证唯一（客户端重试、不同 Token 使用相同的 ID），每次执行都保留一条记录
+func (r *HistoryRepository) Insert(ctx context.Context, h *model.ExecutionHistory) error {
+	query := `
+		INSERT INTO code_execution_history (
+			request_id, token, ws_id, email, code_hash, code_length,
+			status, error_type, error_message, execution_time_ms,
+			input, input_truncated, result, result_truncated, logs, logs_truncated,
+			expires_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		h.RequestID, h.Token, h.WsID, h.Email, h.CodeHash, h.CodeLength,
+		h.Status, h.ErrorType, h.ErrorMessage, h.ExecutionTimeMs,
+		h.Input, h.InputTruncated, h.Result, h.ResultTruncated, h.Logs, h.LogsTruncated,
+		h.ExpiresAt.Time,
+	)
+	if err != nil {
+		return fmt.Errorf("写入执行历史失败: %w", err)
+	}
+	return nil
+}
+
+// Query 分页查询执行历史（不含 input / result / logs，按执行时间倒序）
+func (r *HistoryRepository) Query(ctx context.Context, req *model.HistoryQueryRequest) ([]*model.ExecutionHistory, int, error) {
+	page, pageSize := HistoryPagination(req)
+	offset := (page - 1) * pageSize
+
+	// 构建查询条件
+	where := "WHERE 1 = 1"
+	args := []interface{}{}
+	if req.Token != "" {
+		where += " AND token = ?"
+		args = append(args, req.Token)
+	}
+	if req.WsID != "" {
+		where += " AND ws_id = ?"
+		args = append(args, req.WsID)
+	}
+	if req.Status != "" {
+		where += " AND status = ?"
+		args = append(args, req.Status)
+	}
+	if req.CodeHash != "" {
+		where += " AND code_hash = ?"
+		args = append(args, req.CodeHash)
+	}
+	if req.StartDate != "" {
+		startTime, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return nil, 0, fmt.Errorf("无效的开始日期格式，应为YYYY-MM-DD: %w", err)
+		}
+		where += " AND created_at >= ?"
+		args = append(args, startTime.Format("2006-01-02 00:00:00"))
+	}
+	if req.EndDate != "" {
+		endTime, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return nil, 0, fmt.Errorf("无效的结束日期格式，应为YYYY-MM-DD: %w", err)
+		}
+		where += " AND created_at <= ?"
+		args = append(args, endTime.Format("2006-01-02 23:59:59"))
+	}
+
+	// 查询总数
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM code_execution_history %s", where)
+	if err := r.db.GetContext(ctx, &total, countQuery, args...); err != nil {
+		return nil, 0, fmt.Errorf("查询执行历史总数失败: %w", err)
+	}
+
+	// 查询列表
+	records := make([]*model.ExecutionHistory, 0)
+	query := fmt.Sprintf(`
+		SELECT %s FROM code_execution_history
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, historySummaryColumns, where)
+	args = append(args, pageSize, offset)
+
+	if err := r.db.SelectContext(ctx, &records, query, args...); err != nil {
+		utils.Error("查询执行历史失败", zap.Error(err))
+		return nil, 0, fmt.Errorf("查询执行历史失败: %w", err)
+	}
+
+	return records, total, nil
+}
+
+// GetByRequestID 根据 token + request_id 获取执行历史详情（不存在时返回 nil, nil）
+// token 为空时不限 Token（管理员接口）；同一 request_id 有多条记录时返回最近的一条
+func (r *HistoryRepository) GetByRequestID(ctx context.Context, token, requestID string) (*model.ExecutionHistory, error) {
+	var h model.ExecutionHistory
+	query := `SELECT * FROM code_execution_history WHERE request_id = ?`
+	args := []interface{}{requestID}
+	if token != "" {
+		query += ` AND token = ?`
+		args = append(args, token)
+	}
+	query += ` ORDER BY id DESC LIMIT 1`
+
+	if err := r.db.GetContext(ctx, &h, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询执行历史失败: %w", err)
+	}
+
+	return &h, nil
+}
+
+// CountExpired 查询已过期的记录数
+func (r *HistoryRepository) CountExpired(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM code_execution_history WHERE expires_at < NOW()`)
+	return count, err
+}
+
+// DeleteExpiredBatch 删除一批已过期的记录，返回删除数量
+func (r *HistoryRepository) DeleteExpiredBatch(ctx context.Context, batchSize int) (int, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM code_execution_history WHERE expires_at < NOW() LIMIT ?`, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
+
+// Analyze 更新表统计信息（ANALYZE TABLE 不锁表）
+func (r *HistoryRepository) Analyze(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `ANALYZE TABLE code_execution_history`)
+	return err
+}
+
+// HistoryPagination 执行历史的分页参数（默认第 1 页、每页 20 条，每页最多 100 条）
+func HistoryPagination(req *model.HistoryQueryRequest) (page, pageSize int) {
+	page = req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize = req.PageSize
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ErrPolicyNameExists 命名策略已存在
+var ErrPolicyNameExists = errors.New("策略名称已存在")
+
+// PolicyRepository 沙箱策略数据访问层（sandbox_policies 表）
+type PolicyRepository struct {
+	db *sqlx.DB
+}
+
+// NewPolicyRepository 创建策略 Repository
+func NewPolicyRepository(db *sqlx.DB) *PolicyRepository {
+	return &PolicyRepository{db: db}
+}
+
+// Create 创建命名策略
+func (r *PolicyRepository) Create(ctx context.Context, req *model.CreateSandboxPolicyRequest) (*model.SandboxPolicyProfile, error) {
+	query := `INSERT INTO sandbox_policies (name, description, policy) VALUES (?, ?, ?)`
+
+	if _, err := r.db.ExecContext(ctx, query, req.Name, req.Description, req.Policy); err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, ErrPolicyNameExists
+		}
+		utils.Error("创建沙箱策略失败", zap.Error(err), zap.String("name", req.Name))
+		return nil, fmt.Errorf("创建沙箱策略失败: %w", err)
+	}
+
+	return r.GetByName(ctx, req.Name)
+}
+
+// GetByName 根据名称获取命名策略（不存在时返回 nil, nil）
+func (r *PolicyRepository) GetByName(ctx context.Context, name string) (*model.SandboxPolicyProfile, error) {
+	var profile model.SandboxPolicyProfile
+	query := `SELECT * FROM sandbox_policies WHERE name = ?`
+
+	if err := r.db.GetContext(ctx, &profile, query, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询沙箱策略失败: %w", err)
+	}
+
+	return &profile, nil
+}
+
+// List 获取全部命名策略
+func (r *PolicyRepository) List(ctx context.Context) ([]*model.SandboxPolicyProfile, error) {
+	profiles := make([]*model.SandboxPolicyProfile, 0)
+	query := `SELECT * FROM sandbox_policies ORDER BY name`
+
+	if err := r.db.SelectContext(ctx, &profiles, query); err != nil {
+		utils.Error("查询沙箱策略列表失败", zap.Error(err))
+		return nil, fmt.Errorf("查询沙箱策略列表失败: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// Update 更新命名策略（字段为 nil 表示不修改）
+func (r *PolicyRepository) Update(ctx context.Context, name string, req *model.UpdateSandboxPolicyRequest) (*model.SandboxPolicyProfile, error) {
+	existing, err := r.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("沙箱策略不存在")
+	}
+
+	if req.Description != nil {
+		existing.Description = *req.Description
+	}
+	if req.Policy != nil {
+		existing.Policy = *req.Policy
+	}
+
+	query := `UPDATE sandbox_policies SET description = ?, policy = ? WHERE name = ?`
+	if _, err := r.db.ExecContext(ctx, query, existing.Description, existing.Policy, name); err != nil {
+		utils.Error("更新沙箱策略失败", zap.Error(err), zap.String("name", name))
+		return nil, fmt.Errorf("更新沙箱策略失败: %w", err)
+	}
+
+	return r.GetByName(ctx, name)
+}
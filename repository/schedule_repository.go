@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ScheduleRepository 定时执行数据访问层（function_schedules / function_schedule_runs 表）
+type ScheduleRepository struct {
+	db *sqlx.DB
+}
+
+// NewScheduleRepository 创建定时执行 Repository
+func NewScheduleRepository(db *sqlx.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+// Create 创建定时执行计划
+func (r *ScheduleRepository) Create(ctx context.Context, s *model.FunctionSchedule) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO function_schedules (
+			token, ws_id, email, name, function_name, version, cron_expr, timezone,
+			input, overlap_policy, enabled, next_run_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.Token, s.WsID, s.Email, s.Name, s.FunctionName, s.Version, s.CronExpr, s.Timezone,
+		s.Input, s.OverlapPolicy, s.Enabled, nullableTime(s.NextRunAt.Time))
+	if err != nil {
+		utils.Error("创建定时执行计划失败", zap.Error(err), zap.String("ws_id", s.WsID), zap.String("name", s.Name))
+		return fmt.Errorf("创建定时执行计划失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取定时执行计划ID失败: %w", err)
+	}
+	s.ID = id
+	return nil
+}
+
+// Update 更新定时执行计划的可修改字段
+func (r *ScheduleRepository) Update(ctx context.Context, s *model.FunctionSchedule) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE function_schedules SET
+			name = ?, function_name = ?, version = ?, cron_expr = ?, timezone = ?,
+			input = ?, overlap_policy = ?, enabled = ?, next_run_at = ?
+		WHERE id = ?
+	`, s.Name, s.FunctionName, s.Version, s.CronExpr, s.Timezone,
+		s.Input, s.OverlapPolicy, s.Enabled, nullableTime(s.NextRunAt.Time), s.ID); err != nil {
+		return fmt.Errorf("更新定时执行计划失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 获取定时执行计划（不存在时返回 nil, nil）
+func (r *ScheduleRepository) GetByID(ctx context.Context, id int64) (*model.FunctionSchedule, error) {
+	var s model.FunctionSchedule
+	if err := r.db.GetContext(ctx, &s, `SELECT * FROM function_schedules WHERE id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询定时执行计划失败: %w", err)
+	}
+	return &s, nil
+}
+
+// ListByToken 获取 Token 的全部定时执行计划
+func (r *ScheduleRepository) ListByToken(ctx context.Context, token string) ([]*model.FunctionSchedule, error) {
+	schedules := make([]*model.FunctionSchedule, 0)
+	if err := r.db.SelectContext(ctx, &schedules,
+		`SELECT * FROM function_schedules WHERE token = ? ORDER BY id`, token); err != nil {
+		return nil, fmt.Errorf("查询定时执行计划失败: %w", err)
+	}
+	return schedules, nil
+}
+
+// CountByToken 查询 Token 的定时执行计划数量
+func (r *ScheduleRepository) CountByToken(ctx context.Context, token string) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM function_schedules WHERE token = ?`, token); err != nil {
+		return 0, fmt.Errorf("查询定时执行计划数量失败: %w", err)
+	}
+	return count, nil
+}
+
+// Delete 删除定时执行计划及其执行记录
+func (r *ScheduleRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM function_schedule_runs WHERE schedule_id = ?`, id); err != nil {
+		return fmt.Errorf("删除定时执行记录失败: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM function_schedules WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("删除定时执行计划失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// ListDue 获取已到触发时间的计划（按触发时间排序）
+func (r *ScheduleRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*model.FunctionSchedule, error) {
+	schedules := make([]*model.FunctionSchedule, 0)
+	if err := r.db.SelectContext(ctx, &schedules, `
+		SELECT * FROM function_schedules
+		WHERE enabled = 1 AND next_run_at <= ?
+		ORDER BY next_run_at
+		LIMIT ?
+	`, now, limit); err != nil {
+		return nil, fmt.Errorf("查询到期定时执行计划失败: %w", err)
+	}
+	return schedules, nil
+}
+
+// ClaimNextRun 认领一次触发：仅当 next_run_at 仍为 expected 时推进到 next，返回是否认领成功
+// 🔒 多实例同时认领同一次触发时只有一个 UPDATE 生效（行锁 + 条件更新），保证每次触发只执行一次
+// next 为零值时表示之后不会再触发，同时禁用计划
+func (r *ScheduleRepository) ClaimNextRun(ctx context.Context, id int64, expected, next time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE function_schedules SET next_run_at = ?, enabled = ?
+		WHERE id = ? AND enabled = 1 AND next_run_at = ?
+	`, nullableTime(next), !next.IsZero(), id, expected)
+	if err != nil {
+		return false, fmt.Errorf("认领定时执行失败: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// UpdateLastRun 更新计划的最近一次执行状态
+func (r *ScheduleRepository) UpdateLastRun(ctx context.Context, id int64, at time.Time, status string) error {
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE function_schedules SET last_run_at = ?, last_status = ? WHERE id = ?`, at, status, id); err != nil {
+		return fmt.Errorf("更新定时执行状态失败: %w", err)
+	}
+	return nil
+}
+
+// InsertRun 写入执行记录
+func (r *ScheduleRepository) InsertRun(ctx context.Context, run *model.ScheduleRun) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO function_schedule_runs (
+			schedule_id, run_id, status, function_version, error_type, error_message,
+			result, result_truncated, duration_ms, scheduled_at, started_at, finished_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.ScheduleID, run.RunID, run.Status, run.FunctionVersion, run.ErrorType, run.ErrorMessage,
+		run.Result, run.ResultTruncated, run.DurationMs,
+		run.ScheduledAt.Time, run.StartedAt.Time, nullableTime(run.FinishedAt.Time))
+	if err != nil {
+		return fmt.Errorf("写入定时执行记录失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取定时执行记录ID失败: %w", err)
+	}
+	run.ID = id
+	return nil
+}
+
+// FinishRun 更新执行记录的最终状态
+func (r *ScheduleRepository) FinishRun(ctx context.Context, run *model.ScheduleRun) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE function_schedule_runs SET
+			status = ?, function_version = ?, error_type = ?, error_message = ?,
+			result = ?, result_truncated = ?, duration_ms = ?, finished_at = ?
+		WHERE id = ?
+	`, run.Status, run.FunctionVersion, run.ErrorType, run.ErrorMessage,
+		run.Result, run.ResultTruncated, run.DurationMs, nullableTime(run.FinishedAt.Time), run.ID); err != nil {
+		return fmt.Errorf("更新定时执行记录失败: %w", err)
+	}
+	return nil
+}
+
+// ListRuns 分页查询计划的执行记录（按触发时间倒序）
+func (r *ScheduleRepository) ListRuns(ctx context.Context, scheduleID int64, req *model.ScheduleRunQueryRequest) ([]*model.ScheduleRun, int, error) {
+	page, pageSize := SchedulePagination(req)
+	offset := (page - 1) * pageSize
+
+	where := "WHERE schedule_id = ?"
+	args := []interface{}{scheduleID}
+	if req.Status != "" {
+		where += " AND status = ?"
+		args = append(args, req.Status)
+	}
+
+	var total int
+	if err := r.db.GetContext(ctx, &total,
+		fmt.Sprintf("SELECT COUNT(*) FROM function_schedule_runs %s", where), args...); err != nil {
+		return nil, 0, fmt.Errorf("查询定时执行记录总数失败: %w", err)
+	}
+
+	runs := make([]*model.ScheduleRun, 0)
+	query := fmt.Sprintf(`
+		SELECT * FROM function_schedule_runs
+		%s
+		ORDER BY scheduled_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, where)
+	args = append(args, pageSize, offset)
+	if err := r.db.SelectContext(ctx, &runs, query, args...); err != nil {
+		utils.Error("查询定时执行记录失败", zap.Error(err), zap.Int64("schedule_id", scheduleID))
+		return nil, 0, fmt.Errorf("查询定时执行记录失败: %w", err)
+	}
+
+	return runs, total, nil
+}
+
+// DeleteRunsBefore 删除一批早于 before 的执行记录，返回删除数量
+func (r *ScheduleRepository) DeleteRunsBefore(ctx context.Context, before time.Time, batchSize int) (int, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM function_schedule_runs WHERE scheduled_at < ? LIMIT ?`, before, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+// FailStaleRuns 将开始时间早于 before 仍处于 running 的记录标记为失败（执行实例已退出）
+func (r *ScheduleRepository) FailStaleRuns(ctx context.Context, before time.Time, errorType, errorMessage string) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE function_schedule_runs SET status = ?, error_type = ?, error_message = ?, finished_at = ?
+		WHERE status = ? AND started_at < ?
+	`, model.ScheduleRunFailed, errorType, errorMessage, time.Now(), model.ScheduleRunRunning, before)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+// SchedulePagination 执行记录的分页参数（默认第 1 页、每页 20 条，每页最多 100 条）
+func SchedulePagination(req *model.ScheduleRunQueryRequest) (page, pageSize int) {
+	page = req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize = req.PageSize
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// nullableTime 零值时间写入为 NULL
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -118,8 +118,9 @@ func (r *TokenRepository) Create(ctx context.Context, req *model.CreateTokenRequ
 		INSERT INTO access_tokens (
 			ws_id, email, access_token, expires_at, operation_type,
 			quota_type, total_quota, remaining_quota,
-			rate_limit_per_minute, rate_limit_burst, rate_limit_window_seconds
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			rate_limit_per_minute, rate_limit_burst, rate_limit_window_seconds,
+			policy_name, policy
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
@@ -134,6 +135,8 @@ func (r *TokenRepository) Create(ctx context.Context, req *model.CreateTokenRequ
 		req.RateLimitPerMinute,
 		req.RateLimitBurst,
 		windowSeconds,
+		req.PolicyName, // 🆕 沙箱策略
+		req.Policy,
 	)
 	if err != nil {
 		utils.Error("创建Token失败", zap.Error(err))
@@ -290,6 +293,30 @@ func (r *TokenRepository) Update(ctx context.Context, token string, req *model.U
 	return r.GetByToken(ctx, token)
 }
 
+// UpdatePolicy 设置Token的沙箱策略（整体替换，nil 表示清除）
+func (r *TokenRepository) UpdatePolicy(ctx context.Context, token string, policyName *string, policy *model.SandboxPolicy) (*model.TokenInfo, error) {
+	query := `
+		UPDATE access_tokens
+		SET policy_name = ?, policy = ?
+		WHERE access_token = ? AND is_active = 1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, policyName, policy, token); err != nil {
+		utils.Error("更新Token策略失败", zap.Error(err), zap.String("token", utils.MaskToken(token)))
+		return nil, fmt.Errorf("更新Token策略失败: %w", err)
+	}
+
+	// MySQL 在值未变化时影响行数为 0，以查询结果判断Token是否存在
+	tokenInfo, err := r.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if tokenInfo == nil {
+		return nil, fmt.Errorf("Token不存在")
+	}
+	return tokenInfo, nil
+}
+
 // Delete 删除Token（软删除）
 func (r *TokenRepository) Delete(ctx context.Context, token string) error {
 	query := `UPDATE access_tokens SET is_active = 0 WHERE access_token = ?`
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// TriggerRepository HTTP 触发器数据访问层（function_triggers 表）
+type TriggerRepository struct {
+	db *sqlx.DB
+}
+
+// NewTriggerRepository 创建 HTTP 触发器 Repository
+func NewTriggerRepository(db *sqlx.DB) *TriggerRepository {
+	return &TriggerRepository{db: db}
+}
+
+// Create 创建触发器
+func (r *TriggerRepository) Create(ctx context.Context, t *model.FunctionTrigger) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO function_triggers (
+			trigger_key, token, ws_id, email, name, function_name, version, methods,
+			auth_mode, secret, signature_header, signature_algorithm, signature_encoding,
+			signature_prefix, timestamp_header, rate_limit_per_minute, rate_limit_burst, enabled
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.TriggerKey, t.Token, t.WsID, t.Email, t.Name, t.FunctionName, t.Version, t.Methods,
+		t.AuthMode, t.Secret, t.SignatureHeader, t.SignatureAlgorithm, t.SignatureEncoding,
+		t.SignaturePrefix, t.TimestampHeader, t.RateLimitPerMinute, t.RateLimitBurst, t.Enabled)
+	if err != nil {
+		utils.Error("创建HTTP触发器失败", zap.Error(err), zap.String("ws_id", t.WsID), zap.String("name", t.Name))
+		return fmt.Errorf("创建HTTP触发器失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取HTTP触发器ID失败: %w", err)
+	}
+	t.ID = id
+	return nil
+}
+
+// Update 更新触发器的可修改字段（不含地址和密钥）
+func (r *TriggerRepository) Update(ctx context.Context, t *model.FunctionTrigger) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE function_triggers SET
+			name = ?, function_name = ?, version = ?, methods = ?, auth_mode = ?,
+			signature_header = ?, signature_algorithm = ?, signature_encoding = ?,
+			signature_prefix = ?, timestamp_header = ?, rate_limit_per_minute = ?,
+			rate_limit_burst = ?, enabled = ?
+		WHERE id = ?
+	`, t.Name, t.FunctionName, t.Version, t.Methods, t.AuthMode,
+		t.SignatureHeader, t.SignatureAlgorithm, t.SignatureEncoding,
+		t.SignaturePrefix, t.TimestampHeader, t.RateLimitPerMinute,
+		t.RateLimitBurst, t.Enabled, t.ID); err != nil {
+		return fmt.Errorf("更新HTTP触发器失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateSecret 更新触发器密钥
+func (r *TriggerRepository) UpdateSecret(ctx context.Context, id int64, secret string) error {
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE function_triggers SET secret = ? WHERE id = ?`, secret, id); err != nil {
+		return fmt.Errorf("更新HTTP触发器密钥失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 获取触发器（不存在时返回 nil, nil）
+func (r *TriggerRepository) GetByID(ctx context.Context, id int64) (*model.FunctionTrigger, error) {
+	return r.get(ctx, `SELECT * FROM function_triggers WHERE id = ?`, id)
+}
+
+// GetByKey 按公开地址中的 key 获取触发器（不存在时返回 nil, nil）
+func (r *TriggerRepository) GetByKey(ctx context.Context, key string) (*model.FunctionTrigger, error) {
+	return r.get(ctx, `SELECT * FROM function_triggers WHERE trigger_key = ?`, key)
+}
+
+// get 查询单个触发器
+func (r *TriggerRepository) get(ctx context.Context, query string, arg interface{}) (*model.FunctionTrigger, error) {
+	var t model.FunctionTrigger
+	if err := r.db.GetContext(ctx, &t, query, arg); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询HTTP触发器失败: %w", err)
+	}
+	return &t, nil
+}
+
+// ListByToken 获取 Token 的全部触发器
+func (r *TriggerRepository) ListByToken(ctx context.Context, token string) ([]*model.FunctionTrigger, error) {
+	triggers := make([]*model.FunctionTrigger, 0)
+	if err := r.db.SelectContext(ctx, &triggers,
+		`SELECT * FROM function_triggers WHERE token = ? ORDER BY id`, token); err != nil {
+		return nil, fmt.Errorf("查询HTTP触发器失败: %w", err)
+	}
+	return triggers, nil
+}
+
+// CountByToken 查询 Token 的触发器数量
+func (r *TriggerRepository) CountByToken(ctx context.Context, token string) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM function_triggers WHERE token = ?`, token); err != nil {
+		return 0, fmt.Errorf("查询HTTP触发器数量失败: %w", err)
+	}
+	return count, nil
+}
+
+// Delete 删除触发器
+func (r *TriggerRepository) Delete(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM function_triggers WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("删除HTTP触发器失败: %w", err)
+	}
+	return nil
+}
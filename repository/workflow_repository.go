@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// WorkflowRepository 工作流数据访问层（function_workflows 表）
+type WorkflowRepository struct {
+	db *sqlx.DB
+}
+
+// NewWorkflowRepository 创建工作流 Repository
+func NewWorkflowRepository(db *sqlx.DB) *WorkflowRepository {
+	return &WorkflowRepository{db: db}
+}
+
+// Create 创建工作流
+func (r *WorkflowRepository) Create(ctx context.Context, w *model.Workflow) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO function_workflows (token, ws_id, email, name, description, definition)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, w.Token, w.WsID, w.Email, w.Name, w.Description, w.Definition)
+	if err != nil {
+		utils.Error("创建工作流失败", zap.Error(err), zap.String("ws_id", w.WsID), zap.String("name", w.Name))
+		return fmt.Errorf("创建工作流失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取工作流ID失败: %w", err)
+	}
+	w.ID = id
+	return nil
+}
+
+// Update 更新工作流名称、说明和定义
+func (r *WorkflowRepository) Update(ctx context.Context, w *model.Workflow) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE function_workflows SET name = ?, description = ?, definition = ? WHERE id = ?
+	`, w.Name, w.Description, w.Definition, w.ID); err != nil {
+		return fmt.Errorf("更新工作流失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 获取工作流（不存在时返回 nil, nil）
+func (r *WorkflowRepository) GetByID(ctx context.Context, id int64) (*model.Workflow, error) {
+	var w model.Workflow
+	if err := r.db.GetContext(ctx, &w, `SELECT * FROM function_workflows WHERE id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询工作流失败: %w", err)
+	}
+	return &w, nil
+}
+
+// ListByToken 获取 Token 的全部工作流
+func (r *WorkflowRepository) ListByToken(ctx context.Context, token string) ([]*model.Workflow, error) {
+	workflows := make([]*model.Workflow, 0)
+	if err := r.db.SelectContext(ctx, &workflows,
+		`SELECT * FROM function_workflows WHERE token = ? ORDER BY id`, token); err != nil {
+		return nil, fmt.Errorf("查询工作流失败: %w", err)
+	}
+	return workflows, nil
+}
+
+// CountByToken 查询 Token 的工作流数量
+func (r *WorkflowRepository) CountByToken(ctx context.Context, token string) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM function_workflows WHERE token = ?`, token); err != nil {
+		return 0, fmt.Errorf("查询工作流数量失败: %w", err)
+	}
+	return count, nil
+}
+
+// Delete 删除工作流
+func (r *WorkflowRepository) Delete(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM function_workflows WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("删除工作流失败: %w", err)
+	}
+	return nil
+}
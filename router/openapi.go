@@ -0,0 +1,456 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils/ginutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPI 文档（GET /flow/openapi.json）
+//
+// 请求 / 响应的 schema 由 model 中的真实类型反射生成；路由清单 apiOperations 需要与 SetupRouter 保持一致，
+// openapi_test.go 会在两者不一致时失败
+
+// apiAuth 接口的认证方式
+type apiAuth int
+
+const (
+	apiAuthNone    apiAuth = iota // 无需认证
+	apiAuthToken                  // 访问令牌
+	apiAuthAdmin                  // 管理员令牌
+	apiAuthTrigger                // HTTP 触发器密钥 / 签名
+)
+
+// apiResponseKind 成功响应的格式
+type apiResponseKind int
+
+const (
+	respSuccess apiResponseKind = iota // ginutil.SuccessResponse，data 为 apiResponse.Data
+	respExecute                        // model.ExecuteResponse（失败时同样是 ExecuteResponse）
+	respStats                          // model.StatsAPIResponse（失败时为 StatsErrorResponse）
+	respJSON                           // 直接返回 apiResponse.Data
+	respRaw                            // 非 JSON 内容（apiResponse.ContentType）
+)
+
+// apiResponse 成功响应
+type apiResponse struct {
+	Kind        apiResponseKind
+	Status      int         // 默认 200
+	Data        interface{} // 示例值（按其类型生成 schema），nil 表示任意值
+	ContentType string      // respRaw 使用
+}
+
+// apiParam 额外的查询参数（没有 form 标签结构体的接口）
+type apiParam struct {
+	Name        string
+	Type        string // string / integer
+	Description string
+}
+
+// apiOperation 一个接口
+type apiOperation struct {
+	Method   string // GET / POST / PUT / DELETE，ANY 表示 gin 的 Any
+	Path     string // gin 路径（/flow/tokens/:token）
+	Tag      string
+	Summary  string
+	Auth     apiAuth
+	Query    interface{} // 查询参数结构体（form 标签）
+	Params   []apiParam
+	Request  interface{} // JSON 请求体
+	Optional bool        // 请求体可以省略
+	Response apiResponse
+}
+
+// success / execute / stats / raw 构造成功响应
+func success(data interface{}) apiResponse { return apiResponse{Kind: respSuccess, Data: data} }
+func execute() apiResponse                 { return apiResponse{Kind: respExecute} }
+func stats(data interface{}) apiResponse   { return apiResponse{Kind: respStats, Data: data} }
+func rawJSON(data interface{}) apiResponse { return apiResponse{Kind: respJSON, Data: data} }
+func raw(contentType string) apiResponse   { return apiResponse{Kind: respRaw, ContentType: contentType} }
+
+// statsParams 统计接口的查询参数（StatsQueryParams 没有 form 标签，由控制器逐个读取）
+var statsParams = []apiParam{
+	{Name: "date", Type: "string", Description: "单个日期 yyyy-MM-dd"},
+	{Name: "start_date", Type: "string", Description: "开始日期 yyyy-MM-dd"},
+	{Name: "end_date", Type: "string", Description: "结束日期 yyyy-MM-dd"},
+	{Name: "sort_by", Type: "string", Description: "排序字段"},
+	{Name: "order", Type: "string", Description: "asc / desc（默认 desc）"},
+}
+
+// apiOperations 全部接口（与 SetupRouter 的注册顺序一致）
+var apiOperations = []apiOperation{
+	// 公开接口
+	{Method: "GET", Path: "/health", Tag: "系统", Summary: "简单健康检查", Response: rawJSON(schemaObject{"status": "", "service": "", "version": ""})},
+	{Method: "GET", Path: "/", Tag: "系统", Summary: "服务信息", Response: rawJSON(nil)},
+	{Method: "GET", Path: "/metrics", Tag: "系统", Summary: "Prometheus 指标（METRICS_ENABLED=true；默认需要管理员令牌，METRICS_REQUIRE_AUTH=false 时公开）", Auth: apiAuthAdmin, Response: raw("text/plain")},
+	{Method: "GET", Path: "/flow/test-tool", Tag: "测试工具", Summary: "在线测试工具页面", Response: raw("text/html")},
+	{Method: "GET", Path: "/flow/openapi.json", Tag: "系统", Summary: "OpenAPI 文档（本文档）", Response: rawJSON(nil)},
+	{Method: "GET", Path: "/flow/query-token", Tag: "测试工具", Summary: "公开的 Token 查询（按 ws_id / email 查询时脱敏）", Query: model.TokenQueryRequest{},
+		Response: success(schemaObject{"tokens": []*model.TokenInfo{}, "count": 0})},
+	{Method: "POST", Path: "/flow/token/request-verify-code", Tag: "测试工具", Summary: "发送 Token 查询验证码到邮箱", Request: model.RequestVerifyCodeRequest{},
+		Response: success(schemaObject{"message": ""})},
+	{Method: "POST", Path: "/flow/token/verify-and-query", Tag: "测试工具", Summary: "校验验证码并查询 Token", Request: model.VerifyCodeAndQueryTokenRequest{},
+		Response: success(schemaObject{"tokens": []*model.TokenInfo{}, "count": 0})},
+	{Method: "GET", Path: "/flow/assets/ace.js", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("application/javascript")},
+	{Method: "GET", Path: "/flow/assets/mode-javascript.js", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("application/javascript")},
+	{Method: "GET", Path: "/flow/assets/mode-json.js", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("application/javascript")},
+	{Method: "GET", Path: "/flow/assets/theme-monokai.js", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("application/javascript")},
+	{Method: "GET", Path: "/flow/assets/worker-javascript.js", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("application/javascript")},
+	{Method: "GET", Path: "/flow/assets/worker-json.js", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("application/javascript")},
+	{Method: "GET", Path: "/flow/assets/ext-searchbox.js", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("application/javascript")},
+	{Method: "GET", Path: "/flow/assets/logo.png", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("image/png")},
+	{Method: "GET", Path: "/flow/assets/verify-code.js", Tag: "测试工具", Summary: "测试工具静态资源", Response: raw("application/javascript")},
+
+	// 代码执行（Token 接口）
+	{Method: "POST", Path: "/flow/codeblock", Tag: "代码执行", Summary: "执行代码（代码 Base64 编码；计入 Token 限流和配额）", Auth: apiAuthToken,
+		Request: model.ExecuteRequest{}, Response: execute()},
+	{Method: "POST", Path: "/flow/codeblock/batch", Tag: "代码执行", Summary: "批量执行（每个条目计入 Token 限流和配额，单个条目失败不影响其他条目）", Auth: apiAuthToken,
+		Request: model.BatchExecuteRequest{}, Response: rawJSON(model.BatchExecuteResponse{})},
+	{Method: "POST", Path: "/flow/codeblock/validate", Tag: "代码执行", Summary: "静态校验代码（不执行、不扣减配额）", Auth: apiAuthToken,
+		Request: model.ValidateRequest{}, Response: success(model.ValidateReport{})},
+	{Method: "POST", Path: "/flow/jobs", Tag: "异步任务", Summary: "提交异步任务", Auth: apiAuthToken,
+		Request: model.JobSubmitRequest{}, Response: apiResponse{Kind: respSuccess, Status: http.StatusAccepted, Data: model.JobInfo{}}},
+	{Method: "GET", Path: "/flow/jobs/:id", Tag: "异步任务", Summary: "查询异步任务状态和结果", Auth: apiAuthToken, Response: success(model.JobInfo{})},
+	{Method: "GET", Path: "/flow/history", Tag: "执行历史", Summary: "查询当前 Token 的执行历史", Auth: apiAuthToken, Query: model.HistoryQueryRequest{},
+		Response: success(schemaObject{"records": []*model.ExecutionHistory{}, "total": 0, "page": 0, "page_size": 0, "total_pages": 0})},
+	{Method: "GET", Path: "/flow/history/:request_id", Tag: "执行历史", Summary: "查询当前 Token 的单条执行历史", Auth: apiAuthToken, Response: success(model.ExecutionHistoryDetail{})},
+
+	// 存储脚本
+	{Method: "POST", Path: "/flow/functions", Tag: "存储脚本", Summary: "创建脚本", Auth: apiAuthToken, Request: model.CreateFunctionRequest{}, Response: success(model.FunctionDetail{})},
+	{Method: "GET", Path: "/flow/functions", Tag: "存储脚本", Summary: "脚本列表", Auth: apiAuthToken,
+		Response: success(schemaObject{"functions": []*model.StoredFunction{}, "total": 0})},
+	{Method: "GET", Path: "/flow/functions/:name", Tag: "存储脚本", Summary: "脚本详情", Auth: apiAuthToken, Response: success(model.FunctionDetail{})},
+	{Method: "PUT", Path: "/flow/functions/:name", Tag: "存储脚本", Summary: "更新脚本描述", Auth: apiAuthToken, Request: model.UpdateFunctionRequest{}, Response: success(model.FunctionDetail{})},
+	{Method: "DELETE", Path: "/flow/functions/:name", Tag: "存储脚本", Summary: "删除脚本", Auth: apiAuthToken, Response: success(nil)},
+	{Method: "POST", Path: "/flow/functions/:name", Tag: "存储脚本", Summary: "调用脚本（name 可带 @version 或 @alias；计入 Token 限流和配额）", Auth: apiAuthToken,
+		Request: model.InvokeFunctionRequest{}, Response: execute()},
+	{Method: "POST", Path: "/flow/functions/:name/versions", Tag: "存储脚本", Summary: "发布新版本", Auth: apiAuthToken, Request: model.PublishFunctionRequest{}, Response: success(model.FunctionVersion{})},
+	{Method: "GET", Path: "/flow/functions/:name/versions/:version", Tag: "存储脚本", Summary: "版本详情（含代码）", Auth: apiAuthToken, Response: success(model.FunctionVersionDetail{})},
+	{Method: "PUT", Path: "/flow/functions/:name/aliases/:alias", Tag: "存储脚本", Summary: "设置别名", Auth: apiAuthToken, Request: model.SetFunctionAliasRequest{}, Response: success(model.FunctionAlias{})},
+	{Method: "DELETE", Path: "/flow/functions/:name/aliases/:alias", Tag: "存储脚本", Summary: "删除别名", Auth: apiAuthToken, Response: success(nil)},
+	{Method: "POST", Path: "/flow/functions/:name/rollback", Tag: "存储脚本", Summary: "别名回滚到上一个（或指定）版本", Auth: apiAuthToken, Request: model.RollbackFunctionRequest{}, Optional: true, Response: success(model.FunctionAlias{})},
+
+	// 定时执行
+	{Method: "POST", Path: "/flow/schedules", Tag: "定时执行", Summary: "创建定时执行计划", Auth: apiAuthToken, Request: model.CreateScheduleRequest{}, Response: success(model.FunctionScheduleDetail{})},
+	{Method: "GET", Path: "/flow/schedules", Tag: "定时执行", Summary: "定时执行计划列表", Auth: apiAuthToken,
+		Response: success(schemaObject{"schedules": []*model.FunctionScheduleDetail{}, "total": 0})},
+	{Method: "GET", Path: "/flow/schedules/:id", Tag: "定时执行", Summary: "定时执行计划详情", Auth: apiAuthToken, Response: success(model.FunctionScheduleDetail{})},
+	{Method: "PUT", Path: "/flow/schedules/:id", Tag: "定时执行", Summary: "更新定时执行计划", Auth: apiAuthToken, Request: model.UpdateScheduleRequest{}, Response: success(model.FunctionScheduleDetail{})},
+	{Method: "DELETE", Path: "/flow/schedules/:id", Tag: "定时执行", Summary: "删除定时执行计划", Auth: apiAuthToken, Response: success(nil)},
+	{Method: "GET", Path: "/flow/schedules/:id/runs", Tag: "定时执行", Summary: "执行记录", Auth: apiAuthToken, Query: model.ScheduleRunQueryRequest{},
+		Response: success(schemaObject{"runs": []*model.ScheduleRunDetail{}, "total": 0, "page": 0, "page_size": 0, "total_pages": 0})},
+	{Method: "POST", Path: "/flow/schedules/:id/run", Tag: "定时执行", Summary: "立即执行一次", Auth: apiAuthToken,
+		Response: success(schemaObject{"schedule_id": int64(0), "run_id": int64(0)})},
+
+	// HTTP 触发器
+	{Method: "POST", Path: "/flow/triggers", Tag: "HTTP 触发器", Summary: "创建触发器（响应中的密钥只返回一次）", Auth: apiAuthToken, Request: model.CreateTriggerRequest{}, Response: success(model.FunctionTriggerDetail{})},
+	{Method: "GET", Path: "/flow/triggers", Tag: "HTTP 触发器", Summary: "触发器列表", Auth: apiAuthToken,
+		Response: success(schemaObject{"triggers": []*model.FunctionTriggerDetail{}, "total": 0})},
+	{Method: "GET", Path: "/flow/triggers/:id", Tag: "HTTP 触发器", Summary: "触发器详情", Auth: apiAuthToken, Response: success(model.FunctionTriggerDetail{})},
+	{Method: "PUT", Path: "/flow/triggers/:id", Tag: "HTTP 触发器", Summary: "更新触发器", Auth: apiAuthToken, Request: model.UpdateTriggerRequest{}, Response: success(model.FunctionTriggerDetail{})},
+	{Method: "DELETE", Path: "/flow/triggers/:id", Tag: "HTTP 触发器", Summary: "删除触发器", Auth: apiAuthToken, Response: success(nil)},
+	{Method: "POST", Path: "/flow/triggers/:id/rotate-secret", Tag: "HTTP 触发器", Summary: "轮换密钥（旧密钥立即失效）", Auth: apiAuthToken, Response: success(model.FunctionTriggerDetail{})},
+	{Method: "ANY", Path: "/flow/hooks/:key", Tag: "HTTP 触发器", Summary: "触发地址（响应由脚本返回的 status / headers / body 决定）", Auth: apiAuthTrigger, Response: raw("*/*")},
+	{Method: "ANY", Path: "/flow/hooks/:key/*path", Tag: "HTTP 触发器", Summary: "触发地址（带子路径）", Auth: apiAuthTrigger, Response: raw("*/*")},
+
+	// 工作流
+	{Method: "POST", Path: "/flow/workflows", Tag: "工作流", Summary: "创建工作流", Auth: apiAuthToken, Request: model.CreateWorkflowRequest{}, Response: success(model.WorkflowDetail{})},
+	{Method: "GET", Path: "/flow/workflows", Tag: "工作流", Summary: "工作流列表", Auth: apiAuthToken,
+		Response: success(schemaObject{"workflows": []*model.WorkflowDetail{}, "total": 0})},
+	{Method: "GET", Path: "/flow/workflows/:id", Tag: "工作流", Summary: "工作流详情", Auth: apiAuthToken, Response: success(model.WorkflowDetail{})},
+	{Method: "PUT", Path: "/flow/workflows/:id", Tag: "工作流", Summary: "更新工作流", Auth: apiAuthToken, Request: model.UpdateWorkflowRequest{}, Response: success(model.WorkflowDetail{})},
+	{Method: "DELETE", Path: "/flow/workflows/:id", Tag: "工作流", Summary: "删除工作流", Auth: apiAuthToken, Response: success(nil)},
+	{Method: "POST", Path: "/flow/workflows/:id/run", Tag: "工作流", Summary: "执行工作流（计入一次 Token 限流，每个节点执行扣减一次配额）", Auth: apiAuthToken,
+		Request: model.RunWorkflowRequest{}, Optional: true, Response: success(model.WorkflowRunResult{})},
+
+	// 管理接口：系统状态
+	{Method: "GET", Path: "/flow/health", Tag: "系统", Summary: "详细健康检查（数据库 / Redis / 执行器）", Auth: apiAuthAdmin, Response: rawJSON(nil)},
+	{Method: "GET", Path: "/flow/status", Tag: "系统", Summary: "执行器统计", Auth: apiAuthAdmin, Response: success(nil)},
+	{Method: "GET", Path: "/flow/limits", Tag: "系统", Summary: "当前生效的限制配置", Auth: apiAuthAdmin, Response: rawJSON(nil)},
+	{Method: "POST", Path: "/flow/codeblock/profile", Tag: "代码执行", Summary: "在采样分析下执行代码", Auth: apiAuthAdmin,
+		Request: model.ProfileRequest{}, Response: rawJSON(model.ProfileResponse{})},
+	{Method: "GET", Path: "/flow/executions/running", Tag: "执行管理", Summary: "正在执行的代码", Auth: apiAuthAdmin,
+		Response: success(schemaObject{"executions": []*model.RunningExecution{}, "total": 0})},
+	{Method: "DELETE", Path: "/flow/executions/:execution_id", Tag: "执行管理", Summary: "终止执行", Auth: apiAuthAdmin, Response: success(model.RunningExecution{})},
+	{Method: "GET", Path: "/flow/executions/history", Tag: "执行历史", Summary: "查询所有 Token 的执行历史", Auth: apiAuthAdmin, Query: model.HistoryQueryRequest{},
+		Response: success(schemaObject{"records": []*model.ExecutionHistory{}, "total": 0, "page": 0, "page_size": 0, "total_pages": 0})},
+	{Method: "GET", Path: "/flow/executions/history/:request_id", Tag: "执行历史", Summary: "单条执行历史", Auth: apiAuthAdmin, Response: success(model.ExecutionHistoryDetail{})},
+	{Method: "GET", Path: "/flow/history/cleanup/stats", Tag: "执行历史", Summary: "执行历史清理统计", Auth: apiAuthAdmin, Response: success(nil)},
+	{Method: "POST", Path: "/flow/history/cleanup/trigger", Tag: "执行历史", Summary: "手动触发执行历史清理（后台异步执行）", Auth: apiAuthAdmin, Response: success(schemaObject{"message": ""})},
+	{Method: "GET", Path: "/flow/cron/stats", Tag: "定时执行", Summary: "定时执行统计", Auth: apiAuthAdmin, Response: success(nil)},
+
+	// 管理接口：Token
+	{Method: "POST", Path: "/flow/tokens", Tag: "Token 管理", Summary: "创建 Token", Auth: apiAuthAdmin, Request: model.CreateTokenRequest{}, Response: success(model.TokenInfo{})},
+	{Method: "PUT", Path: "/flow/tokens/:token", Tag: "Token 管理", Summary: "更新 Token 的有效期、限流和配额", Auth: apiAuthAdmin, Request: model.UpdateTokenRequest{}, Response: success(model.TokenInfo{})},
+	{Method: "DELETE", Path: "/flow/tokens/:token", Tag: "Token 管理", Summary: "删除 Token", Auth: apiAuthAdmin, Response: success(nil)},
+	{Method: "GET", Path: "/flow/tokens", Tag: "Token 管理", Summary: "查询 Token（按 ws_id 或 email 单独查询时脱敏）", Auth: apiAuthAdmin, Query: model.TokenQueryRequest{},
+		Response: success(schemaObject{"tokens": []*model.TokenInfo{}, "count": 0})},
+	{Method: "POST", Path: "/flow/policies", Tag: "沙箱策略", Summary: "创建命名策略", Auth: apiAuthAdmin, Request: model.CreateSandboxPolicyRequest{}, Response: success(model.SandboxPolicyProfile{})},
+	{Method: "GET", Path: "/flow/policies", Tag: "沙箱策略", Summary: "命名策略列表", Auth: apiAuthAdmin, Response: success([]*model.SandboxPolicyProfile{})},
+	{Method: "GET", Path: "/flow/policies/:name", Tag: "沙箱策略", Summary: "命名策略详情", Auth: apiAuthAdmin, Response: success(model.SandboxPolicyProfile{})},
+	{Method: "PUT", Path: "/flow/policies/:name", Tag: "沙箱策略", Summary: "更新命名策略", Auth: apiAuthAdmin, Request: model.UpdateSandboxPolicyRequest{}, Response: success(model.SandboxPolicyProfile{})},
+	{Method: "GET", Path: "/flow/tokens/:token/policy", Tag: "沙箱策略", Summary: "Token 的策略（含合并后的生效策略）", Auth: apiAuthAdmin, Response: success(model.TokenPolicyResponse{})},
+	{Method: "PUT", Path: "/flow/tokens/:token/policy", Tag: "沙箱策略", Summary: "设置 Token 的策略", Auth: apiAuthAdmin, Request: model.SetTokenPolicyRequest{}, Response: success(model.TokenPolicyResponse{})},
+
+	// 管理接口：配额
+	{Method: "GET", Path: "/flow/tokens/:token/quota", Tag: "配额", Summary: "查询 Token 配额（时间模式只返回 quota_type 和 message）", Auth: apiAuthAdmin,
+		Response: success(schemaObject{"quota_type": "", "total_quota": 0, "remaining_quota": 0, "consumed_quota": 0, "quota_synced_at": (*model.ShanghaiTime)(nil), "message": ""})},
+	{Method: "GET", Path: "/flow/tokens/:token/quota/logs", Tag: "配额", Summary: "配额消耗日志（page_size 默认 100，最大 1000）", Auth: apiAuthAdmin, Query: model.QuotaLogsQueryRequest{},
+		Response: success(schemaObject{"logs": []*model.QuotaLog{}, "total": 0, "page": 0, "page_size": 0, "total_pages": 0})},
+	{Method: "GET", Path: "/flow/quota/cleanup/stats", Tag: "配额", Summary: "配额日志清理统计", Auth: apiAuthAdmin, Response: success(nil)},
+	{Method: "POST", Path: "/flow/quota/cleanup/trigger", Tag: "配额", Summary: "手动触发配额日志清理（后台异步执行）", Auth: apiAuthAdmin, Response: success(schemaObject{"message": ""})},
+
+	// 管理接口：缓存和限流
+	{Method: "GET", Path: "/flow/cache/stats", Tag: "缓存和限流", Summary: "Token 缓存统计", Auth: apiAuthAdmin, Response: success(nil)},
+	{Method: "GET", Path: "/flow/rate-limit/stats", Tag: "缓存和限流", Summary: "限流统计", Auth: apiAuthAdmin,
+		Response: success(schemaObject{"rate_limit": model.RateLimitStats{}, "write_pool": map[string]interface{}{}})},
+	{Method: "DELETE", Path: "/flow/cache", Tag: "缓存和限流", Summary: "清空 Token 缓存", Auth: apiAuthAdmin, Response: success(nil)},
+	{Method: "DELETE", Path: "/flow/rate-limit/:token", Tag: "缓存和限流", Summary: "清除 Token 的限流状态", Auth: apiAuthAdmin, Response: success(nil)},
+	{Method: "GET", Path: "/flow/cache-write-pool/stats", Tag: "缓存和限流", Summary: "缓存写入池统计", Auth: apiAuthAdmin, Response: success(nil)},
+
+	// 管理接口：统计（启用统计服务时注册）
+	{Method: "GET", Path: "/flow/stats/modules", Tag: "统计", Summary: "模块使用统计", Auth: apiAuthAdmin,
+		Params: append(statsParams[:len(statsParams):len(statsParams)], apiParam{Name: "module", Type: "string", Description: "模块名称"}), Response: stats(model.ModuleStatsResponse{})},
+	{Method: "GET", Path: "/flow/stats/modules/:module_name", Tag: "统计", Summary: "单个模块的详细统计", Auth: apiAuthAdmin,
+		Params: statsParams[:3], Response: stats(model.ModuleDetailResponse{})},
+	{Method: "GET", Path: "/flow/stats/users", Tag: "统计", Summary: "用户活跃度统计", Auth: apiAuthAdmin,
+		Params: append(statsParams[:len(statsParams):len(statsParams)],
+			apiParam{Name: "ws_id", Type: "string", Description: "工作空间ID"},
+			apiParam{Name: "page", Type: "integer", Description: "页码（默认 1）"},
+			apiParam{Name: "page_size", Type: "integer", Description: "每页数量（默认 20）"},
+			apiParam{Name: "min_calls", Type: "integer", Description: "最小调用次数"}),
+		Response: stats(model.UserActivityResponse{})},
+}
+
+// anyMethods gin 的 Any 注册的方法中 OpenAPI 能描述的部分（不含 CONNECT）
+var anyMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE"}
+
+// ginParamPattern gin 路径参数（:name 和 *path）
+var ginParamPattern = regexp.MustCompile(`[:*]([A-Za-z_][A-Za-z0-9_]*)`)
+
+// openAPIPath gin 路径转换为 OpenAPI 路径（/flow/tokens/:token → /flow/tokens/{token}）
+func openAPIPath(ginPath string) string {
+	return ginParamPattern.ReplaceAllString(ginPath, "{$1}")
+}
+
+// buildOpenAPISpec 生成 OpenAPI 3 文档
+func buildOpenAPISpec() map[string]interface{} {
+	g := newSchemaGenerator()
+	errorRef := g.schemaOf(ginutil.ErrorResponse{})
+	successRef := g.schemaOf(ginutil.SuccessResponse{})
+
+	paths := make(map[string]map[string]interface{})
+	for _, op := range apiOperations {
+		p := openAPIPath(op.Path)
+		if paths[p] == nil {
+			paths[p] = make(map[string]interface{})
+		}
+		methods := []string{op.Method}
+		if op.Method == "ANY" {
+			methods = anyMethods
+		}
+		for _, method := range methods {
+			paths[p][strings.ToLower(method)] = g.operation(op, method, errorRef, successRef)
+		}
+	}
+
+	tags := []map[string]interface{}{}
+	seen := map[string]bool{}
+	for _, op := range apiOperations {
+		if !seen[op.Tag] {
+			seen[op.Tag] = true
+			tags = append(tags, map[string]interface{}{"name": op.Tag})
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Flow-CodeBlock API",
+			"version":     "1.0.0",
+			"description": "基于 Go + goja 的 JavaScript 代码执行服务。代码以 Base64 编码提交；Token 接口和管理接口都通过 accessToken 请求头（或 Authorization: Bearer）认证。",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"AccessToken": map[string]interface{}{
+					"type": "apiKey", "in": "header", "name": "accessToken",
+					"description": "访问令牌（flow_ 开头，也可以放在 access-token 请求头）",
+				},
+				"AdminToken": map[string]interface{}{
+					"type": "apiKey", "in": "header", "name": "accessToken",
+					"description": "管理员令牌（ADMIN_TOKEN）",
+				},
+				"BearerAuth": map[string]interface{}{
+					"type": "http", "scheme": "bearer",
+					"description": "Authorization: Bearer <令牌>，与 accessToken 请求头等价",
+				},
+			},
+		},
+	}
+}
+
+// operation 生成单个接口的 Operation Object
+func (g *schemaGenerator) operation(op apiOperation, method string, errorRef, successRef map[string]interface{}) map[string]interface{} {
+	operation := map[string]interface{}{
+		"tags":        []string{op.Tag},
+		"summary":     op.Summary,
+		"operationId": operationID(method, op.Path),
+	}
+
+	var params []map[string]interface{}
+	for _, match := range ginParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true,
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if op.Query != nil {
+		// 与路径参数同名的查询字段由路径参数覆盖（如 /tokens/:token/quota/logs 的 token）
+		for _, param := range g.queryParameters(op.Query) {
+			if !strings.Contains(op.Path, ":"+param["name"].(string)) {
+				params = append(params, param)
+			}
+		}
+	}
+	for _, param := range op.Params {
+		params = append(params, map[string]interface{}{
+			"name": param.Name, "in": "query", "description": param.Description,
+			"schema": map[string]interface{}{"type": param.Type},
+		})
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+
+	if op.Request != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": !op.Optional,
+			"content":  jsonContent(g.schemaOf(op.Request)),
+		}
+	}
+
+	switch op.Auth {
+	case apiAuthToken:
+		operation["security"] = []map[string][]string{{"AccessToken": {}}, {"BearerAuth": {}}}
+	case apiAuthAdmin:
+		operation["security"] = []map[string][]string{{"AdminToken": {}}, {"BearerAuth": {}}}
+	case apiAuthTrigger:
+		operation["description"] = "按触发器配置认证：密钥（X-Trigger-Secret 请求头或 ?secret=）或 HMAC 签名（默认 X-Flow-Signature 请求头）"
+	}
+
+	operation["responses"] = g.responses(op, errorRef, successRef)
+	return operation
+}
+
+// responses 生成 Responses Object
+func (g *schemaGenerator) responses(op apiOperation, errorRef, successRef map[string]interface{}) map[string]interface{} {
+	status := op.Response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	okStatus := http.StatusText(status)
+	responses := map[string]interface{}{}
+	retryAfter := map[string]interface{}{
+		"Retry-After": map[string]interface{}{
+			"description": "建议的重试等待秒数",
+			"schema":      map[string]interface{}{"type": "integer"},
+		},
+	}
+
+	errorSchema := errorRef
+	switch op.Response.Kind {
+	case respSuccess:
+		schema := successRef
+		if op.Response.Data != nil {
+			schema = map[string]interface{}{
+				"allOf": []interface{}{successRef, map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"data": g.schemaOf(op.Response.Data)},
+				}},
+			}
+		}
+		responses[strconv.Itoa(status)] = map[string]interface{}{"description": okStatus, "content": jsonContent(schema)}
+	case respExecute:
+		executeRef := g.schemaOf(model.ExecuteResponse{})
+		errorSchema = executeRef
+		responses[strconv.Itoa(status)] = map[string]interface{}{"description": "执行成功", "content": jsonContent(executeRef)}
+		responses["400"] = map[string]interface{}{"description": "校验失败或执行失败（error.type 为 ValidationError / RuntimeError / TimeoutError 等）", "content": jsonContent(executeRef)}
+		responses["429"] = map[string]interface{}{"description": "Token 限流、配额耗尽（QuotaExceeded）或排队被拒绝", "headers": retryAfter, "content": jsonContent(executeRef)}
+	case respStats:
+		statsRef := g.schemaOf(model.StatsAPIResponse{})
+		errorSchema = g.schemaOf(model.StatsErrorResponse{})
+		schema := map[string]interface{}{
+			"allOf": []interface{}{statsRef, map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"data": g.schemaOf(op.Response.Data)},
+			}},
+		}
+		responses[strconv.Itoa(status)] = map[string]interface{}{"description": okStatus, "content": jsonContent(schema)}
+	case respJSON:
+		responses[strconv.Itoa(status)] = map[string]interface{}{"description": okStatus, "content": jsonContent(g.schemaOf(op.Response.Data))}
+	case respRaw:
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": okStatus,
+			"content": map[string]interface{}{
+				op.Response.ContentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			},
+		}
+	}
+
+	if op.Auth == apiAuthToken || op.Auth == apiAuthAdmin {
+		responses["401"] = map[string]interface{}{"description": "缺少令牌或令牌无效", "content": jsonContent(errorRef)}
+	}
+	if op.Auth == apiAuthAdmin {
+		responses["403"] = map[string]interface{}{"description": "管理员令牌无效", "content": jsonContent(errorRef)}
+	}
+	if _, ok := responses["429"]; !ok && op.Auth != apiAuthAdmin {
+		responses["429"] = map[string]interface{}{"description": "请求过于频繁（IP / Token 限流）", "headers": retryAfter, "content": jsonContent(errorRef)}
+	}
+	responses["default"] = map[string]interface{}{"description": "错误", "content": jsonContent(errorSchema)}
+	return responses
+}
+
+// jsonContent application/json 的 Content Object
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// operationID 由方法和路径生成（GET /flow/tokens/:token/quota → getFlowTokensTokenQuota）
+func operationID(method, ginPath string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(ginPath, func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '-' || r == '.' || r == '_'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+)
+
+// openAPIHandler GET /flow/openapi.json（文档只生成一次）
+func openAPIHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		openAPIOnce.Do(func() {
+			openAPIJSON, _ = json.Marshal(buildOpenAPISpec())
+		})
+		c.Data(http.StatusOK, "application/json; charset=utf-8", openAPIJSON)
+	}
+}
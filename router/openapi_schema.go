@@ -0,0 +1,215 @@
+package router
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"flow-codeblock-go/model"
+)
+
+// schemaObject 内联对象（key 为 JSON 字段名，value 为该字段的示例值，按其 Go 类型生成 schema）
+//
+// 用于控制器中用 map 组装的响应，如 {"tokens": []*model.TokenInfo{}, "count": 0}
+type schemaObject map[string]interface{}
+
+var (
+	rawMessageType   = reflect.TypeOf(json.RawMessage{})
+	timeType         = reflect.TypeOf(time.Time{})
+	durationType     = reflect.TypeOf(time.Duration(0))
+	shanghaiTimeType = reflect.TypeOf(model.ShanghaiTime{})
+	schemaObjectType = reflect.TypeOf(schemaObject{})
+)
+
+// schemaGenerator 从 Go 类型生成 OpenAPI schema（具名结构体放入 components.schemas，用 $ref 引用）
+//
+// 字段名取 json 标签，binding:"required" 标记为必填，binding:"oneof=..." 生成枚举
+type schemaGenerator struct {
+	schemas map[string]interface{}  // components.schemas
+	names   map[reflect.Type]string // 已注册的结构体 → 组件名
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf 值的 schema（nil 表示任意值）
+func (g *schemaGenerator) schemaOf(v interface{}) map[string]interface{} {
+	if v == nil {
+		return map[string]interface{}{}
+	}
+	if obj, ok := v.(schemaObject); ok {
+		return g.objectSchema(obj)
+	}
+	return g.typeSchema(reflect.TypeOf(v))
+}
+
+// objectSchema 内联对象的 schema
+func (g *schemaGenerator) objectSchema(obj schemaObject) map[string]interface{} {
+	properties := make(map[string]interface{}, len(obj))
+	for name, value := range obj {
+		properties[name] = g.schemaOf(value)
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+// typeSchema 类型的 schema
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case rawMessageType:
+		return map[string]interface{}{"description": "任意 JSON 值"}
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]interface{}{"type": "integer", "format": "int64", "description": "纳秒"}
+	case shanghaiTimeType:
+		return map[string]interface{}{"type": "string", "example": "2025-10-15 12:00:00", "description": "上海时区，yyyy-MM-dd HH:mm:ss"}
+	case schemaObjectType:
+		return map[string]interface{}{"type": "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := g.typeSchema(t.Elem())
+		if _, isRef := schema["$ref"]; !isRef {
+			schema["nullable"] = true
+		}
+		return schema
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		return g.structRef(t)
+	}
+	return map[string]interface{}{}
+}
+
+// structRef 注册结构体并返回 $ref（先占位再生成，支持自引用）
+func (g *schemaGenerator) structRef(t reflect.Type) map[string]interface{} {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		g.schemas[name] = map[string]interface{}{}
+		g.schemas[name] = g.structSchema(t)
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// componentName 组件名（默认为类型名；不同包的同名类型加包名前缀）
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		name = "Object"
+	}
+	if _, taken := g.schemas[name]; taken {
+		prefix := path.Base(t.PkgPath())
+		name = strings.ToUpper(prefix[:1]) + prefix[1:] + name
+	}
+	return name
+}
+
+// structSchema 结构体的 schema
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	g.collectFields(t, properties, &required)
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields 收集结构体字段（匿名嵌入且无 json 名的结构体字段展开到外层，与 encoding/json 一致）
+func (g *schemaGenerator) collectFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.collectFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := g.typeSchema(field.Type)
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			switch {
+			case rule == "required":
+				*required = append(*required, name)
+			case strings.HasPrefix(rule, "oneof="):
+				if _, isRef := schema["$ref"]; !isRef {
+					schema["enum"] = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+				}
+			}
+		}
+		properties[name] = schema
+	}
+}
+
+// queryParameters 查询参数结构体（form 标签）生成的参数列表
+func (g *schemaGenerator) queryParameters(v interface{}) []map[string]interface{} {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var params []map[string]interface{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		params = append(params, map[string]interface{}{
+			"name":     name,
+			"in":       "query",
+			"required": strings.Contains(field.Tag.Get("binding"), "required"),
+			"schema":   g.typeSchema(field.Type),
+		})
+	}
+	return params
+}
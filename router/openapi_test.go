@@ -0,0 +1,144 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter 注册全部路由（控制器和服务为 nil，只用于比对路由表，不处理请求）
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Environment: "production"}
+	cfg.Server.MaxRequestBodyMB = 1
+	cfg.RateLimit = config.RateLimitConfig{
+		PreAuthIPRate: 10, PreAuthIPBurst: 20,
+		PostAuthIPRate: 200, PostAuthIPBurst: 400,
+		GlobalIPRate: 1000, GlobalIPBurst: 1000,
+	}
+	cfg.Metrics.Enabled = true // /metrics 和统计接口是按配置注册的，这里全部打开
+
+	engine, resources := SetupRouter(
+		nil, nil, &controller.StatsController{}, nil, nil, nil, nil, nil, nil, nil,
+		nil, nil, nil, "admin-token", cfg, nil,
+	)
+	t.Cleanup(func() {
+		resources.SmartIPLimiter.Close()
+		resources.GlobalIPLimiter.Close()
+	})
+	return engine
+}
+
+// specOperations 文档中的 "METHOD /path" 集合
+func specOperations(t *testing.T) map[string]bool {
+	t.Helper()
+	paths, ok := buildOpenAPISpec()["paths"].(map[string]map[string]interface{})
+	if !ok {
+		t.Fatal("paths 类型错误")
+	}
+	operations := make(map[string]bool)
+	for path, item := range paths {
+		for method := range item {
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return operations
+}
+
+// TestOpenAPICoversAllRoutes 路由中注册的接口必须出现在 OpenAPI 文档中（反之亦然）
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	engine := newTestRouter(t)
+	operations := specOperations(t)
+
+	registered := make(map[string]bool)
+	var missing []string
+	for _, route := range engine.Routes() {
+		// pprof 只在开发环境注册；CONNECT 由 gin 的 Any 注册，OpenAPI 无法描述
+		if strings.HasPrefix(route.Path, "/flow/debug/pprof") || route.Method == http.MethodConnect {
+			continue
+		}
+		key := route.Method + " " + openAPIPath(route.Path)
+		registered[key] = true
+		if !operations[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		t.Errorf("路由 %s 没有出现在 OpenAPI 文档中（请在 router/openapi.go 的 apiOperations 中补充）", key)
+	}
+
+	var stale []string
+	for key := range operations {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	for _, key := range stale {
+		t.Errorf("OpenAPI 文档中的 %s 没有对应的路由", key)
+	}
+}
+
+// TestOpenAPIRefsResolve 文档中的 $ref 都指向已生成的 schema
+func TestOpenAPIRefsResolve(t *testing.T) {
+	data, err := json.Marshal(buildOpenAPISpec())
+	if err != nil {
+		t.Fatalf("序列化 OpenAPI 文档失败: %v", err)
+	}
+	var spec struct {
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, required := range []string{"ExecuteRequest", "ExecuteResponse", "CreateTokenRequest", "UpdateTokenRequest", "QuotaLog", "ModuleStatsResponse", "UserActivityResponse", "ModuleDetailResponse"} {
+		if _, ok := spec.Components.Schemas[required]; !ok {
+			t.Errorf("components.schemas 缺少 %s", required)
+		}
+	}
+
+	const prefix = `"$ref":"#/components/schemas/`
+	for rest := string(data); ; {
+		i := strings.Index(rest, prefix)
+		if i < 0 {
+			break
+		}
+		rest = rest[i+len(prefix):]
+		name := rest[:strings.IndexByte(rest, '"')]
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			t.Errorf("$ref 指向不存在的 schema: %s", name)
+		}
+	}
+}
+
+// TestOpenAPIEndpoint GET /flow/openapi.json 返回文档
+func TestOpenAPIEndpoint(t *testing.T) {
+	engine := newTestRouter(t)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flow/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d，期望 200", w.Code)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("响应不是合法 JSON: %v", err)
+	}
+	if spec["openapi"] != "3.0.3" {
+		t.Errorf("openapi = %v，期望 3.0.3", spec["openapi"])
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"flow-codeblock-go/middleware"
 	"flow-codeblock-go/service"
 	"flow-codeblock-go/utils"
+	"flow-codeblock-go/utils/ginutil"
 
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
@@ -26,8 +27,16 @@ func SetupRouter(
 	executorController *controller.ExecutorController,
 	tokenController *controller.TokenController,
 	statsController *controller.StatsController, // 🆕 统计控制器
+	jobController *controller.JobController, // 🆕 异步任务控制器
+	metricsController *controller.MetricsController, // 🆕 Prometheus 指标控制器
+	historyController *controller.HistoryController, // 🆕 执行历史控制器
+	functionController *controller.FunctionController, // 🆕 存储脚本控制器
+	scheduleController *controller.ScheduleController, // 🆕 定时执行控制器
+	triggerController *controller.TriggerController, // 🆕 HTTP 触发器控制器
+	workflowController *controller.WorkflowController, // 🆕 工作流控制器
 	tokenService *service.TokenService,
 	rateLimiterService *service.RateLimiterService,
+	policyService *service.PolicyService, // 🆕 沙箱策略服务
 	adminToken string,
 	cfg *config.Config,
 	cacheWritePool *service.CacheWritePool, // 🔥 新增：缓存写入池
@@ -43,6 +52,7 @@ func SetupRouter(
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.RequestIDMiddleware()) // 🆕 请求ID中间件（最先执行）
+	router.Use(middleware.TracingMiddleware())   // 🆕 链路追踪（W3C traceparent，未启用导出时为 noop）
 
 	// 🔥 请求体大小限制（DoS 防护 - 第一道防线）
 	maxRequestBodyBytes := int64(cfg.Server.MaxRequestBodyMB) * 1024 * 1024
@@ -110,6 +120,18 @@ func SetupRouter(
 		executorController.Root,
 	)
 
+	// 🆕 Prometheus 指标（默认需要管理员Token，METRICS_REQUIRE_AUTH=false 时仅限全局 IP 限流）
+	if cfg.Metrics.Enabled {
+		metricsAuth := globalIPRateLimiter()
+		if cfg.Metrics.RequireAuth {
+			metricsAuth = middleware.AdminAuthMiddleware(adminToken)
+		}
+		router.GET("/metrics", metricsAuth, metricsController.Metrics)
+		utils.Info("Prometheus 指标接口已启用",
+			zap.String("path", "/metrics"),
+			zap.Bool("require_auth", cfg.Metrics.RequireAuth))
+	}
+
 	// Flow路由组
 	flowGroup := router.Group("/flow")
 	{
@@ -119,6 +141,12 @@ func SetupRouter(
 			executorController.TestTool,
 		)
 
+		// 🆕 OpenAPI 文档（无需认证，带全局IP限流）
+		flowGroup.GET("/openapi.json",
+			globalIPRateLimiter(),
+			openAPIHandler(),
+		)
+
 		// 🔍 公开的Token查询接口（供测试工具使用，带全局IP限流）
 		flowGroup.GET("/query-token",
 			globalIPRateLimiter(),
@@ -188,14 +216,147 @@ func SetupRouter(
 		//      - 认证失败的IP：10 QPS（严格）- 防止暴力破解
 		//      - 认证成功的IP：200 QPS（宽松）- 防止极端滥用
 		//   2. Token 认证 - 验证 Token 有效性，成功后标记IP已认证
-		//   3. Token 限流 - 根据 Token 配置限流
+		//   3. 沙箱策略 - 解析 Token 的策略（模块、限制、网络权限）写入请求 context
+		//   4. Token 限流 - 根据 Token 配置限流
 		flowGroup.POST("/codeblock",
 			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
 			middleware.TokenAuthMiddleware(tokenService),
+			middleware.SandboxPolicyMiddleware(policyService),
 			middleware.RateLimiterMiddleware(rateLimiterService),
 			executorController.Execute,
 		)
 
+		// 🆕 批量执行接口（智能 IP 限流 + Token 认证）
+		// Token 限流和配额按条目计费，在控制器内完成（不使用 RateLimiterMiddleware）
+		flowGroup.POST("/codeblock/batch",
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+			middleware.SandboxPolicyMiddleware(policyService),
+			executorController.ExecuteBatch,
+		)
+
+		// 🆕 代码校验接口（智能 IP 限流 + Token 认证）
+		// 只做静态分析，不执行代码、不扣减配额、不计入 Token 限流
+		flowGroup.POST("/codeblock/validate",
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+			middleware.SandboxPolicyMiddleware(policyService),
+			executorController.Validate,
+		)
+
+		// 🆕 异步任务接口（智能 IP 限流 + Token 认证）
+		// 提交：与同步执行一样计入 Token 限流；查询：仅认证，轮询不消耗限流额度
+		flowGroup.POST("/jobs",
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+			middleware.SandboxPolicyMiddleware(policyService),
+			middleware.RateLimiterMiddleware(rateLimiterService),
+			jobController.Submit,
+		)
+		flowGroup.GET("/jobs/:id",
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+			jobController.Get,
+		)
+
+		// 🆕 执行历史（Token 接口，仅认证）：只能查询当前 Token 的记录
+		flowGroup.GET("/history",
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+			historyController.ListOwn,
+		)
+		flowGroup.GET("/history/:request_id",
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+			historyController.GetOwn,
+		)
+
+		// 🆕 存储脚本（Token 接口）：按工作空间隔离，发布时按 Token 策略校验并预编译
+		// 调用 POST /functions/:name[@version|alias] 与代码执行接口一样计入 Token 限流和配额
+		functionGroup := flowGroup.Group("/functions")
+		functionGroup.Use(
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+			middleware.SandboxPolicyMiddleware(policyService),
+		)
+		{
+			functionGroup.POST("", functionController.Create)
+			functionGroup.GET("", functionController.List)
+			functionGroup.GET("/:name", functionController.Get)
+			functionGroup.PUT("/:name", functionController.Update)
+			functionGroup.DELETE("/:name", functionController.Delete)
+			functionGroup.POST("/:name",
+				middleware.RateLimiterMiddleware(rateLimiterService),
+				executorController.InvokeFunction,
+			)
+			functionGroup.POST("/:name/versions", functionController.Publish)
+			functionGroup.GET("/:name/versions/:version", functionController.GetVersion)
+			functionGroup.PUT("/:name/aliases/:alias", functionController.SetAlias)
+			functionGroup.DELETE("/:name/aliases/:alias", functionController.DeleteAlias)
+			functionGroup.POST("/:name/rollback", functionController.Rollback)
+		}
+
+		// 🆕 定时执行（Token 接口）：按 cron 表达式定时调用存储脚本
+		// 每次触发都按创建计划的 Token 计入限流和配额，执行结果写入执行记录
+		scheduleGroup := flowGroup.Group("/schedules")
+		scheduleGroup.Use(
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+		)
+		{
+			scheduleGroup.POST("", scheduleController.Create)
+			scheduleGroup.GET("", scheduleController.List)
+			scheduleGroup.GET("/:id", scheduleController.Get)
+			scheduleGroup.PUT("/:id", scheduleController.Update)
+			scheduleGroup.DELETE("/:id", scheduleController.Delete)
+			scheduleGroup.GET("/:id/runs", scheduleController.ListRuns)
+			scheduleGroup.POST("/:id/run", scheduleController.Run)
+		}
+
+		// 🆕 HTTP 触发器管理（Token 接口）：把存储脚本绑定为公开的 Webhook 地址
+		triggerGroup := flowGroup.Group("/triggers")
+		triggerGroup.Use(
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+		)
+		{
+			triggerGroup.POST("", triggerController.Create)
+			triggerGroup.GET("", triggerController.List)
+			triggerGroup.GET("/:id", triggerController.Get)
+			triggerGroup.PUT("/:id", triggerController.Update)
+			triggerGroup.DELETE("/:id", triggerController.Delete)
+			triggerGroup.POST("/:id/rotate-secret", triggerController.RotateSecret)
+		}
+
+		// 🆕 HTTP 触发地址（公开，无 Token）：按触发器的密钥 / 签名认证，触发器独立限流
+		// 执行时计入触发器所属 Token 的限流和配额
+		hookHandlers := []gin.HandlerFunc{
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			triggerController.Handle,
+		}
+		flowGroup.Any("/hooks/:key", hookHandlers...)
+		flowGroup.Any("/hooks/:key/*path", hookHandlers...)
+
+		// 🆕 工作流（Token 接口）：存储脚本组成的 DAG，在服务内执行
+		// 每次运行计入一次 Token 限流，每个节点执行（含重试、扇出元素）各扣减一次配额
+		workflowGroup := flowGroup.Group("/workflows")
+		workflowGroup.Use(
+			middleware.SmartIPRateLimiterHandlerWithInstance(resources.SmartIPLimiter, cfg),
+			middleware.TokenAuthMiddleware(tokenService),
+		)
+		{
+			workflowGroup.POST("", workflowController.Create)
+			workflowGroup.GET("", workflowController.List)
+			workflowGroup.GET("/:id", workflowController.Get)
+			workflowGroup.PUT("/:id", workflowController.Update)
+			workflowGroup.DELETE("/:id", workflowController.Delete)
+			workflowGroup.POST("/:id/run",
+				middleware.SandboxPolicyMiddleware(policyService),
+				middleware.RateLimiterMiddleware(rateLimiterService),
+				workflowController.Run,
+			)
+		}
+
 		// 管理接口（需要管理员认证）
 		adminGroup := flowGroup.Group("")
 		adminGroup.Use(middleware.AdminAuthMiddleware(adminToken))
@@ -205,12 +366,36 @@ func SetupRouter(
 			adminGroup.GET("/status", executorController.Stats)
 			adminGroup.GET("/limits", executorController.Limits)
 
+			// 🆕 性能分析：在 goja 采样下执行代码，返回火焰图 / 热点函数 / pprof
+			adminGroup.POST("/codeblock/profile", executorController.Profile)
+
+			// 🆕 正在执行的代码：查看 / 按 request_id 终止
+			adminGroup.GET("/executions/running", executorController.ListRunning)
+			adminGroup.DELETE("/executions/:execution_id", executorController.Kill)
+
+			// 🆕 执行历史：查询所有 Token 的记录 + 过期记录清理
+			adminGroup.GET("/executions/history", historyController.List)
+			adminGroup.GET("/executions/history/:request_id", historyController.Get)
+			adminGroup.GET("/history/cleanup/stats", historyController.GetCleanupStats)
+			adminGroup.POST("/history/cleanup/trigger", historyController.TriggerCleanup)
+
+			// 🆕 定时执行统计
+			adminGroup.GET("/cron/stats", scheduleController.GetStats)
+
 			// Token管理接口
 			adminGroup.POST("/tokens", tokenController.CreateToken)
 			adminGroup.PUT("/tokens/:token", tokenController.UpdateToken)
 			adminGroup.DELETE("/tokens/:token", tokenController.DeleteToken)
 			adminGroup.GET("/tokens", tokenController.GetTokenInfo)
 
+			// 🆕 沙箱策略接口（命名策略 + Token 策略）
+			adminGroup.POST("/policies", tokenController.CreatePolicy)
+			adminGroup.GET("/policies", tokenController.ListPolicies)
+			adminGroup.GET("/policies/:name", tokenController.GetPolicy)
+			adminGroup.PUT("/policies/:name", tokenController.UpdatePolicy)
+			adminGroup.GET("/tokens/:token/policy", tokenController.GetTokenPolicy)
+			adminGroup.PUT("/tokens/:token/policy", tokenController.SetTokenPolicy)
+
 			// 🔥 配额查询接口
 			adminGroup.GET("/tokens/:token/quota", tokenController.GetQuota)
 			adminGroup.GET("/tokens/:token/quota/logs", tokenController.GetQuotaLogs)
@@ -228,7 +413,7 @@ func SetupRouter(
 			// 🔥 缓存写入池统计接口
 			adminGroup.GET("/cache-write-pool/stats", func(c *gin.Context) {
 				stats := cacheWritePool.GetStats()
-				utils.RespondSuccess(c, stats, "")
+				ginutil.RespondSuccess(c, stats, "")
 			})
 
 			// 🆕 统计接口
@@ -282,7 +467,7 @@ func corsMiddleware(allowedOrigins []string) gin.HandlerFunc {
 			// ✅ 允许的 Origin：设置 CORS 响应头
 			c.Header("Access-Control-Allow-Origin", origin)
 			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accessToken")
+			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accessToken, traceparent, tracestate")
 			c.Header("Access-Control-Allow-Credentials", "true")
 
 			if c.Request.Method == "OPTIONS" {
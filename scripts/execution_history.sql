@@ -0,0 +1,50 @@
+-- Flow-CodeBlock Go 执行历史数据库变更（已有部署执行，新部署 init.sql 已包含）
+-- 功能: 按 Token 保存执行的输入、结果/错误、console 输出和耗时（沙箱策略 history.enabled 开启）
+
+SET NAMES utf8mb4;
+
+USE `flow_codeblock_go`;
+
+-- ==================== 表: 代码执行历史表 ====================
+-- 用途: 结果争议时回查；过期记录由 HistoryCleanupService 分批删除
+CREATE TABLE IF NOT EXISTS `code_execution_history` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `request_id` VARCHAR(64) NOT NULL COMMENT '执行请求ID',
+  `token` VARCHAR(255) NOT NULL COMMENT '访问Token',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID',
+  `email` VARCHAR(255) NOT NULL COMMENT '用户邮箱',
+  
+  -- 代码信息（只保存哈希，不保存代码本身）
+  `code_hash` CHAR(16) NOT NULL COMMENT '代码哈希(xxhash64)',
+  `code_length` INT NOT NULL DEFAULT 0 COMMENT '代码长度(字节)',
+  
+  -- 执行结果
+  `status` ENUM('success','failed') NOT NULL COMMENT '执行状态',
+  `error_type` VARCHAR(100) DEFAULT NULL COMMENT '错误类型（仅失败时记录）',
+  `error_message` TEXT DEFAULT NULL COMMENT '错误消息（仅失败时记录，已脱敏）',
+  `execution_time_ms` INT NOT NULL DEFAULT 0 COMMENT '执行耗时(毫秒)',
+  
+  -- 请求/响应内容（已脱敏，超过大小上限时截断）
+  `input` MEDIUMTEXT DEFAULT NULL COMMENT '输入参数(JSON)',
+  `input_truncated` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '输入是否被截断',
+  `result` MEDIUMTEXT DEFAULT NULL COMMENT '执行结果(JSON，仅成功时记录)',
+  `result_truncated` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '结果是否被截断',
+  `logs` MEDIUMTEXT DEFAULT NULL COMMENT 'console 输出(JSON 数组)',
+  `logs_truncated` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'console 输出是否被截断',
+  
+  -- 时间字段
+  `expires_at` TIMESTAMP NOT NULL COMMENT '过期时间（按 Token 的保留天数计算，过期后由清理服务删除）',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '执行时间',
+  
+  PRIMARY KEY (`id`),
+  KEY `idx_token_request_id` (`token`, `request_id`),
+  KEY `idx_request_id` (`request_id`),
+  KEY `idx_token_created` (`token`, `created_at`),
+  KEY `idx_ws_id_created` (`ws_id`, `created_at`),
+  KEY `idx_code_hash` (`code_hash`),
+  KEY `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='代码执行历史表（按 Token 开启）';
+
+-- ==================== 验证表结构 ====================
+SHOW CREATE TABLE `code_execution_history`;
@@ -0,0 +1,54 @@
+-- Flow-CodeBlock Go 存储脚本数据库变更（已有部署执行，新部署 init.sql 已包含）
+-- 功能: 按工作空间保存命名脚本，发布不可变的编号版本，别名（latest / prod 等）指向版本
+
+SET NAMES utf8mb4;
+
+USE `flow_codeblock_go`;
+
+-- ==================== 表: 存储脚本表 ====================
+-- 用途: POST /flow/functions/:name[@version|alias] 按名称调用
+CREATE TABLE IF NOT EXISTS `stored_functions` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID',
+  `name` VARCHAR(64) NOT NULL COMMENT '脚本名称（工作空间内唯一）',
+  `description` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '脚本说明',
+  `latest_version` INT NOT NULL DEFAULT 1 COMMENT '已发布的最大版本号',
+  `created_by` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '创建人邮箱',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_ws_name` (`ws_id`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本表';
+
+CREATE TABLE IF NOT EXISTS `stored_function_versions` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `function_id` BIGINT NOT NULL COMMENT '脚本ID',
+  `version` INT NOT NULL COMMENT '版本号（从1开始递增，发布后不可修改）',
+  `code` MEDIUMTEXT NOT NULL COMMENT '代码',
+  `code_hash` CHAR(16) NOT NULL COMMENT '代码哈希(xxhash64)',
+  `code_length` INT NOT NULL DEFAULT 0 COMMENT '代码长度(字节)',
+  `comment` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '版本说明',
+  `created_by` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '发布人邮箱',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '发布时间',
+  
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_function_version` (`function_id`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本版本表（不可变）';
+
+CREATE TABLE IF NOT EXISTS `stored_function_aliases` (
+  `function_id` BIGINT NOT NULL COMMENT '脚本ID',
+  `alias` VARCHAR(32) NOT NULL COMMENT '别名（latest 随发布自动移动）',
+  `version` INT NOT NULL COMMENT '指向的版本号',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`function_id`, `alias`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本别名表';
+
+-- ==================== 验证表结构 ====================
+SHOW CREATE TABLE `stored_functions`;
+SHOW CREATE TABLE `stored_function_versions`;
+SHOW CREATE TABLE `stored_function_aliases`;
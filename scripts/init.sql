@@ -29,6 +29,9 @@ CREATE TABLE IF NOT EXISTS `access_tokens` (
   `remaining_quota` INT DEFAULT NULL COMMENT '剩余配额次数（冷备份，热数据在Redis）',
   `quota_synced_at` TIMESTAMP NULL COMMENT 'Redis配额最后同步到DB的时间',
   `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  -- 🆕 沙箱策略字段
+  `policy_name` VARCHAR(64) DEFAULT NULL COMMENT '引用的命名沙箱策略（sandbox_policies.name），NULL表示不引用',
+  `policy` JSON DEFAULT NULL COMMENT '内联沙箱策略（覆盖命名策略中的同名字段），NULL表示无',
   PRIMARY KEY (`id`),
   UNIQUE KEY `uk_access_token` (`access_token`),
   KEY `idx_ws_id` (`ws_id`),
@@ -43,6 +46,18 @@ CREATE TABLE IF NOT EXISTS `access_tokens` (
   KEY `idx_ws_email` (`ws_id`, `email`) COMMENT 'ws_id和email复合索引'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='访问令牌表';
 
+-- ==================== 沙箱策略表 ====================
+CREATE TABLE IF NOT EXISTS `sandbox_policies` (
+  `id` INT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `name` VARCHAR(64) NOT NULL COMMENT '策略名称（access_tokens.policy_name 引用）',
+  `description` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '策略说明',
+  `policy` JSON NOT NULL COMMENT '策略内容：allowed_modules/execution_timeout_ms/max_result_size/max_input_size/console_mode/network',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='沙箱策略表';
+
 -- ==================== 限流历史记录表（冷数据层） ====================
 CREATE TABLE IF NOT EXISTS `token_rate_limit_history` (
   `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
@@ -66,6 +81,7 @@ CREATE TABLE IF NOT EXISTS `token_rate_limit_history` (
 -- ==================== 查看表结构（验证创建成功） ====================
 SELECT '✅ 表结构创建完成，开始验证...' AS status;
 SHOW CREATE TABLE `access_tokens`;
+SHOW CREATE TABLE `sandbox_policies`;
 SHOW CREATE TABLE `token_rate_limit_history`;
 
 -- ==================== 插入测试数据（用于验证数据库连接和表结构） ====================
@@ -307,11 +323,202 @@ CREATE TABLE IF NOT EXISTS `user_activity_stats` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
 COMMENT='用户活跃度统计表(按天聚合)';
 
+-- ==================== 表7: 代码执行历史表 ====================
+-- 用途: 按 Token 保存执行的输入、结果/错误、console 输出和耗时（沙箱策略 history.enabled 开启），用于结果争议时回查
+CREATE TABLE IF NOT EXISTS `code_execution_history` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `request_id` VARCHAR(64) NOT NULL COMMENT '执行请求ID',
+  `token` VARCHAR(255) NOT NULL COMMENT '访问Token',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID',
+  `email` VARCHAR(255) NOT NULL COMMENT '用户邮箱',
+  
+  -- 代码信息（只保存哈希，不保存代码本身）
+  `code_hash` CHAR(16) NOT NULL COMMENT '代码哈希(xxhash64)',
+  `code_length` INT NOT NULL DEFAULT 0 COMMENT '代码长度(字节)',
+  
+  -- 执行结果
+  `status` ENUM('success','failed') NOT NULL COMMENT '执行状态',
+  `error_type` VARCHAR(100) DEFAULT NULL COMMENT '错误类型（仅失败时记录）',
+  `error_message` TEXT DEFAULT NULL COMMENT '错误消息（仅失败时记录，已脱敏）',
+  `execution_time_ms` INT NOT NULL DEFAULT 0 COMMENT '执行耗时(毫秒)',
+  
+  -- 请求/响应内容（已脱敏，超过大小上限时截断）
+  `input` MEDIUMTEXT DEFAULT NULL COMMENT '输入参数(JSON)',
+  `input_truncated` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '输入是否被截断',
+  `result` MEDIUMTEXT DEFAULT NULL COMMENT '执行结果(JSON，仅成功时记录)',
+  `result_truncated` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '结果是否被截断',
+  `logs` MEDIUMTEXT DEFAULT NULL COMMENT 'console 输出(JSON 数组)',
+  `logs_truncated` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'console 输出是否被截断',
+  
+  -- 时间字段
+  `expires_at` TIMESTAMP NOT NULL COMMENT '过期时间（按 Token 的保留天数计算，过期后由清理服务删除）',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '执行时间',
+  
+  PRIMARY KEY (`id`),
+  KEY `idx_token_request_id` (`token`, `request_id`),
+  KEY `idx_request_id` (`request_id`),
+  KEY `idx_token_created` (`token`, `created_at`),
+  KEY `idx_ws_id_created` (`ws_id`, `created_at`),
+  KEY `idx_code_hash` (`code_hash`),
+  KEY `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='代码执行历史表（按 Token 开启）';
+
+-- ==================== 表8-10: 存储脚本表 / 版本表 / 别名表 ====================
+-- 用途: 按工作空间保存命名脚本，发布不可变的编号版本，别名（latest / prod 等）指向版本，按名称调用
+CREATE TABLE IF NOT EXISTS `stored_functions` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID',
+  `name` VARCHAR(64) NOT NULL COMMENT '脚本名称（工作空间内唯一）',
+  `description` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '脚本说明',
+  `latest_version` INT NOT NULL DEFAULT 1 COMMENT '已发布的最大版本号',
+  `created_by` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '创建人邮箱',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_ws_name` (`ws_id`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本表';
+
+CREATE TABLE IF NOT EXISTS `stored_function_versions` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `function_id` BIGINT NOT NULL COMMENT '脚本ID',
+  `version` INT NOT NULL COMMENT '版本号（从1开始递增，发布后不可修改）',
+  `code` MEDIUMTEXT NOT NULL COMMENT '代码',
+  `code_hash` CHAR(16) NOT NULL COMMENT '代码哈希(xxhash64)',
+  `code_length` INT NOT NULL DEFAULT 0 COMMENT '代码长度(字节)',
+  `comment` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '版本说明',
+  `created_by` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '发布人邮箱',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '发布时间',
+  
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_function_version` (`function_id`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本版本表（不可变）';
+
+CREATE TABLE IF NOT EXISTS `stored_function_aliases` (
+  `function_id` BIGINT NOT NULL COMMENT '脚本ID',
+  `alias` VARCHAR(32) NOT NULL COMMENT '别名（latest 随发布自动移动）',
+  `version` INT NOT NULL COMMENT '指向的版本号',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`function_id`, `alias`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本别名表';
+
+-- ==================== 表11-12: 定时执行计划表 / 执行记录表 ====================
+-- 用途: 按 cron 表达式定时调用存储脚本，保存每次执行的状态、耗时和结果
+CREATE TABLE IF NOT EXISTS `function_schedules` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `token` VARCHAR(255) NOT NULL COMMENT '创建计划的访问Token（执行时按该Token计入限流和配额）',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID',
+  `email` VARCHAR(255) NOT NULL COMMENT '用户邮箱',
+  `name` VARCHAR(64) NOT NULL COMMENT '计划名称',
+  `function_name` VARCHAR(64) NOT NULL COMMENT '存储脚本名称',
+  `version` VARCHAR(32) NOT NULL DEFAULT 'latest' COMMENT '版本号或别名',
+  `cron_expr` VARCHAR(100) NOT NULL COMMENT 'cron 表达式（5 段）',
+  `timezone` VARCHAR(64) NOT NULL COMMENT '时区（IANA 名称）',
+  `input` MEDIUMTEXT NOT NULL COMMENT '固定输入(JSON)',
+  `overlap_policy` ENUM('skip','queue','allow') NOT NULL DEFAULT 'skip' COMMENT '重叠策略',
+  `enabled` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+  `next_run_at` TIMESTAMP NULL DEFAULT NULL COMMENT '下一次触发时间（禁用时为 NULL）',
+  `last_run_at` TIMESTAMP NULL DEFAULT NULL COMMENT '最近一次执行时间',
+  `last_status` VARCHAR(16) NOT NULL DEFAULT '' COMMENT '最近一次执行状态',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`id`),
+  KEY `idx_enabled_next_run` (`enabled`, `next_run_at`),
+  KEY `idx_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本定时执行计划表';
+
+CREATE TABLE IF NOT EXISTS `function_schedule_runs` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `schedule_id` BIGINT NOT NULL COMMENT '计划ID',
+  `run_id` VARCHAR(64) NOT NULL COMMENT '执行ID（同时作为执行的 request_id）',
+  `status` ENUM('running','success','failed','skipped') NOT NULL COMMENT '执行状态',
+  `function_version` INT NOT NULL DEFAULT 0 COMMENT '实际执行的脚本版本',
+  `error_type` VARCHAR(100) DEFAULT NULL COMMENT '错误类型',
+  `error_message` TEXT DEFAULT NULL COMMENT '错误消息',
+  `result` MEDIUMTEXT DEFAULT NULL COMMENT '执行结果(JSON，超过上限时截断)',
+  `result_truncated` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '结果是否被截断',
+  `duration_ms` BIGINT NOT NULL DEFAULT 0 COMMENT '执行耗时(毫秒)',
+  `scheduled_at` TIMESTAMP NOT NULL COMMENT '计划触发时间',
+  `started_at` TIMESTAMP NOT NULL COMMENT '开始时间',
+  `finished_at` TIMESTAMP NULL DEFAULT NULL COMMENT '结束时间',
+  
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_run_id` (`run_id`),
+  KEY `idx_schedule_scheduled` (`schedule_id`, `scheduled_at`),
+  KEY `idx_status_started` (`status`, `started_at`),
+  KEY `idx_scheduled_at` (`scheduled_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本定时执行记录表';
+
+-- ==================== 表13: HTTP 触发器表 ====================
+-- 用途: 把存储脚本绑定为公开的 Webhook 地址，每个触发器有独立的密钥、认证方式和限流
+CREATE TABLE IF NOT EXISTS `function_triggers` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `trigger_key` VARCHAR(64) NOT NULL COMMENT '公开地址标识（/flow/hooks/:key）',
+  `token` VARCHAR(255) NOT NULL COMMENT '创建触发器的访问Token（执行时按该Token计入限流和配额）',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID',
+  `email` VARCHAR(255) NOT NULL COMMENT '用户邮箱',
+  `name` VARCHAR(64) NOT NULL COMMENT '触发器名称',
+  `function_name` VARCHAR(64) NOT NULL COMMENT '存储脚本名称',
+  `version` VARCHAR(32) NOT NULL DEFAULT 'latest' COMMENT '版本号或别名',
+  `methods` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '允许的 HTTP 方法（逗号分隔，空表示全部）',
+  `auth_mode` ENUM('none','secret','hmac') NOT NULL DEFAULT 'secret' COMMENT '认证方式',
+  `secret` VARCHAR(128) NOT NULL COMMENT '触发器密钥（secret 模式比对 / hmac 模式签名密钥）',
+  `signature_header` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '签名请求头（hmac 模式）',
+  `signature_algorithm` VARCHAR(16) NOT NULL DEFAULT 'sha256' COMMENT '签名算法：sha1 / sha256 / sha512',
+  `signature_encoding` VARCHAR(16) NOT NULL DEFAULT 'hex' COMMENT '签名编码：hex / base64',
+  `signature_prefix` VARCHAR(32) NOT NULL DEFAULT '' COMMENT '签名前缀（如 sha256=）',
+  `timestamp_header` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '时间戳请求头（为空时只对请求体签名）',
+  `rate_limit_per_minute` INT NOT NULL DEFAULT 60 COMMENT '每分钟请求上限',
+  `rate_limit_burst` INT NOT NULL DEFAULT 10 COMMENT '每秒请求上限',
+  `enabled` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_trigger_key` (`trigger_key`),
+  KEY `idx_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本 HTTP 触发器表';
+
+-- ==================== 表14: 工作流表 ====================
+-- 用途: 多个存储脚本组成 DAG，边把上游结果映射为下游输入，在服务内一次执行完成
+CREATE TABLE IF NOT EXISTS `function_workflows` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `token` VARCHAR(255) NOT NULL COMMENT '创建工作流的访问Token（只能由该Token执行）',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID（节点脚本从该工作空间解析）',
+  `email` VARCHAR(255) NOT NULL COMMENT '用户邮箱',
+  `name` VARCHAR(64) NOT NULL COMMENT '工作流名称',
+  `description` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '说明',
+  `definition` MEDIUMTEXT NOT NULL COMMENT '工作流定义（JSON：nodes / edges / output）',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`id`),
+  KEY `idx_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='工作流（存储脚本 DAG）表';
+
 -- ==================== 验证所有表结构 ====================
 SELECT '✅ 统计表创建完成，开始验证...' AS status;
 SHOW CREATE TABLE `code_execution_stats`;
 SHOW CREATE TABLE `module_usage_stats`;
 SHOW CREATE TABLE `user_activity_stats`;
+SHOW CREATE TABLE `code_execution_history`;
+SHOW CREATE TABLE `stored_functions`;
+SHOW CREATE TABLE `stored_function_versions`;
+SHOW CREATE TABLE `stored_function_aliases`;
+SHOW CREATE TABLE `function_schedules`;
+SHOW CREATE TABLE `function_schedule_runs`;
+SHOW CREATE TABLE `function_triggers`;
+SHOW CREATE TABLE `function_workflows`;
 
 SET FOREIGN_KEY_CHECKS = 1;
 
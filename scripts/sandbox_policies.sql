@@ -0,0 +1,27 @@
+-- Flow-CodeBlock Go 沙箱策略数据库变更（已有部署执行，新部署 init.sql 已包含）
+-- 功能: 按 Token 配置模块允许列表、执行限制、console 模式和网络权限
+
+SET NAMES utf8mb4;
+
+USE `flow_codeblock_go`;
+
+-- ==================== 表1: 沙箱策略表（命名策略） ====================
+CREATE TABLE IF NOT EXISTS `sandbox_policies` (
+  `id` INT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `name` VARCHAR(64) NOT NULL COMMENT '策略名称（access_tokens.policy_name 引用）',
+  `description` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '策略说明',
+  `policy` JSON NOT NULL COMMENT '策略内容：allowed_modules/execution_timeout_ms/max_result_size/max_input_size/console_mode/network',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='沙箱策略表';
+
+-- ==================== 访问令牌表: 新增策略字段 ====================
+ALTER TABLE `access_tokens`
+  ADD COLUMN `policy_name` VARCHAR(64) DEFAULT NULL COMMENT '引用的命名沙箱策略（sandbox_policies.name），NULL表示不引用' AFTER `updated_at`,
+  ADD COLUMN `policy` JSON DEFAULT NULL COMMENT '内联沙箱策略（覆盖命名策略中的同名字段），NULL表示无' AFTER `policy_name`;
+
+-- ==================== 验证表结构 ====================
+SHOW CREATE TABLE `sandbox_policies`;
+SHOW CREATE TABLE `access_tokens`;
@@ -0,0 +1,60 @@
+-- Flow-CodeBlock Go 定时执行数据库变更（已有部署执行，新部署 init.sql 已包含）
+-- 功能: 按 cron 表达式定时调用存储脚本，保存每次执行的状态、耗时和结果
+
+SET NAMES utf8mb4;
+
+USE `flow_codeblock_go`;
+
+-- ==================== 表: 定时执行计划表 / 执行记录表 ====================
+-- 用途: /flow/schedules 管理计划，/flow/schedules/:id/runs 查询执行记录
+CREATE TABLE IF NOT EXISTS `function_schedules` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `token` VARCHAR(255) NOT NULL COMMENT '创建计划的访问Token（执行时按该Token计入限流和配额）',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID',
+  `email` VARCHAR(255) NOT NULL COMMENT '用户邮箱',
+  `name` VARCHAR(64) NOT NULL COMMENT '计划名称',
+  `function_name` VARCHAR(64) NOT NULL COMMENT '存储脚本名称',
+  `version` VARCHAR(32) NOT NULL DEFAULT 'latest' COMMENT '版本号或别名',
+  `cron_expr` VARCHAR(100) NOT NULL COMMENT 'cron 表达式（5 段）',
+  `timezone` VARCHAR(64) NOT NULL COMMENT '时区（IANA 名称）',
+  `input` MEDIUMTEXT NOT NULL COMMENT '固定输入(JSON)',
+  `overlap_policy` ENUM('skip','queue','allow') NOT NULL DEFAULT 'skip' COMMENT '重叠策略',
+  `enabled` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+  `next_run_at` TIMESTAMP NULL DEFAULT NULL COMMENT '下一次触发时间（禁用时为 NULL）',
+  `last_run_at` TIMESTAMP NULL DEFAULT NULL COMMENT '最近一次执行时间',
+  `last_status` VARCHAR(16) NOT NULL DEFAULT '' COMMENT '最近一次执行状态',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`id`),
+  KEY `idx_enabled_next_run` (`enabled`, `next_run_at`),
+  KEY `idx_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本定时执行计划表';
+
+CREATE TABLE IF NOT EXISTS `function_schedule_runs` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `schedule_id` BIGINT NOT NULL COMMENT '计划ID',
+  `run_id` VARCHAR(64) NOT NULL COMMENT '执行ID（同时作为执行的 request_id）',
+  `status` ENUM('running','success','failed','skipped') NOT NULL COMMENT '执行状态',
+  `function_version` INT NOT NULL DEFAULT 0 COMMENT '实际执行的脚本版本',
+  `error_type` VARCHAR(100) DEFAULT NULL COMMENT '错误类型',
+  `error_message` TEXT DEFAULT NULL COMMENT '错误消息',
+  `result` MEDIUMTEXT DEFAULT NULL COMMENT '执行结果(JSON，超过上限时截断)',
+  `result_truncated` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '结果是否被截断',
+  `duration_ms` BIGINT NOT NULL DEFAULT 0 COMMENT '执行耗时(毫秒)',
+  `scheduled_at` TIMESTAMP NOT NULL COMMENT '计划触发时间',
+  `started_at` TIMESTAMP NOT NULL COMMENT '开始时间',
+  `finished_at` TIMESTAMP NULL DEFAULT NULL COMMENT '结束时间',
+  
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_run_id` (`run_id`),
+  KEY `idx_schedule_scheduled` (`schedule_id`, `scheduled_at`),
+  KEY `idx_status_started` (`status`, `started_at`),
+  KEY `idx_scheduled_at` (`scheduled_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本定时执行记录表';
+
+-- ==================== 验证表结构 ====================
+SHOW CREATE TABLE `function_schedules`;
+SHOW CREATE TABLE `function_schedule_runs`;
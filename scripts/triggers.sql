@@ -0,0 +1,40 @@
+-- Flow-CodeBlock Go HTTP 触发器数据库变更（已有部署执行，新部署 init.sql 已包含）
+-- 功能: 把存储脚本绑定为公开的 Webhook 地址，每个触发器有独立的密钥、认证方式和限流
+
+SET NAMES utf8mb4;
+
+USE `flow_codeblock_go`;
+
+-- ==================== 表: HTTP 触发器表 ====================
+-- 用途: /flow/triggers 管理触发器，第三方系统请求 /flow/hooks/:key 触发脚本
+CREATE TABLE IF NOT EXISTS `function_triggers` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `trigger_key` VARCHAR(64) NOT NULL COMMENT '公开地址标识（/flow/hooks/:key）',
+  `token` VARCHAR(255) NOT NULL COMMENT '创建触发器的访问Token（执行时按该Token计入限流和配额）',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID',
+  `email` VARCHAR(255) NOT NULL COMMENT '用户邮箱',
+  `name` VARCHAR(64) NOT NULL COMMENT '触发器名称',
+  `function_name` VARCHAR(64) NOT NULL COMMENT '存储脚本名称',
+  `version` VARCHAR(32) NOT NULL DEFAULT 'latest' COMMENT '版本号或别名',
+  `methods` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '允许的 HTTP 方法（逗号分隔，空表示全部）',
+  `auth_mode` ENUM('none','secret','hmac') NOT NULL DEFAULT 'secret' COMMENT '认证方式',
+  `secret` VARCHAR(128) NOT NULL COMMENT '触发器密钥（secret 模式比对 / hmac 模式签名密钥）',
+  `signature_header` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '签名请求头（hmac 模式）',
+  `signature_algorithm` VARCHAR(16) NOT NULL DEFAULT 'sha256' COMMENT '签名算法：sha1 / sha256 / sha512',
+  `signature_encoding` VARCHAR(16) NOT NULL DEFAULT 'hex' COMMENT '签名编码：hex / base64',
+  `signature_prefix` VARCHAR(32) NOT NULL DEFAULT '' COMMENT '签名前缀（如 sha256=）',
+  `timestamp_header` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '时间戳请求头（为空时只对请求体签名）',
+  `rate_limit_per_minute` INT NOT NULL DEFAULT 60 COMMENT '每分钟请求上限',
+  `rate_limit_burst` INT NOT NULL DEFAULT 10 COMMENT '每秒请求上限',
+  `enabled` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_trigger_key` (`trigger_key`),
+  KEY `idx_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='存储脚本 HTTP 触发器表';
+
+-- ==================== 验证表结构 ====================
+SHOW CREATE TABLE `function_triggers`;
@@ -0,0 +1,27 @@
+-- Flow-CodeBlock Go 工作流数据库变更（已有部署执行，新部署 init.sql 已包含）
+-- 功能: 多个存储脚本组成 DAG，边把上游结果映射为下游输入，在服务内一次执行完成
+
+SET NAMES utf8mb4;
+
+USE `flow_codeblock_go`;
+
+-- ==================== 表: 工作流表 ====================
+-- 用途: /flow/workflows 管理工作流，POST /flow/workflows/:id/run 执行
+CREATE TABLE IF NOT EXISTS `function_workflows` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `token` VARCHAR(255) NOT NULL COMMENT '创建工作流的访问Token（只能由该Token执行）',
+  `ws_id` VARCHAR(255) NOT NULL COMMENT '工作空间ID（节点脚本从该工作空间解析）',
+  `email` VARCHAR(255) NOT NULL COMMENT '用户邮箱',
+  `name` VARCHAR(64) NOT NULL COMMENT '工作流名称',
+  `description` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '说明',
+  `definition` MEDIUMTEXT NOT NULL COMMENT '工作流定义（JSON：nodes / edges / output）',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  
+  PRIMARY KEY (`id`),
+  KEY `idx_token` (`token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci 
+COMMENT='工作流（存储脚本 DAG）表';
+
+-- ==================== 验证表结构 ====================
+SHOW CREATE TABLE `function_workflows`;
@@ -0,0 +1,789 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/utils"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	// cronLeaderKey Redis 主节点租约（只有主节点检查到期计划，减少数据库查询）
+	cronLeaderKey = "flow:cron:leader"
+	// cronDueBatchSize 每次检查最多取出的到期计划数
+	cronDueBatchSize = 100
+	// cronMaintenanceInterval 执行记录清理间隔
+	cronMaintenanceInterval = time.Hour
+	// cronStaleRunAfter 超过该时间仍为 running 的记录视为执行实例已退出
+	cronStaleRunAfter = time.Hour
+)
+
+// cronRenewScript 续约：仅当租约仍属于本实例时延长过期时间
+var cronRenewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// cronReleaseScript 释放：仅当租约仍属于本实例时删除
+var cronReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+var (
+	// ErrScheduleNotFound 计划不存在（或不属于当前 Token）
+	ErrScheduleNotFound = errors.New("定时执行计划不存在")
+	// ErrCronDisabled 定时执行未启用
+	ErrCronDisabled = errors.New("定时执行未启用（CRON_ENABLED=false）")
+)
+
+// cronRunner 按 Token 执行存储脚本（*FunctionRunner 实现）
+type cronRunner interface {
+	Run(ctx context.Context, run *FunctionRun) (*FunctionRunResult, *model.ExecutionError)
+}
+
+// cronFire 一次待执行的触发
+type cronFire struct {
+	schedule    *model.FunctionSchedule
+	scheduledAt time.Time
+	runID       string
+}
+
+// cronStats 定时执行统计
+type cronStats struct {
+	fired     int64
+	skipped   int64
+	succeeded int64
+	failed    int64
+}
+
+// CronService 定时执行服务（存储脚本按 cron 表达式定时执行）
+//
+// 职责：
+//   - 管理 Token 的定时执行计划：cron 表达式 + 时区 + 固定输入 + 重叠策略
+//   - 每隔 CRON_POLL_INTERVAL_SEC 检查到期计划，按计划所属 Token 校验、限流、扣减配额后执行，写入执行记录
+//
+// 多实例部署：
+//   - Redis 可用时通过租约选出主节点，只有主节点检查和触发（其他实例空闲，主节点退出后租约过期自动接替）
+//   - 每次触发通过数据库条件更新 next_run_at 认领，即使 Redis 不可用、多个实例同时检查，同一次触发也只会执行一次
+type CronService struct {
+	repo            *repository.ScheduleRepository
+	runner          cronRunner
+	functionService *FunctionService
+	executor        *sandbox.JSExecutor
+	redisClient     *redis.Client
+	cfg             config.CronConfig
+	instanceID      string
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup // 检查协程
+	runWg    sync.WaitGroup // 执行中的触发
+	runSlots chan struct{}  // 本实例同时执行的上限
+
+	mu      sync.Mutex
+	running map[int64]int         // schedule_id -> 执行中的数量
+	queued  map[int64][]*cronFire // schedule_id -> queue 策略下等待的触发
+
+	isLeader        atomic.Bool
+	pollFailing     atomic.Bool
+	lastPollAt      atomic.Int64 // Unix 毫秒
+	lastMaintenance time.Time
+	stats           cronStats
+}
+
+// NewCronService 创建定时执行服务（CRON_ENABLED=true 时需调用 Start 启动检查协程）
+func NewCronService(
+	repo *repository.ScheduleRepository,
+	runner *FunctionRunner,
+	functionService *FunctionService,
+	executor *sandbox.JSExecutor,
+	redisClient *redis.Client,
+	cfg config.CronConfig,
+) *CronService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CronService{
+		repo:            repo,
+		runner:          runner,
+		functionService: functionService,
+		executor:        executor,
+		redisClient:     redisClient,
+		cfg:             cfg,
+		instanceID:      uuid.New().String(),
+		ctx:             ctx,
+		cancel:          cancel,
+		stopChan:        make(chan struct{}),
+		runSlots:        make(chan struct{}, cfg.MaxConcurrentRuns),
+		running:         make(map[int64]int),
+		queued:          make(map[int64][]*cronFire),
+	}
+}
+
+// IsEnabled 是否触发定时执行
+func (s *CronService) IsEnabled() bool {
+	return s.cfg.Enabled
+}
+
+// Start 启动检查协程
+func (s *CronService) Start() {
+	if !s.cfg.Enabled {
+		utils.Info("定时执行未启用（CRON_ENABLED=false）")
+		return
+	}
+
+	s.wg.Add(1)
+	go s.pollLoop()
+
+	utils.Info("定时执行服务已启动",
+		zap.String("instance_id", s.instanceID),
+		zap.Duration("poll_interval", s.cfg.PollInterval),
+		zap.Bool("leader_election", s.redisClient != nil),
+		zap.Int("max_concurrent_runs", s.cfg.MaxConcurrentRuns))
+}
+
+// Stop 停止触发并等待执行中的任务完成（超时后取消）
+func (s *CronService) Stop(timeout time.Duration) {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+		s.wg.Wait()
+
+		done := make(chan struct{})
+		go func() {
+			s.runWg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			utils.Warn("定时执行任务未在超时内完成，取消执行", zap.Duration("timeout", timeout))
+			s.cancel()
+			<-done
+		}
+		s.cancel()
+
+		if s.redisClient != nil && s.isLeader.Load() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			cronReleaseScript.Run(ctx, s.redisClient, []string{cronLeaderKey}, s.instanceID)
+			cancel()
+		}
+		utils.Info("定时执行服务已停止")
+	})
+}
+
+// ==================== 计划管理 ====================
+
+// Create 创建定时执行计划（归属于当前 Token）
+func (s *CronService) Create(ctx context.Context, tokenInfo *model.TokenInfo, req *model.CreateScheduleRequest) (*model.FunctionScheduleDetail, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrCronDisabled
+	}
+
+	count, err := s.repo.CountByToken(ctx, tokenInfo.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.cfg.MaxSchedulesPerToken {
+		return nil, scheduleValidationError(fmt.Sprintf("每个 Token 最多创建 %d 个定时执行计划", s.cfg.MaxSchedulesPerToken))
+	}
+
+	schedule := &model.FunctionSchedule{
+		Token:         tokenInfo.AccessToken,
+		WsID:          tokenInfo.WsID,
+		Email:         tokenInfo.Email,
+		Name:          strings.TrimSpace(req.Name),
+		FunctionName:  req.FunctionName,
+		Version:       req.Version,
+		CronExpr:      req.Cron,
+		Timezone:      req.Timezone,
+		OverlapPolicy: req.OverlapPolicy,
+		Enabled:       req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.prepare(ctx, schedule, req.Input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	utils.Info("定时执行计划已创建",
+		zap.Int64("schedule_id", schedule.ID),
+		zap.String("ws_id", schedule.WsID),
+		zap.String("function", schedule.FunctionName),
+		zap.String("cron", schedule.CronExpr),
+		zap.String("timezone", schedule.Timezone))
+	return newScheduleDetail(schedule), nil
+}
+
+// Update 更新定时执行计划（从当前时间重新计算下次触发时间）
+func (s *CronService) Update(ctx context.Context, token string, id int64, req *model.UpdateScheduleRequest) (*model.FunctionScheduleDetail, error) {
+	schedule, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var input map[string]interface{}
+	if err := json.Unmarshal([]byte(schedule.Input), &input); err != nil {
+		input = nil
+	}
+	if req.Name != nil {
+		schedule.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.FunctionName != nil {
+		schedule.FunctionName = *req.FunctionName
+	}
+	if req.Version != nil {
+		schedule.Version = *req.Version
+	}
+	if req.Cron != nil {
+		schedule.CronExpr = *req.Cron
+	}
+	if req.Timezone != nil {
+		schedule.Timezone = *req.Timezone
+	}
+	if req.Input != nil {
+		input = *req.Input
+	}
+	if req.OverlapPolicy != nil {
+		schedule.OverlapPolicy = *req.OverlapPolicy
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+
+	if err := s.prepare(ctx, schedule, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	utils.Info("定时执行计划已更新",
+		zap.Int64("schedule_id", schedule.ID),
+		zap.Bool("enabled", schedule.Enabled),
+		zap.String("cron", schedule.CronExpr))
+	return newScheduleDetail(schedule), nil
+}
+
+// Delete 删除定时执行计划及其执行记录（已开始的执行不受影响）
+func (s *CronService) Delete(ctx context.Context, token string, id int64) error {
+	if _, err := s.getOwned(ctx, token, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.queued, id)
+	s.mu.Unlock()
+
+	utils.Info("定时执行计划已删除", zap.Int64("schedule_id", id))
+	return nil
+}
+
+// Get 获取定时执行计划
+func (s *CronService) Get(ctx context.Context, token string, id int64) (*model.FunctionScheduleDetail, error) {
+	schedule, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return nil, err
+	}
+	return newScheduleDetail(schedule), nil
+}
+
+// List 获取 Token 的全部定时执行计划
+func (s *CronService) List(ctx context.Context, token string) ([]*model.FunctionScheduleDetail, error) {
+	schedules, err := s.repo.ListByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]*model.FunctionScheduleDetail, 0, len(schedules))
+	for _, schedule := range schedules {
+		details = append(details, newScheduleDetail(schedule))
+	}
+	return details, nil
+}
+
+// ListRuns 分页查询计划的执行记录
+func (s *CronService) ListRuns(ctx context.Context, token string, id int64, req *model.ScheduleRunQueryRequest) ([]*model.ScheduleRunDetail, int, error) {
+	if _, err := s.getOwned(ctx, token, id); err != nil {
+		return nil, 0, err
+	}
+	runs, total, err := s.repo.ListRuns(ctx, id, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	details := make([]*model.ScheduleRunDetail, 0, len(runs))
+	for _, run := range runs {
+		details = append(details, model.NewScheduleRunDetail(run))
+	}
+	return details, total, nil
+}
+
+// RunNow 立即触发一次（不影响下次计划触发时间，同样遵守重叠策略），返回 run_id
+func (s *CronService) RunNow(ctx context.Context, token string, id int64) (string, error) {
+	if !s.cfg.Enabled {
+		return "", ErrCronDisabled
+	}
+	schedule, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return "", err
+	}
+
+	fire := &cronFire{schedule: schedule, scheduledAt: time.Now(), runID: uuid.New().String()}
+	s.dispatch(fire)
+	return fire.runID, nil
+}
+
+// GetStats 定时执行统计（管理员接口）
+func (s *CronService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	running, queued := 0, 0
+	for _, n := range s.running {
+		running += n
+	}
+	for _, fires := range s.queued {
+		queued += len(fires)
+	}
+	s.mu.Unlock()
+
+	lastPollAt := ""
+	if ms := s.lastPollAt.Load(); ms > 0 {
+		lastPollAt = utils.FormatTime(time.UnixMilli(ms))
+	}
+
+	return map[string]interface{}{
+		"enabled":             s.cfg.Enabled,
+		"instance_id":         s.instanceID,
+		"is_leader":           s.isLeader.Load(),
+		"leader_election":     s.redisClient != nil,
+		"poll_interval":       s.cfg.PollInterval.String(),
+		"last_poll_at":        lastPollAt,
+		"running":             running,
+		"queued":              queued,
+		"max_concurrent_runs": s.cfg.MaxConcurrentRuns,
+		"fired":               atomic.LoadInt64(&s.stats.fired),
+		"skipped":             atomic.LoadInt64(&s.stats.skipped),
+		"succeeded":           atomic.LoadInt64(&s.stats.succeeded),
+		"failed":              atomic.LoadInt64(&s.stats.failed),
+	}
+}
+
+// prepare 校验计划并计算下次触发时间
+func (s *CronService) prepare(ctx context.Context, schedule *model.FunctionSchedule, input map[string]interface{}) error {
+	if schedule.Name == "" {
+		return scheduleValidationError("计划名称不能为空")
+	}
+	if schedule.Version == "" {
+		schedule.Version = model.FunctionAliasLatest
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = s.cfg.DefaultTimezone
+	}
+	if schedule.OverlapPolicy == "" {
+		schedule.OverlapPolicy = model.ScheduleOverlapSkip
+	}
+
+	expr, err := utils.ParseCron(schedule.CronExpr)
+	if err != nil {
+		return scheduleValidationError("cron 表达式无效: " + err.Error())
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return scheduleValidationError("时区无效: " + schedule.Timezone)
+	}
+
+	// 脚本和版本必须存在（之后被删除时，执行记录为 NotFoundError）
+	if _, err := s.functionService.Resolve(ctx, schedule.WsID, schedule.FunctionName, schedule.Version); err != nil {
+		if errors.Is(err, ErrFunctionNotFound) || errors.Is(err, ErrFunctionVersionNotFound) {
+			return scheduleValidationError(fmt.Sprintf("%s: %s@%s", err.Error(), schedule.FunctionName, schedule.Version))
+		}
+		return err
+	}
+
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return scheduleValidationError("input 序列化失败: " + err.Error())
+	}
+	if maxInput := s.executor.GetMaxInputSize(); len(data) > maxInput {
+		return scheduleValidationError(fmt.Sprintf("input 大小超过限制: %d > %d 字节", len(data), maxInput))
+	}
+	schedule.Input = string(data)
+
+	schedule.NextRunAt = model.ShanghaiTime{}
+	if schedule.Enabled {
+		next := expr.Next(time.Now().In(loc))
+		if next.IsZero() {
+			return scheduleValidationError("cron 表达式在未来 5 年内不会触发")
+		}
+		schedule.NextRunAt = model.ShanghaiTime{Time: next}
+	}
+	return nil
+}
+
+// getOwned 获取属于 token 的计划（其他 Token 的计划视为不存在）
+func (s *CronService) getOwned(ctx context.Context, token string, id int64) (*model.FunctionSchedule, error) {
+	schedule, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil || schedule.Token != token {
+		return nil, ErrScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// ==================== 触发 ====================
+
+// pollLoop 检查协程
+func (s *CronService) pollLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.acquireLeadership() {
+				s.poll()
+				s.maintain()
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// acquireLeadership 获取或续约主节点租约
+// Redis 未配置或异常时返回 true：每个实例都检查，由 ClaimNextRun 保证每次触发只执行一次
+func (s *CronService) acquireLeadership() bool {
+	if s.redisClient == nil {
+		s.isLeader.Store(true)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 2*time.Second)
+	defer cancel()
+
+	var leader bool
+	if s.isLeader.Load() {
+		renewed, err := cronRenewScript.Run(ctx, s.redisClient, []string{cronLeaderKey},
+			s.instanceID, s.cfg.LeaderTTL.Milliseconds()).Int()
+		if err != nil {
+			utils.Warn("定时执行主节点续约失败，本轮仍然检查", zap.Error(err))
+			return true
+		}
+		leader = renewed == 1
+	}
+	if !leader {
+		acquired, err := s.redisClient.SetNX(ctx, cronLeaderKey, s.instanceID, s.cfg.LeaderTTL).Result()
+		if err != nil {
+			utils.Warn("定时执行主节点选举失败，本轮仍然检查", zap.Error(err))
+			return true
+		}
+		leader = acquired
+	}
+
+	if leader != s.isLeader.Swap(leader) {
+		utils.Info("定时执行主节点变更", zap.String("instance_id", s.instanceID), zap.Bool("is_leader", leader))
+	}
+	return leader
+}
+
+// poll 认领并触发到期计划
+func (s *CronService) poll() {
+	ctx, cancel := context.WithTimeout(s.ctx, s.cfg.PollInterval)
+	defer cancel()
+
+	now := time.Now()
+	s.lastPollAt.Store(now.UnixMilli())
+
+	due, err := s.repo.ListDue(ctx, now, cronDueBatchSize)
+	if err != nil {
+		// 只在开始失败时记录一次（如未执行 scripts/schedules.sql）
+		if !s.pollFailing.Swap(true) {
+			utils.Error("检查到期定时执行计划失败", zap.Error(err))
+		}
+		return
+	}
+	if s.pollFailing.Swap(false) {
+		utils.Info("检查到期定时执行计划已恢复")
+	}
+
+	for _, schedule := range due {
+		next := time.Time{}
+		if expr, err := utils.ParseCron(schedule.CronExpr); err == nil {
+			if loc, err := time.LoadLocation(schedule.Timezone); err == nil {
+				// 错过的触发（如服务停机期间）不补执行，只从当前时间计算下一次
+				next = expr.Next(now.In(loc))
+			}
+		}
+
+		claimed, err := s.repo.ClaimNextRun(ctx, schedule.ID, schedule.NextRunAt.Time, next)
+		if err != nil {
+			utils.Error("认领定时执行失败", zap.Int64("schedule_id", schedule.ID), zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue // 已被其他实例认领
+		}
+		if next.IsZero() {
+			utils.Warn("定时执行计划不会再触发，已禁用", zap.Int64("schedule_id", schedule.ID), zap.String("cron", schedule.CronExpr))
+		}
+
+		s.dispatch(&cronFire{schedule: schedule, scheduledAt: schedule.NextRunAt.Time, runID: uuid.New().String()})
+	}
+}
+
+// dispatch 按重叠策略执行、排队或跳过一次触发
+func (s *CronService) dispatch(fire *cronFire) {
+	atomic.AddInt64(&s.stats.fired, 1)
+	id := fire.schedule.ID
+
+	s.mu.Lock()
+	if s.running[id] > 0 {
+		switch fire.schedule.OverlapPolicy {
+		case model.ScheduleOverlapQueue:
+			if len(s.queued[id]) < s.cfg.MaxQueuedRuns {
+				s.queued[id] = append(s.queued[id], fire)
+				s.mu.Unlock()
+				utils.Debug("定时执行排队等待上一次结束", zap.Int64("schedule_id", id), zap.String("run_id", fire.runID))
+				return
+			}
+			s.mu.Unlock()
+			s.recordSkipped(fire, fmt.Sprintf("排队的触发已达上限（%d）", s.cfg.MaxQueuedRuns))
+			return
+		case model.ScheduleOverlapAllow:
+		default:
+			s.mu.Unlock()
+			s.recordSkipped(fire, "上一次执行尚未结束")
+			return
+		}
+	}
+	s.running[id]++
+	s.mu.Unlock()
+
+	s.runWg.Add(1)
+	go s.runFire(fire)
+}
+
+// runFire 执行一次触发，结束后启动排队中的下一次
+func (s *CronService) runFire(fire *cronFire) {
+	defer s.runWg.Done()
+
+	for fire != nil {
+		s.execute(fire)
+
+		id := fire.schedule.ID
+		s.mu.Lock()
+		fire = nil
+		if pending := s.queued[id]; len(pending) > 0 {
+			fire, s.queued[id] = pending[0], pending[1:]
+		} else {
+			delete(s.queued, id)
+			if s.running[id]--; s.running[id] <= 0 {
+				delete(s.running, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// recordSkipped 写入跳过记录
+func (s *CronService) recordSkipped(fire *cronFire, reason string) {
+	atomic.AddInt64(&s.stats.skipped, 1)
+	now := time.Now()
+	run := &model.ScheduleRun{
+		ScheduleID:   fire.schedule.ID,
+		RunID:        fire.runID,
+		Status:       model.ScheduleRunSkipped,
+		ErrorMessage: &reason,
+		ScheduledAt:  model.ShanghaiTime{Time: fire.scheduledAt},
+		StartedAt:    model.ShanghaiTime{Time: now},
+		FinishedAt:   model.ShanghaiTime{Time: now},
+	}
+	if err := s.repo.InsertRun(s.ctx, run); err != nil {
+		utils.Error("写入定时执行记录失败", zap.Int64("schedule_id", fire.schedule.ID), zap.Error(err))
+	}
+	if err := s.repo.UpdateLastRun(s.ctx, fire.schedule.ID, now, model.ScheduleRunSkipped); err != nil {
+		utils.Warn("更新定时执行状态失败", zap.Int64("schedule_id", fire.schedule.ID), zap.Error(err))
+	}
+	utils.Info("定时执行已跳过",
+		zap.Int64("schedule_id", fire.schedule.ID),
+		zap.String("run_id", fire.runID),
+		zap.String("reason", reason))
+}
+
+// execute 执行一次触发并写入执行记录
+func (s *CronService) execute(fire *cronFire) {
+	select {
+	case s.runSlots <- struct{}{}:
+		defer func() { <-s.runSlots }()
+	case <-s.ctx.Done():
+		return
+	}
+
+	schedule := fire.schedule
+	startTime := time.Now()
+	run := &model.ScheduleRun{
+		ScheduleID:  schedule.ID,
+		RunID:       fire.runID,
+		Status:      model.ScheduleRunRunning,
+		ScheduledAt: model.ShanghaiTime{Time: fire.scheduledAt},
+		StartedAt:   model.ShanghaiTime{Time: startTime},
+	}
+	if err := s.repo.InsertRun(s.ctx, run); err != nil {
+		utils.Error("写入定时执行记录失败", zap.Int64("schedule_id", schedule.ID), zap.Error(err))
+	}
+
+	version, result, execErr := s.runOnce(schedule, fire.runID)
+	run.FunctionVersion = version
+	run.DurationMs = time.Since(startTime).Milliseconds()
+	run.FinishedAt = model.ShanghaiTime{Time: time.Now()}
+
+	if execErr != nil {
+		run.Status = model.ScheduleRunFailed
+		run.ErrorType, run.ErrorMessage = &execErr.Type, &execErr.Message
+		atomic.AddInt64(&s.stats.failed, 1)
+	} else {
+		run.Status = model.ScheduleRunSuccess
+		if len(result) > 0 {
+			stored, truncated := truncateUTF8(string(result), s.cfg.MaxResultBytes)
+			run.Result, run.ResultTruncated = &stored, truncated
+		}
+		atomic.AddInt64(&s.stats.succeeded, 1)
+	}
+
+	// 执行记录使用独立 context：关闭时被取消的执行也要写入最终状态
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if run.ID > 0 {
+		if err := s.repo.FinishRun(ctx, run); err != nil {
+			utils.Error("更新定时执行记录失败", zap.String("run_id", run.RunID), zap.Error(err))
+		}
+	}
+	if err := s.repo.UpdateLastRun(ctx, schedule.ID, startTime, run.Status); err != nil {
+		utils.Warn("更新定时执行状态失败", zap.Int64("schedule_id", schedule.ID), zap.Error(err))
+	}
+
+	utils.Info("定时执行完成",
+		zap.Int64("schedule_id", schedule.ID),
+		zap.String("run_id", run.RunID),
+		zap.String("function", schedule.FunctionName),
+		zap.Int("version", version),
+		zap.String("status", run.Status),
+		zap.Int64("duration_ms", run.DurationMs))
+}
+
+// runOnce 按计划所属 Token 执行脚本，返回实际执行的版本号、结果 JSON 和错误
+func (s *CronService) runOnce(schedule *model.FunctionSchedule, runID string) (int, []byte, *model.ExecutionError) {
+	ctx := context.WithValue(s.ctx, utils.RequestIDKey, runID)
+	ctx, span := utils.StartSpan(ctx, "cron.run",
+		attribute.Int64("schedule_id", schedule.ID),
+		attribute.String("function", schedule.FunctionName))
+	defer span.End()
+
+	var input map[string]interface{}
+	if err := json.Unmarshal([]byte(schedule.Input), &input); err != nil {
+		input = nil
+	}
+
+	result, execErr := s.runner.Run(ctx, &FunctionRun{
+		RequestID:    runID,
+		Token:        schedule.Token,
+		FunctionName: schedule.FunctionName,
+		Version:      schedule.Version,
+		Input:        input,
+	})
+	version := 0
+	if result != nil {
+		version = result.Version
+	}
+	if execErr != nil {
+		return version, nil, execErr
+	}
+	return version, result.ResultJSON, nil
+}
+
+// maintain 定期清理过期执行记录，并将实例退出时遗留的 running 记录标记为失败
+func (s *CronService) maintain() {
+	if time.Since(s.lastMaintenance) < cronMaintenanceInterval {
+		return
+	}
+	s.lastMaintenance = time.Now()
+
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+
+	stale, err := s.repo.FailStaleRuns(ctx, time.Now().Add(-cronStaleRunAfter),
+		utils.ErrorTypeServiceUnavail, "执行实例已退出，执行结果未知")
+	if err != nil {
+		utils.Warn("标记中断的定时执行记录失败", zap.Error(err))
+	} else if stale > 0 {
+		utils.Warn("已将中断的定时执行记录标记为失败", zap.Int("count", stale))
+	}
+
+	before := time.Now().AddDate(0, 0, -s.cfg.RunRetentionDays)
+	total := 0
+	for {
+		deleted, err := s.repo.DeleteRunsBefore(ctx, before, 5000)
+		if err != nil {
+			utils.Warn("清理定时执行记录失败", zap.Error(err))
+			break
+		}
+		total += deleted
+		if deleted < 5000 {
+			break
+		}
+		select {
+		case <-time.After(CleanupBatchDelay):
+		case <-s.stopChan:
+			return
+		}
+	}
+	if total > 0 {
+		utils.Info("已清理过期定时执行记录",
+			zap.Int("count", total),
+			zap.Int("retention_days", s.cfg.RunRetentionDays))
+	}
+}
+
+// newScheduleDetail 转换为输出（Token 脱敏）
+func newScheduleDetail(schedule *model.FunctionSchedule) *model.FunctionScheduleDetail {
+	masked := *schedule
+	masked.Token = utils.MaskToken(schedule.Token)
+	input := json.RawMessage(schedule.Input)
+	if !json.Valid(input) {
+		input = json.RawMessage("{}")
+	}
+	return &model.FunctionScheduleDetail{FunctionSchedule: &masked, Input: input}
+}
+
+// scheduleValidationError 参数校验失败（控制器返回 400）
+func scheduleValidationError(message string) *model.ExecutionError {
+	return &model.ExecutionError{Type: utils.ErrorTypeValidation, Message: message}
+}
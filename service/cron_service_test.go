@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/repository"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// stubCronRunner 记录执行顺序；每次执行阻塞到 release 关闭
+type stubCronRunner struct {
+	mu      sync.Mutex
+	calls   []string
+	started chan string
+	release chan struct{}
+}
+
+func (r *stubCronRunner) Run(ctx context.Context, run *FunctionRun) (*FunctionRunResult, *model.ExecutionError) {
+	r.mu.Lock()
+	r.calls = append(r.calls, run.RequestID)
+	r.mu.Unlock()
+	r.started <- run.RequestID
+	<-r.release
+	return &FunctionRunResult{Version: 1, ResultJSON: []byte("1")}, nil
+}
+
+// waitStarted 等待下一次执行开始
+func (r *stubCronRunner) waitStarted(t *testing.T) string {
+	t.Helper()
+	select {
+	case runID := <-r.started:
+		return runID
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待执行开始超时")
+		return ""
+	}
+}
+
+// newTestCronService 执行记录写入不可达的数据库（写入失败只记录日志），脚本由 stub 执行
+func newTestCronService(t *testing.T, runner cronRunner) *CronService {
+	t.Helper()
+	db, err := sql.Open("mysql", "user:pass@tcp(127.0.0.1:1)/flow?timeout=100ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s := NewCronService(repository.NewScheduleRepository(sqlx.NewDb(db, "mysql")), nil, nil, nil, nil, config.CronConfig{
+		Enabled:           true,
+		MaxConcurrentRuns: 10,
+		MaxQueuedRuns:     1,
+		MaxResultBytes:    1024,
+	})
+	s.runner = runner
+	return s
+}
+
+func TestCronDispatchOverlapPolicies(t *testing.T) {
+	tests := []struct {
+		policy      string
+		concurrent  bool     // 第二次触发是否与第一次同时执行
+		wantCalls   []string // 最终的执行顺序
+		wantSkipped int64
+	}{
+		// 排队：第二次在第一次结束后执行，超出 MaxQueuedRuns 的第三次跳过
+		{model.ScheduleOverlapQueue, false, []string{"run-1", "run-2"}, 1},
+		// 跳过：上一次未结束时的触发都跳过
+		{model.ScheduleOverlapSkip, false, []string{"run-1"}, 2},
+		// 允许：每次触发立即执行
+		{model.ScheduleOverlapAllow, true, []string{"run-1", "run-2", "run-3"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			runner := &stubCronRunner{started: make(chan string, 3), release: make(chan struct{})}
+			s := newTestCronService(t, runner)
+			schedule := &model.FunctionSchedule{ID: 1, OverlapPolicy: tt.policy, Input: "{}"}
+
+			s.dispatch(&cronFire{schedule: schedule, scheduledAt: time.Now(), runID: "run-1"})
+			if got := runner.waitStarted(t); got != "run-1" {
+				t.Fatalf("第一次执行 = %s, want run-1", got)
+			}
+
+			// 第一次执行中再触发两次
+			s.dispatch(&cronFire{schedule: schedule, scheduledAt: time.Now(), runID: "run-2"})
+			s.dispatch(&cronFire{schedule: schedule, scheduledAt: time.Now(), runID: "run-3"})
+			if tt.concurrent {
+				runner.waitStarted(t)
+				runner.waitStarted(t)
+			} else {
+				select {
+				case runID := <-runner.started:
+					t.Fatalf("%s 不应在上一次结束前执行", runID)
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+
+			close(runner.release)
+			s.runWg.Wait()
+
+			runner.mu.Lock()
+			calls := runner.calls
+			runner.mu.Unlock()
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if !tt.concurrent {
+				for i := range calls {
+					if calls[i] != tt.wantCalls[i] {
+						t.Fatalf("calls = %v, want %v", calls, tt.wantCalls)
+					}
+				}
+			}
+			if skipped := atomic.LoadInt64(&s.stats.skipped); skipped != tt.wantSkipped {
+				t.Errorf("skipped = %d, want %d", skipped, tt.wantSkipped)
+			}
+			if fired := atomic.LoadInt64(&s.stats.fired); fired != 3 {
+				t.Errorf("fired = %d, want 3", fired)
+			}
+			if len(s.running) != 0 || len(s.queued) != 0 {
+				t.Errorf("running = %v, queued = %v, want empty", s.running, s.queued)
+			}
+		})
+	}
+}
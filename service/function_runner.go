@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+)
+
+// FunctionRunner 以 Token 的身份执行存储脚本（定时执行、HTTP 触发器、工作流共用）
+//
+// 没有 HTTP 中间件可用的场景下，按与 POST /flow/functions/:name 相同的顺序完成：
+// Token 校验 → Token 限流 → 沙箱策略 → 解析版本 → 配额扣减 → 执行 → 统计和执行历史
+type FunctionRunner struct {
+	functionService    *FunctionService
+	executor           *sandbox.JSExecutor
+	tokenService       *TokenService
+	policyService      *PolicyService
+	rateLimiterService *RateLimiterService
+	quotaService       *QuotaService
+	statsService       *StatsService
+	historyService     *HistoryService
+}
+
+// FunctionRun 一次执行的参数
+type FunctionRun struct {
+	RequestID    string // 执行的 request_id（执行历史、统计、链路追踪使用）
+	Token        string // 归属 Token（计入该 Token 的限流和配额）
+	FunctionName string
+	Version      string // 版本号或别名
+	Input        map[string]interface{}
+}
+
+// FunctionRunResult 执行结果
+type FunctionRunResult struct {
+	Version    int                    // 实际执行的版本号
+	Result     *model.ExecutionResult // 执行器返回的结果（含 console 输出）
+	ResultJSON []byte                 // 结果 JSON
+}
+
+// NewFunctionRunner 创建存储脚本执行器
+func NewFunctionRunner(
+	functionService *FunctionService,
+	executor *sandbox.JSExecutor,
+	tokenService *TokenService,
+	policyService *PolicyService,
+	rateLimiterService *RateLimiterService,
+	quotaService *QuotaService,
+	statsService *StatsService,
+	historyService *HistoryService,
+) *FunctionRunner {
+	return &FunctionRunner{
+		functionService:    functionService,
+		executor:           executor,
+		tokenService:       tokenService,
+		policyService:      policyService,
+		rateLimiterService: rateLimiterService,
+		quotaService:       quotaService,
+		statsService:       statsService,
+		historyService:     historyService,
+	}
+}
+
+// Run 校验、限流、扣减配额后执行脚本，错误类型与代码执行接口一致
+// 脚本执行失败时同时返回结果（包含实际执行的版本号），执行前被拒绝时结果为 nil
+func (r *FunctionRunner) Run(ctx context.Context, run *FunctionRun) (*FunctionRunResult, *model.ExecutionError) {
+	ctx = context.WithValue(ctx, utils.RequestIDKey, run.RequestID)
+
+	// 1. Token 校验（Token 被删除、禁用或过期后执行失败）
+	tokenInfo, err := r.tokenService.ValidateToken(ctx, run.Token)
+	if err != nil {
+		return nil, &model.ExecutionError{Type: utils.ErrorTypeAuthentication, Message: "Token无效或已过期: " + err.Error()}
+	}
+
+	// 2. Token 限流（与 RateLimiterMiddleware 一致：限流器异常时不阻塞）
+	if rateLimitConfig := tokenInfo.GetRateLimitConfig(); !rateLimitConfig.Unlimited && r.rateLimiterService != nil {
+		allowed, limitInfo, err := r.rateLimiterService.CheckLimit(ctx, tokenInfo.AccessToken, rateLimitConfig)
+		if err != nil {
+			utils.Error("存储脚本执行限流检查异常", zap.String("request_id", run.RequestID), zap.Error(err))
+		} else if !allowed {
+			return nil, &model.ExecutionError{
+				Type:       utils.ErrorTypeTokenRateLimit,
+				Message:    limitInfo.Message,
+				RetryAfter: time.Duration(limitInfo.RetryAfter) * time.Second,
+			}
+		}
+	}
+
+	// 3. 沙箱策略
+	policy, err := r.policyService.ResolveForToken(ctx, tokenInfo)
+	if err != nil {
+		if errors.Is(err, ErrPolicyNotFound) {
+			return nil, &model.ExecutionError{Type: utils.ErrorTypeAuthorization, Message: "Token引用的沙箱策略不存在，请联系管理员"}
+		}
+		return nil, &model.ExecutionError{Type: utils.ErrorTypeInternal, Message: "沙箱策略加载失败"}
+	}
+
+	// 4. 解析脚本版本
+	fn, err := r.functionService.Resolve(ctx, tokenInfo.WsID, run.FunctionName, run.Version)
+	if err != nil {
+		if errors.Is(err, ErrFunctionNotFound) || errors.Is(err, ErrFunctionVersionNotFound) {
+			return nil, &model.ExecutionError{
+				Type:    utils.ErrorTypeNotFound,
+				Message: fmt.Sprintf("%s: %s@%s", err.Error(), run.FunctionName, run.Version),
+			}
+		}
+		utils.Error("解析脚本失败", zap.String("request_id", run.RequestID), zap.Error(err))
+		return nil, &model.ExecutionError{Type: utils.ErrorTypeInternal, Message: "解析脚本失败"}
+	}
+
+	return r.Execute(ctx, tokenInfo, policy, fn, run.RequestID, run.Input)
+}
+
+// Execute 以已校验的 Token 执行已解析的脚本：配额扣减 → 执行 → 统计和执行历史
+// 调用方负责 Token 校验、限流和沙箱策略（工作流在 HTTP 中间件中已完成）
+func (r *FunctionRunner) Execute(ctx context.Context, tokenInfo *model.TokenInfo, policy *model.SandboxPolicy, fn *model.ResolvedFunction, requestID string, input map[string]interface{}) (*FunctionRunResult, *model.ExecutionError) {
+	ctx = context.WithValue(ctx, utils.RequestIDKey, requestID)
+
+	// 配额扣减（count / hybrid 类型 Token）
+	if r.quotaService != nil && tokenInfo.NeedsQuotaCheck() {
+		if _, _, err := r.quotaService.ConsumeQuota(ctx, tokenInfo.AccessToken, tokenInfo.WsID, tokenInfo.Email, requestID, true, nil, nil); err != nil {
+			return nil, &model.ExecutionError{Type: "QuotaExceeded", Message: "配额已用完，请联系管理员充值"}
+		}
+	}
+
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+
+	// 执行（与同步请求共用公平调度配额）
+	execCtx := sandbox.WithSandboxPolicy(sandbox.WithExecutionTenant(ctx, tokenInfo.AccessToken, tokenInfo.WsID), policy)
+	startTime := time.Now()
+	result, execErr := r.executor.Execute(execCtx, fn.Code, input)
+	executionTime := time.Since(startTime).Milliseconds()
+
+	entry := &HistoryEntry{
+		RequestID:       requestID,
+		Token:           tokenInfo.AccessToken,
+		WsID:            tokenInfo.WsID,
+		Email:           tokenInfo.Email,
+		Code:            fn.Code,
+		Input:           input,
+		ExecutionTimeMs: executionTime,
+	}
+
+	runResult := &FunctionRunResult{Version: fn.Version, Result: result}
+	var runErr *model.ExecutionError
+	status := "success"
+	if execErr != nil {
+		status = "failed"
+		runErr = &model.ExecutionError{Type: "RuntimeError", Message: execErr.Error()}
+		if e, ok := execErr.(*model.ExecutionError); ok {
+			runErr = &model.ExecutionError{Type: e.Type, Message: e.Message, RetryAfter: e.RetryAfter}
+			entry.Logs, entry.LogsTruncated = e.Logs, e.LogsTruncated
+		}
+		entry.ErrorType, entry.ErrorMessage = runErr.Type, runErr.Message
+	} else {
+		runResult.ResultJSON = result.JSONData
+		if len(runResult.ResultJSON) == 0 {
+			runResult.ResultJSON, _ = json.Marshal(result.Result)
+		}
+		entry.Result, entry.ResultJSON = result.Result, result.JSONData
+		entry.Logs, entry.LogsTruncated = result.Logs, result.LogsTruncated
+	}
+
+	// 统计和执行历史（与代码执行接口一致）
+	if r.statsService != nil {
+		moduleInfo := utils.ParseModuleUsage(fn.Code)
+		r.statsService.RecordExecutionStats(&model.ExecutionStatsRecord{
+			ExecutionID:     requestID,
+			Token:           tokenInfo.AccessToken,
+			WsID:            tokenInfo.WsID,
+			Email:           tokenInfo.Email,
+			HasRequire:      moduleInfo.HasRequire,
+			ModulesUsed:     moduleInfo.GetModuleList(),
+			ModuleCount:     moduleInfo.ModuleCount,
+			ExecutionStatus: status,
+			ExecutionTimeMs: executionTime,
+			CodeLength:      len(fn.Code),
+			IsAsync:         r.executor.GetAnalyzer().AnalyzeCode(fn.Code).IsAsync,
+			ExecutionDate:   time.Now().Format("2006-01-02"),
+			ExecutionTime:   time.Now(),
+		})
+	}
+	r.historyService.Record(policy, entry)
+
+	if runErr != nil {
+		return runResult, runErr
+	}
+	return runResult, nil
+}
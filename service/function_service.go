@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+)
+
+// functionCacheTTL 调用时解析结果的本地缓存时间
+// 本实例发布 / 移动别名 / 删除时立即失效；其他实例最多延迟一个 TTL 生效
+const functionCacheTTL = 30 * time.Second
+
+var (
+	// ErrFunctionNotFound 脚本不存在
+	ErrFunctionNotFound = errors.New("脚本不存在")
+	// ErrFunctionVersionNotFound 版本或别名不存在
+	ErrFunctionVersionNotFound = errors.New("脚本版本不存在")
+)
+
+var (
+	functionNamePattern  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+	functionAliasPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,31}$`)
+)
+
+// functionCacheEntry 解析结果缓存条目
+type functionCacheEntry struct {
+	resolved  *model.ResolvedFunction
+	expiresAt time.Time
+}
+
+// FunctionService 存储脚本服务
+//
+// 职责：
+//   - 按工作空间管理脚本：创建、更新说明、删除、查询
+//   - 发布不可变的编号版本，维护别名（latest 自动指向最新版本，其他别名如 prod 手动移动）和回滚
+//   - 发布时预校验并预编译到执行器的代码缓存，调用时按 name[@version|alias] 解析代码
+type FunctionService struct {
+	repo     *repository.FunctionRepository
+	executor *sandbox.JSExecutor
+
+	mu    sync.RWMutex
+	cache map[string]map[string]*functionCacheEntry // ws_id/name -> 版本引用 -> 解析结果
+}
+
+// NewFunctionService 创建存储脚本服务
+func NewFunctionService(repo *repository.FunctionRepository, executor *sandbox.JSExecutor) *FunctionService {
+	return &FunctionService{
+		repo:     repo,
+		executor: executor,
+		cache:    make(map[string]map[string]*functionCacheEntry),
+	}
+}
+
+// ParseFunctionRef 拆分调用路径中的 name[@version|alias]（未指定时使用 latest）
+func ParseFunctionRef(ref string) (name, version string) {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		name, version = ref[:i], ref[i+1:]
+	} else {
+		name = ref
+	}
+	if version == "" {
+		version = model.FunctionAliasLatest
+	}
+	return name, version
+}
+
+// Create 创建脚本并发布版本 1
+func (s *FunctionService) Create(ctx context.Context, wsID, email string, req *model.CreateFunctionRequest, code string) (*model.FunctionDetail, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if !functionNamePattern.MatchString(req.Name) {
+		return nil, functionValidationError("脚本名称只能包含字母、数字、_ . -（以字母或数字开头，最长64字符）")
+	}
+	if err := s.executor.PrepareCode(ctx, code); err != nil {
+		return nil, err
+	}
+
+	fn := &model.StoredFunction{WsID: wsID, Name: req.Name, Description: req.Description, CreatedBy: email}
+	if err := s.repo.Create(ctx, fn, newFunctionVersion(code, req.Comment, email)); err != nil {
+		return nil, err
+	}
+	s.invalidate(wsID, req.Name)
+
+	utils.Info("脚本创建成功", zap.String("ws_id", wsID), zap.String("name", req.Name))
+	return s.Get(ctx, wsID, req.Name)
+}
+
+// Publish 发布新版本（latest 指向新版本，其他别名不变）
+func (s *FunctionService) Publish(ctx context.Context, wsID, name, email string, req *model.PublishFunctionRequest, code string) (*model.FunctionVersion, error) {
+	fn, err := s.getFunction(ctx, wsID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.executor.PrepareCode(ctx, code); err != nil {
+		return nil, err
+	}
+
+	version := newFunctionVersion(code, req.Comment, email)
+	version.FunctionID = fn.ID
+	if err := s.repo.AddVersion(ctx, version); err != nil {
+		return nil, err
+	}
+	s.invalidate(wsID, name)
+
+	utils.Info("脚本新版本发布成功",
+		zap.String("ws_id", wsID),
+		zap.String("name", name),
+		zap.Int("version", version.Version),
+		zap.String("code_hash", version.CodeHash))
+	return version, nil
+}
+
+// Update 更新脚本说明
+func (s *FunctionService) Update(ctx context.Context, wsID, name string, req *model.UpdateFunctionRequest) (*model.FunctionDetail, error) {
+	fn, err := s.getFunction(ctx, wsID, name)
+	if err != nil {
+		return nil, err
+	}
+	if req.Description != nil {
+		if err := s.repo.UpdateDescription(ctx, fn.ID, *req.Description); err != nil {
+			return nil, err
+		}
+	}
+	return s.Get(ctx, wsID, name)
+}
+
+// Delete 删除脚本及其全部版本和别名
+func (s *FunctionService) Delete(ctx context.Context, wsID, name string) error {
+	fn, err := s.getFunction(ctx, wsID, name)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, fn.ID); err != nil {
+		return err
+	}
+	s.invalidate(wsID, name)
+
+	utils.Info("脚本已删除", zap.String("ws_id", wsID), zap.String("name", name))
+	return nil
+}
+
+// List 获取工作空间的全部脚本
+func (s *FunctionService) List(ctx context.Context, wsID string) ([]*model.StoredFunction, error) {
+	return s.repo.List(ctx, wsID)
+}
+
+// Get 获取脚本详情（别名 + 版本列表）
+func (s *FunctionService) Get(ctx context.Context, wsID, name string) (*model.FunctionDetail, error) {
+	fn, err := s.getFunction(ctx, wsID, name)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := s.repo.ListAliases(ctx, fn.ID)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.repo.ListVersions(ctx, fn.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.FunctionDetail{StoredFunction: fn, Aliases: aliases, Versions: versions}, nil
+}
+
+// GetVersion 获取指定版本（含代码），version 可以是版本号或别名
+func (s *FunctionService) GetVersion(ctx context.Context, wsID, name, version string) (*model.FunctionVersionDetail, error) {
+	fn, err := s.getFunction(ctx, wsID, name)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.loadVersion(ctx, fn, version)
+	if err != nil {
+		return nil, err
+	}
+	return &model.FunctionVersionDetail{
+		FunctionVersion: v,
+		Name:            fn.Name,
+		CodeBase64:      base64.StdEncoding.EncodeToString([]byte(v.Code)),
+	}, nil
+}
+
+// SetAlias 移动别名到指定版本（不存在时创建）
+func (s *FunctionService) SetAlias(ctx context.Context, wsID, name, alias string, version int) (*model.FunctionAlias, error) {
+	if !functionAliasPattern.MatchString(alias) {
+		return nil, functionValidationError("别名只能包含字母、数字、_ -（以字母开头，最长32字符）")
+	}
+	fn, err := s.getFunction(ctx, wsID, name)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.repo.GetVersion(ctx, fn.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, ErrFunctionVersionNotFound
+	}
+
+	if err := s.repo.SetAlias(ctx, fn.ID, alias, version); err != nil {
+		return nil, err
+	}
+	s.invalidate(wsID, name)
+
+	utils.Info("脚本别名已移动",
+		zap.String("ws_id", wsID),
+		zap.String("name", name),
+		zap.String("alias", alias),
+		zap.Int("version", version))
+	return s.repo.GetAlias(ctx, fn.ID, alias)
+}
+
+// DeleteAlias 删除别名（latest 不能删除）
+func (s *FunctionService) DeleteAlias(ctx context.Context, wsID, name, alias string) error {
+	if alias == model.FunctionAliasLatest {
+		return functionValidationError("latest 别名不能删除")
+	}
+	fn, err := s.getFunction(ctx, wsID, name)
+	if err != nil {
+		return err
+	}
+	found, err := s.repo.DeleteAlias(ctx, fn.ID, alias)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrFunctionVersionNotFound
+	}
+	s.invalidate(wsID, name)
+	return nil
+}
+
+// Rollback 回滚别名（默认 latest）到指定版本，未指定版本时回滚到当前版本之前的最近版本
+func (s *FunctionService) Rollback(ctx context.Context, wsID, name string, req *model.RollbackFunctionRequest) (*model.FunctionAlias, error) {
+	alias := req.Alias
+	if alias == "" {
+		alias = model.FunctionAliasLatest
+	}
+
+	target := 0
+	if req.Version != nil {
+		target = *req.Version
+	} else {
+		fn, err := s.getFunction(ctx, wsID, name)
+		if err != nil {
+			return nil, err
+		}
+		current, err := s.repo.GetAlias(ctx, fn.ID, alias)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, ErrFunctionVersionNotFound
+		}
+		if target, err = s.repo.GetPreviousVersion(ctx, fn.ID, current.Version); err != nil {
+			return nil, err
+		}
+		if target == 0 {
+			return nil, functionValidationError(fmt.Sprintf("别名 %s 当前指向版本 %d，没有更早的版本可回滚", alias, current.Version))
+		}
+	}
+
+	return s.SetAlias(ctx, wsID, name, alias, target)
+}
+
+// Resolve 解析调用的脚本版本（带本地缓存），version 可以是版本号或别名
+func (s *FunctionService) Resolve(ctx context.Context, wsID, name, version string) (*model.ResolvedFunction, error) {
+	key := functionCacheKey(wsID, name)
+	s.mu.RLock()
+	entry, found := s.cache[key][version]
+	s.mu.RUnlock()
+	if found && time.Now().Before(entry.expiresAt) {
+		return entry.resolved, nil
+	}
+
+	fn, err := s.getFunction(ctx, wsID, name)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.loadVersion(ctx, fn, version)
+	if err != nil {
+		return nil, err
+	}
+	resolved := &model.ResolvedFunction{FunctionID: fn.ID, Name: fn.Name, Version: v.Version, Code: v.Code}
+
+	s.mu.Lock()
+	if s.cache[key] == nil {
+		s.cache[key] = make(map[string]*functionCacheEntry)
+	}
+	s.cache[key][version] = &functionCacheEntry{resolved: resolved, expiresAt: time.Now().Add(functionCacheTTL)}
+	s.mu.Unlock()
+	return resolved, nil
+}
+
+// getFunction 获取脚本（不存在时返回 ErrFunctionNotFound）
+func (s *FunctionService) getFunction(ctx context.Context, wsID, name string) (*model.StoredFunction, error) {
+	fn, err := s.repo.GetByName(ctx, wsID, name)
+	if err != nil {
+		return nil, err
+	}
+	if fn == nil {
+		return nil, ErrFunctionNotFound
+	}
+	return fn, nil
+}
+
+// loadVersion 按版本号或别名加载版本（含代码）
+func (s *FunctionService) loadVersion(ctx context.Context, fn *model.StoredFunction, version string) (*model.FunctionVersion, error) {
+	number, err := strconv.Atoi(version)
+	if err != nil {
+		alias, err := s.repo.GetAlias(ctx, fn.ID, version)
+		if err != nil {
+			return nil, err
+		}
+		if alias == nil {
+			return nil, ErrFunctionVersionNotFound
+		}
+		number = alias.Version
+	}
+
+	v, err := s.repo.GetVersion(ctx, fn.ID, number)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, ErrFunctionVersionNotFound
+	}
+	return v, nil
+}
+
+// invalidate 使脚本的本地解析缓存失效
+func (s *FunctionService) invalidate(wsID, name string) {
+	s.mu.Lock()
+	delete(s.cache, functionCacheKey(wsID, name))
+	s.mu.Unlock()
+}
+
+func functionCacheKey(wsID, name string) string {
+	return wsID + "/" + name
+}
+
+// functionValidationError 参数校验失败（控制器返回 400，与发布时的代码校验错误格式一致）
+func functionValidationError(message string) *model.ExecutionError {
+	return &model.ExecutionError{Type: "ValidationError", Message: message}
+}
+
+// newFunctionVersion 构造待发布的版本记录
+func newFunctionVersion(code, comment, email string) *model.FunctionVersion {
+	return &model.FunctionVersion{
+		Code:       code,
+		CodeHash:   sandbox.HashCode(code),
+		CodeLength: len(code),
+		Comment:    comment,
+		CreatedBy:  email,
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+)
+
+// HistoryCleanupService 执行历史清理服务
+// 🆕 与 QuotaCleanupService 相同的方式：定时分批删除，删除量较大时更新表统计信息
+// 保留天数在写入时按 Token 策略计算为 expires_at，这里只删除已过期的记录
+type HistoryCleanupService struct {
+	repo              *repository.HistoryRepository
+	cleanupInterval   time.Duration // 清理间隔
+	batchSize         int           // 每批删除数量
+	stopChan          chan struct{}
+	wg                sync.WaitGroup
+	lastCleanupTime   time.Time
+	lastCleanupCount  int
+	totalCleanedCount int64
+	mu                sync.RWMutex
+}
+
+// NewHistoryCleanupService 创建执行历史清理服务
+func NewHistoryCleanupService(
+	repo *repository.HistoryRepository,
+	cleanupInterval time.Duration,
+	batchSize int,
+) *HistoryCleanupService {
+	if cleanupInterval <= 0 {
+		cleanupInterval = time.Hour // 默认每小时清理一次
+	}
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+
+	service := &HistoryCleanupService{
+		repo:            repo,
+		cleanupInterval: cleanupInterval,
+		batchSize:       batchSize,
+		stopChan:        make(chan struct{}),
+	}
+
+	// 启动后台清理协程
+	service.wg.Add(1)
+	go service.startCleanupWorker()
+
+	utils.Info("执行历史清理服务启动",
+		zap.Duration("cleanup_interval", cleanupInterval),
+		zap.Int("batch_size", batchSize))
+
+	return service
+}
+
+// Stop 停止清理服务
+func (s *HistoryCleanupService) Stop() {
+	close(s.stopChan)
+	s.wg.Wait()
+	utils.Info("执行历史清理服务已停止")
+}
+
+// startCleanupWorker 后台清理协程
+func (s *HistoryCleanupService) startCleanupWorker() {
+	defer s.wg.Done()
+
+	// 首次启动时延迟1分钟执行（避免启动时立即清理）
+	firstRunTimer := time.NewTimer(1 * time.Minute)
+	defer firstRunTimer.Stop()
+
+	ticker := time.NewTicker(s.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-firstRunTimer.C:
+			s.performCleanup()
+
+		case <-ticker.C:
+			s.performCleanup()
+
+		case <-s.stopChan:
+			utils.Info("执行历史清理协程收到停止信号")
+			return
+		}
+	}
+}
+
+// performCleanup 执行清理
+func (s *HistoryCleanupService) performCleanup() {
+	ctx := context.Background()
+	startTime := time.Now()
+
+	// 1. 查询待删除记录数
+	count, err := s.repo.CountExpired(ctx)
+	if err != nil {
+		utils.Error("查询过期执行历史数量失败", zap.Error(err))
+		return
+	}
+
+	if count == 0 {
+		utils.Debug("没有需要清理的执行历史")
+		s.updateStats(0)
+		return
+	}
+
+	utils.Info("开始清理过期执行历史",
+		zap.Int("count", count),
+		zap.Int("batch_size", s.batchSize))
+
+	// 2. 批量删除
+	totalDeleted := 0
+	for {
+		deleted, err := s.repo.DeleteExpiredBatch(ctx, s.batchSize)
+		if err != nil {
+			utils.Error("批量删除执行历史失败", zap.Error(err))
+			break
+		}
+
+		if deleted == 0 {
+			break
+		}
+
+		totalDeleted += deleted
+		utils.Debug("批量删除执行历史完成",
+			zap.Int("batch_deleted", deleted),
+			zap.Int("total_deleted", totalDeleted))
+
+		select {
+		case <-time.After(CleanupBatchDelay):
+			// 继续下一批
+		case <-s.stopChan:
+			utils.Info("执行历史清理过程中收到停止信号，已删除记录数",
+				zap.Int("total_deleted", totalDeleted))
+			s.updateStats(totalDeleted)
+			return
+		}
+	}
+
+	// 3. 删除量较大时更新表统计信息
+	if totalDeleted > 10000 {
+		analyzeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		if err := s.repo.Analyze(analyzeCtx); err != nil {
+			utils.Warn("执行历史表分析失败", zap.Error(err))
+		}
+		cancel()
+	}
+
+	// 4. 更新统计
+	s.updateStats(totalDeleted)
+
+	utils.Info("执行历史清理完成",
+		zap.Int("deleted_count", totalDeleted),
+		zap.Duration("duration", time.Since(startTime)))
+}
+
+// updateStats 更新统计信息
+func (s *HistoryCleanupService) updateStats(deletedCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastCleanupTime = time.Now()
+	s.lastCleanupCount = deletedCount
+	s.totalCleanedCount += int64(deletedCount)
+}
+
+// GetStats 获取清理统计信息
+func (s *HistoryCleanupService) GetStats() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return map[string]interface{}{
+		"cleanup_interval":    s.cleanupInterval.String(),
+		"batch_size":          s.batchSize,
+		"last_cleanup_time":   s.lastCleanupTime.Format("2006-01-02 15:04:05"),
+		"last_cleanup_count":  s.lastCleanupCount,
+		"total_cleaned_count": s.totalCleanedCount,
+		"next_cleanup_time":   s.lastCleanupTime.Add(s.cleanupInterval).Format("2006-01-02 15:04:05"),
+	}
+}
+
+// TriggerCleanup 手动触发清理（用于API调用）
+func (s *HistoryCleanupService) TriggerCleanup() {
+	go s.performCleanup()
+}
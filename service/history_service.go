@@ -0,0 +1,385 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+)
+
+// historyRedacted 脱敏后的替换内容
+const historyRedacted = "[REDACTED]"
+
+// ErrHistoryNotFound 执行历史不存在（或不属于当前 Token）
+var ErrHistoryNotFound = errors.New("执行历史不存在")
+
+// HistoryEntry 一次执行需要保存的内容（HistoryService.Record 的参数）
+type HistoryEntry struct {
+	RequestID       string
+	Token           string
+	WsID            string
+	Email           string
+	Code            string
+	Input           map[string]interface{}
+	Result          interface{} // 成功时的结果（ResultJSON 为空时使用）
+	ResultJSON      []byte      // 成功时预序列化的结果
+	ErrorType       string      // 失败时的错误类型
+	ErrorMessage    string      // 失败时的错误信息
+	Logs            []model.ConsoleLogEntry
+	LogsTruncated   bool // 执行时 console 输出已被截断
+	ExecutionTimeMs int64
+}
+
+// historySettings 单个 Token 的生效设置（沙箱策略 history 覆盖 HISTORY_* 全局配置）
+type historySettings struct {
+	retentionDays int
+	maxFieldBytes int
+	redactKeys    map[string]struct{} // 小写
+	patterns      []*regexp.Regexp
+}
+
+// historyWrite 写入队列中的一条执行历史
+type historyWrite struct {
+	entry    *HistoryEntry
+	settings *historySettings
+}
+
+// HistoryService 执行历史服务
+// 🆕 按 Token 开启（沙箱策略 history.enabled），异步写入，不阻塞执行响应
+// 🔥 固定数量的 worker 从有界队列写入：数据库变慢时队列满后直接丢弃（记录日志），不会堆积 goroutine
+//
+// 保存前的处理：
+//   - input / result 中字段名匹配脱敏字段（不区分大小写）的值替换为 [REDACTED]
+//   - 所有字符串值、console 输出和错误信息中匹配脱敏正则的内容替换为 [REDACTED]
+//   - 每个字段超过大小上限时截断（console 输出按条丢弃）并标记 *_truncated
+type HistoryService struct {
+	repo *repository.HistoryRepository
+	cfg  config.HistoryConfig
+
+	patterns sync.Map // 脱敏正则缓存：pattern -> *regexp.Regexp
+
+	queue    chan *historyWrite
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+	dropped  int64 // 队列满丢弃的记录数
+}
+
+// NewHistoryService 创建执行历史服务
+func NewHistoryService(repo *repository.HistoryRepository, cfg config.HistoryConfig) *HistoryService {
+	utils.Info("执行历史服务初始化",
+		zap.Bool("enabled", cfg.Enabled),
+		zap.Int("retention_days", cfg.RetentionDays),
+		zap.Int("max_field_bytes", cfg.MaxFieldBytes),
+		zap.Int("redact_keys", len(cfg.RedactKeys)),
+		zap.Int("write_workers", cfg.WriteWorkers),
+		zap.Int("write_queue_size", cfg.WriteQueueSize))
+
+	s := &HistoryService{
+		repo:  repo,
+		cfg:   cfg,
+		queue: make(chan *historyWrite, cfg.WriteQueueSize),
+		stop:  make(chan struct{}),
+	}
+	for i := 0; i < cfg.WriteWorkers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// ShouldRecord Token 的策略是否开启了执行历史（HISTORY_ENABLED=false 时全部关闭）
+func (s *HistoryService) ShouldRecord(policy *model.SandboxPolicy) bool {
+	return s != nil && s.cfg.Enabled && policy.HistoryEnabled()
+}
+
+// Record 异步保存一次执行（Token 未开启执行历史时为空操作）
+// 写入队列已满或服务已关闭时丢弃这条记录，不阻塞调用方
+func (s *HistoryService) Record(policy *model.SandboxPolicy, entry *HistoryEntry) {
+	if !s.ShouldRecord(policy) {
+		return
+	}
+	write := &historyWrite{entry: entry, settings: s.settingsFor(policy.History)}
+
+	select {
+	case <-s.stop:
+		return
+	default:
+	}
+
+	select {
+	case s.queue <- write:
+	default:
+		dropped := atomic.AddInt64(&s.dropped, 1)
+		utils.Warn("执行历史写入队列已满，丢弃记录",
+			zap.String("request_id", entry.RequestID),
+			zap.Int("queue_size", s.cfg.WriteQueueSize),
+			zap.Int64("dropped", dropped))
+	}
+}
+
+// worker 写入协程（关闭时写完队列中剩余的记录）
+func (s *HistoryService) worker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case write := <-s.queue:
+			s.insert(write)
+		case <-s.stop:
+			for {
+				select {
+				case write := <-s.queue:
+					s.insert(write)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// insert 写入一条执行历史
+func (s *HistoryService) insert(write *historyWrite) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.Insert(ctx, s.buildRecord(write.entry, write.settings)); err != nil {
+		utils.Error("记录执行历史失败",
+			zap.String("request_id", write.entry.RequestID),
+			zap.Error(err))
+	}
+}
+
+// Shutdown 停止接收新记录，等待队列中的记录写完（最多 timeout）
+func (s *HistoryService) Shutdown(timeout time.Duration) {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+
+		done := make(chan struct{})
+		go func() {
+			s.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			utils.Info("执行历史写入队列已停止", zap.Int64("dropped", atomic.LoadInt64(&s.dropped)))
+		case <-time.After(timeout):
+			utils.Warn("执行历史写入队列关闭超时",
+				zap.Duration("timeout", timeout),
+				zap.Int("remaining", len(s.queue)))
+		}
+	})
+}
+
+// Query 分页查询执行历史（Token 已脱敏）
+func (s *HistoryService) Query(ctx context.Context, req *model.HistoryQueryRequest) ([]*model.ExecutionHistory, int, error) {
+	records, total, err := s.repo.Query(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, record := range records {
+		record.Token = utils.MaskToken(record.Token)
+	}
+	return records, total, nil
+}
+
+// Get 获取执行历史详情
+// token 不为空时按 token + request_id 查询（Token 接口），其他 Token 使用相同 request_id 的记录不可见
+func (s *HistoryService) Get(ctx context.Context, requestID, token string) (*model.ExecutionHistoryDetail, error) {
+	record, err := s.repo.GetByRequestID(ctx, token, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrHistoryNotFound
+	}
+	record.Token = utils.MaskToken(record.Token)
+	return model.NewExecutionHistoryDetail(record), nil
+}
+
+// settingsFor 合并 Token 策略和全局配置
+func (s *HistoryService) settingsFor(policy *model.HistoryPolicy) *historySettings {
+	settings := &historySettings{
+		retentionDays: s.cfg.RetentionDays,
+		maxFieldBytes: s.cfg.MaxFieldBytes,
+		redactKeys:    make(map[string]struct{}, len(s.cfg.RedactKeys)+len(policy.RedactKeys)),
+	}
+	if policy.RetentionDays != nil {
+		settings.retentionDays = *policy.RetentionDays
+	}
+	if policy.MaxFieldBytes != nil {
+		settings.maxFieldBytes = *policy.MaxFieldBytes
+	}
+	for _, key := range s.cfg.RedactKeys {
+		settings.redactKeys[strings.ToLower(key)] = struct{}{}
+	}
+	for _, key := range policy.RedactKeys {
+		settings.redactKeys[strings.ToLower(strings.TrimSpace(key))] = struct{}{}
+	}
+	for _, pattern := range policy.RedactPatterns {
+		if re := s.compilePattern(pattern); re != nil {
+			settings.patterns = append(settings.patterns, re)
+		}
+	}
+	return settings
+}
+
+// compilePattern 编译脱敏正则（带缓存；策略保存时已校验，这里的失败只记录日志）
+func (s *HistoryService) compilePattern(pattern string) *regexp.Regexp {
+	if cached, ok := s.patterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		utils.Warn("执行历史脱敏正则无效，已忽略", zap.String("pattern", pattern), zap.Error(err))
+		return nil
+	}
+	s.patterns.Store(pattern, re)
+	return re
+}
+
+// buildRecord 脱敏、截断并转换为数据库记录
+func (s *HistoryService) buildRecord(entry *HistoryEntry, settings *historySettings) *model.ExecutionHistory {
+	now := time.Now()
+	record := &model.ExecutionHistory{
+		RequestID:       entry.RequestID,
+		Token:           entry.Token,
+		WsID:            entry.WsID,
+		Email:           entry.Email,
+		CodeHash:        sandbox.HashCode(entry.Code),
+		CodeLength:      len(entry.Code),
+		Status:          model.HistoryStatusSuccess,
+		ExecutionTimeMs: entry.ExecutionTimeMs,
+		ExpiresAt:       model.ShanghaiTime{Time: now.AddDate(0, 0, settings.retentionDays)},
+	}
+
+	if entry.ErrorType != "" {
+		record.Status = model.HistoryStatusFailed
+		errorType := entry.ErrorType
+		errorMessage, _ := truncateUTF8(settings.redactString(entry.ErrorMessage), settings.maxFieldBytes)
+		record.ErrorType = &errorType
+		record.ErrorMessage = &errorMessage
+	}
+
+	if entry.Input != nil {
+		record.Input, record.InputTruncated = settings.encodePayload(entry.Input)
+	}
+
+	if record.Status == model.HistoryStatusSuccess {
+		// 统一转换为 JSON 通用结构再脱敏（导出结果可能包含具体类型的 map / slice）
+		data := entry.ResultJSON
+		if len(data) == 0 {
+			data, _ = json.Marshal(entry.Result)
+		}
+		var result interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber() // 保持数字原样（大整数不丢精度）
+		if err := decoder.Decode(&result); err != nil {
+			result = string(data)
+		}
+		record.Result, record.ResultTruncated = settings.encodePayload(result)
+	}
+
+	if len(entry.Logs) > 0 {
+		record.Logs, record.LogsTruncated = settings.encodeLogs(entry.Logs)
+	}
+	record.LogsTruncated = record.LogsTruncated || entry.LogsTruncated
+
+	return record
+}
+
+// encodePayload 脱敏后序列化为 JSON，超过大小上限时截断
+func (h *historySettings) encodePayload(value interface{}) (*string, bool) {
+	data, err := json.Marshal(h.redactValue(value))
+	if err != nil {
+		data = []byte(fmt.Sprintf("%q", fmt.Sprintf("<无法序列化: %v>", err)))
+	}
+	text, truncated := truncateUTF8(string(data), h.maxFieldBytes)
+	return &text, truncated
+}
+
+// encodeLogs 脱敏后序列化 console 输出，超过大小上限时丢弃后面的条目
+func (h *historySettings) encodeLogs(logs []model.ConsoleLogEntry) (*string, bool) {
+	kept := make([]model.ConsoleLogEntry, 0, len(logs))
+	size := 2 // []
+	truncated := false
+	for _, entry := range logs {
+		entry.Message = h.redactString(entry.Message)
+		data, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		if size+len(data)+1 > h.maxFieldBytes {
+			truncated = true
+			break
+		}
+		size += len(data) + 1
+		kept = append(kept, entry)
+	}
+
+	data, _ := json.Marshal(kept)
+	text := string(data)
+	return &text, truncated
+}
+
+// redactValue 递归脱敏（返回新值，不修改原始数据）
+func (h *historySettings) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if _, ok := h.redactKeys[strings.ToLower(key)]; ok {
+				out[key] = historyRedacted
+				continue
+			}
+			out[key] = h.redactValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = h.redactValue(item)
+		}
+		return out
+	case string:
+		return h.redactString(v)
+	default:
+		return v
+	}
+}
+
+// redactString 替换匹配脱敏正则的内容
+func (h *historySettings) redactString(s string) string {
+	for _, re := range h.patterns {
+		s = re.ReplaceAllString(s, historyRedacted)
+	}
+	return s
+}
+
+// truncateUTF8 按字节数截断（不截断半个 UTF-8 字符）
+func truncateUTF8(s string, maxBytes int) (string, bool) {
+	if len(s) <= maxBytes {
+		return s, false
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
+}
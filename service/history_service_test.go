@@ -0,0 +1,29 @@
+package service
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/model"
+)
+
+func TestHistoryRecordDropsWhenQueueFull(t *testing.T) {
+	// 没有 worker：队列只能放一条记录
+	s := NewHistoryService(nil, config.HistoryConfig{Enabled: true, RetentionDays: 1, MaxFieldBytes: 1024, WriteQueueSize: 1})
+	enabled := true
+	policy := &model.SandboxPolicy{History: &model.HistoryPolicy{Enabled: &enabled}}
+
+	for i := 0; i < 3; i++ {
+		s.Record(policy, &HistoryEntry{RequestID: "req"})
+	}
+	if len(s.queue) != 1 || atomic.LoadInt64(&s.dropped) != 2 {
+		t.Fatalf("queue = %d, dropped = %d, want 1 / 2", len(s.queue), s.dropped)
+	}
+
+	// 未开启执行历史的 Token 不入队
+	s.Record(&model.SandboxPolicy{}, &HistoryEntry{RequestID: "other"})
+	if atomic.LoadInt64(&s.dropped) != 2 {
+		t.Fatal("未开启执行历史时不应尝试写入")
+	}
+}
@@ -0,0 +1,695 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/enhance_modules"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/utils"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// jobTask 队列中的异步任务（仅存在于提交实例的内存中）
+type jobTask struct {
+	jobID       string
+	token       string // 用于回调签名（不落盘）
+	wsID        string
+	email       string
+	requestID   string
+	code        string
+	input       map[string]interface{}
+	callbackURL string
+	createdAt   time.Time            // 提交时间（用于计算含排队的总耗时）
+	policy      *model.SandboxPolicy // 🆕 提交时解析的沙箱策略（nil 表示使用全局默认值）
+	traceParent trace.SpanContext    // 🆕 提交请求的 span（执行 span 挂在其下，链路跨越排队）
+}
+
+// jobRecord Redis 中保存的任务记录
+type jobRecord struct {
+	Info      *model.JobInfo `json:"info"`
+	OwnerHash string         `json:"owner_hash"` // Token 的 SHA256（仅提交者可查询）
+}
+
+// jobStats 异步任务统计
+type jobStats struct {
+	submitted         int64
+	rejected          int64
+	succeeded         int64
+	failed            int64
+	callbackDelivered int64
+	callbackFailed    int64
+}
+
+// JobService 异步任务服务
+// 特点：
+//  1. 有界 worker 池：固定数量的 worker 复用 JSExecutor.Execute 执行任务
+//  2. 快速拒绝：队列满时立即返回 ErrJobQueueFull（不阻塞调用方）
+//  3. 状态共享：任务状态和结果保存在 Redis（带 TTL），任意实例均可查询
+//  4. 完成回调：可选 callback_url，以 HMAC-SHA256 签名 POST 最终 ExecuteResponse
+type JobService struct {
+	redisClient    *redis.Client
+	executor       *sandbox.JSExecutor
+	statsService   *StatsService
+	historyService *HistoryService // 🆕 执行历史（Token 策略开启时记录）
+	enabled        bool
+
+	tasks     chan *jobTask
+	slots     chan struct{} // 队列位置（Reserve 占用，worker 取出任务后释放；入队因此不会失败）
+	workers   int
+	queueSize int
+	resultTTL time.Duration
+
+	callbackClient     *http.Client
+	callbackMaxRetries int
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	// 🔥 Enqueue 与 Shutdown 互斥：关闭开始后入队的任务直接标记失败，不会滞留在队列中
+	mu     sync.Mutex
+	closed bool
+
+	stats jobStats
+}
+
+// NewJobService 创建异步任务服务
+// 🔥 依赖 Redis 保存任务状态，Redis 不可用时服务禁用
+func NewJobService(redisClient *redis.Client, executor *sandbox.JSExecutor, statsService *StatsService, historyService *HistoryService, cfg *config.Config) *JobService {
+	if redisClient == nil {
+		utils.Warn("Redis未配置，异步任务服务无法启用")
+		return &JobService{enabled: false}
+	}
+
+	workers := cfg.Job.Workers
+	if workers <= 0 {
+		workers = 10
+	}
+	queueSize := cfg.Job.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+
+	// 🛡️ 回调请求复用 SSRF 防护（与 fetch 一致），且不跟随重定向
+	transport := &http.Transport{
+		DialContext: enhance_modules.CreateProtectedDialContext(
+			&enhance_modules.SSRFProtectionConfig{
+				Enabled:        cfg.Fetch.EnableSSRFProtection,
+				AllowPrivateIP: cfg.Fetch.AllowPrivateIP,
+			},
+			cfg.Fetch.HTTPDialTimeout,
+			cfg.Fetch.HTTPKeepAlive,
+		),
+		TLSHandshakeTimeout: cfg.Fetch.HTTPTLSHandshakeTimeout,
+		IdleConnTimeout:     cfg.Fetch.HTTPIdleConnTimeout,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &JobService{
+		redisClient:    redisClient,
+		executor:       executor,
+		statsService:   statsService,
+		historyService: historyService,
+		enabled:        true,
+		tasks:          make(chan *jobTask, queueSize),
+		slots:          make(chan struct{}, queueSize),
+		workers:        workers,
+		queueSize:      queueSize,
+		resultTTL:      cfg.Job.ResultTTL,
+		callbackClient: &http.Client{
+			Timeout:   cfg.Job.CallbackTimeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		callbackMaxRetries: cfg.Job.CallbackMaxRetries,
+		ctx:                ctx,
+		cancel:             cancel,
+		stop:               make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.worker(i)
+	}
+
+	utils.Info("异步任务服务已启动",
+		zap.Int("workers", workers),
+		zap.Int("queue_size", queueSize),
+		zap.Duration("result_ttl", cfg.Job.ResultTTL),
+	)
+
+	return s
+}
+
+// IsEnabled 检查服务是否启用
+func (s *JobService) IsEnabled() bool {
+	return s.enabled
+}
+
+// JobReservation 已预留队列位置的任务（Reserve 返回；Enqueue 入队或 Release 放弃）
+type JobReservation struct {
+	info      *model.JobInfo
+	token     string
+	createdAt time.Time
+	done      bool
+}
+
+// Reserve 校验参数、预留队列位置并写入任务状态（提交时先预留，扣减配额后再入队）
+// 🔥 callback_url 无效、服务关闭或队列已满都在这里返回，预留成功后 Enqueue 不会失败
+func (s *JobService) Reserve(ctx context.Context, token, requestID, callbackURL string) (*JobReservation, error) {
+	if !s.enabled {
+		return nil, ErrJobServiceDisabled
+	}
+
+	select {
+	case <-s.stop:
+		return nil, ErrJobServiceDisabled
+	default:
+	}
+
+	if callbackURL != "" {
+		if err := validateCallbackURL(callbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	// 🔥 队列已满：快速拒绝
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		atomic.AddInt64(&s.stats.rejected, 1)
+		return nil, ErrJobQueueFull
+	}
+
+	createdAt := utils.Now()
+	info := &model.JobInfo{
+		JobID:       uuid.New().String(),
+		Status:      model.JobStatusQueued,
+		RequestID:   requestID,
+		CallbackURL: callbackURL,
+		CreatedAt:   utils.FormatTime(createdAt),
+	}
+	if callbackURL != "" {
+		info.CallbackStatus = model.JobCallbackPending
+	}
+
+	// 任务状态先写入 Redis 再入队，保证返回 job_id 后立即可查询
+	if err := s.save(ctx, info, hashOwnerToken(token)); err != nil {
+		<-s.slots
+		return nil, err
+	}
+
+	return &JobReservation{info: info, token: token, createdAt: createdAt}, nil
+}
+
+// Enqueue 把已预留位置的任务放入队列
+// 🔥 Reserve 之后服务开始关闭时，任务不再入队，直接标记为失败（ServiceUnavailableError）并返回失败状态
+func (s *JobService) Enqueue(ctx context.Context, r *JobReservation, wsID, email, code string, input map[string]interface{}) *model.JobInfo {
+	r.done = true
+
+	task := &jobTask{
+		jobID:       r.info.JobID,
+		token:       r.token,
+		wsID:        wsID,
+		email:       email,
+		requestID:   r.info.RequestID,
+		code:        code,
+		input:       input,
+		callbackURL: r.info.CallbackURL,
+		createdAt:   r.createdAt,
+		policy:      sandbox.SandboxPolicyFromContext(ctx), // 🆕 执行时 context 已与请求无关，随任务保存
+		traceParent: trace.SpanContextFromContext(ctx),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		<-s.slots
+		utils.Warn("异步任务服务正在关闭，任务未入队",
+			zap.String("job_id", r.info.JobID),
+			zap.String("request_id", r.info.RequestID))
+		return s.abandon(ctx, task)
+	}
+	// 已占用队列位置，写入不会阻塞
+	s.tasks <- task
+	s.mu.Unlock()
+
+	atomic.AddInt64(&s.stats.submitted, 1)
+	utils.Info("异步任务已提交",
+		zap.String("job_id", r.info.JobID),
+		zap.String("request_id", r.info.RequestID),
+		zap.String("ws_id", wsID),
+		zap.Bool("has_callback", r.info.CallbackURL != ""))
+	return r.info
+}
+
+// Release 放弃预留（如配额不足）：释放队列位置并删除已写入的状态
+func (s *JobService) Release(ctx context.Context, r *JobReservation) {
+	if r.done {
+		return
+	}
+	r.done = true
+	<-s.slots
+	s.redisClient.Del(ctx, jobRedisKey(r.info.JobID))
+}
+
+// Get 查询任务状态（仅提交者可查询，其他 Token 视为不存在）
+func (s *JobService) Get(ctx context.Context, jobID, token string) (*model.JobInfo, error) {
+	if !s.enabled {
+		return nil, ErrJobServiceDisabled
+	}
+
+	record, err := s.load(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(record.OwnerHash), []byte(hashOwnerToken(token))) {
+		return nil, ErrJobNotFound
+	}
+
+	return record.Info, nil
+}
+
+// worker 工作协程
+func (s *JobService) worker(id int) {
+	defer s.wg.Done()
+
+	utils.Debug("异步任务 worker 已启动", zap.Int("worker_id", id))
+
+	for {
+		// 优先响应停止信号，剩余任务由 Shutdown 统一处理
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		select {
+		case task := <-s.tasks:
+			<-s.slots
+			s.runJob(task)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// runJob 执行单个任务
+func (s *JobService) runJob(task *jobTask) {
+	record, err := s.load(s.ctx, task.jobID)
+	if err != nil {
+		// 状态丢失（如 Redis 被清空或 TTL 过期），仍然执行，重新建立记录
+		utils.Warn("异步任务状态读取失败，重建记录",
+			zap.String("job_id", task.jobID),
+			zap.Error(err))
+		record = &jobRecord{
+			Info: &model.JobInfo{
+				JobID:       task.jobID,
+				RequestID:   task.requestID,
+				CallbackURL: task.callbackURL,
+				CreatedAt:   utils.FormatTime(task.createdAt),
+			},
+			OwnerHash: hashOwnerToken(task.token),
+		}
+	}
+	info := record.Info
+
+	// 1. 标记为执行中
+	info.Status = model.JobStatusRunning
+	info.StartedAt = utils.FormatTime(utils.Now())
+	if err := s.save(s.ctx, info, record.OwnerHash); err != nil {
+		utils.Warn("异步任务状态更新失败", zap.String("job_id", task.jobID), zap.Error(err))
+	}
+
+	// 2. 执行（复用 JSExecutor.Execute：熔断器、并发控制、智能路由）
+	startTime := time.Now()
+	execCtx := sandbox.WithSandboxPolicy(context.WithValue(s.ctx, utils.RequestIDKey, task.requestID), task.policy)
+	execCtx = sandbox.WithExecutionTenant(execCtx, task.token, task.wsID) // 🆕 与同步请求共用公平调度配额
+	execCtx, span := utils.StartSpan(trace.ContextWithRemoteSpanContext(execCtx, task.traceParent), "job.run",
+		attribute.String("job_id", task.jobID))
+	defer span.End()
+	result, execErr := s.executor.Execute(execCtx, task.code, task.input)
+	executionTime := time.Since(startTime).Milliseconds()
+
+	info.Timing = &model.ExecuteTiming{
+		ExecutionTime: executionTime,
+		TotalTime:     time.Since(task.createdAt).Milliseconds(), // 包含排队时间
+	}
+
+	status := "success"
+	if execErr != nil {
+		status = "failed"
+		info.Status = model.JobStatusFailed
+		info.Error = &model.ExecuteError{Type: "RuntimeError", Message: execErr.Error()}
+		if e, ok := execErr.(*model.ExecutionError); ok {
+			info.Error = &model.ExecuteError{Type: e.Type, Message: e.Message, Stack: e.Stack}
+			info.Logs, info.LogsTruncated = e.Logs, e.LogsTruncated
+		}
+		atomic.AddInt64(&s.stats.failed, 1)
+	} else {
+		info.Status = model.JobStatusSucceeded
+		info.Logs, info.LogsTruncated = result.Logs, result.LogsTruncated
+		if len(result.JSONData) > 0 {
+			info.Result = result.JSONData
+		} else if data, err := json.Marshal(result.Result); err == nil {
+			info.Result = data
+		}
+		atomic.AddInt64(&s.stats.succeeded, 1)
+	}
+	info.FinishedAt = utils.FormatTime(utils.Now())
+
+	if err := s.save(s.ctx, info, record.OwnerHash); err != nil {
+		utils.Error("异步任务结果保存失败", zap.String("job_id", task.jobID), zap.Error(err))
+	}
+
+	utils.Info("异步任务执行完成",
+		zap.String("job_id", task.jobID),
+		zap.String("request_id", task.requestID),
+		zap.String("status", info.Status),
+		zap.Int64("execution_time_ms", executionTime))
+
+	// 3. 记录统计数据（与同步接口一致）
+	if s.statsService != nil {
+		moduleInfo := utils.ParseModuleUsage(task.code)
+		s.statsService.RecordExecutionStats(&model.ExecutionStatsRecord{
+			ExecutionID:     task.requestID,
+			Token:           task.token,
+			WsID:            task.wsID,
+			Email:           task.email,
+			HasRequire:      moduleInfo.HasRequire,
+			ModulesUsed:     moduleInfo.GetModuleList(),
+			ModuleCount:     moduleInfo.ModuleCount,
+			ExecutionStatus: status,
+			ExecutionTimeMs: executionTime,
+			CodeLength:      len(task.code),
+			IsAsync:         s.executor.GetAnalyzer().AnalyzeCode(task.code).IsAsync,
+			ExecutionDate:   time.Now().Format("2006-01-02"),
+			ExecutionTime:   time.Now(),
+		})
+	}
+
+	// 4. 记录执行历史（Token 策略开启时，异步；request_id 为提交请求的 ID）
+	entry := &HistoryEntry{
+		RequestID:       task.requestID,
+		Token:           task.token,
+		WsID:            task.wsID,
+		Email:           task.email,
+		Code:            task.code,
+		Input:           task.input,
+		Logs:            info.Logs,
+		LogsTruncated:   info.LogsTruncated,
+		ExecutionTimeMs: executionTime,
+	}
+	if info.Error != nil {
+		entry.ErrorType, entry.ErrorMessage = info.Error.Type, info.Error.Message
+	} else {
+		entry.ResultJSON = info.Result
+	}
+	s.historyService.Record(task.policy, entry)
+
+	// 5. 完成回调
+	if task.callbackURL != "" {
+		s.deliverCallback(task, info)
+		if err := s.save(s.ctx, info, record.OwnerHash); err != nil {
+			utils.Warn("异步任务回调状态保存失败", zap.String("job_id", task.jobID), zap.Error(err))
+		}
+	}
+}
+
+// deliverCallback 投递完成回调（失败时指数退避重试）
+// 🔒 签名方式：
+//
+//	X-Flow-Timestamp: Unix 秒级时间戳
+//	X-Flow-Signature: sha256=hex(HMAC-SHA256(accessToken, timestamp + "." + body))
+//
+// 接收方使用自己的 accessToken 即可验签，无需额外分发密钥
+func (s *JobService) deliverCallback(task *jobTask, info *model.JobInfo) {
+	body, err := json.Marshal(info.ToExecuteResponse())
+	if err != nil {
+		utils.Error("回调数据序列化失败", zap.String("job_id", task.jobID), zap.Error(err))
+		info.CallbackStatus = model.JobCallbackFailed
+		return
+	}
+
+	maxAttempts := s.callbackMaxRetries + 1
+	backoff := time.Second
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		info.CallbackAttempts = attempt
+
+		err = s.postCallback(task, body)
+		if err == nil {
+			info.CallbackStatus = model.JobCallbackDelivered
+			atomic.AddInt64(&s.stats.callbackDelivered, 1)
+			utils.Debug("异步任务回调投递成功",
+				zap.String("job_id", task.jobID),
+				zap.Int("attempt", attempt))
+			return
+		}
+
+		utils.Warn("异步任务回调投递失败",
+			zap.String("job_id", task.jobID),
+			zap.String("request_id", task.requestID),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", maxAttempts),
+			zap.Error(err))
+
+		if attempt == maxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-s.ctx.Done():
+			attempt = maxAttempts
+		}
+	}
+
+	info.CallbackStatus = model.JobCallbackFailed
+	atomic.AddInt64(&s.stats.callbackFailed, 1)
+}
+
+// postCallback 发送一次回调请求（2xx 视为成功）
+func (s *JobService) postCallback(task *jobTask, body []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, task.callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "flow-codeblock-go/job-callback")
+	req.Header.Set("X-Flow-Job-ID", task.jobID)
+	req.Header.Set("X-Request-ID", task.requestID)
+	req.Header.Set("X-Flow-Timestamp", timestamp)
+	req.Header.Set("X-Flow-Signature", "sha256="+SignJobCallback(task.token, timestamp, body))
+
+	resp, err := s.callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("回调返回非 2xx 状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignJobCallback 计算回调签名：hex(HMAC-SHA256(key, timestamp + "." + body))
+func SignJobCallback(key, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// save 保存任务记录到 Redis（每次更新刷新 TTL，保证结果在完成后保留完整的 TTL）
+func (s *JobService) save(ctx context.Context, info *model.JobInfo, ownerHash string) error {
+	data, err := json.Marshal(&jobRecord{Info: info, OwnerHash: ownerHash})
+	if err != nil {
+		return fmt.Errorf("序列化任务记录失败: %w", err)
+	}
+	if err := s.redisClient.Set(ctx, jobRedisKey(info.JobID), data, s.resultTTL).Err(); err != nil {
+		return fmt.Errorf("保存任务记录失败: %w", err)
+	}
+	return nil
+}
+
+// load 从 Redis 读取任务记录
+func (s *JobService) load(ctx context.Context, jobID string) (*jobRecord, error) {
+	data, err := s.redisClient.Get(ctx, jobRedisKey(jobID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取任务记录失败: %w", err)
+	}
+
+	var record jobRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Info == nil {
+		return nil, fmt.Errorf("任务记录格式错误: %v", err)
+	}
+	return &record, nil
+}
+
+// Shutdown 优雅关闭
+// 1. 停止接收新任务
+// 2. 等待执行中的任务完成（或超时后取消）
+// 3. 队列中尚未开始的任务标记为失败（ServiceUnavailableError）
+func (s *JobService) Shutdown(timeout time.Duration) {
+	if !s.enabled {
+		return
+	}
+
+	s.stopOnce.Do(func() {
+		utils.Info("开始关闭异步任务服务",
+			zap.Int("workers", s.workers),
+			zap.Int("pending_jobs", len(s.tasks)))
+
+		// 先标记关闭（之后的 Enqueue 不再入队），此前入队的任务由下面统一处理
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		close(s.stop)
+
+		done := make(chan struct{})
+		go func() {
+			s.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			utils.Warn("异步任务服务关闭超时，取消执行中的任务", zap.Duration("timeout", timeout))
+			s.cancel()
+			<-done
+		}
+
+		// 队列中剩余的任务：标记为失败，避免调用方无限轮询
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		abandoned := 0
+		for {
+			select {
+			case task := <-s.tasks:
+				<-s.slots
+				abandoned++
+				s.abandon(ctx, task)
+				continue
+			default:
+			}
+			break
+		}
+
+		s.cancel()
+		utils.Info("异步任务服务已停止",
+			zap.Int("abandoned_jobs", abandoned),
+			zap.Int64("total_submitted", atomic.LoadInt64(&s.stats.submitted)),
+			zap.Int64("total_succeeded", atomic.LoadInt64(&s.stats.succeeded)),
+			zap.Int64("total_failed", atomic.LoadInt64(&s.stats.failed)))
+	})
+}
+
+// abandon 服务关闭时未执行的任务：标记为失败，避免调用方无限轮询
+func (s *JobService) abandon(ctx context.Context, task *jobTask) *model.JobInfo {
+	info := &model.JobInfo{
+		JobID:       task.jobID,
+		Status:      model.JobStatusFailed,
+		RequestID:   task.requestID,
+		CallbackURL: task.callbackURL,
+		Error: &model.ExecuteError{
+			Type:    "ServiceUnavailableError",
+			Message: "服务正在关闭，任务未执行，请重新提交",
+		},
+		CreatedAt:  utils.FormatTime(task.createdAt),
+		FinishedAt: utils.FormatTime(utils.Now()),
+	}
+	_ = s.save(ctx, info, hashOwnerToken(task.token))
+	return info
+}
+
+// GetStats 获取统计信息
+func (s *JobService) GetStats() map[string]interface{} {
+	if !s.enabled {
+		return map[string]interface{}{"enabled": false}
+	}
+	return map[string]interface{}{
+		"enabled":            true,
+		"workers":            s.workers,
+		"queue_size":         s.queueSize,
+		"queue_used":         len(s.tasks),
+		"total_submitted":    atomic.LoadInt64(&s.stats.submitted),
+		"total_rejected":     atomic.LoadInt64(&s.stats.rejected),
+		"total_succeeded":    atomic.LoadInt64(&s.stats.succeeded),
+		"total_failed":       atomic.LoadInt64(&s.stats.failed),
+		"callback_delivered": atomic.LoadInt64(&s.stats.callbackDelivered),
+		"callback_failed":    atomic.LoadInt64(&s.stats.callbackFailed),
+	}
+}
+
+// validateCallbackURL 校验回调地址（仅允许 http/https 绝对地址）
+func validateCallbackURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ErrInvalidCallbackURL
+	}
+	return nil
+}
+
+// jobRedisKey 任务记录的 Redis Key
+func jobRedisKey(jobID string) string {
+	return fmt.Sprintf("flow_job:%s", jobID)
+}
+
+// hashOwnerToken 计算 Token 的 SHA256（Redis 中不保存明文 Token）
+func hashOwnerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// 错误定义
+var (
+	ErrJobServiceDisabled = &JobError{Message: "异步任务服务不可用（需要启用 Redis）"}
+	ErrJobQueueFull       = &JobError{Message: "任务队列已满，请稍后重试"}
+	ErrJobNotFound        = &JobError{Message: "任务不存在或已过期"}
+	ErrInvalidCallbackURL = &JobError{Message: "callback_url 必须是 http/https 绝对地址"}
+)
+
+// JobError 异步任务错误
+type JobError struct {
+	Message string
+}
+
+func (e *JobError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"flow-codeblock-go/model"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestJobEnqueueAfterShutdownFailsJob(t *testing.T) {
+	// Redis 不可达：只验证入队行为，状态写入失败不影响返回值
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer redisClient.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &JobService{
+		redisClient: redisClient,
+		enabled:     true,
+		tasks:       make(chan *jobTask, 1),
+		slots:       make(chan struct{}, 1),
+		ctx:         ctx,
+		cancel:      cancel,
+		stop:        make(chan struct{}),
+	}
+
+	// Reserve 之后、Enqueue 之前服务开始关闭
+	s.slots <- struct{}{}
+	r := &JobReservation{info: &model.JobInfo{JobID: "job-1", Status: model.JobStatusQueued}, createdAt: time.Now()}
+	s.Shutdown(time.Second)
+
+	info := s.Enqueue(context.Background(), r, "ws", "", "return 1", nil)
+	if info.Status != model.JobStatusFailed || info.Error == nil || info.Error.Type != "ServiceUnavailableError" {
+		t.Fatalf("info = %+v, want failed with ServiceUnavailableError", info)
+	}
+	if len(s.tasks) != 0 || len(s.slots) != 0 {
+		t.Fatalf("tasks = %d, slots = %d, want 0 / 0", len(s.tasks), len(s.slots))
+	}
+}
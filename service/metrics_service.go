@@ -0,0 +1,211 @@
+package service
+
+import (
+	"sync/atomic"
+
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/utils"
+
+	"github.com/sony/gobreaker"
+)
+
+// MetricsService Prometheus 指标导出（/metrics）
+//
+// 抓取时直接读取各组件已有的统计（ExecutorStats、Runtime/EventLoop 池、熔断器、公平调度器、
+// 缓存、配额、限流、缓存写入池），不额外维护一份计数，与各 /flow/*/stats 接口的数据一致。
+// 依赖均可为 nil（对应指标不导出）。
+type MetricsService struct {
+	executor           *sandbox.JSExecutor
+	cacheService       *CacheService
+	quotaService       *QuotaService
+	rateLimiterService *RateLimiterService
+	cacheWritePool     *CacheWritePool
+	jobService         *JobService
+}
+
+// NewMetricsService 创建指标导出服务
+func NewMetricsService(
+	executor *sandbox.JSExecutor,
+	cacheService *CacheService,
+	quotaService *QuotaService,
+	rateLimiterService *RateLimiterService,
+	cacheWritePool *CacheWritePool,
+	jobService *JobService,
+) *MetricsService {
+	return &MetricsService{
+		executor:           executor,
+		cacheService:       cacheService,
+		quotaService:       quotaService,
+		rateLimiterService: rateLimiterService,
+		cacheWritePool:     cacheWritePool,
+		jobService:         jobService,
+	}
+}
+
+// Render 生成 Prometheus 文本格式的指标
+func (s *MetricsService) Render() []byte {
+	w := &utils.PrometheusWriter{}
+	if s.executor != nil {
+		s.writeExecutorMetrics(w)
+	}
+	if s.cacheService != nil {
+		s.writeCacheMetrics(w)
+	}
+	if s.rateLimiterService != nil {
+		s.writeRateLimiterMetrics(w)
+	}
+	if s.quotaService != nil {
+		s.writeQuotaMetrics(w)
+	}
+	if s.cacheWritePool != nil {
+		s.writeCacheWritePoolMetrics(w)
+	}
+	if s.jobService != nil && s.jobService.enabled {
+		s.writeJobMetrics(w)
+	}
+	return w.Bytes()
+}
+
+// writeExecutorMetrics 执行器：执行计数、耗时、Runtime/EventLoop 池、熔断器、公平调度
+func (s *MetricsService) writeExecutorMetrics(w *utils.PrometheusWriter) {
+	e := s.executor
+	stats := e.GetStats()
+
+	// 1. 执行计数
+	w.Counter("flow_executions_total", "Total number of code executions", float64(stats.TotalExecutions))
+	w.Counter("flow_executions_successful_total", "Number of successful code executions", float64(stats.SuccessfulExecs))
+	w.Counter("flow_executions_failed_total", "Number of failed code executions", float64(stats.FailedExecs))
+	w.Gauge("flow_executions_in_progress", "Number of code executions currently running", float64(stats.CurrentExecutions))
+	w.Header("flow_executions_by_route_total", "counter", "Number of code executions by route (sync: runtime pool, async: event loop)")
+	w.Sample("flow_executions_by_route_total", float64(stats.SyncExecutions), utils.MetricLabel{Name: "route", Value: "sync"})
+	w.Sample("flow_executions_by_route_total", float64(stats.AsyncExecutions), utils.MetricLabel{Name: "route", Value: "async"})
+
+	// 2. 执行耗时（按路由和错误类型）
+	w.HistogramVec("flow_execution_duration_seconds", "Code execution latency by route and error type (error_type=none for successful executions)", e.GetExecutionLatency())
+	w.HistogramVec("flow_execution_phase_duration_seconds", "Per-phase latency of code execution requests (cache=hit/miss/none)", e.GetPhaseLatency())
+
+	// 3. Runtime 池
+	health := e.GetRuntimePoolHealth()
+	w.Gauge("flow_runtime_pool_size", "Current runtime pool size", metricFloat(health["currentPoolSize"]))
+	w.Gauge("flow_runtime_pool_idle", "Idle runtimes in the pool", metricFloat(health["idleRuntimes"]))
+	w.Gauge("flow_runtime_pool_tracked", "Runtimes tracked by the health checker", metricFloat(health["trackedRuntimes"]))
+	w.Counter("flow_runtime_pool_destroyed_total", "Runtimes destroyed after reaching the reuse limit or failing", metricFloat(health["destroyCount"]))
+
+	// 4. EventLoop 池
+	elp := stats.EventLoopPool
+	w.Gauge("flow_eventloop_pool_size", "Current event loop pool size", float64(elp.Size))
+	w.Gauge("flow_eventloop_pool_idle", "Idle event loops in the pool", float64(elp.Available))
+	w.Header("flow_eventloop_executions_total", "counter", "Async executions by event loop source")
+	w.Sample("flow_eventloop_executions_total", float64(elp.PooledExecutions), utils.MetricLabel{Name: "source", Value: "pooled"})
+	w.Sample("flow_eventloop_executions_total", float64(elp.TemporaryExecutions), utils.MetricLabel{Name: "source", Value: "temporary"})
+	w.Counter("flow_eventloop_pool_destroyed_total", "Event loops destroyed", float64(elp.DestroyCount))
+	w.Counter("flow_eventloop_pool_reset_failures_total", "Event loop global state reset failures", float64(elp.ResetFailures))
+
+	// 5. 熔断器（0=closed 1=half-open 2=open）
+	w.Gauge("flow_circuit_breaker_state", "Circuit breaker state (0=closed, 1=half-open, 2=open)", circuitBreakerStateValue(e.GetCircuitBreakerState()))
+	w.Counter("flow_circuit_breaker_trips_total", "Number of times the circuit breaker opened", float64(stats.CircuitBreakerTrips))
+
+	// 6. 公平调度（替代原 semaphore）
+	sched := e.GetSchedulerStats()
+	w.Gauge("flow_scheduler_capacity", "Maximum concurrent executions (MAX_CONCURRENT_EXECUTIONS)", float64(sched.Capacity))
+	w.Gauge("flow_scheduler_running", "Execution slots currently in use", float64(sched.Running))
+	w.Gauge("flow_scheduler_queued", "Executions waiting for a slot", float64(sched.Queued))
+	w.Gauge("flow_scheduler_tenants", "Tenants with running or queued executions", float64(len(sched.Tenants)))
+	w.Counter("flow_scheduler_rejected_total", "Executions rejected because the tenant queue was full or the wait timed out", float64(sched.Rejected))
+	w.Histogram("flow_scheduler_wait_seconds", "Time spent waiting for an execution slot", e.GetSchedulerWaitLatency())
+}
+
+// writeCacheMetrics Token 缓存（热缓存 + Redis）
+func (s *MetricsService) writeCacheMetrics(w *utils.PrometheusWriter) {
+	stats := s.cacheService.GetStats()
+	hot, _ := stats["hot_cache"].(map[string]interface{})
+	perf, _ := stats["performance"].(map[string]interface{})
+
+	w.Gauge("flow_token_cache_size", "Entries in the in-memory token cache", metricFloat(hot["size"]))
+	w.Header("flow_token_cache_requests_total", "counter", "Token cache lookups by result")
+	w.Sample("flow_token_cache_requests_total", metricFloat(perf["hot_hits"]), utils.MetricLabel{Name: "result", Value: "hot_hit"})
+	w.Sample("flow_token_cache_requests_total", metricFloat(perf["warm_hits"]), utils.MetricLabel{Name: "result", Value: "warm_hit"})
+	w.Sample("flow_token_cache_requests_total", metricFloat(perf["misses"]), utils.MetricLabel{Name: "result", Value: "miss"})
+	w.Counter("flow_token_cache_evictions_total", "Entries evicted from the in-memory token cache", metricFloat(perf["hot_evictions"]))
+	w.Gauge("flow_token_cache_hit_ratio", "Token cache hit ratio (hot + warm) since startup, 0-1", metricFloat(perf["total_hit_rate"])/100)
+}
+
+// writeRateLimiterMetrics Token 限流（直接读取计数，避免抓取时访问 Redis）
+func (s *MetricsService) writeRateLimiterMetrics(w *utils.PrometheusWriter) {
+	r := s.rateLimiterService
+	hotHits := atomic.LoadInt64(&r.hotHits)
+	warmHits := atomic.LoadInt64(&r.warmHits)
+	coldHits := atomic.LoadInt64(&r.coldHits)
+	misses := atomic.LoadInt64(&r.misses)
+
+	w.Header("flow_rate_limiter_lookups_total", "counter", "Rate limiter state lookups by tier")
+	w.Sample("flow_rate_limiter_lookups_total", float64(hotHits), utils.MetricLabel{Name: "tier", Value: "hot"})
+	w.Sample("flow_rate_limiter_lookups_total", float64(warmHits), utils.MetricLabel{Name: "tier", Value: "warm"})
+	w.Sample("flow_rate_limiter_lookups_total", float64(coldHits), utils.MetricLabel{Name: "tier", Value: "cold"})
+	w.Sample("flow_rate_limiter_lookups_total", float64(misses), utils.MetricLabel{Name: "tier", Value: "miss"})
+
+	hotStats := r.hotTier.GetStats()
+	w.Gauge("flow_rate_limiter_hot_tier_size", "Tokens tracked in the in-memory rate limiter tier", metricFloat(hotStats["size"]))
+}
+
+// writeQuotaMetrics 配额服务队列和丢弃计数
+func (s *MetricsService) writeQuotaMetrics(w *utils.PrometheusWriter) {
+	stats := s.quotaService.GetStats()
+	w.Header("flow_quota_queue_length", "gauge", "Pending tasks in the quota service queues")
+	w.Sample("flow_quota_queue_length", metricFloat(stats["sync_queue_len"]), utils.MetricLabel{Name: "queue", Value: "sync"})
+	w.Sample("flow_quota_queue_length", metricFloat(stats["log_queue_len"]), utils.MetricLabel{Name: "queue", Value: "log"})
+	w.Header("flow_quota_dropped_total", "counter", "Quota tasks dropped because the queue was full")
+	w.Sample("flow_quota_dropped_total", metricFloat(stats["dropped_syncs"]), utils.MetricLabel{Name: "queue", Value: "sync"})
+	w.Sample("flow_quota_dropped_total", metricFloat(stats["dropped_logs"]), utils.MetricLabel{Name: "queue", Value: "log"})
+}
+
+// writeCacheWritePoolMetrics 缓存写入池
+func (s *MetricsService) writeCacheWritePoolMetrics(w *utils.PrometheusWriter) {
+	stats := s.cacheWritePool.GetStats()
+	w.Gauge("flow_cache_write_pool_queue_depth", "Pending tasks in the cache write pool", metricFloat(stats["queue_used"]))
+	w.Gauge("flow_cache_write_pool_queue_capacity", "Cache write pool queue capacity", metricFloat(stats["queue_size"]))
+	w.Header("flow_cache_write_pool_tasks_total", "counter", "Cache write pool tasks by result")
+	w.Sample("flow_cache_write_pool_tasks_total", metricFloat(stats["total_success"]), utils.MetricLabel{Name: "result", Value: "success"})
+	w.Sample("flow_cache_write_pool_tasks_total", metricFloat(stats["total_failed"]), utils.MetricLabel{Name: "result", Value: "failed"})
+	w.Sample("flow_cache_write_pool_tasks_total", metricFloat(stats["total_timeout"]), utils.MetricLabel{Name: "result", Value: "timeout"})
+	w.Counter("flow_cache_write_pool_submit_blocked_total", "Submissions rejected because the queue was full", metricFloat(stats["submit_blocked"]))
+}
+
+// writeJobMetrics 异步任务
+func (s *MetricsService) writeJobMetrics(w *utils.PrometheusWriter) {
+	stats := s.jobService.GetStats()
+	w.Gauge("flow_jobs_queue_depth", "Async jobs waiting for a worker", metricFloat(stats["queue_used"]))
+	w.Header("flow_jobs_total", "counter", "Async jobs by result")
+	w.Sample("flow_jobs_total", metricFloat(stats["total_submitted"]), utils.MetricLabel{Name: "result", Value: "submitted"})
+	w.Sample("flow_jobs_total", metricFloat(stats["total_rejected"]), utils.MetricLabel{Name: "result", Value: "rejected"})
+	w.Sample("flow_jobs_total", metricFloat(stats["total_succeeded"]), utils.MetricLabel{Name: "result", Value: "succeeded"})
+	w.Sample("flow_jobs_total", metricFloat(stats["total_failed"]), utils.MetricLabel{Name: "result", Value: "failed"})
+}
+
+// circuitBreakerStateValue 熔断器状态转换为数值
+func circuitBreakerStateValue(state gobreaker.State) float64 {
+	switch state {
+	case gobreaker.StateHalfOpen:
+		return 1
+	case gobreaker.StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// metricFloat 把统计 map 中的数值转换为 float64（缺失或类型不符时返回 0）
+func metricFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/enhance_modules"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+)
+
+// policyCacheTTL 命名策略本地缓存时间
+// 本实例修改策略时立即失效；其他实例最多延迟一个 TTL 生效
+const policyCacheTTL = 30 * time.Second
+
+// ErrPolicyNotFound Token 引用的命名策略不存在
+var ErrPolicyNotFound = errors.New("沙箱策略不存在")
+
+// policyCacheEntry 命名策略缓存条目（profile 为 nil 表示策略不存在）
+type policyCacheEntry struct {
+	profile   *model.SandboxPolicyProfile
+	expiresAt time.Time
+}
+
+// PolicyService 沙箱策略服务
+//
+// 职责：
+//   - 命名策略的创建、更新、查询（sandbox_policies 表）
+//   - Token 策略的设置和查询（access_tokens.policy_name / policy）
+//   - 执行前解析 Token 的生效策略：内联策略覆盖命名策略，未设置的字段使用全局配置
+type PolicyService struct {
+	repo         *repository.PolicyRepository
+	tokenService *TokenService
+
+	mu    sync.RWMutex
+	cache map[string]*policyCacheEntry
+}
+
+// NewPolicyService 创建沙箱策略服务
+func NewPolicyService(repo *repository.PolicyRepository, tokenService *TokenService) *PolicyService {
+	return &PolicyService{
+		repo:         repo,
+		tokenService: tokenService,
+		cache:        make(map[string]*policyCacheEntry),
+	}
+}
+
+// ResolveForToken 解析 Token 的生效策略（Token 未配置策略时返回 nil，使用全局默认值）
+func (s *PolicyService) ResolveForToken(ctx context.Context, tokenInfo *model.TokenInfo) (*model.SandboxPolicy, error) {
+	if tokenInfo == nil {
+		return nil, nil
+	}
+
+	var base *model.SandboxPolicy
+	if tokenInfo.PolicyName != nil && *tokenInfo.PolicyName != "" {
+		profile, err := s.getCachedProfile(ctx, *tokenInfo.PolicyName)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil {
+			return nil, ErrPolicyNotFound
+		}
+		base = &profile.Policy
+	}
+
+	return model.MergeSandboxPolicy(base, tokenInfo.Policy), nil
+}
+
+// getCachedProfile 获取命名策略（带本地缓存，不存在时返回 nil）
+func (s *PolicyService) getCachedProfile(ctx context.Context, name string) (*model.SandboxPolicyProfile, error) {
+	s.mu.RLock()
+	entry, found := s.cache[name]
+	s.mu.RUnlock()
+	if found && time.Now().Before(entry.expiresAt) {
+		return entry.profile, nil
+	}
+
+	profile, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[name] = &policyCacheEntry{profile: profile, expiresAt: time.Now().Add(policyCacheTTL)}
+	s.mu.Unlock()
+	return profile, nil
+}
+
+// invalidate 使命名策略的本地缓存失效
+func (s *PolicyService) invalidate(name string) {
+	s.mu.Lock()
+	delete(s.cache, name)
+	s.mu.Unlock()
+}
+
+// CreatePolicy 创建命名策略
+func (s *PolicyService) CreatePolicy(ctx context.Context, req *model.CreateSandboxPolicyRequest) (*model.SandboxPolicyProfile, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("name参数不能为空")
+	}
+	if err := ValidateSandboxPolicy(&req.Policy); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.repo.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(req.Name)
+
+	utils.Info("沙箱策略创建成功", zap.String("name", req.Name))
+	return profile, nil
+}
+
+// UpdatePolicy 更新命名策略（引用该策略的 Token 随之生效）
+func (s *PolicyService) UpdatePolicy(ctx context.Context, name string, req *model.UpdateSandboxPolicyRequest) (*model.SandboxPolicyProfile, error) {
+	if req.Policy != nil {
+		if err := ValidateSandboxPolicy(req.Policy); err != nil {
+			return nil, err
+		}
+	}
+
+	profile, err := s.repo.Update(ctx, name, req)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(name)
+
+	utils.Info("沙箱策略更新成功", zap.String("name", name))
+	return profile, nil
+}
+
+// GetPolicy 查询命名策略（不存在时返回 ErrPolicyNotFound）
+func (s *PolicyService) GetPolicy(ctx context.Context, name string) (*model.SandboxPolicyProfile, error) {
+	profile, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, ErrPolicyNotFound
+	}
+	return profile, nil
+}
+
+// ListPolicies 查询全部命名策略
+func (s *PolicyService) ListPolicies(ctx context.Context) ([]*model.SandboxPolicyProfile, error) {
+	return s.repo.List(ctx)
+}
+
+// ValidateTokenPolicy 校验 Token 的策略设置（命名策略必须存在，内联策略字段合法）
+func (s *PolicyService) ValidateTokenPolicy(ctx context.Context, policyName *string, policy *model.SandboxPolicy) error {
+	if policyName != nil && *policyName != "" {
+		profile, err := s.repo.GetByName(ctx, *policyName)
+		if err != nil {
+			return err
+		}
+		if profile == nil {
+			return fmt.Errorf("%w: %s", ErrPolicyNotFound, *policyName)
+		}
+	}
+	if policy != nil {
+		return ValidateSandboxPolicy(policy)
+	}
+	return nil
+}
+
+// SetTokenPolicy 设置 Token 的策略（整体替换）
+func (s *PolicyService) SetTokenPolicy(ctx context.Context, token string, req *model.SetTokenPolicyRequest) (*model.TokenPolicyResponse, error) {
+	if req.PolicyName != nil && *req.PolicyName == "" {
+		req.PolicyName = nil
+	}
+	if err := s.ValidateTokenPolicy(ctx, req.PolicyName, req.Policy); err != nil {
+		return nil, err
+	}
+
+	tokenInfo, err := s.tokenService.UpdateTokenPolicy(ctx, token, req.PolicyName, req.Policy)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokenPolicyResponse(ctx, tokenInfo)
+}
+
+// GetTokenPolicy 查询 Token 的策略及合并后的生效策略
+func (s *PolicyService) GetTokenPolicy(ctx context.Context, token string) (*model.TokenPolicyResponse, error) {
+	tokens, err := s.tokenService.GetTokenInfo(ctx, &model.TokenQueryRequest{Token: token})
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("Token不存在")
+	}
+	return s.tokenPolicyResponse(ctx, tokens[0])
+}
+
+// tokenPolicyResponse 构建 Token 策略查询结果（引用的命名策略已被删除时 effective 为空）
+func (s *PolicyService) tokenPolicyResponse(ctx context.Context, tokenInfo *model.TokenInfo) (*model.TokenPolicyResponse, error) {
+	effective, err := s.ResolveForToken(ctx, tokenInfo)
+	if err != nil && !errors.Is(err, ErrPolicyNotFound) {
+		return nil, err
+	}
+	return &model.TokenPolicyResponse{
+		AccessToken: tokenInfo.AccessToken,
+		PolicyName:  tokenInfo.PolicyName,
+		Policy:      tokenInfo.Policy,
+		Effective:   effective,
+	}, nil
+}
+
+// ValidateSandboxPolicy 校验策略字段
+func ValidateSandboxPolicy(policy *model.SandboxPolicy) error {
+	for i, name := range policy.AllowedModules {
+		module := sandbox.ModuleBaseName(name)
+		if module == "" {
+			return fmt.Errorf("allowed_modules 不能包含空模块名")
+		}
+		if reason, found := sandbox.ProhibitedModule(module); found {
+			return fmt.Errorf("allowed_modules 不能包含 %s 模块（%s出于安全考虑已被禁用）", module, reason)
+		}
+		policy.AllowedModules[i] = module
+	}
+
+	if policy.ExecutionTimeoutMs != nil && *policy.ExecutionTimeoutMs < 1 {
+		return fmt.Errorf("execution_timeout_ms 必须 >= 1，当前值: %d", *policy.ExecutionTimeoutMs)
+	}
+	if policy.MaxResultSize != nil && *policy.MaxResultSize < 1 {
+		return fmt.Errorf("max_result_size 必须 >= 1，当前值: %d", *policy.MaxResultSize)
+	}
+	if policy.MaxInputSize != nil && *policy.MaxInputSize < 1 {
+		return fmt.Errorf("max_input_size 必须 >= 1，当前值: %d", *policy.MaxInputSize)
+	}
+
+	if policy.Network != nil && policy.Network.Egress != nil {
+		for i, rule := range policy.Network.Egress {
+			policy.Network.Egress[i] = strings.TrimSpace(rule)
+		}
+		if _, err := enhance_modules.ParseEgressRules(policy.Network.Egress); err != nil {
+			return fmt.Errorf("network.egress %w", err)
+		}
+	}
+
+	if sched := policy.Scheduling; sched != nil {
+		if sched.Weight != nil && (*sched.Weight < 1 || *sched.Weight > sandbox.MaxSchedulingWeight) {
+			return fmt.Errorf("scheduling.weight 必须在 1-%d 之间，当前值: %d", sandbox.MaxSchedulingWeight, *sched.Weight)
+		}
+		if sched.MaxInFlight != nil && *sched.MaxInFlight < 1 {
+			return fmt.Errorf("scheduling.max_in_flight 必须 >= 1，当前值: %d", *sched.MaxInFlight)
+		}
+		switch sched.PriorityClass {
+		case "", model.PriorityClassHigh, model.PriorityClassNormal, model.PriorityClassLow:
+		default:
+			return fmt.Errorf("scheduling.priority_class 必须是 %s/%s/%s 之一，当前值: %s",
+				model.PriorityClassHigh, model.PriorityClassNormal, model.PriorityClassLow, sched.PriorityClass)
+		}
+	}
+
+	if history := policy.History; history != nil {
+		if history.RetentionDays != nil && *history.RetentionDays < 1 {
+			return fmt.Errorf("history.retention_days 必须 >= 1，当前值: %d", *history.RetentionDays)
+		}
+		if history.MaxFieldBytes != nil && *history.MaxFieldBytes < 1 {
+			return fmt.Errorf("history.max_field_bytes 必须 >= 1，当前值: %d", *history.MaxFieldBytes)
+		}
+		for _, pattern := range history.RedactPatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("history.redact_patterns 包含无效的正则 %q: %v", pattern, err)
+			}
+		}
+	}
+
+	switch policy.ConsoleMode {
+	case "", config.ConsoleModeDisabled, config.ConsoleModeStdout, config.ConsoleModeCapture:
+	default:
+		return fmt.Errorf("console_mode 必须是 %s/%s/%s 之一，当前值: %s",
+			config.ConsoleModeDisabled, config.ConsoleModeStdout, config.ConsoleModeCapture, policy.ConsoleMode)
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"flow-codeblock-go/utils"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -155,7 +156,12 @@ func (s *QuotaService) InitQuota(ctx context.Context, tokenInfo *model.TokenInfo
 // ConsumeQuota 消耗配额（执行代码时调用）
 // 返回: (quotaBefore, quotaAfter, error)
 func (s *QuotaService) ConsumeQuota(ctx context.Context, token string, wsID string, email string, requestID string, executionSuccess bool, errorType *string, errorMessage *string) (int, int, error) {
-	return s.consumeQuotaWithDepth(ctx, token, wsID, email, requestID, executionSuccess, errorType, errorMessage, 0)
+	// 🆕 链路追踪：配额扣减（Redis / DB 降级都计入此 span）
+	ctx, span := utils.StartSpan(ctx, "quota.consume", attribute.String("ws_id", wsID))
+	quotaBefore, quotaAfter, err := s.consumeQuotaWithDepth(ctx, token, wsID, email, requestID, executionSuccess, errorType, errorMessage, 0)
+	span.SetAttributes(attribute.Int("quota.remaining", quotaAfter))
+	utils.EndSpan(span, err)
+	return quotaBefore, quotaAfter, err
 }
 
 // consumeQuotaWithDepth 消耗配额（带递归深度限制）
@@ -629,5 +635,7 @@ func (s *QuotaService) GetStats() map[string]interface{} {
 		"log_queue_cap":   cap(s.logChan),
 		"sync_interval":   s.syncInterval.String(),
 		"sync_batch_size": s.syncBatch,
+		"dropped_syncs":   atomic.LoadInt64(&s.droppedSyncCount), // 🆕 同步队列满时降级的次数
+		"dropped_logs":    atomic.LoadInt64(&s.droppedLogCount),  // 🆕 日志队列满时丢弃的次数
 	}
 }
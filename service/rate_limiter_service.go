@@ -72,9 +72,28 @@ func (s *RateLimiterService) CheckLimit(
 	token string,
 	config model.RateLimitConfig,
 ) (bool, *model.RateLimitInfo, error) {
+	allowedCount, info, err := s.CheckLimitN(ctx, token, config, 1)
+	if err != nil {
+		return false, nil, err
+	}
+	return allowedCount == 1, info, nil
+}
+
+// CheckLimitN 按条目数检查限流（批量执行使用）
+// 🆕 窗口限制按条目计费（一次请求消耗 n 个配额），突发限制按请求计算（整批视为一次到达）
+// 返回实际允许的条目数（0 ~ n），窗口剩余不足时只放行剩余部分
+//
+// 请求历史中每批只有第 1 个条目记为正数时间戳，其余条目记为负数（见 requestTimestamp）：
+// 窗口限制和按小时统计按全部条目计数，突发限制只统计正数条目
+func (s *RateLimiterService) CheckLimitN(
+	ctx context.Context,
+	token string,
+	config model.RateLimitConfig,
+	n int,
+) (int, *model.RateLimitInfo, error) {
 	// 如果不限制，直接通过
 	if config.Unlimited {
-		return true, &model.RateLimitInfo{
+		return n, &model.RateLimitInfo{
 			Allowed:   true,
 			Remaining: -1,
 		}, nil
@@ -86,7 +105,7 @@ func (s *RateLimiterService) CheckLimit(
 	// 1. 获取请求历史（三层查询）
 	requests, err := s.getRequestHistory(ctx, limitKey)
 	if err != nil {
-		return false, nil, err
+		return 0, nil, err
 	}
 
 	// 2. 清理过期记录
@@ -95,9 +114,8 @@ func (s *RateLimiterService) CheckLimit(
 
 	// 3. 检查突发限制（每秒）
 	if config.Burst > 0 {
-		recentRequests := filterValidRequests(validRequests, now, 1*time.Second)
-		if len(recentRequests) >= config.Burst {
-			return false, &model.RateLimitInfo{
+		if countBurstEntries(filterValidRequests(validRequests, now, 1*time.Second)) >= config.Burst {
+			return 0, &model.RateLimitInfo{
 				Allowed:    false,
 				Remaining:  0,
 				ResetTime:  now.Add(1 * time.Second),
@@ -109,8 +127,9 @@ func (s *RateLimiterService) CheckLimit(
 	}
 
 	// 4. 检查窗口限制
-	if len(validRequests) >= config.PerMinute {
-		return false, &model.RateLimitInfo{
+	remaining := config.PerMinute - len(validRequests)
+	if remaining <= 0 {
+		return 0, &model.RateLimitInfo{
 			Allowed:    false,
 			Remaining:  0,
 			ResetTime:  now.Add(window),
@@ -120,14 +139,23 @@ func (s *RateLimiterService) CheckLimit(
 		}, nil
 	}
 
-	// 5. 记录当前请求
-	validRequests = append(validRequests, now.UnixMilli())
+	allowedCount := n
+	if allowedCount > remaining {
+		allowedCount = remaining
+	}
+
+	// 5. 记录本次放行的条目（整批只计一次突发）
+	nowMilli := now.UnixMilli()
+	validRequests = append(validRequests, nowMilli)
+	for i := 1; i < allowedCount; i++ {
+		validRequests = append(validRequests, -nowMilli)
+	}
 	if err := s.updateRequestHistory(ctx, limitKey, validRequests); err != nil {
-		return false, nil, err
+		return 0, nil, err
 	}
 
 	// 6. 返回限流信息
-	return true, &model.RateLimitInfo{
+	return allowedCount, &model.RateLimitInfo{
 		Allowed:   true,
 		Remaining: config.PerMinute - len(validRequests),
 		ResetTime: now.Add(window),
@@ -296,15 +324,35 @@ func filterValidRequests(requests []int64, now time.Time, window time.Duration)
 	threshold := now.Add(-window).UnixMilli()
 	valid := make([]int64, 0, len(requests))
 
-	for _, timestamp := range requests {
-		if timestamp > threshold {
-			valid = append(valid, timestamp)
+	for _, entry := range requests {
+		if requestTimestamp(entry) > threshold {
+			valid = append(valid, entry)
 		}
 	}
 
 	return valid
 }
 
+// requestTimestamp 请求历史条目的时间戳（毫秒）
+// 负数为批量请求中除第 1 个以外的条目：计入窗口限制，不计入突发限制
+func requestTimestamp(entry int64) int64 {
+	if entry < 0 {
+		return -entry
+	}
+	return entry
+}
+
+// countBurstEntries 突发限制计数（每次请求计一次，批量请求整批计一次）
+func countBurstEntries(requests []int64) int {
+	count := 0
+	for _, entry := range requests {
+		if entry > 0 {
+			count++
+		}
+	}
+	return count
+}
+
 func getIntOrZero(m map[string]interface{}, key string) int {
 	if val, ok := m[key]; ok {
 		if intVal, ok := val.(int); ok {
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"flow-codeblock-go/model"
+)
+
+// newTestRateLimiter 只使用热数据层的限流服务（Redis、数据库为空）
+func newTestRateLimiter(t *testing.T) *RateLimiterService {
+	t.Helper()
+	pool := NewCacheWritePool(1, 100)
+	t.Cleanup(func() { pool.Shutdown(time.Second) })
+	return NewRateLimiterService(100, nil, time.Minute, nil, 1000, pool, time.Second)
+}
+
+func TestCheckLimitNBatchLargerThanBurstCountsAsOneBurst(t *testing.T) {
+	limiter := newTestRateLimiter(t)
+	ctx := context.Background()
+	config := model.RateLimitConfig{PerMinute: 100, Burst: 3, WindowSeconds: 60}
+
+	// 整批超过突发上限：按条目计入窗口，只计一次突发
+	allowed, info, err := limiter.CheckLimitN(ctx, "token-a", config, 10)
+	if err != nil {
+		t.Fatalf("CheckLimitN: %v", err)
+	}
+	if allowed != 10 {
+		t.Fatalf("allowed = %d, want 10", allowed)
+	}
+	if info.Remaining != 90 {
+		t.Errorf("remaining = %d, want 90", info.Remaining)
+	}
+
+	// 同一秒内的后续请求：突发计数为 1（批量）+ 已放行的单个请求
+	for i := 1; i < config.Burst; i++ {
+		ok, info, err := limiter.CheckLimit(ctx, "token-a", config)
+		if err != nil {
+			t.Fatalf("CheckLimit: %v", err)
+		}
+		if !ok {
+			t.Fatalf("第 %d 个请求被拒绝: %s", i+1, info.Message)
+		}
+	}
+
+	// 达到突发上限后拒绝
+	ok, info, err := limiter.CheckLimit(ctx, "token-a", config)
+	if err != nil {
+		t.Fatalf("CheckLimit: %v", err)
+	}
+	if ok || info.LimitType != "burst" {
+		t.Fatalf("ok = %v, limit_type = %q, want burst 拒绝", ok, info.LimitType)
+	}
+}
+
+func TestCheckLimitNWindowCountsEveryEntry(t *testing.T) {
+	limiter := newTestRateLimiter(t)
+	ctx := context.Background()
+	config := model.RateLimitConfig{PerMinute: 12, Burst: 5, WindowSeconds: 60}
+
+	allowed, _, err := limiter.CheckLimitN(ctx, "token-b", config, 10)
+	if err != nil || allowed != 10 {
+		t.Fatalf("allowed = %d, err = %v, want 10", allowed, err)
+	}
+
+	// 窗口只剩 2 个条目：只放行剩余部分
+	allowed, info, err := limiter.CheckLimitN(ctx, "token-b", config, 5)
+	if err != nil {
+		t.Fatalf("CheckLimitN: %v", err)
+	}
+	if allowed != 2 || info.Remaining != 0 {
+		t.Fatalf("allowed = %d, remaining = %d, want 2 / 0", allowed, info.Remaining)
+	}
+
+	ok, info, err := limiter.CheckLimit(ctx, "token-b", config)
+	if err != nil {
+		t.Fatalf("CheckLimit: %v", err)
+	}
+	if ok || info.LimitType != "window" {
+		t.Fatalf("ok = %v, limit_type = %q, want window 拒绝", ok, info.LimitType)
+	}
+}
+
+func TestColdTierAggregatesBatchEntries(t *testing.T) {
+	tier := &ColdDataTier{}
+	ts := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local).UnixMilli()
+
+	hourly := tier.aggregateByHour([]int64{ts, -ts, -ts})
+	if len(hourly) != 1 {
+		t.Fatalf("len(hourly) = %d, want 1", len(hourly))
+	}
+	for _, item := range hourly {
+		if item.RequestCount != 3 || item.RecordHour != 9 || item.FirstRequestAt != ts || item.LastRequestAt != ts {
+			t.Errorf("item = %+v, want 3 条 9 点的请求", item)
+		}
+	}
+}
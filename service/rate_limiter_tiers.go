@@ -348,7 +348,8 @@ func (c *ColdDataTier) Set(ctx context.Context, tokenKey string, requests []int6
 func (c *ColdDataTier) aggregateByHour(requests []int64) map[string]*batchItem {
 	hourlyData := make(map[string]*batchItem)
 
-	for _, timestamp := range requests {
+	for _, entry := range requests {
+		timestamp := requestTimestamp(entry)
 		t := time.UnixMilli(timestamp)
 		dateStr := t.Format("2006-01-02")
 		hour := t.Hour()
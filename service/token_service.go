@@ -40,15 +40,29 @@ func NewTokenService(
 
 // ValidateToken 验证Token（带缓存）
 func (s *TokenService) ValidateToken(ctx context.Context, token string) (*model.TokenInfo, error) {
+	tokenInfo, _, err := s.ValidateTokenWithSource(ctx, token)
+	return tokenInfo, err
+}
+
+// Token 校验结果的来源（ValidateTokenWithSource）
+const (
+	TokenSourceHot      = "hot"      // 热缓存（内存）
+	TokenSourceWarm     = "warm"     // 温缓存（Redis）
+	TokenSourceDatabase = "database" // 数据库
+)
+
+// ValidateTokenWithSource 同 ValidateToken，额外返回命中的缓存层级（校验失败时可能为空）
+// 🆕 用于执行时间线区分认证阶段是否命中缓存
+func (s *TokenService) ValidateTokenWithSource(ctx context.Context, token string) (*model.TokenInfo, string, error) {
 	// 1. 先查热缓存
 	if tokenInfo, found := s.cache.GetHot(token); found {
 		// 检查过期
 		if tokenInfo.IsExpired() {
 			s.cache.Delete(ctx, token) // 传递 Context
-			return nil, fmt.Errorf("Token已过期")
+			return nil, "", fmt.Errorf("Token已过期")
 		}
 		utils.Debug("Token热缓存命中", zap.String("token", utils.MaskToken(token)))
-		return tokenInfo, nil
+		return tokenInfo, TokenSourceHot, nil
 	}
 
 	// 2. 查温缓存（Redis）
@@ -58,28 +72,28 @@ func (s *TokenService) ValidateToken(ctx context.Context, token string) (*model.
 
 		if tokenInfo.IsExpired() {
 			s.cache.Delete(ctx, token) // 传递 Context
-			return nil, fmt.Errorf("Token已过期")
+			return nil, "", fmt.Errorf("Token已过期")
 		}
 		utils.Debug("Token温缓存命中", zap.String("token", utils.MaskToken(token)))
-		return tokenInfo, nil
+		return tokenInfo, TokenSourceWarm, nil
 	}
 
 	// 3. 查数据库
 	tokenInfo, err := s.repo.GetByToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return nil, TokenSourceDatabase, err
 	}
 
 	if tokenInfo == nil {
-		return nil, fmt.Errorf("Token不存在")
+		return nil, "", fmt.Errorf("Token不存在")
 	}
 
 	if !tokenInfo.IsActive {
-		return nil, fmt.Errorf("Token已禁用")
+		return nil, "", fmt.Errorf("Token已禁用")
 	}
 
 	if tokenInfo.IsExpired() {
-		return nil, fmt.Errorf("Token已过期")
+		return nil, "", fmt.Errorf("Token已过期")
 	}
 
 	// 4. 存入缓存
@@ -104,7 +118,7 @@ func (s *TokenService) ValidateToken(ctx context.Context, token string) (*model.
 	}
 
 	utils.Debug("Token数据库查询", zap.String("token", utils.MaskToken(token)))
-	return tokenInfo, nil
+	return tokenInfo, TokenSourceDatabase, nil
 }
 
 // CreateToken 创建Token
@@ -184,6 +198,20 @@ func (s *TokenService) UpdateToken(ctx context.Context, token string, req *model
 	return tokenInfo, nil
 }
 
+// UpdateTokenPolicy 设置Token的沙箱策略（整体替换，nil 表示清除）
+func (s *TokenService) UpdateTokenPolicy(ctx context.Context, token string, policyName *string, policy *model.SandboxPolicy) (*model.TokenInfo, error) {
+	tokenInfo, err := s.repo.UpdatePolicy(ctx, token, policyName, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 清除缓存（下次验证时从数据库加载新策略）
+	s.cache.Delete(ctx, token)
+
+	utils.Info("Token策略更新成功", zap.String("token", utils.MaskToken(token)))
+	return tokenInfo, nil
+}
+
 // DeleteToken 删除Token
 func (s *TokenService) DeleteToken(ctx context.Context, token string) error {
 	// 删除Token
@@ -0,0 +1,782 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	// TriggerSecretHeader auth_mode=secret 时携带密钥的请求头（也可以使用查询参数 secret）
+	TriggerSecretHeader = "X-Trigger-Secret"
+	// triggerCacheTTL 按 key 查询触发器的本地缓存时间（本实例修改时立即失效）
+	triggerCacheTTL = 30 * time.Second
+	// 默认签名格式（与异步任务回调签名一致）：X-Flow-Signature: sha256=hex(HMAC-SHA256(密钥, 时间戳 + "." + 请求体))
+	triggerDefaultSignatureHeader = "X-Flow-Signature"
+	triggerDefaultTimestampHeader = "X-Flow-Timestamp"
+	triggerDefaultSignaturePrefix = "sha256="
+)
+
+var (
+	// ErrTriggerNotFound 触发器不存在（或不属于当前 Token、已禁用）
+	ErrTriggerNotFound = errors.New("HTTP触发器不存在")
+	// ErrTriggerDisabled HTTP 触发器未启用
+	ErrTriggerDisabled = errors.New("HTTP触发器未启用（TRIGGER_ENABLED=false）")
+)
+
+// triggerMethods 允许绑定的 HTTP 方法
+var triggerMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true,
+	http.MethodDelete: true, http.MethodHead: true, http.MethodOptions: true,
+}
+
+// triggerHopHeaders 逐跳头和由服务端计算的头：不传给脚本，脚本也不能设置
+var triggerHopHeaders = map[string]bool{
+	"connection": true, "keep-alive": true, "proxy-authenticate": true, "proxy-authorization": true,
+	"te": true, "trailer": true, "transfer-encoding": true, "upgrade": true, "content-length": true,
+}
+
+// triggerCredentialHeaders 调用方的凭据：不传给脚本
+// 触发地址与测试工具页面同源，浏览器会带上 HttpOnly 的 flow_page_session Cookie；API Token 同样不能泄露给脚本作者
+var triggerCredentialHeaders = map[string]bool{
+	"cookie": true, "authorization": true, "accesstoken": true, "access-token": true,
+}
+
+// triggerResponseHeaders 脚本可以设置的响应头（其余忽略，包括 Set-Cookie）
+// 另外允许 X- 开头的自定义头（triggerReservedHeaders 中的除外）
+var triggerResponseHeaders = map[string]bool{
+	"content-type": true, "content-language": true, "content-disposition": true,
+	"cache-control": true, "expires": true, "etag": true, "last-modified": true,
+	"location": true, "vary": true, "retry-after": true,
+}
+
+// triggerReservedHeaders 由服务端设置的 X- 头，脚本不能覆盖
+var triggerReservedHeaders = map[string]bool{
+	"x-content-type-options": true, "x-request-id": true, "x-function-version": true,
+	"x-frame-options": true, "x-xss-protection": true,
+}
+
+// TriggerRequest 触发请求（传给脚本的 input.request）
+type TriggerRequest struct {
+	Method   string
+	Path     string // 触发地址之后的子路径（至少为 /）
+	Query    url.Values
+	Header   http.Header
+	Body     []byte
+	ClientIP string
+}
+
+// TriggerResponse 脚本返回值转换后的 HTTP 响应
+type TriggerResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// triggerCacheEntry 触发器缓存条目
+type triggerCacheEntry struct {
+	trigger   *model.FunctionTrigger
+	expiresAt time.Time
+}
+
+// TriggerService HTTP 触发器服务（存储脚本绑定为公开的 Webhook 地址）
+//
+// 职责：
+//   - 管理 Token 的触发器：公开地址 /flow/hooks/:key、独立密钥、认证方式、独立限流
+//   - 校验触发请求（密钥 / HMAC 签名），把请求转换为 input.request，按触发器所属 Token 执行脚本
+//   - 把脚本返回的 { status, headers, body } 转换为 HTTP 响应（支持 base64 二进制响应体）
+type TriggerService struct {
+	repo               *repository.TriggerRepository
+	runner             *FunctionRunner
+	functionService    *FunctionService
+	rateLimiterService *RateLimiterService
+	cfg                config.TriggerConfig
+
+	mu    sync.RWMutex
+	cache map[string]*triggerCacheEntry // trigger_key -> 触发器
+}
+
+// NewTriggerService 创建 HTTP 触发器服务
+func NewTriggerService(
+	repo *repository.TriggerRepository,
+	runner *FunctionRunner,
+	functionService *FunctionService,
+	rateLimiterService *RateLimiterService,
+	cfg config.TriggerConfig,
+) *TriggerService {
+	return &TriggerService{
+		repo:               repo,
+		runner:             runner,
+		functionService:    functionService,
+		rateLimiterService: rateLimiterService,
+		cfg:                cfg,
+		cache:              make(map[string]*triggerCacheEntry),
+	}
+}
+
+// IsEnabled 是否接收触发请求
+func (s *TriggerService) IsEnabled() bool {
+	return s.cfg.Enabled
+}
+
+// MaxBodyBytes 触发请求体大小上限
+func (s *TriggerService) MaxBodyBytes() int64 {
+	return s.cfg.MaxBodyBytes
+}
+
+// ==================== 触发器管理 ====================
+
+// Create 创建触发器（归属于当前 Token），返回的详情包含密钥
+func (s *TriggerService) Create(ctx context.Context, tokenInfo *model.TokenInfo, req *model.CreateTriggerRequest) (*model.FunctionTriggerDetail, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrTriggerDisabled
+	}
+
+	count, err := s.repo.CountByToken(ctx, tokenInfo.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.cfg.MaxTriggersPerToken {
+		return nil, triggerValidationError(fmt.Sprintf("每个 Token 最多创建 %d 个HTTP触发器", s.cfg.MaxTriggersPerToken))
+	}
+
+	key, err := randomHex(24)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	trigger := &model.FunctionTrigger{
+		TriggerKey:         "hook_" + key,
+		Token:              tokenInfo.AccessToken,
+		WsID:               tokenInfo.WsID,
+		Email:              tokenInfo.Email,
+		Name:               strings.TrimSpace(req.Name),
+		FunctionName:       req.FunctionName,
+		Version:            req.Version,
+		AuthMode:           req.AuthMode,
+		Secret:             secret,
+		SignatureHeader:    req.SignatureHeader,
+		SignatureAlgorithm: req.SignatureAlgorithm,
+		SignatureEncoding:  req.SignatureEncoding,
+		SignaturePrefix:    req.SignaturePrefix,
+		TimestampHeader:    req.TimestampHeader,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		RateLimitBurst:     req.RateLimitBurst,
+		Enabled:            req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.prepare(ctx, trigger, req.Methods); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, trigger); err != nil {
+		return nil, err
+	}
+
+	utils.Info("HTTP触发器已创建",
+		zap.Int64("trigger_id", trigger.ID),
+		zap.String("ws_id", trigger.WsID),
+		zap.String("function", trigger.FunctionName),
+		zap.String("auth_mode", trigger.AuthMode))
+
+	detail := newTriggerDetail(trigger)
+	detail.Secret = secret
+	return detail, nil
+}
+
+// Update 更新触发器（地址和密钥不变）
+func (s *TriggerService) Update(ctx context.Context, token string, id int64, req *model.UpdateTriggerRequest) (*model.FunctionTriggerDetail, error) {
+	trigger, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := splitTriggerMethods(trigger.Methods)
+	if req.Name != nil {
+		trigger.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.FunctionName != nil {
+		trigger.FunctionName = *req.FunctionName
+	}
+	if req.Version != nil {
+		trigger.Version = *req.Version
+	}
+	if req.Methods != nil {
+		methods = *req.Methods
+	}
+	if req.AuthMode != nil {
+		trigger.AuthMode = *req.AuthMode
+	}
+	if req.SignatureHeader != nil {
+		trigger.SignatureHeader = *req.SignatureHeader
+	}
+	if req.SignatureAlgorithm != nil {
+		trigger.SignatureAlgorithm = *req.SignatureAlgorithm
+	}
+	if req.SignatureEncoding != nil {
+		trigger.SignatureEncoding = *req.SignatureEncoding
+	}
+	if req.SignaturePrefix != nil {
+		trigger.SignaturePrefix = *req.SignaturePrefix
+	}
+	if req.TimestampHeader != nil {
+		trigger.TimestampHeader = *req.TimestampHeader
+	}
+	if req.RateLimitPerMinute != nil {
+		trigger.RateLimitPerMinute = *req.RateLimitPerMinute
+	}
+	if req.RateLimitBurst != nil {
+		trigger.RateLimitBurst = *req.RateLimitBurst
+	}
+	if req.Enabled != nil {
+		trigger.Enabled = *req.Enabled
+	}
+
+	if err := s.prepare(ctx, trigger, methods); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, trigger); err != nil {
+		return nil, err
+	}
+	s.invalidate(trigger.TriggerKey)
+
+	utils.Info("HTTP触发器已更新", zap.Int64("trigger_id", trigger.ID), zap.Bool("enabled", trigger.Enabled))
+	return newTriggerDetail(trigger), nil
+}
+
+// RotateSecret 生成新密钥（旧密钥立即失效，其他实例最多延迟一个缓存周期）
+func (s *TriggerService) RotateSecret(ctx context.Context, token string, id int64) (*model.FunctionTriggerDetail, error) {
+	trigger, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateSecret(ctx, id, secret); err != nil {
+		return nil, err
+	}
+	s.invalidate(trigger.TriggerKey)
+	trigger.Secret = secret
+
+	utils.Info("HTTP触发器密钥已轮换", zap.Int64("trigger_id", id))
+	detail := newTriggerDetail(trigger)
+	detail.Secret = secret
+	return detail, nil
+}
+
+// Delete 删除触发器
+func (s *TriggerService) Delete(ctx context.Context, token string, id int64) error {
+	trigger, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate(trigger.TriggerKey)
+
+	utils.Info("HTTP触发器已删除", zap.Int64("trigger_id", id))
+	return nil
+}
+
+// Get 获取触发器详情（不含密钥）
+func (s *TriggerService) Get(ctx context.Context, token string, id int64) (*model.FunctionTriggerDetail, error) {
+	trigger, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return nil, err
+	}
+	return newTriggerDetail(trigger), nil
+}
+
+// List 获取 Token 的全部触发器（不含密钥）
+func (s *TriggerService) List(ctx context.Context, token string) ([]*model.FunctionTriggerDetail, error) {
+	triggers, err := s.repo.ListByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]*model.FunctionTriggerDetail, 0, len(triggers))
+	for _, trigger := range triggers {
+		details = append(details, newTriggerDetail(trigger))
+	}
+	return details, nil
+}
+
+// prepare 校验触发器并填充默认值
+func (s *TriggerService) prepare(ctx context.Context, trigger *model.FunctionTrigger, methods []string) error {
+	if trigger.Name == "" {
+		return triggerValidationError("触发器名称不能为空")
+	}
+	if trigger.Version == "" {
+		trigger.Version = model.FunctionAliasLatest
+	}
+	if trigger.AuthMode == "" {
+		trigger.AuthMode = model.TriggerAuthSecret
+	}
+	if trigger.RateLimitPerMinute <= 0 {
+		trigger.RateLimitPerMinute = s.cfg.DefaultRateLimitPerMinute
+	}
+	if trigger.RateLimitBurst <= 0 {
+		trigger.RateLimitBurst = s.cfg.DefaultRateLimitBurst
+	}
+
+	// 签名请求头为空时使用默认签名格式；指定了请求头时其他字段按提供的值（未提供时为 sha256 / hex / 无前缀 / 无时间戳）
+	if trigger.AuthMode == model.TriggerAuthHMAC && trigger.SignatureHeader == "" {
+		trigger.SignatureHeader = triggerDefaultSignatureHeader
+		trigger.SignaturePrefix = triggerDefaultSignaturePrefix
+		trigger.TimestampHeader = triggerDefaultTimestampHeader
+	}
+	if trigger.SignatureAlgorithm == "" {
+		trigger.SignatureAlgorithm = "sha256"
+	}
+	if trigger.SignatureEncoding == "" {
+		trigger.SignatureEncoding = "hex"
+	}
+
+	normalized := make([]string, 0, len(methods))
+	seen := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if !triggerMethods[method] {
+			return triggerValidationError("不支持的 HTTP 方法: " + method)
+		}
+		if !seen[method] {
+			seen[method] = true
+			normalized = append(normalized, method)
+		}
+	}
+	sort.Strings(normalized)
+	trigger.Methods = strings.Join(normalized, ",")
+
+	// 脚本和版本必须存在（之后被删除时，触发返回 NotFoundError）
+	if _, err := s.functionService.Resolve(ctx, trigger.WsID, trigger.FunctionName, trigger.Version); err != nil {
+		if errors.Is(err, ErrFunctionNotFound) || errors.Is(err, ErrFunctionVersionNotFound) {
+			return triggerValidationError(fmt.Sprintf("%s: %s@%s", err.Error(), trigger.FunctionName, trigger.Version))
+		}
+		return err
+	}
+	return nil
+}
+
+// getOwned 获取属于 token 的触发器（其他 Token 的触发器视为不存在）
+func (s *TriggerService) getOwned(ctx context.Context, token string, id int64) (*model.FunctionTrigger, error) {
+	trigger, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if trigger == nil || trigger.Token != token {
+		return nil, ErrTriggerNotFound
+	}
+	return trigger, nil
+}
+
+// ==================== 触发 ====================
+
+// Lookup 按公开地址中的 key 获取已启用的触发器
+func (s *TriggerService) Lookup(ctx context.Context, key string) (*model.FunctionTrigger, error) {
+	s.mu.RLock()
+	entry, found := s.cache[key]
+	s.mu.RUnlock()
+	if found && time.Now().Before(entry.expiresAt) {
+		if entry.trigger == nil {
+			return nil, ErrTriggerNotFound
+		}
+		return entry.trigger, nil
+	}
+
+	trigger, err := s.repo.GetByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if trigger != nil && !trigger.Enabled {
+		trigger = nil
+	}
+
+	// 不存在的 key 也缓存，避免扫描地址时每次查库
+	s.mu.Lock()
+	s.cache[key] = &triggerCacheEntry{trigger: trigger, expiresAt: time.Now().Add(triggerCacheTTL)}
+	s.mu.Unlock()
+
+	if trigger == nil {
+		return nil, ErrTriggerNotFound
+	}
+	return trigger, nil
+}
+
+// AllowsMethod 触发器是否接受该 HTTP 方法
+func (s *TriggerService) AllowsMethod(trigger *model.FunctionTrigger, method string) bool {
+	if trigger.Methods == "" {
+		return true
+	}
+	for _, allowed := range splitTriggerMethods(trigger.Methods) {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify 按触发器的认证方式校验请求（失败时返回 AuthenticationError）
+func (s *TriggerService) Verify(trigger *model.FunctionTrigger, req *TriggerRequest) *model.ExecutionError {
+	switch trigger.AuthMode {
+	case model.TriggerAuthNone:
+		return nil
+
+	case model.TriggerAuthSecret:
+		provided := req.Header.Get(TriggerSecretHeader)
+		if provided == "" {
+			provided = req.Query.Get("secret")
+		}
+		if provided == "" || !hmac.Equal([]byte(provided), []byte(trigger.Secret)) {
+			return triggerAuthError("触发器密钥无效")
+		}
+		return nil
+
+	case model.TriggerAuthHMAC:
+		signature := strings.TrimSpace(req.Header.Get(trigger.SignatureHeader))
+		if signature == "" {
+			return triggerAuthError("缺少签名请求头 " + trigger.SignatureHeader)
+		}
+		if trigger.SignaturePrefix != "" {
+			if !strings.HasPrefix(signature, trigger.SignaturePrefix) {
+				return triggerAuthError("签名格式错误")
+			}
+			signature = strings.TrimPrefix(signature, trigger.SignaturePrefix)
+		}
+
+		timestamp := ""
+		if trigger.TimestampHeader != "" {
+			timestamp = strings.TrimSpace(req.Header.Get(trigger.TimestampHeader))
+			if err := s.checkTimestamp(timestamp); err != nil {
+				return triggerAuthError(err.Error())
+			}
+		}
+
+		expected := SignTriggerRequest(trigger.SignatureAlgorithm, trigger.SignatureEncoding, trigger.Secret, timestamp, req.Body)
+		if !hmac.Equal([]byte(signature), []byte(expected)) {
+			return triggerAuthError("签名校验失败")
+		}
+		return nil
+	}
+	return triggerAuthError("不支持的认证方式: " + trigger.AuthMode)
+}
+
+// checkTimestamp 校验签名时间戳（Unix 秒或毫秒）在允许的偏差内，防止重放
+func (s *TriggerService) checkTimestamp(timestamp string) error {
+	if timestamp == "" {
+		return errors.New("缺少签名时间戳")
+	}
+	value, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("签名时间戳格式错误")
+	}
+	signedAt := time.Unix(value, 0)
+	if value > 1e12 {
+		signedAt = time.UnixMilli(value)
+	}
+	if skew := time.Since(signedAt); math.Abs(float64(skew)) > float64(s.cfg.SignatureTolerance) {
+		return errors.New("签名已过期")
+	}
+	return nil
+}
+
+// SignTriggerRequest 计算触发请求签名：encoding(HMAC-algorithm(secret, [timestamp + "."] + body))
+// 调用方按同样的方式签名；timestamp 为空时只对请求体签名
+func SignTriggerRequest(algorithm, encoding, secret, timestamp string, body []byte) string {
+	var newHash func() hash.Hash
+	switch algorithm {
+	case "sha1":
+		newHash = sha1.New
+	case "sha512":
+		newHash = sha512.New
+	default:
+		newHash = sha256.New
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	if timestamp != "" {
+		mac.Write([]byte(timestamp))
+		mac.Write([]byte("."))
+	}
+	mac.Write(body)
+	if encoding == "base64" {
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckRateLimit 触发器独立限流（与 Token 限流分开计数；限流器异常时不阻塞）
+func (s *TriggerService) CheckRateLimit(ctx context.Context, trigger *model.FunctionTrigger) *model.ExecutionError {
+	if s.rateLimiterService == nil {
+		return nil
+	}
+	allowed, limitInfo, err := s.rateLimiterService.CheckLimit(ctx, "trigger_"+trigger.TriggerKey, model.RateLimitConfig{
+		PerMinute:     trigger.RateLimitPerMinute,
+		Burst:         trigger.RateLimitBurst,
+		WindowSeconds: 60,
+	})
+	if err != nil {
+		utils.Error("HTTP触发器限流检查异常", zap.Int64("trigger_id", trigger.ID), zap.Error(err))
+		return nil
+	}
+	if !allowed {
+		return &model.ExecutionError{
+			Type:       utils.ErrorTypeRateLimit,
+			Message:    limitInfo.Message,
+			RetryAfter: time.Duration(limitInfo.RetryAfter) * time.Second,
+		}
+	}
+	return nil
+}
+
+// Invoke 按触发器所属 Token 执行脚本，并把返回值转换为 HTTP 响应
+// 脚本收到的 input：{ request: { method, path, query, headers, body, isBase64Encoded, ip }, trigger: { id, name } }
+func (s *TriggerService) Invoke(ctx context.Context, trigger *model.FunctionTrigger, req *TriggerRequest, requestID string) (*TriggerResponse, int, *model.ExecutionError) {
+	ctx, span := utils.StartSpan(ctx, "trigger.invoke",
+		attribute.Int64("trigger_id", trigger.ID),
+		attribute.String("function", trigger.FunctionName))
+	defer span.End()
+
+	result, execErr := s.runner.Run(ctx, &FunctionRun{
+		RequestID:    requestID,
+		Token:        trigger.Token,
+		FunctionName: trigger.FunctionName,
+		Version:      trigger.Version,
+		Input: map[string]interface{}{
+			"request": s.requestInput(trigger, req),
+			"trigger": map[string]interface{}{"id": trigger.ID, "name": trigger.Name},
+		},
+	})
+	version := 0
+	if result != nil {
+		version = result.Version
+	}
+	if execErr != nil {
+		return nil, version, execErr
+	}
+
+	resp, err := buildTriggerResponse(result.ResultJSON)
+	if err != nil {
+		return nil, version, &model.ExecutionError{Type: utils.ErrorTypeValidation, Message: "脚本返回的响应无效: " + err.Error()}
+	}
+	return resp, version, nil
+}
+
+// requestInput 转换为 input.request
+// 请求头名小写，同名请求头用 ", " 合并；查询参数取第一个值；
+// 请求体是合法 UTF-8 时为字符串，否则 base64 编码并设置 isBase64Encoded
+// 触发器密钥（X-Trigger-Secret 请求头 / secret 查询参数）和调用方凭据（Cookie / Authorization / accessToken）不会传给脚本
+func (s *TriggerService) requestInput(trigger *model.FunctionTrigger, req *TriggerRequest) map[string]interface{} {
+	headers := make(map[string]interface{}, len(req.Header))
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if triggerHopHeaders[lower] || triggerCredentialHeaders[lower] || lower == strings.ToLower(TriggerSecretHeader) {
+			continue
+		}
+		headers[lower] = strings.Join(values, ", ")
+	}
+
+	query := make(map[string]interface{}, len(req.Query))
+	for name, values := range req.Query {
+		if trigger.AuthMode == model.TriggerAuthSecret && name == "secret" {
+			continue
+		}
+		if len(values) > 0 {
+			query[name] = values[0]
+		}
+	}
+
+	body, isBase64 := string(req.Body), false
+	if !utf8.Valid(req.Body) {
+		body, isBase64 = base64.StdEncoding.EncodeToString(req.Body), true
+	}
+
+	return map[string]interface{}{
+		"method":          req.Method,
+		"path":            req.Path,
+		"query":           query,
+		"headers":         headers,
+		"body":            body,
+		"isBase64Encoded": isBase64,
+		"ip":              req.ClientIP,
+	}
+}
+
+// buildTriggerResponse 把脚本返回值转换为 HTTP 响应
+//
+// 返回对象包含 body 字段或数字类型的 status 字段时视为响应描述：
+//   - status：状态码（默认 200）
+//   - headers：响应头（值为字符串、数字、布尔或字符串数组）
+//   - body：字符串原样返回；isBase64Encoded=true 时先 base64 解码（二进制响应）；其他 JSON 值序列化后返回
+//
+// 其他返回值按 JSON 原样返回（200）
+//
+// 🔥 触发地址与页面会话 Cookie 同源：脚本只能设置 triggerResponseHeaders 中的响应头（不能设置 Cookie），
+// 所有响应都带 nosniff 和 CSP sandbox，脚本返回的 HTML 在隔离的源中渲染且不执行脚本
+func buildTriggerResponse(resultJSON []byte) (*TriggerResponse, error) {
+	resp := &TriggerResponse{Status: http.StatusOK, Header: make(http.Header)}
+	resp.Header.Set("X-Content-Type-Options", "nosniff")
+	resp.Header.Set("Content-Security-Policy", "sandbox")
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(resultJSON, &fields); err != nil || !isTriggerResponseObject(fields) {
+		resp.Header.Set("Content-Type", "application/json; charset=utf-8")
+		resp.Body = resultJSON
+		return resp, nil
+	}
+
+	if raw, ok := fields["status"]; ok {
+		var status float64
+		if err := json.Unmarshal(raw, &status); err != nil || status != math.Trunc(status) || status < 100 || status > 599 {
+			return nil, fmt.Errorf("status 必须是 100-599 的整数")
+		}
+		resp.Status = int(status)
+	}
+
+	if raw, ok := fields["headers"]; ok && string(raw) != "null" {
+		var headers map[string]interface{}
+		if err := json.Unmarshal(raw, &headers); err != nil {
+			return nil, fmt.Errorf("headers 必须是对象")
+		}
+		for name, value := range headers {
+			if !isTriggerResponseHeader(name) {
+				continue
+			}
+			switch v := value.(type) {
+			case []interface{}:
+				for _, item := range v {
+					resp.Header.Add(name, fmt.Sprint(item))
+				}
+			case nil:
+			default:
+				resp.Header.Set(name, fmt.Sprint(v))
+			}
+		}
+	}
+
+	var isBase64 bool
+	if raw, ok := fields["isBase64Encoded"]; ok {
+		_ = json.Unmarshal(raw, &isBase64)
+	}
+
+	contentType := ""
+	if raw, ok := fields["body"]; ok && string(raw) != "null" {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			if isBase64 {
+				decoded, err := base64.StdEncoding.DecodeString(text)
+				if err != nil {
+					return nil, fmt.Errorf("body 不是有效的 base64: %v", err)
+				}
+				resp.Body, contentType = decoded, "application/octet-stream"
+			} else {
+				resp.Body, contentType = []byte(text), "text/plain; charset=utf-8"
+			}
+		} else {
+			resp.Body, contentType = raw, "application/json; charset=utf-8"
+		}
+	}
+	if contentType != "" && resp.Header.Get("Content-Type") == "" {
+		resp.Header.Set("Content-Type", contentType)
+	}
+	return resp, nil
+}
+
+// isTriggerResponseHeader 脚本是否可以设置该响应头
+func isTriggerResponseHeader(name string) bool {
+	lower := strings.ToLower(name)
+	if triggerResponseHeaders[lower] {
+		return true
+	}
+	return strings.HasPrefix(lower, "x-") && !triggerReservedHeaders[lower] && !strings.HasPrefix(lower, "x-ratelimit-")
+}
+
+// isTriggerResponseObject 返回对象是否为响应描述（包含 body 字段或数字类型的 status 字段）
+func isTriggerResponseObject(fields map[string]json.RawMessage) bool {
+	if fields == nil {
+		return false
+	}
+	if _, ok := fields["body"]; ok {
+		return true
+	}
+	if raw, ok := fields["status"]; ok {
+		var status float64
+		return json.Unmarshal(raw, &status) == nil
+	}
+	return false
+}
+
+// invalidate 清除触发器缓存
+func (s *TriggerService) invalidate(key string) {
+	s.mu.Lock()
+	delete(s.cache, key)
+	s.mu.Unlock()
+}
+
+// newTriggerDetail 转换为输出（Token 脱敏，不含密钥）
+func newTriggerDetail(trigger *model.FunctionTrigger) *model.FunctionTriggerDetail {
+	masked := *trigger
+	masked.Token = utils.MaskToken(trigger.Token)
+	return &model.FunctionTriggerDetail{
+		FunctionTrigger: &masked,
+		Methods:         splitTriggerMethods(trigger.Methods),
+		Path:            "/flow/hooks/" + trigger.TriggerKey,
+	}
+}
+
+// splitTriggerMethods 拆分逗号分隔的方法列表
+func splitTriggerMethods(methods string) []string {
+	if methods == "" {
+		return []string{}
+	}
+	return strings.Split(methods, ",")
+}
+
+// randomHex 生成 n 字节的密码学安全随机数（十六进制）
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成安全随机字节失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// triggerValidationError 参数校验失败（控制器返回 400）
+func triggerValidationError(message string) *model.ExecutionError {
+	return &model.ExecutionError{Type: utils.ErrorTypeValidation, Message: message}
+}
+
+// triggerAuthError 触发请求认证失败（返回 401）
+func triggerAuthError(message string) *model.ExecutionError {
+	return &model.ExecutionError{Type: utils.ErrorTypeAuthentication, Message: message}
+}
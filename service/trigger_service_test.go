@@ -0,0 +1,46 @@
+package service
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"flow-codeblock-go/model"
+)
+
+func TestTriggerRequestInputDropsCredentials(t *testing.T) {
+	s := &TriggerService{}
+	trigger := &model.FunctionTrigger{AuthMode: model.TriggerAuthSecret}
+	req := &TriggerRequest{
+		Method: http.MethodPost,
+		Path:   "/",
+		Query:  url.Values{"secret": {"s3cret"}, "page": {"2"}},
+		Header: http.Header{
+			"Cookie":           {"flow_page_session=abc"},
+			"Authorization":    {"Bearer flow_token"},
+			"Accesstoken":      {"flow_token"},
+			"Access-Token":     {"flow_token"},
+			"X-Trigger-Secret": {"s3cret"},
+			"Connection":       {"keep-alive"},
+			"Content-Type":     {"application/json"},
+			"X-Custom":         {"a", "b"},
+		},
+		Body: []byte(`{}`),
+	}
+
+	input := s.requestInput(trigger, req)
+	headers := input["headers"].(map[string]interface{})
+	for _, name := range []string{"cookie", "authorization", "accesstoken", "access-token", "x-trigger-secret", "connection"} {
+		if _, ok := headers[name]; ok {
+			t.Errorf("请求头 %s 不应传给脚本", name)
+		}
+	}
+	if headers["content-type"] != "application/json" || headers["x-custom"] != "a, b" {
+		t.Errorf("headers = %v, want content-type 和 x-custom 原样传递", headers)
+	}
+
+	query := input["query"].(map[string]interface{})
+	if _, ok := query["secret"]; ok || query["page"] != "2" {
+		t.Errorf("query = %v, want 只有 page", query)
+	}
+}
@@ -0,0 +1,578 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/utils"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// workflowMaxBackoff 节点重试等待时间上限
+const workflowMaxBackoff = 30 * time.Second
+
+// workflowRunner 执行一个已解析版本的脚本（*FunctionRunner 实现）
+type workflowRunner interface {
+	Execute(ctx context.Context, tokenInfo *model.TokenInfo, policy *model.SandboxPolicy, fn *model.ResolvedFunction, requestID string, input map[string]interface{}) (*FunctionRunResult, *model.ExecutionError)
+}
+
+// workflowRun 一次工作流运行
+//
+// 调度协程按拓扑顺序检查节点：上游全部结束后，根据入边是否生效决定执行或跳过；
+// 节点在独立协程中执行，结果通过 completions 回传，results / outputs 只由调度协程修改
+type workflowRun struct {
+	service   *WorkflowService
+	def       *model.WorkflowDefinition
+	tokenInfo *model.TokenInfo
+	policy    *model.SandboxPolicy
+	input     map[string]interface{}
+	startTime time.Time
+
+	nodes       map[string]*model.WorkflowNode
+	functions   map[string]*model.ResolvedFunction // 节点 ID -> 本次运行使用的脚本版本
+	incoming    map[string][]*model.WorkflowEdge
+	hasOutgoing map[string]bool
+	slots       chan struct{} // 本次运行同时执行的脚本数上限
+
+	results map[string]*model.WorkflowNodeResult
+	outputs map[string]interface{} // 成功节点的结果（已解析的 JSON）
+}
+
+// workflowNodeOutcome 节点执行协程回传的结果
+type workflowNodeOutcome struct {
+	result *model.WorkflowNodeResult
+	output interface{}
+}
+
+// Run 执行工作流并返回每个节点的结果和耗时
+//
+// 节点失败（且未设置 continue_on_error）时不再启动新节点，等待执行中的节点结束后返回 status=failed；
+// 工作流不存在、未启用或脚本已被删除时返回 error（不执行任何节点）
+func (s *WorkflowService) Run(ctx context.Context, tokenInfo *model.TokenInfo, policy *model.SandboxPolicy, id int64, input map[string]interface{}, runID string) (*model.WorkflowRunResult, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrWorkflowDisabled
+	}
+	workflow, err := s.getOwned(ctx, tokenInfo.AccessToken, id)
+	if err != nil {
+		return nil, err
+	}
+	def, err := decodeWorkflowDefinition(workflow.Definition)
+	if err != nil {
+		return nil, err
+	}
+	order, err := workflowTopoOrder(def)
+	if err != nil {
+		return nil, err
+	}
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.RunTimeout)
+	defer cancel()
+	ctx, span := utils.StartSpan(ctx, "workflow.run",
+		attribute.Int64("workflow_id", workflow.ID),
+		attribute.Int("nodes", len(order)))
+	defer span.End()
+
+	// 运行开始时解析所有节点的脚本版本，移动别名不影响进行中的运行
+	functions := make(map[string]*model.ResolvedFunction, len(def.Nodes))
+	for _, node := range def.Nodes {
+		fn, err := s.functionService.Resolve(ctx, tokenInfo.WsID, node.Function, node.Version)
+		if err != nil {
+			if errors.Is(err, ErrFunctionNotFound) || errors.Is(err, ErrFunctionVersionNotFound) {
+				return nil, &model.ExecutionError{
+					Type:    utils.ErrorTypeNotFound,
+					Message: fmt.Sprintf("节点 %s: %s: %s@%s", node.ID, err.Error(), node.Function, node.Version),
+				}
+			}
+			return nil, err
+		}
+		functions[node.ID] = fn
+	}
+
+	run := s.newRun(def, tokenInfo, policy, input, functions)
+	failed := run.execute(ctx, order)
+	result := run.result(ctx, workflow.ID, runID, order, failed)
+
+	span.SetAttributes(attribute.String("status", result.Status))
+	utils.Info("工作流运行结束",
+		zap.String("request_id", runID),
+		zap.Int64("workflow_id", workflow.ID),
+		zap.String("status", result.Status),
+		zap.String("failed_node", result.FailedNode),
+		zap.Int64("total_time_ms", result.TotalTimeMs))
+	return result, nil
+}
+
+// newRun 创建一次运行（functions 为每个节点本次使用的脚本版本）
+func (s *WorkflowService) newRun(def *model.WorkflowDefinition, tokenInfo *model.TokenInfo, policy *model.SandboxPolicy, input map[string]interface{}, functions map[string]*model.ResolvedFunction) *workflowRun {
+	run := &workflowRun{
+		service:     s,
+		def:         def,
+		tokenInfo:   tokenInfo,
+		policy:      policy,
+		input:       input,
+		startTime:   time.Now(),
+		nodes:       make(map[string]*model.WorkflowNode, len(def.Nodes)),
+		functions:   functions,
+		incoming:    make(map[string][]*model.WorkflowEdge),
+		hasOutgoing: make(map[string]bool),
+		slots:       make(chan struct{}, s.cfg.MaxParallel),
+		results:     make(map[string]*model.WorkflowNodeResult, len(def.Nodes)),
+		outputs:     make(map[string]interface{}, len(def.Nodes)),
+	}
+	for _, node := range def.Nodes {
+		run.nodes[node.ID] = node
+	}
+	for _, edge := range def.Edges {
+		run.incoming[edge.To] = append(run.incoming[edge.To], edge)
+		run.hasOutgoing[edge.From] = true
+	}
+	return run
+}
+
+// execute 调度所有节点，返回导致工作流失败的节点（没有时为 nil）
+func (r *workflowRun) execute(ctx context.Context, order []*model.WorkflowNode) *model.WorkflowNodeResult {
+	started := make(map[string]bool, len(order))
+	completions := make(chan *workflowNodeOutcome, len(order))
+	running := 0
+	var failed *model.WorkflowNodeResult
+
+	for {
+		// 拓扑顺序保证同一轮中被跳过的节点对后面的节点立即可见
+		for _, node := range order {
+			if started[node.ID] || !r.predecessorsDone(node) {
+				continue
+			}
+			started[node.ID] = true
+
+			active := r.activeEdges(node)
+			if reason := r.skipReason(ctx, node, active, failed); reason != "" {
+				r.results[node.ID] = &model.WorkflowNodeResult{
+					ID:            node.ID,
+					Function:      node.Function,
+					Status:        model.WorkflowNodeSkipped,
+					SkipReason:    reason,
+					StartOffsetMs: time.Since(r.startTime).Milliseconds(),
+				}
+				continue
+			}
+
+			running++
+			go func(node *model.WorkflowNode, input map[string]interface{}) {
+				completions <- r.runNode(ctx, node, input)
+			}(node, r.buildInput(node, active))
+		}
+
+		if running == 0 {
+			return failed
+		}
+		outcome := <-completions
+		running--
+
+		result := outcome.result
+		r.results[result.ID] = result
+		if result.Status == model.WorkflowNodeSuccess {
+			r.outputs[result.ID] = outcome.output
+		} else if failed == nil && !r.nodes[result.ID].ContinueOnError {
+			failed = result
+		}
+	}
+}
+
+// predecessorsDone 上游节点是否都已结束（成功、失败或跳过）
+func (r *workflowRun) predecessorsDone(node *model.WorkflowNode) bool {
+	for _, edge := range r.incoming[node.ID] {
+		if edge.From != model.WorkflowInputSource && r.results[edge.From] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// activeEdges 生效的入边：上游成功（或为 $input）且满足 when 条件
+func (r *workflowRun) activeEdges(node *model.WorkflowNode) []*model.WorkflowEdge {
+	var active []*model.WorkflowEdge
+	for _, edge := range r.incoming[node.ID] {
+		if edge.From != model.WorkflowInputSource && r.results[edge.From].Status != model.WorkflowNodeSuccess {
+			continue
+		}
+		if edge.When != nil && !evalWorkflowCondition(edge.When, r.sourceValue(edge.From)) {
+			continue
+		}
+		active = append(active, edge)
+	}
+	return active
+}
+
+// skipReason 节点不执行的原因（为空时执行）
+func (r *workflowRun) skipReason(ctx context.Context, node *model.WorkflowNode, active []*model.WorkflowEdge, failed *model.WorkflowNodeResult) string {
+	switch {
+	case failed != nil:
+		return fmt.Sprintf("节点 %s 执行失败，后续节点未执行", failed.ID)
+	case ctx.Err() != nil:
+		return "工作流运行超时或已取消，未执行"
+	case len(r.incoming[node.ID]) > 0 && len(active) == 0:
+		return "没有生效的入边（上游条件不成立、被跳过或执行失败）"
+	}
+	return ""
+}
+
+// sourceValue 边起点的值：$input 为运行输入，其他为上游节点的结果
+func (r *workflowRun) sourceValue(from string) interface{} {
+	if from == model.WorkflowInputSource {
+		return r.input
+	}
+	return r.outputs[from]
+}
+
+// buildInput 组装节点输入：固定输入 → 运行输入（没有入边的节点）→ 生效入边的映射（按边的定义顺序，后者覆盖前者）
+func (r *workflowRun) buildInput(node *model.WorkflowNode, active []*model.WorkflowEdge) map[string]interface{} {
+	input := make(map[string]interface{}, len(node.Input)+len(active))
+	for key, value := range node.Input {
+		input[key] = value
+	}
+	if len(r.incoming[node.ID]) == 0 {
+		for key, value := range r.input {
+			input[key] = value
+		}
+	}
+
+	for _, edge := range active {
+		source := r.sourceValue(edge.From)
+		if len(edge.Map) == 0 {
+			if edge.From == model.WorkflowInputSource {
+				for key, value := range r.input {
+					input[key] = value
+				}
+			} else {
+				input[edge.From] = source
+			}
+			continue
+		}
+		for target, path := range edge.Map {
+			value, _ := lookupWorkflowPath(source, path)
+			input[target] = value
+		}
+	}
+	return input
+}
+
+// runNode 执行节点（扇出节点对数组中每个元素执行一次）
+func (r *workflowRun) runNode(ctx context.Context, node *model.WorkflowNode, input map[string]interface{}) *workflowNodeOutcome {
+	start := time.Now()
+	outcome := &workflowNodeOutcome{result: &model.WorkflowNodeResult{
+		ID:            node.ID,
+		Function:      node.Function,
+		Version:       r.functions[node.ID].Version,
+		StartOffsetMs: start.Sub(r.startTime).Milliseconds(),
+	}}
+	defer func() {
+		outcome.result.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	if node.ForEach != "" {
+		r.runFanOut(ctx, node, input, outcome)
+		return outcome
+	}
+
+	result := outcome.result
+	runResult, requestID, attempts, execErr := r.attempt(ctx, node, input)
+	result.RequestID, result.Attempts = requestID, attempts
+	if execErr != nil {
+		result.Status = model.WorkflowNodeFailed
+		result.Error = newWorkflowExecuteError(execErr)
+		return outcome
+	}
+
+	result.Status = model.WorkflowNodeSuccess
+	result.Result = json.RawMessage(runResult.ResultJSON)
+	outcome.output = decodeWorkflowResult(runResult.ResultJSON)
+	return outcome
+}
+
+// runFanOut 扇出：for_each 数组中每个元素执行一次（输入附加 item / index），结果按顺序组成数组
+// 任一元素失败时不再启动剩余元素，节点失败
+func (r *workflowRun) runFanOut(ctx context.Context, node *model.WorkflowNode, input map[string]interface{}, outcome *workflowNodeOutcome) {
+	result := outcome.result
+	value, _ := lookupWorkflowPath(input, node.ForEach)
+	items, ok := value.([]interface{})
+	if !ok {
+		result.Status = model.WorkflowNodeFailed
+		result.Error = &model.ExecuteError{
+			Type:    utils.ErrorTypeValidation,
+			Message: fmt.Sprintf("for_each 路径 %s 的值不是数组", node.ForEach),
+		}
+		return
+	}
+	if maxItems := r.service.cfg.MaxFanOutItems; len(items) > maxItems {
+		result.Status = model.WorkflowNodeFailed
+		result.Error = &model.ExecuteError{
+			Type:    utils.ErrorTypeValidation,
+			Message: fmt.Sprintf("for_each 数组长度超过上限: %d > %d", len(items), maxItems),
+		}
+		return
+	}
+
+	concurrency := node.Concurrency
+	if concurrency <= 0 || concurrency > r.service.cfg.MaxParallel {
+		concurrency = r.service.cfg.MaxParallel
+	}
+
+	result.Items = make([]*model.WorkflowItemResult, len(items))
+	itemJSON := make([]json.RawMessage, len(items))
+	itemValues := make([]interface{}, len(items))
+	sem := make(chan struct{}, concurrency)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		firstFail *model.WorkflowItemResult
+	)
+
+	for i, item := range items {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := firstFail != nil
+		mu.Unlock()
+		if stop || ctx.Err() != nil {
+			<-sem
+			result.Items[i] = &model.WorkflowItemResult{Index: i, Status: model.WorkflowNodeSkipped}
+			continue
+		}
+
+		itemInput := make(map[string]interface{}, len(input)+2)
+		for key, value := range input {
+			itemInput[key] = value
+		}
+		itemInput["item"] = item
+		itemInput["index"] = i
+
+		wg.Add(1)
+		go func(i int, itemInput map[string]interface{}) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			itemStart := time.Now()
+			runResult, requestID, attempts, execErr := r.attempt(ctx, node, itemInput)
+			itemResult := &model.WorkflowItemResult{
+				Index:      i,
+				Status:     model.WorkflowNodeSuccess,
+				RequestID:  requestID,
+				Attempts:   attempts,
+				DurationMs: time.Since(itemStart).Milliseconds(),
+			}
+			if execErr != nil {
+				itemResult.Status = model.WorkflowNodeFailed
+				itemResult.Error = newWorkflowExecuteError(execErr)
+				mu.Lock()
+				if firstFail == nil {
+					firstFail = itemResult
+				}
+				mu.Unlock()
+			} else {
+				itemJSON[i] = runResult.ResultJSON
+				itemValues[i] = decodeWorkflowResult(runResult.ResultJSON)
+			}
+			result.Items[i] = itemResult
+		}(i, itemInput)
+	}
+	wg.Wait()
+
+	for _, item := range result.Items {
+		result.Attempts += item.Attempts
+	}
+	if firstFail != nil {
+		result.Status = model.WorkflowNodeFailed
+		result.Error = &model.ExecuteError{
+			Type:       firstFail.Error.Type,
+			Message:    fmt.Sprintf("第 %d 个元素执行失败: %s", firstFail.Index, firstFail.Error.Message),
+			RetryAfter: firstFail.Error.RetryAfter,
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		result.Status = model.WorkflowNodeFailed
+		result.Error = newWorkflowExecuteError(workflowContextError(ctx))
+		return
+	}
+
+	data, _ := json.Marshal(itemJSON)
+	result.Status = model.WorkflowNodeSuccess
+	result.Result = json.RawMessage(data)
+	outcome.output = itemValues
+}
+
+// attempt 执行一次脚本，失败时按节点的 retry 设置重试（等待时间每次翻倍）
+// 每次执行使用新的 request_id，单独扣减配额、记录统计和执行历史
+func (r *workflowRun) attempt(ctx context.Context, node *model.WorkflowNode, input map[string]interface{}) (*FunctionRunResult, string, int, *model.ExecutionError) {
+	maxAttempts, backoff := 1, time.Duration(0)
+	if node.Retry != nil {
+		maxAttempts = node.Retry.MaxAttempts
+		backoff = time.Duration(node.Retry.BackoffMs) * time.Millisecond
+	}
+	policy := r.nodePolicy(node)
+
+	requestID := ""
+	for attempt := 1; ; attempt++ {
+		select {
+		case r.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, requestID, attempt - 1, workflowContextError(ctx)
+		}
+
+		requestID = uuid.New().String()
+		// 🔥 脚本可以修改 input，每次执行使用独立的副本（上游结果可能同时传给多个节点）
+		runResult, execErr := r.service.runner.Execute(ctx, r.tokenInfo, policy, r.functions[node.ID], requestID, cloneWorkflowInput(input))
+		<-r.slots
+		if execErr == nil {
+			return runResult, requestID, attempt, nil
+		}
+		if attempt >= maxAttempts || !workflowRetryable(execErr) || ctx.Err() != nil {
+			return runResult, requestID, attempt, execErr
+		}
+
+		wait := backoff << (attempt - 1)
+		if wait > workflowMaxBackoff || wait < 0 {
+			wait = workflowMaxBackoff
+		}
+		utils.Debug("工作流节点执行失败，准备重试",
+			zap.String("node", node.ID),
+			zap.String("request_id", requestID),
+			zap.Int("attempt", attempt),
+			zap.Duration("wait", wait),
+			zap.String("error_type", execErr.Type))
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return runResult, requestID, attempt, execErr
+			}
+		}
+	}
+}
+
+// nodePolicy 节点的沙箱策略：timeout_ms 小于当前生效的执行超时时覆盖，其他设置沿用 Token 策略
+func (r *workflowRun) nodePolicy(node *model.WorkflowNode) *model.SandboxPolicy {
+	if node.TimeoutMs <= 0 {
+		return r.policy
+	}
+	var limit time.Duration
+	if r.policy != nil && r.policy.ExecutionTimeoutMs != nil {
+		limit = time.Duration(*r.policy.ExecutionTimeoutMs) * time.Millisecond
+	} else {
+		limit = r.service.executor.GetExecutionTimeout()
+	}
+	if time.Duration(node.TimeoutMs)*time.Millisecond >= limit {
+		return r.policy
+	}
+
+	var policy model.SandboxPolicy
+	if r.policy != nil {
+		policy = *r.policy
+	}
+	timeoutMs := node.TimeoutMs
+	policy.ExecutionTimeoutMs = &timeoutMs
+	return &policy
+}
+
+// result 汇总运行结果
+func (r *workflowRun) result(ctx context.Context, workflowID int64, runID string, order []*model.WorkflowNode, failed *model.WorkflowNodeResult) *model.WorkflowRunResult {
+	result := &model.WorkflowRunResult{
+		RunID:       runID,
+		WorkflowID:  workflowID,
+		Status:      model.WorkflowRunSuccess,
+		Nodes:       make([]*model.WorkflowNodeResult, 0, len(order)),
+		TotalTimeMs: time.Since(r.startTime).Milliseconds(),
+	}
+	for _, node := range order {
+		result.Nodes = append(result.Nodes, r.results[node.ID])
+	}
+
+	switch {
+	case failed != nil:
+		result.Status = model.WorkflowRunFailed
+		result.FailedNode = failed.ID
+		result.Error = failed.Error
+	case ctx.Err() != nil:
+		result.Status = model.WorkflowRunFailed
+		result.Error = newWorkflowExecuteError(workflowContextError(ctx))
+	}
+
+	if r.def.Output != "" {
+		if node := r.results[r.def.Output]; node.Status == model.WorkflowNodeSuccess {
+			result.Output = node.Result
+		}
+		return result
+	}
+	outputs := make(map[string]interface{})
+	for _, node := range order {
+		if !r.hasOutgoing[node.ID] && r.results[node.ID].Status == model.WorkflowNodeSuccess {
+			outputs[node.ID] = r.results[node.ID].Result
+		}
+	}
+	result.Output = outputs
+	return result
+}
+
+// workflowRetryable 是否重试：配额用完、被取消 / 终止，以及重试也不会改变结果的静态校验错误不重试
+func workflowRetryable(execErr *model.ExecutionError) bool {
+	switch execErr.Type {
+	case "QuotaExceeded", "CancelledError", "KilledError",
+		utils.ErrorTypeValidation, "SecurityError", "SyntaxError", "ConsoleDisabledError":
+		return false
+	}
+	return true
+}
+
+// workflowContextError 运行超时（WORKFLOW_RUN_TIMEOUT_SEC）或请求被取消
+func workflowContextError(ctx context.Context) *model.ExecutionError {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &model.ExecutionError{Type: "TimeoutError", Message: "工作流运行超时"}
+	}
+	return &model.ExecutionError{Type: "CancelledError", Message: "请求已取消"}
+}
+
+// newWorkflowExecuteError 转换为响应中的错误
+func newWorkflowExecuteError(execErr *model.ExecutionError) *model.ExecuteError {
+	return &model.ExecuteError{
+		Type:       execErr.Type,
+		Message:    execErr.Message,
+		Stack:      execErr.Stack,
+		RetryAfter: execErr.RetryAfterSeconds(),
+	}
+}
+
+// cloneWorkflowInput 深拷贝输入（JSON 往返）
+func cloneWorkflowInput(input map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return input
+	}
+	var clone map[string]interface{}
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return input
+	}
+	return clone
+}
+
+// decodeWorkflowResult 解析脚本结果，供边映射和条件求值使用
+func decodeWorkflowResult(data []byte) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return value
+}
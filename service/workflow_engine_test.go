@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/model"
+)
+
+// stubWorkflowRunner 按脚本名返回结果，记录执行顺序、收到的输入和策略
+type stubWorkflowRunner struct {
+	mu       sync.Mutex
+	events   []string // start:<name> / end:<name>
+	inputs   map[string]map[string]interface{}
+	policies map[string]*model.SandboxPolicy
+
+	// run 脚本行为（为空时返回 {"node": name}）
+	run func(ctx context.Context, name string, attempt int, policy *model.SandboxPolicy, input map[string]interface{}) (interface{}, *model.ExecutionError)
+	// attempts 每个脚本的执行次数
+	attempts map[string]int
+}
+
+func newStubWorkflowRunner() *stubWorkflowRunner {
+	return &stubWorkflowRunner{
+		inputs:   make(map[string]map[string]interface{}),
+		policies: make(map[string]*model.SandboxPolicy),
+		attempts: make(map[string]int),
+	}
+}
+
+func (r *stubWorkflowRunner) Execute(ctx context.Context, tokenInfo *model.TokenInfo, policy *model.SandboxPolicy, fn *model.ResolvedFunction, requestID string, input map[string]interface{}) (*FunctionRunResult, *model.ExecutionError) {
+	r.mu.Lock()
+	r.events = append(r.events, "start:"+fn.Name)
+	r.inputs[fn.Name] = input
+	r.policies[fn.Name] = policy
+	r.attempts[fn.Name]++
+	attempt := r.attempts[fn.Name]
+	r.mu.Unlock()
+
+	var value interface{} = map[string]interface{}{"node": fn.Name}
+	var execErr *model.ExecutionError
+	if r.run != nil {
+		value, execErr = r.run(ctx, fn.Name, attempt, policy, input)
+	} else {
+		time.Sleep(5 * time.Millisecond) // 让并行节点有机会交错
+	}
+
+	r.mu.Lock()
+	r.events = append(r.events, "end:"+fn.Name)
+	r.mu.Unlock()
+	if execErr != nil {
+		return nil, execErr
+	}
+	data, _ := json.Marshal(value)
+	return &FunctionRunResult{Version: fn.Version, ResultJSON: data}, nil
+}
+
+// eventIndex 事件在执行顺序中的位置（不存在时为 -1）
+func (r *stubWorkflowRunner) eventIndex(event string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+// runTestWorkflow 用 stub 执行工作流定义（跳过存储和脚本版本解析）
+func runTestWorkflow(t *testing.T, def *model.WorkflowDefinition, runner workflowRunner, policy *model.SandboxPolicy) *model.WorkflowRunResult {
+	t.Helper()
+	order, err := workflowTopoOrder(def)
+	if err != nil {
+		t.Fatalf("workflowTopoOrder: %v", err)
+	}
+	s := &WorkflowService{
+		runner: runner,
+		cfg: config.WorkflowConfig{
+			Enabled:        true,
+			MaxFanOutItems: 10,
+			MaxParallel:    4,
+			MaxAttempts:    5,
+			RunTimeout:     5 * time.Second,
+		},
+	}
+	functions := make(map[string]*model.ResolvedFunction, len(def.Nodes))
+	for _, node := range def.Nodes {
+		functions[node.ID] = &model.ResolvedFunction{Name: node.Function, Version: 1}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.RunTimeout)
+	defer cancel()
+	run := s.newRun(def, &model.TokenInfo{}, policy, map[string]interface{}{"seed": 1}, functions)
+	failed := run.execute(ctx, order)
+	return run.result(ctx, 1, "run-1", order, failed)
+}
+
+// nodeResult 按节点 ID 查找结果
+func nodeResult(t *testing.T, result *model.WorkflowRunResult, id string) *model.WorkflowNodeResult {
+	t.Helper()
+	for _, node := range result.Nodes {
+		if node != nil && node.ID == id {
+			return node
+		}
+	}
+	t.Fatalf("缺少节点 %s 的结果", id)
+	return nil
+}
+
+// workflowNodes 节点 ID 即脚本名
+func workflowNodes(ids ...string) []*model.WorkflowNode {
+	nodes := make([]*model.WorkflowNode, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, &model.WorkflowNode{ID: id, Function: id})
+	}
+	return nodes
+}
+
+func TestWorkflowTopoOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []string
+		edges   [][2]string
+		want    string // 拓扑顺序（逗号分隔）
+		wantErr string // 错误中列出的节点
+	}{
+		{"链", []string{"c", "b", "a"}, [][2]string{{"a", "b"}, {"b", "c"}}, "a,b,c", ""},
+		{"同一层按定义顺序", []string{"a", "c", "b", "d"}, [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}}, "a,c,b,d", ""},
+		{"$input 不计入入度", []string{"b", "a"}, [][2]string{{model.WorkflowInputSource, "a"}, {model.WorkflowInputSource, "b"}}, "b,a", ""},
+		{"两个节点成环", []string{"a", "b"}, [][2]string{{"a", "b"}, {"b", "a"}}, "", "a, b"},
+		{"自环", []string{"a", "b"}, [][2]string{{"b", "b"}}, "", "b"},
+		{"环的下游也无法排序", []string{"a", "b", "c", "d"}, [][2]string{{"a", "b"}, {"b", "c"}, {"c", "b"}, {"c", "d"}}, "", "b, c, d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := &model.WorkflowDefinition{Nodes: workflowNodes(tt.nodes...)}
+			for _, e := range tt.edges {
+				def.Edges = append(def.Edges, &model.WorkflowEdge{From: e[0], To: e[1]})
+			}
+			order, err := workflowTopoOrder(def)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), "涉及节点: "+tt.wantErr) {
+					t.Fatalf("err = %v, want 涉及节点: %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("workflowTopoOrder: %v", err)
+			}
+			ids := make([]string, 0, len(order))
+			for _, node := range order {
+				ids = append(ids, node.ID)
+			}
+			if got := strings.Join(ids, ","); got != tt.want {
+				t.Fatalf("order = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflowRunDependencyOrder(t *testing.T) {
+	// 菱形：a → b / c → d
+	def := &model.WorkflowDefinition{
+		Nodes: workflowNodes("a", "b", "c", "d"),
+		Edges: []*model.WorkflowEdge{
+			{From: "a", To: "b"},
+			{From: "a", To: "c", Map: map[string]string{"from_a": "$.node"}},
+			{From: "b", To: "d"},
+			{From: "c", To: "d"},
+		},
+	}
+	runner := newStubWorkflowRunner()
+	result := runTestWorkflow(t, def, runner, nil)
+
+	if result.Status != model.WorkflowRunSuccess {
+		t.Fatalf("status = %s, error = %+v", result.Status, result.Error)
+	}
+	for _, dep := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}} {
+		if end, start := runner.eventIndex("end:"+dep[0]), runner.eventIndex("start:"+dep[1]); end < 0 || start < end {
+			t.Errorf("%s 在 %s 结束前开始: events = %v", dep[1], dep[0], runner.events)
+		}
+	}
+
+	// 输入：没有入边的节点收到运行输入；没有 map 的边以上游 ID 为字段名，有 map 的边按路径取值
+	if runner.inputs["a"]["seed"] != float64(1) {
+		t.Errorf("a input = %v, want seed", runner.inputs["a"])
+	}
+	if runner.inputs["c"]["from_a"] != "a" {
+		t.Errorf("c input = %v, want from_a=a", runner.inputs["c"])
+	}
+	if _, ok := runner.inputs["d"]["b"]; !ok {
+		t.Errorf("d input = %v, want b 和 c 的结果", runner.inputs["d"])
+	}
+	if _, ok := runner.inputs["d"]["c"]; !ok {
+		t.Errorf("d input = %v, want b 和 c 的结果", runner.inputs["d"])
+	}
+
+	// 输出：没有下游的节点
+	outputs, ok := result.Output.(map[string]interface{})
+	if !ok || len(outputs) != 1 || outputs["d"] == nil {
+		t.Errorf("output = %v, want 只有 d", result.Output)
+	}
+}
+
+func TestWorkflowRunFailurePropagation(t *testing.T) {
+	failA := func(ctx context.Context, name string, attempt int, policy *model.SandboxPolicy, input map[string]interface{}) (interface{}, *model.ExecutionError) {
+		if name == "a" {
+			return nil, &model.ExecutionError{Type: "RuntimeError", Message: "boom"}
+		}
+		return map[string]interface{}{"node": name}, nil
+	}
+
+	tests := []struct {
+		name            string
+		continueOnError bool
+		wantStatus      string
+		wantSkipReason  string // b 的跳过原因（包含）
+	}{
+		{"失败终止工作流", false, model.WorkflowRunFailed, "节点 a 执行失败"},
+		{"continue_on_error", true, model.WorkflowRunSuccess, "没有生效的入边"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a → b，c 独立
+			def := &model.WorkflowDefinition{
+				Nodes: workflowNodes("a", "b", "c"),
+				Edges: []*model.WorkflowEdge{{From: "a", To: "b"}},
+			}
+			def.Nodes[0].ContinueOnError = tt.continueOnError
+			runner := newStubWorkflowRunner()
+			runner.run = failA
+			result := runTestWorkflow(t, def, runner, nil)
+
+			if result.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", result.Status, tt.wantStatus)
+			}
+			if a := nodeResult(t, result, "a"); a.Status != model.WorkflowNodeFailed || a.Error == nil || a.Error.Type != "RuntimeError" {
+				t.Errorf("a = %+v, want failed RuntimeError", a)
+			}
+			b := nodeResult(t, result, "b")
+			if b.Status != model.WorkflowNodeSkipped || !strings.Contains(b.SkipReason, tt.wantSkipReason) {
+				t.Errorf("b = %s %q, want skipped %q", b.Status, b.SkipReason, tt.wantSkipReason)
+			}
+			if runner.eventIndex("start:b") >= 0 {
+				t.Error("b 不应执行")
+			}
+			// 与失败节点同时开始的独立节点照常执行
+			if c := nodeResult(t, result, "c"); c.Status != model.WorkflowNodeSuccess {
+				t.Errorf("c = %s, want success", c.Status)
+			}
+			if !tt.continueOnError {
+				if result.FailedNode != "a" || result.Error == nil || result.Error.Message != "boom" {
+					t.Errorf("failed_node = %s, error = %+v, want a / boom", result.FailedNode, result.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflowRunRetry(t *testing.T) {
+	def := &model.WorkflowDefinition{Nodes: workflowNodes("a", "b")}
+	def.Nodes[0].Retry = &model.WorkflowRetry{MaxAttempts: 3}
+	def.Nodes[1].Retry = &model.WorkflowRetry{MaxAttempts: 3}
+
+	runner := newStubWorkflowRunner()
+	runner.run = func(ctx context.Context, name string, attempt int, policy *model.SandboxPolicy, input map[string]interface{}) (interface{}, *model.ExecutionError) {
+		switch {
+		case name == "a" && attempt < 3:
+			return nil, &model.ExecutionError{Type: "RuntimeError", Message: "flaky"}
+		case name == "b":
+			// 静态校验错误重试也不会成功，不重试
+			return nil, &model.ExecutionError{Type: "SyntaxError", Message: "bad"}
+		}
+		return attempt, nil
+	}
+	result := runTestWorkflow(t, def, runner, nil)
+
+	if a := nodeResult(t, result, "a"); a.Status != model.WorkflowNodeSuccess || a.Attempts != 3 {
+		t.Errorf("a = %s attempts %d, want success / 3", a.Status, a.Attempts)
+	}
+	if b := nodeResult(t, result, "b"); b.Status != model.WorkflowNodeFailed || b.Attempts != 1 {
+		t.Errorf("b = %s attempts %d, want failed / 1", b.Status, b.Attempts)
+	}
+}
+
+func TestWorkflowRunNodeTimeout(t *testing.T) {
+	// Token 策略的执行超时为 1000ms：a 缩短为 50ms；b 的 5000ms 超过上限，沿用 Token 策略；c 未设置
+	def := &model.WorkflowDefinition{Nodes: workflowNodes("a", "b", "c")}
+	def.Nodes[0].TimeoutMs = 50
+	def.Nodes[1].TimeoutMs = 5000
+	tokenTimeout := 1000
+	policy := &model.SandboxPolicy{ExecutionTimeoutMs: &tokenTimeout}
+
+	// stub 按策略中的超时执行：脚本需要 200ms
+	runner := newStubWorkflowRunner()
+	runner.run = func(ctx context.Context, name string, attempt int, policy *model.SandboxPolicy, input map[string]interface{}) (interface{}, *model.ExecutionError) {
+		timeout := time.Duration(*policy.ExecutionTimeoutMs) * time.Millisecond
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return name, nil
+		case <-time.After(timeout):
+			return nil, &model.ExecutionError{Type: "TimeoutError", Message: "执行超时"}
+		}
+	}
+	result := runTestWorkflow(t, def, runner, policy)
+
+	wantTimeouts := map[string]int{"a": 50, "b": 1000, "c": 1000}
+	for id, want := range wantTimeouts {
+		got := runner.policies[id]
+		if got == nil || got.ExecutionTimeoutMs == nil || *got.ExecutionTimeoutMs != want {
+			t.Errorf("%s 的执行超时 = %v, want %d", id, got, want)
+		}
+	}
+	if runner.policies["b"] != policy || runner.policies["c"] != policy {
+		t.Error("未缩短超时的节点应直接使用 Token 策略")
+	}
+	if tokenTimeout != 1000 {
+		t.Error("节点超时不应修改 Token 策略")
+	}
+
+	if a := nodeResult(t, result, "a"); a.Status != model.WorkflowNodeFailed || a.Error.Type != "TimeoutError" {
+		t.Errorf("a = %s %+v, want failed TimeoutError", a.Status, a.Error)
+	}
+	for _, id := range []string{"b", "c"} {
+		if node := nodeResult(t, result, id); node.Status != model.WorkflowNodeSuccess {
+			t.Errorf("%s = %s, want success", id, node.Status)
+		}
+	}
+	if result.Status != model.WorkflowRunFailed || result.FailedNode != "a" {
+		t.Errorf("status = %s failed_node = %s, want failed / a", result.Status, result.FailedNode)
+	}
+}
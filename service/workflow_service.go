@@ -0,0 +1,568 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"flow-codeblock-go/config"
+	"flow-codeblock-go/model"
+	"flow-codeblock-go/pkg/sandbox"
+	"flow-codeblock-go/repository"
+	"flow-codeblock-go/utils"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrWorkflowNotFound 工作流不存在（或不属于当前 Token）
+	ErrWorkflowNotFound = errors.New("工作流不存在")
+	// ErrWorkflowDisabled 工作流未启用
+	ErrWorkflowDisabled = errors.New("工作流未启用（WORKFLOW_ENABLED=false）")
+
+	// workflowNodeIDPattern 节点 ID：字母、数字、下划线、中划线
+	workflowNodeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+// workflowMaxBackoffMs 节点重试的最大初始等待时间（毫秒）
+const workflowMaxBackoffMs = 60000
+
+// WorkflowService 工作流服务
+//
+// 🆕 工作流是由存储脚本组成的 DAG：边把上游节点的结果映射为下游节点的输入，支持条件分支、
+// 对数组扇出 / 汇聚、节点级重试和超时；运行在服务内的 JSExecutor 上完成，省去编排方逐个调用的往返
+type WorkflowService struct {
+	repo            *repository.WorkflowRepository
+	runner          workflowRunner
+	functionService *FunctionService
+	executor        *sandbox.JSExecutor
+	cfg             config.WorkflowConfig
+}
+
+// NewWorkflowService 创建工作流服务
+func NewWorkflowService(
+	repo *repository.WorkflowRepository,
+	runner *FunctionRunner,
+	functionService *FunctionService,
+	executor *sandbox.JSExecutor,
+	cfg config.WorkflowConfig,
+) *WorkflowService {
+	return &WorkflowService{
+		repo:            repo,
+		runner:          runner,
+		functionService: functionService,
+		executor:        executor,
+		cfg:             cfg,
+	}
+}
+
+// ==================== 工作流管理 ====================
+
+// Create 创建工作流（归属于当前 Token）
+func (s *WorkflowService) Create(ctx context.Context, tokenInfo *model.TokenInfo, req *model.CreateWorkflowRequest) (*model.WorkflowDetail, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrWorkflowDisabled
+	}
+
+	count, err := s.repo.CountByToken(ctx, tokenInfo.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.cfg.MaxWorkflowsPerToken {
+		return nil, workflowValidationError(fmt.Sprintf("每个 Token 最多创建 %d 个工作流", s.cfg.MaxWorkflowsPerToken))
+	}
+
+	workflow := &model.Workflow{
+		Token:       tokenInfo.AccessToken,
+		WsID:        tokenInfo.WsID,
+		Email:       tokenInfo.Email,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+	}
+	if err := s.prepare(ctx, workflow, req.Definition); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, workflow); err != nil {
+		return nil, err
+	}
+
+	utils.Info("工作流已创建",
+		zap.Int64("workflow_id", workflow.ID),
+		zap.String("ws_id", workflow.WsID),
+		zap.String("name", workflow.Name),
+		zap.Int("nodes", len(req.Definition.Nodes)),
+		zap.Int("edges", len(req.Definition.Edges)))
+	return newWorkflowDetail(workflow), nil
+}
+
+// Update 更新工作流
+func (s *WorkflowService) Update(ctx context.Context, token string, id int64, req *model.UpdateWorkflowRequest) (*model.WorkflowDetail, error) {
+	workflow, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return nil, err
+	}
+
+	definition := req.Definition
+	if definition == nil {
+		if definition, err = decodeWorkflowDefinition(workflow.Definition); err != nil {
+			return nil, err
+		}
+	}
+	if req.Name != nil {
+		workflow.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		workflow.Description = *req.Description
+	}
+
+	if err := s.prepare(ctx, workflow, definition); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, workflow); err != nil {
+		return nil, err
+	}
+
+	utils.Info("工作流已更新",
+		zap.Int64("workflow_id", workflow.ID),
+		zap.Int("nodes", len(definition.Nodes)),
+		zap.Int("edges", len(definition.Edges)))
+	return newWorkflowDetail(workflow), nil
+}
+
+// Delete 删除工作流（运行中的不受影响）
+func (s *WorkflowService) Delete(ctx context.Context, token string, id int64) error {
+	if _, err := s.getOwned(ctx, token, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	utils.Info("工作流已删除", zap.Int64("workflow_id", id))
+	return nil
+}
+
+// Get 获取工作流
+func (s *WorkflowService) Get(ctx context.Context, token string, id int64) (*model.WorkflowDetail, error) {
+	workflow, err := s.getOwned(ctx, token, id)
+	if err != nil {
+		return nil, err
+	}
+	return newWorkflowDetail(workflow), nil
+}
+
+// List 获取 Token 的全部工作流
+func (s *WorkflowService) List(ctx context.Context, token string) ([]*model.WorkflowDetail, error) {
+	workflows, err := s.repo.ListByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]*model.WorkflowDetail, 0, len(workflows))
+	for _, workflow := range workflows {
+		details = append(details, newWorkflowDetail(workflow))
+	}
+	return details, nil
+}
+
+// prepare 校验工作流并序列化定义
+func (s *WorkflowService) prepare(ctx context.Context, workflow *model.Workflow, definition *model.WorkflowDefinition) error {
+	if workflow.Name == "" {
+		return workflowValidationError("工作流名称不能为空")
+	}
+	if err := s.validateDefinition(ctx, workflow.WsID, definition); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return workflowValidationError("definition 序列化失败: " + err.Error())
+	}
+	workflow.Definition = string(data)
+	return nil
+}
+
+// validateDefinition 校验定义：节点、边引用、路径、条件、重试设置、是否有环，以及脚本是否存在
+func (s *WorkflowService) validateDefinition(ctx context.Context, wsID string, def *model.WorkflowDefinition) error {
+	if def == nil || len(def.Nodes) == 0 {
+		return workflowValidationError("工作流至少需要一个节点")
+	}
+	if len(def.Nodes) > s.cfg.MaxNodes {
+		return workflowValidationError(fmt.Sprintf("工作流节点数超过上限: %d > %d", len(def.Nodes), s.cfg.MaxNodes))
+	}
+
+	nodes := make(map[string]*model.WorkflowNode, len(def.Nodes))
+	resolved := make(map[string]bool)
+	for i, node := range def.Nodes {
+		if node == nil {
+			return workflowValidationError(fmt.Sprintf("nodes[%d] 不能为空", i))
+		}
+		if !workflowNodeIDPattern.MatchString(node.ID) {
+			return workflowValidationError(fmt.Sprintf("节点 ID 只能包含字母、数字、下划线和中划线（1-64 个字符）: %q", node.ID))
+		}
+		if nodes[node.ID] != nil {
+			return workflowValidationError("节点 ID 重复: " + node.ID)
+		}
+		nodes[node.ID] = node
+
+		if node.Function == "" {
+			return workflowValidationError(fmt.Sprintf("节点 %s 缺少 function", node.ID))
+		}
+		if node.Version == "" {
+			node.Version = model.FunctionAliasLatest
+		}
+		if node.ForEach != "" {
+			if _, err := parseWorkflowPath(node.ForEach); err != nil {
+				return workflowValidationError(fmt.Sprintf("节点 %s 的 for_each 无效: %v", node.ID, err))
+			}
+		}
+		if node.Concurrency < 0 || node.TimeoutMs < 0 {
+			return workflowValidationError(fmt.Sprintf("节点 %s 的 concurrency / timeout_ms 不能为负数", node.ID))
+		}
+		if node.Retry != nil {
+			if node.Retry.MaxAttempts < 1 || node.Retry.MaxAttempts > s.cfg.MaxAttempts {
+				return workflowValidationError(fmt.Sprintf("节点 %s 的 retry.max_attempts 必须在 1-%d 之间", node.ID, s.cfg.MaxAttempts))
+			}
+			if node.Retry.BackoffMs < 0 || node.Retry.BackoffMs > workflowMaxBackoffMs {
+				return workflowValidationError(fmt.Sprintf("节点 %s 的 retry.backoff_ms 必须在 0-%d 之间", node.ID, workflowMaxBackoffMs))
+			}
+		}
+
+		// 脚本和版本必须存在（之后被删除时，运行返回 NotFoundError）
+		ref := node.Function + "@" + node.Version
+		if resolved[ref] {
+			continue
+		}
+		if _, err := s.functionService.Resolve(ctx, wsID, node.Function, node.Version); err != nil {
+			if errors.Is(err, ErrFunctionNotFound) || errors.Is(err, ErrFunctionVersionNotFound) {
+				return workflowValidationError(fmt.Sprintf("节点 %s: %s: %s", node.ID, err.Error(), ref))
+			}
+			return err
+		}
+		resolved[ref] = true
+	}
+
+	seen := make(map[string]bool, len(def.Edges))
+	for i, edge := range def.Edges {
+		if edge == nil {
+			return workflowValidationError(fmt.Sprintf("edges[%d] 不能为空", i))
+		}
+		if edge.From != model.WorkflowInputSource && nodes[edge.From] == nil {
+			return workflowValidationError(fmt.Sprintf("edges[%d] 的起点不存在: %s", i, edge.From))
+		}
+		if nodes[edge.To] == nil {
+			return workflowValidationError(fmt.Sprintf("edges[%d] 的终点不存在: %s", i, edge.To))
+		}
+		if edge.From == edge.To {
+			return workflowValidationError(fmt.Sprintf("edges[%d] 不能指向自身: %s", i, edge.From))
+		}
+		pair := edge.From + "->" + edge.To
+		if seen[pair] {
+			return workflowValidationError("边重复: " + pair)
+		}
+		seen[pair] = true
+
+		for target, path := range edge.Map {
+			if target == "" {
+				return workflowValidationError(fmt.Sprintf("边 %s 的 map 字段名不能为空", pair))
+			}
+			if _, err := parseWorkflowPath(path); err != nil {
+				return workflowValidationError(fmt.Sprintf("边 %s 的 map.%s 无效: %v", pair, target, err))
+			}
+		}
+		if edge.When != nil {
+			if err := validateWorkflowCondition(edge.When); err != nil {
+				return workflowValidationError(fmt.Sprintf("边 %s 的 when 无效: %v", pair, err))
+			}
+		}
+	}
+
+	if def.Output != "" && nodes[def.Output] == nil {
+		return workflowValidationError("output 节点不存在: " + def.Output)
+	}
+	if _, err := workflowTopoOrder(def); err != nil {
+		return workflowValidationError(err.Error())
+	}
+	return nil
+}
+
+// getOwned 获取属于 token 的工作流（其他 Token 的工作流视为不存在）
+func (s *WorkflowService) getOwned(ctx context.Context, token string, id int64) (*model.Workflow, error) {
+	workflow, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if workflow == nil || workflow.Token != token {
+		return nil, ErrWorkflowNotFound
+	}
+	return workflow, nil
+}
+
+// ==================== 定义辅助 ====================
+
+// workflowTopoOrder 拓扑排序（同一层按定义顺序），存在环时返回错误
+func workflowTopoOrder(def *model.WorkflowDefinition) ([]*model.WorkflowNode, error) {
+	indegree := make(map[string]int, len(def.Nodes))
+	for _, edge := range def.Edges {
+		if edge.From != model.WorkflowInputSource {
+			indegree[edge.To]++
+		}
+	}
+
+	order := make([]*model.WorkflowNode, 0, len(def.Nodes))
+	placed := make(map[string]bool, len(def.Nodes))
+	for len(order) < len(def.Nodes) {
+		var next *model.WorkflowNode
+		for _, node := range def.Nodes {
+			if !placed[node.ID] && indegree[node.ID] == 0 {
+				next = node
+				break
+			}
+		}
+		if next == nil {
+			var cyclic []string
+			for _, node := range def.Nodes {
+				if !placed[node.ID] {
+					cyclic = append(cyclic, node.ID)
+				}
+			}
+			return nil, fmt.Errorf("工作流存在环，涉及节点: %s", strings.Join(cyclic, ", "))
+		}
+
+		placed[next.ID] = true
+		order = append(order, next)
+		for _, edge := range def.Edges {
+			if edge.From == next.ID {
+				indegree[edge.To]--
+			}
+		}
+	}
+	return order, nil
+}
+
+// decodeWorkflowDefinition 解析存储的定义
+func decodeWorkflowDefinition(data string) (*model.WorkflowDefinition, error) {
+	var def model.WorkflowDefinition
+	if err := json.Unmarshal([]byte(data), &def); err != nil {
+		return nil, fmt.Errorf("工作流定义解析失败: %w", err)
+	}
+	return &def, nil
+}
+
+// workflowPathSegment 路径中的一段：对象字段或数组下标
+type workflowPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseWorkflowPath 解析结果路径：$（整个值）、$.a.b、$.items[0].name、$.items.0
+func parseWorkflowPath(path string) ([]workflowPathSegment, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "$" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("路径必须以 $ 开头: %s", path)
+	}
+
+	var segments []workflowPathSegment
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("路径格式错误: %s", path)
+			}
+			segments = append(segments, workflowPathSegment{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("路径缺少 ]: %s", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("数组下标必须是非负整数: %s", path)
+			}
+			segments = append(segments, workflowPathSegment{index: index, isIndex: true})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("路径格式错误: %s", path)
+		}
+	}
+	return segments, nil
+}
+
+// lookupWorkflowPath 按路径取值（路径不存在时返回 false）
+func lookupWorkflowPath(value interface{}, path string) (interface{}, bool) {
+	segments, err := parseWorkflowPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	current := value
+	for _, segment := range segments {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			if segment.isIndex {
+				return nil, false
+			}
+			next, ok := v[segment.key]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index := segment.index
+			if !segment.isIndex {
+				n, err := strconv.Atoi(segment.key)
+				if err != nil {
+					return nil, false
+				}
+				index = n
+			}
+			if index < 0 || index >= len(v) {
+				return nil, false
+			}
+			current = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// validateWorkflowCondition 校验边条件
+func validateWorkflowCondition(cond *model.WorkflowCondition) error {
+	if _, err := parseWorkflowPath(cond.Path); err != nil {
+		return err
+	}
+	switch cond.Op {
+	case model.WorkflowOpEq, model.WorkflowOpNe,
+		model.WorkflowOpExists, model.WorkflowOpNotExists,
+		model.WorkflowOpTruthy, model.WorkflowOpFalsy:
+		return nil
+	case model.WorkflowOpGt, model.WorkflowOpGte, model.WorkflowOpLt, model.WorkflowOpLte:
+		switch cond.Value.(type) {
+		case float64, string:
+			return nil
+		}
+		return fmt.Errorf("%s 的 value 必须是数字或字符串", cond.Op)
+	case model.WorkflowOpIn, model.WorkflowOpNotIn:
+		if _, ok := cond.Value.([]interface{}); !ok {
+			return fmt.Errorf("%s 的 value 必须是数组", cond.Op)
+		}
+		return nil
+	}
+	return fmt.Errorf("不支持的运算符: %q", cond.Op)
+}
+
+// evalWorkflowCondition 对上游结果求值（路径不存在视为 null）
+func evalWorkflowCondition(cond *model.WorkflowCondition, source interface{}) bool {
+	value, found := lookupWorkflowPath(source, cond.Path)
+	switch cond.Op {
+	case model.WorkflowOpExists:
+		return found && value != nil
+	case model.WorkflowOpNotExists:
+		return !found || value == nil
+	case model.WorkflowOpTruthy:
+		return workflowTruthy(value)
+	case model.WorkflowOpFalsy:
+		return !workflowTruthy(value)
+	case model.WorkflowOpEq:
+		return reflect.DeepEqual(value, cond.Value)
+	case model.WorkflowOpNe:
+		return !reflect.DeepEqual(value, cond.Value)
+	case model.WorkflowOpIn, model.WorkflowOpNotIn:
+		matched := false
+		candidates, _ := cond.Value.([]interface{})
+		for _, candidate := range candidates {
+			if reflect.DeepEqual(value, candidate) {
+				matched = true
+				break
+			}
+		}
+		return matched == (cond.Op == model.WorkflowOpIn)
+	}
+
+	cmp, ok := compareWorkflowValues(value, cond.Value)
+	if !ok {
+		return false
+	}
+	switch cond.Op {
+	case model.WorkflowOpGt:
+		return cmp > 0
+	case model.WorkflowOpGte:
+		return cmp >= 0
+	case model.WorkflowOpLt:
+		return cmp < 0
+	case model.WorkflowOpLte:
+		return cmp <= 0
+	}
+	return false
+}
+
+// compareWorkflowValues 比较两个数字或两个字符串（类型不同时不可比较）
+func compareWorkflowValues(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+	return 0, false
+}
+
+// workflowTruthy 按 JavaScript 规则判断真值（null、false、0、"" 为假，对象和数组为真）
+func workflowTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+// newWorkflowDetail 转换为输出（Token 脱敏）
+func newWorkflowDetail(workflow *model.Workflow) *model.WorkflowDetail {
+	masked := *workflow
+	masked.Token = utils.MaskToken(workflow.Token)
+	def, err := decodeWorkflowDefinition(workflow.Definition)
+	if err != nil {
+		def = &model.WorkflowDefinition{}
+	}
+	return &model.WorkflowDetail{Workflow: &masked, Definition: def}
+}
+
+// workflowValidationError 参数校验失败（控制器返回 400）
+func workflowValidationError(message string) *model.ExecutionError {
+	return &model.ExecutionError{Type: utils.ErrorTypeValidation, Message: message}
+}
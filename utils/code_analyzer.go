@@ -2,8 +2,11 @@ package utils
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/cespare/xxhash/v2"
 )
 
 // 🚀 性能优化：预编译正则表达式，避免每次 NewCodeAnalyzer 都重新编译
@@ -13,7 +16,7 @@ var (
 	asyncPatternsOnce  sync.Once
 )
 
-// initAsyncPatterns 初始化异步模式正则表达式（只执行一次）
+// initAsyncPatterns 初始化异步模式正则表达式（只执行一次，用于语法树解析失败时的回退检测）
 func initAsyncPatterns() {
 	asyncPatternsCache = []*regexp.Regexp{
 		// Promise 相关（EventLoop 支持）
@@ -42,9 +45,13 @@ func initAsyncPatterns() {
 
 // CodeAnalyzer 代码分析器
 // 🔥 优化点6：智能检测代码特征，决定使用 Runtime 池还是 EventLoop
+// 🆕 基于 goja 语法树分析（见 code_analyzer_ast.go），解析失败时回退到正则检测
 type CodeAnalyzer struct {
-	// 异步模式正则表达式（共享全局缓存）
+	// 异步模式正则表达式（共享全局缓存，仅用于解析失败时的回退检测）
 	asyncPatterns []*regexp.Regexp
+
+	// 分析结果缓存（同一份代码在校验、路由、统计中会被分析多次）
+	featureCache *GenericLRUCache
 }
 
 // NewCodeAnalyzer 创建代码分析器
@@ -55,38 +62,89 @@ func NewCodeAnalyzer() *CodeAnalyzer {
 
 	return &CodeAnalyzer{
 		asyncPatterns: asyncPatternsCache,
+		featureCache:  NewGenericLRUCache(featureCacheSize),
 	}
 }
 
+// featureCacheSize 分析结果缓存条目数
+const featureCacheSize = 1000
+
+// 路由分析方式
+const (
+	AnalysisMethodKeyword = "keyword" // 快速预筛未命中任何异步关键字
+	AnalysisMethodAST     = "ast"     // 语法树分析
+	AnalysisMethodRegex   = "regex"   // 语法树解析失败，回退到正则检测
+)
+
 // CodeFeatures 代码特征分析结果
 type CodeFeatures struct {
-	IsAsync       bool     // 是否包含异步操作
-	AsyncReasons  []string // 检测到的异步特征
-	EstimatedType string   // 估计类型: "sync" 或 "async"
-	UseEventLoop  bool     // 是否应该使用 EventLoop
+	IsAsync       bool          // 是否包含异步操作
+	AsyncReasons  []string      // 检测到的异步特征
+	EstimatedType string        // 估计类型: "sync" 或 "async"
+	UseEventLoop  bool          // 是否应该使用 EventLoop
+	Confidence    float64       // 🆕 路由判定的置信度（0-1）
+	Trigger       *AsyncTrigger // 🆕 决定路由的语法结构（同步代码为 nil）
+	Method        string        // 🆕 分析方式: keyword / ast / regex
 }
 
 // AnalyzeCode 分析代码特征
 // 🔥 核心方法：决定代码执行策略
+// 🆕 结果按代码哈希缓存，返回值为共享对象，调用方不应修改
 func (ca *CodeAnalyzer) AnalyzeCode(code string) *CodeFeatures {
-	features := &CodeFeatures{
+	cacheKey := strconv.FormatUint(xxhash.Sum64String(code), 16)
+	if cached, found := ca.featureCache.Get(cacheKey); found {
+		if features, ok := cached.(*CodeFeatures); ok {
+			return features
+		}
+	}
+
+	var features *CodeFeatures
+	if !ca.IsLikelyAsync(code) {
+		// 快速预筛：不包含任何异步关键字，必然是同步代码
+		features = newSyncFeatures(AnalysisMethodKeyword, 1.0)
+	} else if astFeatures, ok := analyzeAsyncAST(code); ok {
+		features = astFeatures
+	} else {
+		features = ca.analyzeCodeWithRegex(code)
+	}
+
+	ca.featureCache.Put(cacheKey, features)
+	return features
+}
+
+// newSyncFeatures 创建同步代码的分析结果
+func newSyncFeatures(method string, confidence float64) *CodeFeatures {
+	return &CodeFeatures{
 		IsAsync:       false,
 		AsyncReasons:  make([]string, 0),
 		EstimatedType: "sync",
 		UseEventLoop:  false,
+		Confidence:    confidence,
+		Method:        method,
 	}
+}
+
+// analyzeCodeWithRegex 正则检测（语法树解析失败时的回退方案）
+// 代码存在语法错误时会在编译阶段失败，这里只需给出一个合理的路由
+func (ca *CodeAnalyzer) analyzeCodeWithRegex(code string) *CodeFeatures {
+	features := newSyncFeatures(AnalysisMethodRegex, regexFallbackConfidence)
 
 	// 移除字符串字面量和注释（避免误判）
 	cleanedCode := ca.removeStringsAndComments(code)
 
-	// 🔥 优化：检测异步模式（FindString + 提前退出）
-	// - FindString 替代 FindAllString：只查找第一个匹配（2x 加速）
-	// - 提前退出：找到第一个匹配后立即返回（6x 加速）
-	// - 总计：12x 加速（500μs → 42μs）
+	// 🔥 优化：检测异步模式（FindStringIndex + 提前退出）
 	for _, pattern := range ca.asyncPatterns {
-		if match := pattern.FindString(cleanedCode); match != "" {
+		if loc := pattern.FindStringIndex(cleanedCode); loc != nil {
+			match := cleanedCode[loc[0]:loc[1]]
+			line, column := lineAndColumn(code, loc[0])
 			features.IsAsync = true
 			features.AsyncReasons = append(features.AsyncReasons, match)
+			features.Trigger = &AsyncTrigger{
+				Kind:   AsyncTriggerKeyword,
+				Name:   strings.TrimSpace(match),
+				Line:   line,
+				Column: column,
+			}
 			break // 🔥 提前退出，不再检查其他 pattern
 		}
 	}
@@ -95,9 +153,6 @@ func (ca *CodeAnalyzer) AnalyzeCode(code string) *CodeFeatures {
 	if features.IsAsync {
 		features.EstimatedType = "async"
 		features.UseEventLoop = true
-	} else {
-		features.EstimatedType = "sync"
-		features.UseEventLoop = false
 	}
 
 	return features
@@ -141,19 +196,24 @@ func (ca *CodeAnalyzer) removeStringsAndComments(code string) string {
 }
 
 // IsLikelyAsync 快速判断（不做详细分析）
-// 用于性能敏感场景
+// 用于性能敏感场景；也是 AnalyzeCode 的预筛：返回 false 时代码必然是同步的，
+// 返回 true 只表示包含异步关键字（可能位于字符串、注释或普通对象的方法名中）
 // ✅ 包含 async/await 检测（goja v2025-06-30+ 已支持）
 func (ca *CodeAnalyzer) IsLikelyAsync(code string) bool {
 	// 快速关键字检测（检测 goja 支持的异步特性）
 	quickPatterns := []string{
 		"Promise",
-		".then(",
+		".then",
+		".catch",
+		".finally",
 		"setTimeout",
 		"setInterval",
 		"setImmediate",
-		"async ", // ✅ async 函数
-		"async(", // ✅ async 箭头函数
-		"await ", // ✅ await 表达式
+		"async",  // ✅ async 函数 / 箭头函数
+		"await",  // ✅ await 表达式
+		"fetch",  // 🆕 返回 Promise 的模块
+		"axios",  // 🆕 返回 Promise 的模块
+		"@async", // 特殊注释标记
 	}
 
 	for _, pattern := range quickPatterns {
@@ -168,12 +228,5 @@ func (ca *CodeAnalyzer) IsLikelyAsync(code string) bool {
 // ShouldUseRuntimePool 是否应该使用 Runtime 池
 // 🔥 决策方法：同步代码 → 使用池，异步代码 → 使用 EventLoop
 func (ca *CodeAnalyzer) ShouldUseRuntimePool(code string) bool {
-	// 快速检测（性能优先）
-	if ca.IsLikelyAsync(code) {
-		return false // 使用 EventLoop
-	}
-
-	// 详细分析（确认）
-	features := ca.AnalyzeCode(code)
-	return !features.UseEventLoop
+	return !ca.AnalyzeCode(code).UseEventLoop
 }
@@ -0,0 +1,373 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"flow-codeblock-go/model/execmodel"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+)
+
+// ============================================================================
+// 🆕 基于语法树的同步/异步路由分析
+// ============================================================================
+//
+// 与正则检测相比：
+//   - 字符串、模板字符串、注释中的 await / Promise 不会触发异步路由
+//   - 普通对象上名为 then 的方法（const obj = { then() {} }; obj.then(...)）不会触发异步路由
+//   - 用户自行声明的同名变量（如 function setTimeout() {}）不视为全局定时器
+//   - 能识别返回 Promise 的模块调用（fetch()、axios.get()、axios.create() 创建的实例）
+
+// 异步触发类型
+const (
+	AsyncTriggerMarker        = "marker"         // @async 注释标记（用户强制指定）
+	AsyncTriggerAwait         = "await"          // await 表达式
+	AsyncTriggerAsyncFunction = "async_function" // async 函数 / 箭头函数 / 方法
+	AsyncTriggerPromise       = "promise"        // 引用全局 Promise
+	AsyncTriggerTimer         = "timer"          // setTimeout / setInterval / setImmediate
+	AsyncTriggerPromiseModule = "promise_module" // 调用返回 Promise 的模块（fetch、axios）
+	AsyncTriggerThenCall      = "then_call"      // 对未知对象调用 .then / .catch / .finally
+	AsyncTriggerKeyword       = "keyword"        // 正则回退检测命中的关键字
+)
+
+// 各触发类型的置信度（多个触发时取置信度最高的一个作为路由依据）
+var asyncTriggerConfidence = map[string]float64{
+	AsyncTriggerMarker:        1.0,
+	AsyncTriggerAwait:         1.0,
+	AsyncTriggerAsyncFunction: 0.95,
+	AsyncTriggerPromise:       0.95,
+	AsyncTriggerTimer:         0.95,
+	AsyncTriggerPromiseModule: 0.9,
+	AsyncTriggerThenCall:      0.6,
+}
+
+// 各触发类型的描述（用于 AsyncReasons）
+var asyncTriggerLabels = map[string]string{
+	AsyncTriggerMarker:        "@async 标记",
+	AsyncTriggerAwait:         "await 表达式",
+	AsyncTriggerAsyncFunction: "async 函数",
+	AsyncTriggerPromise:       "引用 Promise",
+	AsyncTriggerTimer:         "定时器",
+	AsyncTriggerPromiseModule: "异步模块调用",
+	AsyncTriggerThenCall:      "then/catch/finally 调用",
+}
+
+const (
+	// 语法树分析未发现异步结构（预筛命中的关键字只出现在字符串、注释、属性名等位置）
+	astSyncConfidence = 0.95
+	// 正则回退检测的置信度
+	regexFallbackConfidence = 0.5
+	// AsyncReasons 最多保留的条数
+	maxAsyncReasons = 10
+	// 触发片段的最大长度
+	maxTriggerSnippetLength = 60
+
+	// 用户代码允许顶层 return / await，解析时包装为（异步）函数体
+	asyncAnalysisAsyncPrefix = "(async function() {"
+	asyncAnalysisSyncPrefix  = "(function() {"
+	asyncAnalysisSuffix      = "\n})"
+)
+
+var (
+	// 返回 Promise 的模块（require 名称）
+	promiseModules = map[string]bool{
+		"axios": true,
+		"fetch": true,
+	}
+
+	// 模块对象上不返回 Promise 的方法（axios.create() 返回实例，实例方法才返回 Promise）
+	promiseModuleSyncMethods = map[string]bool{
+		"create":       true,
+		"isCancel":     true,
+		"isAxiosError": true,
+		"CancelToken":  true,
+		"spread":       true,
+		"getUri":       true,
+		"mergeConfig":  true,
+		"toFormData":   true,
+		"formToJSON":   true,
+	}
+
+	// 全局定时器函数
+	timerFunctions = map[string]bool{
+		"setTimeout":   true,
+		"setInterval":  true,
+		"setImmediate": true,
+	}
+
+	// 特殊注释标记（用户可以强制指定异步路由）
+	asyncMarkerPattern = regexp.MustCompile(`//\s*@async|/\*\s*@async\s*\*/`)
+)
+
+// AsyncTrigger 决定异步路由的语法结构（定义见 execmodel，便于 pkg/sandbox 不依赖 utils）
+type AsyncTrigger = execmodel.AsyncTrigger
+
+// asyncCandidate 一处异步结构
+type asyncCandidate struct {
+	kind   string
+	offset int // 用户代码中的字节偏移
+	name   string
+}
+
+// asyncAnalyzer 遍历语法树收集异步结构
+type asyncAnalyzer struct {
+	code       string
+	offset     int             // 包装前缀长度（语法树位置 → 用户代码位置）
+	declared   map[string]bool // 用户声明过的名称（遮蔽同名全局函数）
+	plainVars  map[string]bool // 以字面量初始化的变量（其上的 then 不是 Promise）
+	moduleVars map[string]bool // 返回 Promise 的模块对象或实例
+	candidates []asyncCandidate
+}
+
+// analyzeAsyncAST 基于语法树分析代码特征；解析失败时返回 false（由调用方回退到正则检测）
+func analyzeAsyncAST(code string) (*CodeFeatures, bool) {
+	// Shebang 行替换为等长空格，保持字节偏移不变
+	source := code
+	if strings.HasPrefix(source, "#!") {
+		end := strings.IndexByte(source, '\n')
+		if end == -1 {
+			end = len(source)
+		}
+		source = strings.Repeat(" ", end) + source[end:]
+	}
+
+	// 优先按异步函数体解析（识别 await 表达式）；await 被用作普通标识符时退回同步函数体
+	prefix := asyncAnalysisAsyncPrefix
+	program, err := parser.ParseFile(nil, "", prefix+source+asyncAnalysisSuffix, 0)
+	if err != nil {
+		prefix = asyncAnalysisSyncPrefix
+		program, err = parser.ParseFile(nil, "", prefix+source+asyncAnalysisSuffix, 0)
+		if err != nil {
+			return nil, false
+		}
+	}
+
+	a := &asyncAnalyzer{
+		code:       code,
+		offset:     len(prefix),
+		declared:   make(map[string]bool),
+		plainVars:  make(map[string]bool),
+		moduleVars: make(map[string]bool),
+	}
+
+	// 第 1 遍：收集声明（遮蔽、普通对象、模块实例）
+	for _, stmt := range program.Body {
+		inspectAST(stmt, a.collect)
+	}
+	// 第 2 遍：收集异步结构
+	for _, stmt := range program.Body {
+		inspectAST(stmt, a.inspect)
+	}
+
+	// 注释不在语法树中，@async 标记单独检测
+	if loc := asyncMarkerPattern.FindStringIndex(code); loc != nil {
+		a.candidates = append(a.candidates, asyncCandidate{
+			kind:   AsyncTriggerMarker,
+			offset: loc[0],
+			name:   code[loc[0]:loc[1]],
+		})
+	}
+
+	return a.features(), true
+}
+
+// features 汇总分析结果：置信度最高的结构决定路由（相同时取位置靠前的）
+func (a *asyncAnalyzer) features() *CodeFeatures {
+	if len(a.candidates) == 0 {
+		return newSyncFeatures(AnalysisMethodAST, astSyncConfidence)
+	}
+
+	sort.SliceStable(a.candidates, func(i, j int) bool {
+		return a.candidates[i].offset < a.candidates[j].offset
+	})
+
+	best := a.candidates[0]
+	reasons := make([]string, 0, len(a.candidates))
+	seen := make(map[string]bool)
+	for _, c := range a.candidates {
+		if asyncTriggerConfidence[c.kind] > asyncTriggerConfidence[best.kind] {
+			best = c
+		}
+		reason := fmt.Sprintf("%s: %s", asyncTriggerLabels[c.kind], c.name)
+		if !seen[reason] && len(reasons) < maxAsyncReasons {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+
+	line, column := lineAndColumn(a.code, best.offset)
+	return &CodeFeatures{
+		IsAsync:       true,
+		AsyncReasons:  reasons,
+		EstimatedType: "async",
+		UseEventLoop:  true,
+		Confidence:    asyncTriggerConfidence[best.kind],
+		Trigger: &AsyncTrigger{
+			Kind:   best.kind,
+			Name:   best.name,
+			Line:   line,
+			Column: column,
+		},
+		Method: AnalysisMethodAST,
+	}
+}
+
+// collect 第 1 遍：记录声明
+func (a *asyncAnalyzer) collect(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.Binding:
+		id, ok := n.Target.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		name := id.Name.String()
+		a.declared[name] = true
+		switch init := n.Initializer.(type) {
+		case *ast.ObjectLiteral, *ast.ArrayLiteral, *ast.StringLiteral, *ast.NumberLiteral:
+			a.plainVars[name] = true
+		case *ast.CallExpression:
+			// const axios = require('axios') / const client = axios.create({...})
+			if module, ok := requiredModule(init); ok && promiseModules[module] {
+				a.moduleVars[name] = true
+			} else if dot, ok := init.Callee.(*ast.DotExpression); ok && dot.Identifier.Name == "create" {
+				if obj, ok := dot.Left.(*ast.Identifier); ok && a.moduleVars[obj.Name.String()] {
+					a.moduleVars[name] = true
+				}
+			}
+		}
+	case *ast.FunctionLiteral:
+		if n.Name != nil {
+			a.declared[n.Name.Name.String()] = true
+		}
+	case *ast.ClassLiteral:
+		if n.Name != nil {
+			a.declared[n.Name.Name.String()] = true
+		}
+	}
+	return true
+}
+
+// inspect 第 2 遍：记录异步结构
+func (a *asyncAnalyzer) inspect(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.AwaitExpression:
+		a.add(AsyncTriggerAwait, n.Idx0(), n.Idx1())
+	case *ast.FunctionLiteral:
+		if n.Async {
+			a.add(AsyncTriggerAsyncFunction, n.Idx0(), n.Body.LeftBrace)
+		}
+	case *ast.ArrowFunctionLiteral:
+		if n.Async {
+			a.add(AsyncTriggerAsyncFunction, n.Idx0(), n.Body.Idx0())
+		}
+	case *ast.Identifier:
+		name := n.Name.String()
+		if a.declared[name] {
+			return true
+		}
+		if name == "Promise" {
+			a.add(AsyncTriggerPromise, n.Idx0(), n.Idx1())
+		} else if timerFunctions[name] {
+			a.add(AsyncTriggerTimer, n.Idx0(), n.Idx1())
+		}
+	case *ast.CallExpression:
+		a.inspectCall(n)
+	}
+	return true
+}
+
+// inspectCall 识别返回 Promise 的模块调用和 then/catch/finally 调用
+func (a *asyncAnalyzer) inspectCall(call *ast.CallExpression) {
+	switch callee := call.Callee.(type) {
+	case *ast.Identifier:
+		name := callee.Name.String()
+		if (name == "fetch" && !a.declared[name]) || a.moduleVars[name] {
+			a.add(AsyncTriggerPromiseModule, call.Idx0(), call.Idx1())
+		}
+	case *ast.DotExpression:
+		method := callee.Identifier.Name.String()
+
+		// axios.get(...) / client.post(...) / require('axios').get(...)
+		if !promiseModuleSyncMethods[method] {
+			switch obj := callee.Left.(type) {
+			case *ast.Identifier:
+				if a.moduleVars[obj.Name.String()] {
+					a.add(AsyncTriggerPromiseModule, call.Idx0(), call.Idx1())
+					return
+				}
+			case *ast.CallExpression:
+				if module, ok := requiredModule(obj); ok && promiseModules[module] {
+					a.add(AsyncTriggerPromiseModule, call.Idx0(), call.Idx1())
+					return
+				}
+			}
+		}
+
+		if method != "then" && method != "catch" && method != "finally" {
+			return
+		}
+		// 普通对象上的同名方法不是 Promise
+		switch obj := callee.Left.(type) {
+		case *ast.ObjectLiteral, *ast.ArrayLiteral:
+			return
+		case *ast.Identifier:
+			if a.plainVars[obj.Name.String()] {
+				return
+			}
+		}
+		a.add(AsyncTriggerThenCall, call.Idx0(), call.Idx1())
+	}
+}
+
+// add 记录一处异步结构（idx0/idx1 为语法树中的位置）
+func (a *asyncAnalyzer) add(kind string, idx0, idx1 file.Idx) {
+	start := int(idx0) - 1 - a.offset
+	end := int(idx1) - 1 - a.offset
+	if start < 0 || start >= len(a.code) {
+		return
+	}
+	if end > len(a.code) {
+		end = len(a.code)
+	}
+
+	// 片段只保留第一行，并限制长度
+	name := a.code[start:end]
+	if nl := strings.IndexByte(name, '\n'); nl != -1 {
+		name = name[:nl]
+	}
+	name = strings.TrimSpace(name)
+	if len(name) > maxTriggerSnippetLength {
+		name = strings.ToValidUTF8(SafeSubstring(name, maxTriggerSnippetLength), "")
+	}
+
+	a.candidates = append(a.candidates, asyncCandidate{kind: kind, offset: start, name: name})
+}
+
+// requiredModule 识别 require('xxx') 调用，返回模块名
+func requiredModule(call *ast.CallExpression) (string, bool) {
+	callee, ok := call.Callee.(*ast.Identifier)
+	if !ok || callee.Name != "require" || len(call.ArgumentList) == 0 {
+		return "", false
+	}
+	arg, ok := call.ArgumentList[0].(*ast.StringLiteral)
+	if !ok {
+		return "", false
+	}
+	return arg.Value.String(), true
+}
+
+// lineAndColumn 根据字节偏移计算行号和列号（从 1 开始）
+func lineAndColumn(code string, offset int) (int, int) {
+	if offset > len(code) {
+		offset = len(code)
+	}
+	before := code[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - (strings.LastIndexByte(before, '\n') + 1) + 1
+	return line, column
+}
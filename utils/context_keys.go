@@ -6,4 +6,10 @@ type ContextKey string
 const (
 	// RequestIDKey context中request_id的key
 	RequestIDKey ContextKey = "request_id"
+
+	// SandboxPolicyKey context中沙箱策略（*model.SandboxPolicy）的key
+	SandboxPolicyKey ContextKey = "sandbox_policy"
+
+	// ExecutionTenantKey context中调度身份（Token / 工作空间）的key
+	ExecutionTenantKey ContextKey = "execution_tenant"
 )
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // 测试不依赖系统时区数据库
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%s): %v", name, err)
+	}
+	return loc
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"段数不足", "* * * *"},
+		{"段数过多", "* * * * * *"},
+		{"分钟越界", "60 * * * *"},
+		{"小时越界", "0 24 * * *"},
+		{"日为 0", "0 0 0 * *"},
+		{"月越界", "0 0 1 13 *"},
+		{"星期越界", "0 0 * * 8"},
+		{"范围反向", "0 10-5 * * *"},
+		{"步长为 0", "*/0 * * * *"},
+		{"步长非数字", "*/x * * * *"},
+		{"空列表项", "1,,2 * * * *"},
+		{"未知缩写", "0 0 * foo *"},
+		{"未知预定义", "@every"},
+		{"空表达式", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCron(tt.expr); err == nil {
+				t.Fatalf("ParseCron(%q) 应返回错误", tt.expr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time // 零值表示不会触发
+	}{
+		{"步长", "*/15 * * * *",
+			time.Date(2026, 1, 1, 10, 7, 30, 0, time.UTC), time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"严格晚于 after", "@daily",
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"起点加步长", "5/20 * * * *",
+			time.Date(2026, 1, 1, 10, 26, 0, 0, time.UTC), time.Date(2026, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"工作日跳过周末", "0 9 * * 1-5",
+			time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)},
+		{"星期 7 为周日", "0 12 * * 7",
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)},
+		{"日和星期满足任意一个", "0 0 1,15 * MON",
+			time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"星期为 * 时只按日", "0 0 15 * *",
+			time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"跳过没有 31 日的月份", "30 23 31 * *",
+			time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC)},
+		{"月份缩写跨年", "0 0 1 jan *",
+			time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"闰年 2 月 29 日", "0 0 29 2 *",
+			time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"永远不会触发", "0 0 30 2 *",
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+
+		// 时区：按 after 所在时区计算
+		{"上海时区", "0 9 * * *",
+			time.Date(2026, 1, 1, 9, 30, 0, 0, shanghai), time.Date(2026, 1, 2, 9, 0, 0, 0, shanghai)},
+		{"上海时区的 UTC 输入", "0 9 * * *",
+			time.Date(2026, 1, 1, 1, 30, 0, 0, time.UTC).In(shanghai), time.Date(2026, 1, 2, 1, 0, 0, 0, time.UTC)},
+
+		// 夏令时开始（2026-03-08 02:00 → 03:00）：跳过的时刻不触发，顺延到下一个匹配时刻
+		{"夏令时跳过的时刻", "30 2 * * *",
+			time.Date(2026, 3, 7, 3, 0, 0, 0, newYork), time.Date(2026, 3, 9, 2, 30, 0, 0, newYork)},
+		{"夏令时开始后的整点", "0 * * * *",
+			time.Date(2026, 3, 8, 1, 30, 0, 0, newYork), time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC)},
+
+		// 夏令时结束（2026-11-01 02:00 → 01:00）：重复的时刻只触发一次
+		{"夏令时重复时刻第一次", "30 1 * * *",
+			time.Date(2026, 11, 1, 0, 0, 0, 0, newYork), time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC)},
+		{"夏令时重复时刻不再触发", "30 1 * * *",
+			time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC).In(newYork), time.Date(2026, 11, 2, 1, 30, 0, 0, newYork)},
+		{"夏令时重复的一小时内按分钟", "*/30 * * * *",
+			time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC).In(newYork), time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+			}
+			got := c.Next(tt.after)
+			if !got.Equal(tt.want) {
+				t.Fatalf("Next(%v) = %v, want %v", tt.after, got, tt.want)
+			}
+			if !got.IsZero() && got.Location() != tt.after.Location() {
+				t.Errorf("时区 = %v, want %v", got.Location(), tt.after.Location())
+			}
+		})
+	}
+}
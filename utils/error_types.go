@@ -0,0 +1,15 @@
+package utils
+
+// 常用错误类型常量
+const (
+	ErrorTypeAuthentication = "AuthenticationError"
+	ErrorTypeAuthorization  = "AuthorizationError"
+	ErrorTypeValidation     = "ValidationError"
+	ErrorTypeNotFound       = "NotFoundError"
+	ErrorTypeRateLimit      = "RateLimitError"
+	ErrorTypeTokenRateLimit = "TokenRateLimitError"
+	ErrorTypeIPRateLimit    = "IPRateLimitError"
+	ErrorTypeInternal       = "InternalError"
+	ErrorTypeServiceUnavail = "ServiceUnavailableError"
+	ErrorTypeBadRequest     = "BadRequestError"
+)
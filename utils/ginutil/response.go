@@ -1,7 +1,13 @@
-package utils
+// Package ginutil gin 响应辅助函数（统一的成功 / 错误响应格式）
+//
+// 从 utils 中拆出，避免 utils（以及依赖它的 pkg/sandbox）依赖 gin
+package ginutil
 
 import (
 	"net/http"
+	"strconv"
+
+	"flow-codeblock-go/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -45,7 +51,7 @@ func RespondError(c *gin.Context, code int, errType, message string, details map
 			Message: message,
 			Details: details,
 		},
-		Timestamp: FormatTime(Now()),
+		Timestamp: utils.FormatTime(utils.Now()),
 		RequestID: c.GetString("request_id"), // 从上下文获取请求ID（如果有中间件设置）
 	}
 
@@ -62,7 +68,7 @@ func RespondSuccess(c *gin.Context, data interface{}, message string) {
 		Success:   true,
 		Data:      data,
 		Message:   message,
-		Timestamp: FormatTime(Now()),
+		Timestamp: utils.FormatTime(utils.Now()),
 		RequestID: c.GetString("request_id"),
 	}
 
@@ -80,23 +86,16 @@ func RespondSuccessWithCode(c *gin.Context, code int, data interface{}, message
 		Success:   true,
 		Data:      data,
 		Message:   message,
-		Timestamp: FormatTime(Now()),
+		Timestamp: utils.FormatTime(utils.Now()),
 		RequestID: c.GetString("request_id"),
 	}
 
 	c.JSON(code, response)
 }
 
-// 常用错误类型常量
-const (
-	ErrorTypeAuthentication = "AuthenticationError"
-	ErrorTypeAuthorization  = "AuthorizationError"
-	ErrorTypeValidation     = "ValidationError"
-	ErrorTypeNotFound       = "NotFoundError"
-	ErrorTypeRateLimit      = "RateLimitError"
-	ErrorTypeTokenRateLimit = "TokenRateLimitError"
-	ErrorTypeIPRateLimit    = "IPRateLimitError"
-	ErrorTypeInternal       = "InternalError"
-	ErrorTypeServiceUnavail = "ServiceUnavailableError"
-	ErrorTypeBadRequest     = "BadRequestError"
-)
+// SetRetryAfter 设置 Retry-After 响应头（单位：秒，<= 0 时不设置）
+func SetRetryAfter(c *gin.Context, seconds int) {
+	if seconds > 0 {
+		c.Header("Retry-After", strconv.Itoa(seconds))
+	}
+}
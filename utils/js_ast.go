@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"github.com/dop251/goja/ast"
+)
+
+// inspectAST 深度优先遍历 goja 语法树（类似 go/ast.Inspect）
+// fn 返回 false 时不再遍历该节点的子节点
+//
+// 说明：
+//   - 只访问表达式和语句位置的节点；函数名、类名、标签、obj.prop 中的属性名不会作为 Identifier 访问
+//   - 声明中的 *ast.Binding 会被访问（可据此收集变量声明）
+func inspectAST(node ast.Node, fn func(ast.Node) bool) {
+	if isNilNode(node) || !fn(node) {
+		return
+	}
+
+	walkExprs := func(list ...ast.Expression) {
+		for _, e := range list {
+			if e != nil {
+				inspectAST(e, fn)
+			}
+		}
+	}
+	walkStmts := func(list ...ast.Statement) {
+		for _, s := range list {
+			if s != nil {
+				inspectAST(s, fn)
+			}
+		}
+	}
+	walkBindings := func(list []*ast.Binding) {
+		for _, b := range list {
+			if b != nil {
+				inspectAST(b, fn)
+			}
+		}
+	}
+	walkParams := func(params *ast.ParameterList) {
+		if params != nil {
+			walkBindings(params.List)
+			walkExprs(params.Rest)
+		}
+	}
+
+	switch n := node.(type) {
+	// ==================== 语句 ====================
+	case *ast.BlockStatement:
+		walkStmts(n.List...)
+	case *ast.ExpressionStatement:
+		walkExprs(n.Expression)
+	case *ast.VariableStatement:
+		walkBindings(n.List)
+	case *ast.LexicalDeclaration:
+		walkBindings(n.List)
+	case *ast.FunctionDeclaration:
+		inspectAST(n.Function, fn)
+	case *ast.ClassDeclaration:
+		inspectAST(n.Class, fn)
+	case *ast.IfStatement:
+		walkExprs(n.Test)
+		walkStmts(n.Consequent, n.Alternate)
+	case *ast.ForStatement:
+		switch init := n.Initializer.(type) {
+		case *ast.ForLoopInitializerExpression:
+			walkExprs(init.Expression)
+		case *ast.ForLoopInitializerVarDeclList:
+			walkBindings(init.List)
+		case *ast.ForLoopInitializerLexicalDecl:
+			walkBindings(init.LexicalDeclaration.List)
+		}
+		walkExprs(n.Test, n.Update)
+		walkStmts(n.Body)
+	case *ast.ForInStatement:
+		inspectForInto(n.Into, fn)
+		walkExprs(n.Source)
+		walkStmts(n.Body)
+	case *ast.ForOfStatement:
+		inspectForInto(n.Into, fn)
+		walkExprs(n.Source)
+		walkStmts(n.Body)
+	case *ast.WhileStatement:
+		walkExprs(n.Test)
+		walkStmts(n.Body)
+	case *ast.DoWhileStatement:
+		walkStmts(n.Body)
+		walkExprs(n.Test)
+	case *ast.LabelledStatement:
+		walkStmts(n.Statement)
+	case *ast.ReturnStatement:
+		walkExprs(n.Argument)
+	case *ast.ThrowStatement:
+		walkExprs(n.Argument)
+	case *ast.TryStatement:
+		walkStmts(n.Body)
+		if n.Catch != nil {
+			walkExprs(n.Catch.Parameter)
+			walkStmts(n.Catch.Body)
+		}
+		if n.Finally != nil {
+			walkStmts(n.Finally)
+		}
+	case *ast.SwitchStatement:
+		walkExprs(n.Discriminant)
+		for _, c := range n.Body {
+			walkExprs(c.Test)
+			walkStmts(c.Consequent...)
+		}
+	case *ast.WithStatement:
+		walkExprs(n.Object)
+		walkStmts(n.Body)
+
+	// ==================== 表达式 ====================
+	case *ast.Binding:
+		walkExprs(n.Target, n.Initializer)
+	case *ast.FunctionLiteral:
+		walkParams(n.ParameterList)
+		if n.Body != nil {
+			walkStmts(n.Body)
+		}
+	case *ast.ArrowFunctionLiteral:
+		walkParams(n.ParameterList)
+		switch body := n.Body.(type) {
+		case *ast.BlockStatement:
+			walkStmts(body)
+		case *ast.ExpressionBody:
+			walkExprs(body.Expression)
+		}
+	case *ast.ClassLiteral:
+		walkExprs(n.SuperClass)
+		for _, elem := range n.Body {
+			switch el := elem.(type) {
+			case *ast.MethodDefinition:
+				if el.Computed {
+					walkExprs(el.Key)
+				}
+				if el.Body != nil {
+					inspectAST(el.Body, fn)
+				}
+			case *ast.FieldDefinition:
+				if el.Computed {
+					walkExprs(el.Key)
+				}
+				walkExprs(el.Initializer)
+			case *ast.ClassStaticBlock:
+				if el.Block != nil {
+					walkStmts(el.Block)
+				}
+			}
+		}
+	case *ast.DotExpression:
+		walkExprs(n.Left)
+	case *ast.PrivateDotExpression:
+		walkExprs(n.Left)
+	case *ast.BracketExpression:
+		walkExprs(n.Left, n.Member)
+	case *ast.CallExpression:
+		walkExprs(n.Callee)
+		walkExprs(n.ArgumentList...)
+	case *ast.NewExpression:
+		walkExprs(n.Callee)
+		walkExprs(n.ArgumentList...)
+	case *ast.BinaryExpression:
+		walkExprs(n.Left, n.Right)
+	case *ast.AssignExpression:
+		walkExprs(n.Left, n.Right)
+	case *ast.UnaryExpression:
+		walkExprs(n.Operand)
+	case *ast.ConditionalExpression:
+		walkExprs(n.Test, n.Consequent, n.Alternate)
+	case *ast.SequenceExpression:
+		walkExprs(n.Sequence...)
+	case *ast.ArrayLiteral:
+		walkExprs(n.Value...)
+	case *ast.ArrayPattern:
+		walkExprs(n.Elements...)
+		walkExprs(n.Rest)
+	case *ast.ObjectLiteral:
+		for _, prop := range n.Value {
+			inspectAST(prop, fn)
+		}
+	case *ast.ObjectPattern:
+		for _, prop := range n.Properties {
+			inspectAST(prop, fn)
+		}
+		walkExprs(n.Rest)
+	case *ast.PropertyShort:
+		inspectAST(&n.Name, fn)
+		walkExprs(n.Initializer)
+	case *ast.PropertyKeyed:
+		if n.Computed {
+			walkExprs(n.Key)
+		}
+		walkExprs(n.Value)
+	case *ast.SpreadElement:
+		walkExprs(n.Expression)
+	case *ast.TemplateLiteral:
+		walkExprs(n.Tag)
+		walkExprs(n.Expressions...)
+	case *ast.AwaitExpression:
+		walkExprs(n.Argument)
+	case *ast.YieldExpression:
+		walkExprs(n.Argument)
+	case *ast.OptionalChain:
+		walkExprs(n.Expression)
+	case *ast.Optional:
+		walkExprs(n.Expression)
+	}
+}
+
+// inspectForInto 遍历 for-in / for-of 的左侧
+func inspectForInto(into ast.ForInto, fn func(ast.Node) bool) {
+	switch i := into.(type) {
+	case *ast.ForIntoVar:
+		if i.Binding != nil {
+			inspectAST(i.Binding, fn)
+		}
+	case *ast.ForDeclaration:
+		if i.Target != nil {
+			inspectAST(i.Target, fn)
+		}
+	case *ast.ForIntoExpression:
+		if i.Expression != nil {
+			inspectAST(i.Expression, fn)
+		}
+	}
+}
+
+// isNilNode 判断接口值是否为 nil（包括持有 nil 指针的接口）
+func isNilNode(node ast.Node) bool {
+	if node == nil {
+		return true
+	}
+	switch n := node.(type) {
+	case *ast.BlockStatement:
+		return n == nil
+	case *ast.FunctionLiteral:
+		return n == nil
+	case *ast.ClassLiteral:
+		return n == nil
+	case *ast.Binding:
+		return n == nil
+	}
+	return false
+}
@@ -47,6 +47,25 @@ func InitLogger(env string) error {
 	return nil
 }
 
+// InitCLILogger 初始化命令行工具的日志（输出到 stderr，stdout 留给执行结果）
+// verbose: true 时输出 DEBUG 级别，否则只输出 WARN 及以上
+func InitCLILogger(verbose bool) error {
+	config := zap.NewDevelopmentConfig()
+	config.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+	if verbose {
+		config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+	}
+	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	config.OutputPaths = []string{"stderr"}
+	config.ErrorOutputPaths = []string{"stderr"}
+	config.DisableStacktrace = !verbose
+
+	var err error
+	Logger, err = config.Build(zap.AddCallerSkip(1))
+	return err
+}
+
 // GetLoggerWithExecutionID 创建带 execution_id 的 logger
 func GetLoggerWithExecutionID(executionID string) *zap.Logger {
 	if Logger == nil {
@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Prometheus 文本格式（原生实现，不依赖 client_golang）
+// 格式说明：https://prometheus.io/docs/instrumenting/exposition_formats/
+// ============================================================================
+
+// PrometheusContentType /metrics 响应的 Content-Type
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultLatencyBuckets 默认耗时分桶（秒），覆盖 1ms ~ 60s
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Histogram 并发安全的直方图（固定分桶）
+type Histogram struct {
+	buckets []float64 // 升序的分桶上限
+
+	mu     sync.Mutex
+	counts []uint64 // 每个分桶的计数（非累计），最后一个为 +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram 创建直方图
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64) {
+	idx := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	h.counts[idx]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// ObserveDuration 记录耗时（秒）
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// snapshot 返回累计计数、总和、总数
+func (h *Histogram) snapshot() (cumulative []uint64, sum float64, count uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cumulative = make([]uint64, len(h.counts))
+	var acc uint64
+	for i, c := range h.counts {
+		acc += c
+		cumulative[i] = acc
+	}
+	return cumulative, h.sum, h.count
+}
+
+// HistogramVec 按标签值分组的直方图
+type HistogramVec struct {
+	labelNames []string
+	buckets    []float64
+
+	mu         sync.RWMutex
+	histograms map[string]*labeledHistogram
+}
+
+type labeledHistogram struct {
+	labelValues []string
+	histogram   *Histogram
+}
+
+// NewHistogramVec 创建按标签分组的直方图
+func NewHistogramVec(buckets []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{
+		labelNames: labelNames,
+		buckets:    buckets,
+		histograms: make(map[string]*labeledHistogram),
+	}
+}
+
+// With 获取标签值对应的直方图（不存在时创建），标签值数量必须与标签名一致
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.RLock()
+	lh, ok := v.histograms[key]
+	v.mu.RUnlock()
+	if ok {
+		return lh.histogram
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if lh, ok = v.histograms[key]; !ok {
+		lh = &labeledHistogram{
+			labelValues: append([]string(nil), labelValues...),
+			histogram:   NewHistogram(v.buckets),
+		}
+		v.histograms[key] = lh
+	}
+	return lh.histogram
+}
+
+// HistogramTotal 直方图按标签值分组的累计值
+type HistogramTotal struct {
+	LabelValues []string
+	Count       uint64
+	Sum         float64
+}
+
+// Totals 返回每组标签值的累计次数和总和（用于 JSON 统计接口）
+func (v *HistogramVec) Totals() []HistogramTotal {
+	v.mu.RLock()
+	entries := make([]*labeledHistogram, 0, len(v.histograms))
+	for _, lh := range v.histograms {
+		entries = append(entries, lh)
+	}
+	v.mu.RUnlock()
+
+	totals := make([]HistogramTotal, 0, len(entries))
+	for _, lh := range entries {
+		lh.histogram.mu.Lock()
+		totals = append(totals, HistogramTotal{
+			LabelValues: lh.labelValues,
+			Count:       lh.histogram.count,
+			Sum:         lh.histogram.sum,
+		})
+		lh.histogram.mu.Unlock()
+	}
+	return totals
+}
+
+// MetricLabel 指标标签
+type MetricLabel struct {
+	Name  string
+	Value string
+}
+
+// PrometheusWriter Prometheus 文本格式构建器
+type PrometheusWriter struct {
+	buf bytes.Buffer
+}
+
+// Header 写入指标的 HELP 和 TYPE（每个指标名只写一次）
+func (w *PrometheusWriter) Header(name, metricType, help string) {
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, metricType)
+}
+
+// Sample 写入一个样本
+func (w *PrometheusWriter) Sample(name string, value float64, labels ...MetricLabel) {
+	w.buf.WriteString(name)
+	writeLabels(&w.buf, labels)
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(formatMetricValue(value))
+	w.buf.WriteByte('\n')
+}
+
+// Gauge 写入单值 gauge（HELP + TYPE + 样本）
+func (w *PrometheusWriter) Gauge(name, help string, value float64) {
+	w.Header(name, "gauge", help)
+	w.Sample(name, value)
+}
+
+// Counter 写入单值 counter（HELP + TYPE + 样本）
+func (w *PrometheusWriter) Counter(name, help string, value float64) {
+	w.Header(name, "counter", help)
+	w.Sample(name, value)
+}
+
+// Histogram 写入直方图
+func (w *PrometheusWriter) Histogram(name, help string, h *Histogram) {
+	w.Header(name, "histogram", help)
+	w.writeHistogram(name, h, nil)
+}
+
+// HistogramVec 写入按标签分组的直方图（按标签值排序，输出稳定）
+func (w *PrometheusWriter) HistogramVec(name, help string, v *HistogramVec) {
+	w.Header(name, "histogram", help)
+
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.histograms))
+	for key := range v.histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([]*labeledHistogram, len(keys))
+	for i, key := range keys {
+		entries[i] = v.histograms[key]
+	}
+	v.mu.RUnlock()
+
+	for _, lh := range entries {
+		labels := make([]MetricLabel, len(v.labelNames))
+		for i, labelName := range v.labelNames {
+			labels[i] = MetricLabel{Name: labelName, Value: lh.labelValues[i]}
+		}
+		w.writeHistogram(name, lh.histogram, labels)
+	}
+}
+
+// writeHistogram 写入直方图的 _bucket / _sum / _count 样本
+func (w *PrometheusWriter) writeHistogram(name string, h *Histogram, labels []MetricLabel) {
+	cumulative, sum, count := h.snapshot()
+	bucketLabels := make([]MetricLabel, len(labels)+1)
+	copy(bucketLabels, labels)
+
+	for i, upper := range h.buckets {
+		bucketLabels[len(labels)] = MetricLabel{Name: "le", Value: formatMetricValue(upper)}
+		w.Sample(name+"_bucket", float64(cumulative[i]), bucketLabels...)
+	}
+	bucketLabels[len(labels)] = MetricLabel{Name: "le", Value: "+Inf"}
+	w.Sample(name+"_bucket", float64(cumulative[len(cumulative)-1]), bucketLabels...)
+	w.Sample(name+"_sum", sum, labels...)
+	w.Sample(name+"_count", float64(count), labels...)
+}
+
+// Bytes 返回构建结果
+func (w *PrometheusWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// writeLabels 写入 {name="value",...}
+func writeLabels(buf *bytes.Buffer, labels []MetricLabel) {
+	if len(labels) == 0 {
+		return
+	}
+	buf.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(label.Name)
+		buf.WriteString(`="`)
+		buf.WriteString(escapeLabelValue(label.Value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('}')
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+// formatMetricValue 格式化样本值（整数不带小数点，特殊值按规范输出）
+func formatMetricValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName 链路追踪的 instrumentation 名称
+// 🔥 这里只有 span 辅助函数（只依赖 otel API）；导出器和 TracerProvider 初始化在 utils/tracingutil 中
+const TracerName = "flow-codeblock-go"
+
+func init() {
+	// 🔥 W3C traceparent 传播器始终生效：即使未启用导出，也会把入站请求的 traceparent 透传给出站请求
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// StartSpan 创建子 span，并附加 context 中的 request_id
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if reqID, ok := ctx.Value(RequestIDKey).(string); ok && reqID != "" {
+		attrs = append(attrs, attribute.String("request_id", reqID))
+	}
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan 结束 span，err 不为 nil 时记录错误并标记失败
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
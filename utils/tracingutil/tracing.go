@@ -0,0 +1,93 @@
+// Package tracingutil 链路追踪初始化（OTLP 导出器和 TracerProvider）
+//
+// 从 utils 中拆出，避免 utils（以及依赖它的 pkg/sandbox）依赖 OTLP 导出器和 gRPC；
+// span 辅助函数（utils.StartSpan / utils.EndSpan）只依赖 otel API，仍在 utils 中
+package tracingutil
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"flow-codeblock-go/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// 链路追踪导出器类型
+const (
+	ExporterOTLPHTTP = "otlphttp" // OTLP/HTTP（protobuf，默认端口 4318）
+	ExporterOTLPGRPC = "otlpgrpc" // OTLP/gRPC（默认端口 4317）
+)
+
+// Options 链路追踪初始化参数
+type Options struct {
+	ServiceName string
+	Exporter    string  // otlphttp / otlpgrpc
+	Endpoint    string  // 完整地址（如 http://127.0.0.1:4318）；为空时使用 OTEL_EXPORTER_OTLP_* 环境变量或导出器默认值
+	SampleRatio float64 // 根 span 采样率（0-1）；有上游 traceparent 时跟随上游的采样决定
+}
+
+// Init 初始化链路追踪（批量导出到 OTLP）
+// 未调用时全局 TracerProvider 为 noop，所有 span 操作开销可忽略
+// 返回的 shutdown 在优雅关闭时调用，刷新尚未导出的 span
+func Init(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	var client otlptrace.Client
+	switch opts.Exporter {
+	case ExporterOTLPGRPC:
+		var grpcOpts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithEndpointURL(opts.Endpoint))
+		}
+		client = otlptracegrpc.NewClient(grpcOpts...)
+	case ExporterOTLPHTTP, "":
+		var httpOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			// 与 OTEL_EXPORTER_OTLP_ENDPOINT 一致：只配置到端口时追加 /v1/traces
+			endpoint := opts.Endpoint
+			if u, err := url.Parse(endpoint); err == nil && strings.Trim(u.Path, "/") == "" {
+				endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+			}
+			httpOpts = append(httpOpts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		client = otlptracehttp.NewClient(httpOpts...)
+	default:
+		return nil, fmt.Errorf("不支持的链路追踪导出器: %s", opts.Exporter)
+	}
+
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("创建链路追踪导出器失败: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", opts.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("创建链路追踪资源失败: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		utils.Warn("链路追踪导出失败", zap.Error(err))
+	}))
+
+	utils.Info("链路追踪已启用",
+		zap.String("exporter", opts.Exporter),
+		zap.String("endpoint", opts.Endpoint),
+		zap.Float64("sample_ratio", opts.SampleRatio))
+	return provider.Shutdown, nil
+}
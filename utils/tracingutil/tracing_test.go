@@ -0,0 +1,151 @@
+package tracingutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"flow-codeblock-go/utils"
+
+	"go.opentelemetry.io/otel"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpReceiver 本地 OTLP/HTTP 接收端（代替 Collector，记录收到的 span）
+type otlpReceiver struct {
+	mu       sync.Mutex
+	paths    []string
+	services []string
+	spans    []*tracepb.Span
+}
+
+func (r *otlpReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var export collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &export); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	r.paths = append(r.paths, req.URL.Path)
+	for _, rs := range export.ResourceSpans {
+		for _, attr := range rs.GetResource().GetAttributes() {
+			if attr.Key == "service.name" {
+				r.services = append(r.services, attr.GetValue().GetStringValue())
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			r.spans = append(r.spans, ss.Spans...)
+		}
+	}
+	r.mu.Unlock()
+
+	resp, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(resp)
+}
+
+// spanNamed 按名称查找收到的 span
+func (r *otlpReceiver) spanNamed(name string) *tracepb.Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, span := range r.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func spanAttribute(span *tracepb.Span, key string) string {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.GetValue().GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestInitExportsSpansToOTLPHTTP(t *testing.T) {
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	// 只配置到端口：与 OTEL_EXPORTER_OTLP_ENDPOINT 一致，追加 /v1/traces
+	shutdown, err := Init(context.Background(), Options{
+		ServiceName: "flow-codeblock-test",
+		Exporter:    ExporterOTLPHTTP,
+		Endpoint:    server.URL,
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatalf("InitTracing: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), utils.RequestIDKey, "req-123")
+	ctx, parent := utils.StartSpan(ctx, "http.request")
+	_, child := utils.StartSpan(ctx, "quota.consume")
+	utils.EndSpan(child, errors.New("配额不足"))
+	utils.EndSpan(parent, nil)
+
+	// shutdown 刷新批量导出器中尚未导出的 span
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	receiver.mu.Lock()
+	paths, services := receiver.paths, receiver.services
+	receiver.mu.Unlock()
+	if len(paths) == 0 {
+		t.Fatal("接收端没有收到导出请求")
+	}
+	for _, path := range paths {
+		if path != "/v1/traces" {
+			t.Errorf("导出路径 = %q, want /v1/traces", path)
+		}
+	}
+	if len(services) == 0 || services[0] != "flow-codeblock-test" {
+		t.Errorf("service.name = %v, want flow-codeblock-test", services)
+	}
+
+	parentSpan := receiver.spanNamed("http.request")
+	childSpan := receiver.spanNamed("quota.consume")
+	if parentSpan == nil || childSpan == nil {
+		t.Fatalf("缺少 span: http.request=%v quota.consume=%v", parentSpan != nil, childSpan != nil)
+	}
+	if string(childSpan.TraceId) != string(parentSpan.TraceId) || string(childSpan.ParentSpanId) != string(parentSpan.SpanId) {
+		t.Error("quota.consume 不是 http.request 的子 span")
+	}
+	if got := spanAttribute(childSpan, "request_id"); got != "req-123" {
+		t.Errorf("request_id = %q, want req-123", got)
+	}
+	if childSpan.GetStatus().GetCode() != tracepb.Status_STATUS_CODE_ERROR || childSpan.GetStatus().GetMessage() != "配额不足" {
+		t.Errorf("status = %v, want error 配额不足", childSpan.GetStatus())
+	}
+	if parentSpan.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		t.Error("http.request 不应标记为失败")
+	}
+}
+
+func TestInitRejectsUnknownExporter(t *testing.T) {
+	if _, err := Init(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Fatal("不支持的导出器应返回错误")
+	}
+}